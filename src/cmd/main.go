@@ -27,6 +27,16 @@ func main() {
 		app.RestoreApp(os.Args[2:])
 	case "log":
 		app.LogApp(os.Args[2:])
+	case "key":
+		app.KeyApp(os.Args[2:])
+	case "verify":
+		app.VerifyApp(os.Args[2:])
+	case "snapshot":
+		app.SnapshotApp(os.Args[2:])
+	case "prune":
+		app.PruneApp(os.Args[2:])
+	case "gc":
+		app.GcApp(os.Args[2:])
 	default:
 		printRootHelp()
 	}
@@ -37,4 +47,9 @@ func printRootHelp() {
 	fmt.Println("Supported modes:")
 	fmt.Println("  - backup: \tIt creates or updates backups from a database.")
 	fmt.Println("  - restore: \tIt restores your database from a backup.")
+	fmt.Println("  - key: \tIt manages the passphrase of an encrypted backup.")
+	fmt.Println("  - verify: \tIt checks the integrity of a backup.")
+	fmt.Println("  - snapshot: \tIt deletes a snapshot from a backup.")
+	fmt.Println("  - prune: \tIt deletes the snapshots of a backup which a retention policy does not keep.")
+	fmt.Println("  - gc: \tIt deletes the files of a backup which no snapshot references.")
 }
@@ -5,6 +5,7 @@ import (
 	"flag"
 	"fmt"
 	"historydb/src/internal/handlers"
+	"historydb/src/internal/services/backup/binary"
 	"historydb/src/internal/usecases"
 	"net/url"
 	"os"
@@ -25,11 +26,19 @@ func BackupApp(args []string) {
 
 	action := args[0]
 	connString := backupFlags.String("connString", "", "Database connection string")
-	basePath := backupFlags.String("path", "", "Path where the backup directory is located, or where it will be created")
+	basePath := backupFlags.String("path", "", "Path where the backup directory is located, or where it will be created. It can be an s3://bucket/prefix or sftp://user@host/path URL")
 	message := backupFlags.String("message", "", "Optional message which will be saved in the snapshot")
+	jobs := backupFlags.Int("jobs", 1, "Number of schemas whose records are saved at once")
+	compressionArg := backupFlags.String("compression", "zstd", "Codec used to compress a new backup (none or zstd)")
+	compressionLevel := backupFlags.Int64("compressionLevel", binary.DefaultCompressionLevel, "Level used by the zstd codec")
+	encrypt := backupFlags.Bool("encrypt", false, "Encrypt a new backup with a key derived from its passphrase")
+	keyFile := backupFlags.String("keyFile", "", "File with the passphrase of the backup")
+	resume := backupFlags.Bool("resume", false, "Resume the last snapshot interrupted before its commit")
+	maxChainDepth := backupFlags.Int64("max-chain-depth", 0, "Maximum number of diffs applied to rebuild an object of the backup")
+	reverseDeltas := backupFlags.Bool("reverse-deltas", false, "Save every snapshot of a new backup in full, and the snapshot before it as diffs")
 	backupFlags.Parse(args[1:])
 
-	engine, err := checkBackupArgsAndObtainEngine(action, *connString, *basePath)
+	engine, err := checkBackupArgsAndObtainEngine(action, *connString, *basePath, *jobs, *compressionArg, *maxChainDepth)
 	if err != nil {
 		if errors.Is(err, ErrUnsuportedAction) || errors.Is(err, ErrArgumentNotProvided) {
 			return
@@ -37,16 +46,30 @@ func BackupApp(args []string) {
 		panic(err)
 	}
 
-	db, err := openDBConnection(engine, *connString)
+	passphrase, err := readPassphrase(*keyFile, passphraseEnv)
+	if err != nil {
+		return
+	}
+	if *encrypt && passphrase == nil {
+		fmt.Printf("It is required to provide the passphrase of the backup with --keyFile or %s to encrypt it\n", passphraseEnv)
+		return
+	}
+
+	dbFactory, closeDB, err := openDatabaseFactory(engine, *connString)
 	if err != nil {
 		panic(err)
 	}
-	defer db.Close()
+	defer closeDB()
 
-	if err := os.MkdirAll(*basePath, 0755); err != nil {
+	storage, logPath, closeStorage, err := openBackupStorage(*basePath)
+	if err != nil {
+		return
+	}
+	defer closeStorage()
+	if err := os.MkdirAll(logPath, 0755); err != nil {
 		return
 	}
-	loggerFile, err := os.OpenFile(path.Join(*basePath, "backup.log"), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	loggerFile, err := os.OpenFile(path.Join(logPath, "backup.log"), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
 	if err != nil {
 		panic(err)
 	}
@@ -58,8 +81,19 @@ func BackupApp(args []string) {
 	}
 	logrus.SetLevel(logrus.InfoLevel)
 
-	dbFactory := createDatabaseFactory(engine, db)
-	backupFactory := createBackupFactory(*basePath)
+	backupFactory := createBackupFactory(storage, binary.BinaryBackupOptions{
+		Compression:      supportedCompressions[*compressionArg],
+		CompressionLevel: *compressionLevel,
+		Encrypt:          *encrypt,
+		Passphrase:       passphrase,
+		MaxChainDepth:    *maxChainDepth,
+		ReverseDeltas:    *reverseDeltas,
+	})
+	unlock, ok := lockBackup(backupFactory, true, !*resume, logger)
+	if !ok {
+		return
+	}
+	defer unlock()
 
 	backupUsecases := usecases.NewBackupUsecasesImpl(dbFactory, backupFactory, logger)
 
@@ -67,13 +101,13 @@ func BackupApp(args []string) {
 
 	switch action {
 	case "create":
-		backupHandler.CreateBackup(*message)
+		backupHandler.CreateBackup(*message, *jobs, *resume)
 	case "snapshot":
-		backupHandler.SnapshotBackup(*message)
+		backupHandler.SnapshotBackup(*message, *jobs, *resume)
 	}
 }
 
-func checkBackupArgsAndObtainEngine(action, connString, path string) (string, error) {
+func checkBackupArgsAndObtainEngine(action, connString, path string, jobs int, compression string, maxChainDepth int64) (string, error) {
 	if _, ok := supportedBackupActions[action]; !ok {
 		fmt.Printf("The action '%s' is not supported in the backup app.\n", action)
 		return "", ErrUnsuportedAction
@@ -87,6 +121,18 @@ func checkBackupArgsAndObtainEngine(action, connString, path string) (string, er
 		fmt.Print("It is required to provide the argument --path\n")
 		return "", ErrArgumentNotProvided
 	}
+	if jobs < 1 {
+		fmt.Print("The argument --jobs must be at least 1\n")
+		return "", ErrArgumentNotProvided
+	}
+	if _, ok := supportedCompressions[compression]; !ok {
+		fmt.Printf("The compression '%s' is not supported, it must be none or zstd\n", compression)
+		return "", ErrArgumentNotProvided
+	}
+	if maxChainDepth < 0 {
+		fmt.Print("The argument --max-chain-depth can not be negative\n")
+		return "", ErrArgumentNotProvided
+	}
 
 	parsedCDN, err := url.Parse(connString)
 	if err != nil || parsedCDN.Scheme == "" {
@@ -109,6 +155,14 @@ func printBackupHelp() {
 	fmt.Println("  snapshot \tIt snapshots the current state of the database into the already created backup")
 	fmt.Println("Options:")
 	fmt.Println("  --connString \tDatabase connection string from where to back-up the data")
-	fmt.Println("  --path \tPath where the backup is located, or where it will be created")
+	fmt.Println("  --path \tPath where the backup is located, or where it will be created. It can be an s3://bucket/prefix or sftp://user@host/path URL")
 	fmt.Println("  --message \tOptional message which will be saved in the snapshot")
+	fmt.Println("  --jobs \tOptional number of schemas whose records are saved at once, each one through its own connection (1 by default)")
+	fmt.Println("  --compression \tOptional codec used to compress a new backup, none or zstd (zstd by default). Snapshots keep the codec of the backup")
+	fmt.Println("  --compressionLevel \tOptional level used by the zstd codec (3 by default)")
+	fmt.Println("  --encrypt \tOptional flag to encrypt a new backup with a key derived from its passphrase")
+	fmt.Println("  --keyFile \tOptional file with the passphrase of the backup. If it is not provided, HISTORYDB_PASSPHRASE is used")
+	fmt.Println("  --resume \tOptional flag to resume the last snapshot interrupted before its commit, keeping the records it saved. Without it, the interrupted snapshot is discarded")
+	fmt.Println("  --max-chain-depth \tOptional maximum number of diffs applied to rebuild an object. Once an object would need more, it is saved in full again. It is saved in the backup, so later snapshots keep it (unbounded by default)")
+	fmt.Println("  --reverse-deltas \tOptional flag to save every snapshot of a new backup in full, and the record chunks of the snapshot before it as diffs of its chunks, so the last snapshot is restored without applying diffs. Snapshots keep the mode of the backup")
 }
@@ -0,0 +1,80 @@
+package app
+
+import (
+	"flag"
+	"fmt"
+	"historydb/src/internal/handlers"
+	"historydb/src/internal/services/backup/binary"
+	"historydb/src/internal/usecases"
+	"os"
+	"path"
+
+	"github.com/sirupsen/logrus"
+)
+
+// GcApp is the main execution for gc mode in the app
+func GcApp(args []string) {
+	if len(args) < 1 {
+		printGcHelp()
+		return
+	}
+
+	gcFlags := flag.NewFlagSet("gc", flag.ExitOnError)
+	gcFlags.Usage = printGcHelp
+
+	backupPath := gcFlags.String("path", "", "Path where the backup is located")
+	dryRun := gcFlags.Bool("dry-run", false, "List the unreferenced files without deleting them")
+	keyFile := gcFlags.String("keyFile", "", "File with the passphrase of the backup")
+	gcFlags.Parse(args)
+
+	if *backupPath == "" {
+		fmt.Print("It is required to provide the argument --path\n")
+		return
+	}
+
+	passphrase, err := readPassphrase(*keyFile, passphraseEnv)
+	if err != nil {
+		return
+	}
+
+	storage, logPath, closeStorage, err := openBackupStorage(*backupPath)
+	if err != nil {
+		return
+	}
+	defer closeStorage()
+	loggerFile, err := os.OpenFile(path.Join(logPath, "backup.log"), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		fmt.Println("There is no backup located in the specified path")
+		return
+	}
+
+	logger := &logrus.Logger{
+		Out:       loggerFile,
+		Level:     logrus.InfoLevel,
+		Formatter: &logrus.TextFormatter{FullTimestamp: true},
+	}
+	logrus.SetLevel(logrus.InfoLevel)
+
+	// A dry run only reads the backup, while unfinished snapshots are discarded before collecting, as their staged objects
+	// may be taken from unreferenced objects which are deleted
+	backupFactory := createBackupFactory(storage, binary.BinaryBackupOptions{Passphrase: passphrase})
+	unlock, ok := lockBackup(backupFactory, !*dryRun, !*dryRun, logger)
+	if !ok {
+		return
+	}
+	defer unlock()
+
+	gcUsecases := usecases.NewGcUsecasesImpl(backupFactory, logger)
+
+	gcHandler := handlers.NewGcHandler(gcUsecases)
+
+	gcHandler.CollectGarbage(*dryRun)
+}
+
+func printGcHelp() {
+	fmt.Println("Usage: historydb gc [options]")
+	fmt.Println("Options:")
+	fmt.Println("  --path \tPath where the backup is located. It can be an s3://bucket/prefix or sftp://user@host/path URL")
+	fmt.Println("  --dry-run \tOptional flag which lists the files no snapshot references and the bytes they take, without deleting them")
+	fmt.Println("  --keyFile \tOptional file with the passphrase of an encrypted backup. If it is not provided, HISTORYDB_PASSPHRASE is used")
+}
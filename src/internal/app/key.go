@@ -0,0 +1,98 @@
+package app
+
+import (
+	"flag"
+	"fmt"
+	"historydb/src/internal/handlers"
+	"historydb/src/internal/services/backup/binary"
+	"historydb/src/internal/usecases"
+	"os"
+	"path"
+
+	"github.com/sirupsen/logrus"
+)
+
+var supportedKeyActions = map[string]bool{"rotate": true}
+
+// KeyApp is the main execution for key mode in the app
+func KeyApp(args []string) {
+	if len(args) < 1 {
+		printKeyHelp()
+		return
+	}
+
+	keyFlags := flag.NewFlagSet("key", flag.ExitOnError)
+	keyFlags.Usage = printKeyHelp
+
+	action := args[0]
+	backupPath := keyFlags.String("path", "", "Path where the backup is located")
+	keyFile := keyFlags.String("keyFile", "", "File with the current passphrase of the backup")
+	newKeyFile := keyFlags.String("newKeyFile", "", "File with the new passphrase of the backup")
+	keyFlags.Parse(args[1:])
+
+	if _, ok := supportedKeyActions[action]; !ok {
+		fmt.Printf("The action '%s' is not supported in the key app.\n", action)
+		return
+	}
+	if *backupPath == "" {
+		fmt.Print("It is required to provide the argument --path\n")
+		return
+	}
+
+	passphrase, err := readPassphrase(*keyFile, passphraseEnv)
+	if err != nil {
+		return
+	}
+	newPassphrase, err := readPassphrase(*newKeyFile, newPassphraseEnv)
+	if err != nil {
+		return
+	}
+	if newPassphrase == nil {
+		fmt.Printf("It is required to provide the new passphrase of the backup with --newKeyFile or %s\n", newPassphraseEnv)
+		return
+	}
+
+	storage, logPath, closeStorage, err := openBackupStorage(*backupPath)
+	if err != nil {
+		return
+	}
+	defer closeStorage()
+	loggerFile, err := os.OpenFile(path.Join(logPath, "backup.log"), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		fmt.Println("There is no backup located in the specified path")
+		return
+	}
+
+	logger := &logrus.Logger{
+		Out:       loggerFile,
+		Level:     logrus.InfoLevel,
+		Formatter: &logrus.TextFormatter{FullTimestamp: true},
+	}
+	logrus.SetLevel(logrus.InfoLevel)
+
+	backupFactory := createBackupFactory(storage, binary.BinaryBackupOptions{Passphrase: passphrase})
+	unlock, ok := lockBackup(backupFactory, true, false, logger)
+	if !ok {
+		return
+	}
+	defer unlock()
+
+	keyUsecases := usecases.NewKeyUsecasesImpl(backupFactory, logger)
+
+	keyHandler := handlers.NewKeyHandler(keyUsecases)
+
+	switch action {
+	case "rotate":
+		keyHandler.RotateKey(newPassphrase)
+	}
+}
+
+func printKeyHelp() {
+	fmt.Println("Usage: historydb key [action] [options]")
+	fmt.Println("Actions:")
+	fmt.Println("  rotate \tIt wraps the data key of an encrypted backup with a new passphrase")
+	fmt.Println("Options:")
+	fmt.Println("  --path \tPath where the backup is located. It can be an s3://bucket/prefix or sftp://user@host/path URL")
+	fmt.Println("  --keyFile \tOptional file with the current passphrase of the backup. If it is not provided, HISTORYDB_PASSPHRASE is used")
+	fmt.Println("  --newKeyFile \tOptional file with the new passphrase of the backup. If it is not provided, HISTORYDB_NEW_PASSPHRASE is used")
+}
@@ -4,6 +4,7 @@ import (
 	"flag"
 	"fmt"
 	"historydb/src/internal/handlers"
+	"historydb/src/internal/services/backup/binary"
 	"historydb/src/internal/usecases"
 	"os"
 	"path"
@@ -21,9 +22,20 @@ func LogApp(args []string) {
 	logFlags.Usage = printLogHelp
 
 	backupPath := logFlags.String("path", "", "Path where the backup is located")
+	keyFile := logFlags.String("keyFile", "", "File with the passphrase of the backup")
 	logFlags.Parse(args[:])
 
-	loggerFile, err := os.OpenFile(path.Join(*backupPath, "backup.log"), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	passphrase, err := readPassphrase(*keyFile, passphraseEnv)
+	if err != nil {
+		return
+	}
+
+	storage, logPath, closeStorage, err := openBackupStorage(*backupPath)
+	if err != nil {
+		return
+	}
+	defer closeStorage()
+	loggerFile, err := os.OpenFile(path.Join(logPath, "backup.log"), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
 	if err != nil {
 		fmt.Println("There is no backup located in the specified path")
 		return
@@ -36,7 +48,12 @@ func LogApp(args []string) {
 	}
 	logrus.SetLevel(logrus.InfoLevel)
 
-	backupFactory := createBackupFactory(*backupPath)
+	backupFactory := createBackupFactory(storage, binary.BinaryBackupOptions{Passphrase: passphrase})
+	unlock, ok := lockBackup(backupFactory, false, false, logger)
+	if !ok {
+		return
+	}
+	defer unlock()
 
 	logUsecases := usecases.NewLogUsecasesImpl(backupFactory, logger)
 
@@ -48,5 +65,6 @@ func LogApp(args []string) {
 func printLogHelp() {
 	fmt.Println("Usage: historydb log [options]")
 	fmt.Println("Options:")
-	fmt.Println("  --path \tPath where the backup is located")
+	fmt.Println("  --path \tPath where the backup is located. It can be an s3://bucket/prefix or sftp://user@host/path URL")
+	fmt.Println("  --keyFile \tOptional file with the passphrase of an encrypted backup. If it is not provided, HISTORYDB_PASSPHRASE is used")
 }
@@ -0,0 +1,94 @@
+package app
+
+import (
+	"flag"
+	"fmt"
+	"historydb/src/internal/entities"
+	"historydb/src/internal/handlers"
+	"historydb/src/internal/services/backup/binary"
+	"historydb/src/internal/usecases"
+	"os"
+	"path"
+
+	"github.com/sirupsen/logrus"
+)
+
+// PruneApp is the main execution for prune mode in the app
+func PruneApp(args []string) {
+	if len(args) < 1 {
+		printPruneHelp()
+		return
+	}
+
+	pruneFlags := flag.NewFlagSet("prune", flag.ExitOnError)
+	pruneFlags.Usage = printPruneHelp
+
+	backupPath := pruneFlags.String("path", "", "Path where the backup is located")
+	keepHourly := pruneFlags.Int("keep-hourly", 0, "Number of hours whose newest snapshot is kept")
+	keepDaily := pruneFlags.Int("keep-daily", 0, "Number of days whose newest snapshot is kept")
+	keepMonthly := pruneFlags.Int("keep-monthly", 0, "Number of months whose newest snapshot is kept")
+	keyFile := pruneFlags.String("keyFile", "", "File with the passphrase of the backup")
+	pruneFlags.Parse(args)
+
+	if *backupPath == "" {
+		fmt.Print("It is required to provide the argument --path\n")
+		return
+	}
+	if *keepHourly < 0 || *keepDaily < 0 || *keepMonthly < 0 {
+		fmt.Println("The arguments --keep-hourly, --keep-daily and --keep-monthly can not be negative.")
+		return
+	}
+	// A policy which keeps nothing would delete every snapshot of the backup
+	if *keepHourly == 0 && *keepDaily == 0 && *keepMonthly == 0 {
+		fmt.Println("It is required to provide at least one of the arguments --keep-hourly, --keep-daily or --keep-monthly")
+		return
+	}
+
+	passphrase, err := readPassphrase(*keyFile, passphraseEnv)
+	if err != nil {
+		return
+	}
+
+	storage, logPath, closeStorage, err := openBackupStorage(*backupPath)
+	if err != nil {
+		return
+	}
+	defer closeStorage()
+	loggerFile, err := os.OpenFile(path.Join(logPath, "backup.log"), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		fmt.Println("There is no backup located in the specified path")
+		return
+	}
+
+	logger := &logrus.Logger{
+		Out:       loggerFile,
+		Level:     logrus.InfoLevel,
+		Formatter: &logrus.TextFormatter{FullTimestamp: true},
+	}
+	logrus.SetLevel(logrus.InfoLevel)
+
+	// Unfinished snapshots are discarded, as their staged diffs may be taken from the objects being deleted
+	backupFactory := createBackupFactory(storage, binary.BinaryBackupOptions{Passphrase: passphrase})
+	unlock, ok := lockBackup(backupFactory, true, true, logger)
+	if !ok {
+		return
+	}
+	defer unlock()
+
+	snapshotUsecases := usecases.NewSnapshotUsecasesImpl(backupFactory, logger)
+
+	snapshotHandler := handlers.NewSnapshotHandler(snapshotUsecases)
+
+	snapshotHandler.PruneSnapshots(entities.RetentionPolicy{Hourly: *keepHourly, Daily: *keepDaily, Monthly: *keepMonthly})
+}
+
+func printPruneHelp() {
+	fmt.Println("Usage: historydb prune [options]")
+	fmt.Println("Options:")
+	fmt.Println("  --path \t\tPath where the backup is located. It can be an s3://bucket/prefix or sftp://user@host/path URL")
+	fmt.Println("  --keep-hourly \tOptional number of the last hours with snapshots whose newest snapshot is kept")
+	fmt.Println("  --keep-daily \t\tOptional number of the last days with snapshots whose newest snapshot is kept")
+	fmt.Println("  --keep-monthly \tOptional number of the last months with snapshots whose newest snapshot is kept")
+	fmt.Println("  --keyFile \t\tOptional file with the passphrase of an encrypted backup. If it is not provided, HISTORYDB_PASSPHRASE is used")
+	fmt.Println("At least one of --keep-hourly, --keep-daily and --keep-monthly must be provided. The snapshots kept by none of them are deleted")
+}
@@ -4,7 +4,9 @@ import (
 	"errors"
 	"flag"
 	"fmt"
+	"historydb/src/internal/entities"
 	"historydb/src/internal/handlers"
+	"historydb/src/internal/services/backup/binary"
 	"historydb/src/internal/usecases"
 	"historydb/src/internal/utils/pointers"
 	"net/url"
@@ -23,6 +25,10 @@ func RestoreApp(args []string) {
 	connString := restoreFlags.String("connString", "", "Database connection string where to restore all the data")
 	basePath := restoreFlags.String("path", "", "Path where the backup is located")
 	snapshotArg := restoreFlags.String("from", "", "Snapshot ID or Timestamp from where to restore the database")
+	jobs := restoreFlags.Int("jobs", 1, "Number of schemas which are restored at once")
+	keyFile := restoreFlags.String("keyFile", "", "File with the passphrase of the backup")
+	commit := restoreFlags.String("commit", string(entities.RestoreCommitAll), "How often the restore is committed: all, table or batch")
+	resume := restoreFlags.Bool("resume", false, "Resume the restore interrupted in the database")
 
 	if err := restoreFlags.Parse(args); err != nil {
 		return
@@ -33,7 +39,12 @@ func RestoreApp(args []string) {
 		panic(err)
 	}
 
-	engine, err := checkRestoreArgsAndObtainEngine(*connString, *basePath)
+	commitMode, err := checkRestoreCommit(*commit, snapshot, *resume)
+	if err != nil {
+		return
+	}
+
+	engine, err := checkRestoreArgsAndObtainEngine(*connString, *basePath, *jobs)
 	if err != nil {
 		if errors.Is(err, ErrUnsuportedAction) || errors.Is(err, ErrArgumentNotProvided) {
 			return
@@ -41,17 +52,27 @@ func RestoreApp(args []string) {
 		panic(err)
 	}
 
-	db, err := openDBConnection(engine, *connString)
+	passphrase, err := readPassphrase(*keyFile, passphraseEnv)
+	if err != nil {
+		return
+	}
+
+	dbFactory, closeDB, err := openDatabaseFactory(engine, *connString)
 	if err != nil {
 		panic(err)
 	}
-	defer db.Close()
+	defer closeDB()
 
-	if _, err := os.Stat(*basePath); err != nil {
+	storage, logPath, closeStorage, err := openBackupStorage(*basePath)
+	if err != nil {
+		return
+	}
+	defer closeStorage()
+	if _, err := os.Stat(logPath); err != nil {
 		fmt.Println("The specified path does not seem to contain a backup")
 		return
 	}
-	loggerFile, err := os.OpenFile(path.Join(*basePath, "backup.log"), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	loggerFile, err := os.OpenFile(path.Join(logPath, "backup.log"), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
 	if err != nil {
 		panic(err)
 	}
@@ -63,13 +84,17 @@ func RestoreApp(args []string) {
 	}
 	logrus.SetLevel(logrus.InfoLevel)
 
-	dbFactory := createDatabaseFactory(engine, db)
-	backupFactory := createBackupFactory(*basePath)
+	backupFactory := createBackupFactory(storage, binary.BinaryBackupOptions{Passphrase: passphrase})
+	unlock, ok := lockBackup(backupFactory, false, false, logger)
+	if !ok {
+		return
+	}
+	defer unlock()
 
 	restoreUsecases := usecases.NewRestoreUsecasesImpl(dbFactory, backupFactory, logger)
 
 	restoreHandler := handlers.NewRestoreHandler(restoreUsecases)
-	restoreHandler.RestoreDatabase(snapshot)
+	restoreHandler.RestoreDatabase(snapshot, *jobs, commitMode, *resume)
 }
 
 func checkSnapshot(snapshot string) (*string, error) {
@@ -88,7 +113,21 @@ func checkSnapshot(snapshot string) (*string, error) {
 	return pointers.Ptr(snapshot), nil
 }
 
-func checkRestoreArgsAndObtainEngine(connString, path string) (string, error) {
+func checkRestoreCommit(commit string, snapshot *string, resume bool) (entities.RestoreCommitMode, error) {
+	commitMode := entities.RestoreCommitMode(commit)
+	if commitMode != entities.RestoreCommitAll && commitMode != entities.RestoreCommitTable && commitMode != entities.RestoreCommitBatch {
+		fmt.Println("--commit argument needs to be all, table or batch")
+		return "", fmt.Errorf("invalid --commit")
+	}
+	if resume && snapshot != nil {
+		fmt.Println("--from argument cannot be provided with --resume, as the interrupted restore is resumed from its snapshot")
+		return "", fmt.Errorf("invalid --from")
+	}
+
+	return commitMode, nil
+}
+
+func checkRestoreArgsAndObtainEngine(connString, path string, jobs int) (string, error) {
 	if connString == "" {
 		fmt.Printf("It is required to provide the argument --connString\n")
 		return "", ErrArgumentNotProvided
@@ -97,6 +136,10 @@ func checkRestoreArgsAndObtainEngine(connString, path string) (string, error) {
 		fmt.Print("It is required to provide the argument --path\n")
 		return "", ErrArgumentNotProvided
 	}
+	if jobs < 1 {
+		fmt.Print("The argument --jobs must be at least 1\n")
+		return "", ErrArgumentNotProvided
+	}
 
 	parsedCDN, err := url.Parse(connString)
 	if err != nil || parsedCDN.Scheme == "" {
@@ -116,6 +159,10 @@ func printRestoreHelp() {
 	fmt.Println("Usage: historydb restroe [options]")
 	fmt.Println("Options:")
 	fmt.Println("  --connString \tDatabase connection string where to restore all the data")
-	fmt.Println("  --path \tPath where the backup is located")
+	fmt.Println("  --path \tPath where the backup is located. It can be an s3://bucket/prefix or sftp://user@host/path URL")
 	fmt.Println("  --from \tSnapshot ID or Timestamp from where to restore the database")
+	fmt.Println("  --jobs \tOptional number of schemas which are restored at once, each one through its own connection (1 by default)")
+	fmt.Println("  --keyFile \tOptional file with the passphrase of an encrypted backup. If it is not provided, HISTORYDB_PASSPHRASE is used")
+	fmt.Println("  --commit \tOptional mode to commit the restore: all of it at once (all by default), every table, or every batch of records. Except for all, an interrupted restore keeps what it committed and can be resumed")
+	fmt.Println("  --resume \tOptional flag to resume the restore interrupted in the database from its last commit. It is committed as the interrupted restore was")
 }
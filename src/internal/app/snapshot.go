@@ -0,0 +1,103 @@
+package app
+
+import (
+	"flag"
+	"fmt"
+	"historydb/src/internal/handlers"
+	"historydb/src/internal/services/backup/binary"
+	"historydb/src/internal/usecases"
+	"os"
+	"path"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+)
+
+var supportedSnapshotActions = map[string]bool{"delete": true}
+
+// SnapshotApp is the main execution for snapshot mode in the app
+func SnapshotApp(args []string) {
+	if len(args) < 1 {
+		printSnapshotHelp()
+		return
+	}
+
+	snapshotFlags := flag.NewFlagSet("snapshot", flag.ExitOnError)
+	snapshotFlags.Usage = printSnapshotHelp
+
+	action := args[0]
+	backupPath := snapshotFlags.String("path", "", "Path where the backup is located")
+	keyFile := snapshotFlags.String("keyFile", "", "File with the passphrase of the backup")
+
+	// The snapshot id can be given either before or after the options
+	snapshotId := ""
+	if len(args) > 1 && !strings.HasPrefix(args[1], "-") {
+		snapshotId = args[1]
+		snapshotFlags.Parse(args[2:])
+	} else {
+		snapshotFlags.Parse(args[1:])
+		snapshotId = snapshotFlags.Arg(0)
+	}
+
+	if _, ok := supportedSnapshotActions[action]; !ok {
+		fmt.Printf("The action '%s' is not supported in the snapshot app.\n", action)
+		return
+	}
+	if snapshotId == "" {
+		fmt.Printf("It is required to provide the id of the snapshot to %s\n", action)
+		return
+	}
+	if *backupPath == "" {
+		fmt.Print("It is required to provide the argument --path\n")
+		return
+	}
+
+	passphrase, err := readPassphrase(*keyFile, passphraseEnv)
+	if err != nil {
+		return
+	}
+
+	storage, logPath, closeStorage, err := openBackupStorage(*backupPath)
+	if err != nil {
+		return
+	}
+	defer closeStorage()
+	loggerFile, err := os.OpenFile(path.Join(logPath, "backup.log"), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		fmt.Println("There is no backup located in the specified path")
+		return
+	}
+
+	logger := &logrus.Logger{
+		Out:       loggerFile,
+		Level:     logrus.InfoLevel,
+		Formatter: &logrus.TextFormatter{FullTimestamp: true},
+	}
+	logrus.SetLevel(logrus.InfoLevel)
+
+	// Unfinished snapshots are discarded, as their staged diffs may be taken from the objects being deleted
+	backupFactory := createBackupFactory(storage, binary.BinaryBackupOptions{Passphrase: passphrase})
+	unlock, ok := lockBackup(backupFactory, true, true, logger)
+	if !ok {
+		return
+	}
+	defer unlock()
+
+	snapshotUsecases := usecases.NewSnapshotUsecasesImpl(backupFactory, logger)
+
+	snapshotHandler := handlers.NewSnapshotHandler(snapshotUsecases)
+
+	switch action {
+	case "delete":
+		snapshotHandler.DeleteSnapshot(snapshotId)
+	}
+}
+
+func printSnapshotHelp() {
+	fmt.Println("Usage: historydb snapshot [action] [snapshot id] [options]")
+	fmt.Println("Actions:")
+	fmt.Println("  delete \tIt deletes a snapshot from the backup, keeping every object the rest of snapshots need")
+	fmt.Println("Options:")
+	fmt.Println("  --path \tPath where the backup is located. It can be an s3://bucket/prefix or sftp://user@host/path URL")
+	fmt.Println("  --keyFile \tOptional file with the passphrase of an encrypted backup. If it is not provided, HISTORYDB_PASSPHRASE is used")
+}
@@ -1,15 +1,40 @@
 package app
 
 import (
+	"bytes"
+	"context"
 	"database/sql"
 	"errors"
 	"fmt"
+	"historydb/src/internal/entities"
+	"historydb/src/internal/services"
 	backup_services "historydb/src/internal/services/backup"
 	"historydb/src/internal/services/backup/binary"
 	database_services "historydb/src/internal/services/database"
+	"historydb/src/internal/services/database/mongo"
+	"historydb/src/internal/services/database/mysql"
 	"historydb/src/internal/services/database/psql"
+	"historydb/src/internal/services/database/sqlite"
+	storage_services "historydb/src/internal/services/storage"
+	"historydb/src/internal/services/storage/local"
+	"historydb/src/internal/services/storage/s3"
+	"historydb/src/internal/services/storage/sftp"
+	"net"
+	"net/url"
+	"os"
+	"os/user"
+	"path/filepath"
+	"strings"
 
-	_ "github.com/lib/pq"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/config"
+	aws_s3 "github.com/aws/aws-sdk-go-v2/service/s3"
+	_ "github.com/go-sql-driver/mysql"
+	_ "github.com/jackc/pgx/v5/stdlib"
+	"github.com/sirupsen/logrus"
+	mongo_driver "go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+	_ "modernc.org/sqlite"
 )
 
 var (
@@ -18,11 +43,83 @@ var (
 )
 
 var supportedBackupActions = map[string]bool{"create": true, "snapshot": true}
-var supportedEngines = map[string]string{"postgres": "postgres", "postgresql": "postgres"}
+var supportedCompressions = map[string]entities.CompressionCodec{"none": entities.NoCompression, "zstd": entities.ZstdCompression}
+var supportedEngines = map[string]string{"postgres": "postgres", "postgresql": "postgres", "mysql": "mysql", "mariadb": "mysql", "sqlite": "sqlite", "sqlite3": "sqlite", "mongodb": "mongodb", "mongodb+srv": "mongodb"}
+
+// passphraseEnv and newPassphraseEnv are the environment variables read when no key file is provided
+const (
+	passphraseEnv    = "HISTORYDB_PASSPHRASE"
+	newPassphraseEnv = "HISTORYDB_NEW_PASSPHRASE"
+)
+
+// sshKeyEnv and sshKnownHostsEnv are the environment variables which replace the default files used to reach SFTP hosts
+const (
+	sshKeyEnv        = "HISTORYDB_SSH_KEY"
+	sshKnownHostsEnv = "HISTORYDB_SSH_KNOWN_HOSTS"
+)
+
+// openDatabaseFactory connects to the database from the connection string provided by the user and creates the
+// implementation of the DatabaseFactory needed for its engine. The returned function closes the connection.
+func openDatabaseFactory(engine string, connString string) (database_services.DatabaseFactory, func(), error) {
+	if engine == "mongodb" {
+		db, err := openMongoConnection(connString)
+		if err != nil {
+			return nil, nil, err
+		}
+		return mongo.NewMongoDatabaseFactory(db), func() { db.Client().Disconnect(context.Background()) }, nil
+	}
+
+	db, err := openDBConnection(engine, connString)
+	if err != nil {
+		return nil, nil, err
+	}
+	return createDatabaseFactory(engine, db), func() { db.Close() }, nil
+}
+
+// openMongoConnection connects to the MongoDB server and returns the database from the connection string provided by the user
+func openMongoConnection(connString string) (*mongo_driver.Database, error) {
+	dbName, err := mongo.ParseDatabaseName(connString)
+	if err != nil {
+		fmt.Printf("Invalid MongoDB connection string.\n")
+		return nil, err
+	}
+
+	client, err := mongo_driver.Connect(options.Client().ApplyURI(connString))
+	if err != nil {
+		fmt.Printf("Impossible to open DB connection.\n")
+		return nil, err
+	}
+	if err := client.Ping(context.Background(), nil); err != nil {
+		client.Disconnect(context.Background())
+		fmt.Printf("Could not connect to DB, check tls and credentials.\n")
+		return nil, err
+	}
+
+	return client.Database(dbName), nil
+}
 
 // openDBConnection opens a sql.DB connection from the DSN provided bny the user
 func openDBConnection(engine string, dsn string) (*sql.DB, error) {
-	db, err := sql.Open(engine, dsn)
+	driverName := engine
+	switch engine {
+	case "postgres":
+		// PostgreSQL connections are opened with pgx, as records are transferred with its COPY protocol support
+		driverName = "pgx"
+	case "mysql":
+		var err error
+		if dsn, err = mysql.ParseConnString(dsn); err != nil {
+			fmt.Printf("Invalid MySQL connection string.\n")
+			return nil, err
+		}
+	case "sqlite":
+		var err error
+		if dsn, err = sqlite.ParseConnString(dsn); err != nil {
+			fmt.Printf("Invalid SQLite connection string.\n")
+			return nil, err
+		}
+	}
+
+	db, err := sql.Open(driverName, dsn)
 	if err != nil {
 		fmt.Printf("Impossible to open DB connection.\n")
 		return nil, err
@@ -40,12 +137,261 @@ func createDatabaseFactory(engine string, db *sql.DB) database_services.Database
 	switch engine {
 	case "postgres":
 		return psql.NewPSQLDatabaseFactory(db)
+	case "mysql":
+		return mysql.NewMySQLDatabaseFactory(db)
+	case "sqlite":
+		return sqlite.NewSQLiteDatabaseFactory(db)
 	default:
 		return nil
 	}
 }
 
-// createBackupFactory creates the implementation of the BackupFactory needed
-func createBackupFactory(basePath string) backup_services.BackupFactory {
-	return binary.NewBinaryBackupFactory(basePath)
+// createBackupFactory creates the implementation of the BackupFactory needed. The compression and encryption options are
+// only used by new backups
+func createBackupFactory(storage storage_services.Storage, options binary.BinaryBackupOptions) backup_services.BackupFactory {
+	return binary.NewBinaryBackupFactory(storage, options)
+}
+
+// lockBackup locks the backup, exclusively for the commands which change it, and recovers its interrupted snapshots once it
+// is locked exclusively, so no snapshot being taken by another process is discarded. The snapshots which can be resumed
+// are kept unless discardResumable is true. The commands which only read the backup leave the interrupted snapshots as
+// they are and just report them, as other readers may hold the backup meanwhile. The returned function releases the lock.
+func lockBackup(backupFactory backup_services.BackupFactory, exclusive, discardResumable bool, logger *logrus.Logger) (func(), bool) {
+	backupWriter := backupFactory.CreateWriter()
+	staleLocks, err := backupWriter.LockBackup(exclusive)
+	if err != nil {
+		logger.Errorf("could not lock the backup: %v", err)
+		if errors.Is(err, services.ErrBackupLocked) {
+			fmt.Printf("The backup could not be locked, %v. Try again once that process finishes.\n", err)
+		} else {
+			fmt.Println("The backup could not be locked")
+		}
+		return nil, false
+	}
+	for _, lock := range staleLocks {
+		logger.Warnf("the stale %s was removed", lock)
+	}
+
+	unlock := func() {
+		if err := backupWriter.UnlockBackup(); err != nil {
+			logger.Errorf("could not unlock the backup: %v", err)
+		}
+	}
+	if !exclusive {
+		reportPendingSnapshots(backupWriter, logger)
+		return unlock, true
+	}
+	if ok := recoverBackupSnapshots(backupWriter, discardResumable, logger); !ok {
+		unlock()
+		return nil, false
+	}
+	return unlock, true
+}
+
+// reportPendingSnapshots warns about the snapshots whose commit was interrupted and the unfinished snapshots of the backup,
+// which are not listed by it until the next command which changes the backup recovers them.
+func reportPendingSnapshots(backupWriter backup_services.BackupWriter, logger *logrus.Logger) {
+	committing, unfinished, err := backupWriter.PendingSnapshots()
+	if err != nil {
+		logger.Warnf("could not check the interrupted snapshots of the backup: %v", err)
+		return
+	}
+
+	for _, snapshotId := range committing {
+		logger.Warnf("the commit of snapshot %s was interrupted, it is completed by the next command which changes the backup", snapshotId)
+	}
+	for _, snapshotId := range unfinished {
+		logger.Warnf("snapshot %s was left unfinished, it is not part of the backup", snapshotId)
+	}
+	if len(committing) > 0 {
+		fmt.Printf("The commit of %d snapshot(s) was interrupted, they are not listed until the next command which changes the backup completes them\n", len(committing))
+	}
+}
+
+// recoverBackupSnapshots completes the snapshots whose commit was interrupted and discards the snapshots left unfinished
+// by an interrupted run, so every command starts from a consistent backup. Unfinished snapshots which can be resumed are
+// only discarded if discardResumable is true.
+func recoverBackupSnapshots(backupWriter backup_services.BackupWriter, discardResumable bool, logger *logrus.Logger) bool {
+	committed, discarded, err := backupWriter.RecoverSnapshots(discardResumable)
+	if err != nil {
+		logger.Errorf("could not recover the interrupted snapshots of the backup: %v", err)
+		fmt.Println("The interrupted snapshots of the backup could not be recovered")
+		return false
+	}
+
+	for _, snapshotId := range committed {
+		logger.Infof("interrupted commit of snapshot %s was completed", snapshotId)
+	}
+	for _, snapshotId := range discarded {
+		logger.Infof("unfinished snapshot %s was discarded", snapshotId)
+	}
+	return true
+}
+
+// openBackupStorage creates the storage of the backup located in basePath, which is an S3-compatible bucket for paths like
+// s3://bucket/prefix, a directory of a remote host for paths like sftp://user@host/path and a local directory otherwise.
+// It also returns the local directory where the log of the backup is written, which is the backup directory itself for
+// local backups, and a function which closes the connection to the storage.
+func openBackupStorage(basePath string) (storage_services.Storage, string, func(), error) {
+	parsedPath, err := url.Parse(basePath)
+	if err != nil {
+		return local.NewLocalStorage(basePath), basePath, func() {}, nil
+	}
+
+	switch parsedPath.Scheme {
+	case "s3":
+		return openS3Storage(basePath, parsedPath)
+	case "sftp":
+		return openSFTPStorage(basePath, parsedPath)
+	default:
+		return local.NewLocalStorage(basePath), basePath, func() {}, nil
+	}
+}
+
+// openS3Storage creates the storage of a backup kept in an S3-compatible bucket
+func openS3Storage(basePath string, parsedPath *url.URL) (storage_services.Storage, string, func(), error) {
+	bucket := parsedPath.Host
+	prefix := strings.Trim(parsedPath.Path, "/")
+	if bucket == "" {
+		fmt.Printf("Invalid S3 path '%s', it must be s3://bucket/prefix\n", basePath)
+		return nil, "", nil, fmt.Errorf("invalid s3 path")
+	}
+
+	// The credentials, region and endpoint are read from the environment and the shared files of the AWS CLI
+	cfg, err := config.LoadDefaultConfig(context.Background())
+	if err != nil {
+		fmt.Printf("Could not load the AWS configuration.\n")
+		return nil, "", nil, err
+	}
+	if cfg.Region == "" {
+		cfg.Region = "us-east-1"
+	}
+	// Most S3-compatible stores do not support the checksums the SDK sends by default
+	cfg.RequestChecksumCalculation = aws.RequestChecksumCalculationWhenRequired
+	cfg.ResponseChecksumValidation = aws.ResponseChecksumValidationWhenRequired
+	client := aws_s3.NewFromConfig(cfg, func(o *aws_s3.Options) {
+		// S3-compatible stores reached through a custom endpoint rarely support virtual hosted buckets
+		o.UsePathStyle = o.BaseEndpoint != nil
+	})
+
+	logPath, err := remoteLogPath("s3", bucket, prefix)
+	if err != nil {
+		return nil, "", nil, err
+	}
+	return s3.NewS3Storage(client, bucket, prefix), logPath, func() {}, nil
+}
+
+// openSFTPStorage connects to the host of a backup kept over SFTP. Paths are absolute, unless they start with /~/ which
+// makes them relative to the home directory of the user.
+func openSFTPStorage(basePath string, parsedPath *url.URL) (storage_services.Storage, string, func(), error) {
+	rootPath := parsedPath.Path
+	if relativePath, ok := strings.CutPrefix(rootPath, "/~/"); ok {
+		rootPath = relativePath
+	}
+	if parsedPath.Hostname() == "" || strings.Trim(rootPath, "/") == "" {
+		fmt.Printf("Invalid SFTP path '%s', it must be sftp://user@host/path\n", basePath)
+		return nil, "", nil, fmt.Errorf("invalid sftp path")
+	}
+
+	username := parsedPath.User.Username()
+	if username == "" {
+		currentUser, err := user.Current()
+		if err != nil {
+			fmt.Printf("The user of the SFTP host must be provided in the path, as sftp://user@host/path\n")
+			return nil, "", nil, err
+		}
+		username = currentUser.Username
+	}
+	port := parsedPath.Port()
+	if port == "" {
+		port = "22"
+	}
+
+	options, err := sshOptions()
+	if err != nil {
+		return nil, "", nil, err
+	}
+	client, closeClient, err := sftp.Dial(username, net.JoinHostPort(parsedPath.Hostname(), port), options)
+	if err != nil {
+		fmt.Printf("Could not connect to the SFTP host '%s': %v\n", parsedPath.Host, err)
+		return nil, "", nil, err
+	}
+
+	logPath, err := remoteLogPath("sftp", parsedPath.Hostname(), rootPath)
+	if err != nil {
+		closeClient()
+		return nil, "", nil, err
+	}
+	return sftp.NewSFTPStorage(client, parsedPath.Hostname(), rootPath), logPath, closeClient, nil
+}
+
+// sshOptions reads how SFTP hosts are authenticated from the environment. The keys of the SSH agent in SSH_AUTH_SOCK are
+// tried first, then the key file in HISTORYDB_SSH_KEY or the default keys of the user. Hosts are checked against the
+// file in HISTORYDB_SSH_KNOWN_HOSTS, or ~/.ssh/known_hosts.
+func sshOptions() (sftp.SSHOptions, error) {
+	options := sftp.SSHOptions{AgentSocket: os.Getenv("SSH_AUTH_SOCK"), KeyFiles: []string{}}
+
+	// Without a home directory only the files set in the environment are used
+	homeDir, _ := os.UserHomeDir()
+
+	if keyFile, ok := os.LookupEnv(sshKeyEnv); ok {
+		options.KeyFiles = append(options.KeyFiles, keyFile)
+	} else if homeDir != "" {
+		for _, name := range []string{"id_ed25519", "id_ecdsa", "id_rsa"} {
+			if keyFile := filepath.Join(homeDir, ".ssh", name); fileExists(keyFile) {
+				options.KeyFiles = append(options.KeyFiles, keyFile)
+			}
+		}
+	}
+
+	if knownHostsFile, ok := os.LookupEnv(sshKnownHostsEnv); ok {
+		options.KnownHostsFile = knownHostsFile
+	} else if homeDir != "" {
+		options.KnownHostsFile = filepath.Join(homeDir, ".ssh", "known_hosts")
+	}
+	if !fileExists(options.KnownHostsFile) {
+		fmt.Printf("The SFTP host can not be verified without a known hosts file, add it to ~/.ssh/known_hosts or set %s\n", sshKnownHostsEnv)
+		return sftp.SSHOptions{}, fmt.Errorf("known hosts file not found")
+	}
+
+	return options, nil
+}
+
+// remoteLogPath creates the directory of the user cache where the log of a remote backup is written
+func remoteLogPath(scheme, host, path string) (string, error) {
+	cacheDir, err := os.UserCacheDir()
+	if err != nil {
+		cacheDir = os.TempDir()
+	}
+	logPath := filepath.Join(cacheDir, "historydb", scheme, host, filepath.FromSlash(strings.Trim(path, "/")))
+	if err := os.MkdirAll(logPath, 0755); err != nil {
+		return "", err
+	}
+	return logPath, nil
+}
+
+func fileExists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
+
+// readPassphrase reads the passphrase of a backup from the key file provided by the user, or from the environment variable
+// envName if no key file was provided. It returns nil if there is no passphrase.
+func readPassphrase(keyFile, envName string) ([]byte, error) {
+	if keyFile != "" {
+		passphrase, err := os.ReadFile(keyFile)
+		if err != nil {
+			fmt.Printf("Could not read the key file '%s'\n", keyFile)
+			return nil, err
+		}
+		// Key files written by editors or echo end with a newline which is not part of the passphrase
+		passphrase = bytes.TrimSuffix(passphrase, []byte("\n"))
+		passphrase = bytes.TrimSuffix(passphrase, []byte("\r"))
+		return passphrase, nil
+	}
+
+	if passphrase, ok := os.LookupEnv(envName); ok {
+		return []byte(passphrase), nil
+	}
+	return nil, nil
 }
@@ -0,0 +1,86 @@
+package app
+
+import (
+	"flag"
+	"fmt"
+	"historydb/src/internal/handlers"
+	"historydb/src/internal/services/backup/binary"
+	"historydb/src/internal/usecases"
+	"os"
+	"path"
+
+	"github.com/sirupsen/logrus"
+)
+
+var supportedVerifyLevels = map[string]bool{"quick": true, "deep": true}
+
+// VerifyApp is the main execution for verify mode in the app. It exits with a non-zero status if the backup is not intact.
+func VerifyApp(args []string) {
+	if ok := verifyBackup(args); !ok {
+		os.Exit(1)
+	}
+}
+
+// verifyBackup verifies the backup, returning whether it is intact, so the backup is unlocked before the app exits.
+func verifyBackup(args []string) bool {
+	verifyFlags := flag.NewFlagSet("verify", flag.ExitOnError)
+	verifyFlags.Usage = printVerifyHelp
+
+	backupPath := verifyFlags.String("path", "", "Path where the backup is located")
+	level := verifyFlags.String("level", "quick", "Level of the verification: quick or deep")
+	keyFile := verifyFlags.String("keyFile", "", "File with the passphrase of the backup")
+	verifyFlags.Parse(args)
+
+	if *backupPath == "" {
+		fmt.Print("It is required to provide the argument --path\n")
+		return false
+	}
+	if _, ok := supportedVerifyLevels[*level]; !ok {
+		fmt.Printf("The verification level '%s' is not supported, it must be quick or deep.\n", *level)
+		return false
+	}
+
+	passphrase, err := readPassphrase(*keyFile, passphraseEnv)
+	if err != nil {
+		return false
+	}
+
+	storage, logPath, closeStorage, err := openBackupStorage(*backupPath)
+	if err != nil {
+		return false
+	}
+	defer closeStorage()
+	loggerFile, err := os.OpenFile(path.Join(logPath, "backup.log"), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		fmt.Println("There is no backup located in the specified path")
+		return false
+	}
+
+	logger := &logrus.Logger{
+		Out:       loggerFile,
+		Level:     logrus.InfoLevel,
+		Formatter: &logrus.TextFormatter{FullTimestamp: true},
+	}
+	logrus.SetLevel(logrus.InfoLevel)
+
+	backupFactory := createBackupFactory(storage, binary.BinaryBackupOptions{Passphrase: passphrase})
+	unlock, ok := lockBackup(backupFactory, false, false, logger)
+	if !ok {
+		return false
+	}
+	defer unlock()
+
+	verifyUsecases := usecases.NewVerifyUsecasesImpl(backupFactory, logger)
+
+	verifyHandler := handlers.NewVerifyHandler(verifyUsecases)
+
+	return verifyHandler.VerifyBackup(*level == "deep")
+}
+
+func printVerifyHelp() {
+	fmt.Println("Usage: historydb verify [options]")
+	fmt.Println("Options:")
+	fmt.Println("  --path \tPath where the backup is located. It can be an s3://bucket/prefix or sftp://user@host/path URL")
+	fmt.Println("  --level \tOptional level of the verification. quick (by default) checks the integrity hash of every file, while deep also rebuilds every object of every snapshot and looks for missing and orphaned objects")
+	fmt.Println("  --keyFile \tOptional file with the passphrase of an encrypted backup. If it is not provided, HISTORYDB_PASSPHRASE is used")
+}
@@ -0,0 +1,199 @@
+package entities
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"historydb/src/internal/utils/decode"
+	"historydb/src/internal/utils/encode"
+	"time"
+)
+
+// BackupCheckpoint defines the progress of a snapshot, which is saved into its transaction so the snapshot can be resumed
+// if it is interrupted
+//
+// SnapshotId -> The identifier of the snapshot in progress
+// Timestamp -> The timestamp when the snapshot began
+// Message -> The message of the snapshot
+// PrevSnapshotId -> The last snapshot of the backup when the snapshot began, empty if it is the first one
+// Schemas -> The progress of the records of every schema whose records began to be saved
+type BackupCheckpoint struct {
+	SnapshotId     string                           `json:"snapshotId"`
+	Timestamp      time.Time                        `json:"timestamp"`
+	Message        string                           `json:"message"`
+	PrevSnapshotId string                           `json:"prevSnapshotId"`
+	Schemas        map[string]*BackupSchemaProgress `json:"schemas"`
+}
+
+// BackupSchemaProgress defines how many records of a schema are saved in a snapshot in progress
+//
+// SchemaHash -> The hash of the schema definition its records were read with
+// Complete -> Whether every record of the schema is saved
+// Data -> The batches saved so far, which are all the batches of the schema once it is complete. It is nil if the schema has no records
+// SavedRecords -> The number of records in the saved batches
+// Cursor -> The encoded DB cursor after the last saved batch, so the next batch is read from it
+// CursorOffset -> The number of records read from Cursor that are already in the saved batches, as batches split by content or by key may end in the middle of a DB chunk
+// ComparedBatches -> The number of batches of the last snapshot already compared with the records, as a snapshot by key may drop or add batches
+type BackupSchemaProgress struct {
+	SchemaHash      string                    `json:"schemaHash"`
+	Complete        bool                      `json:"complete"`
+	Data            *BackupSnapshotSchemaData `json:"data"`
+	SavedRecords    int64                     `json:"savedRecords"`
+	Cursor          []byte                    `json:"cursor"`
+	CursorOffset    int64                     `json:"cursorOffset"`
+	ComparedBatches int64                     `json:"comparedBatches"`
+}
+
+func (checkpoint *BackupCheckpoint) EncodeToBytes() []byte {
+	var buf bytes.Buffer
+
+	var flags byte
+	if len(checkpoint.Schemas) > 0 {
+		flags |= 1 << 0
+	}
+
+	buf.WriteByte(flags)
+	encode.EncodeString(&buf, &checkpoint.SnapshotId)
+	encode.EncodeTime(&buf, &checkpoint.Timestamp)
+	encode.EncodeString(&buf, &checkpoint.Message)
+	encode.EncodeString(&buf, &checkpoint.PrevSnapshotId)
+	encode.EncodeStructMap(&buf, checkpoint.Schemas)
+
+	integrityHash := sha256.Sum256(buf.Bytes())
+	return append(integrityHash[:], buf.Bytes()...)
+}
+
+// DecodeFromBytes decodes a checkpoint read without its integrity hash.
+func (checkpoint *BackupCheckpoint) DecodeFromBytes(data []byte) error {
+	buf := bytes.NewBuffer(data)
+
+	flags, err := buf.ReadByte()
+	if err != nil {
+		return err
+	}
+	snapshotId, err := decode.DecodeString(buf)
+	if err != nil {
+		return err
+	}
+	timestamp, err := decode.DecodeTime(buf)
+	if err != nil {
+		return err
+	}
+	message, err := decode.DecodeString(buf)
+	if err != nil {
+		return err
+	}
+	prevSnapshotId, err := decode.DecodeString(buf)
+	if err != nil {
+		return err
+	}
+	schemas := make(map[string]*BackupSchemaProgress)
+	if flags&(1<<0) != 0 {
+		if schemas, err = decode.DecodeStructMap[*BackupSchemaProgress](buf); err != nil {
+			return err
+		}
+	}
+
+	checkpoint.SnapshotId = *snapshotId
+	checkpoint.Timestamp = *timestamp
+	checkpoint.Message = *message
+	checkpoint.PrevSnapshotId = *prevSnapshotId
+	checkpoint.Schemas = schemas
+	return nil
+}
+
+func (progress *BackupSchemaProgress) EncodeToBytes() []byte {
+	var buf bytes.Buffer
+
+	var flags byte
+	if progress.Complete {
+		flags |= 1 << 0
+	}
+	if progress.Data != nil {
+		flags |= 1 << 1
+	}
+	if progress.Cursor != nil {
+		flags |= 1 << 2
+	}
+	if progress.CursorOffset != 0 {
+		flags |= 1 << 3
+	}
+	if progress.ComparedBatches != 0 {
+		flags |= 1 << 4
+	}
+
+	buf.WriteByte(flags)
+	encode.EncodeString(&buf, &progress.SchemaHash)
+	if progress.Data != nil {
+		encode.EncodeBytes(&buf, progress.Data.EncodeToBytes())
+	}
+	encode.EncodeInt(&buf, &progress.SavedRecords)
+	if progress.Cursor != nil {
+		encode.EncodeBytes(&buf, progress.Cursor)
+	}
+	if progress.CursorOffset != 0 {
+		encode.EncodeInt(&buf, &progress.CursorOffset)
+	}
+	if progress.ComparedBatches != 0 {
+		encode.EncodeInt(&buf, &progress.ComparedBatches)
+	}
+
+	return buf.Bytes()
+}
+
+func (progress *BackupSchemaProgress) DecodeFromBytes(data []byte) (*BackupSchemaProgress, error) {
+	buf := bytes.NewBuffer(data)
+
+	flags, err := buf.ReadByte()
+	if err != nil {
+		return nil, err
+	}
+	schemaHash, err := decode.DecodeString(buf)
+	if err != nil {
+		return nil, err
+	}
+	var schemaData *BackupSnapshotSchemaData
+	if flags&(1<<1) != 0 {
+		encodedData, err := decode.DecodeBytes(buf)
+		if err != nil {
+			return nil, err
+		}
+		if schemaData, err = schemaData.DecodeFromBytes(encodedData); err != nil {
+			return nil, err
+		}
+	}
+	savedRecords, err := decode.DecodeInt(buf)
+	if err != nil {
+		return nil, err
+	}
+	var cursor []byte
+	if flags&(1<<2) != 0 {
+		if cursor, err = decode.DecodeBytes(buf); err != nil {
+			return nil, err
+		}
+	}
+	var cursorOffset, comparedBatches int64
+	if flags&(1<<3) != 0 {
+		value, err := decode.DecodeInt(buf)
+		if err != nil {
+			return nil, err
+		}
+		cursorOffset = *value
+	}
+	if flags&(1<<4) != 0 {
+		value, err := decode.DecodeInt(buf)
+		if err != nil {
+			return nil, err
+		}
+		comparedBatches = *value
+	}
+
+	return &BackupSchemaProgress{
+		SchemaHash:      *schemaHash,
+		Complete:        flags&(1<<0) != 0,
+		Data:            schemaData,
+		SavedRecords:    *savedRecords,
+		Cursor:          cursor,
+		CursorOffset:    cursorOffset,
+		ComparedBatches: comparedBatches,
+	}, nil
+}
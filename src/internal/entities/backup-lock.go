@@ -0,0 +1,70 @@
+package entities
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"fmt"
+	"historydb/src/internal/utils/decode"
+	"historydb/src/internal/utils/encode"
+	"time"
+)
+
+// BackupLock defines the owner of a lock of the backup
+//
+// Exclusive -> Whether the owner changes the backup, so no other process can use it meanwhile
+// PID -> The identifier of the process of the owner
+// Host -> The name of the host the owner runs in
+// Time -> The last time the lock was refreshed by its owner
+type BackupLock struct {
+	Exclusive bool      `json:"exclusive"`
+	PID       int64     `json:"pid"`
+	Host      string    `json:"host"`
+	Time      time.Time `json:"time"`
+}
+
+func (lock *BackupLock) EncodeToBytes() []byte {
+	var buf bytes.Buffer
+	encode.EncodeBool(&buf, &lock.Exclusive)
+	encode.EncodeInt(&buf, &lock.PID)
+	encode.EncodeString(&buf, &lock.Host)
+	encode.EncodeTime(&buf, &lock.Time)
+
+	integrityHash := sha256.Sum256(buf.Bytes())
+	return append(integrityHash[:], buf.Bytes()...)
+}
+
+// DecodeFromBytes decodes a lock read without its integrity hash.
+func (lock *BackupLock) DecodeFromBytes(data []byte) error {
+	buf := bytes.NewBuffer(data)
+
+	exclusive, err := decode.DecodeBool(buf)
+	if err != nil {
+		return err
+	}
+	pid, err := decode.DecodeInt(buf)
+	if err != nil {
+		return err
+	}
+	host, err := decode.DecodeString(buf)
+	if err != nil {
+		return err
+	}
+	lockTime, err := decode.DecodeTime(buf)
+	if err != nil {
+		return err
+	}
+
+	lock.Exclusive = *exclusive
+	lock.PID = *pid
+	lock.Host = *host
+	lock.Time = *lockTime
+	return nil
+}
+
+func (lock BackupLock) String() string {
+	mode := "shared"
+	if lock.Exclusive {
+		mode = "exclusive"
+	}
+	return fmt.Sprintf("%s lock of process %d on host %s, refreshed at %s", mode, lock.PID, lock.Host, lock.Time.Local().Format(time.RFC3339))
+}
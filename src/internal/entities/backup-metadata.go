@@ -5,19 +5,73 @@ import (
 	"crypto/sha256"
 	"historydb/src/internal/utils/decode"
 	"historydb/src/internal/utils/encode"
+	"historydb/src/internal/utils/pointers"
 	"time"
 )
 
-var BACKUPMETADATA_VERSION int64 = 1
+// BACKUPMETADATA_VERSION is the newest backup format this version can read and the one it writes.
+//
+// 1 -> Initial format
+// 2 -> Record values are written with the typed value codec (bytes, decimals, nanosecond timestamps, arrays...)
+// 3 -> Record chunks, schemas and routines can be compressed with the codec saved in the metadata
+// 4 -> Every backup file can be encrypted with the data key wrapped in the metadata
+// 5 -> Record chunks can be kept once in a chunk store shared by every batch, which only lists the hashes of its chunks
+// 6 -> The objects of every snapshot are saved into pack files, and found through the indexes of the packs
+var BACKUPMETADATA_VERSION int64 = 6
+
+// CompressionCodec defines how the record chunks, schemas and routines of a backup are compressed.
+type CompressionCodec string
+
+const (
+	// NoCompression saves the payloads as they are encoded. Backups saved before compression was supported use it.
+	NoCompression CompressionCodec = "none"
+	// ZstdCompression compresses every payload on its own with zstd, so a chunk can still be read without the rest of its batch.
+	ZstdCompression CompressionCodec = "zstd"
+)
 
 // BackupMetadata defines a struct which contains all the basic data required by the app
 //
 // DatabaseEngine -> The DB Engine used in the backup
 // Snapshots -> List of all snapshots taken in the backup
+// Compression -> The codec used to compress the backup payloads
+// CompressionLevel -> The level of the codec used to compress the backup payloads
+// Encryption -> The key derivation parameters and wrapped data key of an encrypted backup, nil if it is not encrypted
+// SealedSnapshots -> The snapshots of an encrypted backup, sealed with its data key. They are saved instead of Snapshots
+// ChunkStore -> Whether the record chunks are kept in the chunk store, so a chunk found in several batches is only saved once
+// MaxChainDepth -> The maximum number of diffs applied to rebuild an object, after which a full copy is saved again. 0 if the
+// diff chains are not bounded
+// ReverseDeltas -> Whether every snapshot is saved in full, and the record chunks of the snapshot before it are saved again as
+// diffs of its chunks
 type BackupMetadata struct {
-	Version        int64                    `json:"version"`
-	DatabaseEngine string                   `json:"databaseEngine"`
-	Snapshots      []BackupMetadataSnapshot `json:"snapshots"`
+	Version          int64                    `json:"version"`
+	DatabaseEngine   string                   `json:"databaseEngine"`
+	Snapshots        []BackupMetadataSnapshot `json:"snapshots"`
+	Compression      CompressionCodec         `json:"compression"`
+	CompressionLevel int64                    `json:"compressionLevel"`
+	Encryption       *BackupEncryption        `json:"encryption"`
+	SealedSnapshots  []byte                   `json:"-"`
+	ChunkStore       bool                     `json:"chunkStore"`
+	MaxChainDepth    int64                    `json:"maxChainDepth"`
+	ReverseDeltas    bool                     `json:"reverseDeltas"`
+}
+
+// BackupEncryption defines how the data key of an encrypted backup is obtained from its passphrase
+//
+// Kdf -> The key derivation function used to derive the key encryption key from the passphrase
+// Salt -> The random salt of the key derivation
+// Time -> The number of iterations of the key derivation
+// Memory -> The memory in KiB used by the key derivation
+// Threads -> The number of threads used by the key derivation
+// KeyCheck -> A value derived from the key encryption key, used to tell a wrong passphrase from a corrupted data key
+// WrappedKey -> The data key of the backup, sealed with the key encryption key
+type BackupEncryption struct {
+	Kdf        string `json:"kdf"`
+	Salt       []byte `json:"salt"`
+	Time       int64  `json:"time"`
+	Memory     int64  `json:"memory"`
+	Threads    int64  `json:"threads"`
+	KeyCheck   []byte `json:"keyCheck"`
+	WrappedKey []byte `json:"wrappedKey"`
 }
 
 func (metadata *BackupMetadata) EncodeToBytes() []byte {
@@ -36,18 +90,75 @@ func (metadata *BackupMetadata) encodeData() []byte {
 	var buf bytes.Buffer
 
 	var flags byte
-	if len(metadata.Snapshots) > 0 {
+	if len(metadata.Snapshots) > 0 && metadata.Encryption == nil {
 		flags |= 1 << 0
 	}
+	if metadata.Compression != "" {
+		flags |= 1 << 1
+	}
+	if metadata.Encryption != nil {
+		flags |= 1 << 2
+	}
+	if metadata.ChunkStore {
+		flags |= 1 << 3
+	}
+	if metadata.MaxChainDepth > 0 {
+		flags |= 1 << 4
+	}
+	if metadata.ReverseDeltas {
+		flags |= 1 << 5
+	}
 
 	buf.WriteByte(flags)
 	encode.EncodeInt(&buf, &BACKUPMETADATA_VERSION)
 	encode.EncodeString(&buf, &metadata.DatabaseEngine)
-	encode.EncodeSlice(&buf, metadata.Snapshots)
+	if flags&(1<<0) != 0 {
+		encode.EncodeSlice(&buf, metadata.Snapshots)
+	}
+	if flags&(1<<1) != 0 {
+		encode.EncodeString(&buf, pointers.Ptr(string(metadata.Compression)))
+		encode.EncodeInt(&buf, &metadata.CompressionLevel)
+	}
+	if flags&(1<<2) != 0 {
+		// The snapshots of an encrypted backup are only saved sealed
+		encode.EncodeString(&buf, &metadata.Encryption.Kdf)
+		encode.EncodeBytes(&buf, metadata.Encryption.Salt)
+		encode.EncodeInt(&buf, &metadata.Encryption.Time)
+		encode.EncodeInt(&buf, &metadata.Encryption.Memory)
+		encode.EncodeInt(&buf, &metadata.Encryption.Threads)
+		encode.EncodeBytes(&buf, metadata.Encryption.KeyCheck)
+		encode.EncodeBytes(&buf, metadata.Encryption.WrappedKey)
+		encode.EncodeBytes(&buf, metadata.SealedSnapshots)
+	}
+	// The depth is saved last, so older versions, which do not bound the diff chains, can still read the backup
+	if flags&(1<<4) != 0 {
+		encode.EncodeInt(&buf, &metadata.MaxChainDepth)
+	}
+
+	return buf.Bytes()
+}
 
+// EncodeSnapshots encodes the snapshots of the metadata on their own, so they can be sealed in encrypted backups.
+func (metadata *BackupMetadata) EncodeSnapshots() []byte {
+	var buf bytes.Buffer
+	encode.EncodeSlice(&buf, metadata.Snapshots)
 	return buf.Bytes()
 }
 
+// DecodeSnapshots decodes the snapshots previously encoded with EncodeSnapshots into the metadata.
+func (metadata *BackupMetadata) DecodeSnapshots(data []byte) error {
+	snapshots, err := decode.DecodeSlice[*BackupMetadataSnapshot](bytes.NewBuffer(data))
+	if err != nil {
+		return err
+	}
+
+	metadata.Snapshots = make([]BackupMetadataSnapshot, 0, len(snapshots))
+	for _, v := range snapshots {
+		metadata.Snapshots = append(metadata.Snapshots, *v)
+	}
+	return nil
+}
+
 func (metadata *BackupMetadata) DecodeFromBytes(data []byte) error {
 	buf := bytes.NewBuffer(data)
 
@@ -75,13 +186,95 @@ func (metadata *BackupMetadata) DecodeFromBytes(data []byte) error {
 			snapshotSlice = append(snapshotSlice, *v)
 		}
 	}
+	// Backups saved before compression was supported do not have a codec
+	compression, compressionLevel := NoCompression, int64(0)
+	if flags&(1<<1) != 0 {
+		codec, err := decode.DecodeString(buf)
+		if err != nil {
+			return err
+		}
+		level, err := decode.DecodeInt(buf)
+		if err != nil {
+			return err
+		}
+		compression, compressionLevel = CompressionCodec(*codec), *level
+	}
+	var encryption *BackupEncryption
+	var sealedSnapshots []byte
+	if flags&(1<<2) != 0 {
+		encryption, sealedSnapshots, err = decodeEncryption(buf)
+		if err != nil {
+			return err
+		}
+	}
+	maxChainDepth := int64(0)
+	if flags&(1<<4) != 0 {
+		depth, err := decode.DecodeInt(buf)
+		if err != nil {
+			return err
+		}
+		maxChainDepth = *depth
+	}
 
 	metadata.Version = *version
 	metadata.DatabaseEngine = *engine
 	metadata.Snapshots = snapshotSlice
+	metadata.Compression = compression
+	metadata.CompressionLevel = compressionLevel
+	metadata.Encryption = encryption
+	metadata.SealedSnapshots = sealedSnapshots
+	// Backups saved before the chunk store was supported keep every chunk in the files of its batches
+	metadata.ChunkStore = flags&(1<<3) != 0
+	metadata.MaxChainDepth = maxChainDepth
+	metadata.ReverseDeltas = flags&(1<<5) != 0
 	return nil
 }
 
+func decodeEncryption(buf *bytes.Buffer) (*BackupEncryption, []byte, error) {
+	kdf, err := decode.DecodeString(buf)
+	if err != nil {
+		return nil, nil, err
+	}
+	salt, err := decode.DecodeBytes(buf)
+	if err != nil {
+		return nil, nil, err
+	}
+	iterations, err := decode.DecodeInt(buf)
+	if err != nil {
+		return nil, nil, err
+	}
+	memory, err := decode.DecodeInt(buf)
+	if err != nil {
+		return nil, nil, err
+	}
+	threads, err := decode.DecodeInt(buf)
+	if err != nil {
+		return nil, nil, err
+	}
+	keyCheck, err := decode.DecodeBytes(buf)
+	if err != nil {
+		return nil, nil, err
+	}
+	wrappedKey, err := decode.DecodeBytes(buf)
+	if err != nil {
+		return nil, nil, err
+	}
+	sealedSnapshots, err := decode.DecodeBytes(buf)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return &BackupEncryption{
+		Kdf:        *kdf,
+		Salt:       salt,
+		Time:       *iterations,
+		Memory:     *memory,
+		Threads:    *threads,
+		KeyCheck:   keyCheck,
+		WrappedKey: wrappedKey,
+	}, sealedSnapshots, nil
+}
+
 // BackupMetadataSnapshot defines the basic info of a struct saved into the BackupMetadata struct to recover the snapshots
 //
 // Timestamp -> The timestamp when the snapshot was taken
@@ -5,6 +5,7 @@ import (
 	"crypto/sha256"
 	"historydb/src/internal/utils/decode"
 	"historydb/src/internal/utils/encode"
+	"historydb/src/internal/utils/pointers"
 	"historydb/src/internal/utils/types"
 	"time"
 )
@@ -154,16 +155,29 @@ func (snapshot *BackupSnapshot) DecodeFromBytes(data []byte) error {
 	return nil
 }
 
+// ChunkingMode defines how the records of a schema are split into chunks.
+type ChunkingMode string
+
+const (
+	// FixedSizeChunking ends every chunk after ChunkSize records, in the order they are read from the DB.
+	FixedSizeChunking ChunkingMode = "FixedSize"
+	// ContentDefinedChunking ends the chunks and batches where a rolling hash of the records decides, so a change
+	// only moves the boundaries around it. ChunkSize is the average chunk length, and BatchSize is still the max-size.
+	ContentDefinedChunking ChunkingMode = "ContentDefined"
+)
+
 // BackupSnapshotSchemaData defines the info saved from each schema in a snapshot
 // that serves to rebuild the schema data.
 //
 // BatchSize -> The max-size for all batches used to save the schema data.
 // ChunkSize -> The max-size for all chunks used to save the schema data.
 // Data -> A string of paths that represents all the batch files needed to rebuild the schema data.
+// Chunking -> How the schema records were split into chunks. Snapshots saved before it existed use fixed-size chunks.
 type BackupSnapshotSchemaData struct {
-	BatchSize int64    `json:"batchSize"`
-	ChunkSize int64    `json:"chunkSize"`
-	Data      []string `json:"data"`
+	BatchSize int64        `json:"batchSize"`
+	ChunkSize int64        `json:"chunkSize"`
+	Data      []string     `json:"data"`
+	Chunking  ChunkingMode `json:"chunking"`
 }
 
 func (schemaData BackupSnapshotSchemaData) EncodeToBytes() []byte {
@@ -173,11 +187,17 @@ func (schemaData BackupSnapshotSchemaData) EncodeToBytes() []byte {
 	if len(schemaData.Data) > 0 {
 		flags |= 1 << 0
 	}
+	if schemaData.Chunking != "" {
+		flags |= 1 << 1
+	}
 
 	buf.WriteByte(flags)
 	encode.EncodeInt(&buf, &schemaData.BatchSize)
 	encode.EncodeInt(&buf, &schemaData.ChunkSize)
 	encode.EncodePrimitiveSlice(&buf, schemaData.Data)
+	if schemaData.Chunking != "" {
+		encode.EncodeString(&buf, pointers.Ptr(string(schemaData.Chunking)))
+	}
 
 	return buf.Bytes()
 }
@@ -205,9 +225,19 @@ func (schemaData *BackupSnapshotSchemaData) DecodeFromBytes(data []byte) (*Backu
 		}
 	}
 
+	chunking := FixedSizeChunking
+	if flags&(1<<1) != 0 {
+		chunkingMode, err := decode.DecodeString(buf)
+		if err != nil {
+			return nil, err
+		}
+		chunking = ChunkingMode(*chunkingMode)
+	}
+
 	return &BackupSnapshotSchemaData{
 		BatchSize: *batchSize,
 		ChunkSize: *chunkSize,
 		Data:      dataSlice,
+		Chunking:  chunking,
 	}, nil
 }
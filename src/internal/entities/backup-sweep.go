@@ -0,0 +1,12 @@
+package entities
+
+// BackupSweep defines the changes made to a backup to remove the files no snapshot needs any longer
+//
+// RebasedObjects -> The diff objects saved again as full objects, as the objects they were applied to were removed
+// DeletedFiles -> The files deleted from the backup, including the pack files whose kept objects were packed again
+// ReclaimedBytes -> The bytes of the deleted files, less the bytes of the objects packed again
+type BackupSweep struct {
+	RebasedObjects []string `json:"rebasedObjects"`
+	DeletedFiles   []string `json:"deletedFiles"`
+	ReclaimedBytes int64    `json:"reclaimedBytes"`
+}
@@ -0,0 +1,88 @@
+package entities
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// RestoreCommitMode defines how often a restore is committed into the DB.
+type RestoreCommitMode string
+
+const (
+	// RestoreCommitAll commits the whole restore at once, so a failed restore leaves the DB empty.
+	RestoreCommitAll RestoreCommitMode = "all"
+	// RestoreCommitTable commits every step of the restore, and the records of every schema once they are all restored.
+	RestoreCommitTable RestoreCommitMode = "table"
+	// RestoreCommitBatch commits every step of the restore, and the records of every schema after each batch.
+	RestoreCommitBatch RestoreCommitMode = "batch"
+)
+
+// Entries of a restore checkpoint, which is saved into the restored DB as a list of entries so every step saves its own
+// entry in the same transaction as the objects it restores.
+const (
+	RestoreSnapshotEntry    = "snapshot"
+	RestoreCommitEntry      = "commit"
+	RestoreSchemasEntry     = "schemas"
+	RestoreConstraintsEntry = "constraints"
+	restoreRecordsEntry     = "records:"
+	restoreRulesEntry       = "rules:"
+)
+
+// RestoreRecordsEntry returns the entry with the number of batches of a schema whose records are restored.
+func RestoreRecordsEntry(schemaName string) string {
+	return restoreRecordsEntry + schemaName
+}
+
+// RestoreRulesEntry returns the entry which marks that the rules of a schema are restored.
+func RestoreRulesEntry(schemaName string) string {
+	return restoreRulesEntry + schemaName
+}
+
+// RestoreCheckpoint defines the progress of a restore committed in several transactions, so it can be resumed if it is
+// interrupted
+//
+// SnapshotId -> The snapshot being restored
+// CommitMode -> How often the restore is committed
+// SchemasRestored -> Whether the schema dependencies and schemas are restored
+// RestoredBatches -> The number of batches whose records are restored, by schema. A schema is only listed once its first batch is committed
+// RestoredRules -> The schemas whose rules are restored
+// ConstraintsRestored -> Whether the foreign keys of every schema are restored
+type RestoreCheckpoint struct {
+	SnapshotId          string            `json:"snapshotId"`
+	CommitMode          RestoreCommitMode `json:"commitMode"`
+	SchemasRestored     bool              `json:"schemasRestored"`
+	RestoredBatches     map[string]int64  `json:"restoredBatches"`
+	RestoredRules       map[string]bool   `json:"restoredRules"`
+	ConstraintsRestored bool              `json:"constraintsRestored"`
+}
+
+// DecodeFromEntries decodes a checkpoint from the entries saved into the DB.
+func (checkpoint *RestoreCheckpoint) DecodeFromEntries(entries map[string]string) error {
+	checkpoint.SnapshotId = entries[RestoreSnapshotEntry]
+	checkpoint.CommitMode = RestoreCommitMode(entries[RestoreCommitEntry])
+	_, checkpoint.SchemasRestored = entries[RestoreSchemasEntry]
+	_, checkpoint.ConstraintsRestored = entries[RestoreConstraintsEntry]
+	checkpoint.RestoredBatches = make(map[string]int64)
+	checkpoint.RestoredRules = make(map[string]bool)
+
+	for name, value := range entries {
+		if schemaName, ok := strings.CutPrefix(name, restoreRecordsEntry); ok {
+			batches, err := strconv.ParseInt(value, 10, 64)
+			if err != nil {
+				return fmt.Errorf("invalid restored batches of %s schema: %w", schemaName, err)
+			}
+			checkpoint.RestoredBatches[schemaName] = batches
+		} else if schemaName, ok := strings.CutPrefix(name, restoreRulesEntry); ok {
+			checkpoint.RestoredRules[schemaName] = true
+		}
+	}
+
+	if checkpoint.SnapshotId == "" {
+		return fmt.Errorf("restore checkpoint without snapshot")
+	}
+	if checkpoint.CommitMode != RestoreCommitTable && checkpoint.CommitMode != RestoreCommitBatch {
+		return fmt.Errorf("restore checkpoint with unsupported %s commit mode", checkpoint.CommitMode)
+	}
+	return nil
+}
@@ -0,0 +1,44 @@
+package entities
+
+import "slices"
+
+// RetentionPolicy defines the snapshots of a backup kept when it is pruned. For every kind of period, the newest snapshot
+// of each of the last periods with snapshots is kept, the periods being counted in the local time zone.
+//
+// Hourly -> The number of hours whose newest snapshot is kept
+// Daily -> The number of days whose newest snapshot is kept
+// Monthly -> The number of months whose newest snapshot is kept
+type RetentionPolicy struct {
+	Hourly  int `json:"hourly"`
+	Daily   int `json:"daily"`
+	Monthly int `json:"monthly"`
+}
+
+// KeptSnapshots returns the identifiers of the snapshots kept by the policy, from the snapshots in the order they were taken.
+func (policy RetentionPolicy) KeptSnapshots(snapshots []BackupMetadataSnapshot) map[string]bool {
+	periods := []struct {
+		count  int
+		layout string
+	}{
+		{policy.Hourly, "2006-01-02 15"},
+		{policy.Daily, "2006-01-02"},
+		{policy.Monthly, "2006-01"},
+	}
+
+	kept := make(map[string]bool)
+	for _, period := range periods {
+		keptPeriods := 0
+		lastPeriod := ""
+		for _, snapshot := range slices.Backward(snapshots) {
+			if keptPeriods == period.count {
+				break
+			}
+			if snapshotPeriod := snapshot.Timestamp.Local().Format(period.layout); snapshotPeriod != lastPeriod {
+				kept[snapshot.SnapshotId] = true
+				lastPeriod = snapshotPeriod
+				keptPeriods++
+			}
+		}
+	}
+	return kept
+}
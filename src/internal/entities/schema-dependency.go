@@ -3,7 +3,9 @@ package entities
 type DependencyType string
 
 const (
-	PSQLSequence DependencyType = "PSQLSequence"
+	PSQLSequence       DependencyType = "PSQLSequence"
+	MySQLAutoIncrement DependencyType = "MySQLAutoIncrement"
+	SQLiteSequence     DependencyType = "SQLiteSequence"
 )
 
 // SchemaDependency is our main entity used to represent all the schema dependencies metadata in a Database.
@@ -3,7 +3,8 @@ package entities
 type RecordType string
 
 const (
-	SQLRecord RecordType = "SQLRecord"
+	SQLRecord     RecordType = "SQLRecord"
+	MongoDocument RecordType = "MongoDocument"
 )
 
 var LIMIT_RECORD_SIZE = 5 * 1024 * 1024 // 5 MB
@@ -24,7 +25,7 @@ type SchemaRecordMetadata struct {
 // Hash() -> Returns the chunk signature
 // Diff() -> Returns the differences that has our chunk comparing it with the parameter older chunk
 // ApplyDiff() -> Returns a new chunk applying the differences to our chunk
-// EncodeToBytes() -> Encodes the entity into a []byte
+// EncodeToBytes() -> Encodes the entity into a []byte, failing if a record holds a value of an unsupported type
 // DecodeFromBytes() -> Decode the entity from []byte
 type SchemaRecordChunk interface {
 	Length() int
@@ -34,7 +35,7 @@ type SchemaRecordChunk interface {
 	DiffFromEmpty() SchemaRecordChunkDiff
 	DiffToEmpty(isDiff bool) SchemaRecordChunkDiff
 	ApplyDiff(diff SchemaRecordChunkDiff) SchemaRecordChunk
-	EncodeToBytes() []byte
+	EncodeToBytes() ([]byte, error)
 	DecodeFromBytes(data []byte) error
 }
 
@@ -42,14 +43,55 @@ type SchemaRecordChunk interface {
 //
 // Length() -> Retrieves the number of items the chunk hash.
 // Hash() -> Returns the chunk signature
-// EncodeToBytes() -> Encodes the entity into a []byte
+// EncodeToBytes() -> Encodes the entity into a []byte, failing if a record holds a value of an unsupported type
 // DecodeFromBytes() -> Decode the entity from []byte
 type SchemaRecordChunkDiff interface {
 	Length() int
 	Hash() *string
 	GetPrevRef() *string
 	GetRecordType() RecordType
-	EncodeToBytes() []byte
+	EncodeToBytes() ([]byte, error)
 	DecodeFromBytes(data []byte) error
 	ApplyDiffFromEmpty() SchemaRecordChunk
 }
+
+// SchemaRecordKey identifies a record by its key, and its content by its hash.
+type SchemaRecordKey struct {
+	Key  string
+	Hash string
+}
+
+// KeyedSchemaRecordChunk is a chunk whose records can be identified by a key, as the primary key of a table.
+// The snapshots of its schema match the records by key across all the chunks, so inserting or deleting a record
+// only changes the chunk that contains it instead of shifting every following chunk.
+//
+// IsKeyed() -> Returns whether the records have a key. Otherwise the chunk is compared by its position
+// RecordKeys() -> Returns the key and hash of every record in the chunk, in the chunk order
+// CompareKeys() -> Returns a negative number, zero or a positive number as the first key goes before, is equal to or goes after the second one, in the order the DB reads the records
+// KeyRecords() -> Returns a chunk with the records of the parameter chunk, identified by the key of our chunk
+// Select() -> Returns a new chunk with the records in the given positions
+// Patch() -> Returns a new chunk replacing the records with the same key as the changed records, removing the deleted keys and appending the rest of changed records
+type KeyedSchemaRecordChunk interface {
+	SchemaRecordChunk
+	IsKeyed() bool
+	RecordKeys() []SchemaRecordKey
+	CompareKeys(key, otherKey string) int
+	KeyRecords(chunk SchemaRecordChunk) KeyedSchemaRecordChunk
+	Select(positions []int) KeyedSchemaRecordChunk
+	Patch(changes []KeyedSchemaRecordChunk, deletedKeys map[string]bool) KeyedSchemaRecordChunk
+}
+
+// SplittableSchemaRecordChunk is a chunk whose records can be regrouped into chunks of any length, so the snapshots of
+// a schema without key can end its chunks where the record contents decide instead of every fixed number of records.
+//
+// RecordHashes() -> Returns the hash of every record in the chunk, in the chunk order
+// Slice() -> Returns a new chunk with the records from the start position up to the end position, not included
+// Append() -> Returns a new chunk with the records of our chunk followed by the records of the parameter chunk
+// DiffFromEmptyBefore() -> Same as DiffFromEmpty(), but the new chunk is placed before the chunk with the given reference instead of at the end of the batch
+type SplittableSchemaRecordChunk interface {
+	SchemaRecordChunk
+	RecordHashes() []string
+	Slice(start, end int) SplittableSchemaRecordChunk
+	Append(chunk SchemaRecordChunk) SplittableSchemaRecordChunk
+	DiffFromEmptyBefore(nextRef string) SchemaRecordChunkDiff
+}
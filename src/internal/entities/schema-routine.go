@@ -3,9 +3,15 @@ package entities
 type RoutineType string
 
 const (
-	PSQLFunction  RoutineType = "PSQLFunction"
-	PSQLProcedure RoutineType = "PSQLProcedure"
-	PSQLTrigger   RoutineType = "PSQLTrigger"
+	PSQLFunction   RoutineType = "PSQLFunction"
+	PSQLProcedure  RoutineType = "PSQLProcedure"
+	PSQLTrigger    RoutineType = "PSQLTrigger"
+	MySQLFunction  RoutineType = "MySQLFunction"
+	MySQLProcedure RoutineType = "MySQLProcedure"
+	MySQLTrigger   RoutineType = "MySQLTrigger"
+	MySQLEvent     RoutineType = "MySQLEvent"
+	SQLiteTrigger  RoutineType = "SQLiteTrigger"
+	SQLiteView     RoutineType = "SQLiteView"
 )
 
 // Routine is our main entity used to represent all the routines metadata in a Database.
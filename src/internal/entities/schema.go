@@ -3,7 +3,8 @@ package entities
 type SchemaType string
 
 const (
-	SQLTable SchemaType = "SQLTable"
+	SQLTable        SchemaType = "SQLTable"
+	MongoCollection SchemaType = "MongoCollection"
 )
 
 // Schema is our main entity used to represent all the schemas metadata in a Database.
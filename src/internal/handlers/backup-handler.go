@@ -1,7 +1,10 @@
 package handlers
 
 import (
+	"historydb/src/internal/entities"
 	"historydb/src/internal/usecases"
+	"sync"
+	"sync/atomic"
 )
 
 type BackupHandler struct {
@@ -12,11 +15,23 @@ func NewBackupHandler(backupUc usecases.BackupUsecases) *BackupHandler {
 	return &BackupHandler{backupUc}
 }
 
-func (handler *BackupHandler) CreateBackup(message string) {
-	snapshot := handler.backupUc.CreateSnapshot(true, message)
+func (handler *BackupHandler) CreateBackup(message string, jobs int, resume bool) {
+	var snapshot *entities.BackupSnapshot
+	var checkpoint *entities.BackupCheckpoint
+	if resume {
+		snapshot, checkpoint = handler.backupUc.ResumeSnapshot(true)
+	}
 	if snapshot == nil {
+		if snapshot = handler.backupUc.CreateSnapshot(true, message); snapshot == nil {
+			return
+		}
+	}
+
+	if ok := handler.backupUc.BeginDatabaseTransaction(); !ok {
+		handler.backupUc.RollbackSnapshot(true)
 		return
 	}
+	defer handler.backupUc.EndDatabaseTransaction()
 
 	if ok := handler.backupUc.BackupSchemaDependencies(snapshot); !ok {
 		handler.backupUc.RollbackSnapshot(true)
@@ -29,11 +44,9 @@ func (handler *BackupHandler) CreateBackup(message string) {
 		return
 	}
 
-	for _, schema := range schemas {
-		if ok := handler.backupUc.BackupSchemaRecords(snapshot, schema); !ok {
-			handler.backupUc.RollbackSnapshot(true)
-			return
-		}
+	if ok := handler.saveSchemasRecords(nil, snapshot, checkpoint, schemas, jobs); !ok {
+		handler.backupUc.RollbackSnapshot(true)
+		return
 	}
 
 	if ok := handler.backupUc.BackupRoutines(snapshot); !ok {
@@ -46,7 +59,7 @@ func (handler *BackupHandler) CreateBackup(message string) {
 	}
 }
 
-func (handler *BackupHandler) SnapshotBackup(message string) {
+func (handler *BackupHandler) SnapshotBackup(message string, jobs int, resume bool) {
 	backupMetadata := handler.backupUc.GetBackupMetadata()
 	if backupMetadata == nil {
 		return
@@ -56,11 +69,23 @@ func (handler *BackupHandler) SnapshotBackup(message string) {
 		return
 	}
 
-	newSnapshot := handler.backupUc.CreateSnapshot(false, message)
+	var newSnapshot *entities.BackupSnapshot
+	var checkpoint *entities.BackupCheckpoint
+	if resume {
+		newSnapshot, checkpoint = handler.backupUc.ResumeSnapshot(false)
+	}
 	if newSnapshot == nil {
+		if newSnapshot = handler.backupUc.CreateSnapshot(false, message); newSnapshot == nil {
+			handler.backupUc.RollbackSnapshot(false)
+			return
+		}
+	}
+
+	if ok := handler.backupUc.BeginDatabaseTransaction(); !ok {
 		handler.backupUc.RollbackSnapshot(false)
 		return
 	}
+	defer handler.backupUc.EndDatabaseTransaction()
 
 	if ok := handler.backupUc.SnapshotSchemaDependencies(lastSnapshot, newSnapshot); !ok {
 		handler.backupUc.RollbackSnapshot(false)
@@ -73,18 +98,9 @@ func (handler *BackupHandler) SnapshotBackup(message string) {
 		return
 	}
 
-	for _, schema := range schemas {
-		if _, ok := lastSnapshot.Data[schema.GetName()]; !ok {
-			if ok := handler.backupUc.BackupSchemaRecords(newSnapshot, schema); !ok {
-				handler.backupUc.RollbackSnapshot(false)
-				return
-			}
-		} else {
-			if ok := handler.backupUc.SnapshotSchemaRecords(lastSnapshot, newSnapshot, schema); !ok {
-				handler.backupUc.RollbackSnapshot(false)
-				return
-			}
-		}
+	if ok := handler.saveSchemasRecords(lastSnapshot, newSnapshot, checkpoint, schemas, jobs); !ok {
+		handler.backupUc.RollbackSnapshot(false)
+		return
 	}
 
 	if ok := handler.backupUc.SnapshotRoutines(lastSnapshot, newSnapshot); !ok {
@@ -96,3 +112,95 @@ func (handler *BackupHandler) SnapshotBackup(message string) {
 		handler.backupUc.RollbackSnapshot(false)
 	}
 }
+
+// saveSchemasRecords saves the records of every schema, backing up the schemas that are not in the last snapshot and
+// snapshotting the rest. With more than one job, up to jobs schemas are saved at once, each one by a worker with its own
+// DB connection that reads the same state of the DB. If the DB engine cannot share that state, schemas are saved one after another.
+// When a snapshot is resumed, the schemas whose records were saved before it was interrupted are not read again, and the rest
+// go on from their last saved batch, as long as their definition did not change meanwhile.
+func (handler *BackupHandler) saveSchemasRecords(lastSnapshot, snapshot *entities.BackupSnapshot, checkpoint *entities.BackupCheckpoint, schemas []entities.Schema, jobs int) bool {
+	saveSchemaRecords := func(backupUc usecases.BackupUsecases, snapshot *entities.BackupSnapshot, schema entities.Schema) bool {
+		var progress *entities.BackupSchemaProgress
+		if checkpoint != nil {
+			if progress = checkpoint.Schemas[schema.GetName()]; progress != nil && progress.SchemaHash != schema.Hash() {
+				progress = nil
+			}
+		}
+		if progress != nil && progress.Complete {
+			if progress.Data != nil {
+				snapshot.Data[schema.GetName()] = *progress.Data
+			}
+			return true
+		}
+
+		if lastSnapshot != nil {
+			if _, ok := lastSnapshot.Data[schema.GetName()]; ok {
+				return backupUc.SnapshotSchemaRecords(lastSnapshot, snapshot, schema, progress) && backupUc.CheckpointSchemaRecords(snapshot, schema)
+			}
+		}
+		return backupUc.BackupSchemaRecords(snapshot, schema, progress) && backupUc.CheckpointSchemaRecords(snapshot, schema)
+	}
+
+	workers := []usecases.BackupUsecases{}
+	for jobs > 1 && len(workers) < min(jobs, len(schemas)) {
+		worker := handler.backupUc.CreateWorker()
+		if worker == nil {
+			break
+		}
+		if ok := worker.BeginDatabaseTransaction(); !ok {
+			return false
+		}
+		defer worker.EndDatabaseTransaction()
+
+		workers = append(workers, worker)
+	}
+
+	if len(workers) == 0 {
+		for _, schema := range schemas {
+			if ok := saveSchemaRecords(handler.backupUc, snapshot, schema); !ok {
+				return false
+			}
+		}
+		return true
+	}
+
+	// Every worker saves the schema data into its own snapshot, so they are merged once all of them finish
+	schemaQueue := make(chan entities.Schema)
+	workerSnapshots := make([]*entities.BackupSnapshot, len(workers))
+	var failed atomic.Bool
+	var wg sync.WaitGroup
+	for i, worker := range workers {
+		workerSnapshot := *snapshot
+		workerSnapshot.Data = make(map[string]entities.BackupSnapshotSchemaData)
+		workerSnapshots[i] = &workerSnapshot
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for schema := range schemaQueue {
+				if ok := saveSchemaRecords(worker, workerSnapshots[i], schema); !ok {
+					failed.Store(true)
+				}
+			}
+		}()
+	}
+
+	for _, schema := range schemas {
+		if failed.Load() {
+			break
+		}
+		schemaQueue <- schema
+	}
+	close(schemaQueue)
+	wg.Wait()
+
+	if failed.Load() {
+		return false
+	}
+	for _, workerSnapshot := range workerSnapshots {
+		for schemaName, schemaData := range workerSnapshot.Data {
+			snapshot.Data[schemaName] = schemaData
+		}
+	}
+	return true
+}
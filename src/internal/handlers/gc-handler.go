@@ -0,0 +1,15 @@
+package handlers
+
+import "historydb/src/internal/usecases"
+
+type GcHandler struct {
+	gcUc usecases.GcUsecases
+}
+
+func NewGcHandler(gcUc usecases.GcUsecases) *GcHandler {
+	return &GcHandler{gcUc}
+}
+
+func (handler *GcHandler) CollectGarbage(dryRun bool) {
+	handler.gcUc.CollectGarbage(dryRun)
+}
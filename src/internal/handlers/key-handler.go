@@ -0,0 +1,15 @@
+package handlers
+
+import "historydb/src/internal/usecases"
+
+type KeyHandler struct {
+	keyUc usecases.KeyUsecases
+}
+
+func NewKeyHandler(keyUc usecases.KeyUsecases) *KeyHandler {
+	return &KeyHandler{keyUc}
+}
+
+func (handler *KeyHandler) RotateKey(newPassphrase []byte) {
+	handler.keyUc.RotateKey(newPassphrase)
+}
@@ -1,7 +1,10 @@
 package handlers
 
 import (
+	"historydb/src/internal/entities"
 	"historydb/src/internal/usecases"
+	"sync"
+	"sync/atomic"
 )
 
 type RestoreHanlder struct {
@@ -12,12 +15,29 @@ func NewRestoreHandler(restoreUc usecases.RestoreUsecases) *RestoreHanlder {
 	return &RestoreHanlder{restoreUc}
 }
 
-func (handler *RestoreHanlder) RestoreDatabase(snapshotId *string) {
-	snapshot := handler.restoreUc.GetBackupSnapshot(snapshotId)
+func (handler *RestoreHanlder) RestoreDatabase(snapshotId *string, jobs int, commitMode entities.RestoreCommitMode, resume bool) {
+	// A resumed restore is committed as it was when it was interrupted
+	var checkpoint *entities.RestoreCheckpoint
+	if resume {
+		checkpoint = handler.restoreUc.GetRestoreCheckpoint()
+		if checkpoint == nil {
+			return
+		}
+	}
+
+	snapshot := handler.restoreUc.GetBackupSnapshot(snapshotId, checkpoint)
 	if snapshot == nil {
 		return
 	}
 
+	if checkpoint == nil && commitMode != entities.RestoreCommitAll {
+		checkpoint = &entities.RestoreCheckpoint{SnapshotId: snapshot.SnapshotId, CommitMode: commitMode}
+	}
+	if checkpoint != nil {
+		handler.restoreDatabaseInSteps(snapshot, checkpoint, jobs)
+		return
+	}
+
 	if ok := handler.restoreUc.BeginDatabaseRestore(); !ok {
 		return
 	}
@@ -27,30 +47,228 @@ func (handler *RestoreHanlder) RestoreDatabase(snapshotId *string) {
 		return
 	}
 
-	schemas := handler.restoreUc.RestoreSchemas(snapshot)
+	schemas := handler.restoreUc.RestoreSchemas(snapshot, nil)
 	if schemas == nil {
 		handler.restoreUc.RollbackDatabaseRestore()
 		return
 	}
 
-	for _, schema := range schemas {
-		if ok := handler.restoreUc.RestoreSchemaRecords(snapshot, schema); !ok {
+	workers := handler.createWorkers(jobs, len(schemas))
+	var ok bool
+	if len(workers) == 0 {
+		ok = handler.restoreSchemasContent(snapshot, schemas)
+	} else {
+		ok = handler.restoreSchemasContentInParallel(snapshot, schemas, workers)
+	}
+	if !ok {
+		handler.restoreUc.RollbackDatabaseRestore()
+		return
+	}
+
+	if ok := handler.restoreUc.RestoreRoutines(snapshot); !ok {
+		handler.restoreUc.RollbackDatabaseRestore()
+		return
+	}
+
+	if ok := handler.restoreUc.CommitDatabaseRestore(); !ok {
+		handler.restoreUc.RollbackDatabaseRestore()
+	}
+}
+
+// restoreDatabaseInSteps restores the snapshot committing every step on its own, and the records of every schema per table
+// or per batch, along with the checkpoint of the restored steps. If the restore fails the committed steps are kept, and
+// when it is resumed the steps listed in the checkpoint are skipped. The checkpoint is dropped in the last step.
+func (handler *RestoreHanlder) restoreDatabaseInSteps(snapshot *entities.BackupSnapshot, checkpoint *entities.RestoreCheckpoint, jobs int) {
+	var schemas []entities.Schema
+	if checkpoint.SchemasRestored {
+		schemas = handler.restoreUc.GetSnapshotSchemas(snapshot)
+		if schemas == nil {
+			return
+		}
+	} else {
+		if ok := handler.restoreUc.BeginDatabaseRestore(); !ok {
+			return
+		}
+		if ok := handler.restoreUc.CreateRestoreCheckpoint(checkpoint) && handler.restoreUc.RestoreSchemaDependencies(snapshot); !ok {
+			handler.restoreUc.RollbackDatabaseRestore()
+			return
+		}
+		schemas = handler.restoreUc.RestoreSchemas(snapshot, checkpoint)
+		if schemas == nil || !handler.restoreUc.CommitRestoreStep() {
 			handler.restoreUc.RollbackDatabaseRestore()
 			return
 		}
 	}
 
-	if ok := handler.restoreUc.RestoreSchemaRules(snapshot, schemas); !ok {
+	workers := handler.createWorkers(jobs, len(schemas))
+	if ok := handler.runRecordsRestoreStep(workers, schemas, func(restoreUc usecases.RestoreUsecases, schema entities.Schema) bool {
+		return restoreUc.RestoreSchemaRecords(snapshot, schema, checkpoint)
+	}); !ok {
 		handler.restoreUc.RollbackDatabaseRestore()
 		return
 	}
 
-	if ok := handler.restoreUc.RestoreRoutines(snapshot); !ok {
+	if ok := handler.runSchemasRestoreStep(workers, schemas, func(restoreUc usecases.RestoreUsecases, schemas []entities.Schema) bool {
+		return restoreUc.RestoreSchemaRules(snapshot, schemas, checkpoint)
+	}); !ok {
 		handler.restoreUc.RollbackDatabaseRestore()
 		return
 	}
 
-	if ok := handler.restoreUc.CommitDatabaseRestore(); !ok {
+	if ok := handler.restoreUc.BeginDatabaseRestore(); !ok {
+		handler.restoreUc.RollbackDatabaseRestore()
+		return
+	}
+	if ok := handler.restoreUc.RestoreSchemaConstraints(snapshot, schemas, checkpoint) && handler.restoreUc.CommitRestoreStep(); !ok {
+		handler.restoreUc.RollbackDatabaseRestore()
+		return
+	}
+
+	if ok := handler.runSchemasRestoreStep(workers, schemas, func(restoreUc usecases.RestoreUsecases, schemas []entities.Schema) bool {
+		return restoreUc.ValidateSchemaConstraints(snapshot, schemas)
+	}); !ok {
+		handler.restoreUc.RollbackDatabaseRestore()
+		return
+	}
+
+	// The routines are restored along with the drop of the checkpoint, so the restore is completed at once
+	if ok := handler.restoreUc.BeginDatabaseRestore(); !ok {
 		handler.restoreUc.RollbackDatabaseRestore()
+		return
+	}
+	if ok := handler.restoreUc.RestoreRoutines(snapshot) && handler.restoreUc.DropRestoreCheckpoint() && handler.restoreUc.CommitDatabaseRestore(); !ok {
+		handler.restoreUc.RollbackDatabaseRestore()
+	}
+}
+
+// createWorkers creates up to jobs workers to restore the schemas, one for each schema at most. It returns no workers if
+// the schemas are restored one after another.
+func (handler *RestoreHanlder) createWorkers(jobs, schemaCount int) []usecases.RestoreUsecases {
+	workers := []usecases.RestoreUsecases{}
+	for jobs > 1 && len(workers) < min(jobs, schemaCount) {
+		worker := handler.restoreUc.CreateWorker()
+		if worker == nil {
+			break
+		}
+		workers = append(workers, worker)
+	}
+	return workers
+}
+
+// runRecordsRestoreStep restores the records of every schema in its own transaction. Without workers the schemas are
+// restored one after another, and every one is committed once its records are restored.
+func (handler *RestoreHanlder) runRecordsRestoreStep(workers []usecases.RestoreUsecases, schemas []entities.Schema, restoreStep func(restoreUc usecases.RestoreUsecases, schema entities.Schema) bool) bool {
+	if len(workers) > 0 {
+		return handler.runWorkers(workers, schemas, restoreStep)
+	}
+
+	for _, schema := range schemas {
+		if ok := handler.restoreUc.BeginDatabaseRestore(); !ok {
+			return false
+		}
+		if ok := restoreStep(handler.restoreUc, schema) && handler.restoreUc.CommitRestoreStep(); !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// runSchemasRestoreStep runs a restore step of every schema. Without workers it is run for all the schemas in a single
+// transaction, while the workers run it for every schema in its own transaction.
+func (handler *RestoreHanlder) runSchemasRestoreStep(workers []usecases.RestoreUsecases, schemas []entities.Schema, restoreStep func(restoreUc usecases.RestoreUsecases, schemas []entities.Schema) bool) bool {
+	if len(workers) > 0 {
+		return handler.runWorkers(workers, schemas, func(worker usecases.RestoreUsecases, schema entities.Schema) bool {
+			return restoreStep(worker, []entities.Schema{schema})
+		})
+	}
+
+	if ok := handler.restoreUc.BeginDatabaseRestore(); !ok {
+		return false
+	}
+	return restoreStep(handler.restoreUc, schemas) && handler.restoreUc.CommitRestoreStep()
+}
+
+// restoreSchemasContent restores the records of every schema one after another, and then their rules and constraints.
+func (handler *RestoreHanlder) restoreSchemasContent(snapshot *entities.BackupSnapshot, schemas []entities.Schema) bool {
+	for _, schema := range schemas {
+		if ok := handler.restoreUc.RestoreSchemaRecords(snapshot, schema, nil); !ok {
+			return false
+		}
+	}
+
+	return handler.restoreUc.RestoreSchemaRules(snapshot, schemas, nil) &&
+		handler.restoreUc.RestoreSchemaConstraints(snapshot, schemas, nil) &&
+		handler.restoreUc.ValidateSchemaConstraints(snapshot, schemas)
+}
+
+// restoreSchemasContentInParallel commits the restored schemas so the workers can use them from their own DB connections,
+// and then lets the workers restore the records and rules of the schemas at once. Foreign keys are added one after another,
+// as adding them locks the referenced schemas too, and validated by the workers once added.
+func (handler *RestoreHanlder) restoreSchemasContentInParallel(snapshot *entities.BackupSnapshot, schemas []entities.Schema, workers []usecases.RestoreUsecases) bool {
+	if ok := handler.restoreUc.CheckpointDatabaseRestore(); !ok {
+		return false
+	}
+
+	if ok := handler.runWorkers(workers, schemas, func(worker usecases.RestoreUsecases, schema entities.Schema) bool {
+		return worker.RestoreSchemaRecords(snapshot, schema, nil)
+	}); !ok {
+		return false
 	}
+
+	if ok := handler.runWorkers(workers, schemas, func(worker usecases.RestoreUsecases, schema entities.Schema) bool {
+		return worker.RestoreSchemaRules(snapshot, []entities.Schema{schema}, nil)
+	}); !ok {
+		return false
+	}
+
+	if ok := handler.restoreUc.RestoreSchemaConstraints(snapshot, schemas, nil); !ok {
+		return false
+	}
+	if ok := handler.restoreUc.CheckpointDatabaseRestore(); !ok {
+		return false
+	}
+
+	return handler.runWorkers(workers, schemas, func(worker usecases.RestoreUsecases, schema entities.Schema) bool {
+		return worker.ValidateSchemaConstraints(snapshot, []entities.Schema{schema})
+	})
+}
+
+// runWorkers runs the restore step of every schema in the workers, each schema in its own worker transaction.
+// Once a step fails no more schemas are given to the workers, and it waits for the ones in progress before returning.
+func (handler *RestoreHanlder) runWorkers(workers []usecases.RestoreUsecases, schemas []entities.Schema, restoreStep func(worker usecases.RestoreUsecases, schema entities.Schema) bool) bool {
+	schemaQueue := make(chan entities.Schema)
+	var failed atomic.Bool
+	var wg sync.WaitGroup
+	for _, worker := range workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for schema := range schemaQueue {
+				if ok := worker.BeginDatabaseRestore(); !ok {
+					failed.Store(true)
+					continue
+				}
+				if ok := restoreStep(worker, schema); !ok {
+					worker.RollbackDatabaseRestore()
+					failed.Store(true)
+					continue
+				}
+				if ok := worker.CommitDatabaseRestore(); !ok {
+					worker.RollbackDatabaseRestore()
+					failed.Store(true)
+				}
+			}
+		}()
+	}
+
+	for _, schema := range schemas {
+		if failed.Load() {
+			break
+		}
+		schemaQueue <- schema
+	}
+	close(schemaQueue)
+	wg.Wait()
+
+	return !failed.Load()
 }
@@ -0,0 +1,43 @@
+package handlers
+
+import (
+	"historydb/src/internal/entities"
+	"historydb/src/internal/usecases"
+)
+
+type SnapshotHandler struct {
+	snapshotUc usecases.SnapshotUsecases
+}
+
+func NewSnapshotHandler(snapshotUc usecases.SnapshotUsecases) *SnapshotHandler {
+	return &SnapshotHandler{snapshotUc}
+}
+
+func (handler *SnapshotHandler) DeleteSnapshot(snapshotId string) {
+	metadata := handler.snapshotUc.GetBackupMetadata()
+	if metadata == nil {
+		return
+	}
+
+	snapshot := handler.snapshotUc.GetDeletedSnapshot(metadata, snapshotId)
+	if snapshot == nil {
+		return
+	}
+
+	handler.snapshotUc.DeleteSnapshots([]entities.BackupMetadataSnapshot{*snapshot})
+}
+
+// PruneSnapshots deletes the snapshots of the backup which the retention policy does not keep.
+func (handler *SnapshotHandler) PruneSnapshots(policy entities.RetentionPolicy) {
+	metadata := handler.snapshotUc.GetBackupMetadata()
+	if metadata == nil {
+		return
+	}
+
+	snapshots := handler.snapshotUc.GetPrunedSnapshots(metadata, policy)
+	if len(snapshots) == 0 {
+		return
+	}
+
+	handler.snapshotUc.DeleteSnapshots(snapshots)
+}
@@ -0,0 +1,508 @@
+package test
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"historydb/src/internal/entities"
+	"historydb/src/internal/handlers"
+	"historydb/src/internal/services/backup/binary"
+	database_services "historydb/src/internal/services/database"
+	"historydb/src/internal/services/database/sqlite"
+	"historydb/src/internal/services/storage/local"
+	"historydb/src/internal/usecases"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	_ "modernc.org/sqlite"
+)
+
+func TestSnapshotContentDefinedRecords(t *testing.T) {
+	testSmallBatches(t, 200)
+
+	db := setupSQLiteTestDatabase(t, "source.db", "CREATE TABLE events (name TEXT, amount INTEGER)")
+	for i := 1; i <= 2000; i++ {
+		testExec(t, db, "INSERT INTO events (rowid, name, amount) VALUES (?, ?, ?)", i, fmt.Sprintf("event-%d", i), i*10)
+	}
+	backupPath := filepath.Join(t.TempDir(), "backup")
+	options := binary.BinaryBackupOptions{Compression: entities.NoCompression}
+
+	testBackupHandler(db, backupPath, options).CreateBackup("", 1, false)
+	firstRows := testTableRows(t, db, "events")
+
+	// Records are updated, deleted and inserted in the middle of the table, which has no primary key
+	testExec(t, db, "UPDATE events SET amount = -1 WHERE rowid BETWEEN 500 AND 502")
+	testExec(t, db, "DELETE FROM events WHERE rowid BETWEEN 1000 AND 1010")
+	testExec(t, db, "INSERT INTO events (rowid, name, amount) VALUES (1005, 'inserted', 0)")
+	testExec(t, db, "DELETE FROM events WHERE rowid = 1700")
+
+	testBackupHandler(db, backupPath, options).SnapshotBackup("", 1, false)
+	secondRows := testTableRows(t, db, "events")
+
+	snapshots := testSnapshots(t, backupPath, options)
+	if !assert.Len(t, snapshots, 2, "Snapshots of the backup") {
+		return
+	}
+	firstData, secondData := snapshots[0].Data["events"], snapshots[1].Data["events"]
+	assert.Equal(t, entities.ContentDefinedChunking, secondData.Chunking, "Chunking of the snapshot")
+
+	// Boundaries resynchronise after every change, so the batches far from the changes are kept as they are
+	keptBatches := 0
+	for _, batchRef := range secondData.Data {
+		for _, prevBatchRef := range firstData.Data {
+			if batchRef == prevBatchRef {
+				keptBatches++
+			}
+		}
+	}
+	// Every change rewrites at most the two batches around it
+	assert.GreaterOrEqual(t, keptBatches, len(secondData.Data)-6, "Batches kept from the last snapshot")
+
+	assert.Equal(t, firstRows, testTableRows(t, testRestore(t, backupPath, options, &snapshots[0].SnapshotId), "events"), "Restore of the first snapshot")
+	assert.Equal(t, secondRows, testTableRows(t, testRestore(t, backupPath, options, nil), "events"), "Restore of the last snapshot")
+}
+
+func TestSnapshotKeyedRecords(t *testing.T) {
+	testSmallBatches(t, 1000)
+
+	// Every batch holds 1000 records in chunks of 10, and the ids are even so records can be inserted between them
+	db := setupSQLiteTestDatabase(t, "source.db", "CREATE TABLE users (id INTEGER PRIMARY KEY, name TEXT)")
+	for i := 1; i <= 3000; i++ {
+		testExec(t, db, "INSERT INTO users (id, name) VALUES (?, ?)", i*2, fmt.Sprintf("user-%d", i))
+	}
+	backupPath := filepath.Join(t.TempDir(), "backup")
+	options := binary.BinaryBackupOptions{Compression: entities.NoCompression}
+
+	testBackupHandler(db, backupPath, options).CreateBackup("", 1, false)
+	expectedRows := [][]string{testTableRows(t, db, "users")}
+
+	// Records are updated, deleted and inserted across chunk and batch boundaries, many records are inserted between
+	// two records and after the last one, and a whole batch is deleted
+	testExec(t, db, "UPDATE users SET name = 'updated' WHERE id BETWEEN 18 AND 24")
+	testExec(t, db, "DELETE FROM users WHERE id BETWEEN 190 AND 210")
+	testExec(t, db, "INSERT INTO users (id, name) VALUES (1999, 'inserted'), (2001, 'inserted'), (21, 'inserted')")
+	for id := 1001; id < 1200; id += 2 {
+		testExec(t, db, "INSERT INTO users (id, name) VALUES (?, 'inserted')", id)
+	}
+	testExec(t, db, "DELETE FROM users WHERE id BETWEEN 2001 AND 4000")
+	for id := 6001; id <= 7500; id++ {
+		testExec(t, db, "INSERT INTO users (id, name) VALUES (?, 'appended')", id)
+	}
+
+	testBackupHandler(db, backupPath, options).SnapshotBackup("", 1, false)
+	expectedRows = append(expectedRows, testTableRows(t, db, "users"))
+
+	testExec(t, db, "UPDATE users SET name = 'updated again' WHERE id IN (2, 1101, 7500)")
+	testExec(t, db, "DELETE FROM users WHERE id = 1500")
+	testExec(t, db, "INSERT INTO users (id, name) VALUES (1, 'inserted')")
+
+	testBackupHandler(db, backupPath, options).SnapshotBackup("", 1, false)
+	expectedRows = append(expectedRows, testTableRows(t, db, "users"))
+
+	// A snapshot of the same records keeps every batch, as the chunks keep the records of their range of keys
+	testBackupHandler(db, backupPath, options).SnapshotBackup("", 1, false)
+
+	snapshots := testSnapshots(t, backupPath, options)
+	if !assert.Len(t, snapshots, 4, "Snapshots of the backup") {
+		return
+	}
+	assert.Equal(t, snapshots[2].Data["users"], snapshots[3].Data["users"], "Snapshot of unchanged records")
+	assert.True(t, testVerify(backupPath, options), "Verify the backup")
+
+	for i, expected := range expectedRows {
+		assert.Equal(t, expected, testTableRows(t, testRestore(t, backupPath, options, &snapshots[i].SnapshotId), "users"), fmt.Sprintf("Restore of snapshot %d", i))
+	}
+}
+
+func TestResumeSnapshotRecords(t *testing.T) {
+	testSmallBatches(t, 1000)
+
+	// The records of users are matched by key and the records of events are split with content-defined chunks
+	db := setupSQLiteTestDatabase(t, "source.db", "CREATE TABLE users (id INTEGER PRIMARY KEY, name TEXT)", "CREATE TABLE events (name TEXT, amount INTEGER)")
+	for i := 1; i <= 3000; i++ {
+		testExec(t, db, "INSERT INTO users (id, name) VALUES (?, ?)", i*2, fmt.Sprintf("user-%d", i))
+		testExec(t, db, "INSERT INTO events (rowid, name, amount) VALUES (?, ?, ?)", i, fmt.Sprintf("event-%d", i), i*10)
+	}
+	backupPath := filepath.Join(t.TempDir(), "backup")
+	options := binary.BinaryBackupOptions{Compression: entities.NoCompression}
+
+	testBackupHandler(db, backupPath, options).CreateBackup("", 1, false)
+
+	testExec(t, db, "UPDATE users SET name = 'updated' WHERE id BETWEEN 100 AND 120")
+	testExec(t, db, "DELETE FROM users WHERE id BETWEEN 3000 AND 3100")
+	for id := 4001; id < 4400; id += 2 {
+		testExec(t, db, "INSERT INTO users (id, name) VALUES (?, 'inserted')", id)
+	}
+	testExec(t, db, "UPDATE events SET amount = -1 WHERE rowid BETWEEN 200 AND 210")
+	testExec(t, db, "DELETE FROM events WHERE rowid BETWEEN 1500 AND 1510")
+	for i := 3001; i <= 3500; i++ {
+		testExec(t, db, "INSERT INTO events (rowid, name, amount) VALUES (?, 'appended', 0)", i)
+	}
+	expectedUsers, expectedEvents := testTableRows(t, db, "users"), testTableRows(t, db, "events")
+
+	// An uninterrupted snapshot of a copy of the backup counts the DB chunks a snapshot reads
+	backupCopyPath := filepath.Join(t.TempDir(), "backup")
+	if err := os.CopyFS(backupCopyPath, os.DirFS(backupPath)); err != nil {
+		t.Fatalf("could not copy backup: %v", err)
+	}
+	uninterrupted := &testInterruptedDatabaseFactory{DatabaseFactory: sqlite.NewSQLiteDatabaseFactory(db)}
+	testBackupHandlerWithFactory(uninterrupted, backupCopyPath, options).SnapshotBackup("", 1, false)
+
+	// The snapshot is interrupted twice while its records are saved, and every resumed snapshot goes on from its checkpoint
+	readChunks := 0
+	for _, maxChunks := range []int{150, 300} {
+		interrupted := &testInterruptedDatabaseFactory{DatabaseFactory: sqlite.NewSQLiteDatabaseFactory(db), maxChunks: maxChunks}
+		testBackupHandlerWithFactory(interrupted, backupPath, options).SnapshotBackup("", 1, true)
+		readChunks += interrupted.chunks
+
+		checkpoint, err := binary.NewBinaryBackupWriter(local.NewLocalStorage(backupPath), options).FindCheckpoint()
+		if !assert.NoError(t, err, "Checkpoint of the interrupted snapshot") || !assert.NotNil(t, checkpoint, "Checkpoint of the interrupted snapshot") {
+			return
+		}
+		savedBatches := 0
+		for _, progress := range checkpoint.Schemas {
+			if progress.Data != nil {
+				savedBatches += len(progress.Data.Data)
+			}
+		}
+		assert.Greater(t, savedBatches, 0, "Batches saved before the interruption")
+	}
+	assert.Len(t, testSnapshots(t, backupPath, options), 1, "Snapshots of the backup after the interruptions")
+
+	resumed := &testInterruptedDatabaseFactory{DatabaseFactory: sqlite.NewSQLiteDatabaseFactory(db)}
+	testBackupHandlerWithFactory(resumed, backupPath, options).SnapshotBackup("", 1, true)
+
+	// The chunks read before the interruptions are not read again, but the ones of the batches they were saving
+	assert.Less(t, resumed.chunks, uninterrupted.chunks-readChunks/2, "DB chunks read by the resumed snapshot")
+	snapshots := testSnapshots(t, backupPath, options)
+	if !assert.Len(t, snapshots, 2, "Snapshots of the backup") {
+		return
+	}
+	assert.Equal(t, testSnapshots(t, backupCopyPath, options)[1].Data, snapshots[1].Data, "Resumed snapshot data")
+	assert.True(t, testVerify(backupPath, options), "Verify the backup")
+
+	restoredDB := testRestore(t, backupPath, options, nil)
+	assert.Equal(t, expectedUsers, testTableRows(t, restoredDB, "users"), "Restore of the users")
+	assert.Equal(t, expectedEvents, testTableRows(t, restoredDB, "events"), "Restore of the events")
+}
+
+func TestResumeBackupRecords(t *testing.T) {
+	testSmallBatches(t, 1000)
+
+	db := setupSQLiteTestDatabase(t, "source.db", "CREATE TABLE events (name TEXT, amount INTEGER)")
+	for i := 1; i <= 3000; i++ {
+		testExec(t, db, "INSERT INTO events (rowid, name, amount) VALUES (?, ?, ?)", i, fmt.Sprintf("event-%d", i), i*10)
+	}
+	backupPath := filepath.Join(t.TempDir(), "backup")
+	options := binary.BinaryBackupOptions{Compression: entities.NoCompression}
+
+	// The first snapshot is interrupted while its content-defined batches are saved
+	interrupted := &testInterruptedDatabaseFactory{DatabaseFactory: sqlite.NewSQLiteDatabaseFactory(db), maxChunks: 200}
+	testBackupHandlerWithFactory(interrupted, backupPath, options).CreateBackup("", 1, true)
+
+	resumed := &testInterruptedDatabaseFactory{DatabaseFactory: sqlite.NewSQLiteDatabaseFactory(db)}
+	testBackupHandlerWithFactory(resumed, backupPath, options).CreateBackup("", 1, true)
+
+	assert.Less(t, resumed.chunks, 200, "DB chunks read by the resumed snapshot")
+	assert.Len(t, testSnapshots(t, backupPath, options), 1, "Snapshots of the backup")
+	assert.True(t, testVerify(backupPath, options), "Verify the backup")
+	assert.Equal(t, testTableRows(t, db, "events"), testTableRows(t, testRestore(t, backupPath, options, nil), "events"), "Restore of the events")
+}
+
+func TestSnapshotWithJobs(t *testing.T) {
+	testSmallBatches(t, 200)
+
+	db := setupSQLiteTestDatabase(t, "source.db",
+		"CREATE TABLE users (id INTEGER PRIMARY KEY, name TEXT)",
+		"CREATE TABLE orders (id INTEGER PRIMARY KEY, user_id INTEGER, total INTEGER)",
+		"CREATE TABLE events (name TEXT, amount INTEGER)",
+		"CREATE TABLE logs (message TEXT)",
+	)
+	for i := 1; i <= 1000; i++ {
+		testExec(t, db, "INSERT INTO users (id, name) VALUES (?, ?)", i, fmt.Sprintf("user-%d", i))
+		testExec(t, db, "INSERT INTO orders (id, user_id, total) VALUES (?, ?, ?)", i, i%50, i*3)
+		testExec(t, db, "INSERT INTO events (rowid, name, amount) VALUES (?, ?, ?)", i, fmt.Sprintf("event-%d", i), i*10)
+		testExec(t, db, "INSERT INTO logs (rowid, message) VALUES (?, ?)", i, fmt.Sprintf("log-%d", i))
+	}
+	parallelPath := filepath.Join(t.TempDir(), "backup")
+	sequentialPath := filepath.Join(t.TempDir(), "backup")
+	options := binary.BinaryBackupOptions{Compression: entities.NoCompression}
+
+	// The same backup and snapshot are saved by 3 workers and by a single one
+	parallel := &testParallelDatabaseFactory{DatabaseFactory: sqlite.NewSQLiteDatabaseFactory(db), db: db}
+	testBackupHandlerWithFactory(parallel, parallelPath, options).CreateBackup("", 3, false)
+	testBackupHandler(db, sequentialPath, options).CreateBackup("", 1, false)
+
+	// A new table is backed up while the rest are snapshotted
+	testExec(t, db, "UPDATE users SET name = 'updated' WHERE id BETWEEN 10 AND 20")
+	testExec(t, db, "DELETE FROM orders WHERE id BETWEEN 500 AND 520")
+	testExec(t, db, "INSERT INTO events (name, amount) VALUES ('appended', 0)")
+	testExec(t, db, "CREATE TABLE tags (name TEXT PRIMARY KEY)")
+	testExec(t, db, "INSERT INTO tags (name) VALUES ('a'), ('b'), ('c')")
+
+	testBackupHandlerWithFactory(parallel, parallelPath, options).SnapshotBackup("", 3, false)
+	testBackupHandler(db, sequentialPath, options).SnapshotBackup("", 1, false)
+	assert.Equal(t, 6, parallel.parallelReaders, "Parallel readers of the workers")
+
+	// The data the workers save into their own snapshots is merged into the snapshot of the backup
+	parallelSnapshots, sequentialSnapshots := testSnapshots(t, parallelPath, options), testSnapshots(t, sequentialPath, options)
+	if !assert.Len(t, parallelSnapshots, 2, "Snapshots of the backup") || !assert.Len(t, sequentialSnapshots, 2, "Snapshots of the backup") {
+		return
+	}
+	for i := range parallelSnapshots {
+		assert.Len(t, parallelSnapshots[i].Data, i+4, fmt.Sprintf("Schemas of snapshot %d", i))
+		assert.Equal(t, sequentialSnapshots[i].Data, parallelSnapshots[i].Data, fmt.Sprintf("Data of snapshot %d", i))
+	}
+	assert.True(t, testVerify(parallelPath, options), "Verify the backup")
+
+	restoredDB := testRestore(t, parallelPath, options, nil)
+	for _, table := range []string{"users", "orders", "events", "logs", "tags"} {
+		assert.Equal(t, testTableRows(t, db, table), testTableRows(t, restoredDB, table), fmt.Sprintf("Restore of %s", table))
+	}
+}
+
+func TestBackupCompression(t *testing.T) {
+	testSmallBatches(t, 200)
+
+	db := setupSQLiteTestDatabase(t, "source.db", "CREATE TABLE events (id INTEGER PRIMARY KEY, name TEXT, payload TEXT)")
+	for i := 1; i <= 1000; i++ {
+		testExec(t, db, "INSERT INTO events (id, name, payload) VALUES (?, ?, ?)", i, fmt.Sprintf("event-%d", i), strings.Repeat("payload ", 50))
+	}
+	zstdPath := filepath.Join(t.TempDir(), "backup")
+	zstdOptions := binary.BinaryBackupOptions{Compression: entities.ZstdCompression, CompressionLevel: 7}
+	nonePath := filepath.Join(t.TempDir(), "backup")
+	noneOptions := binary.BinaryBackupOptions{Compression: entities.NoCompression}
+
+	testBackupHandler(db, zstdPath, zstdOptions).CreateBackup("", 1, false)
+	testBackupHandler(db, nonePath, noneOptions).CreateBackup("", 1, false)
+	firstRows := testTableRows(t, db, "events")
+
+	// The codec and its level are saved into the metadata, and the compressed payloads take less space
+	zstdMetadata := testBackupMetadata(t, zstdPath)
+	assert.Equal(t, entities.ZstdCompression, zstdMetadata.Compression, "Compression of the zstd backup")
+	assert.Equal(t, int64(7), zstdMetadata.CompressionLevel, "Compression level of the zstd backup")
+	noneMetadata := testBackupMetadata(t, nonePath)
+	assert.Equal(t, entities.NoCompression, noneMetadata.Compression, "Compression of the uncompressed backup")
+	assert.Equal(t, int64(0), noneMetadata.CompressionLevel, "Compression level of the uncompressed backup")
+	assert.Less(t, testDirSize(t, zstdPath), testDirSize(t, nonePath)/2, "Size of the zstd backup")
+
+	// A backup saved without compression keeps its codec when it is opened with the default options, which compress with zstd
+	defaultOptions := binary.BinaryBackupOptions{Compression: entities.ZstdCompression, CompressionLevel: binary.DefaultCompressionLevel}
+	testExec(t, db, "UPDATE events SET payload = 'updated' WHERE id BETWEEN 100 AND 110")
+	testExec(t, db, "DELETE FROM events WHERE id BETWEEN 500 AND 510")
+	testBackupHandler(db, nonePath, defaultOptions).SnapshotBackup("", 1, false)
+	secondRows := testTableRows(t, db, "events")
+
+	assert.Equal(t, entities.NoCompression, testBackupMetadata(t, nonePath).Compression, "Compression of the uncompressed backup after a snapshot")
+	assert.True(t, testVerify(nonePath, defaultOptions), "Verify the uncompressed backup")
+	snapshots := testSnapshots(t, nonePath, defaultOptions)
+	if !assert.Len(t, snapshots, 2, "Snapshots of the uncompressed backup") {
+		return
+	}
+	assert.Equal(t, firstRows, testTableRows(t, testRestore(t, nonePath, defaultOptions, &snapshots[0].SnapshotId), "events"), "Restore of the first uncompressed snapshot")
+	assert.Equal(t, secondRows, testTableRows(t, testRestore(t, nonePath, defaultOptions, nil), "events"), "Restore of the last uncompressed snapshot")
+	assert.Equal(t, firstRows, testTableRows(t, testRestore(t, zstdPath, noneOptions, nil), "events"), "Restore of the zstd backup")
+}
+
+// testSmallBatches lowers the max-length of the batches, so a few records are split into many batches and chunks.
+func testSmallBatches(t *testing.T, maxBatchLength int) {
+	prevMaxBatchLength := entities.MAX_BATCH_LENGTH
+	entities.MAX_BATCH_LENGTH = maxBatchLength
+	t.Cleanup(func() { entities.MAX_BATCH_LENGTH = prevMaxBatchLength })
+}
+
+func setupSQLiteTestDatabase(t *testing.T, dbName string, statements ...string) *sql.DB {
+	db, err := sql.Open("sqlite", filepath.Join(t.TempDir(), dbName))
+	if err != nil {
+		t.Fatalf("failed to open sqlite database: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	for _, statement := range statements {
+		testExec(t, db, statement)
+	}
+	return db
+}
+
+func testExec(t *testing.T, db *sql.DB, statement string, args ...interface{}) {
+	if _, err := db.Exec(statement, args...); err != nil {
+		t.Fatalf("could not run statement %s: %v", statement, err)
+	}
+}
+
+// testTableRows returns every row of the table in rowid order, with its values joined in a single string.
+func testTableRows(t *testing.T, db *sql.DB, table string) []string {
+	rows, err := db.Query(fmt.Sprintf("SELECT * FROM %s ORDER BY rowid", table))
+	if err != nil {
+		t.Fatalf("could not read %s table: %v", table, err)
+	}
+	defer rows.Close()
+
+	columns, err := rows.Columns()
+	if err != nil {
+		t.Fatalf("could not read %s table columns: %v", table, err)
+	}
+
+	tableRows := []string{}
+	for rows.Next() {
+		values := make([]interface{}, len(columns))
+		valuesPtrs := make([]interface{}, len(columns))
+		for i := range values {
+			valuesPtrs[i] = &values[i]
+		}
+		if err := rows.Scan(valuesPtrs...); err != nil {
+			t.Fatalf("could not read %s table row: %v", table, err)
+		}
+
+		row := make([]string, len(values))
+		for i, value := range values {
+			row[i] = fmt.Sprintf("%v", value)
+		}
+		tableRows = append(tableRows, strings.Join(row, "|"))
+	}
+	return tableRows
+}
+
+func testLogger() *logrus.Logger {
+	return &logrus.Logger{Out: io.Discard, Level: logrus.InfoLevel, Formatter: &logrus.TextFormatter{}}
+}
+
+// testBackupHandler returns the handler of a backup command run against the SQLite database, which opens the backup again
+// as every command does.
+func testBackupHandler(db *sql.DB, backupPath string, options binary.BinaryBackupOptions) *handlers.BackupHandler {
+	backupFactory := binary.NewBinaryBackupFactory(local.NewLocalStorage(backupPath), options)
+	return handlers.NewBackupHandler(usecases.NewBackupUsecasesImpl(sqlite.NewSQLiteDatabaseFactory(db), backupFactory, testLogger()))
+}
+
+// testBackupHandlerWithFactory returns the handler of a backup command run against the DB of the factory.
+func testBackupHandlerWithFactory(dbFactory database_services.DatabaseFactory, backupPath string, options binary.BinaryBackupOptions) *handlers.BackupHandler {
+	backupFactory := binary.NewBinaryBackupFactory(local.NewLocalStorage(backupPath), options)
+	return handlers.NewBackupHandler(usecases.NewBackupUsecasesImpl(dbFactory, backupFactory, testLogger()))
+}
+
+// testParallelDatabaseFactory creates parallel readers and writers of the SQLite database, each one with its own connection,
+// so several schemas are saved or restored at once. It counts the parallel readers and writers it creates, and the parallel
+// writers fail to save the records of failingSchema.
+type testParallelDatabaseFactory struct {
+	database_services.DatabaseFactory
+	db              *sql.DB
+	failingSchema   string
+	parallelReaders int
+	parallelWriters int
+}
+
+func (factory *testParallelDatabaseFactory) CreateParallelReader() (database_services.DatabaseReader, error) {
+	factory.parallelReaders++
+	return sqlite.NewSQLiteDatabaseReader(factory.db), nil
+}
+
+func (factory *testParallelDatabaseFactory) CreateParallelWriter() (database_services.DatabaseWriter, error) {
+	factory.parallelWriters++
+	return &testFailingDatabaseWriter{DatabaseWriter: sqlite.NewSQLiteDatabaseWriter(factory.db), failingSchema: factory.failingSchema}, nil
+}
+
+type testFailingDatabaseWriter struct {
+	database_services.DatabaseWriter
+	failingSchema string
+}
+
+func (writer *testFailingDatabaseWriter) SaveSchemaRecords(schema entities.Schema, chunk entities.SchemaRecordChunk) error {
+	if schema.GetName() == writer.failingSchema {
+		return errors.New("disk full")
+	}
+	return writer.DatabaseWriter.SaveSchemaRecords(schema, chunk)
+}
+
+// testInterruptedDatabaseFactory counts the record chunks read from the DB, and fails to read any chunk after maxChunks of
+// them as if the connection to the DB was lost. It never fails if maxChunks is 0.
+type testInterruptedDatabaseFactory struct {
+	database_services.DatabaseFactory
+	maxChunks int
+	chunks    int
+}
+
+func (factory *testInterruptedDatabaseFactory) CreateReader() database_services.DatabaseReader {
+	return &testInterruptedDatabaseReader{DatabaseReader: factory.DatabaseFactory.CreateReader(), factory: factory}
+}
+
+type testInterruptedDatabaseReader struct {
+	database_services.DatabaseReader
+	factory *testInterruptedDatabaseFactory
+}
+
+func (reader *testInterruptedDatabaseReader) GetSchemaRecordChunk(schema entities.Schema, chunkSize int64, chunkCursor interface{}) (entities.SchemaRecordChunk, interface{}, error) {
+	if reader.factory.maxChunks > 0 && reader.factory.chunks >= reader.factory.maxChunks {
+		return nil, nil, errors.New("connection lost")
+	}
+	reader.factory.chunks++
+	return reader.DatabaseReader.GetSchemaRecordChunk(schema, chunkSize, chunkCursor)
+}
+
+// testRestore restores the snapshot, or the last one if it is nil, into a new SQLite database.
+func testRestore(t *testing.T, backupPath string, options binary.BinaryBackupOptions, snapshotId *string) *sql.DB {
+	db := setupSQLiteTestDatabase(t, "restore.db")
+	backupFactory := binary.NewBinaryBackupFactory(local.NewLocalStorage(backupPath), options)
+	restoreHandler := handlers.NewRestoreHandler(usecases.NewRestoreUsecasesImpl(sqlite.NewSQLiteDatabaseFactory(db), backupFactory, testLogger()))
+	restoreHandler.RestoreDatabase(snapshotId, 1, entities.RestoreCommitAll, false)
+	return db
+}
+
+// testVerify checks the backup at the deep level, rebuilding every object of every snapshot.
+func testVerify(backupPath string, options binary.BinaryBackupOptions) bool {
+	backupFactory := binary.NewBinaryBackupFactory(local.NewLocalStorage(backupPath), options)
+	return handlers.NewVerifyHandler(usecases.NewVerifyUsecasesImpl(backupFactory, testLogger())).VerifyBackup(true)
+}
+
+// testBackupMetadata returns the metadata of the backup as it is saved in its storage.
+func testBackupMetadata(t *testing.T, backupPath string) entities.BackupMetadata {
+	metadata, err := binary.NewBinaryBackupReader(local.NewLocalStorage(backupPath), nil).GetBackupMetadata()
+	if err != nil {
+		t.Fatalf("could not read backup metadata: %v", err)
+	}
+	return metadata
+}
+
+// testDirSize returns the size of every file in the directory and its subdirectories.
+func testDirSize(t *testing.T, path string) int64 {
+	var size int64
+	err := filepath.WalkDir(path, func(_ string, entry fs.DirEntry, err error) error {
+		if err != nil || entry.IsDir() {
+			return err
+		}
+		info, err := entry.Info()
+		if err != nil {
+			return err
+		}
+		size += info.Size()
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("could not read directory size: %v", err)
+	}
+	return size
+}
+
+// testSnapshots returns every committed snapshot of the backup, from the oldest to the newest.
+func testSnapshots(t *testing.T, backupPath string, options binary.BinaryBackupOptions) []entities.BackupSnapshot {
+	backupReader := binary.NewBinaryBackupReader(local.NewLocalStorage(backupPath), options.Passphrase)
+	backupMetadata, err := backupReader.GetBackupMetadata()
+	if err != nil {
+		t.Fatalf("could not read backup metadata: %v", err)
+	}
+
+	snapshots := []entities.BackupSnapshot{}
+	for _, metadataSnapshot := range backupMetadata.Snapshots {
+		snapshot, err := backupReader.GetBackupSnapshot(metadataSnapshot.SnapshotId)
+		if err != nil {
+			t.Fatalf("could not read backup snapshot: %v", err)
+		}
+		snapshots = append(snapshots, snapshot)
+	}
+	return snapshots
+}
@@ -0,0 +1,82 @@
+package test
+
+import (
+	"database/sql"
+	"fmt"
+	"historydb/src/internal/entities"
+	"historydb/src/internal/handlers"
+	"historydb/src/internal/services/backup/binary"
+	database_services "historydb/src/internal/services/database"
+	"historydb/src/internal/services/database/sqlite"
+	"historydb/src/internal/services/storage/local"
+	"historydb/src/internal/usecases"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParallelRestoreRollback(t *testing.T) {
+	testSmallBatches(t, 200)
+
+	db := setupSQLiteTestDatabase(t, "source.db",
+		"CREATE TABLE users (id INTEGER PRIMARY KEY, name TEXT UNIQUE)",
+		"CREATE TABLE orders (id INTEGER PRIMARY KEY, user_id INTEGER REFERENCES users (id), total INTEGER)",
+		"CREATE TABLE events (name TEXT, amount INTEGER)",
+		"CREATE INDEX events_name ON events (name)",
+	)
+	for i := 1; i <= 1000; i++ {
+		testExec(t, db, "INSERT INTO users (id, name) VALUES (?, ?)", i, fmt.Sprintf("user-%d", i))
+		testExec(t, db, "INSERT INTO orders (id, user_id, total) VALUES (?, ?, ?)", i, i, i*3)
+		testExec(t, db, "INSERT INTO events (rowid, name, amount) VALUES (?, ?, ?)", i, fmt.Sprintf("event-%d", i), i*10)
+	}
+	backupPath := filepath.Join(t.TempDir(), "backup")
+	options := binary.BinaryBackupOptions{Compression: entities.NoCompression}
+	testBackupHandler(db, backupPath, options).CreateBackup("", 1, false)
+
+	// The workers write into the target from their own connections, waiting for each other as SQLite has a single writer
+	restoredDB, err := sql.Open("sqlite", filepath.Join(t.TempDir(), "restore.db")+"?_pragma=busy_timeout(10000)")
+	if err != nil {
+		t.Fatalf("failed to open sqlite database: %v", err)
+	}
+	t.Cleanup(func() { restoredDB.Close() })
+
+	// The restore fails while a worker saves the records of orders, once the tables are committed for the workers
+	failing := &testParallelDatabaseFactory{DatabaseFactory: sqlite.NewSQLiteDatabaseFactory(restoredDB), db: restoredDB, failingSchema: "orders"}
+	testRestoreHandlerWithFactory(failing, backupPath, options).RestoreDatabase(nil, 3, entities.RestoreCommitAll, false)
+	assert.Equal(t, 3, failing.parallelWriters, "Parallel writers of the workers")
+	assert.Empty(t, testObjectNames(t, restoredDB), "Objects left in the target after the rollback")
+
+	// The clean target can be restored again
+	restored := &testParallelDatabaseFactory{DatabaseFactory: sqlite.NewSQLiteDatabaseFactory(restoredDB), db: restoredDB}
+	testRestoreHandlerWithFactory(restored, backupPath, options).RestoreDatabase(nil, 3, entities.RestoreCommitAll, false)
+	assert.Equal(t, testObjectNames(t, db), testObjectNames(t, restoredDB), "Objects of the restored database")
+	for _, table := range []string{"users", "orders", "events"} {
+		assert.Equal(t, testTableRows(t, db, table), testTableRows(t, restoredDB, table), fmt.Sprintf("Restore of %s", table))
+	}
+}
+
+// testRestoreHandlerWithFactory returns the handler of a restore command run against the DB of the factory.
+func testRestoreHandlerWithFactory(dbFactory database_services.DatabaseFactory, backupPath string, options binary.BinaryBackupOptions) *handlers.RestoreHanlder {
+	backupFactory := binary.NewBinaryBackupFactory(local.NewLocalStorage(backupPath), options)
+	return handlers.NewRestoreHandler(usecases.NewRestoreUsecasesImpl(dbFactory, backupFactory, testLogger()))
+}
+
+// testObjectNames returns the type and name of every table, index, view and trigger of the database.
+func testObjectNames(t *testing.T, db *sql.DB) []string {
+	rows, err := db.Query("SELECT type, name FROM sqlite_master WHERE name NOT LIKE 'sqlite_%' ORDER BY type, name")
+	if err != nil {
+		t.Fatalf("could not read database objects: %v", err)
+	}
+	defer rows.Close()
+
+	objectNames := []string{}
+	for rows.Next() {
+		var objectType, name string
+		if err := rows.Scan(&objectType, &name); err != nil {
+			t.Fatalf("could not read database object: %v", err)
+		}
+		objectNames = append(objectNames, objectType+" "+name)
+	}
+	return objectNames
+}
@@ -0,0 +1,40 @@
+package handlers
+
+import (
+	"historydb/src/internal/entities"
+	"historydb/src/internal/usecases"
+)
+
+type VerifyHandler struct {
+	verifyUc usecases.VerifyUsecases
+}
+
+func NewVerifyHandler(verifyUc usecases.VerifyUsecases) *VerifyHandler {
+	return &VerifyHandler{verifyUc}
+}
+
+// VerifyBackup checks the integrity hash of every file of the backup and, at the deep level, rebuilds every object of every
+// snapshot and looks for the objects no snapshot references. It returns whether the backup is intact.
+func (handler *VerifyHandler) VerifyBackup(deep bool) bool {
+	metadata := handler.verifyUc.GetBackupMetadata()
+	if metadata == nil {
+		return false
+	}
+
+	ok := handler.verifyUc.VerifyBackupFiles()
+	if !deep {
+		return ok
+	}
+
+	snapshots := make([]entities.BackupSnapshot, 0, len(metadata.Snapshots))
+	for _, snapshotMetadata := range metadata.Snapshots {
+		snapshot, snapshotOk := handler.verifyUc.VerifySnapshot(snapshotMetadata)
+		if snapshot != nil {
+			snapshots = append(snapshots, *snapshot)
+		}
+		ok = ok && snapshotOk
+	}
+
+	objectsOk := handler.verifyUc.VerifyBackupObjects(snapshots, len(snapshots) == len(metadata.Snapshots))
+	return ok && objectsOk
+}
@@ -12,6 +12,10 @@ import "historydb/src/internal/entities"
 // GetSchemaRecordChunkRefsInBatch() -> Retrieves a list of the chunk references contained by a batch from it backup file.
 // GetSchemaRecordChunk() -> Retrieves a data chunk from its backup files.
 // GetRoutine() -> Retrieves the current state of a routine from its backup files.
+// ListBackupFiles() -> Lists every file of the backup, leaving out its locks and staged snapshots.
+// CheckBackupFile() -> Checks the integrity of a backup file without decoding its content.
+// ListBackupObjects() -> Lists the snapshot files and objects saved in the backup, whether they are packed or not.
+// ListReferencedObjects() -> Lists the snapshot files and objects referenced by the snapshots, including the previous objects of their diff chains.
 type BackupReader interface {
 	CheckBackupExists() bool
 	GetBackupMetadata() (entities.BackupMetadata, error)
@@ -21,4 +25,8 @@ type BackupReader interface {
 	GetSchemaRecordChunkRefsInBatch(batchRef string) ([]string, error)
 	GetSchemaRecordChunk(batchRef, chunkRef string) (entities.SchemaRecordChunk, bool, error)
 	GetRoutine(routineRef string) (entities.Routine, bool, error)
+	ListBackupFiles() ([]string, error)
+	CheckBackupFile(name string) error
+	ListBackupObjects() ([]string, error)
+	ListReferencedObjects(snapshots []entities.BackupSnapshot) ([]string, error)
 }
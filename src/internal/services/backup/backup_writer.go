@@ -9,6 +9,13 @@ import "historydb/src/internal/entities"
 // BeginSnapshot() -> Begins a transaction for saving all the new snapshot content.
 // CommitSnapshot() -> Commits the previous transaction.
 // RollbackSnapshot() -> Rollbacks the previous transaction.
+// RecoverSnapshots() -> Completes the transactions whose commit was interrupted and discards the unfinished ones, returning the committed and discarded snapshot ids. Unfinished transactions with a checkpoint are kept to be resumed unless discardResumable is true. Requires the exclusive lock of the backup.
+// PendingSnapshots() -> Returns the snapshot ids of the transactions whose commit was interrupted and of the unfinished ones, without changing the backup.
+// ResumeSnapshot() -> Begins again the last transaction interrupted before its commit as the given snapshot, returning its checkpoint, or nil if there is none.
+// SaveSnapshotProgress() -> Saves the progress of the records of a schema into the checkpoint of the transaction, so it can be resumed if it is interrupted.
+// CanResumeSnapshot() -> Returns whether the transaction saved a checkpoint, so it is kept to be resumed once it is rolled back.
+// LockBackup() -> Locks the backup, exclusively if it is changed, so no other process changes it meanwhile. Returns the stale locks which were removed.
+// UnlockBackup() -> Releases the lock of the backup.
 // SaveSchemaDependency() -> Saves a schema dependency into the transaction previously created.
 // SaveSchemaDependencyDiff() -> Saves a schema dependency reduced version with its updates from the last state.
 // SaveSchema() -> Saves a schema definition into the transaction previously created.
@@ -18,6 +25,9 @@ import "historydb/src/internal/entities"
 // SaveSchemaRecordBatch() -> Saves a complete schema record batch with all its chunks.
 // SaveRoutine() -> Saves a database routine into the transaction previously created.
 // SaveSchemaRoutineDiff() -> Saves a database routine reduced version with its updates from the last state.
+// RotateBackupKey() -> Wraps the data key of an encrypted backup with a new passphrase.
+// DeleteSnapshots() -> Deletes snapshots from the backup, re-basing the objects the rest of snapshots need onto full objects, and removes the files no snapshot needs.
+// CollectGarbage() -> Removes the files of the backup which no snapshot references, or only lists them if dryRun is true.
 type BackupWriter interface {
 	CreateBackupStructure() error
 	DeleteBackupStructure() error
@@ -25,6 +35,14 @@ type BackupWriter interface {
 	BeginSnapshot(snapshot *entities.BackupSnapshot) error
 	CommitSnapshot(metadata *entities.BackupMetadata) error
 	RollbackSnapshot() error
+	RecoverSnapshots(discardResumable bool) ([]string, []string, error)
+	PendingSnapshots() ([]string, []string, error)
+	ResumeSnapshot(snapshot *entities.BackupSnapshot) (*entities.BackupCheckpoint, error)
+	SaveSnapshotProgress(schemaName string, progress *entities.BackupSchemaProgress) error
+	CanResumeSnapshot() bool
+
+	LockBackup(exclusive bool) ([]entities.BackupLock, error)
+	UnlockBackup() error
 
 	SaveSchemaDependency(dependency entities.SchemaDependency) error
 	SaveSchemaDependencyDiff(diff entities.SchemaDependencyDiff) error
@@ -38,4 +56,8 @@ type BackupWriter interface {
 
 	SaveRoutine(routine entities.Routine) error
 	SaveRoutineDiff(diff entities.RoutineDiff) error
+
+	RotateBackupKey(newPassphrase []byte) error
+	DeleteSnapshots(snapshotIds []string) (entities.BackupSweep, error)
+	CollectGarbage(dryRun bool) (entities.BackupSweep, error)
 }
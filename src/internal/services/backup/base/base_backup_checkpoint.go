@@ -0,0 +1,95 @@
+package base
+
+import (
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"historydb/src/internal/entities"
+	"historydb/src/internal/services"
+	"historydb/src/internal/utils/crypto"
+	"io/fs"
+	"os"
+	"path/filepath"
+
+	"github.com/google/uuid"
+)
+
+// CheckpointName is the file of a transaction directory which records the progress of the snapshot, so the snapshot can
+// be resumed if it is interrupted. It is not a file of the backup, so it is never committed.
+const CheckpointName = "snapshot.checkpoint"
+
+// SaveCheckpoint saves the progress of the records of a schema into the checkpoint of the snapshot in progress. The
+// checkpoint is written with a temporary name and renamed, so an interrupted write keeps the previous checkpoint.
+func (writer *BaseBackupWriter) SaveCheckpoint(schemaName string, progress *entities.BackupSchemaProgress) error {
+	writer.checkpointMu.Lock()
+	defer writer.checkpointMu.Unlock()
+
+	if writer.TxSnapshot == nil || writer.Checkpoint == nil {
+		return services.ErrBackupTransactionNotFound
+	}
+
+	// The transaction directory is only created once its first file is saved
+	if err := os.MkdirAll(writer.TransactionPath(), 0755); err != nil {
+		return err
+	}
+	writer.Checkpoint.Schemas[schemaName] = progress
+	return writeTransactionFile(writer.TransactionPath(), CheckpointName, writer.Checkpoint.EncodeToBytes())
+}
+
+// CanResumeSnapshot returns whether the snapshot in progress saved a checkpoint, so it can be resumed once it is rolled back.
+func (writer *BaseBackupWriter) CanResumeSnapshot() bool {
+	return writer.TxSnapshot != nil && fileExists(filepath.Join(writer.TransactionPath(), CheckpointName))
+}
+
+// FindCheckpoint returns the checkpoint of the last snapshot which was interrupted before its commit began, or nil if
+// there is no snapshot to resume.
+func (writer *BaseBackupWriter) FindCheckpoint() (*entities.BackupCheckpoint, error) {
+	entries, err := os.ReadDir(writer.Storage.StagingPath())
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	var last *entities.BackupCheckpoint
+	for _, entry := range entries {
+		if !entry.IsDir() || uuid.Validate(entry.Name()) != nil {
+			continue
+		}
+
+		// The transactions whose commit began are completed by RecoverSnapshots instead
+		transactionDir := filepath.Join(writer.Storage.StagingPath(), entry.Name())
+		if fileExists(filepath.Join(transactionDir, commitIntentName)) {
+			continue
+		}
+		data, err := os.ReadFile(filepath.Join(transactionDir, CheckpointName))
+		if errors.Is(err, fs.ErrNotExist) {
+			continue
+		} else if err != nil {
+			return nil, err
+		}
+
+		if len(data) < sha256.Size || !crypto.CheckDataSignature(data[:sha256.Size], data[sha256.Size:]) {
+			return nil, fmt.Errorf("%w: %s", services.ErrBackupCorruptedFile, filepath.Join(transactionDir, CheckpointName))
+		}
+		var checkpoint entities.BackupCheckpoint
+		if err := checkpoint.DecodeFromBytes(data[sha256.Size:]); err != nil {
+			return nil, err
+		}
+		if last == nil || checkpoint.Timestamp.After(last.Timestamp) {
+			last = &checkpoint
+		}
+	}
+
+	return last, nil
+}
+
+// DiscardCheckpoint removes the transaction directory of an interrupted snapshot, so it is no longer resumed.
+func (writer *BaseBackupWriter) DiscardCheckpoint(checkpoint *entities.BackupCheckpoint) error {
+	return os.RemoveAll(filepath.Join(writer.Storage.StagingPath(), checkpoint.SnapshotId))
+}
+
+func fileExists(pathToFile string) bool {
+	_, err := os.Stat(pathToFile)
+	return err == nil
+}
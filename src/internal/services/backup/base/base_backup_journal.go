@@ -0,0 +1,292 @@
+package base
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"historydb/src/internal/services"
+	"historydb/src/internal/utils/crypto"
+	"historydb/src/internal/utils/decode"
+	"historydb/src/internal/utils/encode"
+	"historydb/src/internal/utils/pointers"
+	"io/fs"
+	"os"
+	"path/filepath"
+
+	"github.com/google/uuid"
+)
+
+// commitIntentName is the file of a transaction directory which records that the snapshot is being committed. It is only
+// written once every file of the snapshot is staged and synced, so a transaction with an intent is always completed when
+// it is recovered, while a transaction without one is discarded.
+const commitIntentName = "commit.intent"
+
+// commitIntent defines the steps left to commit a snapshot
+//
+// SnapshotId -> The snapshot being committed
+// Files -> The names of the staged files, in the order they are committed into the storage
+// Metadata -> The encoded metadata which lists the snapshot, put into the storage once every file is committed
+// Deletes -> The names of the files of the backup deleted once the metadata is put, in the order they are deleted
+type commitIntent struct {
+	SnapshotId string
+	Files      []string
+	Metadata   []byte
+	Deletes    []string
+}
+
+func (intent *commitIntent) EncodeToBytes() []byte {
+	var buf bytes.Buffer
+	encode.EncodeString(&buf, &intent.SnapshotId)
+	encode.EncodeBytes(&buf, intent.Metadata)
+	encode.EncodeInt(&buf, pointers.Ptr(int64(len(intent.Files))))
+	for _, name := range intent.Files {
+		encode.EncodeString(&buf, &name)
+	}
+	encode.EncodeInt(&buf, pointers.Ptr(int64(len(intent.Deletes))))
+	for _, name := range intent.Deletes {
+		encode.EncodeString(&buf, &name)
+	}
+
+	integrityHash := sha256.Sum256(buf.Bytes())
+	return append(integrityHash[:], buf.Bytes()...)
+}
+
+func (intent *commitIntent) DecodeFromBytes(data []byte) error {
+	if len(data) < sha256.Size || !crypto.CheckDataSignature(data[:sha256.Size], data[sha256.Size:]) {
+		return services.ErrBackupCorruptedFile
+	}
+	buf := bytes.NewBuffer(data[sha256.Size:])
+
+	snapshotId, err := decode.DecodeString(buf)
+	if err != nil {
+		return err
+	}
+	metadata, err := decode.DecodeBytes(buf)
+	if err != nil {
+		return err
+	}
+	count, err := decode.DecodeInt(buf)
+	if err != nil {
+		return err
+	}
+	files, err := decodeIntentNames(buf, *count)
+	if err != nil {
+		return err
+	}
+
+	// Intents written before files could be deleted along with the commit end after their files
+	deletes := []string{}
+	if buf.Len() > 0 {
+		count, err := decode.DecodeInt(buf)
+		if err != nil {
+			return err
+		}
+		if deletes, err = decodeIntentNames(buf, *count); err != nil {
+			return err
+		}
+	}
+
+	intent.SnapshotId = *snapshotId
+	intent.Files = files
+	intent.Metadata = metadata
+	intent.Deletes = deletes
+	return nil
+}
+
+// decodeIntentNames decodes count file names of a commit intent.
+func decodeIntentNames(buf *bytes.Buffer, count int64) ([]string, error) {
+	names := make([]string, 0, count)
+	for range count {
+		name, err := decode.DecodeString(buf)
+		if err != nil {
+			return nil, err
+		}
+		names = append(names, *name)
+	}
+	return names, nil
+}
+
+// CommitTransaction commits the files staged in the snapshot transaction, in the given order, and puts the metadata into
+// the storage, so the snapshot is only listed once all its files are saved. The files of the backup in deletes, which the
+// new metadata no longer needs, are deleted as the last step. The files and the intent to commit them are synced to disk
+// first, so the commit can be completed by RecoverSnapshots if it is interrupted.
+func (writer *BaseBackupWriter) CommitTransaction(files []string, metadata []byte, deletes []string) error {
+	if writer.TxSnapshot == nil {
+		return services.ErrBackupTransactionNotFound
+	}
+
+	transactionDir := writer.TransactionPath()
+	for _, name := range files {
+		if err := syncFile(filepath.Join(transactionDir, filepath.FromSlash(name))); err != nil {
+			return err
+		}
+	}
+
+	intent := commitIntent{SnapshotId: writer.TxSnapshot.SnapshotId, Files: files, Metadata: metadata, Deletes: deletes}
+	if err := writeTransactionFile(transactionDir, commitIntentName, intent.EncodeToBytes()); err != nil {
+		return err
+	}
+
+	if err := writer.applyCommitIntent(transactionDir, intent); err != nil {
+		return err
+	}
+	writer.TxSnapshot = nil
+	writer.Checkpoint = nil
+	return nil
+}
+
+// RecoverSnapshots completes the snapshots whose commit was interrupted, and discards the transactions which never began
+// their commit. The transactions with a checkpoint are kept to be resumed, unless discardResumable is true. It returns the
+// identifiers of the snapshots committed and discarded. It fails with ErrBackupLockNotExclusive unless the writer holds
+// the exclusive lock of the backup, as no other process may recover the same transactions meanwhile.
+func (writer *BaseBackupWriter) RecoverSnapshots(discardResumable bool) ([]string, []string, error) {
+	if writer.lock == nil || !writer.lock.lock.Exclusive {
+		return nil, nil, services.ErrBackupLockNotExclusive
+	}
+
+	snapshotIds, err := writer.listTransactions()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	committed, discarded := []string{}, []string{}
+	for _, snapshotId := range snapshotIds {
+		transactionDir := filepath.Join(writer.Storage.StagingPath(), snapshotId)
+		data, err := os.ReadFile(filepath.Join(transactionDir, commitIntentName))
+		if errors.Is(err, fs.ErrNotExist) {
+			if !discardResumable && fileExists(filepath.Join(transactionDir, CheckpointName)) {
+				continue
+			}
+			if err := os.RemoveAll(transactionDir); err != nil {
+				return nil, nil, err
+			}
+			discarded = append(discarded, snapshotId)
+			continue
+		} else if err != nil {
+			return nil, nil, err
+		}
+
+		var intent commitIntent
+		if err := intent.DecodeFromBytes(data); err != nil {
+			return nil, nil, fmt.Errorf("%w: %s", err, filepath.Join(transactionDir, commitIntentName))
+		}
+		if err := writer.applyCommitIntent(transactionDir, intent); err != nil {
+			return nil, nil, err
+		}
+		committed = append(committed, intent.SnapshotId)
+	}
+
+	return committed, discarded, nil
+}
+
+// PendingSnapshots returns the identifiers of the snapshots whose commit was interrupted and of the unfinished snapshots
+// left by an interrupted run, without changing the backup, so the processes which only read the backup can report them.
+func (writer *BaseBackupWriter) PendingSnapshots() ([]string, []string, error) {
+	snapshotIds, err := writer.listTransactions()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	committing, unfinished := []string{}, []string{}
+	for _, snapshotId := range snapshotIds {
+		if fileExists(filepath.Join(writer.Storage.StagingPath(), snapshotId, commitIntentName)) {
+			committing = append(committing, snapshotId)
+		} else {
+			unfinished = append(unfinished, snapshotId)
+		}
+	}
+	return committing, unfinished, nil
+}
+
+// listTransactions returns the identifiers of the snapshots with a transaction directory, other than the transaction in
+// progress of the writer.
+func (writer *BaseBackupWriter) listTransactions() ([]string, error) {
+	entries, err := os.ReadDir(writer.Storage.StagingPath())
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	snapshotIds := []string{}
+	for _, entry := range entries {
+		// Transaction directories are named by the snapshot identifier, as the rest of directories belong to the backup
+		if !entry.IsDir() || uuid.Validate(entry.Name()) != nil {
+			continue
+		}
+		if writer.TxSnapshot != nil && writer.TxSnapshot.SnapshotId == entry.Name() {
+			continue
+		}
+		snapshotIds = append(snapshotIds, entry.Name())
+	}
+	return snapshotIds, nil
+}
+
+// applyCommitIntent commits the files of the intent which are still staged, puts the metadata, deletes the files the
+// metadata no longer needs and removes the transaction directory. Every step can be repeated, so an interrupted commit is
+// applied again from the beginning, but only by one process at a time: the files are moved out of the transaction, so
+// the caller must hold the exclusive lock of the backup or be the process which staged the transaction.
+func (writer *BaseBackupWriter) applyCommitIntent(transactionDir string, intent commitIntent) error {
+	for _, name := range intent.Files {
+		stagedPath := filepath.Join(transactionDir, filepath.FromSlash(name))
+		if _, err := os.Stat(stagedPath); errors.Is(err, fs.ErrNotExist) {
+			// The file was already committed
+			continue
+		}
+
+		if err := writer.Storage.Commit(name, stagedPath); err != nil {
+			return err
+		}
+	}
+
+	if err := writer.Storage.Put("metadata.hdb", intent.Metadata); err != nil {
+		return err
+	}
+	for _, name := range intent.Deletes {
+		if err := writer.Storage.Delete(name); err != nil {
+			return err
+		}
+	}
+
+	if err := os.RemoveAll(transactionDir); err != nil {
+		return fmt.Errorf("failed to remove backup transaction dir %s: %w", transactionDir, err)
+	}
+	return nil
+}
+
+// writeTransactionFile writes a file of the transaction directory with a temporary name, syncs it and renames it, so it is
+// either missing or complete.
+func writeTransactionFile(transactionDir, name string, content []byte) error {
+	pathToFile := filepath.Join(transactionDir, name)
+	f, err := os.Create(pathToFile + ".tmp")
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(content); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+
+	if err := os.Rename(pathToFile+".tmp", pathToFile); err != nil {
+		return err
+	}
+	return syncFile(transactionDir)
+}
+
+// syncFile flushes a file, or the entries of a directory, to disk.
+func syncFile(pathToFile string) error {
+	f, err := os.Open(pathToFile)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	return f.Sync()
+}
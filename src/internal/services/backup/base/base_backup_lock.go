@@ -0,0 +1,169 @@
+package base
+
+import (
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"historydb/src/internal/entities"
+	"historydb/src/internal/services"
+	"historydb/src/internal/utils/crypto"
+	"io/fs"
+	"os"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// A backup is locked by saving a locks/<id>.lock file into its storage, so the processes which use the same backup from
+// several hosts are also kept apart. Exclusive locks are held by the processes which change the backup and shared locks by
+// the ones which only read it. A lock is kept fresh while it is held, so a lock which was not refreshed for
+// lockStaleTimeout, or whose process is gone from the host of the lock, is left by a process which was interrupted.
+const (
+	lockStaleTimeout    = 30 * time.Minute
+	lockRefreshInterval = 5 * time.Minute
+)
+
+// isStaleLock returns whether the owner of the lock is gone, as the lock was not refreshed for too long or its process no
+// longer runs. Only the processes of the current host can be checked.
+func isStaleLock(lock entities.BackupLock, host string) bool {
+	if time.Since(lock.Time) > lockStaleTimeout {
+		return true
+	}
+	if lock.Host != host {
+		return false
+	}
+
+	process, err := os.FindProcess(int(lock.PID))
+	if err != nil {
+		return true
+	}
+	// Signal 0 only checks the process exists, a process of another user is still running even if it cannot be signaled
+	return errors.Is(process.Signal(syscall.Signal(0)), os.ErrProcessDone)
+}
+
+// heldLock is the lock of the backup held by the writer, which is refreshed until it is released
+type heldLock struct {
+	name    string
+	lock    entities.BackupLock
+	stop    chan struct{}
+	stopped sync.WaitGroup
+	err     error
+}
+
+// LockBackup locks the backup, exclusively if the backup is changed. It fails with ErrBackupLocked if another process holds
+// an exclusive lock, or any lock when an exclusive one is requested. The stale locks found are removed, and they are
+// returned so they can be reported.
+func (writer *BaseBackupWriter) LockBackup(exclusive bool) ([]entities.BackupLock, error) {
+	if writer.lock != nil {
+		return nil, services.ErrBackupLockHeld
+	}
+
+	host, err := os.Hostname()
+	if err != nil {
+		return nil, err
+	}
+
+	stale, err := writer.checkLocks(exclusive, host, "")
+	if err != nil {
+		return nil, err
+	}
+
+	name := fmt.Sprintf("locks/%s.lock", uuid.NewString())
+	lock := entities.BackupLock{Exclusive: exclusive, PID: int64(os.Getpid()), Host: host, Time: time.Now().UTC()}
+	if err := writer.Storage.Put(name, lock.EncodeToBytes()); err != nil {
+		return nil, err
+	}
+
+	// Another process may have saved its lock at the same time, so the locks are checked again once this one is saved
+	if _, err := writer.checkLocks(exclusive, host, name); err != nil {
+		writer.Storage.Delete(name)
+		return nil, err
+	}
+
+	writer.lock = &heldLock{name: name, lock: lock, stop: make(chan struct{})}
+	writer.lock.stopped.Add(1)
+	go writer.refreshLock(writer.lock)
+	return stale, nil
+}
+
+// UnlockBackup releases the lock of the backup. It returns the error of the last refresh of the lock, if it failed.
+func (writer *BaseBackupWriter) UnlockBackup() error {
+	if writer.lock == nil {
+		return services.ErrBackupLockNotFound
+	}
+
+	held := writer.lock
+	writer.lock = nil
+	close(held.stop)
+	held.stopped.Wait()
+
+	if err := writer.Storage.Delete(held.name); err != nil {
+		return err
+	}
+	return held.err
+}
+
+// checkLocks removes the stale locks of the backup and fails if a lock other than ownName conflicts with the lock requested.
+func (writer *BaseBackupWriter) checkLocks(exclusive bool, host, ownName string) ([]entities.BackupLock, error) {
+	names, err := writer.Storage.List("locks/")
+	if err != nil {
+		return nil, err
+	}
+
+	stale := []entities.BackupLock{}
+	for _, name := range names {
+		if name == ownName || !strings.HasSuffix(name, ".lock") {
+			continue
+		}
+
+		data, err := writer.Storage.Get(name)
+		if errors.Is(err, fs.ErrNotExist) {
+			// The lock was released meanwhile
+			continue
+		} else if err != nil {
+			return nil, err
+		}
+		if len(data) < sha256.Size || !crypto.CheckDataSignature(data[:sha256.Size], data[sha256.Size:]) {
+			return nil, fmt.Errorf("%w: %s", services.ErrBackupCorruptedFile, name)
+		}
+		var lock entities.BackupLock
+		if err := lock.DecodeFromBytes(data[sha256.Size:]); err != nil {
+			return nil, fmt.Errorf("%w: %s", err, name)
+		}
+
+		if isStaleLock(lock, host) {
+			if err := writer.Storage.Delete(name); err != nil {
+				return nil, err
+			}
+			stale = append(stale, lock)
+			continue
+		}
+		if exclusive || lock.Exclusive {
+			return nil, fmt.Errorf("%w: %s", services.ErrBackupLocked, lock.String())
+		}
+	}
+
+	return stale, nil
+}
+
+// refreshLock saves the lock again with the current time every lockRefreshInterval, until the lock is released.
+func (writer *BaseBackupWriter) refreshLock(held *heldLock) {
+	defer held.stopped.Done()
+
+	ticker := time.NewTicker(lockRefreshInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-held.stop:
+			return
+		case <-ticker.C:
+			held.lock.Time = time.Now().UTC()
+			if err := writer.Storage.Put(held.name, held.lock.EncodeToBytes()); err != nil {
+				held.err = fmt.Errorf("could not refresh the lock of the backup: %w", err)
+			}
+		}
+	}
+}
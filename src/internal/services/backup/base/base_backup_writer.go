@@ -3,65 +3,51 @@ package base
 import (
 	"historydb/src/internal/entities"
 	"historydb/src/internal/services"
+	storage_services "historydb/src/internal/services/storage"
 	"os"
 	"path/filepath"
+	"sync"
 )
 
+// backupDirs are the directories of the files of a backup, which are the ones deleted along with the backup.
+var backupDirs = []string{"snapshots/", "schemas/", "data/", "chunks/", "routines/", "packs/"}
+
 type BaseBackupWriter struct {
-	BackupPath string
+	Storage    storage_services.Storage
 	TxSnapshot *entities.BackupSnapshot
+	Checkpoint *entities.BackupCheckpoint
+
+	lock         *heldLock
+	checkpointMu sync.Mutex
 }
 
+// CreateBackupStructure creates the local directory where the snapshots are staged. The directories of the backup files
+// are created by the storage once the files are committed into them.
 func (writer *BaseBackupWriter) CreateBackupStructure() error {
-	if err := os.MkdirAll(writer.BackupPath, 0755); err != nil {
-		return err
-	}
-
-	if err := os.Mkdir(filepath.Join(writer.BackupPath, "snapshots"), 0755); err != nil {
-		return err
-	}
-	if err := os.Mkdir(filepath.Join(writer.BackupPath, "schemas"), 0755); err != nil {
-		return err
-	}
-	if err := os.Mkdir(filepath.Join(writer.BackupPath, "schemas", "diffs"), 0755); err != nil {
-		return err
-	}
-	if err := os.Mkdir(filepath.Join(writer.BackupPath, "schemas", "dependencies"), 0755); err != nil {
-		return err
-	}
-	if err := os.Mkdir(filepath.Join(writer.BackupPath, "schemas", "dependencies", "diffs"), 0755); err != nil {
-		return err
-	}
-
-	if err := os.Mkdir(filepath.Join(writer.BackupPath, "data"), 0755); err != nil {
-		return err
-	}
-	if err := os.Mkdir(filepath.Join(writer.BackupPath, "data", "diffs"), 0755); err != nil {
-		return err
-	}
-
-	if err := os.Mkdir(filepath.Join(writer.BackupPath, "routines"), 0755); err != nil {
-		return err
-	}
-	return os.Mkdir(filepath.Join(writer.BackupPath, "routines", "diffs"), 0755)
+	return os.MkdirAll(writer.Storage.StagingPath(), 0755)
 }
 
 func (writer *BaseBackupWriter) DeleteBackupStructure() error {
-	entries, err := os.ReadDir(writer.BackupPath)
-	if err != nil {
-		return err
+	if writer.TxSnapshot != nil {
+		if err := os.RemoveAll(writer.TransactionPath()); err != nil {
+			return err
+		}
 	}
 
-	for _, entry := range entries {
-		if entry.IsDir() {
-			dirPath := filepath.Join(writer.BackupPath, entry.Name())
-			if err := os.RemoveAll(dirPath); err != nil {
+	for _, dir := range backupDirs {
+		names, err := writer.Storage.List(dir)
+		if err != nil {
+			return err
+		}
+
+		for _, name := range names {
+			if err := writer.Storage.Delete(name); err != nil {
 				return err
 			}
 		}
 	}
 
-	return nil
+	return writer.Storage.Delete("metadata.hdb")
 }
 
 func (writer *BaseBackupWriter) BeginSnapshot(snapshot *entities.BackupSnapshot) error {
@@ -69,7 +55,18 @@ func (writer *BaseBackupWriter) BeginSnapshot(snapshot *entities.BackupSnapshot)
 		return services.ErrBackupTransactionInProgress
 	}
 
+	// The transaction directory is created at once, as a transaction which stages no files still commits through it
+	if err := os.MkdirAll(filepath.Join(writer.Storage.StagingPath(), snapshot.SnapshotId), 0755); err != nil {
+		return err
+	}
+
 	writer.TxSnapshot = snapshot
+	writer.Checkpoint = &entities.BackupCheckpoint{
+		SnapshotId: snapshot.SnapshotId,
+		Timestamp:  snapshot.Timestamp,
+		Message:    snapshot.Message,
+		Schemas:    make(map[string]*entities.BackupSchemaProgress),
+	}
 	return nil
 }
 
@@ -78,12 +75,20 @@ func (writer *BaseBackupWriter) RollbackSnapshot() error {
 		return services.ErrBackupTransactionNotFound
 	}
 
-	transactionDir := filepath.Join(writer.BackupPath, writer.TxSnapshot.SnapshotId)
-
-	if err := os.RemoveAll(transactionDir); err != nil {
-		return err
+	// Once its commit intent is written the snapshot is committed, so it is left to be completed by RecoverSnapshots, and a
+	// snapshot with a checkpoint is left to be resumed
+	if !fileExists(filepath.Join(writer.TransactionPath(), commitIntentName)) && !fileExists(filepath.Join(writer.TransactionPath(), CheckpointName)) {
+		if err := os.RemoveAll(writer.TransactionPath()); err != nil {
+			return err
+		}
 	}
 
 	writer.TxSnapshot = nil
+	writer.Checkpoint = nil
 	return nil
 }
+
+// TransactionPath returns the local directory where the files of the snapshot in progress are staged.
+func (writer *BaseBackupWriter) TransactionPath() string {
+	return filepath.Join(writer.Storage.StagingPath(), writer.TxSnapshot.SnapshotId)
+}
@@ -1,27 +1,53 @@
 package binary
 
-import backup_services "historydb/src/internal/services/backup"
+import (
+	"historydb/src/internal/entities"
+	backup_services "historydb/src/internal/services/backup"
+	storage_services "historydb/src/internal/services/storage"
+)
+
+// BinaryBackupOptions defines how a new backup is encoded, and how the backup is opened if it is encrypted
+//
+// Compression -> The codec used to compress a new backup
+// CompressionLevel -> The level of the codec used to compress a new backup
+// Encrypt -> Whether a new backup is encrypted with a data key wrapped with the passphrase
+// Passphrase -> The passphrase of the backup, nil if none was provided
+// MaxChainDepth -> The maximum number of diffs applied to rebuild an object, saved into the backup metadata. 0 keeps the
+// depth saved in the metadata
+// ReverseDeltas -> Whether a new backup saves every snapshot in full, and the record chunks of the snapshot before it as
+// diffs of its chunks
+type BinaryBackupOptions struct {
+	Compression      entities.CompressionCodec
+	CompressionLevel int64
+	Encrypt          bool
+	Passphrase       []byte
+	MaxChainDepth    int64
+	ReverseDeltas    bool
+}
 
 type BinaryBackupFactory struct {
-	backupPath   string
+	storage      storage_services.Storage
+	options      BinaryBackupOptions
 	backupReader *BinaryBackupReader
 	backupWriter *BinaryBackupWriter
 }
 
-func NewBinaryBackupFactory(backupPath string) *BinaryBackupFactory {
-	return &BinaryBackupFactory{backupPath, nil, nil}
+// NewBinaryBackupFactory creates the factory of the backup kept in storage. The compression and encryption options are only
+// used to create a new backup, as an existing backup is always read and written with the codec saved in its metadata.
+func NewBinaryBackupFactory(storage storage_services.Storage, options BinaryBackupOptions) *BinaryBackupFactory {
+	return &BinaryBackupFactory{storage, options, nil, nil}
 }
 
 func (factory *BinaryBackupFactory) CreateReader() backup_services.BackupReader {
 	if factory.backupReader == nil {
-		factory.backupReader = NewBinaryBackupReader(factory.backupPath)
+		factory.backupReader = NewBinaryBackupReader(factory.storage, factory.options.Passphrase)
 	}
 	return factory.backupReader
 }
 
 func (factory *BinaryBackupFactory) CreateWriter() backup_services.BackupWriter {
 	if factory.backupWriter == nil {
-		factory.backupWriter = NewBinaryBackupWriter(factory.backupPath)
+		factory.backupWriter = NewBinaryBackupWriter(factory.storage, factory.options)
 	}
 	return factory.backupWriter
 }
@@ -0,0 +1,86 @@
+package binary
+
+import (
+	"fmt"
+	"historydb/src/internal/entities"
+	"historydb/src/internal/services"
+	"os"
+	"slices"
+
+	"github.com/google/uuid"
+)
+
+// CollectGarbage removes the files of the backup which no snapshot references, following the diff chains of the objects of
+// every snapshot, as the objects left by rolled back snapshots or the pack files left without an index. The pack files
+// holding unreferenced objects are packed again without them. With dryRun, the files are only listed.
+func (writer *BinaryBackupWriter) CollectGarbage(dryRun bool) (entities.BackupSweep, error) {
+	if writer.TxSnapshot != nil {
+		return entities.BackupSweep{}, services.ErrBackupTransactionInProgress
+	}
+
+	reader := NewBinaryBackupReader(writer.Storage, writer.options.Passphrase)
+	metadata, err := reader.GetBackupMetadata()
+	if err != nil {
+		return entities.BackupSweep{}, err
+	}
+	codec, err := reader.getPayloadCodec()
+	if err != nil {
+		return entities.BackupSweep{}, err
+	}
+
+	snapshots := make([]entities.BackupSnapshot, 0, len(metadata.Snapshots))
+	for _, snapshotMetadata := range metadata.Snapshots {
+		snapshot, err := reader.GetBackupSnapshot(snapshotMetadata.SnapshotId)
+		if err != nil {
+			return entities.BackupSweep{}, err
+		}
+		snapshots = append(snapshots, snapshot)
+	}
+	referenced, err := reader.ListReferencedObjects(snapshots)
+	if err != nil {
+		return entities.BackupSweep{}, err
+	}
+
+	writer.codec = codec
+	sweep, err := writer.planSweep(slices.Concat([]string{"snapshots/"}, backupObjectDirs), func(name string) bool {
+		_, ok := slices.BinarySearch(referenced, name)
+		return !ok
+	})
+	if err != nil {
+		return entities.BackupSweep{}, err
+	}
+	result := entities.BackupSweep{DeletedFiles: sweep.deletes, ReclaimedBytes: sweep.reclaimed}
+	if dryRun || len(sweep.deletes) == 0 {
+		return result, nil
+	}
+
+	// The objects packed again are committed as a snapshot transaction, so the files are only deleted once they are saved
+	if err := writer.BaseBackupWriter.BeginSnapshot(&entities.BackupSnapshot{SnapshotId: uuid.NewString()}); err != nil {
+		return entities.BackupSweep{}, err
+	}
+	writer.encryption = metadata.Encryption
+	writer.chunkStore = metadata.ChunkStore
+
+	if err := writer.collectGarbage(&metadata, sweep); err != nil {
+		if rollbackErr := writer.RollbackSnapshot(); rollbackErr != nil {
+			return entities.BackupSweep{}, fmt.Errorf("%w: %v", err, rollbackErr)
+		}
+		return entities.BackupSweep{}, err
+	}
+	return result, nil
+}
+
+// collectGarbage stages the objects packed again and commits the transaction which deletes the swept files.
+func (writer *BinaryBackupWriter) collectGarbage(metadata *entities.BackupMetadata, sweep objectSweep) error {
+	// No object may be packed again, but the commit intent is still written into the transaction directory
+	if err := os.MkdirAll(writer.TransactionPath(), 0755); err != nil {
+		return err
+	}
+	if err := writer.repackObjects(sweep); err != nil {
+		return err
+	}
+	if err := writer.packObjects(); err != nil {
+		return err
+	}
+	return writer.commitStagedFiles(metadata, sweep.deletes)
+}
@@ -0,0 +1,325 @@
+package binary
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"historydb/src/internal/entities"
+	"historydb/src/internal/services"
+	"historydb/src/internal/utils/decode"
+	"io"
+	"io/fs"
+	"maps"
+	"path"
+	"slices"
+	"strings"
+)
+
+// backupObjectDirs are the directories of the backup which hold the objects of its snapshots that are not packed.
+var backupObjectDirs = []string{"schemas/", "data/", "routines/", "chunks/"}
+
+// ListBackupFiles lists every file of the backup kept in storage, leaving out its locks and staged snapshots.
+func (reader *BinaryBackupReader) ListBackupFiles() ([]string, error) {
+	names := []string{"metadata.hdb"}
+	for _, prefix := range slices.Concat([]string{"snapshots/", "packs/"}, backupObjectDirs) {
+		files, err := reader.storage.List(prefix)
+		if err != nil {
+			return nil, err
+		}
+		names = append(names, files...)
+	}
+
+	slices.Sort(names)
+	return names, nil
+}
+
+// CheckBackupFile checks the integrity hash of a file of the backup, or authenticates it in encrypted backups. The objects
+// of a pack file are checked one by one through its index, and the batch files of backups without a chunk store, which
+// have no integrity hash of their own, are checked by decoding every chunk entry.
+func (reader *BinaryBackupReader) CheckBackupFile(name string) error {
+	codec, err := reader.getPayloadCodec()
+	if err != nil {
+		return err
+	}
+	if name == "metadata.hdb" {
+		_, err := reader.GetBackupMetadata()
+		return err
+	}
+
+	data, err := reader.storage.Get(name)
+	if err != nil {
+		return err
+	}
+
+	switch {
+	case strings.HasPrefix(name, "snapshots/"):
+		_, err = decodeBackupFile(codec, name, data, false)
+	case path.Ext(name) == ".pack":
+		err = reader.checkPackFile(codec, name, data)
+	case strings.HasPrefix(name, "data/") && !reader.chunkStore:
+		err = reader.checkBatchFile(name, data)
+	default:
+		_, err = decodeBackupFile(codec, name, data, true)
+	}
+	return err
+}
+
+// checkPackFile checks every object listed in the index of a pack file.
+func (reader *BinaryBackupReader) checkPackFile(codec *payloadCodec, name string, data []byte) error {
+	indexName := strings.TrimSuffix(name, ".pack") + ".idx"
+	indexData, err := reader.storage.Get(indexName)
+	if errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("%w: %s has no index", services.ErrBackupCorruptedFile, name)
+	} else if err != nil {
+		return err
+	}
+	objects, err := decodePackIndex(codec, indexName, indexData)
+	if err != nil {
+		return err
+	}
+
+	for objectName, location := range objects {
+		if location.Offset < 0 || location.Offset+location.Length > int64(len(data)) {
+			return fmt.Errorf("%w: %s in %s", services.ErrBackupCorruptedFile, objectName, name)
+		}
+		if _, err := decodeBackupFile(codec, objectName, data[location.Offset:location.Offset+location.Length], true); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// checkBatchFile checks that every chunk entry of a batch file of a backup without a chunk store can be decoded.
+func (reader *BinaryBackupReader) checkBatchFile(name string, data []byte) error {
+	f := bytes.NewReader(data)
+	if _, _, err := readBatchHeader(f, strings.HasPrefix(name, "data/diffs/")); err != nil {
+		return fmt.Errorf("%w: %s", services.ErrBackupCorruptedFile, name)
+	}
+
+	for {
+		entry, err := reader.readBatchEntry(f)
+		if err == io.EOF {
+			return nil
+		} else if err != nil {
+			return fmt.Errorf("%w: %s", services.ErrBackupCorruptedFile, name)
+		}
+		if _, err := entry.Bytes(); err != nil {
+			return err
+		}
+	}
+}
+
+// ListBackupObjects lists the snapshot files of the backup and every object of its snapshots, whether it is saved in its
+// own file or packed.
+func (reader *BinaryBackupReader) ListBackupObjects() ([]string, error) {
+	objects := make(map[string]bool)
+	for _, prefix := range slices.Concat([]string{"snapshots/"}, backupObjectDirs) {
+		names, err := reader.storage.List(prefix)
+		if err != nil {
+			return nil, err
+		}
+		for _, name := range names {
+			objects[name] = true
+		}
+	}
+
+	packs, err := reader.getPackIndex()
+	if err != nil {
+		return nil, err
+	}
+	for name := range packs {
+		objects[name] = true
+	}
+
+	return slices.Sorted(maps.Keys(objects)), nil
+}
+
+// ListReferencedObjects lists the snapshot files of the snapshots and every object they reference, following the diff
+// chains of the objects through their previous objects. Objects missing from the backup are listed, but their chains can
+// not be followed any further.
+func (reader *BinaryBackupReader) ListReferencedObjects(snapshots []entities.BackupSnapshot) ([]string, error) {
+	chunkStore, err := reader.usesChunkStore()
+	if err != nil {
+		return nil, err
+	}
+
+	referenced := make(map[string]bool)
+	for _, snapshot := range snapshots {
+		referenced[path.Join("snapshots", fmt.Sprintf("%s.hdb", snapshot.SnapshotId))] = true
+		for _, ref := range snapshot.SchemaDependencies {
+			if err := reader.referenceDiffChain(referenced, "schemas/dependencies", ref); err != nil {
+				return nil, err
+			}
+		}
+		for _, ref := range snapshot.Schemas {
+			if err := reader.referenceDiffChain(referenced, "schemas", ref); err != nil {
+				return nil, err
+			}
+		}
+		for _, ref := range snapshot.Routines {
+			if err := reader.referenceDiffChain(referenced, "routines", ref); err != nil {
+				return nil, err
+			}
+		}
+
+		for _, schemaData := range snapshot.Data {
+			for _, batchRef := range schemaData.Data {
+				if chunkStore {
+					err = reader.referenceManifestChain(referenced, batchRef)
+				} else {
+					err = reader.referenceBatchChain(referenced, batchRef)
+				}
+				if err != nil {
+					return nil, err
+				}
+			}
+		}
+	}
+
+	return slices.Sorted(maps.Keys(referenced)), nil
+}
+
+// referenceDiffChain references the object ref of the directory dir, and the previous objects of its diff chain.
+func (reader *BinaryBackupReader) referenceDiffChain(referenced map[string]bool, dir, ref string) error {
+	for {
+		name := path.Join(dir, fmt.Sprintf("%s.hdb", ref))
+		if referenced[name] {
+			return nil
+		}
+		referenced[name] = true
+		if !strings.HasPrefix(ref, "diffs") {
+			return nil
+		}
+
+		content, err := reader.readFile(name, true)
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil
+		} else if err != nil {
+			return err
+		}
+		prevRef, err := decode.DecodeString(bytes.NewBuffer(content))
+		if err != nil {
+			return err
+		}
+		ref = *prevRef
+	}
+}
+
+// referenceManifestChain references the manifest of a batch, the chunks it lists and the previous manifests of its diff
+// chain, in backups with a chunk store.
+func (reader *BinaryBackupReader) referenceManifestChain(referenced map[string]bool, batchRef string) error {
+	for {
+		name := path.Join("data", fmt.Sprintf("%s.hdb", batchRef))
+		if referenced[name] {
+			return nil
+		}
+		referenced[name] = true
+
+		content, err := reader.readFile(name, true)
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil
+		} else if err != nil {
+			return err
+		}
+		var manifest batchManifest
+		if err := manifest.DecodeFromBytes(content, strings.HasPrefix(batchRef, "diffs")); err != nil {
+			return err
+		}
+
+		for _, entry := range manifest.Entries {
+			if entry.Hash == nil {
+				continue
+			}
+			if err := reader.referenceChunkChain(referenced, *entry.Hash); err != nil {
+				return err
+			}
+		}
+		if manifest.PrevBatchRef == nil {
+			return nil
+		}
+		batchRef = *manifest.PrevBatchRef
+	}
+}
+
+// referenceChunkChain references a chunk of the chunk store, and the previous chunks of its diff chain.
+func (reader *BinaryBackupReader) referenceChunkChain(referenced map[string]bool, chunkRef string) error {
+	for {
+		name := chunkObjectName(reader.codec.chunkRef(chunkRef))
+		if referenced[name] {
+			return nil
+		}
+		referenced[name] = true
+
+		content, err := reader.readFile(name, true)
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil
+		} else if err != nil {
+			return err
+		}
+		_, diff, err := decodeChunkObject(content)
+		if err != nil {
+			return err
+		}
+		if diff == nil || diff.GetPrevRef() == nil {
+			return nil
+		}
+		chunkRef = chunkPrevRef(diff.GetPrevRef())
+	}
+}
+
+// referenceBatchChain references a batch file and the previous batches of its diff chain, in backups without a chunk
+// store, whose batch files hold their own chunks.
+func (reader *BinaryBackupReader) referenceBatchChain(referenced map[string]bool, batchRef string) error {
+	for {
+		name := path.Join("data", fmt.Sprintf("%s.hdb", batchRef))
+		if referenced[name] {
+			return nil
+		}
+		referenced[name] = true
+		if !strings.HasPrefix(batchRef, "diffs") {
+			return nil
+		}
+
+		data, err := reader.readBatch(name)
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil
+		} else if err != nil {
+			return err
+		}
+		_, prevBatchRef, err := readBatchHeader(bytes.NewReader(data), true)
+		if err != nil {
+			return err
+		}
+		batchRef = prevBatchRef
+	}
+}
+
+// readBatchHeader reads the record type of a batch file of a backup without a chunk store, and its previous batch if it is
+// a diff batch.
+func readBatchHeader(f *bytes.Reader, isDiff bool) (entities.RecordType, string, error) {
+	recordType, err := readBatchHeaderField(f)
+	if err != nil || !isDiff {
+		return entities.RecordType(recordType), "", err
+	}
+
+	prevBatchRef, err := readBatchHeaderField(f)
+	return entities.RecordType(recordType), prevBatchRef, err
+}
+
+// readBatchHeaderField reads a string of the header of a batch file, checking its length first as the file may be corrupted.
+func readBatchHeaderField(f *bytes.Reader) (string, error) {
+	var length int64
+	if err := binary.Read(f, binary.LittleEndian, &length); err != nil {
+		return "", err
+	}
+	if length < 0 || length > int64(f.Len()) {
+		return "", io.ErrUnexpectedEOF
+	}
+
+	field := make([]byte, length)
+	if _, err := io.ReadFull(f, field); err != nil {
+		return "", err
+	}
+	return string(field), nil
+}
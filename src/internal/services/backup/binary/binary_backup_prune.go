@@ -0,0 +1,664 @@
+package binary
+
+import (
+	"bytes"
+	"cmp"
+	"crypto/sha256"
+	"fmt"
+	"historydb/src/internal/entities"
+	"historydb/src/internal/services"
+	"historydb/src/internal/utils/decode"
+	"historydb/src/internal/utils/encode"
+	"historydb/src/internal/utils/pointers"
+	"maps"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/google/uuid"
+)
+
+// Deleting snapshots removes the objects only they reference. As the objects of a snapshot may be diffs applied to the
+// objects of the snapshots before it, the diffs whose previous object no kept snapshot references are re-based first:
+// they are saved again as full objects, and the snapshots and diffs which referenced them reference the full objects
+// instead. Record chunks saved as a diff of a chunk no kept batch lists are saved again in full under the same name.
+
+// DeleteSnapshots deletes the snapshots from the backup, re-basing the objects the rest of snapshots need onto full objects,
+// and removes every file no snapshot needs once the new metadata is committed. The pack files which hold removed objects
+// are deleted once the objects they keep are packed again. It fails with ErrBackupSnapshotNotFound if a snapshot is not
+// listed in the backup.
+func (writer *BinaryBackupWriter) DeleteSnapshots(snapshotIds []string) (entities.BackupSweep, error) {
+	if writer.TxSnapshot != nil {
+		return entities.BackupSweep{}, services.ErrBackupTransactionInProgress
+	}
+
+	reader := NewBinaryBackupReader(writer.Storage, writer.options.Passphrase)
+	metadata, err := reader.GetBackupMetadata()
+	if err != nil {
+		return entities.BackupSweep{}, err
+	}
+	codec, err := reader.getPayloadCodec()
+	if err != nil {
+		return entities.BackupSweep{}, err
+	}
+
+	listed := make(map[string]bool)
+	for _, snapshot := range metadata.Snapshots {
+		listed[snapshot.SnapshotId] = true
+	}
+	for _, snapshotId := range snapshotIds {
+		if !listed[snapshotId] {
+			return entities.BackupSweep{}, fmt.Errorf("%w: %s", services.ErrBackupSnapshotNotFound, snapshotId)
+		}
+	}
+
+	keptSnapshots := slices.DeleteFunc(slices.Clone(metadata.Snapshots), func(snapshot entities.BackupMetadataSnapshot) bool {
+		return slices.Contains(snapshotIds, snapshot.SnapshotId)
+	})
+	snapshots := make([]entities.BackupSnapshot, 0, len(keptSnapshots))
+	for _, snapshotMetadata := range keptSnapshots {
+		snapshot, err := reader.GetBackupSnapshot(snapshotMetadata.SnapshotId)
+		if err != nil {
+			return entities.BackupSweep{}, err
+		}
+		snapshots = append(snapshots, snapshot)
+	}
+
+	// The changes are staged and committed as a snapshot transaction, so an interrupted deletion is completed or discarded
+	if err := writer.BaseBackupWriter.BeginSnapshot(&entities.BackupSnapshot{SnapshotId: uuid.NewString()}); err != nil {
+		return entities.BackupSweep{}, err
+	}
+	writer.codec = codec
+	writer.encryption = metadata.Encryption
+	writer.chunkStore = metadata.ChunkStore
+
+	metadata.Snapshots = keptSnapshots
+	sweep, err := writer.deleteSnapshots(reader, &metadata, snapshots, snapshotIds)
+	if err != nil {
+		if rollbackErr := writer.RollbackSnapshot(); rollbackErr != nil {
+			return entities.BackupSweep{}, fmt.Errorf("%w: %v", err, rollbackErr)
+		}
+		return entities.BackupSweep{}, err
+	}
+	return sweep, nil
+}
+
+// deleteSnapshots stages the re-based objects and snapshots of the transaction, along with the objects packed again, and
+// commits the metadata which only lists the kept snapshots.
+func (writer *BinaryBackupWriter) deleteSnapshots(reader *BinaryBackupReader, metadata *entities.BackupMetadata, snapshots []entities.BackupSnapshot, snapshotIds []string) (entities.BackupSweep, error) {
+	stored, err := reader.ListBackupObjects()
+	if err != nil {
+		return entities.BackupSweep{}, err
+	}
+
+	rebase := snapshotRebase{
+		reader:     reader,
+		codec:      writer.codec,
+		stage:      writer.stageFile,
+		stored:     make(map[string]bool),
+		referenced: make(map[string]bool),
+		replaced:   make(map[string]bool),
+	}
+	for _, name := range stored {
+		rebase.stored[name] = true
+	}
+	changed, err := rebase.rebaseSnapshots(snapshots)
+	if err != nil {
+		return entities.BackupSweep{}, err
+	}
+	for i, snapshot := range snapshots {
+		if !changed[i] {
+			continue
+		}
+		name := path.Join("snapshots", fmt.Sprintf("%s.hdb", snapshot.SnapshotId))
+		if err := writer.stageFile(name, writer.codec.seal(name, snapshot.EncodeToBytes())); err != nil {
+			return entities.BackupSweep{}, err
+		}
+	}
+
+	// The stored copies of the replaced objects are removed, as their new copies are packed
+	sweep, err := writer.planSweep(backupObjectDirs, func(name string) bool {
+		return !rebase.referenced[name] || rebase.replaced[name]
+	})
+	if err != nil {
+		return entities.BackupSweep{}, err
+	}
+	for _, snapshotId := range snapshotIds {
+		sweep.deletes = append(sweep.deletes, path.Join("snapshots", fmt.Sprintf("%s.hdb", snapshotId)))
+	}
+
+	if err := writer.repackObjects(sweep); err != nil {
+		return entities.BackupSweep{}, err
+	}
+	if err := writer.packObjects(); err != nil {
+		return entities.BackupSweep{}, err
+	}
+	if err := writer.commitStagedFiles(metadata, sweep.deletes); err != nil {
+		return entities.BackupSweep{}, err
+	}
+	return entities.BackupSweep{RebasedObjects: rebase.rebased, DeletedFiles: sweep.deletes}, nil
+}
+
+// objectSweep is the removal of the objects of a backup which are not needed any longer
+//
+// kept -> The objects of the pack files which are kept as they are
+// repacked -> The objects which are packed again, as the pack holding them also holds removed objects, mapped by their pack
+// deletes -> The files deleted once the transaction is committed
+// reclaimed -> The bytes of the files deleted, less the bytes of the objects packed again
+type objectSweep struct {
+	kept      packIndex
+	repacked  map[string]packIndex
+	deletes   []string
+	reclaimed int64
+}
+
+// planSweep plans the removal of the objects of dirs, and of the packed objects, for which removed is true. The pack files
+// holding any of them are packed again, keeping the objects another kept pack does not hold, and the pack files without an
+// index, which were left by an interrupted commit, are removed. The indexes are deleted before their packs, so an index
+// never lists objects which are already deleted.
+func (writer *BinaryBackupWriter) planSweep(dirs []string, removed func(name string) bool) (objectSweep, error) {
+	packs, err := readPackIndexes(writer.Storage, writer.codec)
+	if err != nil {
+		return objectSweep{}, err
+	}
+	sweep := objectSweep{kept: make(packIndex), repacked: make(map[string]packIndex)}
+	for _, pack := range slices.Sorted(maps.Keys(packs)) {
+		if slices.ContainsFunc(slices.Collect(maps.Keys(packs[pack])), removed) {
+			sweep.repacked[pack] = make(packIndex)
+		} else {
+			maps.Copy(sweep.kept, packs[pack])
+		}
+	}
+
+	packed := make(map[string]bool)
+	for _, pack := range slices.Sorted(maps.Keys(sweep.repacked)) {
+		for name, location := range packs[pack] {
+			if _, ok := sweep.kept[name]; ok || packed[name] || removed(name) {
+				continue
+			}
+			sweep.repacked[pack][name] = location
+			sweep.reclaimed -= location.Length
+			packed[name] = true
+		}
+	}
+
+	packFiles, err := writer.Storage.List("packs/")
+	if err != nil {
+		return objectSweep{}, err
+	}
+	for _, pack := range slices.Sorted(maps.Keys(sweep.repacked)) {
+		sweep.deletes = append(sweep.deletes, strings.TrimSuffix(pack, ".pack")+".idx")
+	}
+	for _, name := range packFiles {
+		if _, ok := sweep.repacked[name]; ok || (path.Ext(name) != ".idx" && packs[name] == nil) {
+			sweep.deletes = append(sweep.deletes, name)
+		}
+	}
+	for _, dir := range dirs {
+		names, err := writer.Storage.List(dir)
+		if err != nil {
+			return objectSweep{}, err
+		}
+		sweep.deletes = append(sweep.deletes, slices.DeleteFunc(names, func(name string) bool { return !removed(name) })...)
+	}
+
+	for _, name := range sweep.deletes {
+		size, err := writer.Storage.Size(name)
+		if err != nil {
+			return objectSweep{}, err
+		}
+		sweep.reclaimed += size
+	}
+	return sweep, nil
+}
+
+// repackObjects stages the objects of the pack files which are packed again, so they are packed along with the staged
+// objects, and keeps the objects of the rest of packs from being packed again.
+func (writer *BinaryBackupWriter) repackObjects(sweep objectSweep) error {
+	writer.packedObjects = sweep.kept
+	for _, pack := range slices.Sorted(maps.Keys(sweep.repacked)) {
+		if len(sweep.repacked[pack]) == 0 {
+			continue
+		}
+		data, err := writer.Storage.Get(pack)
+		if err != nil {
+			return err
+		}
+		for name, location := range sweep.repacked[pack] {
+			if location.Offset < 0 || location.Offset+location.Length > int64(len(data)) {
+				return fmt.Errorf("%w: %s in %s", services.ErrBackupCorruptedFile, name, pack)
+			}
+			// Objects are sealed with their own name, so they are packed again as they were saved
+			if err := writer.stageFile(name, data[location.Offset:location.Offset+location.Length]); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// stageFile stages the data of a file of the backup in the transaction, as it is saved in the storage.
+func (writer *BinaryBackupWriter) stageFile(name string, data []byte) error {
+	pathToFile := filepath.Join(writer.TransactionPath(), filepath.FromSlash(name))
+	if err := os.MkdirAll(filepath.Dir(pathToFile), 0755); err != nil {
+		return err
+	}
+	return os.WriteFile(pathToFile, data, 0644)
+}
+
+// snapshotRebase re-bases the objects referenced by the kept snapshots of a backup, so none of them needs an object which
+// only the deleted snapshots referenced.
+//
+// stage -> Stages the data of a file of the backup in the transaction
+// stored -> The objects saved in the backup, so the full objects already saved are not staged again
+// referenced -> The objects the kept snapshots need once they are re-based
+// replaced -> The objects staged again under the name they already had, whose stored copies are removed
+// rebased -> The objects saved again as full objects
+type snapshotRebase struct {
+	reader     *BinaryBackupReader
+	codec      *payloadCodec
+	stage      func(name string, data []byte) error
+	stored     map[string]bool
+	referenced map[string]bool
+	replaced   map[string]bool
+	rebased    []string
+}
+
+// rebaseSnapshots re-bases every object of the snapshots, changing the references of the snapshots to the objects saved
+// again as full objects. It returns whether every snapshot was changed.
+func (rebase *snapshotRebase) rebaseSnapshots(snapshots []entities.BackupSnapshot) ([]bool, error) {
+	collectRefs := func(refs func(snapshot entities.BackupSnapshot) []string) []string {
+		collected := []string{}
+		for _, snapshot := range snapshots {
+			collected = append(collected, refs(snapshot)...)
+		}
+		return collected
+	}
+
+	dependencyRefs, err := rebase.rebaseDiffChains("schemas/dependencies", collectRefs(func(snapshot entities.BackupSnapshot) []string {
+		return slices.Collect(maps.Values(snapshot.SchemaDependencies))
+	}), func(ref string) ([]byte, error) {
+		dependency, _, err := rebase.reader.GetSchemaDependency(ref)
+		if err != nil {
+			return nil, err
+		}
+		return dependency.EncodeToBytes(), nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	schemaRefs, err := rebase.rebaseDiffChains("schemas", collectRefs(func(snapshot entities.BackupSnapshot) []string {
+		return slices.Collect(maps.Values(snapshot.Schemas))
+	}), func(ref string) ([]byte, error) {
+		schema, _, err := rebase.reader.GetSchema(ref)
+		if err != nil {
+			return nil, err
+		}
+		return schema.EncodeToBytes(), nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	routineRefs, err := rebase.rebaseDiffChains("routines", collectRefs(func(snapshot entities.BackupSnapshot) []string {
+		return slices.Collect(maps.Values(snapshot.Routines))
+	}), func(ref string) ([]byte, error) {
+		routine, _, err := rebase.reader.GetRoutine(ref)
+		if err != nil {
+			return nil, err
+		}
+		return routine.EncodeToBytes(), nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	batchRefs := collectRefs(func(snapshot entities.BackupSnapshot) []string {
+		refs := []string{}
+		for _, schemaData := range snapshot.Data {
+			refs = append(refs, schemaData.Data...)
+		}
+		return refs
+	})
+	var newBatchRefs map[string]string
+	if chunkStore, err := rebase.reader.usesChunkStore(); err != nil {
+		return nil, err
+	} else if chunkStore {
+		if newBatchRefs, err = rebase.rebaseManifests(batchRefs); err != nil {
+			return nil, err
+		}
+		if err := rebase.rebaseChunks(batchRefs); err != nil {
+			return nil, err
+		}
+	} else if newBatchRefs, err = rebase.rebaseBatchFiles(batchRefs); err != nil {
+		return nil, err
+	}
+
+	changed := make([]bool, len(snapshots))
+	for i, snapshot := range snapshots {
+		changed[i] = rebaseRefs(snapshot.SchemaDependencies, dependencyRefs)
+		changed[i] = rebaseRefs(snapshot.Schemas, schemaRefs) || changed[i]
+		changed[i] = rebaseRefs(snapshot.Routines, routineRefs) || changed[i]
+		for name, schemaData := range snapshot.Data {
+			schemaData.Data = slices.Clone(schemaData.Data)
+			for j, batchRef := range schemaData.Data {
+				if newRef, ok := newBatchRefs[batchRef]; ok {
+					schemaData.Data[j] = newRef
+					changed[i] = true
+				}
+			}
+			snapshot.Data[name] = schemaData
+		}
+	}
+	return changed, nil
+}
+
+// rebaseRefs changes the references of the objects saved again as full objects, returning whether any was changed.
+func rebaseRefs(refs map[string]string, newRefs map[string]string) bool {
+	changed := false
+	for name, ref := range refs {
+		if newRef, ok := newRefs[ref]; ok {
+			refs[name] = newRef
+			changed = true
+		}
+	}
+	return changed
+}
+
+// rebaseDiffChains re-bases the schema dependencies, schemas or routines of dir referenced by the snapshots. The diffs whose
+// previous object no snapshot references are saved as the full object materialize returns, and the diffs applied to them
+// are staged again pointing to the full object. It returns the new reference of every object saved as a full object.
+func (rebase *snapshotRebase) rebaseDiffChains(dir string, refs []string, materialize func(ref string) ([]byte, error)) (map[string]string, error) {
+	roots := make(map[string]bool)
+	for _, ref := range refs {
+		roots[ref] = true
+	}
+
+	newRefs := make(map[string]string)
+	prevRefs := make(map[string]string)
+	for _, ref := range slices.Sorted(maps.Keys(roots)) {
+		if !strings.HasPrefix(ref, "diffs") {
+			continue
+		}
+		content, err := rebase.reader.readFile(objectFileName(dir, ref), true)
+		if err != nil {
+			return nil, err
+		}
+		prevRef, err := decode.DecodeString(bytes.NewBuffer(content))
+		if err != nil {
+			return nil, err
+		}
+		if roots[*prevRef] {
+			prevRefs[ref] = *prevRef
+			continue
+		}
+
+		content, err = materialize(ref)
+		if err != nil {
+			return nil, err
+		}
+		newRef := strings.TrimPrefix(ref, "diffs/")
+		if err := rebase.stageFullObject(objectFileName(dir, newRef), rebase.codec.encodeFile(objectFileName(dir, newRef), content)); err != nil {
+			return nil, err
+		}
+		newRefs[ref] = newRef
+	}
+
+	// The content of a diff begins with the reference of its previous object, which is the only part changed
+	for _, ref := range slices.Sorted(maps.Keys(prevRefs)) {
+		newPrevRef, ok := newRefs[prevRefs[ref]]
+		if !ok {
+			continue
+		}
+		name := objectFileName(dir, ref)
+		content, err := rebase.reader.readFile(name, true)
+		if err != nil {
+			return nil, err
+		}
+		buf := bytes.NewBuffer(content)
+		if _, err := decode.DecodeString(buf); err != nil {
+			return nil, err
+		}
+
+		var rewritten bytes.Buffer
+		encode.EncodeString(&rewritten, &newPrevRef)
+		rewritten.Write(buf.Bytes())
+		if err := rebase.stageReplacedObject(name, rebase.codec.encodeFile(name, withIntegrityHash(rewritten.Bytes()))); err != nil {
+			return nil, err
+		}
+	}
+
+	for ref := range roots {
+		rebase.referenced[objectFileName(dir, cmp.Or(newRefs[ref], ref))] = true
+	}
+	return newRefs, nil
+}
+
+// rebaseManifests re-bases the batch manifests referenced by the snapshots of a backup with a chunk store. The manifests of
+// diff batches whose previous batch no snapshot references are saved as full manifests listing every chunk of the batch.
+func (rebase *snapshotRebase) rebaseManifests(refs []string) (map[string]string, error) {
+	roots := make(map[string]bool)
+	for _, ref := range refs {
+		roots[ref] = true
+	}
+
+	readManifest := func(ref string) (batchManifest, error) {
+		var manifest batchManifest
+		content, err := rebase.reader.readFile(objectFileName("data", ref), true)
+		if err != nil {
+			return manifest, err
+		}
+		err = manifest.DecodeFromBytes(content, strings.HasPrefix(ref, "diffs"))
+		return manifest, err
+	}
+
+	newRefs := make(map[string]string)
+	prevRefs := make(map[string]string)
+	for _, ref := range slices.Sorted(maps.Keys(roots)) {
+		if !strings.HasPrefix(ref, "diffs") {
+			continue
+		}
+		manifest, err := readManifest(ref)
+		if err != nil {
+			return nil, err
+		}
+		if roots[*manifest.PrevBatchRef] {
+			prevRefs[ref] = *manifest.PrevBatchRef
+			continue
+		}
+
+		chunkRefs, err := rebase.reader.GetSchemaRecordChunkRefsInBatch(ref)
+		if err != nil {
+			return nil, err
+		}
+		full := batchManifest{RecordType: manifest.RecordType}
+		for _, chunkRef := range chunkRefs {
+			full.Entries = append(full.Entries, batchManifestEntry{Hash: pointers.Ptr(chunkRef)})
+		}
+		newRef := strings.TrimPrefix(ref, "diffs/")
+		if err := rebase.stageFullObject(objectFileName("data", newRef), rebase.codec.encodeFile(objectFileName("data", newRef), full.EncodeToBytes())); err != nil {
+			return nil, err
+		}
+		newRefs[ref] = newRef
+	}
+
+	for _, ref := range slices.Sorted(maps.Keys(prevRefs)) {
+		newPrevRef, ok := newRefs[prevRefs[ref]]
+		if !ok {
+			continue
+		}
+		manifest, err := readManifest(ref)
+		if err != nil {
+			return nil, err
+		}
+		manifest.PrevBatchRef = &newPrevRef
+		name := objectFileName("data", ref)
+		if err := rebase.stageReplacedObject(name, rebase.codec.encodeFile(name, manifest.EncodeToBytes())); err != nil {
+			return nil, err
+		}
+	}
+
+	for ref := range roots {
+		rebase.referenced[objectFileName("data", cmp.Or(newRefs[ref], ref))] = true
+	}
+	return newRefs, nil
+}
+
+// rebaseChunks re-bases the chunks of the batches referenced by the snapshots of a backup with a chunk store. The chunks
+// saved as a diff of a chunk no batch lists are saved again in full under the same name.
+func (rebase *snapshotRebase) rebaseChunks(batchRefs []string) error {
+	chunks := make(map[string]bool)
+	for _, batchRef := range batchRefs {
+		chunkRefs, err := rebase.reader.GetSchemaRecordChunkRefsInBatch(batchRef)
+		if err != nil {
+			return err
+		}
+		for _, chunkRef := range chunkRefs {
+			chunks[chunkRef] = true
+		}
+	}
+
+	for _, chunkRef := range slices.Sorted(maps.Keys(chunks)) {
+		name := chunkObjectName(rebase.codec.chunkRef(chunkRef))
+		rebase.referenced[name] = true
+
+		content, err := rebase.reader.readFile(name, true)
+		if err != nil {
+			return fmt.Errorf("%w: %s", err, chunkRef)
+		}
+		_, diff, err := decodeChunkObject(content)
+		if err != nil {
+			return err
+		}
+		if diff == nil || diff.GetPrevRef() == nil || chunks[chunkPrevRef(diff.GetPrevRef())] {
+			continue
+		}
+
+		chunk, _, err := rebase.reader.readChunkObject(chunkRef)
+		if err != nil {
+			return err
+		}
+		content, err = chunk.EncodeToBytes()
+		if err != nil {
+			return err
+		}
+		if err := rebase.stageReplacedObject(name, rebase.codec.encodeFile(name, encodeChunkObject(chunk.GetRecordType(), false, content))); err != nil {
+			return err
+		}
+		rebase.rebased = append(rebase.rebased, name)
+	}
+	return nil
+}
+
+// rebaseBatchFiles re-bases the batch files referenced by the snapshots of a backup without a chunk store. The diff batches
+// whose previous batch no snapshot references are saved as full batches holding every chunk of the batch.
+func (rebase *snapshotRebase) rebaseBatchFiles(refs []string) (map[string]string, error) {
+	roots := make(map[string]bool)
+	for _, ref := range refs {
+		roots[ref] = true
+	}
+
+	newRefs := make(map[string]string)
+	prevRefs := make(map[string]string)
+	for _, ref := range slices.Sorted(maps.Keys(roots)) {
+		if !strings.HasPrefix(ref, "diffs") {
+			continue
+		}
+		data, err := rebase.reader.readBatch(objectFileName("data", ref))
+		if err != nil {
+			return nil, err
+		}
+		recordType, prevBatchRef, err := readBatchHeader(bytes.NewReader(data), true)
+		if err != nil {
+			return nil, err
+		}
+		if roots[prevBatchRef] {
+			prevRefs[ref] = prevBatchRef
+			continue
+		}
+
+		chunkRefs, err := rebase.reader.GetSchemaRecordChunkRefsInBatch(ref)
+		if err != nil {
+			return nil, err
+		}
+		var batch bytes.Buffer
+		encode.EncodeString(&batch, pointers.Ptr(string(recordType)))
+		for _, chunkRef := range chunkRefs {
+			chunk, _, err := rebase.reader.GetSchemaRecordChunk(ref, chunkRef)
+			if err != nil {
+				return nil, err
+			}
+			content, err := chunk.EncodeToBytes()
+			if err != nil {
+				return nil, err
+			}
+			batch.Write(rebase.codec.encodeBatchEntry(pointers.Ptr(chunkRef), content))
+		}
+		newRef := strings.TrimPrefix(ref, "diffs/")
+		if err := rebase.stageFullObject(objectFileName("data", newRef), batch.Bytes()); err != nil {
+			return nil, err
+		}
+		newRefs[ref] = newRef
+	}
+
+	// Batch files have no integrity hash of their own, so only their header is changed
+	for _, ref := range slices.Sorted(maps.Keys(prevRefs)) {
+		newPrevRef, ok := newRefs[prevRefs[ref]]
+		if !ok {
+			continue
+		}
+		name := objectFileName("data", ref)
+		data, err := rebase.reader.readBatch(name)
+		if err != nil {
+			return nil, err
+		}
+		f := bytes.NewReader(data)
+		recordType, _, err := readBatchHeader(f, true)
+		if err != nil {
+			return nil, err
+		}
+
+		var batch bytes.Buffer
+		encode.EncodeString(&batch, pointers.Ptr(string(recordType)))
+		encode.EncodeString(&batch, &newPrevRef)
+		batch.Write(data[len(data)-f.Len():])
+		if err := rebase.stageReplacedObject(name, batch.Bytes()); err != nil {
+			return nil, err
+		}
+	}
+
+	for ref := range roots {
+		rebase.referenced[objectFileName("data", cmp.Or(newRefs[ref], ref))] = true
+	}
+	return newRefs, nil
+}
+
+// stageFullObject stages an object saved again as a full object, unless the backup already holds it.
+func (rebase *snapshotRebase) stageFullObject(name string, data []byte) error {
+	rebase.rebased = append(rebase.rebased, name)
+	if rebase.stored[name] {
+		return nil
+	}
+	rebase.stored[name] = true
+	return rebase.stage(name, data)
+}
+
+// stageReplacedObject stages an object which replaces the stored object with the same name.
+func (rebase *snapshotRebase) stageReplacedObject(name string, data []byte) error {
+	rebase.replaced[name] = true
+	return rebase.stage(name, data)
+}
+
+// objectFileName returns the name of the file of the object ref of dir.
+func objectFileName(dir, ref string) string {
+	return path.Join(dir, fmt.Sprintf("%s.hdb", ref))
+}
+
+// withIntegrityHash prefixes content with its SHA-256 hash, as the content of every backup file is saved.
+func withIntegrityHash(content []byte) []byte {
+	integrityHash := sha256.Sum256(content)
+	return append(integrityHash[:], content...)
+}
@@ -4,35 +4,78 @@ import (
 	"bytes"
 	"crypto/sha256"
 	"encoding/binary"
+	"errors"
 	"fmt"
 	"historydb/src/internal/entities"
 	"historydb/src/internal/services"
+	"historydb/src/internal/services/entities/mongo"
+	"historydb/src/internal/services/entities/mysql"
 	"historydb/src/internal/services/entities/psql"
 	"historydb/src/internal/services/entities/sql"
+	"historydb/src/internal/services/entities/sqlite"
+	storage_services "historydb/src/internal/services/storage"
 	"historydb/src/internal/utils/crypto"
 	"historydb/src/internal/utils/decode"
 	"io"
-	"os"
-	"path/filepath"
+	"io/fs"
+	"path"
+	"slices"
 	"strings"
+	"sync"
 )
 
+// BinaryBackupReader reads the backup files, decompressing them with the codec saved in the backup metadata.
+// The files of encrypted backups are authenticated and opened with the data key unwrapped with the passphrase.
 type BinaryBackupReader struct {
-	backupPath string
+	storage    storage_services.Storage
+	passphrase []byte
+	cache      fileCache
+
+	codecOnce  sync.Once
+	codec      *payloadCodec
+	chunkStore bool
+	codecErr   error
+
+	packsOnce sync.Once
+	packs     packIndex
+	packsErr  error
 }
 
-func NewBinaryBackupReader(backupPath string) *BinaryBackupReader {
-	return &BinaryBackupReader{backupPath}
+// NewBinaryBackupReader creates the reader of the backup kept in storage. The passphrase is only needed by encrypted backups.
+func NewBinaryBackupReader(storage storage_services.Storage, passphrase []byte) *BinaryBackupReader {
+	return &BinaryBackupReader{storage: storage, passphrase: passphrase}
 }
 
 func (reader *BinaryBackupReader) CheckBackupExists() bool {
-	_, err := os.Stat(filepath.Join(reader.backupPath, "metadata.hdb"))
+	_, err := reader.storage.Get("metadata.hdb")
 	return err == nil
 }
 
 func (reader *BinaryBackupReader) GetBackupMetadata() (entities.BackupMetadata, error) {
-	pathToFile := filepath.Join(reader.backupPath, "metadata.hdb")
-	data, err := os.ReadFile(pathToFile)
+	metadata, err := readBackupMetadata(reader.storage)
+	if err != nil || metadata.Encryption == nil {
+		return metadata, err
+	}
+
+	codec, err := reader.getPayloadCodec()
+	if err != nil {
+		return entities.BackupMetadata{}, err
+	}
+	snapshots, err := codec.open("metadata.hdb", metadata.SealedSnapshots)
+	if err != nil {
+		return entities.BackupMetadata{}, err
+	}
+	if err := metadata.DecodeSnapshots(snapshots); err != nil {
+		return entities.BackupMetadata{}, err
+	}
+	return metadata, nil
+}
+
+// readBackupMetadata reads the metadata of the backup kept in storage. The metadata is never compressed nor sealed, as it
+// holds the codec and wrapped key of the backup, so the snapshots of encrypted backups are left sealed.
+func readBackupMetadata(storage storage_services.Storage) (entities.BackupMetadata, error) {
+	pathToFile := "metadata.hdb"
+	data, err := storage.Get(pathToFile)
 	if err != nil {
 		return entities.BackupMetadata{}, fmt.Errorf("%w: %s", services.ErrBackupDirNotExists, err.Error())
 	}
@@ -52,28 +95,21 @@ func (reader *BinaryBackupReader) GetBackupMetadata() (entities.BackupMetadata,
 	if err := metadata.DecodeFromBytes(content); err != nil {
 		return entities.BackupMetadata{}, err
 	}
+	// Newer backups may hold values this version can not decode
+	if metadata.Version > entities.BACKUPMETADATA_VERSION {
+		return entities.BackupMetadata{}, fmt.Errorf("%w: version %d", services.ErrBackupVersionNotSupported, metadata.Version)
+	}
 
 	return metadata, nil
 }
 
 func (reader *BinaryBackupReader) GetBackupSnapshot(snapshotId string) (entities.BackupSnapshot, error) {
-	pathToFile := filepath.Join(reader.backupPath, "snapshots", fmt.Sprintf("%s.hdb", snapshotId))
-	data, err := os.ReadFile(pathToFile)
+	pathToFile := path.Join("snapshots", fmt.Sprintf("%s.hdb", snapshotId))
+	content, err := reader.readFile(pathToFile, false)
 	if err != nil {
 		return entities.BackupSnapshot{}, err
 	}
 
-	if len(data) < sha256.Size {
-		return entities.BackupSnapshot{}, fmt.Errorf("%w: %s", services.ErrBackupCorruptedFile, pathToFile)
-	}
-
-	hashBytes := data[:sha256.Size]
-	content := data[sha256.Size:]
-
-	if ok := crypto.CheckDataSignature(hashBytes, content); !ok {
-		return entities.BackupSnapshot{}, fmt.Errorf("%w: %s", services.ErrBackupCorruptedFile, pathToFile)
-	}
-
 	var snapshot entities.BackupSnapshot
 	if err := snapshot.DecodeFromBytes(content); err != nil {
 		return entities.BackupSnapshot{}, err
@@ -83,23 +119,12 @@ func (reader *BinaryBackupReader) GetBackupSnapshot(snapshotId string) (entities
 }
 
 func (reader *BinaryBackupReader) GetSchemaDependency(dependencyRef string) (entities.SchemaDependency, bool, error) {
-	pathToFile := filepath.Join(reader.backupPath, "schemas", "dependencies", fmt.Sprintf("%s.hdb", dependencyRef))
-	data, err := os.ReadFile(pathToFile)
+	pathToFile := path.Join("schemas", "dependencies", fmt.Sprintf("%s.hdb", dependencyRef))
+	content, err := reader.readFile(pathToFile, true)
 	if err != nil {
 		return nil, false, err
 	}
 
-	if len(data) < sha256.Size {
-		return nil, false, fmt.Errorf("%w: %s", services.ErrBackupCorruptedFile, pathToFile)
-	}
-
-	hashBytes := data[:sha256.Size]
-	content := data[sha256.Size:]
-
-	if ok := crypto.CheckDataSignature(hashBytes, content); !ok {
-		return nil, false, fmt.Errorf("%w: %s", services.ErrBackupCorruptedFile, pathToFile)
-	}
-
 	if strings.HasPrefix(dependencyRef, "diffs") {
 		prevRef, err := decode.DecodeString(bytes.NewBuffer(content))
 		if err != nil {
@@ -125,28 +150,17 @@ func (reader *BinaryBackupReader) GetSchemaDependency(dependencyRef string) (ent
 		}
 
 		dependency, err := reader.readSchemaDependencyByType(entities.DependencyType(*dependencyType), content)
-		return dependency, true, err
+		return dependency, false, err
 	}
 }
 
 func (reader *BinaryBackupReader) GetSchema(schemaRef string) (entities.Schema, bool, error) {
-	pathToFile := filepath.Join(reader.backupPath, "schemas", fmt.Sprintf("%s.hdb", schemaRef))
-	data, err := os.ReadFile(pathToFile)
+	pathToFile := path.Join("schemas", fmt.Sprintf("%s.hdb", schemaRef))
+	content, err := reader.readFile(pathToFile, true)
 	if err != nil {
 		return nil, false, err
 	}
 
-	if len(data) < sha256.Size {
-		return nil, false, fmt.Errorf("%w: %s", services.ErrBackupCorruptedFile, pathToFile)
-	}
-
-	hashBytes := data[:sha256.Size]
-	content := data[sha256.Size:]
-
-	if ok := crypto.CheckDataSignature(hashBytes, content); !ok {
-		return nil, false, fmt.Errorf("%w: %s", services.ErrBackupCorruptedFile, pathToFile)
-	}
-
 	if strings.HasPrefix(schemaRef, "diffs") {
 		prevRef, err := decode.DecodeString(bytes.NewBuffer(content))
 		if err != nil {
@@ -177,12 +191,18 @@ func (reader *BinaryBackupReader) GetSchema(schemaRef string) (entities.Schema,
 }
 
 func (reader *BinaryBackupReader) GetSchemaRecordChunkRefsInBatch(batchRef string) ([]string, error) {
-	pathToFile := filepath.Join(reader.backupPath, "data", fmt.Sprintf("%s.hdb", batchRef))
-	f, err := os.Open(pathToFile)
+	if chunkStore, err := reader.usesChunkStore(); err != nil {
+		return nil, err
+	} else if chunkStore {
+		return reader.readManifestChunkRefs(batchRef)
+	}
+
+	pathToFile := path.Join("data", fmt.Sprintf("%s.hdb", batchRef))
+	data, err := reader.readBatch(pathToFile)
 	if err != nil {
 		return nil, err
 	}
-	defer f.Close()
+	f := bytes.NewReader(data)
 
 	// Gets record type
 	var recordTypeLength int64
@@ -217,12 +237,19 @@ func (reader *BinaryBackupReader) GetSchemaRecordChunkRefsInBatch(batchRef strin
 }
 
 func (reader *BinaryBackupReader) GetSchemaRecordChunk(batchRef, chunkRef string) (entities.SchemaRecordChunk, bool, error) {
-	pathToFile := filepath.Join(reader.backupPath, "data", fmt.Sprintf("%s.hdb", batchRef))
-	f, err := os.Open(pathToFile)
+	if chunkStore, err := reader.usesChunkStore(); err != nil {
+		return nil, false, err
+	} else if chunkStore {
+		// The chunk store holds the chunks of every batch
+		return reader.readChunkObject(chunkRef)
+	}
+
+	pathToFile := path.Join("data", fmt.Sprintf("%s.hdb", batchRef))
+	data, err := reader.readBatch(pathToFile)
 	if err != nil {
 		return nil, false, err
 	}
-	defer f.Close()
+	f := bytes.NewReader(data)
 
 	// Gets record type
 	var recordTypeLength int64
@@ -246,12 +273,15 @@ func (reader *BinaryBackupReader) GetSchemaRecordChunk(batchRef, chunkRef string
 		}
 
 		diff, err := reader.readSchemaDataChunkDiffByType(entities.RecordType(recordType), chunkRef, f)
-		if err != nil {
+		if errors.Is(err, services.ErrBackupChunkNotFound) {
+			// Chunk was not modified in this batch, so it lives in the previous one
+			return reader.GetSchemaRecordChunk(string(prevBatchRef), chunkRef)
+		} else if err != nil {
 			return nil, false, err
 		}
 
 		if diff.GetPrevRef() != nil {
-			chunk, _, err := reader.GetSchemaRecordChunk(string(prevBatchRef), *diff.GetPrevRef())
+			chunk, _, err := reader.GetSchemaRecordChunk(string(prevBatchRef), chunkPrevRef(diff.GetPrevRef()))
 			if err != nil {
 				return nil, false, err
 			}
@@ -275,23 +305,12 @@ func (reader *BinaryBackupReader) GetSchemaRecordChunk(batchRef, chunkRef string
 }
 
 func (reader *BinaryBackupReader) GetRoutine(routineRef string) (entities.Routine, bool, error) {
-	pathToFile := filepath.Join(reader.backupPath, "routines", fmt.Sprintf("%s.hdb", routineRef))
-	data, err := os.ReadFile(pathToFile)
+	pathToFile := path.Join("routines", fmt.Sprintf("%s.hdb", routineRef))
+	content, err := reader.readFile(pathToFile, true)
 	if err != nil {
 		return nil, false, err
 	}
 
-	if len(data) < sha256.Size {
-		return nil, false, fmt.Errorf("%w: %s", services.ErrBackupCorruptedFile, pathToFile)
-	}
-
-	hashBytes := data[:sha256.Size]
-	content := data[sha256.Size:]
-
-	if ok := crypto.CheckDataSignature(hashBytes, content); !ok {
-		return nil, false, fmt.Errorf("%w: %s", services.ErrBackupCorruptedFile, pathToFile)
-	}
-
 	if strings.HasPrefix(routineRef, "diffs") {
 		prevRef, err := decode.DecodeString(bytes.NewBuffer(content))
 		if err != nil {
@@ -330,6 +349,18 @@ func (reader *BinaryBackupReader) readSchemaDependencyByType(dependencyType enti
 			return nil, err
 		}
 		return &seq, nil
+	case entities.MySQLAutoIncrement:
+		var autoIncrement mysql.MySQLAutoIncrement
+		if err := autoIncrement.DecodeFromBytes(content); err != nil {
+			return nil, err
+		}
+		return &autoIncrement, nil
+	case entities.SQLiteSequence:
+		var sequence sqlite.SQLiteSequence
+		if err := sequence.DecodeFromBytes(content); err != nil {
+			return nil, err
+		}
+		return &sequence, nil
 	default:
 		return nil, fmt.Errorf("unsupported schema dependency type")
 	}
@@ -343,6 +374,18 @@ func (reader *BinaryBackupReader) readSchemaDependencyDiffByType(dependencyType
 			return nil, err
 		}
 		return &diff, nil
+	case entities.MySQLAutoIncrement:
+		var diff mysql.MySQLAutoIncrementDiff
+		if err := diff.DecodeFromBytes(content); err != nil {
+			return nil, err
+		}
+		return &diff, nil
+	case entities.SQLiteSequence:
+		var diff sqlite.SQLiteSequenceDiff
+		if err := diff.DecodeFromBytes(content); err != nil {
+			return nil, err
+		}
+		return &diff, nil
 	default:
 		return nil, services.ErrDependencyNotSupported
 	}
@@ -356,6 +399,12 @@ func (reader *BinaryBackupReader) readSchemaByType(schemaType entities.SchemaTyp
 			return nil, err
 		}
 		return &table, nil
+	case entities.MongoCollection:
+		var collection mongo.MongoCollection
+		if err := collection.DecodeFromBytes(content); err != nil {
+			return nil, err
+		}
+		return &collection, nil
 	default:
 		return nil, services.ErrSchemaNotSupported
 	}
@@ -369,25 +418,35 @@ func (reader *BinaryBackupReader) readSchemaDiffByType(schemaType entities.Schem
 			return nil, err
 		}
 		return &diff, nil
+	case entities.MongoCollection:
+		var diff mongo.MongoCollectionDiff
+		if err := diff.DecodeFromBytes(content); err != nil {
+			return nil, err
+		}
+		return &diff, nil
 	default:
 		return nil, services.ErrSchemaNotSupported
 	}
 }
 
-func (reader *BinaryBackupReader) readSchemaChunkRefsByType(recordType entities.RecordType, f *os.File) ([]string, error) {
+func (reader *BinaryBackupReader) readSchemaChunkRefsByType(recordType entities.RecordType, f io.Reader) ([]string, error) {
 	chunkRefs := []string{}
 
 	switch recordType {
-	case entities.SQLRecord:
+	case entities.SQLRecord, entities.MongoDocument:
 		for {
-			var chunkLength int64
-			if err := binary.Read(f, binary.LittleEndian, &chunkLength); err == io.EOF || err == io.ErrUnexpectedEOF {
+			entry, err := reader.readBatchEntry(f)
+			if err == io.EOF {
 				break
 			} else if err != nil {
 				return nil, err
 			}
-			chunkBytes := make([]byte, chunkLength)
-			if _, err := io.ReadFull(f, chunkBytes); err != nil {
+			if hash := entry.hash(); hash != nil {
+				chunkRefs = append(chunkRefs, *hash)
+				continue
+			}
+			chunkBytes, err := entry.Bytes()
+			if err != nil {
 				return nil, err
 			}
 
@@ -405,18 +464,18 @@ func (reader *BinaryBackupReader) readSchemaChunkRefsByType(recordType entities.
 	}
 }
 
-func (reader *BinaryBackupReader) readSchemaChunkRefsDiffByType(recordType entities.RecordType, f *os.File, originalChunks []string) ([]string, error) {
+func (reader *BinaryBackupReader) readSchemaChunkRefsDiffByType(recordType entities.RecordType, f io.Reader, originalChunks []string) ([]string, error) {
 	switch recordType {
 	case entities.SQLRecord:
 		for {
-			var chunkLength int64
-			if err := binary.Read(f, binary.LittleEndian, &chunkLength); err == io.EOF || err == io.ErrUnexpectedEOF {
+			entry, err := reader.readBatchEntry(f)
+			if err == io.EOF {
 				break
 			} else if err != nil {
 				return nil, err
 			}
-			chunkBytes := make([]byte, chunkLength)
-			if _, err := io.ReadFull(f, chunkBytes); err != nil {
+			chunkBytes, err := entry.Bytes()
+			if err != nil {
 				return nil, err
 			}
 
@@ -425,11 +484,54 @@ func (reader *BinaryBackupReader) readSchemaChunkRefsDiffByType(recordType entit
 				return nil, err
 			}
 
+			if diff.PrevRef == nil && diff.NextRef != nil {
+				// The chunk is placed before the next one, so the batch keeps the order of its records
+				nextIndex := slices.Index(originalChunks, *diff.NextRef)
+				if nextIndex == -1 {
+					return nil, services.ErrBackupCorruptedFile
+				}
+				originalChunks = slices.Insert(originalChunks, nextIndex, *diff.Hash())
+			} else if diff.PrevRef == nil {
+				originalChunks = append(originalChunks, *diff.Hash())
+			} else if diff.Hash() == nil {
+				// The chunk was emptied, so it is no longer part of the batch
+				originalChunks = slices.DeleteFunc(originalChunks, func(v string) bool { return v == chunkPrevRef(diff.PrevRef) })
+			} else {
+				for i, v := range originalChunks {
+					if v == chunkPrevRef(diff.PrevRef) {
+						originalChunks[i] = *diff.Hash()
+					}
+				}
+			}
+		}
+
+		return originalChunks, nil
+	case entities.MongoDocument:
+		for {
+			entry, err := reader.readBatchEntry(f)
+			if err == io.EOF {
+				break
+			} else if err != nil {
+				return nil, err
+			}
+			chunkBytes, err := entry.Bytes()
+			if err != nil {
+				return nil, err
+			}
+
+			var diff mongo.MongoDocumentChunkDiff
+			if err := diff.DecodeFromBytes(chunkBytes); err != nil {
+				return nil, err
+			}
+
 			if diff.PrevRef == nil {
 				originalChunks = append(originalChunks, *diff.Hash())
+			} else if diff.Hash() == nil {
+				// The chunk was emptied, so it is no longer part of the batch
+				originalChunks = slices.DeleteFunc(originalChunks, func(v string) bool { return v == chunkPrevRef(diff.PrevRef) })
 			} else {
 				for i, v := range originalChunks {
-					if v == *diff.PrevRef {
+					if v == chunkPrevRef(diff.PrevRef) {
 						originalChunks[i] = *diff.Hash()
 					}
 				}
@@ -442,18 +544,21 @@ func (reader *BinaryBackupReader) readSchemaChunkRefsDiffByType(recordType entit
 	}
 }
 
-func (reader *BinaryBackupReader) readSchemaRecordChunkByType(recordType entities.RecordType, chunkRef string, f *os.File) (entities.SchemaRecordChunk, error) {
+func (reader *BinaryBackupReader) readSchemaRecordChunkByType(recordType entities.RecordType, chunkRef string, f io.Reader) (entities.SchemaRecordChunk, error) {
 	switch recordType {
 	case entities.SQLRecord:
 		for {
-			var chunkLength int64
-			if err := binary.Read(f, binary.LittleEndian, &chunkLength); err == io.EOF || err == io.ErrUnexpectedEOF {
+			entry, err := reader.readBatchEntry(f)
+			if err == io.EOF {
 				break
 			} else if err != nil {
 				return nil, err
 			}
-			chunkBytes := make([]byte, chunkLength)
-			if _, err := io.ReadFull(f, chunkBytes); err != nil {
+			if entry.skips(chunkRef) {
+				continue
+			}
+			chunkBytes, err := entry.Bytes()
+			if err != nil {
 				return nil, err
 			}
 
@@ -471,24 +576,58 @@ func (reader *BinaryBackupReader) readSchemaRecordChunkByType(recordType entitie
 			}
 		}
 
+		return nil, services.ErrBackupChunkNotFound
+	case entities.MongoDocument:
+		for {
+			entry, err := reader.readBatchEntry(f)
+			if err == io.EOF {
+				break
+			} else if err != nil {
+				return nil, err
+			}
+			if entry.skips(chunkRef) {
+				continue
+			}
+			chunkBytes, err := entry.Bytes()
+			if err != nil {
+				return nil, err
+			}
+
+			chunkHash, err := decode.DecodeString(bytes.NewBuffer(chunkBytes))
+			if err != nil {
+				return nil, err
+			}
+
+			if crypto.CompareHashes(chunkRef, *chunkHash) {
+				var chunk mongo.MongoDocumentChunk
+				if err := chunk.DecodeFromBytes(chunkBytes); err != nil {
+					return nil, err
+				}
+				return &chunk, nil
+			}
+		}
+
 		return nil, services.ErrBackupChunkNotFound
 	default:
 		return nil, services.ErrRecordNotSupported
 	}
 }
 
-func (reader *BinaryBackupReader) readSchemaDataChunkDiffByType(recordType entities.RecordType, chunkRef string, f *os.File) (entities.SchemaRecordChunkDiff, error) {
+func (reader *BinaryBackupReader) readSchemaDataChunkDiffByType(recordType entities.RecordType, chunkRef string, f io.Reader) (entities.SchemaRecordChunkDiff, error) {
 	switch recordType {
 	case entities.SQLRecord:
 		for {
-			var chunkLength int64
-			if err := binary.Read(f, binary.LittleEndian, &chunkLength); err == io.EOF || err == io.ErrUnexpectedEOF {
+			entry, err := reader.readBatchEntry(f)
+			if err == io.EOF {
 				break
 			} else if err != nil {
 				return nil, err
 			}
-			chunkBytes := make([]byte, chunkLength)
-			if _, err := io.ReadFull(f, chunkBytes); err != nil {
+			if entry.skips(chunkRef) {
+				continue
+			}
+			chunkBytes, err := entry.Bytes()
+			if err != nil {
 				return nil, err
 			}
 
@@ -497,7 +636,34 @@ func (reader *BinaryBackupReader) readSchemaDataChunkDiffByType(recordType entit
 				return nil, err
 			}
 
-			if crypto.CompareHashes(chunkRef, *diff.Hash()) {
+			if diff.Hash() != nil && crypto.CompareHashes(chunkRef, *diff.Hash()) {
+				return &diff, nil
+			}
+		}
+
+		return nil, services.ErrBackupChunkNotFound
+	case entities.MongoDocument:
+		for {
+			entry, err := reader.readBatchEntry(f)
+			if err == io.EOF {
+				break
+			} else if err != nil {
+				return nil, err
+			}
+			if entry.skips(chunkRef) {
+				continue
+			}
+			chunkBytes, err := entry.Bytes()
+			if err != nil {
+				return nil, err
+			}
+
+			var diff mongo.MongoDocumentChunkDiff
+			if err := diff.DecodeFromBytes(chunkBytes); err != nil {
+				return nil, err
+			}
+
+			if diff.Hash() != nil && crypto.CompareHashes(chunkRef, *diff.Hash()) {
 				return &diff, nil
 			}
 		}
@@ -528,6 +694,42 @@ func (reader *BinaryBackupReader) readRoutineByType(routineType entities.Routine
 			return nil, err
 		}
 		return &trigger, nil
+	case entities.MySQLFunction:
+		var function mysql.MySQLFunction
+		if err := function.DecodeFromBytes(content); err != nil {
+			return nil, err
+		}
+		return &function, nil
+	case entities.MySQLProcedure:
+		var procedure mysql.MySQLProcedure
+		if err := procedure.DecodeFromBytes(content); err != nil {
+			return nil, err
+		}
+		return &procedure, nil
+	case entities.MySQLTrigger:
+		var trigger mysql.MySQLTrigger
+		if err := trigger.DecodeFromBytes(content); err != nil {
+			return nil, err
+		}
+		return &trigger, nil
+	case entities.MySQLEvent:
+		var event mysql.MySQLEvent
+		if err := event.DecodeFromBytes(content); err != nil {
+			return nil, err
+		}
+		return &event, nil
+	case entities.SQLiteTrigger:
+		var trigger sqlite.SQLiteTrigger
+		if err := trigger.DecodeFromBytes(content); err != nil {
+			return nil, err
+		}
+		return &trigger, nil
+	case entities.SQLiteView:
+		var view sqlite.SQLiteView
+		if err := view.DecodeFromBytes(content); err != nil {
+			return nil, err
+		}
+		return &view, nil
 	default:
 		return nil, services.ErrRoutineNotSupported
 	}
@@ -553,7 +755,237 @@ func (reader *BinaryBackupReader) readRoutineDiffByType(routineType entities.Rou
 			return nil, err
 		}
 		return &diff, nil
+	case entities.MySQLFunction, entities.MySQLProcedure, entities.MySQLTrigger, entities.MySQLEvent:
+		var diff mysql.MySQLRoutineDiff
+		if err := diff.DecodeFromBytes(content); err != nil {
+			return nil, err
+		}
+		return &diff, nil
+	case entities.SQLiteTrigger:
+		var diff sqlite.SQLiteTriggerDiff
+		if err := diff.DecodeFromBytes(content); err != nil {
+			return nil, err
+		}
+		return &diff, nil
+	case entities.SQLiteView:
+		var diff sqlite.SQLiteViewDiff
+		if err := diff.DecodeFromBytes(content); err != nil {
+			return nil, err
+		}
+		return &diff, nil
 	default:
 		return nil, services.ErrRoutineNotSupported
 	}
 }
+
+// readFile reads a whole backup file, opening it and decompressing it if it is compressed, and returns its content without
+// its integrity hash. The content of encrypted backups is authenticated when it is opened, so the hash is only checked
+// in the rest of backups.
+func (reader *BinaryBackupReader) readFile(pathToFile string, compressed bool) ([]byte, error) {
+	codec, err := reader.getPayloadCodec()
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := reader.getObject(pathToFile)
+	if err != nil {
+		return nil, err
+	}
+	return decodeBackupFile(codec, pathToFile, data, compressed)
+}
+
+// decodeBackupFile decodes the data of a backup file as readFile does.
+func decodeBackupFile(codec *payloadCodec, pathToFile string, data []byte, compressed bool) ([]byte, error) {
+	var err error
+	if compressed {
+		data, err = codec.decodeFile(pathToFile, data)
+	} else {
+		data, err = codec.open(pathToFile, data)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if len(data) < sha256.Size {
+		return nil, fmt.Errorf("%w: %s", services.ErrBackupCorruptedFile, pathToFile)
+	}
+	if codec.cipher == nil && !crypto.CheckDataSignature(data[:sha256.Size], data[sha256.Size:]) {
+		return nil, fmt.Errorf("%w: %s", services.ErrBackupCorruptedFile, pathToFile)
+	}
+	return data[sha256.Size:], nil
+}
+
+// getObject reads the data of a backup file from the pack file which holds it, or from its own file if it is not packed.
+func (reader *BinaryBackupReader) getObject(name string) ([]byte, error) {
+	packs, err := reader.getPackIndex()
+	if err != nil {
+		return nil, err
+	}
+	if location, ok := packs[name]; ok {
+		return reader.readPackRange(location)
+	}
+	return reader.storage.Get(name)
+}
+
+// readPackRange reads an object from the blocks of its pack file. The blocks are kept in the file cache, so the objects
+// read next from the same blocks are not downloaded again.
+func (reader *BinaryBackupReader) readPackRange(location packLocation) ([]byte, error) {
+	content := make([]byte, 0, location.Length)
+	end := location.Offset + location.Length
+	for blockOffset := location.Offset - location.Offset%packBlockSize; blockOffset < end; blockOffset += packBlockSize {
+		name := fmt.Sprintf("%s@%d", location.Pack, blockOffset)
+		block, ok := reader.cache.get(name)
+		if !ok {
+			var err error
+			if block, err = reader.storage.GetRange(location.Pack, blockOffset, min(packBlockSize, location.PackLength-blockOffset)); err != nil {
+				return nil, err
+			}
+			reader.cache.put(name, block)
+		}
+
+		content = append(content, block[max(location.Offset-blockOffset, 0):min(end-blockOffset, int64(len(block)))]...)
+	}
+	return content, nil
+}
+
+// readBatch reads the whole content of a batch file, which is kept in the batch cache as the rest of its chunks will be
+// read next.
+func (reader *BinaryBackupReader) readBatch(pathToFile string) ([]byte, error) {
+	if data, ok := reader.cache.get(pathToFile); ok {
+		return data, nil
+	}
+
+	data, err := reader.getObject(pathToFile)
+	if err != nil {
+		return nil, err
+	}
+	reader.cache.put(pathToFile, data)
+	return data, nil
+}
+
+// readManifestChunkRefs returns the chunks listed in the manifest of a batch, applying the manifests of diff batches to the
+// chunks of their previous batch.
+func (reader *BinaryBackupReader) readManifestChunkRefs(batchRef string) ([]string, error) {
+	pathToFile := path.Join("data", fmt.Sprintf("%s.hdb", batchRef))
+	content, err := reader.readFile(pathToFile, true)
+	if err != nil {
+		return nil, err
+	}
+
+	var manifest batchManifest
+	if err := manifest.DecodeFromBytes(content, strings.HasPrefix(batchRef, "diffs")); err != nil {
+		return nil, err
+	}
+	if manifest.PrevBatchRef == nil {
+		return manifest.chunkRefs(nil)
+	}
+
+	prevChunkRefs, err := reader.readManifestChunkRefs(*manifest.PrevBatchRef)
+	if err != nil {
+		return nil, err
+	}
+	return manifest.chunkRefs(prevChunkRefs)
+}
+
+// readChunkObject reads a chunk from the chunk store. Chunks saved as a diff are applied to their previous chunk, which is
+// read from the store as well.
+func (reader *BinaryBackupReader) readChunkObject(chunkRef string) (entities.SchemaRecordChunk, bool, error) {
+	codec, err := reader.getPayloadCodec()
+	if err != nil {
+		return nil, false, err
+	}
+
+	content, err := reader.readFile(chunkObjectName(codec.chunkRef(chunkRef)), true)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, false, fmt.Errorf("%w: %s", services.ErrBackupChunkNotFound, chunkRef)
+	} else if err != nil {
+		return nil, false, err
+	}
+
+	chunk, diff, err := decodeChunkObject(content)
+	if err != nil {
+		return nil, false, err
+	}
+	if diff == nil {
+		return chunk, false, nil
+	}
+
+	if diff.GetPrevRef() != nil {
+		prevChunk, _, err := reader.readChunkObject(chunkPrevRef(diff.GetPrevRef()))
+		if err != nil {
+			return nil, false, err
+		}
+		chunk = prevChunk.ApplyDiff(diff)
+	} else {
+		chunk = diff.ApplyDiffFromEmpty()
+	}
+	if chunk == nil {
+		return nil, false, services.ErrBackupCorruptedFile
+	}
+	return chunk, true, nil
+}
+
+// readBatchEntry reads the next chunk or chunk diff of a batch file, returning io.EOF once there are no more.
+func (reader *BinaryBackupReader) readBatchEntry(f io.Reader) (*batchEntry, error) {
+	codec, err := reader.getPayloadCodec()
+	if err != nil {
+		return nil, err
+	}
+
+	var entryLength int64
+	if err := binary.Read(f, binary.LittleEndian, &entryLength); err == io.EOF || err == io.ErrUnexpectedEOF {
+		return nil, io.EOF
+	} else if err != nil {
+		return nil, err
+	}
+	// The entry is read through a limited reader, so the length of a corrupted entry does not allocate more than the file
+	entryBytes, err := io.ReadAll(io.LimitReader(f, entryLength))
+	if err != nil {
+		return nil, err
+	} else if int64(len(entryBytes)) != entryLength {
+		return nil, io.ErrUnexpectedEOF
+	}
+
+	return codec.decodeBatchEntry(entryBytes)
+}
+
+// getPayloadCodec creates the codec saved in the backup metadata the first time it is needed.
+func (reader *BinaryBackupReader) getPayloadCodec() (*payloadCodec, error) {
+	reader.codecOnce.Do(func() {
+		metadata, err := readBackupMetadata(reader.storage)
+		if err != nil {
+			reader.codecErr = err
+			return
+		}
+		reader.chunkStore = metadata.ChunkStore
+		reader.codec, reader.codecErr = newBackupCodec(metadata, reader.passphrase)
+	})
+	return reader.codec, reader.codecErr
+}
+
+// getPackIndex reads the indexes of every pack file of the backup the first time an object is read.
+func (reader *BinaryBackupReader) getPackIndex() (packIndex, error) {
+	reader.packsOnce.Do(func() {
+		codec, err := reader.getPayloadCodec()
+		if err != nil {
+			reader.packsErr = err
+			return
+		}
+		reader.packs, reader.packsErr = readPackIndex(reader.storage, codec)
+	})
+	return reader.packs, reader.packsErr
+}
+
+// usesChunkStore returns whether the record chunks of the backup are kept in the chunk store instead of in its batch files.
+func (reader *BinaryBackupReader) usesChunkStore() (bool, error) {
+	if _, err := reader.getPayloadCodec(); err != nil {
+		return false, err
+	}
+	return reader.chunkStore, nil
+}
+
+// chunkPrevRef returns the chunk reference a chunk diff points to. Diffs of chunks read from a diffs batch prefix their
+// PrevRef with "diffs/", but the chunk references of a batch never have that prefix.
+func chunkPrevRef(prevRef *string) string {
+	return strings.TrimPrefix(*prevRef, "diffs/")
+}
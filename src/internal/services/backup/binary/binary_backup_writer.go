@@ -2,23 +2,298 @@ package binary
 
 import (
 	"bytes"
+	"crypto/sha256"
+	"errors"
 	"fmt"
 	"historydb/src/internal/entities"
 	"historydb/src/internal/services"
 	"historydb/src/internal/services/backup/base"
+	storage_services "historydb/src/internal/services/storage"
+	"historydb/src/internal/utils/crypto"
+	"historydb/src/internal/utils/decode"
 	"historydb/src/internal/utils/encode"
 	"historydb/src/internal/utils/pointers"
 	"io/fs"
+	"maps"
 	"os"
+	"path"
 	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
 )
 
+// BinaryBackupWriter saves the backup files, compressing and sealing them with the codec of the backup.
+//
+// New backups are compressed and encrypted as set in the options the writer is created with, while the snapshots of an
+// existing backup keep the codec and data key saved in its metadata, so every file of a backup is read with the same codec.
+// New backups keep their record chunks in the chunk store, while older backups keep saving them inside their batch files.
+//
+// If the backup bounds its diff chains, a diff which would make the chain of its object deeper than the maximum depth is
+// saved as the full object instead, so the reader never applies more diffs than the maximum to rebuild an object. Backups
+// without a chunk store cannot be given a maximum depth, as their batch files hold the chunk diffs themselves.
+//
+// Backups with reverse deltas save every object of a snapshot in full, and once the snapshot is committed the record chunks
+// of the snapshot before it are saved again, under the same name, as diffs of the chunks which replaced them. The last
+// snapshot is then restored without applying any diff, while the older ones walk the diffs back from it.
 type BinaryBackupWriter struct {
 	base.BaseBackupWriter
+
+	options    BinaryBackupOptions
+	codec      *payloadCodec
+	encryption *entities.BackupEncryption
+
+	chunkStore    bool
+	storedChunks  map[string]bool
+	manifests     map[string]*batchManifest
+	packedObjects packIndex
+
+	// maxChainDepth is the maximum number of diffs applied to rebuild an object, 0 if the chains are not bounded, and
+	// chainReader reads the objects of the backup the diffs are applied to
+	maxChainDepth int64
+	chainReader   *BinaryBackupReader
+
+	// latestChunks are the chunks saved in full as chunks of the snapshot, reverseChunks the chunk diffs the chunks they
+	// replaced are saved as once the snapshot is committed, and replacedChunks the stored chunks saved again in full, in
+	// backups with reverse deltas
+	reverseDeltas  bool
+	latestChunks   map[string]bool
+	reverseChunks  map[string][]byte
+	replacedChunks map[string]bool
+
+	// mu guards the chunks and manifests of the snapshot, as the records of several schemas can be saved at once
+	mu sync.Mutex
+}
+
+func NewBinaryBackupWriter(storage storage_services.Storage, options BinaryBackupOptions) *BinaryBackupWriter {
+	return &BinaryBackupWriter{BaseBackupWriter: base.BaseBackupWriter{Storage: storage}, options: options}
+}
+
+// BeginSnapshot begins the snapshot with the codec of the backup, or with the codec of the writer if the backup is new.
+func (writer *BinaryBackupWriter) BeginSnapshot(snapshot *entities.BackupSnapshot) error {
+	var codec *payloadCodec
+	var encryption *entities.BackupEncryption
+	var chainReader *BinaryBackupReader
+	chunkStore := true
+	maxChainDepth := int64(0)
+	reverseDeltas := writer.options.ReverseDeltas
+	prevSnapshotId := ""
+	if metadata, err := readBackupMetadata(writer.Storage); err == nil {
+		encryption = metadata.Encryption
+		chunkStore = metadata.ChunkStore
+		maxChainDepth = metadata.MaxChainDepth
+		reverseDeltas = metadata.ReverseDeltas
+		chainReader = NewBinaryBackupReader(writer.Storage, writer.options.Passphrase)
+		if codec, err = newBackupCodec(metadata, writer.options.Passphrase); err != nil {
+			return err
+		}
+		if prevSnapshotId, err = lastSnapshotId(metadata, codec); err != nil {
+			return err
+		}
+	} else if errors.Is(err, services.ErrBackupDirNotExists) {
+		if codec, encryption, err = writer.newCodec(); err != nil {
+			return err
+		}
+	} else {
+		return err
+	}
+	if writer.options.MaxChainDepth > 0 {
+		if !chunkStore {
+			return services.ErrChainDepthNotSupported
+		}
+		maxChainDepth = writer.options.MaxChainDepth
+	}
+
+	// The objects already in the backup are listed once, so the objects found again are not saved twice
+	packedObjects, err := readPackIndex(writer.Storage, codec)
+	if err != nil {
+		return err
+	}
+	storedChunks := make(map[string]bool)
+	if chunkStore {
+		names, err := writer.Storage.List("chunks/")
+		if err != nil {
+			return err
+		}
+		for _, name := range names {
+			storedChunks[name] = true
+		}
+		for name := range packedObjects {
+			if strings.HasPrefix(name, "chunks/") {
+				storedChunks[name] = true
+			}
+		}
+	}
+
+	if err := writer.BaseBackupWriter.BeginSnapshot(snapshot); err != nil {
+		return err
+	}
+
+	writer.Checkpoint.PrevSnapshotId = prevSnapshotId
+	writer.codec = codec
+	writer.encryption = encryption
+	writer.chunkStore = chunkStore
+	writer.storedChunks = storedChunks
+	writer.manifests = make(map[string]*batchManifest)
+	writer.packedObjects = packedObjects
+	writer.maxChainDepth = maxChainDepth
+	writer.chainReader = chainReader
+	// The reverse diffs of the chunks saved before an interruption are not kept, so a resumed snapshot leaves them in full
+	writer.reverseDeltas = reverseDeltas && chunkStore
+	writer.latestChunks = make(map[string]bool)
+	writer.reverseChunks = make(map[string][]byte)
+	writer.replacedChunks = make(map[string]bool)
+	return nil
+}
+
+// newCodec creates the codec of a new backup from the options of the writer, with a new data key if it is encrypted.
+func (writer *BinaryBackupWriter) newCodec() (*payloadCodec, *entities.BackupEncryption, error) {
+	if !writer.options.Encrypt {
+		codec, err := newPayloadCodec(writer.options.Compression, writer.options.CompressionLevel, nil)
+		return codec, nil, err
+	}
+	if writer.options.Passphrase == nil {
+		return nil, nil, services.ErrBackupKeyRequired
+	}
+
+	encryption, dataKey, err := newBackupEncryption(writer.options.Passphrase)
+	if err != nil {
+		return nil, nil, err
+	}
+	cipher, err := newPayloadCipher(dataKey)
+	if err != nil {
+		return nil, nil, err
+	}
+	codec, err := newPayloadCodec(writer.options.Compression, writer.options.CompressionLevel, cipher)
+	return codec, encryption, err
+}
+
+// lastSnapshotId returns the identifier of the last snapshot listed in the metadata, opening the snapshots of an encrypted backup.
+func lastSnapshotId(metadata entities.BackupMetadata, codec *payloadCodec) (string, error) {
+	if metadata.Encryption != nil {
+		snapshots, err := codec.open("metadata.hdb", metadata.SealedSnapshots)
+		if err != nil {
+			return "", err
+		}
+		if err := metadata.DecodeSnapshots(snapshots); err != nil {
+			return "", err
+		}
+	}
+
+	if len(metadata.Snapshots) == 0 {
+		return "", nil
+	}
+	return metadata.Snapshots[len(metadata.Snapshots)-1].SnapshotId, nil
+}
+
+// ResumeSnapshot begins again the last snapshot interrupted before its commit as the given snapshot, keeping the files it
+// staged, and returns its checkpoint. It returns nil if there is no snapshot to resume. A snapshot begun from another state
+// of the backup is discarded, failing with ErrBackupCheckpointOutdated.
+func (writer *BinaryBackupWriter) ResumeSnapshot(snapshot *entities.BackupSnapshot) (*entities.BackupCheckpoint, error) {
+	checkpoint, err := writer.FindCheckpoint()
+	if err != nil || checkpoint == nil {
+		return nil, err
+	}
+
+	snapshot.SnapshotId = checkpoint.SnapshotId
+	snapshot.Timestamp = checkpoint.Timestamp
+	snapshot.Message = checkpoint.Message
+	if err := writer.BeginSnapshot(snapshot); err != nil {
+		return nil, err
+	}
+	if writer.Checkpoint.PrevSnapshotId != checkpoint.PrevSnapshotId {
+		writer.TxSnapshot = nil
+		writer.Checkpoint = nil
+		if err := writer.DiscardCheckpoint(checkpoint); err != nil {
+			return nil, err
+		}
+		return nil, services.ErrBackupCheckpointOutdated
+	}
+
+	// The batches of the schemas which were being saved are only kept up to the last checkpoint, so the batch files staged
+	// after it, which may be incomplete, are removed
+	savedBatches := make(map[string]bool)
+	for _, progress := range checkpoint.Schemas {
+		if progress.Data == nil {
+			continue
+		}
+		// The batches saved as full manifests, as their chain was too deep, are staged without the diffs prefix
+		for _, batchRef := range progress.Data.Data {
+			savedBatches[path.Join("data", fmt.Sprintf("%s.hdb", batchRef))] = true
+			savedBatches[path.Join("data", fmt.Sprintf("%s.hdb", strings.TrimPrefix(batchRef, "diffs/")))] = true
+		}
+	}
+	if err := filepath.WalkDir(filepath.Join(writer.TransactionPath(), "data"), func(pathToFile string, d fs.DirEntry, err error) error {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil
+		} else if err != nil {
+			return err
+		}
+		if !d.IsDir() && !savedBatches[writer.fileName(pathToFile)] {
+			return os.Remove(pathToFile)
+		}
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+
+	// The checkpoint returned is a copy, as the one of the writer is updated while the schemas are saved
+	writer.Checkpoint = checkpoint
+	resumed := *checkpoint
+	resumed.Schemas = maps.Clone(checkpoint.Schemas)
+	return &resumed, nil
+}
+
+// SaveSnapshotProgress saves the progress of the records of a schema into the checkpoint of the snapshot. The manifests of
+// the diff batches saved so far are staged, so they are kept if the snapshot is resumed.
+func (writer *BinaryBackupWriter) SaveSnapshotProgress(schemaName string, progress *entities.BackupSchemaProgress) error {
+	if writer.TxSnapshot == nil {
+		return services.ErrBackupTransactionNotFound
+	}
+
+	if progress.Data != nil && writer.chunkStore {
+		for _, batchRef := range progress.Data.Data {
+			writer.mu.Lock()
+			_, ok := writer.manifests[batchRef]
+			writer.mu.Unlock()
+			if !ok {
+				continue
+			}
+
+			if err := writer.saveBatchManifest(batchRef, batchRef); err != nil {
+				return err
+			}
+		}
+	}
+
+	return writer.SaveCheckpoint(schemaName, progress)
 }
 
-func NewBinaryBackupWriter(backupPath string) *BinaryBackupWriter {
-	return &BinaryBackupWriter{BaseBackupWriter: base.BaseBackupWriter{BackupPath: backupPath}}
+// RotateBackupKey wraps the data key of the backup with a new passphrase. The data key is kept, so no file but the
+// metadata needs to be written again.
+func (writer *BinaryBackupWriter) RotateBackupKey(newPassphrase []byte) error {
+	if writer.TxSnapshot != nil {
+		return services.ErrBackupTransactionInProgress
+	}
+
+	metadata, err := readBackupMetadata(writer.Storage)
+	if err != nil {
+		return err
+	}
+	if metadata.Encryption == nil {
+		return services.ErrBackupNotEncrypted
+	}
+
+	dataKey, err := unwrapDataKey(metadata.Encryption, writer.options.Passphrase)
+	if err != nil {
+		return err
+	}
+	if metadata.Encryption, err = wrapDataKey(dataKey, newPassphrase); err != nil {
+		return err
+	}
+
+	// The metadata holds the only copy of the data key, which the storage replaces at once
+	return writer.Storage.Put("metadata.hdb", metadata.EncodeToBytes())
 }
 
 func (writer *BinaryBackupWriter) CommitSnapshot(metadata *entities.BackupMetadata) error {
@@ -26,48 +301,69 @@ func (writer *BinaryBackupWriter) CommitSnapshot(metadata *entities.BackupMetada
 		return services.ErrBackupTransactionNotFound
 	}
 
+	// The manifests of diff batches are complete once every schema is saved
+	for batchRef := range writer.manifests {
+		if err := writer.saveBatchManifest(batchRef, batchRef); err != nil {
+			return err
+		}
+	}
+	if writer.maxChainDepth > 0 || writer.reverseDeltas {
+		writer.referenceSavedObjects()
+	}
+
 	content := writer.TxSnapshot.EncodeToBytes()
-	pathToFile := filepath.Join(writer.BackupPath, writer.TxSnapshot.SnapshotId, "snapshots", fmt.Sprintf("%s.hdb", writer.TxSnapshot.SnapshotId))
+	pathToFile := filepath.Join(writer.TransactionPath(), "snapshots", fmt.Sprintf("%s.hdb", writer.TxSnapshot.SnapshotId))
 	if err := os.MkdirAll(filepath.Dir(pathToFile), 0755); err != nil {
 		return err
 	}
-	if err := os.WriteFile(pathToFile, content, 0644); err != nil {
+	if err := os.WriteFile(pathToFile, writer.codec.seal(writer.fileName(pathToFile), content), 0644); err != nil {
 		return err
 	}
 
-	content = metadata.EncodeToBytes()
-	pathToFile = filepath.Join(writer.BackupPath, "metadata.hdb")
-	if err := os.WriteFile(pathToFile, content, 0644); err != nil {
+	var deletes []string
+	if writer.reverseDeltas {
+		var err error
+		if deletes, err = writer.saveReverseChunks(); err != nil {
+			return err
+		}
+	}
+
+	if err := writer.packObjects(); err != nil {
 		return err
 	}
+	metadata.MaxChainDepth = writer.maxChainDepth
+	metadata.ReverseDeltas = writer.reverseDeltas
+	return writer.commitStagedFiles(metadata, deletes)
+}
 
-	transactionDir := filepath.Join(writer.BackupPath, writer.TxSnapshot.SnapshotId)
-	if err := filepath.WalkDir(transactionDir, func(path string, d fs.DirEntry, err error) error {
+// commitStagedFiles commits every file staged in the transaction along with the metadata, and deletes the files in deletes
+// once the metadata is committed.
+func (writer *BinaryBackupWriter) commitStagedFiles(metadata *entities.BackupMetadata, deletes []string) error {
+	// Packs are committed before their indexes, so an index never lists objects which are not saved yet
+	stagedFiles := []string{}
+	if err := filepath.WalkDir(writer.TransactionPath(), func(path string, d fs.DirEntry, err error) error {
 		if err != nil {
 			return err
 		}
-		if d.IsDir() {
-			return nil
-		}
-
-		rel, err := filepath.Rel(transactionDir, path)
-		if err != nil {
-			return err
+		if !d.IsDir() && !strings.HasPrefix(writer.fileName(path), base.CheckpointName) {
+			stagedFiles = append(stagedFiles, writer.fileName(path))
 		}
-
-		dst := filepath.Join(writer.BackupPath, rel)
-		err = os.Rename(path, dst)
-		return err
+		return nil
 	}); err != nil {
 		return err
 	}
-
-	if err := os.RemoveAll(transactionDir); err != nil {
-		return fmt.Errorf("failed to remove backup transaction dir %s: %w", transactionDir, err)
+	packs := slices.DeleteFunc(slices.Clone(stagedFiles), func(name string) bool { return path.Ext(name) != ".pack" })
+	rest := slices.DeleteFunc(stagedFiles, func(name string) bool { return path.Ext(name) == ".pack" })
+
+	metadata.Compression = writer.codec.compression
+	metadata.CompressionLevel = writer.codec.level
+	metadata.Encryption = writer.encryption
+	metadata.ChunkStore = writer.chunkStore
+	if writer.encryption != nil {
+		metadata.SealedSnapshots = writer.codec.seal("metadata.hdb", metadata.EncodeSnapshots())
 	}
 
-	writer.TxSnapshot = nil
-	return nil
+	return writer.CommitTransaction(slices.Concat(packs, rest), metadata.EncodeToBytes(), deletes)
 }
 
 func (writer *BinaryBackupWriter) SaveSchemaDependency(dependency entities.SchemaDependency) error {
@@ -78,12 +374,12 @@ func (writer *BinaryBackupWriter) SaveSchemaDependency(dependency entities.Schem
 	content := dependency.EncodeToBytes()
 	hash := dependency.Hash()
 
-	pathToFile := filepath.Join(writer.BackupPath, writer.TxSnapshot.SnapshotId, "schemas", "dependencies", fmt.Sprintf("%s.hdb", hash))
+	pathToFile := filepath.Join(writer.TransactionPath(), "schemas", "dependencies", fmt.Sprintf("%s.hdb", hash))
 	if err := os.MkdirAll(filepath.Dir(pathToFile), 0755); err != nil {
 		return err
 	}
 
-	return os.WriteFile(pathToFile, content, 0644)
+	return os.WriteFile(pathToFile, writer.codec.encodeFile(writer.fileName(pathToFile), content), 0644)
 }
 
 func (writer *BinaryBackupWriter) SaveSchemaDependencyDiff(diff entities.SchemaDependencyDiff) error {
@@ -94,12 +390,22 @@ func (writer *BinaryBackupWriter) SaveSchemaDependencyDiff(diff entities.SchemaD
 	content := diff.EncodeToBytes()
 	hash := diff.Hash()
 
-	pathToFile := filepath.Join(writer.BackupPath, writer.TxSnapshot.SnapshotId, "schemas", "dependencies", "diffs", fmt.Sprintf("%s.hdb", hash))
+	if prevRef, full, err := writer.diffExceedsChainDepth("schemas/dependencies", content); err != nil {
+		return err
+	} else if full {
+		dependency, _, err := writer.chainReader.GetSchemaDependency(prevRef)
+		if err != nil {
+			return err
+		}
+		return writer.SaveSchemaDependency(dependency.ApplyDiff(diff))
+	}
+
+	pathToFile := filepath.Join(writer.TransactionPath(), "schemas", "dependencies", "diffs", fmt.Sprintf("%s.hdb", hash))
 	if err := os.MkdirAll(filepath.Dir(pathToFile), 0755); err != nil {
 		return err
 	}
 
-	return os.WriteFile(pathToFile, content, 0644)
+	return os.WriteFile(pathToFile, writer.codec.encodeFile(writer.fileName(pathToFile), content), 0644)
 }
 
 func (writer *BinaryBackupWriter) SaveSchema(schema entities.Schema) error {
@@ -110,12 +416,12 @@ func (writer *BinaryBackupWriter) SaveSchema(schema entities.Schema) error {
 	content := schema.EncodeToBytes()
 	hash := schema.Hash()
 
-	pathToFile := filepath.Join(writer.BackupPath, writer.TxSnapshot.SnapshotId, "schemas", fmt.Sprintf("%s.hdb", hash))
+	pathToFile := filepath.Join(writer.TransactionPath(), "schemas", fmt.Sprintf("%s.hdb", hash))
 	if err := os.MkdirAll(filepath.Dir(pathToFile), 0755); err != nil {
 		return err
 	}
 
-	return os.WriteFile(pathToFile, content, 0644)
+	return os.WriteFile(pathToFile, writer.codec.encodeFile(writer.fileName(pathToFile), content), 0644)
 }
 
 func (writer *BinaryBackupWriter) SaveSchemaDiff(diff entities.SchemaDiff) error {
@@ -126,20 +432,42 @@ func (writer *BinaryBackupWriter) SaveSchemaDiff(diff entities.SchemaDiff) error
 	content := diff.EncodeToBytes()
 	hash := diff.Hash()
 
-	pathToFile := filepath.Join(writer.BackupPath, writer.TxSnapshot.SnapshotId, "schemas", "diffs", fmt.Sprintf("%s.hdb", hash))
+	if prevRef, full, err := writer.diffExceedsChainDepth("schemas", content); err != nil {
+		return err
+	} else if full {
+		schema, _, err := writer.chainReader.GetSchema(prevRef)
+		if err != nil {
+			return err
+		}
+		return writer.SaveSchema(schema.ApplyDiff(diff))
+	}
+
+	pathToFile := filepath.Join(writer.TransactionPath(), "schemas", "diffs", fmt.Sprintf("%s.hdb", hash))
 	if err := os.MkdirAll(filepath.Dir(pathToFile), 0755); err != nil {
 		return err
 	}
 
-	return os.WriteFile(pathToFile, content, 0644)
+	return os.WriteFile(pathToFile, writer.codec.encodeFile(writer.fileName(pathToFile), content), 0644)
 }
 
 func (writer *BinaryBackupWriter) SaveSchemaRecordChunk(batchRef string, chunk entities.SchemaRecordChunk) error {
 	if writer.TxSnapshot == nil {
 		return services.ErrBackupTransactionNotFound
 	}
+	if writer.chunkStore {
+		hash := chunk.Hash()
+		if err := writer.saveChunk(hash, chunk); err != nil {
+			return err
+		}
+		writer.addManifestEntry(batchRef, nil, chunk.GetRecordType(), batchManifestEntry{Hash: &hash})
+		return nil
+	}
 
-	pathToFile := filepath.Join(writer.BackupPath, writer.TxSnapshot.SnapshotId, "data", fmt.Sprintf("%s.hdb", batchRef))
+	encodedChunk, err := chunk.EncodeToBytes()
+	if err != nil {
+		return err
+	}
+	pathToFile := filepath.Join(writer.TransactionPath(), "data", fmt.Sprintf("%s.hdb", batchRef))
 	if err := os.MkdirAll(filepath.Dir(pathToFile), 0755); err != nil {
 		return err
 	}
@@ -161,7 +489,7 @@ func (writer *BinaryBackupWriter) SaveSchemaRecordChunk(batchRef string, chunk e
 		f.Write(recordTypeBytes.Bytes())
 	}
 
-	content := chunk.EncodeToBytes()
+	content := writer.codec.encodeBatchEntry(pointers.Ptr(chunk.Hash()), encodedChunk)
 	_, err = f.Write(content)
 	return err
 }
@@ -170,8 +498,22 @@ func (writer *BinaryBackupWriter) SaveSchemaRecordChunkDiff(prevBatchRef, batchR
 	if writer.TxSnapshot == nil {
 		return services.ErrBackupTransactionNotFound
 	}
+	if writer.chunkStore {
+		// Removed chunks only change the chunk list of the batch
+		if hash := chunk.Hash(); hash != nil {
+			if err := writer.saveChunkDiffObject(*hash, chunk); err != nil {
+				return err
+			}
+		}
+		writer.addManifestEntry(batchRef, &prevBatchRef, chunk.GetRecordType(), newDiffManifestEntry(chunk))
+		return nil
+	}
 
-	pathToFile := filepath.Join(writer.BackupPath, writer.TxSnapshot.SnapshotId, "data", fmt.Sprintf("%s.hdb", batchRef))
+	encodedDiff, err := chunk.EncodeToBytes()
+	if err != nil {
+		return err
+	}
+	pathToFile := filepath.Join(writer.TransactionPath(), "data", fmt.Sprintf("%s.hdb", batchRef))
 	if err := os.MkdirAll(filepath.Dir(pathToFile), 0755); err != nil {
 		return err
 	}
@@ -194,7 +536,7 @@ func (writer *BinaryBackupWriter) SaveSchemaRecordChunkDiff(prevBatchRef, batchR
 		f.Write(batchInit.Bytes())
 	}
 
-	content := chunk.EncodeToBytes()
+	content := writer.codec.encodeBatchEntry(chunk.Hash(), encodedDiff)
 	_, err = f.Write(content)
 	return err
 }
@@ -203,9 +545,12 @@ func (writer *BinaryBackupWriter) SaveSchemaRecordBatch(batchTempRef, batchRef s
 	if writer.TxSnapshot == nil {
 		return services.ErrBackupTransactionNotFound
 	}
+	if writer.chunkStore {
+		return writer.saveBatchManifest(batchTempRef, batchRef)
+	}
 
-	oldPath := filepath.Join(writer.BackupPath, writer.TxSnapshot.SnapshotId, "data", fmt.Sprintf("%s.hdb", batchTempRef))
-	newPath := filepath.Join(writer.BackupPath, writer.TxSnapshot.SnapshotId, "data", fmt.Sprintf("%s.hdb", batchRef))
+	oldPath := filepath.Join(writer.TransactionPath(), "data", fmt.Sprintf("%s.hdb", batchTempRef))
+	newPath := filepath.Join(writer.TransactionPath(), "data", fmt.Sprintf("%s.hdb", batchRef))
 	return os.Rename(oldPath, newPath)
 }
 
@@ -217,12 +562,12 @@ func (writer *BinaryBackupWriter) SaveRoutine(routine entities.Routine) error {
 	content := routine.EncodeToBytes()
 	hash := routine.Hash()
 
-	pathToFile := filepath.Join(writer.BackupPath, writer.TxSnapshot.SnapshotId, "routines", fmt.Sprintf("%s.hdb", hash))
+	pathToFile := filepath.Join(writer.TransactionPath(), "routines", fmt.Sprintf("%s.hdb", hash))
 	if err := os.MkdirAll(filepath.Dir(pathToFile), 0755); err != nil {
 		return err
 	}
 
-	return os.WriteFile(pathToFile, content, 0644)
+	return os.WriteFile(pathToFile, writer.codec.encodeFile(writer.fileName(pathToFile), content), 0644)
 }
 
 func (writer *BinaryBackupWriter) SaveRoutineDiff(diff entities.RoutineDiff) error {
@@ -233,10 +578,429 @@ func (writer *BinaryBackupWriter) SaveRoutineDiff(diff entities.RoutineDiff) err
 	content := diff.EncodeToBytes()
 	hash := diff.Hash()
 
-	pathToFile := filepath.Join(writer.BackupPath, writer.TxSnapshot.SnapshotId, "routines", "diffs", fmt.Sprintf("%s.hdb", hash))
+	if prevRef, full, err := writer.diffExceedsChainDepth("routines", content); err != nil {
+		return err
+	} else if full {
+		routine, _, err := writer.chainReader.GetRoutine(prevRef)
+		if err != nil {
+			return err
+		}
+		return writer.SaveRoutine(routine.ApplyDiff(diff))
+	}
+
+	pathToFile := filepath.Join(writer.TransactionPath(), "routines", "diffs", fmt.Sprintf("%s.hdb", hash))
 	if err := os.MkdirAll(filepath.Dir(pathToFile), 0755); err != nil {
 		return err
 	}
 
-	return os.WriteFile(pathToFile, content, 0644)
+	return os.WriteFile(pathToFile, writer.codec.encodeFile(writer.fileName(pathToFile), content), 0644)
+}
+
+// packObjects moves every object staged in the transaction into pack files, leaving only the snapshot file on its own.
+// Objects which are already packed in the backup are dropped, as their name is the hash of their content.
+func (writer *BinaryBackupWriter) packObjects() error {
+	transactionDir := writer.TransactionPath()
+	objects := []string{}
+	if err := filepath.WalkDir(transactionDir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			return nil
+		}
+
+		if name := writer.fileName(path); !strings.HasPrefix(name, "snapshots/") && !strings.HasPrefix(name, "packs/") && !strings.HasPrefix(name, base.CheckpointName) {
+			objects = append(objects, path)
+		}
+		return nil
+	}); err != nil {
+		return err
+	}
+
+	pathTo := func(name string) string { return filepath.Join(transactionDir, filepath.FromSlash(name)) }
+	var pack *stagedPack
+	for _, pathToFile := range objects {
+		if _, ok := writer.packedObjects[writer.fileName(pathToFile)]; !ok {
+			data, err := os.ReadFile(pathToFile)
+			if err != nil {
+				return err
+			}
+
+			if pack == nil {
+				if pack, err = newStagedPack(writer.codec, pathTo); err != nil {
+					return err
+				}
+			}
+			if err := pack.add(writer.fileName(pathToFile), data); err != nil {
+				pack.f.Close()
+				return err
+			}
+			if pack.size >= packTargetSize {
+				if err := pack.close(); err != nil {
+					return err
+				}
+				pack = nil
+			}
+		}
+
+		if err := os.Remove(pathToFile); err != nil {
+			return err
+		}
+	}
+
+	if pack != nil {
+		return pack.close()
+	}
+	return nil
+}
+
+// saveChunkObject stages a chunk, or a chunk diff if isDiff is true, in the chunk store, unless the store already has a
+// chunk with the same hash. Chunk diffs are kept by the hash of the chunk they result in, so any batch can read them.
+func (writer *BinaryBackupWriter) saveChunkObject(hash string, recordType entities.RecordType, isDiff bool, content []byte) error {
+	name := chunkObjectName(writer.codec.chunkRef(hash))
+	writer.mu.Lock()
+	stored := writer.storedChunks[name]
+	writer.storedChunks[name] = true
+	writer.mu.Unlock()
+	if stored {
+		return nil
+	}
+
+	pathToFile := filepath.Join(writer.TransactionPath(), filepath.FromSlash(name))
+	err := os.MkdirAll(filepath.Dir(pathToFile), 0755)
+	if err == nil {
+		err = os.WriteFile(pathToFile, writer.codec.encodeFile(name, encodeChunkObject(recordType, isDiff, content)), 0644)
+	}
+	if err != nil {
+		writer.mu.Lock()
+		delete(writer.storedChunks, name)
+		writer.mu.Unlock()
+	}
+	return err
+}
+
+// addManifestEntry adds an entry to the manifest of a batch, which is kept in memory until the batch is complete.
+func (writer *BinaryBackupWriter) addManifestEntry(batchRef string, prevBatchRef *string, recordType entities.RecordType, entry batchManifestEntry) {
+	writer.mu.Lock()
+	defer writer.mu.Unlock()
+
+	manifest, ok := writer.manifests[batchRef]
+	if !ok {
+		manifest = &batchManifest{RecordType: recordType, PrevBatchRef: prevBatchRef}
+		writer.manifests[batchRef] = manifest
+	}
+	manifest.Entries = append(manifest.Entries, entry)
+}
+
+// saveBatchManifest stages the manifest of the batch batchTempRef as the file of the batch batchRef.
+func (writer *BinaryBackupWriter) saveBatchManifest(batchTempRef, batchRef string) error {
+	writer.mu.Lock()
+	manifest, ok := writer.manifests[batchTempRef]
+	delete(writer.manifests, batchTempRef)
+	writer.mu.Unlock()
+	if !ok {
+		return fmt.Errorf("batch %s has no chunks: %w", batchTempRef, fs.ErrNotExist)
+	}
+
+	// Diff manifests which would make the chain of the batch too deep are saved as full manifests, without the diffs prefix
+	if manifest.PrevBatchRef != nil {
+		full, err := writer.exceedsChainDepth(*manifest.PrevBatchRef, writer.manifestPrevRef)
+		if err != nil {
+			return err
+		}
+		if full {
+			prevChunkRefs, err := writer.chainReader.readManifestChunkRefs(*manifest.PrevBatchRef)
+			if err != nil {
+				return err
+			}
+			chunkRefs, err := manifest.chunkRefs(prevChunkRefs)
+			if err != nil {
+				return err
+			}
+
+			manifest = &batchManifest{RecordType: manifest.RecordType}
+			for _, chunkRef := range chunkRefs {
+				manifest.Entries = append(manifest.Entries, batchManifestEntry{Hash: pointers.Ptr(chunkRef)})
+			}
+			batchRef = strings.TrimPrefix(batchRef, "diffs/")
+		}
+	}
+
+	pathToFile := filepath.Join(writer.TransactionPath(), "data", fmt.Sprintf("%s.hdb", batchRef))
+	if err := os.MkdirAll(filepath.Dir(pathToFile), 0755); err != nil {
+		return err
+	}
+	return os.WriteFile(pathToFile, writer.codec.encodeFile(writer.fileName(pathToFile), manifest.EncodeToBytes()), 0644)
+}
+
+// saveChunkDiffObject stages a chunk diff in the chunk store, or the chunk it results in if the diff would make the chain
+// of the chunk too deep.
+func (writer *BinaryBackupWriter) saveChunkDiffObject(hash string, diff entities.SchemaRecordChunkDiff) error {
+	if writer.reverseDeltas {
+		return writer.saveReverseChunkDiff(hash, diff)
+	}
+
+	full := false
+	if diff.GetPrevRef() != nil {
+		var err error
+		if full, err = writer.exceedsChainDepth(chunkPrevRef(diff.GetPrevRef()), writer.chunkPrevRef); err != nil {
+			return err
+		}
+	}
+	if !full {
+		content, err := diff.EncodeToBytes()
+		if err != nil {
+			return err
+		}
+		return writer.saveChunkObject(hash, diff.GetRecordType(), true, content)
+	}
+
+	prevChunk, _, err := writer.chainReader.readChunkObject(chunkPrevRef(diff.GetPrevRef()))
+	if err != nil {
+		return err
+	}
+	chunk := prevChunk.ApplyDiff(diff)
+	if chunk == nil {
+		return services.ErrBackupCorruptedFile
+	}
+	content, err := chunk.EncodeToBytes()
+	if err != nil {
+		return err
+	}
+	return writer.saveChunkObject(hash, chunk.GetRecordType(), false, content)
+}
+
+// diffExceedsChainDepth returns the previous object of the diff of dir with the given content, whose data begins with its
+// reference after the integrity hash, and whether the diff would make the chain of the object deeper than the maximum
+// depth of the backup.
+func (writer *BinaryBackupWriter) diffExceedsChainDepth(dir string, content []byte) (string, bool, error) {
+	prevRef, err := decode.DecodeString(bytes.NewBuffer(content[sha256.Size:]))
+	if err != nil {
+		return "", false, err
+	}
+
+	full, err := writer.exceedsChainDepth(*prevRef, func(ref string) (string, bool, error) {
+		if !strings.HasPrefix(ref, "diffs") {
+			return "", false, nil
+		}
+		content, err := writer.chainReader.readFile(objectFileName(dir, ref), true)
+		if err != nil {
+			return "", false, err
+		}
+		prevRef, err := decode.DecodeString(bytes.NewBuffer(content))
+		if err != nil {
+			return "", false, err
+		}
+		return *prevRef, true, nil
+	})
+	return *prevRef, full, err
+}
+
+// exceedsChainDepth returns whether a diff applied to the stored object prevRef would make its chain deeper than the
+// maximum depth of the backup. prev returns the previous object of an object and whether it has one, and the chain is
+// only followed up to the maximum depth.
+func (writer *BinaryBackupWriter) exceedsChainDepth(prevRef string, prev func(ref string) (string, bool, error)) (bool, error) {
+	// Backups with reverse deltas save every object of the snapshot in full
+	if writer.reverseDeltas {
+		return writer.chainReader != nil, nil
+	}
+	if writer.maxChainDepth <= 0 || writer.chainReader == nil {
+		return false, nil
+	}
+
+	ref := prevRef
+	for depth := int64(0); depth < writer.maxChainDepth; depth++ {
+		prevRef, ok, err := prev(ref)
+		if err != nil || !ok {
+			return false, err
+		}
+		ref = prevRef
+	}
+	return true, nil
+}
+
+// manifestPrevRef returns the previous batch of a stored batch manifest, if it is the manifest of a diff batch.
+func (writer *BinaryBackupWriter) manifestPrevRef(batchRef string) (string, bool, error) {
+	content, err := writer.chainReader.readFile(objectFileName("data", batchRef), true)
+	if err != nil {
+		return "", false, err
+	}
+
+	var manifest batchManifest
+	if err := manifest.DecodeFromBytes(content, strings.HasPrefix(batchRef, "diffs")); err != nil {
+		return "", false, err
+	}
+	if manifest.PrevBatchRef == nil {
+		return "", false, nil
+	}
+	return *manifest.PrevBatchRef, true, nil
+}
+
+// chunkPrevRef returns the previous chunk of a stored chunk, if it is saved as a diff of another chunk.
+func (writer *BinaryBackupWriter) chunkPrevRef(chunkRef string) (string, bool, error) {
+	content, err := writer.chainReader.readFile(chunkObjectName(writer.codec.chunkRef(chunkRef)), true)
+	if err != nil {
+		return "", false, err
+	}
+
+	_, diff, err := decodeChunkObject(content)
+	if err != nil {
+		return "", false, err
+	}
+	if diff == nil || diff.GetPrevRef() == nil {
+		return "", false, nil
+	}
+	return chunkPrevRef(diff.GetPrevRef()), true, nil
+}
+
+// referenceSavedObjects changes the references of the snapshot to the diffs which were saved as full objects, as their
+// chain was too deep, to the full objects staged in their place.
+func (writer *BinaryBackupWriter) referenceSavedObjects() {
+	saved := func(dir, ref string) (string, bool) {
+		fullRef, ok := strings.CutPrefix(ref, "diffs/")
+		if !ok {
+			return ref, false
+		}
+		_, err := os.Stat(filepath.Join(writer.TransactionPath(), filepath.FromSlash(objectFileName(dir, fullRef))))
+		return fullRef, err == nil
+	}
+	referenceObjects := func(dir string, refs map[string]string) {
+		for name, ref := range refs {
+			if fullRef, ok := saved(dir, ref); ok {
+				refs[name] = fullRef
+			}
+		}
+	}
+
+	referenceObjects("schemas/dependencies", writer.TxSnapshot.SchemaDependencies)
+	referenceObjects("schemas", writer.TxSnapshot.Schemas)
+	referenceObjects("routines", writer.TxSnapshot.Routines)
+	if !writer.chunkStore {
+		return
+	}
+	for _, schemaData := range writer.TxSnapshot.Data {
+		for i, batchRef := range schemaData.Data {
+			if fullRef, ok := saved("data", batchRef); ok {
+				schemaData.Data[i] = fullRef
+			}
+		}
+	}
+}
+
+// fileName returns the name a file of the snapshot transaction has inside the backup once the snapshot is committed,
+// which is the additional data its content is sealed with.
+func (writer *BinaryBackupWriter) fileName(pathToFile string) string {
+	name, _ := filepath.Rel(writer.TransactionPath(), pathToFile) // Every file of the transaction is inside its directory
+	return filepath.ToSlash(name)
+}
+
+// saveChunk stages a chunk in full in the chunk store. In backups with reverse deltas, a chunk the backup keeps as a diff,
+// as it was replaced by another chunk, is saved again in full, so the chunks of the last snapshot never need a diff.
+func (writer *BinaryBackupWriter) saveChunk(hash string, chunk entities.SchemaRecordChunk) error {
+	if writer.reverseDeltas {
+		name := chunkObjectName(writer.codec.chunkRef(hash))
+		writer.mu.Lock()
+		latest := writer.latestChunks[name]
+		stored := writer.storedChunks[name]
+		writer.latestChunks[name] = true
+		writer.mu.Unlock()
+
+		if !latest && stored && writer.chainReader != nil {
+			content, err := writer.chainReader.readFile(name, true)
+			if err != nil && !errors.Is(err, fs.ErrNotExist) {
+				return err
+			}
+			if err == nil {
+				_, diff, err := decodeChunkObject(content)
+				if err != nil {
+					return err
+				}
+				if diff != nil {
+					writer.mu.Lock()
+					delete(writer.storedChunks, name)
+					writer.replacedChunks[name] = true
+					writer.mu.Unlock()
+				}
+			}
+		}
+	}
+
+	content, err := chunk.EncodeToBytes()
+	if err != nil {
+		return err
+	}
+	return writer.saveChunkObject(hash, chunk.GetRecordType(), false, content)
+}
+
+// saveReverseChunkDiff stages the chunk a chunk diff results in, in a backup with reverse deltas, and keeps the diff its
+// previous chunk is saved as once the snapshot is committed.
+func (writer *BinaryBackupWriter) saveReverseChunkDiff(hash string, diff entities.SchemaRecordChunkDiff) error {
+	if diff.GetPrevRef() == nil {
+		chunk := diff.ApplyDiffFromEmpty()
+		if chunk == nil {
+			return services.ErrBackupCorruptedFile
+		}
+		return writer.saveChunk(hash, chunk)
+	}
+
+	// The previous chunk is read again for the reverse diff, as applying a diff changes the records of its chunk
+	prevRef := chunkPrevRef(diff.GetPrevRef())
+	prevChunk, _, err := writer.chainReader.readChunkObject(prevRef)
+	if err != nil {
+		return err
+	}
+	chunk := prevChunk.ApplyDiff(diff)
+	if chunk == nil {
+		return services.ErrBackupCorruptedFile
+	}
+	if err := writer.saveChunk(hash, chunk); err != nil {
+		return err
+	}
+	if prevChunk, _, err = writer.chainReader.readChunkObject(prevRef); err != nil {
+		return err
+	}
+
+	// Diffs find the records they change by their hash, so the reverse diff of a chunk with repeated records may not rebuild
+	// it, in which case the previous chunk is kept in full
+	reverse := prevChunk.Diff(chunk, false)
+	content, err := reverse.EncodeToBytes()
+	if err != nil {
+		return err
+	}
+	if rebuilt := chunk.ApplyDiff(reverse); rebuilt == nil || !crypto.CompareHashes(rebuilt.Hash(), prevRef) {
+		return nil
+	}
+
+	writer.mu.Lock()
+	writer.reverseChunks[chunkObjectName(writer.codec.chunkRef(prevRef))] = encodeChunkObject(prevChunk.GetRecordType(), true, content)
+	writer.mu.Unlock()
+	return nil
+}
+
+// saveReverseChunks stages the chunks replaced by the chunks of the snapshot as their reverse diffs, unless the snapshot
+// keeps them as well, and packs again the objects kept with the stored copies of the chunks staged again. It returns the
+// files deleted once the snapshot is committed.
+func (writer *BinaryBackupWriter) saveReverseChunks() ([]string, error) {
+	replaced := maps.Clone(writer.replacedChunks)
+	for _, name := range slices.Sorted(maps.Keys(writer.reverseChunks)) {
+		if writer.latestChunks[name] {
+			continue
+		}
+		if err := writer.stageFile(name, writer.codec.encodeFile(name, writer.reverseChunks[name])); err != nil {
+			return nil, err
+		}
+		replaced[name] = true
+	}
+	if len(replaced) == 0 {
+		return nil, nil
+	}
+
+	sweep, err := writer.planSweep([]string{"chunks/"}, func(name string) bool { return replaced[name] })
+	if err != nil {
+		return nil, err
+	}
+	if err := writer.repackObjects(sweep); err != nil {
+		return nil, err
+	}
+	return sweep.deletes, nil
 }
@@ -0,0 +1,237 @@
+package binary
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"fmt"
+	"historydb/src/internal/entities"
+	"historydb/src/internal/services"
+	"historydb/src/internal/services/entities/mongo"
+	"historydb/src/internal/services/entities/sql"
+	"historydb/src/internal/utils/decode"
+	"historydb/src/internal/utils/encode"
+	"historydb/src/internal/utils/pointers"
+	"path"
+	"slices"
+)
+
+// Backups with a chunk store keep every record chunk once in chunks/<ref[:2]>/<ref>.hdb, where ref is the chunk reference
+// of its hash, whatever the batches and schemas it is found in. The batch files of data/ become manifests which only list
+// the hashes of their chunks, or the changes of the chunk list of the previous batch in data/diffs/.
+
+// chunkObjectName returns the name of the chunk store file of the chunk reference.
+func chunkObjectName(ref string) string {
+	return path.Join("chunks", ref[:2], fmt.Sprintf("%s.hdb", ref))
+}
+
+// encodeChunkObject encodes a chunk, or a chunk diff if isDiff is true, to be saved in the chunk store. The record type is
+// saved along with it, as the object may be read from a batch of any schema. The content is encoded without its length.
+func encodeChunkObject(recordType entities.RecordType, isDiff bool, content []byte) []byte {
+	var buf bytes.Buffer
+	encode.EncodeString(&buf, pointers.Ptr(string(recordType)))
+	encode.EncodeBool(&buf, &isDiff)
+	buf.Write(content[8:])
+
+	integrityHash := sha256.Sum256(buf.Bytes())
+	return append(integrityHash[:], buf.Bytes()...)
+}
+
+// decodeChunkObject decodes a chunk store file, as read without its integrity hash. Chunk diffs are returned as they are
+// saved, so their previous chunk still needs to be applied.
+func decodeChunkObject(data []byte) (entities.SchemaRecordChunk, entities.SchemaRecordChunkDiff, error) {
+	buf := bytes.NewBuffer(data)
+	recordType, err := decode.DecodeString(buf)
+	if err != nil {
+		return nil, nil, err
+	}
+	isDiff, err := decode.DecodeBool(buf)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if *isDiff {
+		diff, err := decodeChunkDiffByType(entities.RecordType(*recordType), buf.Bytes())
+		return nil, diff, err
+	}
+	chunk, err := decodeChunkByType(entities.RecordType(*recordType), buf.Bytes())
+	return chunk, nil, err
+}
+
+func decodeChunkByType(recordType entities.RecordType, content []byte) (entities.SchemaRecordChunk, error) {
+	switch recordType {
+	case entities.SQLRecord:
+		var chunk sql.SQLRecordChunk
+		if err := chunk.DecodeFromBytes(content); err != nil {
+			return nil, err
+		}
+		return &chunk, nil
+	case entities.MongoDocument:
+		var chunk mongo.MongoDocumentChunk
+		if err := chunk.DecodeFromBytes(content); err != nil {
+			return nil, err
+		}
+		return &chunk, nil
+	default:
+		return nil, services.ErrRecordNotSupported
+	}
+}
+
+func decodeChunkDiffByType(recordType entities.RecordType, content []byte) (entities.SchemaRecordChunkDiff, error) {
+	switch recordType {
+	case entities.SQLRecord:
+		var diff sql.SQLRecordChunkDiff
+		if err := diff.DecodeFromBytes(content); err != nil {
+			return nil, err
+		}
+		return &diff, nil
+	case entities.MongoDocument:
+		var diff mongo.MongoDocumentChunkDiff
+		if err := diff.DecodeFromBytes(content); err != nil {
+			return nil, err
+		}
+		return &diff, nil
+	default:
+		return nil, services.ErrRecordNotSupported
+	}
+}
+
+// batchManifest lists the chunks of a batch in a backup with a chunk store. The manifest of a diff batch lists the changes
+// of the chunk list of its previous batch instead.
+//
+// RecordType -> The record type of the chunks of the batch
+// PrevBatchRef -> The previous batch of a diff batch, nil in the rest of batches
+// Entries -> The chunks of the batch, or the changes of the chunk list of the previous batch, in the batch order
+type batchManifest struct {
+	RecordType   entities.RecordType
+	PrevBatchRef *string
+	Entries      []batchManifestEntry
+}
+
+// batchManifestEntry is a chunk of a batch manifest. In diff batches, an entry without PrevRef adds the chunk at the end of
+// the batch, or before the chunk NextRef, an entry without Hash removes the chunk PrevRef, and the rest replace PrevRef
+// with the chunk Hash.
+type batchManifestEntry struct {
+	Hash    *string
+	PrevRef *string
+	NextRef *string
+}
+
+// newDiffManifestEntry returns the manifest entry of the change a chunk diff makes to the chunk list of its batch.
+func newDiffManifestEntry(diff entities.SchemaRecordChunkDiff) batchManifestEntry {
+	entry := batchManifestEntry{Hash: diff.Hash()}
+	if diff.GetPrevRef() != nil {
+		entry.PrevRef = pointers.Ptr(chunkPrevRef(diff.GetPrevRef()))
+	}
+	if sqlDiff, ok := diff.(*sql.SQLRecordChunkDiff); ok {
+		entry.NextRef = sqlDiff.NextRef
+	}
+	return entry
+}
+
+func (manifest *batchManifest) EncodeToBytes() []byte {
+	var buf bytes.Buffer
+	encode.EncodeString(&buf, pointers.Ptr(string(manifest.RecordType)))
+	encode.EncodeString(&buf, manifest.PrevBatchRef)
+	for _, entry := range manifest.Entries {
+		var flags byte
+		if entry.Hash != nil {
+			flags |= 1 << 0
+		}
+		if entry.PrevRef != nil {
+			flags |= 1 << 1
+		}
+		if entry.NextRef != nil {
+			flags |= 1 << 2
+		}
+
+		buf.WriteByte(flags)
+		encode.EncodeString(&buf, entry.Hash)
+		encode.EncodeString(&buf, entry.PrevRef)
+		encode.EncodeString(&buf, entry.NextRef)
+	}
+
+	integrityHash := sha256.Sum256(buf.Bytes())
+	return append(integrityHash[:], buf.Bytes()...)
+}
+
+// DecodeFromBytes decodes a manifest read without its integrity hash. Only the manifests of diff batches have a previous batch.
+func (manifest *batchManifest) DecodeFromBytes(data []byte, isDiff bool) error {
+	buf := bytes.NewBuffer(data)
+
+	recordType, err := decode.DecodeString(buf)
+	if err != nil {
+		return err
+	}
+	var prevBatchRef *string
+	if isDiff {
+		if prevBatchRef, err = decode.DecodeString(buf); err != nil {
+			return err
+		}
+	}
+
+	entries := []batchManifestEntry{}
+	for buf.Len() > 0 {
+		flags, err := buf.ReadByte()
+		if err != nil {
+			return err
+		}
+
+		var entry batchManifestEntry
+		if flags&(1<<0) != 0 {
+			if entry.Hash, err = decode.DecodeString(buf); err != nil {
+				return err
+			}
+		}
+		if flags&(1<<1) != 0 {
+			if entry.PrevRef, err = decode.DecodeString(buf); err != nil {
+				return err
+			}
+		}
+		if flags&(1<<2) != 0 {
+			if entry.NextRef, err = decode.DecodeString(buf); err != nil {
+				return err
+			}
+		}
+		entries = append(entries, entry)
+	}
+
+	manifest.RecordType = entities.RecordType(*recordType)
+	manifest.PrevBatchRef = prevBatchRef
+	manifest.Entries = entries
+	return nil
+}
+
+// chunkRefs returns the chunks of the batch, applying the entries of a diff batch to the chunks of its previous batch.
+func (manifest *batchManifest) chunkRefs(prevChunkRefs []string) ([]string, error) {
+	if manifest.PrevBatchRef == nil {
+		chunkRefs := make([]string, 0, len(manifest.Entries))
+		for _, entry := range manifest.Entries {
+			chunkRefs = append(chunkRefs, *entry.Hash)
+		}
+		return chunkRefs, nil
+	}
+
+	chunkRefs := slices.Clone(prevChunkRefs)
+	for _, entry := range manifest.Entries {
+		if entry.PrevRef == nil && entry.NextRef != nil {
+			// The chunk is placed before the next one, so the batch keeps the order of its records
+			nextIndex := slices.Index(chunkRefs, *entry.NextRef)
+			if nextIndex == -1 {
+				return nil, services.ErrBackupCorruptedFile
+			}
+			chunkRefs = slices.Insert(chunkRefs, nextIndex, *entry.Hash)
+		} else if entry.PrevRef == nil {
+			chunkRefs = append(chunkRefs, *entry.Hash)
+		} else if entry.Hash == nil {
+			// The chunk was emptied, so it is no longer part of the batch
+			chunkRefs = slices.DeleteFunc(chunkRefs, func(v string) bool { return v == *entry.PrevRef })
+		} else {
+			for i, v := range chunkRefs {
+				if v == *entry.PrevRef {
+					chunkRefs[i] = *entry.Hash
+				}
+			}
+		}
+	}
+	return chunkRefs, nil
+}
@@ -0,0 +1,189 @@
+package binary
+
+import (
+	"bytes"
+	"fmt"
+	"historydb/src/internal/entities"
+	"historydb/src/internal/services"
+	"historydb/src/internal/utils/crypto"
+	"historydb/src/internal/utils/decode"
+	"historydb/src/internal/utils/encode"
+	"historydb/src/internal/utils/pointers"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+// DefaultCompressionLevel is the zstd level used when no other one is provided, which is the default level of zstd.
+const DefaultCompressionLevel int64 = 3
+
+// payloadCodec compresses the payloads of the backup files with the codec saved in the backup metadata, and seals them
+// afterwards if the backup is encrypted. The zstd encoder and decoder and the AEAD can be used by several goroutines at
+// once, so a codec is shared by all the workers.
+type payloadCodec struct {
+	compression entities.CompressionCodec
+	level       int64
+	encoder     *zstd.Encoder
+	decoder     *zstd.Decoder
+	cipher      *payloadCipher
+}
+
+// newPayloadCodec creates the codec of a backup. The cipher is nil if the backup is not encrypted.
+func newPayloadCodec(compression entities.CompressionCodec, level int64, cipher *payloadCipher) (*payloadCodec, error) {
+	switch compression {
+	case entities.NoCompression:
+		return &payloadCodec{compression: compression, level: 0, cipher: cipher}, nil
+	case entities.ZstdCompression:
+		encoder, err := zstd.NewWriter(nil, zstd.WithEncoderLevel(zstd.EncoderLevelFromZstd(int(level))))
+		if err != nil {
+			return nil, err
+		}
+		decoder, err := zstd.NewReader(nil)
+		if err != nil {
+			return nil, err
+		}
+		return &payloadCodec{compression: compression, level: level, encoder: encoder, decoder: decoder, cipher: cipher}, nil
+	default:
+		return nil, fmt.Errorf("%w: %s", services.ErrCompressionNotSupported, compression)
+	}
+}
+
+// newBackupCodec creates the codec of an existing backup from its metadata, unwrapping its data key if it is encrypted.
+func newBackupCodec(metadata entities.BackupMetadata, passphrase []byte) (*payloadCodec, error) {
+	var cipher *payloadCipher
+	if metadata.Encryption != nil {
+		dataKey, err := unwrapDataKey(metadata.Encryption, passphrase)
+		if err != nil {
+			return nil, err
+		}
+		if cipher, err = newPayloadCipher(dataKey); err != nil {
+			return nil, err
+		}
+	}
+	return newPayloadCodec(metadata.Compression, metadata.CompressionLevel, cipher)
+}
+
+func (codec *payloadCodec) compress(content []byte) []byte {
+	if codec.compression == entities.NoCompression {
+		return content
+	}
+	return codec.encoder.EncodeAll(content, nil)
+}
+
+func (codec *payloadCodec) decompress(content []byte) ([]byte, error) {
+	if codec.compression == entities.NoCompression {
+		return content, nil
+	}
+
+	decompressed, err := codec.decoder.DecodeAll(content, nil)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", services.ErrBackupCorruptedFile, err.Error())
+	}
+	return decompressed, nil
+}
+
+// encodeFile compresses and seals a whole file, whose name inside the backup is the additional data of the seal.
+func (codec *payloadCodec) encodeFile(name string, content []byte) []byte {
+	return codec.seal(name, codec.compress(content))
+}
+
+func (codec *payloadCodec) decodeFile(name string, data []byte) ([]byte, error) {
+	content, err := codec.open(name, data)
+	if err != nil {
+		return nil, err
+	}
+	return codec.decompress(content)
+}
+
+// seal only seals the content, for the files which are never compressed.
+func (codec *payloadCodec) seal(name string, content []byte) []byte {
+	if codec.cipher == nil {
+		return content
+	}
+	return codec.cipher.seal(name, content)
+}
+
+func (codec *payloadCodec) open(name string, data []byte) ([]byte, error) {
+	if codec.cipher == nil {
+		return data, nil
+	}
+	return codec.cipher.open(name, data)
+}
+
+// chunkRef returns the reference saved next to the entry of the chunk hash, which is keyed in encrypted backups.
+func (codec *payloadCodec) chunkRef(hash string) string {
+	if codec.cipher == nil {
+		return hash
+	}
+	return codec.cipher.chunkRef(hash)
+}
+
+// encodeBatchEntry frames a chunk or chunk diff, encoded with its length in front, to be appended into a batch file.
+// Compressed and sealed entries keep the reference of the chunk before its payload, so the chunks of a batch can be
+// looked up without decoding every one of them.
+func (codec *payloadCodec) encodeBatchEntry(hash *string, content []byte) []byte {
+	if codec.compression == entities.NoCompression && codec.cipher == nil {
+		return content
+	}
+
+	var entry bytes.Buffer
+	ref := ""
+	if hash != nil {
+		// Removed chunks do not have a hash, and they are never looked up by it
+		ref = codec.chunkRef(*hash)
+	}
+	encode.EncodeString(&entry, &ref)
+	entry.Write(codec.seal(ref, codec.compress(content[8:]))) // Skips the length of the content, as the entry has its own
+
+	var buf bytes.Buffer
+	encode.EncodeInt(&buf, pointers.Ptr(int64(entry.Len())))
+	buf.Write(entry.Bytes())
+	return buf.Bytes()
+}
+
+// batchEntry is a chunk or chunk diff read from a batch file, which is only decoded once it is needed.
+type batchEntry struct {
+	codec   *payloadCodec
+	ref     *string
+	content []byte
+}
+
+// decodeBatchEntry splits an entry read from a batch file. Its reference is only known before decoding the entry if it is
+// compressed or sealed.
+func (codec *payloadCodec) decodeBatchEntry(entryBytes []byte) (*batchEntry, error) {
+	if codec.compression == entities.NoCompression && codec.cipher == nil {
+		return &batchEntry{codec: codec, content: entryBytes}, nil
+	}
+
+	buf := bytes.NewBuffer(entryBytes)
+	ref, err := decode.DecodeString(buf)
+	if err != nil {
+		return nil, err
+	}
+	return &batchEntry{codec: codec, ref: ref, content: buf.Bytes()}, nil
+}
+
+// hash returns the hash of the chunk if it is known without decoding the entry, which is never the case in encrypted backups.
+func (entry *batchEntry) hash() *string {
+	if entry.codec.cipher != nil {
+		return nil
+	}
+	return entry.ref
+}
+
+// skips returns whether the entry is known not to be the chunk chunkRef without decoding it.
+func (entry *batchEntry) skips(chunkRef string) bool {
+	return entry.ref != nil && !crypto.CompareHashes(entry.codec.chunkRef(chunkRef), *entry.ref)
+}
+
+// Bytes returns the chunk or chunk diff bytes, as encoded without their length.
+func (entry *batchEntry) Bytes() ([]byte, error) {
+	if entry.ref == nil {
+		return entry.content, nil
+	}
+
+	content, err := entry.codec.open(*entry.ref, entry.content)
+	if err != nil {
+		return nil, err
+	}
+	return entry.codec.decompress(content)
+}
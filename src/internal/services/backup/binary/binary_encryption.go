@@ -0,0 +1,182 @@
+package binary
+
+import (
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"historydb/src/internal/entities"
+	"historydb/src/internal/services"
+	"io"
+
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/chacha20poly1305"
+	"golang.org/x/crypto/hkdf"
+)
+
+// Argon2id parameters used for new backups and rotated keys. They are saved in the metadata, so they can change in the future.
+const (
+	argon2idKdf     = "argon2id"
+	argon2idTime    = 3
+	argon2idMemory  = 64 * 1024
+	argon2idThreads = 4
+)
+
+const (
+	dataKeySize = chacha20poly1305.KeySize
+	saltSize    = 16
+)
+
+// newBackupEncryption creates a random data key for a new backup and wraps it with the key derived from the passphrase.
+func newBackupEncryption(passphrase []byte) (*entities.BackupEncryption, []byte, error) {
+	dataKey := make([]byte, dataKeySize)
+	if _, err := io.ReadFull(rand.Reader, dataKey); err != nil {
+		return nil, nil, err
+	}
+
+	encryption, err := wrapDataKey(dataKey, passphrase)
+	if err != nil {
+		return nil, nil, err
+	}
+	return encryption, dataKey, nil
+}
+
+// wrapDataKey seals the data key with the key derived from the passphrase, using a new salt.
+func wrapDataKey(dataKey, passphrase []byte) (*entities.BackupEncryption, error) {
+	salt := make([]byte, saltSize)
+	if _, err := io.ReadFull(rand.Reader, salt); err != nil {
+		return nil, err
+	}
+
+	encryption := &entities.BackupEncryption{
+		Kdf:     argon2idKdf,
+		Salt:    salt,
+		Time:    argon2idTime,
+		Memory:  argon2idMemory,
+		Threads: argon2idThreads,
+	}
+	keyEncryptionKey, err := deriveKeyEncryptionKey(encryption, passphrase)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := chacha20poly1305.NewX(keyEncryptionKey)
+	if err != nil {
+		return nil, err
+	}
+
+	encryption.KeyCheck = keyCheckValue(keyEncryptionKey)
+	encryption.WrappedKey = seal(aead, []byte(argon2idKdf), dataKey)
+	return encryption, nil
+}
+
+// unwrapDataKey opens the data key of an encrypted backup with the key derived from the passphrase.
+func unwrapDataKey(encryption *entities.BackupEncryption, passphrase []byte) ([]byte, error) {
+	if passphrase == nil {
+		return nil, services.ErrBackupKeyRequired
+	}
+
+	keyEncryptionKey, err := deriveKeyEncryptionKey(encryption, passphrase)
+	if err != nil {
+		return nil, err
+	}
+	if !hmac.Equal(encryption.KeyCheck, keyCheckValue(keyEncryptionKey)) {
+		return nil, services.ErrBackupKeyInvalid
+	}
+
+	aead, err := chacha20poly1305.NewX(keyEncryptionKey)
+	if err != nil {
+		return nil, err
+	}
+	dataKey, err := open(aead, []byte(encryption.Kdf), encryption.WrappedKey)
+	if err != nil {
+		return nil, fmt.Errorf("%w: wrapped data key", services.ErrBackupCorruptedFile)
+	}
+	return dataKey, nil
+}
+
+func deriveKeyEncryptionKey(encryption *entities.BackupEncryption, passphrase []byte) ([]byte, error) {
+	switch encryption.Kdf {
+	case argon2idKdf:
+		return argon2.IDKey(passphrase, encryption.Salt, uint32(encryption.Time), uint32(encryption.Memory), uint8(encryption.Threads), chacha20poly1305.KeySize), nil
+	default:
+		return nil, fmt.Errorf("%w: %s", services.ErrEncryptionNotSupported, encryption.Kdf)
+	}
+}
+
+// keyCheckValue is saved next to the wrapped data key, so a wrong passphrase is not reported as a corrupted backup.
+func keyCheckValue(keyEncryptionKey []byte) []byte {
+	mac := hmac.New(sha256.New, keyEncryptionKey)
+	mac.Write([]byte("historydb key check"))
+	return mac.Sum(nil)
+}
+
+// payloadCipher seals the backup files with an AEAD keyed by the data key of the backup.
+//
+// Every file is sealed with the name it has inside the backup as additional data, so a file moved into another one's place
+// is rejected. Batch entries are sealed with their chunk reference instead, as batches are renamed once they are complete.
+type payloadCipher struct {
+	aead   cipher.AEAD
+	refKey []byte
+}
+
+func newPayloadCipher(dataKey []byte) (*payloadCipher, error) {
+	encryptionKey, err := deriveSubkey(dataKey, "historydb payload encryption")
+	if err != nil {
+		return nil, err
+	}
+	refKey, err := deriveSubkey(dataKey, "historydb chunk references")
+	if err != nil {
+		return nil, err
+	}
+
+	aead, err := chacha20poly1305.NewX(encryptionKey)
+	if err != nil {
+		return nil, err
+	}
+	return &payloadCipher{aead: aead, refKey: refKey}, nil
+}
+
+func deriveSubkey(dataKey []byte, purpose string) ([]byte, error) {
+	subkey := make([]byte, dataKeySize)
+	if _, err := io.ReadFull(hkdf.New(sha256.New, dataKey, nil, []byte(purpose)), subkey); err != nil {
+		return nil, err
+	}
+	return subkey, nil
+}
+
+func (payloadCipher *payloadCipher) seal(name string, content []byte) []byte {
+	return seal(payloadCipher.aead, []byte(name), content)
+}
+
+func (payloadCipher *payloadCipher) open(name string, data []byte) ([]byte, error) {
+	content, err := open(payloadCipher.aead, []byte(name), data)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s could not be authenticated", services.ErrBackupCorruptedFile, name)
+	}
+	return content, nil
+}
+
+// chunkRef keys the hash of a chunk, so the references saved next to the sealed entries do not reveal their content.
+func (payloadCipher *payloadCipher) chunkRef(hash string) string {
+	mac := hmac.New(sha256.New, payloadCipher.refKey)
+	mac.Write([]byte(hash))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// seal encrypts the content with a random nonce, which is written before the ciphertext.
+func seal(aead cipher.AEAD, additionalData, content []byte) []byte {
+	nonce := make([]byte, aead.NonceSize(), aead.NonceSize()+len(content)+aead.Overhead())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		panic(err)
+	}
+	return aead.Seal(nonce, nonce, content, additionalData)
+}
+
+func open(aead cipher.AEAD, additionalData, data []byte) ([]byte, error) {
+	if len(data) < aead.NonceSize() {
+		return nil, services.ErrBackupCorruptedFile
+	}
+	return aead.Open(nil, data[:aead.NonceSize()], data[aead.NonceSize():], additionalData)
+}
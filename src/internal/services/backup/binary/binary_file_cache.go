@@ -0,0 +1,54 @@
+package binary
+
+import (
+	"slices"
+	"sync"
+)
+
+// fileCacheSize is the most bytes of batch files and pack blocks kept in memory by a reader.
+const fileCacheSize = 128 * 1024 * 1024
+
+// fileCache keeps the last batch files and pack blocks read by a reader. Every chunk of a batch is read on its own, so
+// without the cache a batch or pack kept in a remote storage would be downloaded once per chunk. The oldest files are
+// evicted first.
+type fileCache struct {
+	mu    sync.Mutex
+	names []string
+	files map[string][]byte
+	size  int
+}
+
+func (cache *fileCache) get(name string) ([]byte, bool) {
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+
+	content, ok := cache.files[name]
+	return content, ok
+}
+
+func (cache *fileCache) put(name string, content []byte) {
+	if len(content) > fileCacheSize {
+		return
+	}
+
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+
+	if cache.files == nil {
+		cache.files = make(map[string][]byte)
+	}
+	if _, ok := cache.files[name]; ok {
+		return
+	}
+
+	for cache.size+len(content) > fileCacheSize {
+		oldest := cache.names[0]
+		cache.size -= len(cache.files[oldest])
+		delete(cache.files, oldest)
+		cache.names = slices.Delete(cache.names, 0, 1)
+	}
+
+	cache.names = append(cache.names, name)
+	cache.files[name] = content
+	cache.size += len(content)
+}
@@ -0,0 +1,172 @@
+package binary
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"fmt"
+	storage_services "historydb/src/internal/services/storage"
+	"historydb/src/internal/utils/decode"
+	"historydb/src/internal/utils/encode"
+	"maps"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/google/uuid"
+)
+
+// The objects of a snapshot, which are its schemas, routines, batches, chunks and their diffs, are saved into a few pack
+// files instead of a file each. Every packs/<id>.pack file is the concatenation of the objects as they would be saved
+// in their own file, and its packs/<id>.idx index maps the name of every object to its place inside the pack. Backups
+// saved before pack files were supported keep their objects in their own files, which are still read when an object is
+// not found in any index.
+
+// packTargetSize is the size a pack file is closed at, so the objects of a large snapshot are split into a few packs.
+const packTargetSize = 64 * 1024 * 1024
+
+// packBlockSize is the size of the blocks pack files are read in, so the objects saved next to each other are read at once.
+const packBlockSize = 1024 * 1024
+
+// packLocation defines where an object is kept inside a pack file
+//
+// Pack -> The name of the pack file
+// Offset -> The position of the first byte of the object inside the pack
+// Length -> The number of bytes of the object
+// PackLength -> The number of bytes of the whole pack
+type packLocation struct {
+	Pack       string
+	Offset     int64
+	Length     int64
+	PackLength int64
+}
+
+// packIndex maps the name of every packed object of a backup to its place inside a pack file.
+type packIndex map[string]packLocation
+
+// readPackIndex reads the indexes of all the pack files of the backup kept in storage.
+func readPackIndex(storage storage_services.Storage, codec *payloadCodec) (packIndex, error) {
+	packs, err := readPackIndexes(storage, codec)
+	if err != nil {
+		return nil, err
+	}
+
+	index := make(packIndex)
+	for _, objects := range packs {
+		maps.Copy(index, objects)
+	}
+	return index, nil
+}
+
+// readPackIndexes reads the index of every pack file of the backup kept in storage, mapping the name of each pack to the
+// place of its objects.
+func readPackIndexes(storage storage_services.Storage, codec *payloadCodec) (map[string]packIndex, error) {
+	names, err := storage.List("packs/")
+	if err != nil {
+		return nil, err
+	}
+
+	packs := make(map[string]packIndex)
+	for _, name := range names {
+		if !strings.HasSuffix(name, ".idx") {
+			continue
+		}
+
+		data, err := storage.Get(name)
+		if err != nil {
+			return nil, err
+		}
+		objects, err := decodePackIndex(codec, name, data)
+		if err != nil {
+			return nil, err
+		}
+		packs[strings.TrimSuffix(name, ".idx")+".pack"] = objects
+	}
+
+	return packs, nil
+}
+
+// decodePackIndex decodes the data of the index file name, returning the place of every object of its pack.
+func decodePackIndex(codec *payloadCodec, name string, data []byte) (packIndex, error) {
+	content, err := decodeBackupFile(codec, name, data, true)
+	if err != nil {
+		return nil, err
+	}
+
+	pack := strings.TrimSuffix(name, ".idx") + ".pack"
+	objects := make(packIndex)
+	packLength := int64(0)
+	buf := bytes.NewBuffer(content)
+	for buf.Len() > 0 {
+		objectName, err := decode.DecodeString(buf)
+		if err != nil {
+			return nil, err
+		}
+		offset, err := decode.DecodeInt(buf)
+		if err != nil {
+			return nil, err
+		}
+		length, err := decode.DecodeInt(buf)
+		if err != nil {
+			return nil, err
+		}
+		objects[*objectName] = packLocation{Pack: pack, Offset: *offset, Length: *length}
+		packLength = max(packLength, *offset+*length)
+	}
+
+	for objectName, location := range objects {
+		location.PackLength = packLength
+		objects[objectName] = location
+	}
+	return objects, nil
+}
+
+// stagedPack is a pack file being written into the directory of a snapshot transaction, along with its index.
+type stagedPack struct {
+	codec  *payloadCodec
+	name   string
+	pathTo func(name string) string
+	f      *os.File
+	index  bytes.Buffer
+	size   int64
+}
+
+// newStagedPack creates a new pack file with a random name. pathTo returns the staged path of a file of the backup.
+func newStagedPack(codec *payloadCodec, pathTo func(name string) string) (*stagedPack, error) {
+	name := fmt.Sprintf("packs/%s.pack", uuid.NewString())
+	pathToFile := pathTo(name)
+	if err := os.MkdirAll(filepath.Dir(pathToFile), 0755); err != nil {
+		return nil, err
+	}
+
+	f, err := os.Create(pathToFile)
+	if err != nil {
+		return nil, err
+	}
+	return &stagedPack{codec: codec, name: name, pathTo: pathTo, f: f}, nil
+}
+
+// add appends the data of an object, as it would be saved in its own file, to the pack.
+func (pack *stagedPack) add(name string, data []byte) error {
+	if _, err := pack.f.Write(data); err != nil {
+		return err
+	}
+
+	length := int64(len(data))
+	encode.EncodeString(&pack.index, &name)
+	encode.EncodeInt(&pack.index, &pack.size)
+	encode.EncodeInt(&pack.index, &length)
+	pack.size += length
+	return nil
+}
+
+// close closes the pack file and writes its index next to it.
+func (pack *stagedPack) close() error {
+	if err := pack.f.Close(); err != nil {
+		return err
+	}
+
+	indexName := strings.TrimSuffix(pack.name, ".pack") + ".idx"
+	integrityHash := sha256.Sum256(pack.index.Bytes())
+	content := append(integrityHash[:], pack.index.Bytes()...)
+	return os.WriteFile(pack.pathTo(indexName), pack.codec.encodeFile(indexName, content), 0644)
+}
@@ -8,6 +8,7 @@ import (
 	"historydb/src/internal/services/backup/binary"
 	"historydb/src/internal/services/entities/psql"
 	"historydb/src/internal/services/entities/sql"
+	"historydb/src/internal/services/storage/local"
 	"os"
 	"testing"
 
@@ -54,7 +55,7 @@ func TestBinaryBackupReader(t *testing.T) {
 			t.Fatal("could not decode expected data", err)
 		}
 
-		backupReader := binary.NewBinaryBackupReader(test.BackupPath)
+		backupReader := binary.NewBinaryBackupReader(local.NewLocalStorage(test.BackupPath), nil)
 		testCheckBackupExists(t, backupReader, expectedData.BackupExists)
 		snapshot := testGetBackupMetadata(t, backupReader, expectedData.Metadata)
 		testGetBackupSnapshot(t, backupReader, snapshot, expectedData.Snapshots[len(expectedData.Snapshots)-1])
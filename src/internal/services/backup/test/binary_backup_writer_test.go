@@ -0,0 +1,755 @@
+package test
+
+import (
+	"fmt"
+	"historydb/src/internal/entities"
+	"historydb/src/internal/services"
+	"historydb/src/internal/services/backup/binary"
+	"historydb/src/internal/services/entities/sql"
+	"historydb/src/internal/services/storage/local"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBinaryBackupDeduplicatesChunks(t *testing.T) {
+	storage := local.NewLocalStorage(filepath.Join(t.TempDir(), "backup"))
+	options := binary.BinaryBackupOptions{Compression: entities.NoCompression}
+	shared := &sql.SQLRecordChunk{Content: []sql.SQLRecord{{Content: map[string]interface{}{"id": "1"}}}}
+	users := &sql.SQLRecordChunk{Content: []sql.SQLRecord{{Content: map[string]interface{}{"id": "2"}}}}
+	updatedUsers := &sql.SQLRecordChunk{Content: []sql.SQLRecord{{Content: map[string]interface{}{"id": "3"}}}}
+	sharedHash, updatedUsersHash := shared.Hash(), updatedUsers.Hash()
+	metadata := entities.BackupMetadata{DatabaseEngine: "sqlite"}
+
+	writer := binary.NewBinaryBackupWriter(storage, options)
+	assert.NoError(t, writer.CreateBackupStructure())
+	assert.NoError(t, writer.BeginSnapshot(&entities.BackupSnapshot{SnapshotId: uuid.NewString()}))
+	assert.NoError(t, writer.SaveSchemaRecordChunk("temp-users", shared))
+	assert.NoError(t, writer.SaveSchemaRecordChunk("temp-users", users))
+	assert.NoError(t, writer.SaveSchemaRecordBatch("temp-users", "users"))
+	assert.NoError(t, writer.SaveSchemaRecordChunk("temp-accounts", shared))
+	assert.NoError(t, writer.SaveSchemaRecordBatch("temp-accounts", "accounts"))
+	assert.NoError(t, writer.CommitSnapshot(&metadata))
+
+	assert.Len(t, packedObjects(t, storage, "chunks/"), 2, "chunk found in two batches is saved once")
+
+	// The next snapshot only saves the diff of the changed chunk
+	assert.NoError(t, writer.BeginSnapshot(&entities.BackupSnapshot{SnapshotId: uuid.NewString()}))
+	assert.NoError(t, writer.SaveSchemaRecordChunkDiff("users", "diffs/users", updatedUsers.Diff(users, false)))
+	assert.NoError(t, writer.CommitSnapshot(&metadata))
+
+	assert.Len(t, packedObjects(t, storage, "chunks/"), 3)
+
+	reader := binary.NewBinaryBackupReader(storage, nil)
+	readMetadata, err := reader.GetBackupMetadata()
+	assert.NoError(t, err)
+	assert.True(t, readMetadata.ChunkStore)
+
+	chunkRefs, err := reader.GetSchemaRecordChunkRefsInBatch("accounts")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{sharedHash}, chunkRefs)
+	chunkRefs, err = reader.GetSchemaRecordChunkRefsInBatch("diffs/users")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{sharedHash, updatedUsersHash}, chunkRefs)
+
+	readChunk, isDiff, err := reader.GetSchemaRecordChunk("diffs/users", updatedUsersHash)
+	assert.NoError(t, err)
+	assert.True(t, isDiff)
+	assert.Equal(t, updatedUsersHash, readChunk.Hash())
+	readChunk, isDiff, err = reader.GetSchemaRecordChunk("accounts", sharedHash)
+	assert.NoError(t, err)
+	assert.False(t, isDiff)
+	assert.Equal(t, sharedHash, readChunk.Hash())
+
+	_, _, err = reader.GetSchemaRecordChunk("users", strings.Repeat("0", len(sharedHash)))
+	assert.ErrorIs(t, err, services.ErrBackupChunkNotFound)
+}
+
+func TestBinaryBackupChecksObjects(t *testing.T) {
+	storage := local.NewLocalStorage(filepath.Join(t.TempDir(), "backup"))
+	options := binary.BinaryBackupOptions{Compression: entities.NoCompression}
+	table := &sql.SQLTable{Name: "users", Columns: []sql.SQLTableColumn{{Name: "id", Type: "integer", Position: 1}}}
+	users := &sql.SQLRecordChunk{Content: []sql.SQLRecord{{Content: map[string]interface{}{"id": "1"}}}}
+	updatedUsers := &sql.SQLRecordChunk{Content: []sql.SQLRecord{{Content: map[string]interface{}{"id": "2"}}}}
+	first := entities.BackupSnapshot{
+		SnapshotId: uuid.NewString(),
+		Schemas:    map[string]string{table.Name: table.Hash()},
+		Data:       map[string]entities.BackupSnapshotSchemaData{table.Name: {Data: []string{"users"}}},
+	}
+	second := entities.BackupSnapshot{
+		SnapshotId: uuid.NewString(),
+		Schemas:    first.Schemas,
+		Data:       map[string]entities.BackupSnapshotSchemaData{table.Name: {Data: []string{"diffs/users"}}},
+	}
+	metadata := entities.BackupMetadata{DatabaseEngine: "sqlite"}
+
+	writer := binary.NewBinaryBackupWriter(storage, options)
+	assert.NoError(t, writer.CreateBackupStructure())
+	assert.NoError(t, writer.BeginSnapshot(&first))
+	assert.NoError(t, writer.SaveSchema(table))
+	assert.NoError(t, writer.SaveSchemaRecordChunk("temp-users", users))
+	assert.NoError(t, writer.SaveSchemaRecordBatch("temp-users", "users"))
+	metadata.Snapshots = append(metadata.Snapshots, entities.BackupMetadataSnapshot{SnapshotId: first.SnapshotId})
+	assert.NoError(t, writer.CommitSnapshot(&metadata))
+
+	assert.NoError(t, writer.BeginSnapshot(&second))
+	assert.NoError(t, writer.SaveSchemaRecordChunkDiff("users", "diffs/users", updatedUsers.Diff(users, false)))
+	metadata.Snapshots = append(metadata.Snapshots, entities.BackupMetadataSnapshot{SnapshotId: second.SnapshotId})
+	assert.NoError(t, writer.CommitSnapshot(&metadata))
+
+	reader := binary.NewBinaryBackupReader(storage, nil)
+	files, err := reader.ListBackupFiles()
+	assert.NoError(t, err)
+	for _, name := range files {
+		assert.NoError(t, reader.CheckBackupFile(name), "%s is intact", name)
+	}
+
+	// The diff of the second snapshot keeps the batch and chunk of the first one referenced, even if only the second
+	// snapshot is kept
+	objects, err := reader.ListBackupObjects()
+	assert.NoError(t, err)
+	referenced, err := reader.ListReferencedObjects([]entities.BackupSnapshot{first, second})
+	assert.NoError(t, err)
+	assert.Equal(t, objects, referenced)
+	referenced, err = reader.ListReferencedObjects([]entities.BackupSnapshot{second})
+	assert.NoError(t, err)
+	assert.Equal(t, slices.DeleteFunc(objects, func(name string) bool {
+		return name == filepath.Join("snapshots", first.SnapshotId+".hdb")
+	}), referenced)
+
+	// An object no snapshot references is listed, and a corrupted pack fails its check
+	assert.NoError(t, storage.Put("schemas/orphan.hdb", []byte("orphan")))
+	objects, err = reader.ListBackupObjects()
+	assert.NoError(t, err)
+	assert.Contains(t, objects, "schemas/orphan.hdb")
+	assert.ErrorIs(t, reader.CheckBackupFile("schemas/orphan.hdb"), services.ErrBackupCorruptedFile)
+
+	packs, err := storage.List("packs/")
+	assert.NoError(t, err)
+	pack := packs[slices.IndexFunc(packs, func(name string) bool { return strings.HasSuffix(name, ".pack") })]
+	content, err := storage.Get(pack)
+	assert.NoError(t, err)
+	content[len(content)-1] ^= 0xff
+	assert.NoError(t, storage.Put(pack, content))
+	assert.ErrorIs(t, reader.CheckBackupFile(pack), services.ErrBackupCorruptedFile)
+}
+
+func TestBinaryBackupDeletesSnapshots(t *testing.T) {
+	storage := local.NewLocalStorage(filepath.Join(t.TempDir(), "backup"))
+	options := binary.BinaryBackupOptions{Compression: entities.NoCompression}
+	table := &sql.SQLTable{Name: "users", Columns: []sql.SQLTableColumn{{Name: "id", Type: "integer", Position: 1}}}
+	updatedTable := &sql.SQLTable{Name: "users", Columns: append(slices.Clone(table.Columns), sql.SQLTableColumn{Name: "name", Type: "text", Position: 2})}
+	lastTable := &sql.SQLTable{Name: "users", Columns: append(slices.Clone(updatedTable.Columns), sql.SQLTableColumn{Name: "email", Type: "text", Position: 3})}
+	users := &sql.SQLRecordChunk{Content: []sql.SQLRecord{{Content: map[string]interface{}{"id": "1"}}}}
+	updatedUsers := &sql.SQLRecordChunk{Content: []sql.SQLRecord{{Content: map[string]interface{}{"id": "2"}}}}
+	lastUsers := &sql.SQLRecordChunk{Content: []sql.SQLRecord{{Content: map[string]interface{}{"id": "3"}}}}
+	snapshots := []entities.BackupSnapshot{
+		{
+			SnapshotId: uuid.NewString(),
+			Schemas:    map[string]string{table.Name: table.Hash()},
+			Data:       map[string]entities.BackupSnapshotSchemaData{table.Name: {Data: []string{"users"}}},
+		},
+		{
+			SnapshotId: uuid.NewString(),
+			Schemas:    map[string]string{table.Name: "diffs/" + updatedTable.Hash()},
+			Data:       map[string]entities.BackupSnapshotSchemaData{table.Name: {Data: []string{"diffs/updated-users"}}},
+		},
+		{
+			SnapshotId: uuid.NewString(),
+			Schemas:    map[string]string{table.Name: "diffs/" + lastTable.Hash()},
+			Data:       map[string]entities.BackupSnapshotSchemaData{table.Name: {Data: []string{"diffs/last-users"}}},
+		},
+	}
+	metadata := entities.BackupMetadata{DatabaseEngine: "sqlite"}
+
+	writer := binary.NewBinaryBackupWriter(storage, options)
+	assert.NoError(t, writer.CreateBackupStructure())
+	assert.NoError(t, writer.BeginSnapshot(&snapshots[0]))
+	assert.NoError(t, writer.SaveSchema(table))
+	assert.NoError(t, writer.SaveSchemaRecordChunk("temp-users", users))
+	assert.NoError(t, writer.SaveSchemaRecordBatch("temp-users", "users"))
+	metadata.Snapshots = append(metadata.Snapshots, entities.BackupMetadataSnapshot{SnapshotId: snapshots[0].SnapshotId})
+	assert.NoError(t, writer.CommitSnapshot(&metadata))
+
+	assert.NoError(t, writer.BeginSnapshot(&snapshots[1]))
+	assert.NoError(t, writer.SaveSchemaDiff(updatedTable.Diff(table, false)))
+	assert.NoError(t, writer.SaveSchemaRecordChunkDiff("users", "diffs/updated-users", updatedUsers.Diff(users, false)))
+	metadata.Snapshots = append(metadata.Snapshots, entities.BackupMetadataSnapshot{SnapshotId: snapshots[1].SnapshotId})
+	assert.NoError(t, writer.CommitSnapshot(&metadata))
+
+	assert.NoError(t, writer.BeginSnapshot(&snapshots[2]))
+	assert.NoError(t, writer.SaveSchemaDiff(lastTable.Diff(updatedTable, true)))
+	assert.NoError(t, writer.SaveSchemaRecordChunkDiff("diffs/updated-users", "diffs/last-users", lastUsers.Diff(updatedUsers, true)))
+	metadata.Snapshots = append(metadata.Snapshots, entities.BackupMetadataSnapshot{SnapshotId: snapshots[2].SnapshotId})
+	assert.NoError(t, writer.CommitSnapshot(&metadata))
+
+	reader := binary.NewBinaryBackupReader(storage, nil)
+	objects, err := reader.ListBackupObjects()
+	assert.NoError(t, err)
+
+	_, err = binary.NewBinaryBackupWriter(storage, options).DeleteSnapshots([]string{uuid.NewString()})
+	assert.ErrorIs(t, err, services.ErrBackupSnapshotNotFound)
+
+	// The diffs of the second snapshot are re-based onto full objects, as the objects they were taken from are deleted
+	sweep, err := binary.NewBinaryBackupWriter(storage, options).DeleteSnapshots([]string{snapshots[0].SnapshotId})
+	assert.NoError(t, err)
+	assert.NotEmpty(t, sweep.RebasedObjects)
+	assert.Contains(t, sweep.DeletedFiles, filepath.Join("snapshots", snapshots[0].SnapshotId+".hdb"))
+
+	reader = binary.NewBinaryBackupReader(storage, nil)
+	metadata, err = reader.GetBackupMetadata()
+	assert.NoError(t, err)
+	assert.Equal(t, []string{snapshots[1].SnapshotId, snapshots[2].SnapshotId}, []string{metadata.Snapshots[0].SnapshotId, metadata.Snapshots[1].SnapshotId})
+
+	kept := []entities.BackupSnapshot{}
+	for i, expected := range []struct {
+		table *sql.SQLTable
+		chunk *sql.SQLRecordChunk
+	}{{updatedTable, updatedUsers}, {lastTable, lastUsers}} {
+		snapshot, err := reader.GetBackupSnapshot(snapshots[i+1].SnapshotId)
+		assert.NoError(t, err)
+		kept = append(kept, snapshot)
+
+		schema, _, err := reader.GetSchema(snapshot.Schemas[table.Name])
+		assert.NoError(t, err)
+		assert.Equal(t, expected.table.Hash(), schema.Hash())
+
+		batchRef := snapshot.Data[table.Name].Data[0]
+		chunkRefs, err := reader.GetSchemaRecordChunkRefsInBatch(batchRef)
+		assert.NoError(t, err)
+		assert.Equal(t, []string{expected.chunk.Hash()}, chunkRefs)
+		chunk, _, err := reader.GetSchemaRecordChunk(batchRef, chunkRefs[0])
+		assert.NoError(t, err)
+		assert.Equal(t, expected.chunk.Hash(), chunk.Hash())
+	}
+
+	// Only the objects of the kept snapshots are left, so the first table and chunk are gone
+	remaining, err := reader.ListBackupObjects()
+	assert.NoError(t, err)
+	referenced, err := reader.ListReferencedObjects(kept)
+	assert.NoError(t, err)
+	assert.Equal(t, referenced, remaining)
+	assert.Contains(t, objects, filepath.Join("schemas", table.Hash()+".hdb"))
+	assert.NotContains(t, remaining, filepath.Join("schemas", table.Hash()+".hdb"))
+
+	files, err := reader.ListBackupFiles()
+	assert.NoError(t, err)
+	for _, name := range files {
+		assert.NoError(t, reader.CheckBackupFile(name), "%s is intact", name)
+	}
+
+	// A new snapshot saves its objects into a pack of its own, so deleting it stages no files and deletes the whole pack
+	packs, err := storage.List("packs/")
+	assert.NoError(t, err)
+	newestTable := &sql.SQLTable{Name: "users", Columns: append(slices.Clone(lastTable.Columns), sql.SQLTableColumn{Name: "age", Type: "integer", Position: 4})}
+	newestUsers := &sql.SQLRecordChunk{Content: []sql.SQLRecord{{Content: map[string]interface{}{"id": "4"}}}}
+	newest := entities.BackupSnapshot{
+		SnapshotId: uuid.NewString(),
+		Schemas:    map[string]string{table.Name: "diffs/" + newestTable.Hash()},
+		Data:       map[string]entities.BackupSnapshotSchemaData{table.Name: {Data: []string{"diffs/newest-users"}}},
+	}
+	assert.NoError(t, writer.BeginSnapshot(&newest))
+	assert.NoError(t, writer.SaveSchemaDiff(newestTable.Diff(lastTable, true)))
+	assert.NoError(t, writer.SaveSchemaRecordChunkDiff(kept[1].Data[table.Name].Data[0], "diffs/newest-users", newestUsers.Diff(lastUsers, true)))
+	metadata.Snapshots = append(metadata.Snapshots, entities.BackupMetadataSnapshot{SnapshotId: newest.SnapshotId})
+	assert.NoError(t, writer.CommitSnapshot(&metadata))
+	newestPacks, err := storage.List("packs/")
+	assert.NoError(t, err)
+	assert.Len(t, newestPacks, len(packs)+2)
+
+	sweep, err = binary.NewBinaryBackupWriter(storage, options).DeleteSnapshots([]string{newest.SnapshotId})
+	assert.NoError(t, err)
+	assert.Empty(t, sweep.RebasedObjects)
+	assert.Contains(t, sweep.DeletedFiles, filepath.Join("snapshots", newest.SnapshotId+".hdb"))
+	remainingPacks, err := storage.List("packs/")
+	assert.NoError(t, err)
+	assert.Equal(t, packs, remainingPacks)
+
+	// The newest of the first snapshots is deleted as well, leaving the objects of the other one packed again
+	sweep, err = binary.NewBinaryBackupWriter(storage, options).DeleteSnapshots([]string{snapshots[2].SnapshotId})
+	assert.NoError(t, err)
+	assert.Empty(t, sweep.RebasedObjects)
+	assert.Contains(t, sweep.DeletedFiles, filepath.Join("snapshots", snapshots[2].SnapshotId+".hdb"))
+
+	reader = binary.NewBinaryBackupReader(storage, nil)
+	metadata, err = reader.GetBackupMetadata()
+	assert.NoError(t, err)
+	assert.Len(t, metadata.Snapshots, 1)
+	assert.Equal(t, snapshots[1].SnapshotId, metadata.Snapshots[0].SnapshotId)
+
+	remaining, err = reader.ListBackupObjects()
+	assert.NoError(t, err)
+	referenced, err = reader.ListReferencedObjects(kept[:1])
+	assert.NoError(t, err)
+	assert.Equal(t, referenced, remaining)
+	schema, _, err := reader.GetSchema(kept[0].Schemas[table.Name])
+	assert.NoError(t, err)
+	assert.Equal(t, updatedTable.Hash(), schema.Hash())
+}
+
+func TestBinaryBackupBoundsChainDepth(t *testing.T) {
+	storage := local.NewLocalStorage(filepath.Join(t.TempDir(), "backup"))
+	options := binary.BinaryBackupOptions{Compression: entities.NoCompression, MaxChainDepth: 1}
+	tables := []*sql.SQLTable{{Name: "users", Columns: []sql.SQLTableColumn{{Name: "id", Type: "integer", Position: 1}}}}
+	chunks := []*sql.SQLRecordChunk{{Content: []sql.SQLRecord{{Content: map[string]interface{}{"id": "0"}}}}}
+	for i := 1; i < 4; i++ {
+		column := sql.SQLTableColumn{Name: fmt.Sprintf("column%d", i), Type: "text", Position: int64(i + 1)}
+		tables = append(tables, &sql.SQLTable{Name: "users", Columns: append(slices.Clone(tables[i-1].Columns), column)})
+		chunks = append(chunks, &sql.SQLRecordChunk{Content: []sql.SQLRecord{{Content: map[string]interface{}{"id": fmt.Sprint(i)}}}})
+	}
+	metadata := entities.BackupMetadata{DatabaseEngine: "sqlite"}
+
+	snapshots := []entities.BackupSnapshot{{
+		SnapshotId: uuid.NewString(),
+		Schemas:    map[string]string{"users": tables[0].Hash()},
+		Data:       map[string]entities.BackupSnapshotSchemaData{"users": {Data: []string{"users-0"}}},
+	}}
+	writer := binary.NewBinaryBackupWriter(storage, options)
+	assert.NoError(t, writer.CreateBackupStructure())
+	assert.NoError(t, writer.BeginSnapshot(&snapshots[0]))
+	assert.NoError(t, writer.SaveSchema(tables[0]))
+	assert.NoError(t, writer.SaveSchemaRecordChunk("temp-users", chunks[0]))
+	assert.NoError(t, writer.SaveSchemaRecordBatch("temp-users", "users-0"))
+	metadata.Snapshots = append(metadata.Snapshots, entities.BackupMetadataSnapshot{SnapshotId: snapshots[0].SnapshotId})
+	assert.NoError(t, writer.CommitSnapshot(&metadata))
+
+	// Every snapshot after the first saves diffs, so the third one would apply two diffs to its first objects. The later
+	// snapshots are taken without the option, as the depth is kept in the backup
+	prevBatchRef := "users-0"
+	for i := 1; i < 4; i++ {
+		snapshot := entities.BackupSnapshot{
+			SnapshotId: uuid.NewString(),
+			Schemas:    map[string]string{"users": "diffs/" + tables[i].Hash()},
+			Data:       map[string]entities.BackupSnapshotSchemaData{"users": {Data: []string{fmt.Sprintf("diffs/users-%d", i)}}},
+		}
+		prevIsDiff := strings.HasPrefix(prevBatchRef, "diffs")
+		writer := binary.NewBinaryBackupWriter(storage, binary.BinaryBackupOptions{})
+		assert.NoError(t, writer.BeginSnapshot(&snapshot))
+		assert.NoError(t, writer.SaveSchemaDiff(tables[i].Diff(tables[i-1], prevIsDiff)))
+		assert.NoError(t, writer.SaveSchemaRecordChunkDiff(prevBatchRef, fmt.Sprintf("diffs/users-%d", i), chunks[i].Diff(chunks[i-1], prevIsDiff)))
+		metadata.Snapshots = append(metadata.Snapshots, entities.BackupMetadataSnapshot{SnapshotId: snapshot.SnapshotId})
+		assert.NoError(t, writer.CommitSnapshot(&metadata))
+
+		snapshots = append(snapshots, snapshot)
+		prevBatchRef = snapshot.Data["users"].Data[0]
+	}
+
+	reader := binary.NewBinaryBackupReader(storage, nil)
+	readMetadata, err := reader.GetBackupMetadata()
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), readMetadata.MaxChainDepth)
+
+	for i, expected := range []struct {
+		schemaRef string
+		batchRef  string
+		isDiff    bool
+	}{
+		{tables[0].Hash(), "users-0", false},
+		{"diffs/" + tables[1].Hash(), "diffs/users-1", true},
+		{tables[2].Hash(), "users-2", false},
+		{"diffs/" + tables[3].Hash(), "diffs/users-3", true},
+	} {
+		snapshot, err := reader.GetBackupSnapshot(snapshots[i].SnapshotId)
+		assert.NoError(t, err)
+		assert.Equal(t, expected.schemaRef, snapshot.Schemas["users"], "schema of snapshot %d", i)
+		assert.Equal(t, []string{expected.batchRef}, snapshot.Data["users"].Data, "batch of snapshot %d", i)
+
+		schema, isDiff, err := reader.GetSchema(snapshot.Schemas["users"])
+		assert.NoError(t, err)
+		assert.Equal(t, expected.isDiff, isDiff)
+		assert.Equal(t, tables[i].Hash(), schema.Hash())
+
+		chunkRefs, err := reader.GetSchemaRecordChunkRefsInBatch(expected.batchRef)
+		assert.NoError(t, err)
+		assert.Equal(t, []string{chunks[i].Hash()}, chunkRefs)
+		chunk, isDiff, err := reader.GetSchemaRecordChunk(expected.batchRef, chunkRefs[0])
+		assert.NoError(t, err)
+		assert.Equal(t, expected.isDiff, isDiff)
+		assert.Equal(t, chunks[i].Hash(), chunk.Hash())
+	}
+
+	objects, err := reader.ListBackupObjects()
+	assert.NoError(t, err)
+	referenced, err := reader.ListReferencedObjects(snapshots)
+	assert.NoError(t, err)
+	assert.Equal(t, referenced, objects, "the diffs saved in full are not kept")
+
+	// The batch files of backups without a chunk store hold the chunk diffs themselves, so their chains can not be bounded
+	legacyStorage := local.NewLocalStorage(filepath.Join(t.TempDir(), "legacy"))
+	legacyWriter := binary.NewBinaryBackupWriter(legacyStorage, binary.BinaryBackupOptions{Compression: entities.NoCompression})
+	legacySnapshot := entities.BackupMetadataSnapshot{SnapshotId: uuid.NewString()}
+	assert.NoError(t, legacyWriter.CreateBackupStructure())
+	assert.NoError(t, legacyWriter.BeginSnapshot(&entities.BackupSnapshot{SnapshotId: legacySnapshot.SnapshotId}))
+	assert.NoError(t, legacyWriter.CommitSnapshot(&entities.BackupMetadata{DatabaseEngine: "sqlite", Snapshots: []entities.BackupMetadataSnapshot{legacySnapshot}}))
+	legacyMetadata, err := binary.NewBinaryBackupReader(legacyStorage, nil).GetBackupMetadata()
+	assert.NoError(t, err)
+	legacyMetadata.ChunkStore = false
+	assert.NoError(t, legacyStorage.Put("metadata.hdb", legacyMetadata.EncodeToBytes()))
+
+	err = binary.NewBinaryBackupWriter(legacyStorage, options).BeginSnapshot(&entities.BackupSnapshot{SnapshotId: uuid.NewString()})
+	assert.ErrorIs(t, err, services.ErrChainDepthNotSupported)
+	assert.NoError(t, binary.NewBinaryBackupWriter(legacyStorage, binary.BinaryBackupOptions{}).BeginSnapshot(&entities.BackupSnapshot{SnapshotId: uuid.NewString()}), "snapshots without a maximum depth are taken")
+}
+
+func TestBinaryBackupSavesReverseDeltas(t *testing.T) {
+	storage := local.NewLocalStorage(filepath.Join(t.TempDir(), "backup"))
+	options := binary.BinaryBackupOptions{Compression: entities.NoCompression, ReverseDeltas: true}
+	table := &sql.SQLTable{Name: "users", Columns: []sql.SQLTableColumn{{Name: "id", Type: "integer", Position: 1}}}
+	updatedTable := &sql.SQLTable{Name: "users", Columns: append(slices.Clone(table.Columns), sql.SQLTableColumn{Name: "name", Type: "text", Position: 2})}
+	newChunk := func(ids ...string) *sql.SQLRecordChunk {
+		chunk := &sql.SQLRecordChunk{PrimaryKey: []string{"id"}}
+		for _, id := range ids {
+			chunk.Content = append(chunk.Content, sql.SQLRecord{Content: map[string]interface{}{"id": id}})
+		}
+		return chunk
+	}
+	// Every state of the chunk keeps the order of the records, so its reverse diff rebuilds it
+	chunks := []*sql.SQLRecordChunk{newChunk("1", "2"), newChunk("1", "2", "3"), newChunk("1", "2", "3", "4")}
+	// Chunks read from the backup have no primary key, so the reverse diff of the chunk which grows removes its last record
+	logs := &sql.SQLRecordChunk{Content: []sql.SQLRecord{{Content: map[string]interface{}{"line": "a"}}}}
+	updatedLogs := &sql.SQLRecordChunk{Content: append(slices.Clone(logs.Content), sql.SQLRecord{Content: map[string]interface{}{"line": "b"}})}
+	logsHash := logs.Hash()
+	metadata := entities.BackupMetadata{DatabaseEngine: "sqlite"}
+
+	snapshots := []entities.BackupSnapshot{{
+		SnapshotId: uuid.NewString(),
+		Schemas:    map[string]string{"users": table.Hash()},
+		Data:       map[string]entities.BackupSnapshotSchemaData{"users": {Data: []string{"users-0"}}, "logs": {Data: []string{"logs-0"}}},
+	}}
+	writer := binary.NewBinaryBackupWriter(storage, options)
+	assert.NoError(t, writer.CreateBackupStructure())
+	assert.NoError(t, writer.BeginSnapshot(&snapshots[0]))
+	assert.NoError(t, writer.SaveSchema(table))
+	assert.NoError(t, writer.SaveSchemaRecordChunk("temp-users", chunks[0]))
+	assert.NoError(t, writer.SaveSchemaRecordBatch("temp-users", "users-0"))
+	assert.NoError(t, writer.SaveSchemaRecordChunk("temp-logs", logs))
+	assert.NoError(t, writer.SaveSchemaRecordBatch("temp-logs", "logs-0"))
+	metadata.Snapshots = append(metadata.Snapshots, entities.BackupMetadataSnapshot{SnapshotId: snapshots[0].SnapshotId})
+	assert.NoError(t, writer.CommitSnapshot(&metadata))
+
+	snapshots = append(snapshots, entities.BackupSnapshot{
+		SnapshotId: uuid.NewString(),
+		Schemas:    map[string]string{"users": "diffs/" + updatedTable.Hash()},
+		Data:       map[string]entities.BackupSnapshotSchemaData{"users": {Data: []string{"diffs/users-1"}}, "logs": {Data: []string{"diffs/logs-1"}}},
+	})
+	writer = binary.NewBinaryBackupWriter(storage, binary.BinaryBackupOptions{})
+	assert.NoError(t, writer.BeginSnapshot(&snapshots[1]))
+	assert.NoError(t, writer.SaveSchemaDiff(updatedTable.Diff(table, false)))
+	assert.NoError(t, writer.SaveSchemaRecordChunkDiff("users-0", "diffs/users-1", chunks[1].Diff(chunks[0], false)))
+	assert.NoError(t, writer.SaveSchemaRecordChunkDiff("logs-0", "diffs/logs-1", updatedLogs.Diff(logs, false)))
+	metadata.Snapshots = append(metadata.Snapshots, entities.BackupMetadataSnapshot{SnapshotId: snapshots[1].SnapshotId})
+	assert.NoError(t, writer.CommitSnapshot(&metadata))
+
+	snapshots = append(snapshots, entities.BackupSnapshot{
+		SnapshotId: uuid.NewString(),
+		Schemas:    map[string]string{"users": updatedTable.Hash()},
+		Data:       map[string]entities.BackupSnapshotSchemaData{"users": {Data: []string{"diffs/users-2"}}, "logs": {Data: []string{"logs-1"}}},
+	})
+	writer = binary.NewBinaryBackupWriter(storage, binary.BinaryBackupOptions{})
+	assert.NoError(t, writer.BeginSnapshot(&snapshots[2]))
+	assert.NoError(t, writer.SaveSchemaRecordChunkDiff("users-1", "diffs/users-2", chunks[2].Diff(chunks[1], false)))
+	metadata.Snapshots = append(metadata.Snapshots, entities.BackupMetadataSnapshot{SnapshotId: snapshots[2].SnapshotId})
+	assert.NoError(t, writer.CommitSnapshot(&metadata))
+
+	reader := binary.NewBinaryBackupReader(storage, nil)
+	readMetadata, err := reader.GetBackupMetadata()
+	assert.NoError(t, err)
+	assert.True(t, readMetadata.ReverseDeltas)
+
+	// The last snapshot is saved in full, while the chunks of the older ones are diffs of the chunks which replaced them
+	for i, expected := range []struct {
+		batchRef string
+		isDiff   bool
+	}{{"users-0", true}, {"users-1", true}, {"users-2", false}} {
+		snapshot, err := reader.GetBackupSnapshot(snapshots[i].SnapshotId)
+		assert.NoError(t, err)
+		assert.Equal(t, []string{expected.batchRef}, snapshot.Data["users"].Data, "batch of snapshot %d", i)
+
+		schema, isDiff, err := reader.GetSchema(snapshot.Schemas["users"])
+		assert.NoError(t, err)
+		assert.False(t, isDiff)
+		assert.Equal(t, min(i, 1), slices.Index([]string{table.Hash(), updatedTable.Hash()}, schema.Hash()))
+
+		chunkRefs, err := reader.GetSchemaRecordChunkRefsInBatch(expected.batchRef)
+		assert.NoError(t, err)
+		assert.Equal(t, []string{chunks[i].Hash()}, chunkRefs)
+		chunk, isDiff, err := reader.GetSchemaRecordChunk(expected.batchRef, chunkRefs[0])
+		assert.NoError(t, err)
+		assert.Equal(t, expected.isDiff, isDiff, "chunk of snapshot %d", i)
+		assert.Equal(t, chunks[i].Hash(), chunk.Hash())
+	}
+
+	chunk, isDiff, err := reader.GetSchemaRecordChunk("logs-0", logsHash)
+	assert.NoError(t, err)
+	assert.True(t, isDiff)
+	assert.Equal(t, logsHash, chunk.Hash())
+
+	// The full copies of the replaced chunks are removed
+	objects, err := reader.ListBackupObjects()
+	assert.NoError(t, err)
+	referenced, err := reader.ListReferencedObjects(snapshots)
+	assert.NoError(t, err)
+	assert.Equal(t, referenced, objects)
+	files, err := reader.ListBackupFiles()
+	assert.NoError(t, err)
+	for _, name := range files {
+		assert.NoError(t, reader.CheckBackupFile(name), "%s is intact", name)
+	}
+}
+
+func TestBinaryBackupCollectsGarbage(t *testing.T) {
+	storage := local.NewLocalStorage(filepath.Join(t.TempDir(), "backup"))
+	options := binary.BinaryBackupOptions{Compression: entities.NoCompression}
+	table := &sql.SQLTable{Name: "users", Columns: []sql.SQLTableColumn{{Name: "id", Type: "integer", Position: 1}}}
+	unreferencedTable := &sql.SQLTable{Name: "groups", Columns: []sql.SQLTableColumn{{Name: "id", Type: "integer", Position: 1}}}
+	snapshot := entities.BackupSnapshot{SnapshotId: uuid.NewString(), Schemas: map[string]string{table.Name: table.Hash()}}
+
+	// The pack of the snapshot also holds a schema the snapshot does not reference
+	writer := binary.NewBinaryBackupWriter(storage, options)
+	assert.NoError(t, writer.CreateBackupStructure())
+	assert.NoError(t, writer.BeginSnapshot(&snapshot))
+	assert.NoError(t, writer.SaveSchema(table))
+	assert.NoError(t, writer.SaveSchema(unreferencedTable))
+	assert.NoError(t, writer.CommitSnapshot(&entities.BackupMetadata{DatabaseEngine: "sqlite", Snapshots: []entities.BackupMetadataSnapshot{{SnapshotId: snapshot.SnapshotId}}}))
+	assert.NoError(t, storage.Put("data/orphan.hdb", []byte("orphan")))
+	assert.NoError(t, storage.Put("packs/unindexed.pack", []byte("unindexed")))
+	packs, err := storage.List("packs/")
+	assert.NoError(t, err)
+
+	sweep, err := binary.NewBinaryBackupWriter(storage, options).CollectGarbage(true)
+	assert.NoError(t, err)
+	assert.Contains(t, sweep.DeletedFiles, "data/orphan.hdb")
+	assert.Contains(t, sweep.DeletedFiles, "packs/unindexed.pack")
+	assert.Positive(t, sweep.ReclaimedBytes)
+	names, err := storage.List("packs/")
+	assert.NoError(t, err)
+	assert.ElementsMatch(t, packs, names, "dry run deletes nothing")
+
+	collected, err := binary.NewBinaryBackupWriter(storage, options).CollectGarbage(false)
+	assert.NoError(t, err)
+	assert.Equal(t, sweep, collected)
+	assert.Equal(t, []string{filepath.Join("schemas", table.Hash()+".hdb")}, packedObjects(t, storage, "schemas/"), "pack is packed again without the unreferenced schema")
+
+	reader := binary.NewBinaryBackupReader(storage, nil)
+	objects, err := reader.ListBackupObjects()
+	assert.NoError(t, err)
+	referenced, err := reader.ListReferencedObjects([]entities.BackupSnapshot{snapshot})
+	assert.NoError(t, err)
+	assert.Equal(t, referenced, objects)
+	schema, _, err := reader.GetSchema(table.Hash())
+	assert.NoError(t, err)
+	assert.Equal(t, table.Hash(), schema.Hash())
+
+	sweep, err = binary.NewBinaryBackupWriter(storage, options).CollectGarbage(false)
+	assert.NoError(t, err)
+	assert.Empty(t, sweep.DeletedFiles)
+}
+
+func TestBinaryBackupRecoversInterruptedSnapshots(t *testing.T) {
+	storage := local.NewLocalStorage(filepath.Join(t.TempDir(), "backup"))
+	options := binary.BinaryBackupOptions{Compression: entities.NoCompression}
+	table := &sql.SQLTable{Name: "users", Columns: []sql.SQLTableColumn{{Name: "id", Type: "integer", Position: 1}}}
+	first := entities.BackupMetadataSnapshot{SnapshotId: uuid.NewString()}
+	second := entities.BackupMetadataSnapshot{SnapshotId: uuid.NewString()}
+
+	writer := binary.NewBinaryBackupWriter(storage, options)
+	assert.NoError(t, writer.CreateBackupStructure())
+	assert.NoError(t, writer.BeginSnapshot(&entities.BackupSnapshot{SnapshotId: first.SnapshotId}))
+	assert.NoError(t, writer.SaveSchema(table))
+	assert.NoError(t, writer.CommitSnapshot(&entities.BackupMetadata{DatabaseEngine: "sqlite", Snapshots: []entities.BackupMetadataSnapshot{first}}))
+
+	// The commit of the second snapshot is interrupted before the metadata lists it
+	crashedWriter := binary.NewBinaryBackupWriter(crashingStorage{storage}, options)
+	assert.NoError(t, crashedWriter.BeginSnapshot(&entities.BackupSnapshot{SnapshotId: second.SnapshotId}))
+	assert.NoError(t, crashedWriter.SaveSchema(table))
+	assert.Error(t, crashedWriter.CommitSnapshot(&entities.BackupMetadata{DatabaseEngine: "sqlite", Snapshots: []entities.BackupMetadataSnapshot{first, second}}))
+	assert.NoError(t, crashedWriter.RollbackSnapshot(), "snapshot whose commit began is kept to be recovered")
+
+	// A third snapshot is interrupted before its commit begins
+	unfinished := uuid.NewString()
+	unfinishedWriter := binary.NewBinaryBackupWriter(storage, options)
+	assert.NoError(t, unfinishedWriter.BeginSnapshot(&entities.BackupSnapshot{SnapshotId: unfinished}))
+	assert.NoError(t, unfinishedWriter.SaveSchema(table))
+
+	metadata, err := binary.NewBinaryBackupReader(storage, nil).GetBackupMetadata()
+	assert.NoError(t, err)
+	assert.Len(t, metadata.Snapshots, 1, "interrupted snapshot is not listed before the recovery")
+
+	committing, pending, err := binary.NewBinaryBackupWriter(storage, options).PendingSnapshots()
+	assert.NoError(t, err)
+	assert.Equal(t, []string{second.SnapshotId}, committing)
+	assert.Equal(t, []string{unfinished}, pending)
+	_, err = os.Stat(filepath.Join(storage.StagingPath(), second.SnapshotId, "commit.intent"))
+	assert.NoError(t, err, "pending snapshots are only listed")
+
+	sharedWriter := binary.NewBinaryBackupWriter(storage, options)
+	_, _, err = sharedWriter.RecoverSnapshots(true)
+	assert.ErrorIs(t, err, services.ErrBackupLockNotExclusive, "snapshots are not recovered without a lock")
+	_, err = sharedWriter.LockBackup(false)
+	assert.NoError(t, err)
+	_, _, err = sharedWriter.RecoverSnapshots(true)
+	assert.ErrorIs(t, err, services.ErrBackupLockNotExclusive, "snapshots are not recovered under a shared lock")
+	assert.NoError(t, sharedWriter.UnlockBackup())
+
+	committed, discarded, err := recoverSnapshots(t, storage, options, true)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{second.SnapshotId}, committed)
+	assert.Equal(t, []string{unfinished}, discarded)
+
+	reader := binary.NewBinaryBackupReader(storage, nil)
+	metadata, err = reader.GetBackupMetadata()
+	assert.NoError(t, err)
+	assert.Len(t, metadata.Snapshots, 2, "interrupted commit is completed")
+	_, err = reader.GetBackupSnapshot(second.SnapshotId)
+	assert.NoError(t, err)
+	for _, snapshotId := range []string{second.SnapshotId, unfinished} {
+		_, err = os.Stat(filepath.Join(storage.StagingPath(), snapshotId))
+		assert.ErrorIs(t, err, fs.ErrNotExist, "transaction directory is removed")
+	}
+
+	committed, discarded, err = recoverSnapshots(t, storage, options, true)
+	assert.NoError(t, err)
+	assert.Empty(t, committed)
+	assert.Empty(t, discarded)
+}
+
+func TestBinaryBackupResumesSnapshot(t *testing.T) {
+	storage := local.NewLocalStorage(filepath.Join(t.TempDir(), "backup"))
+	options := binary.BinaryBackupOptions{Compression: entities.NoCompression}
+	table := &sql.SQLTable{Name: "users", Columns: []sql.SQLTableColumn{{Name: "id", Type: "integer", Position: 1}}}
+	users := &sql.SQLRecordChunk{Content: []sql.SQLRecord{{Content: map[string]interface{}{"id": "1"}}}}
+	first := entities.BackupMetadataSnapshot{SnapshotId: uuid.NewString()}
+	second := entities.BackupMetadataSnapshot{SnapshotId: uuid.NewString(), Message: "interrupted"}
+	newSnapshot := func() *entities.BackupSnapshot {
+		return &entities.BackupSnapshot{Data: make(map[string]entities.BackupSnapshotSchemaData)}
+	}
+
+	writer := binary.NewBinaryBackupWriter(storage, options)
+	assert.NoError(t, writer.CreateBackupStructure())
+	assert.NoError(t, writer.BeginSnapshot(&entities.BackupSnapshot{SnapshotId: first.SnapshotId}))
+	assert.NoError(t, writer.SaveSchema(table))
+	assert.NoError(t, writer.CommitSnapshot(&entities.BackupMetadata{DatabaseEngine: "sqlite", Snapshots: []entities.BackupMetadataSnapshot{first}}))
+
+	// The second snapshot is interrupted once the records of users are saved
+	interruptedWriter := binary.NewBinaryBackupWriter(storage, options)
+	assert.False(t, interruptedWriter.CanResumeSnapshot())
+	assert.NoError(t, interruptedWriter.BeginSnapshot(&entities.BackupSnapshot{SnapshotId: second.SnapshotId, Message: second.Message}))
+	assert.NoError(t, interruptedWriter.SaveSchemaRecordChunk("temp-users", users))
+	assert.NoError(t, interruptedWriter.SaveSchemaRecordBatch("temp-users", "users"))
+	progress := &entities.BackupSchemaProgress{SchemaHash: table.Hash(), Complete: true, Data: &entities.BackupSnapshotSchemaData{Data: []string{"users"}, Chunking: entities.FixedSizeChunking}, SavedRecords: 1}
+	assert.NoError(t, interruptedWriter.SaveSnapshotProgress(table.GetName(), progress))
+	assert.True(t, interruptedWriter.CanResumeSnapshot())
+	assert.NoError(t, interruptedWriter.RollbackSnapshot())
+
+	committed, discarded, err := recoverSnapshots(t, storage, options, false)
+	assert.NoError(t, err)
+	assert.Empty(t, committed)
+	assert.Empty(t, discarded, "resumable snapshot is kept")
+
+	resumed := newSnapshot()
+	resumedWriter := binary.NewBinaryBackupWriter(storage, options)
+	checkpoint, err := resumedWriter.ResumeSnapshot(resumed)
+	assert.NoError(t, err)
+	assert.Equal(t, second.SnapshotId, resumed.SnapshotId)
+	assert.Equal(t, second.Message, resumed.Message)
+	assert.Equal(t, first.SnapshotId, checkpoint.PrevSnapshotId)
+	assert.Equal(t, progress, checkpoint.Schemas[table.GetName()])
+
+	resumed.Data[table.GetName()] = *progress.Data
+	assert.NoError(t, resumedWriter.SaveSchema(table))
+	assert.NoError(t, resumedWriter.CommitSnapshot(&entities.BackupMetadata{DatabaseEngine: "sqlite", Snapshots: []entities.BackupMetadataSnapshot{first, second}}))
+
+	reader := binary.NewBinaryBackupReader(storage, nil)
+	chunkRefs, err := reader.GetSchemaRecordChunkRefsInBatch("users")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{users.Hash()}, chunkRefs, "batch saved before the interruption is committed")
+	checkpoint, err = binary.NewBinaryBackupWriter(storage, options).ResumeSnapshot(newSnapshot())
+	assert.NoError(t, err)
+	assert.Nil(t, checkpoint, "committed snapshot is not resumed again")
+
+	// A snapshot begun from a state of the backup which changed meanwhile is discarded
+	outdated := uuid.NewString()
+	outdatedWriter := binary.NewBinaryBackupWriter(storage, options)
+	assert.NoError(t, outdatedWriter.BeginSnapshot(&entities.BackupSnapshot{SnapshotId: outdated}))
+	assert.NoError(t, outdatedWriter.SaveSnapshotProgress(table.GetName(), &entities.BackupSchemaProgress{SchemaHash: table.Hash(), Complete: true}))
+	assert.NoError(t, outdatedWriter.RollbackSnapshot())
+	third := entities.BackupMetadataSnapshot{SnapshotId: uuid.NewString()}
+	assert.NoError(t, writer.BeginSnapshot(&entities.BackupSnapshot{SnapshotId: third.SnapshotId}))
+	assert.NoError(t, writer.CommitSnapshot(&entities.BackupMetadata{DatabaseEngine: "sqlite", Snapshots: []entities.BackupMetadataSnapshot{first, second, third}}))
+
+	_, err = binary.NewBinaryBackupWriter(storage, options).ResumeSnapshot(newSnapshot())
+	assert.ErrorIs(t, err, services.ErrBackupCheckpointOutdated)
+	_, err = os.Stat(filepath.Join(storage.StagingPath(), outdated))
+	assert.ErrorIs(t, err, fs.ErrNotExist, "outdated snapshot is discarded")
+
+	// Commands which do not resume snapshots discard them
+	unfinished := uuid.NewString()
+	unfinishedWriter := binary.NewBinaryBackupWriter(storage, options)
+	assert.NoError(t, unfinishedWriter.BeginSnapshot(&entities.BackupSnapshot{SnapshotId: unfinished}))
+	assert.NoError(t, unfinishedWriter.SaveSnapshotProgress(table.GetName(), &entities.BackupSchemaProgress{SchemaHash: table.Hash(), Complete: true}))
+	assert.NoError(t, unfinishedWriter.RollbackSnapshot())
+
+	committed, discarded, err = recoverSnapshots(t, storage, options, true)
+	assert.NoError(t, err)
+	assert.Empty(t, committed)
+	assert.Equal(t, []string{unfinished}, discarded)
+}
+
+func TestBinaryBackupLock(t *testing.T) {
+	storage := local.NewLocalStorage(filepath.Join(t.TempDir(), "backup"))
+	options := binary.BinaryBackupOptions{Compression: entities.NoCompression}
+	host, err := os.Hostname()
+	assert.NoError(t, err)
+
+	owner := binary.NewBinaryBackupWriter(storage, options)
+	_, err = owner.LockBackup(true)
+	assert.NoError(t, err)
+	_, err = binary.NewBinaryBackupWriter(storage, options).LockBackup(false)
+	assert.ErrorIs(t, err, services.ErrBackupLocked, "exclusive lock keeps readers out")
+	_, err = binary.NewBinaryBackupWriter(storage, options).LockBackup(true)
+	assert.ErrorIs(t, err, services.ErrBackupLocked, "exclusive lock keeps writers out")
+	assert.NoError(t, owner.UnlockBackup())
+
+	readers := []*binary.BinaryBackupWriter{binary.NewBinaryBackupWriter(storage, options), binary.NewBinaryBackupWriter(storage, options)}
+	for _, reader := range readers {
+		_, err = reader.LockBackup(false)
+		assert.NoError(t, err, "shared locks are held at once")
+	}
+	_, err = binary.NewBinaryBackupWriter(storage, options).LockBackup(true)
+	assert.ErrorIs(t, err, services.ErrBackupLocked, "shared lock keeps writers out")
+	for _, reader := range readers {
+		assert.NoError(t, reader.UnlockBackup())
+	}
+	names, err := storage.List("locks/")
+	assert.NoError(t, err)
+	assert.Empty(t, names, "released locks are removed")
+
+	// The locks left by a process which is gone from this host, or which were not refreshed for long, are stale
+	process := exec.Command("true")
+	assert.NoError(t, process.Run())
+	staleLocks := []entities.BackupLock{
+		{Exclusive: true, PID: int64(process.Process.Pid), Host: host, Time: time.Now().UTC()},
+		{Exclusive: true, PID: 1, Host: "other-host", Time: time.Now().Add(-time.Hour).UTC()},
+	}
+	for _, lock := range staleLocks {
+		assert.NoError(t, storage.Put("locks/"+uuid.NewString()+".lock", lock.EncodeToBytes()))
+	}
+	removed, err := owner.LockBackup(true)
+	assert.NoError(t, err)
+	assert.Len(t, removed, 2)
+	assert.NoError(t, owner.UnlockBackup())
+
+	// A lock of another host can only be stale once it is not refreshed
+	assert.NoError(t, storage.Put("locks/"+uuid.NewString()+".lock", (&entities.BackupLock{PID: 1, Host: "other-host", Time: time.Now().UTC()}).EncodeToBytes()))
+	_, err = owner.LockBackup(true)
+	assert.ErrorIs(t, err, services.ErrBackupLocked)
+	assert.ErrorContains(t, err, "other-host")
+}
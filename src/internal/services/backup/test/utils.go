@@ -1,9 +1,17 @@
 package test
 
 import (
+	"bytes"
+	"crypto/sha256"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"historydb/src/internal/services/backup/binary"
+	storage_services "historydb/src/internal/services/storage"
+	"historydb/src/internal/utils/decode"
 	"os"
+	"strings"
+	"testing"
 	"time"
 )
 
@@ -58,3 +66,58 @@ func normalize(v interface{}) interface{} {
 	}
 	return v
 }
+
+// crashingStorage is a storage whose writes of the metadata fail, as if the process crashed before swapping it.
+type crashingStorage struct {
+	storage_services.Storage
+}
+
+func (storage crashingStorage) Put(name string, content []byte) error {
+	if name == "metadata.hdb" {
+		return errors.New("crashed before putting the metadata")
+	}
+	return storage.Storage.Put(name, content)
+}
+
+// recoverSnapshots recovers the interrupted snapshots of the backup under its exclusive lock, as a command would.
+func recoverSnapshots(t *testing.T, storage storage_services.Storage, options binary.BinaryBackupOptions, discardResumable bool) ([]string, []string, error) {
+	writer := binary.NewBinaryBackupWriter(storage, options)
+	if _, err := writer.LockBackup(true); err != nil {
+		t.Fatal("could not lock the backup", err)
+	}
+	defer writer.UnlockBackup()
+	return writer.RecoverSnapshots(discardResumable)
+}
+
+// packedObjects returns the names of the objects with the prefix listed in the pack indexes of an uncompressed and
+// unencrypted backup, whose indexes are only their integrity hash followed by their entries.
+func packedObjects(t *testing.T, storage storage_services.Storage, prefix string) []string {
+	names, err := storage.List("packs/")
+	if err != nil {
+		t.Fatal("could not list pack files", err)
+	}
+
+	objects := []string{}
+	for _, name := range names {
+		if !strings.HasSuffix(name, ".idx") {
+			continue
+		}
+		content, err := storage.Get(name)
+		if err != nil {
+			t.Fatal("could not read pack index", err)
+		}
+
+		buf := bytes.NewBuffer(content[sha256.Size:])
+		for buf.Len() > 0 {
+			object, err := decode.DecodeString(buf)
+			if err != nil {
+				t.Fatal("could not decode pack index", err)
+			}
+			buf.Next(16) // Skips the offset and length of the object
+			if strings.HasPrefix(*object, prefix) {
+				objects = append(objects, *object)
+			}
+		}
+	}
+	return objects
+}
@@ -6,9 +6,13 @@ package database_services
 // CreateWriter() -> Creates the Writer to insert data into the DB.
 // GetDBEngine() -> Returns the DB engine.
 // CheckBackupDB() -> Check the backup engine matches the DB engine.
+// CreateParallelReader() -> Creates a new Reader with its own connection, which reads the same state of the DB as the Reader transaction in progress.
+// CreateParallelWriter() -> Creates a new Writer with its own connection, which inserts data into the schemas committed by the Writer.
 type DatabaseFactory interface {
 	CreateReader() DatabaseReader
+	CreateParallelReader() (DatabaseReader, error)
 	CreateWriter() DatabaseWriter
+	CreateParallelWriter() (DatabaseWriter, error)
 	GetDBEngine() string
 	CheckBackupDB(engine string) bool
 }
@@ -4,14 +4,21 @@ import "historydb/src/internal/entities"
 
 // DatabaseReader is the interface that defines the functionality for querying the DB.
 //
+// BeginTransaction() -> Begins a read-only DB transaction, so every query until EndTransaction reads the same state of the DB.
+// EndTransaction() -> Ends the read-only DB transaction.
 // CheckDBIsEmpty() -> Checks the DB is empty. It is used when we desire to save our backup into a DB so we need an empty DB.
 // ListSchemaDependencies() -> List the DB dependencies. (Sequences, etc...)
 // ListSchemaNames() -> Retrieves all the schema names from the DB.
 // GetSchemaDefinition() -> Retrieves the schema definition from a DB given its name. (Tables, etc...)
 // GetSchemaRecordMetadata() -> Retrieves the metadata needed to get the records in a single schema. (record size, total records)
 // GetSchemaRecordChunk() -> Retrieves a chunk of records from the given schema and use a cursor to iterate over it.
+// EncodeChunkCursor() -> Encodes a cursor returned by GetSchemaRecordChunk, so the records can be read from it by another process.
+// DecodeChunkCursor() -> Decodes a cursor encoded with EncodeChunkCursor.
 // ListRotines() -> Retrieves a list of db routines from the DB. (Functions, procedures, triggers...)
 type DatabaseReader interface {
+	BeginTransaction() error
+	EndTransaction() error
+
 	CheckDBIsEmpty() (bool, error)
 
 	ListSchemaDependencies() ([]entities.SchemaDependency, error)
@@ -19,5 +26,7 @@ type DatabaseReader interface {
 	GetSchemaDefinition(schemaName string) (entities.Schema, error)
 	GetSchemaRecordMetadata(schemaName string) (entities.SchemaRecordMetadata, error)
 	GetSchemaRecordChunk(schema entities.Schema, chunkSize int64, chunkCursor interface{}) (entities.SchemaRecordChunk, interface{}, error)
+	EncodeChunkCursor(chunkCursor interface{}) []byte
+	DecodeChunkCursor(data []byte) (interface{}, error)
 	ListRoutines() ([]entities.Routine, error)
 }
@@ -2,6 +2,10 @@ package database_services
 
 import "historydb/src/internal/entities"
 
+// RestoreCheckpointTable is the table where a restore committed in several transactions saves its progress. It is
+// dropped once the restore is completed.
+const RestoreCheckpointTable = "historydb_restore_checkpoint"
+
 // DatabaseWriter is the interface that defines the functionality to insert data into the DB.
 //
 // BeginTransaction() -> Begins a DB transaction.
@@ -9,9 +13,17 @@ import "historydb/src/internal/entities"
 // RollbacksTransaction() -> Rollbacks a DB transaction.
 // SaveSchemaDependency() -> Inserts a schema dependency into the DB.
 // SaveSchema() -> Inserts a schema into the DB.
-// SaveSchemaRules() -> Updates a schema with its rules and constraints in the DB.
+// SaveSchemaRules() -> Updates a schema with its indexes and rules in the DB.
+// SaveSchemaConstraints() -> Updates a schema with its foreign keys in the DB, without checking its records when the DB allows it.
+// ValidateSchemaConstraints() -> Checks the records of a schema against the foreign keys added without checking them.
 // SaveSchemaRecords() -> Inserts a chunk of data into its schema in the DB.
 // SaveRoutine() -> Inserts a routine into the DB.
+// DropCreatedObjects() -> Drops every object created by the writer, including the ones of its committed transactions.
+// KeepCreatedObjects() -> Stops tracking the objects created so far, so they are kept even if a later transaction is rolled back.
+// CreateRestoreCheckpoint() -> Creates the table where the progress of a restore committed in several transactions is saved.
+// SaveRestoreCheckpoint() -> Saves an entry of the progress of the restore inside the transaction, so it is committed along with the objects it refers to.
+// GetRestoreCheckpoint() -> Retrieves the entries of the progress saved by an interrupted restore, or nil if there is none.
+// DropRestoreCheckpoint() -> Drops the table of the progress of the restore inside the transaction, once the restore is completed.
 type DatabaseWriter interface {
 	BeginTransaction() error
 	CommitTransaction() error
@@ -20,6 +32,15 @@ type DatabaseWriter interface {
 	SaveSchemaDependency(dependency entities.SchemaDependency) error
 	SaveSchema(schema entities.Schema) error
 	SaveSchemaRules(schema entities.Schema) error
+	SaveSchemaConstraints(schema entities.Schema) error
+	ValidateSchemaConstraints(schema entities.Schema) error
 	SaveSchemaRecords(schema entities.Schema, chunk entities.SchemaRecordChunk) error
 	SaveRoutine(routine entities.Routine) error
+	DropCreatedObjects() error
+	KeepCreatedObjects()
+
+	CreateRestoreCheckpoint() error
+	SaveRestoreCheckpoint(name, value string) error
+	GetRestoreCheckpoint() (map[string]string, error)
+	DropRestoreCheckpoint() error
 }
@@ -0,0 +1,64 @@
+package mongo
+
+import (
+	"fmt"
+	database_services "historydb/src/internal/services/database"
+
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/x/mongo/driver/connstring"
+)
+
+type MongoDatabaseFactory struct {
+	db       *mongo.Database
+	dbReader *MongoDatabaseReader
+	dbWriter *MongoDatabaseWriter
+}
+
+func NewMongoDatabaseFactory(db *mongo.Database) *MongoDatabaseFactory {
+	return &MongoDatabaseFactory{db, nil, nil}
+}
+
+func (factory *MongoDatabaseFactory) CreateReader() database_services.DatabaseReader {
+	if factory.dbReader == nil {
+		factory.dbReader = NewMongoDatabaseReader(factory.db)
+	}
+	return factory.dbReader
+}
+
+// CreateParallelReader creates a new reader, as MongoDB readers do not use transactions.
+func (factory *MongoDatabaseFactory) CreateParallelReader() (database_services.DatabaseReader, error) {
+	return NewMongoDatabaseReader(factory.db), nil
+}
+
+func (factory *MongoDatabaseFactory) CreateWriter() database_services.DatabaseWriter {
+	if factory.dbWriter == nil {
+		factory.dbWriter = NewMongoDatabaseWriter(factory.db)
+	}
+	return factory.dbWriter
+}
+
+// CreateParallelWriter creates a new writer, as MongoDB writers do not use transactions.
+func (factory *MongoDatabaseFactory) CreateParallelWriter() (database_services.DatabaseWriter, error) {
+	return NewMongoDatabaseWriter(factory.db), nil
+}
+
+func (factory *MongoDatabaseFactory) GetDBEngine() string {
+	return "mongodb"
+}
+
+func (factory *MongoDatabaseFactory) CheckBackupDB(engine string) bool {
+	return engine == "mongodb"
+}
+
+// ParseDatabaseName extracts the database name from a MongoDB connection string (mongodb://host:port/database?params).
+func ParseDatabaseName(connString string) (string, error) {
+	parsedConnString, err := connstring.ParseAndValidate(connString)
+	if err != nil {
+		return "", err
+	}
+
+	if parsedConnString.Database == "" {
+		return "", fmt.Errorf("no database provided in connection string")
+	}
+	return parsedConnString.Database, nil
+}
@@ -0,0 +1,347 @@
+package mongo
+
+import (
+	"context"
+	"historydb/src/internal/entities"
+	mongo_entities "historydb/src/internal/services/entities/mongo"
+	"slices"
+	"sort"
+	"strings"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+// BSON types sorted by the order MongoDB uses to compare values of different types.
+// Types in the same group are compared by their value.
+var bsonTypeSortOrder = [][]bson.Type{
+	{bson.TypeMinKey},
+	{bson.TypeUndefined, bson.TypeNull},
+	{bson.TypeInt32, bson.TypeInt64, bson.TypeDouble, bson.TypeDecimal128},
+	{bson.TypeSymbol, bson.TypeString},
+	{bson.TypeEmbeddedDocument},
+	{bson.TypeBinary},
+	{bson.TypeObjectID},
+	{bson.TypeBoolean},
+	{bson.TypeDateTime},
+	{bson.TypeTimestamp},
+	{bson.TypeRegex},
+	{bson.TypeMaxKey},
+}
+
+type MongoDatabaseReader struct {
+	db *mongo.Database
+}
+
+func NewMongoDatabaseReader(db *mongo.Database) *MongoDatabaseReader {
+	return &MongoDatabaseReader{db}
+}
+
+// BeginTransaction does nothing, as MongoDB snapshot reads require a replica set and expire after a few minutes.
+func (reader *MongoDatabaseReader) BeginTransaction() error {
+	return nil
+}
+
+// EndTransaction does nothing, as MongoDB readers do not use transactions.
+func (reader *MongoDatabaseReader) EndTransaction() error {
+	return nil
+}
+
+func (reader *MongoDatabaseReader) CheckDBIsEmpty() (bool, error) {
+	names, err := reader.db.ListCollectionNames(context.Background(), bson.D{})
+	if err != nil {
+		return false, err
+	}
+
+	for _, name := range names {
+		if !strings.HasPrefix(name, "system.") {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// ListSchemaDependencies returns no dependencies, as MongoDB collections do not depend on other objects.
+func (reader *MongoDatabaseReader) ListSchemaDependencies() ([]entities.SchemaDependency, error) {
+	return []entities.SchemaDependency{}, nil
+}
+
+func (reader *MongoDatabaseReader) ListSchemaNames() ([]string, error) {
+	names, err := reader.db.ListCollectionNames(context.Background(), bson.D{{Key: "type", Value: "collection"}})
+	if err != nil {
+		return nil, err
+	}
+
+	schemaNames := []string{}
+	for _, name := range names {
+		if !strings.HasPrefix(name, "system.") {
+			schemaNames = append(schemaNames, name)
+		}
+	}
+	sort.Strings(schemaNames)
+
+	return schemaNames, nil
+}
+
+func (reader *MongoDatabaseReader) GetSchemaDefinition(schemaName string) (entities.Schema, error) {
+	specs, err := reader.db.ListCollectionSpecifications(context.Background(), bson.D{{Key: "name", Value: schemaName}})
+	if err != nil {
+		return nil, err
+	}
+	if len(specs) == 0 {
+		return nil, mongo.ErrNoDocuments
+	}
+
+	collection := mongo_entities.MongoCollection{Name: schemaName}
+	if err := extractCollectionOptions(&collection, specs[0].Options); err != nil {
+		return nil, err
+	}
+
+	indexes, err := reader.extractIndexesFromCollection(schemaName)
+	if err != nil {
+		return nil, err
+	}
+	collection.Indexes = indexes
+
+	return &collection, nil
+}
+
+func (reader *MongoDatabaseReader) GetSchemaRecordMetadata(schemaName string) (entities.SchemaRecordMetadata, error) {
+	metadata := entities.SchemaRecordMetadata{}
+	collection := reader.db.Collection(schemaName)
+
+	count, err := collection.CountDocuments(context.Background(), bson.D{})
+	if err != nil {
+		return metadata, err
+	}
+	metadata.Count = int(count)
+	if count == 0 {
+		return metadata, nil
+	}
+
+	maxRecordSize, err := reader.aggregateMaxDocumentSize(collection)
+	if err != nil {
+		// Servers without $bsonSize (MongoDB < 4.4 and some compatible servers) need the documents to be scanned
+		maxRecordSize, err = reader.scanMaxDocumentSize(collection)
+		if err != nil {
+			return metadata, err
+		}
+	}
+	metadata.MaxRecordSize = maxRecordSize
+
+	return metadata, nil
+}
+
+func (reader *MongoDatabaseReader) GetSchemaRecordChunk(schema entities.Schema, chunkSize int64, chunkCursor interface{}) (entities.SchemaRecordChunk, interface{}, error) {
+	collection := schema.(*mongo_entities.MongoCollection)
+
+	filter := bson.D{}
+	if cursor, ok := chunkCursor.(*mongo_entities.MongoChunkCursor); ok && cursor != nil {
+		filter = buildIDFilter(cursor.LastID)
+	}
+
+	opts := options.Find().SetSort(bson.D{{Key: "_id", Value: 1}}).SetLimit(chunkSize)
+	cursor, err := reader.db.Collection(collection.Name).Find(context.Background(), filter, opts)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer cursor.Close(context.Background())
+
+	results := make([]mongo_entities.MongoDocument, 0, chunkSize)
+	for cursor.Next(context.Background()) {
+		// The cursor reuses its buffer, so the document needs to be copied
+		content := make([]byte, len(cursor.Current))
+		copy(content, cursor.Current)
+		results = append(results, mongo_entities.MongoDocument{Content: content})
+	}
+	if err := cursor.Err(); err != nil {
+		return nil, nil, err
+	}
+
+	// A new cursor is returned so the previous one can still be used to read the same chunk again
+	nextCursor, _ := chunkCursor.(*mongo_entities.MongoChunkCursor)
+	if len(results) > 0 {
+		nextCursor = &mongo_entities.MongoChunkCursor{
+			LastID: bson.Raw(results[len(results)-1].Content).Lookup("_id"),
+		}
+	}
+	return &mongo_entities.MongoDocumentChunk{
+		Content: results,
+	}, nextCursor, nil
+}
+
+func (reader *MongoDatabaseReader) EncodeChunkCursor(chunkCursor interface{}) []byte {
+	cursor, ok := chunkCursor.(*mongo_entities.MongoChunkCursor)
+	if !ok || cursor == nil {
+		// A nil cursor is read from the first record
+		return nil
+	}
+	return cursor.EncodeToBytes()
+}
+
+func (reader *MongoDatabaseReader) DecodeChunkCursor(data []byte) (interface{}, error) {
+	if len(data) == 0 {
+		return nil, nil
+	}
+	var cursor mongo_entities.MongoChunkCursor
+	if err := cursor.DecodeFromBytes(data); err != nil {
+		return nil, err
+	}
+	return &cursor, nil
+}
+
+// ListRoutines returns no routines, as MongoDB does not store any routine in the database.
+func (reader *MongoDatabaseReader) ListRoutines() ([]entities.Routine, error) {
+	return []entities.Routine{}, nil
+}
+
+// This function is a private MongoDB function that calculates the size of the biggest document of a collection in the server.
+func (reader *MongoDatabaseReader) aggregateMaxDocumentSize(collection *mongo.Collection) (int, error) {
+	cursor, err := collection.Aggregate(context.Background(), mongo.Pipeline{
+		{{Key: "$group", Value: bson.D{
+			{Key: "_id", Value: nil},
+			{Key: "maxRecordSize", Value: bson.D{{Key: "$max", Value: bson.D{{Key: "$bsonSize", Value: "$$ROOT"}}}}},
+		}}},
+	})
+	if err != nil {
+		return 0, err
+	}
+	defer cursor.Close(context.Background())
+
+	var result struct {
+		MaxRecordSize int `bson:"maxRecordSize"`
+	}
+	if cursor.Next(context.Background()) {
+		if err := cursor.Decode(&result); err != nil {
+			return 0, err
+		}
+	}
+
+	return result.MaxRecordSize, cursor.Err()
+}
+
+// This function is a private MongoDB function that calculates the size of the biggest document of a collection
+// reading every document from the server.
+func (reader *MongoDatabaseReader) scanMaxDocumentSize(collection *mongo.Collection) (int, error) {
+	cursor, err := collection.Find(context.Background(), bson.D{})
+	if err != nil {
+		return 0, err
+	}
+	defer cursor.Close(context.Background())
+
+	maxRecordSize := 0
+	for cursor.Next(context.Background()) {
+		maxRecordSize = max(maxRecordSize, len(cursor.Current))
+	}
+
+	return maxRecordSize, cursor.Err()
+}
+
+// This function is a private MongoDB function that extracts the index definitions from a collection into the database.
+// The _id index is skipped, as MongoDB creates it with the collection.
+func (reader *MongoDatabaseReader) extractIndexesFromCollection(collectionName string) ([]mongo_entities.MongoCollectionIndex, error) {
+	cursor, err := reader.db.Collection(collectionName).Indexes().List(context.Background())
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(context.Background())
+
+	indexes := []mongo_entities.MongoCollectionIndex{}
+	for cursor.Next(context.Background()) {
+		elements, err := cursor.Current.Elements()
+		if err != nil {
+			return nil, err
+		}
+
+		var index mongo_entities.MongoCollectionIndex
+		options := bson.D{}
+		for _, element := range elements {
+			switch element.Key() {
+			case "name":
+				index.Name = element.Value().StringValue()
+			case "key":
+				keys, err := bson.MarshalExtJSON(element.Value().Document(), true, false)
+				if err != nil {
+					return nil, err
+				}
+				index.Keys = string(keys)
+			case "v", "ns":
+				// Version and namespace are set by MongoDB when the index is created
+			default:
+				options = append(options, bson.E{Key: element.Key(), Value: element.Value()})
+			}
+		}
+		if index.Name == "_id_" {
+			continue
+		}
+
+		encodedOptions, err := bson.MarshalExtJSON(options, true, false)
+		if err != nil {
+			return nil, err
+		}
+		index.Options = string(encodedOptions)
+		indexes = append(indexes, index)
+	}
+	if err := cursor.Err(); err != nil {
+		return nil, err
+	}
+
+	sort.Slice(indexes, func(i, j int) bool { return indexes[i].Name < indexes[j].Name })
+	return indexes, nil
+}
+
+// extractCollectionOptions fills the capped and validation options of a collection from its listCollections options.
+func extractCollectionOptions(collection *mongo_entities.MongoCollection, collectionOptions bson.Raw) error {
+	if len(collectionOptions) == 0 {
+		return nil
+	}
+
+	if capped, ok := collectionOptions.Lookup("capped").BooleanOK(); ok {
+		collection.Capped = capped
+	}
+	if size, ok := collectionOptions.Lookup("size").AsInt64OK(); ok {
+		collection.Size = size
+	}
+	if max, ok := collectionOptions.Lookup("max").AsInt64OK(); ok {
+		collection.Max = max
+	}
+	if validator, ok := collectionOptions.Lookup("validator").DocumentOK(); ok {
+		encodedValidator, err := bson.MarshalExtJSON(validator, true, false)
+		if err != nil {
+			return err
+		}
+		collection.Validator = string(encodedValidator)
+	}
+	if validationLevel, ok := collectionOptions.Lookup("validationLevel").StringValueOK(); ok {
+		collection.ValidationLevel = validationLevel
+	}
+	if validationAction, ok := collectionOptions.Lookup("validationAction").StringValueOK(); ok {
+		collection.ValidationAction = validationAction
+	}
+
+	return nil
+}
+
+// buildIDFilter creates the filter to get the documents after the given _id when batching data queries.
+// MongoDB only compares values of the same type with $gt, so the documents whose _id has a type sorted after
+// the type of the last _id are selected by their type.
+func buildIDFilter(lastID bson.RawValue) bson.D {
+	nextTypes := bson.A{}
+	found := false
+	for _, group := range bsonTypeSortOrder {
+		if found {
+			for _, t := range group {
+				// $type expects signed type numbers, so MinKey (0xFF) is -1
+				nextTypes = append(nextTypes, int32(int8(t)))
+			}
+			continue
+		}
+		found = slices.Contains(group, lastID.Type)
+	}
+
+	return bson.D{{Key: "$or", Value: bson.A{
+		bson.D{{Key: "_id", Value: bson.D{{Key: "$gt", Value: lastID}}}},
+		bson.D{{Key: "_id", Value: bson.D{{Key: "$type", Value: nextTypes}}}},
+	}}}
+}
@@ -0,0 +1,234 @@
+package mongo
+
+import (
+	"context"
+	"historydb/src/internal/entities"
+	"historydb/src/internal/services"
+	mongo_entities "historydb/src/internal/services/entities/mongo"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+// MongoDatabaseWriter inserts the backup data into a MongoDB database.
+//
+// MongoDB transactions require a replica set and can not create indexes on existing collections, so the writer
+// does not use them. To leave the database clean on a rollback, it keeps track of the collections it creates and drops them.
+// The collections are tracked across transactions, as a restore can be committed in several of them.
+type MongoDatabaseWriter struct {
+	db *mongo.Database
+
+	inTransaction      bool
+	createdCollections []string
+}
+
+func NewMongoDatabaseWriter(db *mongo.Database) *MongoDatabaseWriter {
+	return &MongoDatabaseWriter{db: db}
+}
+
+func (writer *MongoDatabaseWriter) BeginTransaction() error {
+	if writer.inTransaction {
+		return services.ErrDatabaseTransactionAlreadyStarted
+	}
+
+	writer.inTransaction = true
+	return nil
+}
+
+func (writer *MongoDatabaseWriter) CommitTransaction() error {
+	if !writer.inTransaction {
+		return services.ErrDatabaseTransactionNotFound
+	}
+
+	writer.inTransaction = false
+	return nil
+}
+
+func (writer *MongoDatabaseWriter) RollbackTransaction() error {
+	if !writer.inTransaction {
+		return services.ErrDatabaseTransactionNotFound
+	}
+
+	writer.inTransaction = false
+	return writer.DropCreatedObjects()
+}
+
+// SaveSchemaDependency always fails, as MongoDB backups do not have dependencies.
+func (writer *MongoDatabaseWriter) SaveSchemaDependency(dependency entities.SchemaDependency) error {
+	if !writer.inTransaction {
+		return services.ErrDatabaseTransactionNotFound
+	}
+
+	return services.ErrDependencyNotSupported
+}
+
+func (writer *MongoDatabaseWriter) SaveSchema(schema entities.Schema) error {
+	if !writer.inTransaction {
+		return services.ErrDatabaseTransactionNotFound
+	}
+
+	collection, ok := schema.(*mongo_entities.MongoCollection)
+	if !ok {
+		return services.ErrSchemaNotSupported
+	}
+
+	opts := options.CreateCollection()
+	if collection.Capped {
+		opts.SetCapped(true).SetSizeInBytes(collection.Size)
+		if collection.Max > 0 {
+			opts.SetMaxDocuments(collection.Max)
+		}
+	}
+	if err := writer.db.CreateCollection(context.Background(), collection.Name, opts); err != nil {
+		return err
+	}
+
+	writer.createdCollections = append(writer.createdCollections, collection.Name)
+	return nil
+}
+
+// SaveSchemaRules creates the indexes and sets the validator of a collection.
+// It is called once the documents are restored, so they are not validated against rules added after they were inserted.
+func (writer *MongoDatabaseWriter) SaveSchemaRules(schema entities.Schema) error {
+	if !writer.inTransaction {
+		return services.ErrDatabaseTransactionNotFound
+	}
+
+	collection, ok := schema.(*mongo_entities.MongoCollection)
+	if !ok {
+		return services.ErrSchemaNotSupported
+	}
+
+	if len(collection.Indexes) > 0 {
+		indexes := bson.A{}
+		for _, idx := range collection.Indexes {
+			var keys, options bson.D
+			if err := bson.UnmarshalExtJSON([]byte(idx.Keys), true, &keys); err != nil {
+				return err
+			}
+			if err := bson.UnmarshalExtJSON([]byte(idx.Options), true, &options); err != nil {
+				return err
+			}
+
+			index := bson.D{{Key: "key", Value: keys}, {Key: "name", Value: idx.Name}}
+			indexes = append(indexes, append(index, options...))
+		}
+
+		command := bson.D{{Key: "createIndexes", Value: collection.Name}, {Key: "indexes", Value: indexes}}
+		if err := writer.db.RunCommand(context.Background(), command).Err(); err != nil {
+			return err
+		}
+	}
+
+	if collection.Validator != "" {
+		var validator bson.D
+		if err := bson.UnmarshalExtJSON([]byte(collection.Validator), true, &validator); err != nil {
+			return err
+		}
+
+		command := bson.D{{Key: "collMod", Value: collection.Name}, {Key: "validator", Value: validator}}
+		if collection.ValidationLevel != "" {
+			command = append(command, bson.E{Key: "validationLevel", Value: collection.ValidationLevel})
+		}
+		if collection.ValidationAction != "" {
+			command = append(command, bson.E{Key: "validationAction", Value: collection.ValidationAction})
+		}
+		if err := writer.db.RunCommand(context.Background(), command).Err(); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// SaveSchemaConstraints does nothing, as MongoDB collections do not have foreign keys.
+func (writer *MongoDatabaseWriter) SaveSchemaConstraints(schema entities.Schema) error {
+	if !writer.inTransaction {
+		return services.ErrDatabaseTransactionNotFound
+	}
+
+	return nil
+}
+
+// ValidateSchemaConstraints does nothing, as MongoDB collections do not have foreign keys.
+func (writer *MongoDatabaseWriter) ValidateSchemaConstraints(schema entities.Schema) error {
+	if !writer.inTransaction {
+		return services.ErrDatabaseTransactionNotFound
+	}
+
+	return nil
+}
+
+func (writer *MongoDatabaseWriter) SaveSchemaRecords(schema entities.Schema, chunk entities.SchemaRecordChunk) error {
+	if !writer.inTransaction {
+		return services.ErrDatabaseTransactionNotFound
+	}
+
+	collection := schema.(*mongo_entities.MongoCollection)
+	documentChunk, ok := chunk.(*mongo_entities.MongoDocumentChunk)
+	if !ok {
+		return services.ErrRecordNotSupported
+	}
+	if len(documentChunk.Content) == 0 {
+		return nil
+	}
+
+	documents := make([]interface{}, len(documentChunk.Content))
+	for i, document := range documentChunk.Content {
+		documents[i] = bson.Raw(document.Content)
+	}
+
+	// Documents are inserted in order, so capped collections keep their natural order
+	_, err := writer.db.Collection(collection.Name).InsertMany(context.Background(), documents, options.InsertMany().SetOrdered(true))
+	return err
+}
+
+// SaveRoutine always fails, as MongoDB backups do not have routines.
+func (writer *MongoDatabaseWriter) SaveRoutine(routine entities.Routine) error {
+	if !writer.inTransaction {
+		return services.ErrDatabaseTransactionNotFound
+	}
+
+	return services.ErrRoutineNotSupported
+}
+
+// DropCreatedObjects drops every collection created by the writer, together with its documents and indexes.
+func (writer *MongoDatabaseWriter) DropCreatedObjects() error {
+	if writer.inTransaction {
+		return services.ErrDatabaseTransactionAlreadyStarted
+	}
+
+	for i := len(writer.createdCollections) - 1; i >= 0; i-- {
+		if err := writer.db.Collection(writer.createdCollections[i]).Drop(context.Background()); err != nil {
+			return err
+		}
+	}
+
+	writer.createdCollections = nil
+	return nil
+}
+
+func (writer *MongoDatabaseWriter) KeepCreatedObjects() {
+	writer.createdCollections = nil
+}
+
+// CreateRestoreCheckpoint always fails, as the documents could not be committed along with the checkpoint without transactions.
+func (writer *MongoDatabaseWriter) CreateRestoreCheckpoint() error {
+	return services.ErrRestoreCheckpointNotSupported
+}
+
+// SaveRestoreCheckpoint always fails, as the documents could not be committed along with the checkpoint without transactions.
+func (writer *MongoDatabaseWriter) SaveRestoreCheckpoint(name, value string) error {
+	return services.ErrRestoreCheckpointNotSupported
+}
+
+// GetRestoreCheckpoint always fails, as MongoDB restores are not committed in several transactions.
+func (writer *MongoDatabaseWriter) GetRestoreCheckpoint() (map[string]string, error) {
+	return nil, services.ErrRestoreCheckpointNotSupported
+}
+
+// DropRestoreCheckpoint always fails, as MongoDB restores are not committed in several transactions.
+func (writer *MongoDatabaseWriter) DropRestoreCheckpoint() error {
+	return services.ErrRestoreCheckpointNotSupported
+}
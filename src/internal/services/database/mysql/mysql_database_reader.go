@@ -0,0 +1,840 @@
+package mysql
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"historydb/src/internal/entities"
+	"historydb/src/internal/services"
+	"historydb/src/internal/services/entities/mysql"
+	sql_entities "historydb/src/internal/services/entities/sql"
+	"historydb/src/internal/services/utils"
+	"historydb/src/internal/utils/types"
+	"math"
+	"math/big"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+var (
+	autoIncrementRegexp = regexp.MustCompile(`(?i)\)[^)]*\bAUTO_INCREMENT=(\d+)`)
+	definerRegexp       = regexp.MustCompile(`(?i)\s+DEFINER\s*=\s*\S+`)
+	onUpdateRegexp      = regexp.MustCompile(`(?i)on update \S+`)
+)
+
+// MySQLDatabaseReader queries a MySQL or MariaDB database.
+//
+// While its transaction is in progress, every query runs inside a read-only REPEATABLE READ transaction
+// with a consistent snapshot, so all of them read the same state of the database.
+type MySQLDatabaseReader struct {
+	db        *sql.DB
+	tx        *sql.Tx
+	isMariaDB *bool
+}
+
+func NewMySQLDatabaseReader(db *sql.DB) *MySQLDatabaseReader {
+	return &MySQLDatabaseReader{db: db}
+}
+
+func (reader *MySQLDatabaseReader) BeginTransaction() error {
+	if reader.tx != nil {
+		return services.ErrDatabaseTransactionAlreadyStarted
+	}
+
+	tx, err := reader.db.BeginTx(context.Background(), &sql.TxOptions{Isolation: sql.LevelRepeatableRead, ReadOnly: true})
+	if err != nil {
+		return err
+	}
+
+	reader.tx = tx
+	return nil
+}
+
+func (reader *MySQLDatabaseReader) EndTransaction() error {
+	if reader.tx == nil {
+		return services.ErrDatabaseTransactionNotFound
+	}
+
+	// Nothing is written in a read-only transaction, so it is just discarded
+	err := reader.tx.Rollback()
+	reader.tx = nil
+	return err
+}
+
+func (reader *MySQLDatabaseReader) CheckDBIsEmpty() (bool, error) {
+	var hasObjects bool
+	err := reader.conn().QueryRow(`
+		SELECT EXISTS (SELECT 1 FROM information_schema.tables WHERE table_schema = DATABASE())
+			OR EXISTS (SELECT 1 FROM information_schema.routines WHERE routine_schema = DATABASE())
+			OR EXISTS (SELECT 1 FROM information_schema.events WHERE event_schema = DATABASE())
+	`).Scan(&hasObjects)
+	return !hasObjects, err
+}
+
+func (reader *MySQLDatabaseReader) ListSchemaDependencies() ([]entities.SchemaDependency, error) {
+	rows, err := reader.conn().Query(`
+		SELECT DISTINCT c.table_name
+		FROM information_schema.columns c
+			JOIN information_schema.tables t ON t.table_schema = c.table_schema AND t.table_name = c.table_name
+		WHERE c.table_schema = DATABASE() AND t.table_type = 'BASE TABLE' AND c.extra LIKE '%auto_increment%'
+		ORDER BY c.table_name
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	tableNames := []string{}
+	for rows.Next() {
+		var tableName string
+		if err := rows.Scan(&tableName); err != nil {
+			return nil, err
+		}
+		tableNames = append(tableNames, tableName)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	// The AUTO_INCREMENT value in information_schema.tables can be cached by the server,
+	// so it is taken from the table definition that always holds the current value.
+	autoIncrements := []entities.SchemaDependency{}
+	for _, tableName := range tableNames {
+		var name, createTable string
+		if err := reader.conn().QueryRow(fmt.Sprintf("SHOW CREATE TABLE %s", quoteIdentifier(tableName))).Scan(&name, &createTable); err != nil {
+			return nil, err
+		}
+
+		value := big.NewInt(1)
+		if matches := autoIncrementRegexp.FindStringSubmatch(createTable); matches != nil {
+			value.SetString(matches[1], 10)
+		}
+
+		autoIncrements = append(autoIncrements, &mysql.MySQLAutoIncrement{
+			Name:  tableName,
+			Value: types.BigInt{Int: *value},
+		})
+	}
+
+	return autoIncrements, nil
+}
+
+func (reader *MySQLDatabaseReader) ListSchemaNames() ([]string, error) {
+	rows, err := reader.conn().Query(`
+		SELECT table_name
+		FROM information_schema.tables
+		WHERE table_schema = DATABASE() AND table_type = 'BASE TABLE'
+		ORDER BY table_name
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	schemaNames := []string{}
+	for rows.Next() {
+		var tableName string
+		if err := rows.Scan(&tableName); err != nil {
+			return nil, err
+		}
+
+		schemaNames = append(schemaNames, tableName)
+	}
+
+	return schemaNames, rows.Err()
+}
+
+func (reader *MySQLDatabaseReader) GetSchemaDefinition(schemaName string) (entities.Schema, error) {
+	columns, err := reader.extractColumnsFromTable(schemaName)
+	if err != nil {
+		return nil, err
+	}
+	constraints, err := reader.extractConstraintsFromTable(schemaName)
+	if err != nil {
+		return nil, err
+	}
+	foreignKeys, err := reader.extractForeignKeysFromTable(schemaName)
+	if err != nil {
+		return nil, err
+	}
+	indexes, err := reader.extractIndexesFromTable(schemaName)
+	if err != nil {
+		return nil, err
+	}
+
+	return &sql_entities.SQLTable{
+		Name:        schemaName,
+		Columns:     columns,
+		Constraints: constraints,
+		ForeignKeys: foreignKeys,
+		Indexes:     indexes,
+	}, nil
+}
+
+func (reader *MySQLDatabaseReader) GetSchemaRecordMetadata(schemaName string) (entities.SchemaRecordMetadata, error) {
+	metadata := entities.SchemaRecordMetadata{}
+
+	countQuery := fmt.Sprintf("SELECT COUNT(*) FROM %s", quoteIdentifier(schemaName))
+	if err := reader.conn().QueryRow(countQuery).Scan(&metadata.Count); err != nil {
+		return metadata, err
+	}
+
+	columns, err := reader.extractColumnsFromTable(schemaName)
+	if err != nil {
+		return metadata, err
+	}
+	if len(columns) == 0 {
+		return metadata, nil
+	}
+
+	// MySQL has no function to get the size of a full row, so it is estimated adding up the length of every column
+	columnSizes := make([]string, len(columns))
+	for i, col := range columns {
+		columnSizes[i] = fmt.Sprintf("COALESCE(LENGTH(%s), 0)", quoteIdentifier(col.Name))
+	}
+	sizeQuery := fmt.Sprintf("SELECT COALESCE(MAX(%s), 0) AS max_row_size FROM %s", strings.Join(columnSizes, " + "), quoteIdentifier(schemaName))
+	if err := reader.conn().QueryRow(sizeQuery).Scan(&metadata.MaxRecordSize); err != nil {
+		return metadata, err
+	}
+
+	return metadata, nil
+}
+
+func (reader *MySQLDatabaseReader) GetSchemaRecordChunk(schema entities.Schema, chunkSize int64, chunkCursor interface{}) (entities.SchemaRecordChunk, interface{}, error) {
+	table := schema.(*sql_entities.SQLTable)
+
+	cursor, ok := chunkCursor.(*sql_entities.SQLChunkCursor)
+	if !ok || cursor == nil {
+		cursor = &sql_entities.SQLChunkCursor{}
+	}
+
+	columnNames := make([]string, len(table.Columns))
+	for i, col := range table.Columns {
+		columnNames[i] = col.Name
+	}
+	selectClause := fmt.Sprintf("SELECT %s FROM %s", strings.Join(quoteIdentifiers(columnNames), ", "), quoteIdentifier(table.Name))
+
+	var rows *sql.Rows
+	var err error
+	pKeys, ok := utils.ExtractMySQLPrimaryKey(*table)
+	if !ok {
+		// If table does not have primary keys, it queries the table using offset ordering by all columns so the order is stable
+		positions := make([]string, len(table.Columns))
+		for i := range table.Columns {
+			positions[i] = strconv.Itoa(i + 1)
+		}
+		query := fmt.Sprintf("%s ORDER BY %s LIMIT ? OFFSET ?", selectClause, strings.Join(positions, ", "))
+		rows, err = reader.conn().Query(query, chunkSize, cursor.Offset)
+	} else {
+		// If table has primary keys, it queries the table using the for optimization
+		orderClause := fmt.Sprintf("ORDER BY %s", strings.Join(quoteIdentifiers(pKeys), ", "))
+
+		if cursor.LastPK == nil {
+			// No where clause since it is first chunk
+			query := fmt.Sprintf("%s %s LIMIT ?", selectClause, orderClause)
+			rows, err = reader.conn().Query(query, chunkSize)
+		} else {
+			query := fmt.Sprintf("%s WHERE %s %s LIMIT ?", selectClause, buildPKWhereClause(pKeys), orderClause)
+			args := append(types.ToInterfaceSlice(cursor.LastPK), chunkSize)
+			rows, err = reader.conn().Query(query, args...)
+		}
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	defer rows.Close()
+
+	columnTypes, err := rows.ColumnTypes()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	numCols := len(table.Columns)
+	var lastPKey []interface{} = nil
+	if pKeys != nil {
+		lastPKey = make([]interface{}, len(pKeys))
+	}
+
+	results := make([]sql_entities.SQLRecord, 0, chunkSize)
+	for rows.Next() {
+		values := make([]interface{}, numCols)
+		valuesPtrs := make([]interface{}, numCols)
+		for i := range values {
+			valuesPtrs[i] = &values[i]
+		}
+
+		if err := rows.Scan(valuesPtrs...); err != nil {
+			return nil, nil, err
+		}
+
+		// Save data in SQLRecord
+		row := make(map[string]interface{})
+		for i, col := range table.Columns {
+			val, err := normalizeValue(values[i], columnTypes[i].DatabaseTypeName())
+			if err != nil {
+				return nil, nil, err
+			}
+			row[col.Name] = val
+		}
+
+		// Update lastPK
+		for i, key := range pKeys {
+			lastPKey[i] = row[key]
+		}
+
+		results = append(results, sql_entities.SQLRecord{Content: row})
+	}
+	if err := rows.Err(); err != nil {
+		return nil, nil, err
+	}
+
+	cursor.Offset += int(chunkSize)
+	cursor.LastPK = lastPKey
+	return &sql_entities.SQLRecordChunk{
+		PrimaryKey: table.PrimaryKeyColumns(),
+		Content:    results,
+	}, cursor, nil
+}
+
+func (reader *MySQLDatabaseReader) EncodeChunkCursor(chunkCursor interface{}) []byte {
+	cursor, ok := chunkCursor.(*sql_entities.SQLChunkCursor)
+	if !ok || cursor == nil {
+		// A nil cursor is read from the first record
+		return nil
+	}
+	return cursor.EncodeToBytes()
+}
+
+func (reader *MySQLDatabaseReader) DecodeChunkCursor(data []byte) (interface{}, error) {
+	if len(data) == 0 {
+		return nil, nil
+	}
+	var cursor sql_entities.SQLChunkCursor
+	if err := cursor.DecodeFromBytes(data); err != nil {
+		return nil, err
+	}
+	return &cursor, nil
+}
+
+func (reader *MySQLDatabaseReader) ListRoutines() ([]entities.Routine, error) {
+	routines := []entities.Routine{}
+
+	routineRows, err := reader.conn().Query(`
+		SELECT routine_name, routine_type
+		FROM information_schema.routines
+		WHERE routine_schema = DATABASE()
+		ORDER BY routine_type, routine_name
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer routineRows.Close()
+
+	type routineRef struct {
+		name        string
+		routineType string
+	}
+	routineRefs := []routineRef{}
+	for routineRows.Next() {
+		var ref routineRef
+		if err := routineRows.Scan(&ref.name, &ref.routineType); err != nil {
+			return nil, err
+		}
+		routineRefs = append(routineRefs, ref)
+	}
+	if err := routineRows.Err(); err != nil {
+		return nil, err
+	}
+
+	for _, ref := range routineRefs {
+		if ref.routineType == "FUNCTION" {
+			definition, err := reader.showCreate("FUNCTION", ref.name, "Create Function")
+			if err != nil {
+				return nil, err
+			}
+			routines = append(routines, &mysql.MySQLFunction{Name: ref.name, Definition: definition})
+		} else {
+			definition, err := reader.showCreate("PROCEDURE", ref.name, "Create Procedure")
+			if err != nil {
+				return nil, err
+			}
+			routines = append(routines, &mysql.MySQLProcedure{Name: ref.name, Definition: definition})
+		}
+	}
+
+	triggerNames, err := reader.listNames(`
+		SELECT trigger_name
+		FROM information_schema.triggers
+		WHERE trigger_schema = DATABASE()
+		ORDER BY event_object_table, action_timing, event_manipulation, action_order
+	`)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, triggerName := range triggerNames {
+		definition, err := reader.showCreate("TRIGGER", triggerName, "SQL Original Statement")
+		if err != nil {
+			return nil, err
+		}
+		routines = append(routines, &mysql.MySQLTrigger{Name: triggerName, Definition: definition})
+	}
+
+	eventNames, err := reader.listNames(`
+		SELECT event_name
+		FROM information_schema.events
+		WHERE event_schema = DATABASE()
+		ORDER BY event_name
+	`)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, eventName := range eventNames {
+		definition, err := reader.showCreate("EVENT", eventName, "Create Event")
+		if err != nil {
+			return nil, err
+		}
+		routines = append(routines, &mysql.MySQLEvent{Name: eventName, Definition: definition})
+	}
+
+	return routines, nil
+}
+
+// This function is a private MySQL function that runs a query returning a single column of names.
+func (reader *MySQLDatabaseReader) listNames(query string) ([]string, error) {
+	rows, err := reader.conn().Query(query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	names := []string{}
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, err
+		}
+		names = append(names, name)
+	}
+
+	return names, rows.Err()
+}
+
+// This function is a private MySQL function that retrieves the CREATE statement of a database object without its DEFINER.
+// The columns returned by SHOW CREATE differ between MySQL and MariaDB versions, so the statement is looked up by column name.
+func (reader *MySQLDatabaseReader) showCreate(objectType, objectName, column string) (string, error) {
+	rows, err := reader.conn().Query(fmt.Sprintf("SHOW CREATE %s %s", objectType, quoteIdentifier(objectName)))
+	if err != nil {
+		return "", err
+	}
+	defer rows.Close()
+
+	columns, err := rows.Columns()
+	if err != nil {
+		return "", err
+	}
+	if !rows.Next() {
+		if err := rows.Err(); err != nil {
+			return "", err
+		}
+		return "", fmt.Errorf("%s %s not found", strings.ToLower(objectType), objectName)
+	}
+
+	values := make([]sql.NullString, len(columns))
+	valuesPtrs := make([]interface{}, len(columns))
+	for i := range values {
+		valuesPtrs[i] = &values[i]
+	}
+	if err := rows.Scan(valuesPtrs...); err != nil {
+		return "", err
+	}
+
+	for i, name := range columns {
+		if strings.EqualFold(name, column) {
+			if !values[i].Valid {
+				return "", fmt.Errorf("not enough privileges to read the definition of %s %s", strings.ToLower(objectType), objectName)
+			}
+			return definerRegexp.ReplaceAllString(values[i].String, ""), nil
+		}
+	}
+
+	return "", fmt.Errorf("definition of %s %s not found", strings.ToLower(objectType), objectName)
+}
+
+// This function is a private MySQL function that checks if the server is a MariaDB server.
+// MariaDB and MySQL report the column default values differently, so the reader needs to know it.
+func (reader *MySQLDatabaseReader) checkIsMariaDB() (bool, error) {
+	if reader.isMariaDB == nil {
+		var version string
+		if err := reader.conn().QueryRow("SELECT VERSION()").Scan(&version); err != nil {
+			return false, err
+		}
+		isMariaDB := strings.Contains(strings.ToLower(version), "mariadb")
+		reader.isMariaDB = &isMariaDB
+	}
+	return *reader.isMariaDB, nil
+}
+
+// This function is a private MySQL function that extracts the column definitions from a table into the database.
+func (reader *MySQLDatabaseReader) extractColumnsFromTable(tableName string) ([]sql_entities.SQLTableColumn, error) {
+	isMariaDB, err := reader.checkIsMariaDB()
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := reader.conn().Query(`
+		SELECT column_name, column_type, data_type, CASE is_nullable WHEN 'YES' THEN TRUE ELSE FALSE END AS is_nullable, column_default, extra, generation_expression, ordinal_position
+		FROM information_schema.columns
+		WHERE table_schema = DATABASE() AND table_name = ?
+		ORDER BY ordinal_position
+	`, tableName)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	columns := []sql_entities.SQLTableColumn{}
+	for rows.Next() {
+		var columnName, columnType, dataType, extra string
+		var columnDefault, generationExpression *string
+		var isNullable bool
+		var ordinalPosition int64
+		if err := rows.Scan(&columnName, &columnType, &dataType, &isNullable, &columnDefault, &extra, &generationExpression, &ordinalPosition); err != nil {
+			return nil, err
+		}
+
+		lowerExtra := strings.ToLower(extra)
+		if strings.Contains(lowerExtra, "generated") && !strings.Contains(lowerExtra, "default_generated") && generationExpression != nil {
+			storage := "VIRTUAL"
+			if strings.Contains(lowerExtra, "stored") || strings.Contains(lowerExtra, "persistent") {
+				storage = "STORED"
+			}
+			expression := *generationExpression
+			if !isMariaDB {
+				expression = unescapeExpression(expression)
+			}
+			columnType = fmt.Sprintf("%s GENERATED ALWAYS AS (%s) %s", columnType, expression, storage)
+			columnDefault = nil
+		}
+		if strings.Contains(lowerExtra, "auto_increment") {
+			columnType += " AUTO_INCREMENT"
+		}
+		if onUpdate := onUpdateRegexp.FindString(extra); onUpdate != "" {
+			columnType += " " + strings.ToUpper(onUpdate)
+		}
+
+		columns = append(columns, sql_entities.SQLTableColumn{
+			Name:         columnName,
+			Type:         columnType,
+			IsNullable:   isNullable,
+			DefaultValue: formatColumnDefault(columnDefault, dataType, lowerExtra, isMariaDB),
+			Position:     ordinalPosition,
+		})
+	}
+
+	return columns, rows.Err()
+}
+
+// This function is a private MySQL function that extracts the constraint definitions from a table into the database.
+func (reader *MySQLDatabaseReader) extractConstraintsFromTable(tableName string) ([]sql_entities.SQLTableConstraint, error) {
+	rows, err := reader.conn().Query(`
+		SELECT tc.constraint_name, tc.constraint_type, kcu.column_name
+		FROM information_schema.table_constraints tc
+			JOIN information_schema.key_column_usage kcu ON kcu.constraint_schema = tc.constraint_schema AND kcu.table_name = tc.table_name AND kcu.constraint_name = tc.constraint_name
+		WHERE tc.table_schema = DATABASE() AND tc.table_name = ? AND tc.constraint_type IN ('PRIMARY KEY', 'UNIQUE')
+		ORDER BY tc.constraint_name ASC, kcu.ordinal_position ASC
+	`, tableName)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	constraintMap := make(map[string]*sql_entities.SQLTableConstraint)
+	for rows.Next() {
+		var constraintName, constraintType, columnName string
+		if err := rows.Scan(&constraintName, &constraintType, &columnName); err != nil {
+			return nil, err
+		}
+
+		tc, exists := constraintMap[constraintName]
+		if !exists {
+			constraintMap[constraintName] = &sql_entities.SQLTableConstraint{
+				Type:    sql_entities.ConstraintType(constraintType),
+				Name:    constraintName,
+				Columns: []string{columnName},
+			}
+		} else {
+			tc.Columns = append(tc.Columns, columnName)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	isMariaDB, err := reader.checkIsMariaDB()
+	if err != nil {
+		return nil, err
+	}
+
+	// MariaDB check constraint names are unique by table instead of by database, so it needs to filter by table too
+	checkQuery := `
+		SELECT cc.constraint_name, cc.check_clause
+		FROM information_schema.table_constraints tc
+			JOIN information_schema.check_constraints cc ON cc.constraint_schema = tc.constraint_schema AND cc.constraint_name = tc.constraint_name
+		WHERE tc.table_schema = DATABASE() AND tc.table_name = ? AND tc.constraint_type = 'CHECK'
+		ORDER BY cc.constraint_name ASC
+	`
+	if isMariaDB {
+		checkQuery = strings.Replace(checkQuery, "cc.constraint_name = tc.constraint_name", "cc.constraint_name = tc.constraint_name AND cc.table_name = tc.table_name", 1)
+	}
+
+	checkRows, err := reader.conn().Query(checkQuery, tableName)
+	if err != nil {
+		return nil, err
+	}
+	defer checkRows.Close()
+
+	for checkRows.Next() {
+		var constraintName, checkClause string
+		if err := checkRows.Scan(&constraintName, &checkClause); err != nil {
+			return nil, err
+		}
+
+		if !isMariaDB {
+			checkClause = unescapeExpression(checkClause)
+		}
+
+		constraintMap[constraintName] = &sql_entities.SQLTableConstraint{
+			Type:       sql_entities.Check,
+			Name:       constraintName,
+			Definition: &checkClause,
+		}
+	}
+	if err := checkRows.Err(); err != nil {
+		return nil, err
+	}
+
+	keys := make([]string, 0, len(constraintMap))
+	for k := range constraintMap {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	constraints := make([]sql_entities.SQLTableConstraint, 0, len(constraintMap))
+	for _, k := range keys {
+		constraints = append(constraints, *constraintMap[k])
+	}
+
+	return constraints, nil
+}
+
+// This function is a private MySQL function that extracts the foreign keys definitions from a table into the database.
+func (reader *MySQLDatabaseReader) extractForeignKeysFromTable(tableName string) ([]sql_entities.SQLTableForeignKey, error) {
+	rows, err := reader.conn().Query(`
+		SELECT kcu.constraint_name, kcu.column_name, kcu.referenced_table_name, kcu.referenced_column_name, rc.update_rule, rc.delete_rule
+		FROM information_schema.key_column_usage kcu
+			JOIN information_schema.referential_constraints rc ON rc.constraint_schema = kcu.constraint_schema AND rc.constraint_name = kcu.constraint_name AND rc.table_name = kcu.table_name
+		WHERE kcu.table_schema = DATABASE() AND kcu.table_name = ? AND kcu.referenced_table_name IS NOT NULL
+		ORDER BY kcu.constraint_name ASC, kcu.ordinal_position ASC
+	`, tableName)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	constraintMap := make(map[string]*sql_entities.SQLTableForeignKey)
+	for rows.Next() {
+		var constraintName, columnName, referencedTable, referencedColumn, updateRule, deleteRule string
+		if err := rows.Scan(&constraintName, &columnName, &referencedTable, &referencedColumn, &updateRule, &deleteRule); err != nil {
+			return nil, err
+		}
+
+		fk, exists := constraintMap[constraintName]
+		if !exists {
+			constraintMap[constraintName] = &sql_entities.SQLTableForeignKey{
+				Name:              constraintName,
+				Columns:           []string{columnName},
+				ReferencedTable:   referencedTable,
+				ReferencedColumns: []string{referencedColumn},
+				UpdateAction:      sql_entities.ActionType(updateRule),
+				DeleteAction:      sql_entities.ActionType(deleteRule),
+			}
+		} else {
+			fk.Columns = append(fk.Columns, columnName)
+			fk.ReferencedColumns = append(fk.ReferencedColumns, referencedColumn)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	keys := make([]string, 0, len(constraintMap))
+	for k := range constraintMap {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	foreignKeys := make([]sql_entities.SQLTableForeignKey, 0, len(constraintMap))
+	for _, k := range keys {
+		foreignKeys = append(foreignKeys, *constraintMap[k])
+	}
+
+	return foreignKeys, nil
+}
+
+// This function is a private MySQL function that extracts the index definitions from a table into the database.
+// Indexes backing a primary key or unique constraint are skipped as they are created along with the constraint.
+// Prefix indexes keep the prefix length in the column name as <column>(<length>).
+func (reader *MySQLDatabaseReader) extractIndexesFromTable(tableName string) ([]sql_entities.SQLTableIndex, error) {
+	rows, err := reader.conn().Query(`
+		SELECT s.index_name, s.index_type, s.non_unique, s.column_name, s.sub_part
+		FROM information_schema.statistics s
+		WHERE s.table_schema = DATABASE() AND s.table_name = ? AND NOT EXISTS (
+			SELECT 1
+			FROM information_schema.table_constraints tc
+			WHERE tc.table_schema = s.table_schema AND tc.table_name = s.table_name AND tc.constraint_name = s.index_name AND tc.constraint_type IN ('PRIMARY KEY', 'UNIQUE')
+		)
+		ORDER BY s.index_name ASC, s.seq_in_index ASC
+	`, tableName)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	indexes := []sql_entities.SQLTableIndex{}
+	indexPositions := make(map[string]int)
+	for rows.Next() {
+		var indexName, indexType string
+		var nonUnique int64
+		var columnName sql.NullString
+		var subPart sql.NullInt64
+		if err := rows.Scan(&indexName, &indexType, &nonUnique, &columnName, &subPart); err != nil {
+			return nil, err
+		}
+
+		if !columnName.Valid {
+			return nil, fmt.Errorf("functional index %s on table %s is not supported", indexName, tableName)
+		}
+
+		column := columnName.String
+		if subPart.Valid {
+			column = fmt.Sprintf("%s(%d)", column, subPart.Int64)
+		}
+
+		if position, exists := indexPositions[indexName]; exists {
+			indexes[position].Columns = append(indexes[position].Columns, column)
+			continue
+		}
+
+		indexPositions[indexName] = len(indexes)
+		indexes = append(indexes, sql_entities.SQLTableIndex{
+			Name:    indexName,
+			Type:    indexType,
+			Columns: []string{column},
+			Options: map[string]interface{}{"isUnique": nonUnique == 0},
+		})
+	}
+
+	return indexes, rows.Err()
+}
+
+// buildPKWhereClause creates a where clause when batching data queries using the primary key of the table.
+func buildPKWhereClause(pKeys []string) string {
+	if len(pKeys) == 1 {
+		return fmt.Sprintf("%s > ?", quoteIdentifier(pKeys[0]))
+	}
+
+	placeholders := make([]string, len(pKeys))
+	for i := range pKeys {
+		placeholders[i] = "?"
+	}
+
+	return fmt.Sprintf("(%s) > (%s)", strings.Join(quoteIdentifiers(pKeys), ", "), strings.Join(placeholders, ", "))
+}
+
+// formatColumnDefault transforms the column default from information_schema into a valid DEFAULT expression.
+// MariaDB already returns it as an SQL expression, while MySQL returns literals unquoted and
+// flags expression defaults with DEFAULT_GENERATED.
+func formatColumnDefault(columnDefault *string, dataType, extra string, isMariaDB bool) *string {
+	if columnDefault == nil {
+		return nil
+	}
+
+	value := *columnDefault
+	if isMariaDB {
+		if strings.EqualFold(value, "NULL") {
+			return nil
+		}
+		return &value
+	}
+
+	if strings.Contains(extra, "default_generated") {
+		if strings.HasPrefix(strings.ToUpper(value), "CURRENT_TIMESTAMP") {
+			return &value
+		}
+		value = fmt.Sprintf("(%s)", unescapeExpression(value))
+		return &value
+	}
+
+	switch dataType {
+	case "tinyint", "smallint", "mediumint", "int", "integer", "bigint", "decimal", "numeric", "float", "double", "year", "bit":
+		return &value
+	}
+
+	value = fmt.Sprintf("'%s'", strings.ReplaceAll(strings.ReplaceAll(value, `\`, `\\`), "'", "''"))
+	return &value
+}
+
+// unescapeExpression removes the escaping MySQL adds to the quotes of the expressions stored in information_schema.
+func unescapeExpression(expression string) string {
+	return strings.ReplaceAll(expression, `\'`, "'")
+}
+
+// normalizeValue converts a value read from MySQL into a type supported by the record encoding.
+// Integers and floating point values are kept as numbers, and every other value is kept as its exact
+// text or binary representation so decimals, dates and blobs are stored without losing precision.
+func normalizeValue(raw interface{}, databaseType string) (interface{}, error) {
+	if raw == nil {
+		return nil, nil
+	}
+
+	databaseType = strings.TrimPrefix(databaseType, "UNSIGNED ")
+	switch v := raw.(type) {
+	case int64:
+		return v, nil
+	case uint64:
+		if v > math.MaxInt64 {
+			return strconv.FormatUint(v, 10), nil
+		}
+		return int64(v), nil
+	case float32:
+		return strconv.ParseFloat(strconv.FormatFloat(float64(v), 'g', -1, 32), 64)
+	case float64:
+		return v, nil
+	case []byte:
+		switch databaseType {
+		case "TINYINT", "SMALLINT", "MEDIUMINT", "INT", "BIGINT", "YEAR":
+			if i, err := strconv.ParseInt(string(v), 10, 64); err == nil {
+				return i, nil
+			}
+			return string(v), nil
+		case "FLOAT", "DOUBLE":
+			return strconv.ParseFloat(string(v), 64)
+		default:
+			return string(v), nil
+		}
+	default:
+		return v, nil
+	}
+}
+
+// This function is a private MySQL function that returns the transaction in progress, or the DB if there is none.
+func (reader *MySQLDatabaseReader) conn() utils.SQLQuerier {
+	if reader.tx != nil {
+		return reader.tx
+	}
+	return reader.db
+}
@@ -0,0 +1,410 @@
+package mysql
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"historydb/src/internal/entities"
+	"historydb/src/internal/services"
+	database_services "historydb/src/internal/services/database"
+	"historydb/src/internal/services/entities/mysql"
+	sql_entities "historydb/src/internal/services/entities/sql"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// MySQL limits the number of placeholders in a single prepared statement.
+const maxStatementPlaceholders = 65535
+
+var indexPrefixRegexp = regexp.MustCompile(`^(.*)\((\d+)\)$`)
+
+// MySQLDatabaseWriter inserts the backup data into a MySQL database.
+//
+// MySQL commits every DDL statement implicitly, so the transaction can only discard the pending records.
+// To leave the database clean on a rollback, the writer keeps track of the objects it creates and drops them.
+// The objects are tracked across transactions, as a restore can be committed in several of them.
+type MySQLDatabaseWriter struct {
+	db *sql.DB
+	tx *sql.Tx
+
+	autoIncrements  map[string]*mysql.MySQLAutoIncrement
+	createdTables   []string
+	createdRoutines []entities.Routine
+}
+
+func NewMySQLDatabaseWriter(db *sql.DB) *MySQLDatabaseWriter {
+	return &MySQLDatabaseWriter{db: db, autoIncrements: make(map[string]*mysql.MySQLAutoIncrement)}
+}
+
+func (writer *MySQLDatabaseWriter) BeginTransaction() error {
+	if writer.tx != nil {
+		return services.ErrDatabaseTransactionAlreadyStarted
+	}
+	var err error
+	writer.tx, err = writer.db.Begin()
+	return err
+}
+
+func (writer *MySQLDatabaseWriter) CommitTransaction() error {
+	if writer.tx == nil {
+		return services.ErrDatabaseTransactionNotFound
+	}
+
+	err := writer.tx.Commit()
+	if err != nil {
+		return err
+	}
+
+	writer.tx = nil
+	return nil
+}
+
+func (writer *MySQLDatabaseWriter) RollbackTransaction() error {
+	if writer.tx == nil {
+		return services.ErrDatabaseTransactionNotFound
+	}
+
+	err := writer.tx.Rollback()
+	if err != nil {
+		return err
+	}
+
+	writer.tx = nil
+	return writer.DropCreatedObjects()
+}
+
+// SaveSchemaDependency saves the AUTO_INCREMENT counter until its table is created,
+// as in MySQL the counter can not exist without the table.
+func (writer *MySQLDatabaseWriter) SaveSchemaDependency(dependency entities.SchemaDependency) error {
+	if writer.tx == nil {
+		return services.ErrDatabaseTransactionNotFound
+	}
+
+	autoIncrement, ok := dependency.(*mysql.MySQLAutoIncrement)
+	if !ok {
+		return services.ErrDependencyNotSupported
+	}
+
+	writer.autoIncrements[autoIncrement.Name] = autoIncrement
+	return nil
+}
+
+func (writer *MySQLDatabaseWriter) SaveSchema(schema entities.Schema) error {
+	if writer.tx == nil {
+		return services.ErrDatabaseTransactionNotFound
+	}
+
+	table := schema.(*sql_entities.SQLTable)
+
+	definitions := []string{}
+	for _, col := range table.Columns {
+		definition := fmt.Sprintf("%s %s", quoteIdentifier(col.Name), col.Type)
+		if !col.IsNullable {
+			definition += " NOT NULL"
+		}
+		if col.DefaultValue != nil {
+			definition += fmt.Sprintf(" DEFAULT %s", *col.DefaultValue)
+		}
+		definitions = append(definitions, definition)
+	}
+	for _, c := range table.Constraints {
+		switch c.Type {
+		case sql_entities.PrimaryKey:
+			definitions = append(definitions, fmt.Sprintf("PRIMARY KEY (%s)", strings.Join(quoteIdentifiers(c.Columns), ", ")))
+		case sql_entities.Check:
+			definitions = append(definitions, fmt.Sprintf("CONSTRAINT %s CHECK (%s)", quoteIdentifier(c.Name), *c.Definition))
+		default:
+			definitions = append(definitions, fmt.Sprintf("CONSTRAINT %s %s (%s)", quoteIdentifier(c.Name), c.Type, strings.Join(quoteIdentifiers(c.Columns), ", ")))
+		}
+	}
+
+	query := fmt.Sprintf("CREATE TABLE %s (%s)", quoteIdentifier(table.Name), strings.Join(definitions, ", "))
+	if _, err := writer.tx.Exec(query); err != nil {
+		return err
+	}
+
+	writer.createdTables = append(writer.createdTables, table.Name)
+	return nil
+}
+
+func (writer *MySQLDatabaseWriter) SaveSchemaRules(schema entities.Schema) error {
+	if writer.tx == nil {
+		return services.ErrDatabaseTransactionNotFound
+	}
+
+	table := schema.(*sql_entities.SQLTable)
+
+	for _, idx := range table.Indexes {
+		columns := make([]string, len(idx.Columns))
+		for i, column := range idx.Columns {
+			if matches := indexPrefixRegexp.FindStringSubmatch(column); matches != nil {
+				columns[i] = fmt.Sprintf("%s(%s)", quoteIdentifier(matches[1]), matches[2])
+			} else {
+				columns[i] = quoteIdentifier(column)
+			}
+		}
+
+		var kind, using string
+		switch idx.Type {
+		case "FULLTEXT", "SPATIAL":
+			kind = idx.Type + " "
+		default:
+			if isUnique, ok := idx.Options["isUnique"].(bool); ok && isUnique {
+				kind = "UNIQUE "
+			}
+			if idx.Type == "HASH" || idx.Type == "BTREE" {
+				using = " USING " + idx.Type
+			}
+		}
+
+		query := fmt.Sprintf(
+			"CREATE %sINDEX %s ON %s (%s)%s",
+			kind,
+			quoteIdentifier(idx.Name),
+			quoteIdentifier(table.Name),
+			strings.Join(columns, ", "),
+			using,
+		)
+		if _, err := writer.tx.Exec(query); err != nil {
+			return err
+		}
+	}
+
+	if autoIncrement, ok := writer.autoIncrements[table.Name]; ok {
+		query := fmt.Sprintf("ALTER TABLE %s AUTO_INCREMENT = %s", quoteIdentifier(table.Name), autoIncrement.Value.String())
+		if _, err := writer.tx.Exec(query); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// SaveSchemaConstraints adds the foreign keys checking the records, as MySQL can not validate them later.
+// It is called once the indexes are created so MySQL uses them instead of creating new ones for the foreign keys.
+func (writer *MySQLDatabaseWriter) SaveSchemaConstraints(schema entities.Schema) error {
+	if writer.tx == nil {
+		return services.ErrDatabaseTransactionNotFound
+	}
+
+	table := schema.(*sql_entities.SQLTable)
+
+	for _, fk := range table.ForeignKeys {
+		query := fmt.Sprintf(
+			"ALTER TABLE %s ADD CONSTRAINT %s FOREIGN KEY (%s) REFERENCES %s (%s) ON UPDATE %s ON DELETE %s",
+			quoteIdentifier(table.Name),
+			quoteIdentifier(fk.Name),
+			strings.Join(quoteIdentifiers(fk.Columns), ", "),
+			quoteIdentifier(fk.ReferencedTable),
+			strings.Join(quoteIdentifiers(fk.ReferencedColumns), ", "),
+			fk.UpdateAction,
+			fk.DeleteAction,
+		)
+		if _, err := writer.tx.Exec(query); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// ValidateSchemaConstraints does nothing, as the records are checked when the foreign keys are added.
+func (writer *MySQLDatabaseWriter) ValidateSchemaConstraints(schema entities.Schema) error {
+	if writer.tx == nil {
+		return services.ErrDatabaseTransactionNotFound
+	}
+
+	return nil
+}
+
+func (writer *MySQLDatabaseWriter) SaveSchemaRecords(schema entities.Schema, chunk entities.SchemaRecordChunk) error {
+	if writer.tx == nil {
+		return services.ErrDatabaseTransactionNotFound
+	}
+
+	table := schema.(*sql_entities.SQLTable)
+	recordChunk := chunk.(*sql_entities.SQLRecordChunk)
+	if len(recordChunk.Content) == 0 {
+		return nil
+	}
+
+	// Generated columns are calculated by MySQL, so they can not be inserted
+	columns := []string{}
+	for _, col := range table.Columns {
+		if !strings.Contains(col.Type, " GENERATED ALWAYS AS ") {
+			columns = append(columns, col.Name)
+		}
+	}
+	if len(columns) == 0 {
+		return nil
+	}
+
+	rowPlaceholders := fmt.Sprintf("(%s)", strings.TrimSuffix(strings.Repeat("?, ", len(columns)), ", "))
+	batchSize := maxStatementPlaceholders / len(columns)
+
+	for start := 0; start < len(recordChunk.Content); start += batchSize {
+		end := min(start+batchSize, len(recordChunk.Content))
+
+		placeholders := make([]string, 0, end-start)
+		args := make([]interface{}, 0, (end-start)*len(columns))
+		for _, record := range recordChunk.Content[start:end] {
+			placeholders = append(placeholders, rowPlaceholders)
+			for _, col := range columns {
+				val := record.Content[col]
+				if t, ok := val.(time.Time); ok {
+					val = t.Format(time.DateTime)
+				}
+				args = append(args, val)
+			}
+		}
+
+		query := fmt.Sprintf("INSERT INTO %s (%s) VALUES %s", quoteIdentifier(table.Name), strings.Join(quoteIdentifiers(columns), ", "), strings.Join(placeholders, ", "))
+		if _, err := writer.tx.Exec(query, args...); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (writer *MySQLDatabaseWriter) SaveRoutine(routine entities.Routine) error {
+	if writer.tx == nil {
+		return services.ErrDatabaseTransactionNotFound
+	}
+
+	var query string
+	switch r := routine.(type) {
+	case *mysql.MySQLFunction:
+		query = r.Definition
+	case *mysql.MySQLProcedure:
+		query = r.Definition
+	case *mysql.MySQLTrigger:
+		query = r.Definition
+	case *mysql.MySQLEvent:
+		query = r.Definition
+	default:
+		return services.ErrBackupCorruptedFile
+	}
+
+	if _, err := writer.tx.Exec(query); err != nil {
+		return err
+	}
+
+	writer.createdRoutines = append(writer.createdRoutines, routine)
+	return nil
+}
+
+// DropCreatedObjects drops every object created by the writer.
+// It uses a single connection so disabling the foreign key checks applies to every DROP statement.
+func (writer *MySQLDatabaseWriter) DropCreatedObjects() error {
+	if writer.tx != nil {
+		return services.ErrDatabaseTransactionAlreadyStarted
+	}
+
+	ctx := context.Background()
+	conn, err := writer.db.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	for i := len(writer.createdRoutines) - 1; i >= 0; i-- {
+		var objectType string
+		switch writer.createdRoutines[i].GetRoutineType() {
+		case entities.MySQLFunction:
+			objectType = "FUNCTION"
+		case entities.MySQLProcedure:
+			objectType = "PROCEDURE"
+		case entities.MySQLTrigger:
+			objectType = "TRIGGER"
+		case entities.MySQLEvent:
+			objectType = "EVENT"
+		}
+
+		query := fmt.Sprintf("DROP %s IF EXISTS %s", objectType, quoteIdentifier(writer.createdRoutines[i].GetName()))
+		if _, err := conn.ExecContext(ctx, query); err != nil {
+			return err
+		}
+	}
+
+	if _, err := conn.ExecContext(ctx, "SET FOREIGN_KEY_CHECKS = 0"); err != nil {
+		return err
+	}
+	defer conn.ExecContext(ctx, "SET FOREIGN_KEY_CHECKS = 1")
+
+	for i := len(writer.createdTables) - 1; i >= 0; i-- {
+		if _, err := conn.ExecContext(ctx, fmt.Sprintf("DROP TABLE IF EXISTS %s", quoteIdentifier(writer.createdTables[i]))); err != nil {
+			return err
+		}
+	}
+
+	writer.createdRoutines = nil
+	writer.createdTables = nil
+	return nil
+}
+
+func (writer *MySQLDatabaseWriter) KeepCreatedObjects() {
+	writer.createdRoutines = nil
+	writer.createdTables = nil
+}
+
+// CreateRestoreCheckpoint creates the checkpoint table, which is committed implicitly as every DDL statement. It is kept
+// track of as the restored tables, so it is dropped if the restore fails before its first step is committed.
+func (writer *MySQLDatabaseWriter) CreateRestoreCheckpoint() error {
+	if writer.tx == nil {
+		return services.ErrDatabaseTransactionNotFound
+	}
+
+	if _, err := writer.tx.Exec(fmt.Sprintf("CREATE TABLE %s (name VARCHAR(255) PRIMARY KEY, value TEXT NOT NULL)", quoteIdentifier(database_services.RestoreCheckpointTable))); err != nil {
+		return err
+	}
+
+	writer.createdTables = append(writer.createdTables, database_services.RestoreCheckpointTable)
+	return nil
+}
+
+func (writer *MySQLDatabaseWriter) SaveRestoreCheckpoint(name, value string) error {
+	if writer.tx == nil {
+		return services.ErrDatabaseTransactionNotFound
+	}
+
+	query := fmt.Sprintf("INSERT INTO %s (name, value) VALUES (?, ?) ON DUPLICATE KEY UPDATE value = VALUES(value)", quoteIdentifier(database_services.RestoreCheckpointTable))
+	_, err := writer.tx.Exec(query, name, value)
+	return err
+}
+
+func (writer *MySQLDatabaseWriter) GetRestoreCheckpoint() (map[string]string, error) {
+	var exists bool
+	if err := writer.db.QueryRow("SELECT COUNT(*) > 0 FROM information_schema.tables WHERE table_schema = DATABASE() AND table_name = ?", database_services.RestoreCheckpointTable).Scan(&exists); err != nil {
+		return nil, err
+	}
+	if !exists {
+		return nil, nil
+	}
+
+	rows, err := writer.db.Query(fmt.Sprintf("SELECT name, value FROM %s", quoteIdentifier(database_services.RestoreCheckpointTable)))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	entries := make(map[string]string)
+	for rows.Next() {
+		var name, value string
+		if err := rows.Scan(&name, &value); err != nil {
+			return nil, err
+		}
+		entries[name] = value
+	}
+	return entries, rows.Err()
+}
+
+func (writer *MySQLDatabaseWriter) DropRestoreCheckpoint() error {
+	if writer.tx == nil {
+		return services.ErrDatabaseTransactionNotFound
+	}
+
+	_, err := writer.tx.Exec(fmt.Sprintf("DROP TABLE %s", quoteIdentifier(database_services.RestoreCheckpointTable)))
+	return err
+}
@@ -0,0 +1,676 @@
+package psql
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"historydb/src/internal/services"
+	sql_entities "historydb/src/internal/services/entities/sql"
+	"historydb/src/internal/services/utils"
+	"historydb/src/internal/utils/types"
+	"io"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/jackc/pgx/v5/stdlib"
+	"github.com/lib/pq"
+)
+
+// copyBinaryTypes maps the column types that are transferred in the COPY binary format to their type OID.
+// Columns of any other type (user defined types, geometric types...) are transferred as their text representation,
+// as their binary format depends on the server version or on the type definition.
+var copyBinaryTypes = map[string]uint32{
+	"smallint":                    pgtype.Int2OID,
+	"integer":                     pgtype.Int4OID,
+	"bigint":                      pgtype.Int8OID,
+	"real":                        pgtype.Float4OID,
+	"double precision":            pgtype.Float8OID,
+	"numeric":                     pgtype.NumericOID,
+	"boolean":                     pgtype.BoolOID,
+	"text":                        pgtype.TextOID,
+	"character varying":           pgtype.VarcharOID,
+	"character":                   pgtype.BPCharOID,
+	"bytea":                       pgtype.ByteaOID,
+	"date":                        pgtype.DateOID,
+	"timestamp without time zone": pgtype.TimestampOID,
+	"timestamp with time zone":    pgtype.TimestamptzOID,
+	"interval":                    pgtype.IntervalOID,
+	"uuid":                        pgtype.UUIDOID,
+	"json":                        pgtype.JSONOID,
+	"jsonb":                       pgtype.JSONBOID,
+	"int4range":                   pgtype.Int4rangeOID,
+	"int8range":                   pgtype.Int8rangeOID,
+	"numrange":                    pgtype.NumrangeOID,
+	"tsrange":                     pgtype.TsrangeOID,
+	"tstzrange":                   pgtype.TstzrangeOID,
+	"daterange":                   pgtype.DaterangeOID,
+}
+
+// copyRangeSubtypes maps the range types to the type OID of their bounds.
+var copyRangeSubtypes = map[uint32]uint32{
+	pgtype.Int4rangeOID: pgtype.Int4OID,
+	pgtype.Int8rangeOID: pgtype.Int8OID,
+	pgtype.NumrangeOID:  pgtype.NumericOID,
+	pgtype.TsrangeOID:   pgtype.TimestampOID,
+	pgtype.TstzrangeOID: pgtype.TimestamptzOID,
+	pgtype.DaterangeOID: pgtype.DateOID,
+}
+
+// Flags of the range binary format.
+const (
+	rangeEmpty          = 0x01
+	rangeLowerInclusive = 0x02
+	rangeUpperInclusive = 0x04
+	rangeLowerInfinite  = 0x08
+	rangeUpperInfinite  = 0x10
+)
+
+// COPY binary format signature, followed by the flags field and the header extension length.
+var copyBinarySignature = []byte("PGCOPY\n\377\r\n\000")
+
+// copyColumn is a table column as it is transferred by COPY.
+// Columns whose type is not in copyBinaryTypes are transferred as text, and their OID is the text OID.
+// Array columns are read in the binary format when their element type is in copyBinaryTypes, and their OID is the element OID.
+type copyColumn struct {
+	name     string
+	oid      uint32
+	isBinary bool
+	isArray  bool
+}
+
+// buildCopyColumns returns the COPY columns of a table, and the select list that reads them in the COPY binary format.
+func buildCopyColumns(columns []sql_entities.SQLTableColumn) ([]copyColumn, string) {
+	copyColumns := make([]copyColumn, len(columns))
+	selectList := make([]string, len(columns))
+	for i, column := range columns {
+		columnType, isArray := strings.CutSuffix(column.Type, "[]")
+		oid, isBinary := copyBinaryTypes[baseColumnType(columnType)]
+		if isBinary {
+			selectList[i] = pq.QuoteIdentifier(column.Name)
+		} else {
+			oid = pgtype.TextOID
+			isArray = false
+			selectList[i] = fmt.Sprintf("%s::text", pq.QuoteIdentifier(column.Name))
+		}
+		copyColumns[i] = copyColumn{name: column.Name, oid: oid, isBinary: isBinary, isArray: isArray}
+	}
+
+	return copyColumns, strings.Join(selectList, ", ")
+}
+
+var typeModifierRegexp = regexp.MustCompile(`\([0-9, ]*\)`)
+
+// baseColumnType removes the length and precision modifiers from a column type.
+// (character varying(50) -> character varying, timestamp(3) with time zone -> timestamp with time zone)
+func baseColumnType(columnType string) string {
+	return typeModifierRegexp.ReplaceAllString(columnType, "")
+}
+
+// withPgxConn runs f with the pgx connection underlying a database/sql connection, so it can use the COPY protocol.
+func withPgxConn(conn *sql.Conn, f func(pgxConn *pgx.Conn) error) error {
+	return conn.Raw(func(driverConn any) error {
+		stdlibConn, ok := driverConn.(*stdlib.Conn)
+		if !ok {
+			return services.ErrDatabaseDriverNotSupported
+		}
+		return f(stdlibConn.Conn())
+	})
+}
+
+// decodeCopyBinary decodes the rows of a COPY ... TO STDOUT WITH (FORMAT binary) output.
+func decodeCopyBinary(data *bytes.Buffer, columns []copyColumn, typeMap *pgtype.Map) ([]map[string]interface{}, error) {
+	if !bytes.Equal(data.Next(len(copyBinarySignature)), copyBinarySignature) {
+		return nil, fmt.Errorf("invalid COPY binary signature")
+	}
+	var flags, extensionLength uint32
+	if err := binary.Read(data, binary.BigEndian, &flags); err != nil {
+		return nil, err
+	}
+	if err := binary.Read(data, binary.BigEndian, &extensionLength); err != nil {
+		return nil, err
+	}
+	data.Next(int(extensionLength))
+
+	rows := []map[string]interface{}{}
+	for {
+		var fieldCount int16
+		if err := binary.Read(data, binary.BigEndian, &fieldCount); err != nil {
+			return nil, err
+		}
+		// The trailer is a field count of -1
+		if fieldCount == -1 {
+			return rows, nil
+		}
+		if int(fieldCount) != len(columns) {
+			return nil, fmt.Errorf("COPY row has %d fields, expected %d", fieldCount, len(columns))
+		}
+
+		row := make(map[string]interface{}, len(columns))
+		for _, column := range columns {
+			var length int32
+			if err := binary.Read(data, binary.BigEndian, &length); err != nil {
+				return nil, err
+			}
+			if length == -1 {
+				row[column.name] = nil
+				continue
+			}
+
+			src := data.Next(int(length))
+			if len(src) != int(length) {
+				return nil, io.ErrUnexpectedEOF
+			}
+			var value interface{}
+			var err error
+			if column.isArray {
+				value, err = decodeCopyBinaryArray(typeMap, column.oid, src)
+			} else {
+				value, err = decodeCopyBinaryValue(typeMap, column.oid, src)
+			}
+			if err != nil {
+				return nil, fmt.Errorf("could not decode column %s: %w", column.name, err)
+			}
+			row[column.name] = value
+		}
+		rows = append(rows, row)
+	}
+}
+
+// decodeCopyBinaryValue decodes a single COPY binary value into the type stored in the backup records.
+func decodeCopyBinaryValue(typeMap *pgtype.Map, oid uint32, src []byte) (interface{}, error) {
+	switch oid {
+	case pgtype.Int2OID, pgtype.Int4OID, pgtype.Int8OID:
+		var value int64
+		err := typeMap.Scan(oid, pgtype.BinaryFormatCode, src, &value)
+		return value, err
+	case pgtype.Float4OID:
+		var value float32
+		if err := typeMap.Scan(oid, pgtype.BinaryFormatCode, src, &value); err != nil {
+			return nil, err
+		}
+		// Widened from its shortest representation, so 73.8 is not read as 73.80000305175781
+		return strconv.ParseFloat(strconv.FormatFloat(float64(value), 'g', -1, 32), 64)
+	case pgtype.Float8OID:
+		var value float64
+		err := typeMap.Scan(oid, pgtype.BinaryFormatCode, src, &value)
+		return value, err
+	case pgtype.NumericOID:
+		var value pgtype.Numeric
+		if err := typeMap.Scan(oid, pgtype.BinaryFormatCode, src, &value); err != nil {
+			return nil, err
+		}
+		text, err := value.Value()
+		if err != nil {
+			return nil, err
+		}
+		return types.Decimal(text.(string)), nil
+	case pgtype.BoolOID:
+		var value bool
+		err := typeMap.Scan(oid, pgtype.BinaryFormatCode, src, &value)
+		return value, err
+	case pgtype.DateOID:
+		var value pgtype.Date
+		if err := typeMap.Scan(oid, pgtype.BinaryFormatCode, src, &value); err != nil {
+			return nil, err
+		}
+		if value.InfinityModifier != pgtype.Finite {
+			return types.Infinity(value.InfinityModifier), nil
+		}
+		return types.DateOf(value.Time), nil
+	case pgtype.TimestampOID:
+		var value pgtype.Timestamp
+		if err := typeMap.Scan(oid, pgtype.BinaryFormatCode, src, &value); err != nil {
+			return nil, err
+		}
+		if value.InfinityModifier != pgtype.Finite {
+			return types.Infinity(value.InfinityModifier), nil
+		}
+		return value.Time, nil
+	case pgtype.TimestamptzOID:
+		var value pgtype.Timestamptz
+		if err := typeMap.Scan(oid, pgtype.BinaryFormatCode, src, &value); err != nil {
+			return nil, err
+		}
+		if value.InfinityModifier != pgtype.Finite {
+			return types.Infinity(value.InfinityModifier), nil
+		}
+		// PostgreSQL does not keep the time zone, so every value is read in UTC instead of the local time zone
+		return value.Time.UTC(), nil
+	case pgtype.IntervalOID:
+		var value pgtype.Interval
+		if err := typeMap.Scan(oid, pgtype.BinaryFormatCode, src, &value); err != nil {
+			return nil, err
+		}
+		return types.Interval{Months: value.Months, Days: value.Days, Microseconds: value.Microseconds}, nil
+	case pgtype.UUIDOID:
+		var value pgtype.UUID
+		if err := typeMap.Scan(oid, pgtype.BinaryFormatCode, src, &value); err != nil {
+			return nil, err
+		}
+		return types.UUID(value.Bytes), nil
+	case pgtype.JSONOID:
+		return types.JSON(src), nil
+	case pgtype.JSONBOID:
+		// The binary format of jsonb is a version byte followed by the document text
+		if len(src) == 0 || src[0] != 1 {
+			return nil, fmt.Errorf("unsupported jsonb binary format")
+		}
+		return types.JSON(src[1:]), nil
+	case pgtype.ByteaOID:
+		return bytes.Clone(src), nil
+	default:
+		if subtype, isRange := copyRangeSubtypes[oid]; isRange {
+			return decodeCopyBinaryRange(typeMap, subtype, src)
+		}
+		// Text types and the columns cast to text are sent as their UTF-8 bytes
+		return string(src), nil
+	}
+}
+
+// decodeCopyBinaryArray decodes an array in the binary format, whose elements are of the type oid.
+func decodeCopyBinaryArray(typeMap *pgtype.Map, oid uint32, src []byte) (types.Array, error) {
+	buf := bytes.NewBuffer(src)
+
+	var dimensionCount, hasNull int32
+	var elementOid uint32
+	if err := binary.Read(buf, binary.BigEndian, &dimensionCount); err != nil {
+		return types.Array{}, err
+	}
+	if err := binary.Read(buf, binary.BigEndian, &hasNull); err != nil {
+		return types.Array{}, err
+	}
+	if err := binary.Read(buf, binary.BigEndian, &elementOid); err != nil {
+		return types.Array{}, err
+	}
+	if elementOid != oid {
+		return types.Array{}, fmt.Errorf("array element type %d, expected %d", elementOid, oid)
+	}
+
+	array := types.Array{Dimensions: make([]types.ArrayDimension, dimensionCount)}
+	elementCount := 0
+	for i := range array.Dimensions {
+		if err := binary.Read(buf, binary.BigEndian, &array.Dimensions[i].Length); err != nil {
+			return types.Array{}, err
+		}
+		if err := binary.Read(buf, binary.BigEndian, &array.Dimensions[i].LowerBound); err != nil {
+			return types.Array{}, err
+		}
+		if i == 0 {
+			elementCount = int(array.Dimensions[i].Length)
+		} else {
+			elementCount *= int(array.Dimensions[i].Length)
+		}
+	}
+
+	array.Elements = make([]interface{}, elementCount)
+	for i := range array.Elements {
+		element, err := decodeCopyBinaryElement(typeMap, oid, buf)
+		if err != nil {
+			return types.Array{}, err
+		}
+		array.Elements[i] = element
+	}
+
+	return array, nil
+}
+
+// decodeCopyBinaryRange decodes a range in the binary format, whose bounds are of the type subtype.
+func decodeCopyBinaryRange(typeMap *pgtype.Map, subtype uint32, src []byte) (types.Range, error) {
+	buf := bytes.NewBuffer(src)
+
+	flags, err := buf.ReadByte()
+	if err != nil {
+		return types.Range{}, err
+	}
+
+	r := types.Range{
+		Empty:          flags&rangeEmpty != 0,
+		LowerInclusive: flags&rangeLowerInclusive != 0,
+		UpperInclusive: flags&rangeUpperInclusive != 0,
+	}
+	if r.Empty {
+		return r, nil
+	}
+	if flags&rangeLowerInfinite == 0 {
+		if r.Lower, err = decodeCopyBinaryElement(typeMap, subtype, buf); err != nil {
+			return types.Range{}, err
+		}
+	}
+	if flags&rangeUpperInfinite == 0 {
+		if r.Upper, err = decodeCopyBinaryElement(typeMap, subtype, buf); err != nil {
+			return types.Range{}, err
+		}
+	}
+
+	return r, nil
+}
+
+// decodeCopyBinaryElement decodes an array element or a range bound, which are preceded by their length as the COPY fields.
+func decodeCopyBinaryElement(typeMap *pgtype.Map, oid uint32, buf *bytes.Buffer) (interface{}, error) {
+	var length int32
+	if err := binary.Read(buf, binary.BigEndian, &length); err != nil {
+		return nil, err
+	}
+	if length == -1 {
+		return nil, nil
+	}
+
+	src := buf.Next(int(length))
+	if len(src) != int(length) {
+		return nil, io.ErrUnexpectedEOF
+	}
+	return decodeCopyBinaryValue(typeMap, oid, src)
+}
+
+// canCopyBinary checks every value of the records can be encoded in the COPY binary format of its column.
+// Otherwise, the records are sent in the COPY text format and the server parses them.
+func canCopyBinary(columns []copyColumn, records []sql_entities.SQLRecord) bool {
+	for _, column := range columns {
+		if !column.isBinary {
+			return false
+		}
+	}
+
+	for _, record := range records {
+		for _, column := range columns {
+			value := record.Content[column.name]
+			if array, ok := value.(types.Array); ok && column.isArray {
+				for _, element := range array.Elements {
+					if !canCopyBinaryValue(column.oid, element) {
+						return false
+					}
+				}
+			} else if value != nil && (column.isArray || !canCopyBinaryValue(column.oid, value)) {
+				return false
+			}
+		}
+	}
+
+	return true
+}
+
+// canCopyBinaryValue checks a value can be encoded in the COPY binary format of the type oid.
+func canCopyBinaryValue(oid uint32, value interface{}) bool {
+	switch value := value.(type) {
+	case nil:
+		return true
+	case int64:
+		return oid == pgtype.Int2OID || oid == pgtype.Int4OID || oid == pgtype.Int8OID || oid == pgtype.NumericOID
+	case float64:
+		return oid == pgtype.Float4OID || oid == pgtype.Float8OID || oid == pgtype.NumericOID
+	case bool:
+		return oid == pgtype.BoolOID
+	case string:
+		return oid == pgtype.TextOID || oid == pgtype.VarcharOID || oid == pgtype.BPCharOID
+	case time.Time, types.Infinity:
+		return oid == pgtype.DateOID || oid == pgtype.TimestampOID || oid == pgtype.TimestamptzOID
+	case []byte:
+		return oid == pgtype.ByteaOID
+	case types.Decimal:
+		return oid == pgtype.NumericOID
+	case types.Date:
+		return oid == pgtype.DateOID
+	case types.Interval:
+		return oid == pgtype.IntervalOID
+	case types.UUID:
+		return oid == pgtype.UUIDOID
+	case types.JSON:
+		return oid == pgtype.JSONOID || oid == pgtype.JSONBOID
+	case types.Range:
+		subtype, isRange := copyRangeSubtypes[oid]
+		return isRange && canCopyBinaryValue(subtype, value.Lower) && canCopyBinaryValue(subtype, value.Upper)
+	default:
+		return false
+	}
+}
+
+// copyRange is a range whose bounds are already converted into values pgx can encode.
+type copyRange struct {
+	lower, upper         interface{}
+	lowerType, upperType pgtype.BoundType
+}
+
+func (r copyRange) IsNull() bool {
+	return false
+}
+
+func (r copyRange) BoundTypes() (pgtype.BoundType, pgtype.BoundType) {
+	return r.lowerType, r.upperType
+}
+
+func (r copyRange) Bounds() (interface{}, interface{}) {
+	return r.lower, r.upper
+}
+
+// copyBinaryValue converts a record value into the value pgx encodes in the COPY binary format of the type oid.
+// The value must have been checked with canCopyBinaryValue.
+func copyBinaryValue(oid uint32, value interface{}) (interface{}, error) {
+	switch value := value.(type) {
+	case types.Decimal:
+		var numeric pgtype.Numeric
+		if err := numeric.Scan(string(value)); err != nil {
+			return nil, err
+		}
+		return numeric, nil
+	case types.Date:
+		return pgtype.Date{Time: value.Time(), Valid: true}, nil
+	case types.Infinity:
+		switch oid {
+		case pgtype.DateOID:
+			return pgtype.Date{InfinityModifier: pgtype.InfinityModifier(value), Valid: true}, nil
+		case pgtype.TimestampOID:
+			return pgtype.Timestamp{InfinityModifier: pgtype.InfinityModifier(value), Valid: true}, nil
+		default:
+			return pgtype.Timestamptz{InfinityModifier: pgtype.InfinityModifier(value), Valid: true}, nil
+		}
+	case types.Interval:
+		return pgtype.Interval{Months: value.Months, Days: value.Days, Microseconds: value.Microseconds, Valid: true}, nil
+	case types.UUID:
+		return pgtype.UUID{Bytes: value, Valid: true}, nil
+	case types.JSON:
+		return string(value), nil
+	case types.Array:
+		// Arrays without dimensions are empty arrays, while pgx writes nil dimensions as NULL
+		array := pgtype.Array[any]{Dims: make([]pgtype.ArrayDimension, len(value.Dimensions)), Elements: make([]any, len(value.Elements)), Valid: true}
+		for i, dimension := range value.Dimensions {
+			array.Dims[i] = pgtype.ArrayDimension{Length: dimension.Length, LowerBound: dimension.LowerBound}
+		}
+		for i, element := range value.Elements {
+			var err error
+			if array.Elements[i], err = copyBinaryValue(oid, element); err != nil {
+				return nil, err
+			}
+		}
+		return array, nil
+	case types.Range:
+		if value.Empty {
+			return copyRange{lowerType: pgtype.Empty, upperType: pgtype.Empty}, nil
+		}
+
+		subtype := copyRangeSubtypes[oid]
+		lower, err := copyBinaryValue(subtype, value.Lower)
+		if err != nil {
+			return nil, err
+		}
+		upper, err := copyBinaryValue(subtype, value.Upper)
+		if err != nil {
+			return nil, err
+		}
+		return copyRange{lower: lower, upper: upper, lowerType: rangeBoundType(value.Lower, value.LowerInclusive), upperType: rangeBoundType(value.Upper, value.UpperInclusive)}, nil
+	default:
+		return value, nil
+	}
+}
+
+// rangeBoundType returns the pgx bound type of a range bound. Nil bounds are infinite.
+func rangeBoundType(bound interface{}, inclusive bool) pgtype.BoundType {
+	switch {
+	case bound == nil:
+		return pgtype.Unbounded
+	case inclusive:
+		return pgtype.Inclusive
+	default:
+		return pgtype.Exclusive
+	}
+}
+
+var copyTextReplacer = strings.NewReplacer(`\`, `\\`, "\n", `\n`, "\r", `\r`, "\t", `\t`)
+
+// encodeCopyText encodes the records in the COPY text format.
+func encodeCopyText(columns []copyColumn, records []sql_entities.SQLRecord) *bytes.Buffer {
+	var buf bytes.Buffer
+	for _, record := range records {
+		for i, column := range columns {
+			if i > 0 {
+				buf.WriteByte('\t')
+			}
+
+			value := record.Content[column.name]
+			if value == nil {
+				buf.WriteString(`\N`)
+			} else {
+				buf.WriteString(copyTextReplacer.Replace(formatCopyText(value)))
+			}
+		}
+		buf.WriteByte('\n')
+	}
+
+	return &buf
+}
+
+var copyTextQuoteReplacer = strings.NewReplacer(`\`, `\\`, `"`, `\"`)
+
+// formatCopyText returns the text representation PostgreSQL parses a value from, before it is escaped for COPY.
+func formatCopyText(value interface{}) string {
+	switch value := value.(type) {
+	case string:
+		return value
+	case int64:
+		return strconv.FormatInt(value, 10)
+	case float64:
+		switch {
+		case math.IsInf(value, 1):
+			return "Infinity"
+		case math.IsInf(value, -1):
+			return "-Infinity"
+		default:
+			// NaN is formatted as the server expects it
+			return strconv.FormatFloat(value, 'g', -1, 64)
+		}
+	case bool:
+		return strconv.FormatBool(value)
+	case time.Time:
+		return value.Format(time.RFC3339Nano)
+	case []byte:
+		return `\x` + hex.EncodeToString(value)
+	case types.Interval:
+		return fmt.Sprintf("%d months %d days %d microseconds", value.Months, value.Days, value.Microseconds)
+	case types.Array:
+		var buf strings.Builder
+		// The bounds are only written when an array does not start at index 1. ([0:1][1:2]={{1,2},{3,4}})
+		for _, dimension := range value.Dimensions {
+			if dimension.LowerBound != 1 {
+				for _, dimension := range value.Dimensions {
+					fmt.Fprintf(&buf, "[%d:%d]", dimension.LowerBound, dimension.LowerBound+dimension.Length-1)
+				}
+				buf.WriteByte('=')
+				break
+			}
+		}
+		writeCopyTextArray(&buf, value.Dimensions, value.Elements)
+		return buf.String()
+	case types.Range:
+		if value.Empty {
+			return "empty"
+		}
+
+		var buf strings.Builder
+		if value.LowerInclusive {
+			buf.WriteByte('[')
+		} else {
+			buf.WriteByte('(')
+		}
+		if value.Lower != nil {
+			buf.WriteString(quoteCopyText(formatCopyText(value.Lower)))
+		}
+		buf.WriteByte(',')
+		if value.Upper != nil {
+			buf.WriteString(quoteCopyText(formatCopyText(value.Upper)))
+		}
+		if value.UpperInclusive {
+			buf.WriteByte(']')
+		} else {
+			buf.WriteByte(')')
+		}
+		return buf.String()
+	default:
+		// Decimals, dates, infinite dates and timestamps, UUIDs and JSON documents are written as their text
+		return fmt.Sprintf("%v", value)
+	}
+}
+
+// writeCopyTextArray writes the elements of an array nested in braces by its dimensions. ({{1,2},{3,4}})
+func writeCopyTextArray(buf *strings.Builder, dimensions []types.ArrayDimension, elements []interface{}) {
+	buf.WriteByte('{')
+	if len(dimensions) > 0 {
+		elementsPerItem := len(elements) / max(int(dimensions[0].Length), 1)
+		for i := 0; i < int(dimensions[0].Length); i++ {
+			if i > 0 {
+				buf.WriteByte(',')
+			}
+
+			if len(dimensions) > 1 {
+				writeCopyTextArray(buf, dimensions[1:], elements[i*elementsPerItem:(i+1)*elementsPerItem])
+			} else if elements[i] == nil {
+				buf.WriteString("NULL")
+			} else {
+				buf.WriteString(quoteCopyText(formatCopyText(elements[i])))
+			}
+		}
+	}
+	buf.WriteByte('}')
+}
+
+// quoteCopyText quotes an array element or a range bound, so its delimiters and spaces are not parsed.
+func quoteCopyText(text string) string {
+	return `"` + copyTextQuoteReplacer.Replace(text) + `"`
+}
+
+// copyRecordsFrom inserts the records into a table with COPY ... FROM STDIN, in the binary format when the values allow it.
+func copyRecordsFrom(pgxConn *pgx.Conn, tableSchema, tableName string, columns []copyColumn, records []sql_entities.SQLRecord) error {
+	ctx := context.Background()
+
+	columnNames := make([]string, len(columns))
+	for i, column := range columns {
+		columnNames[i] = column.name
+	}
+
+	if canCopyBinary(columns, records) {
+		rows := make([][]any, len(records))
+		for i, record := range records {
+			rows[i] = make([]any, len(columns))
+			for j, column := range columns {
+				value, err := copyBinaryValue(column.oid, record.Content[column.name])
+				if err != nil {
+					return fmt.Errorf("could not encode column %s: %w", column.name, err)
+				}
+				rows[i][j] = value
+			}
+		}
+
+		_, err := pgxConn.CopyFrom(ctx, pgx.Identifier{tableSchema, tableName}, columnNames, pgx.CopyFromRows(rows))
+		return err
+	}
+
+	query := fmt.Sprintf("COPY %s.%s (%s) FROM STDIN", pq.QuoteIdentifier(tableSchema), pq.QuoteIdentifier(tableName), strings.Join(utils.QuoteIdentifiers(columnNames), ", "))
+	_, err := pgxConn.PgConn().CopyFrom(ctx, encodeCopyText(columns, records), query)
+	return err
+}
@@ -2,6 +2,7 @@ package psql
 
 import (
 	"database/sql"
+	"historydb/src/internal/services"
 	database_services "historydb/src/internal/services/database"
 )
 
@@ -22,6 +23,14 @@ func (factory *PSQLDatabaseFactory) CreateReader() database_services.DatabaseRea
 	return factory.dbReader
 }
 
+// CreateParallelReader creates a reader that imports the snapshot exported by the transaction in progress of the factory reader.
+func (factory *PSQLDatabaseFactory) CreateParallelReader() (database_services.DatabaseReader, error) {
+	if factory.dbReader == nil || factory.dbReader.ExportedSnapshot() == "" {
+		return nil, services.ErrDatabaseTransactionNotFound
+	}
+	return NewPSQLDatabaseReaderFromSnapshot(factory.db, factory.dbReader.ExportedSnapshot()), nil
+}
+
 func (factory *PSQLDatabaseFactory) CreateWriter() database_services.DatabaseWriter {
 	if factory.dbWriter == nil {
 		factory.dbWriter = NewPSQLDatabaseWriter(factory.db)
@@ -29,6 +38,11 @@ func (factory *PSQLDatabaseFactory) CreateWriter() database_services.DatabaseWri
 	return factory.dbWriter
 }
 
+// CreateParallelWriter creates a new writer, as every writer transaction takes its own connection from the pool.
+func (factory *PSQLDatabaseFactory) CreateParallelWriter() (database_services.DatabaseWriter, error) {
+	return NewPSQLDatabaseWriter(factory.db), nil
+}
+
 func (factory *PSQLDatabaseFactory) GetDBEngine() string {
 	return "postgres"
 }
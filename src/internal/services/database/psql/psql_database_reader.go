@@ -1,9 +1,12 @@
 package psql
 
 import (
+	"bytes"
+	"context"
 	"database/sql"
 	"fmt"
 	"historydb/src/internal/entities"
+	"historydb/src/internal/services"
 	"historydb/src/internal/services/entities/psql"
 	sql_entities "historydb/src/internal/services/entities/sql"
 	"historydb/src/internal/services/utils"
@@ -11,23 +14,100 @@ import (
 	"math/big"
 	"regexp"
 	"sort"
-	"strconv"
 	"strings"
 
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
 	"github.com/lib/pq"
 )
 
+// PSQLDatabaseReader queries a PostgreSQL database.
+//
+// While its transaction is in progress, every query runs inside a read-only REPEATABLE READ transaction,
+// so all of them read the same state of the database. The transaction snapshot is exported,
+// so readers created from it with NewPSQLDatabaseReaderFromSnapshot read that same state through other connections.
 type PSQLDatabaseReader struct {
-	db *sql.DB
+	db     *sql.DB
+	dbConn *sql.Conn
+	tx     *sql.Tx
+
+	typeMap *pgtype.Map
+
+	snapshotId     string
+	importSnapshot bool
 }
 
 func NewPSQLDatabaseReader(db *sql.DB) *PSQLDatabaseReader {
-	return &PSQLDatabaseReader{db}
+	return &PSQLDatabaseReader{db: db, typeMap: pgtype.NewMap()}
+}
+
+// NewPSQLDatabaseReaderFromSnapshot creates a reader whose transaction imports the snapshot exported by another reader.
+// The snapshot can only be imported while the transaction of the reader that exported it is in progress.
+func NewPSQLDatabaseReaderFromSnapshot(db *sql.DB, snapshotId string) *PSQLDatabaseReader {
+	return &PSQLDatabaseReader{db: db, typeMap: pgtype.NewMap(), snapshotId: snapshotId, importSnapshot: true}
+}
+
+func (reader *PSQLDatabaseReader) BeginTransaction() error {
+	if reader.tx != nil {
+		return services.ErrDatabaseTransactionAlreadyStarted
+	}
+
+	// The transaction is bound to a single connection, so COPY queries run on the same connection inside it
+	dbConn, err := reader.db.Conn(context.Background())
+	if err != nil {
+		return err
+	}
+	tx, err := dbConn.BeginTx(context.Background(), &sql.TxOptions{Isolation: sql.LevelRepeatableRead, ReadOnly: true})
+	if err != nil {
+		dbConn.Close()
+		return err
+	}
+
+	// The snapshot has to be set before any other query runs in the transaction
+	if reader.importSnapshot {
+		_, err = tx.Exec(fmt.Sprintf("SET TRANSACTION SNAPSHOT %s", pq.QuoteLiteral(reader.snapshotId)))
+	} else {
+		err = tx.QueryRow("SELECT pg_export_snapshot()").Scan(&reader.snapshotId)
+	}
+	if err != nil {
+		tx.Rollback()
+		dbConn.Close()
+		return err
+	}
+
+	reader.dbConn = dbConn
+	reader.tx = tx
+	return nil
+}
+
+func (reader *PSQLDatabaseReader) EndTransaction() error {
+	if reader.tx == nil {
+		return services.ErrDatabaseTransactionNotFound
+	}
+
+	// Nothing is written in a read-only transaction, so it is just discarded
+	err := reader.tx.Rollback()
+	reader.dbConn.Close()
+	reader.dbConn = nil
+	reader.tx = nil
+	if !reader.importSnapshot {
+		reader.snapshotId = ""
+	}
+	return err
+}
+
+// ExportedSnapshot returns the identifier of the snapshot read by the transaction in progress,
+// or an empty string if there is no transaction in progress.
+func (reader *PSQLDatabaseReader) ExportedSnapshot() string {
+	if reader.tx == nil {
+		return ""
+	}
+	return reader.snapshotId
 }
 
 func (reader *PSQLDatabaseReader) CheckDBIsEmpty() (bool, error) {
 	var hasObjects bool
-	err := reader.db.QueryRow(`
+	err := reader.conn().QueryRow(`
 		SELECT EXISTS (
 			SELECT 1
 			FROM pg_class c
@@ -39,7 +119,7 @@ func (reader *PSQLDatabaseReader) CheckDBIsEmpty() (bool, error) {
 }
 
 func (reader *PSQLDatabaseReader) ListSchemaDependencies() ([]entities.SchemaDependency, error) {
-	rows, err := reader.db.Query(`
+	rows, err := reader.conn().Query(`
 		SELECT sequence_schema, sequence_name, data_type, start_value, minimum_value, maximum_value, increment, CASE cycle_option WHEN 'YES' THEN TRUE ELSE FALSE END AS cycle_option
 		FROM information_schema.sequences
 		ORDER BY sequence_schema, sequence_name
@@ -49,35 +129,46 @@ func (reader *PSQLDatabaseReader) ListSchemaDependencies() ([]entities.SchemaDep
 	}
 	defer rows.Close()
 
-	sequences := []entities.SchemaDependency{}
+	// Sequences are read before querying their values, as a transaction can not run a query while reading the rows of another one
+	type sequenceInfo struct {
+		schema, name, dataType, startValue, minimumValue, maximumValue, increment string
+		cycleOption                                                               bool
+	}
+	sequenceInfos := []sequenceInfo{}
 	for rows.Next() {
-		var sequenceSchema, sequenceName, dataType, startValueStr, minimumValueStr, maximumValueStr, incrementStr string
-		var cycle_option bool
-		if err := rows.Scan(&sequenceSchema, &sequenceName, &dataType, &startValueStr, &minimumValueStr, &maximumValueStr, &incrementStr, &cycle_option); err != nil {
+		var info sequenceInfo
+		if err := rows.Scan(&info.schema, &info.name, &info.dataType, &info.startValue, &info.minimumValue, &info.maximumValue, &info.increment, &info.cycleOption); err != nil {
 			return nil, err
 		}
+		sequenceInfos = append(sequenceInfos, info)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
 
+	sequences := []entities.SchemaDependency{}
+	for _, info := range sequenceInfos {
 		var lastValueStr string
 		var isCalled bool
-		valueQuery := fmt.Sprintf("SELECT last_value::text, is_called FROM %s.%s", pq.QuoteIdentifier(sequenceSchema), pq.QuoteIdentifier(sequenceName))
-		if err := reader.db.QueryRow(valueQuery).Scan(&lastValueStr, &isCalled); err != nil {
+		valueQuery := fmt.Sprintf("SELECT last_value::text, is_called FROM %s.%s", pq.QuoteIdentifier(info.schema), pq.QuoteIdentifier(info.name))
+		if err := reader.conn().QueryRow(valueQuery).Scan(&lastValueStr, &isCalled); err != nil {
 			return nil, err
 		}
 
-		startValue, _ := new(big.Int).SetString(startValueStr, 10)
-		minimumValue, _ := new(big.Int).SetString(minimumValueStr, 10)
-		maximumValue, _ := new(big.Int).SetString(maximumValueStr, 10)
-		increment, _ := new(big.Int).SetString(incrementStr, 10)
+		startValue, _ := new(big.Int).SetString(info.startValue, 10)
+		minimumValue, _ := new(big.Int).SetString(info.minimumValue, 10)
+		maximumValue, _ := new(big.Int).SetString(info.maximumValue, 10)
+		increment, _ := new(big.Int).SetString(info.increment, 10)
 		lastValue, _ := new(big.Int).SetString(lastValueStr, 10)
 
 		sequences = append(sequences, &psql.PSQLSequence{
-			Name:      fmt.Sprintf("%s.%s", sequenceSchema, sequenceName),
-			Type:      dataType,
+			Name:      fmt.Sprintf("%s.%s", info.schema, info.name),
+			Type:      info.dataType,
 			Start:     types.BigInt{Int: *startValue},
 			Min:       types.BigInt{Int: *minimumValue},
 			Max:       types.BigInt{Int: *maximumValue},
 			Increment: types.BigInt{Int: *increment},
-			IsCycle:   cycle_option,
+			IsCycle:   info.cycleOption,
 			LastValue: types.BigInt{Int: *lastValue},
 			IsCalled:  isCalled,
 		})
@@ -87,7 +178,7 @@ func (reader *PSQLDatabaseReader) ListSchemaDependencies() ([]entities.SchemaDep
 }
 
 func (reader *PSQLDatabaseReader) ListSchemaNames() ([]string, error) {
-	rows, err := reader.db.Query(`
+	rows, err := reader.conn().Query(`
 		SELECT table_schema, table_name
 		FROM information_schema.tables
 		WHERE table_schema NOT IN ('information_schema', 'pg_catalog') AND table_type = 'BASE TABLE'
@@ -145,12 +236,12 @@ func (reader *PSQLDatabaseReader) GetSchemaRecordMetadata(schemaName string) (en
 	tableSchema, tableName := reader.parseDBObjectName(schemaName)
 
 	countQuery := fmt.Sprintf("SELECT COUNT(*) FROM %s.%s", pq.QuoteIdentifier(tableSchema), pq.QuoteIdentifier(tableName))
-	if err := reader.db.QueryRow(countQuery).Scan(&metadata.Count); err != nil {
+	if err := reader.conn().QueryRow(countQuery).Scan(&metadata.Count); err != nil {
 		return metadata, err
 	}
 
 	sizeQuery := fmt.Sprintf("SELECT COALESCE(MAX(pg_column_size(t)), 0) AS max_row_size FROM %s.%s AS t", pq.QuoteIdentifier(tableSchema), pq.QuoteIdentifier(tableName))
-	if err := reader.db.QueryRow(sizeQuery).Scan(&metadata.MaxRecordSize); err != nil {
+	if err := reader.conn().QueryRow(sizeQuery).Scan(&metadata.MaxRecordSize); err != nil {
 		return metadata, err
 	}
 
@@ -166,80 +257,50 @@ func (reader *PSQLDatabaseReader) GetSchemaRecordChunk(schema entities.Schema, c
 		cursor = &sql_entities.SQLChunkCursor{}
 	}
 
-	var rows *sql.Rows
-	var err error
+	// COPY does not accept query parameters, so every value is written into the query
+	columns, selectList := buildCopyColumns(table.Columns)
+	var query string
 	pKeys, ok := utils.ExtractPrimaryKey(*table)
 	if !ok {
 		// If table does not have primary keys, it queries the table using offset
-		query := fmt.Sprintf("SELECT * FROM %s.%s ORDER BY ctid LIMIT $1 OFFSET $2", pq.QuoteIdentifier(tableSchema), pq.QuoteIdentifier(tableName))
-		rows, err = reader.db.Query(query, chunkSize, cursor.Offset)
+		query = fmt.Sprintf("SELECT %s FROM %s.%s ORDER BY ctid LIMIT %d OFFSET %d", selectList, pq.QuoteIdentifier(tableSchema), pq.QuoteIdentifier(tableName), chunkSize, cursor.Offset)
 	} else {
 		// If table has primary keys, it queries the table using the for optimization
 		orderClause := fmt.Sprintf("ORDER BY %s", strings.Join(utils.QuoteIdentifiers(pKeys), ", "))
 
 		if cursor.LastPK == nil {
 			// No where clause since it is first chunk
-			query := fmt.Sprintf("SELECT * FROM %s.%s %s LIMIT $1", pq.QuoteIdentifier(tableSchema), pq.QuoteIdentifier(tableName), orderClause)
-			rows, err = reader.db.Query(query, chunkSize)
+			query = fmt.Sprintf("SELECT %s FROM %s.%s %s LIMIT %d", selectList, pq.QuoteIdentifier(tableSchema), pq.QuoteIdentifier(tableName), orderClause, chunkSize)
 		} else {
-			whereClause := utils.BuildPKWhereClause(pKeys, cursor.LastPK)
-			query := fmt.Sprintf("SELECT * FROM %s.%s WHERE %s %s LIMIT $%d", pq.QuoteIdentifier(tableSchema), pq.QuoteIdentifier(tableName), whereClause, orderClause, len(pKeys)+1)
-			args := append(types.ToInterfaceSlice(cursor.LastPK), chunkSize)
-			rows, err = reader.db.Query(query, args...)
+			whereClause := utils.BuildPKWhereClause(pKeys, reader.extractPKTypes(table, pKeys), cursor.LastPK)
+			query = fmt.Sprintf("SELECT %s FROM %s.%s WHERE %s %s LIMIT %d", selectList, pq.QuoteIdentifier(tableSchema), pq.QuoteIdentifier(tableName), whereClause, orderClause, chunkSize)
 		}
 	}
+
+	var data bytes.Buffer
+	err := reader.withPgxConn(func(pgxConn *pgx.Conn) error {
+		_, err := pgxConn.PgConn().CopyTo(context.Background(), &data, fmt.Sprintf("COPY (%s) TO STDOUT WITH (FORMAT binary)", query))
+		return err
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	rows, err := decodeCopyBinary(&data, columns, reader.typeMap)
 	if err != nil {
 		return nil, nil, err
 	}
-	defer rows.Close()
 
-	numCols := len(table.Columns)
 	var lastPKey []interface{} = nil
 	if pKeys != nil {
 		lastPKey = make([]interface{}, len(pKeys))
 	}
 
-	results := make([]sql_entities.SQLRecord, 0, chunkSize)
-	for rows.Next() {
-		values := make([]interface{}, numCols)
-		valuesPtrs := make([]interface{}, numCols)
-		for i := range values {
-			valuesPtrs[i] = &values[i]
-		}
-
-		if err := rows.Scan(valuesPtrs...); err != nil {
-			return nil, nil, err
-		}
-
-		// Save data in SQLRecord
-		row := make(map[string]interface{})
-		for i, col := range table.Columns {
-			var val interface{}
-			raw := values[i]
-
-			b, ok := raw.([]byte)
-			if ok {
-				if col.Type == "bytea" {
-					val = b
-				} else if strings.HasPrefix(col.Type, "numeric") {
-					val, _ = strconv.ParseFloat(string(b), 64)
-				} else {
-					val = string(b)
-				}
-			} else {
-				val = raw
-			}
-
-			row[col.Name] = val
-		}
-
+	results := make([]sql_entities.SQLRecord, 0, len(rows))
+	for _, row := range rows {
 		// Update lastPK
 		for i, key := range pKeys {
-			for k, v := range row {
-				if key == k {
-					lastPKey[i] = v
-				}
-			}
+			lastPKey[i] = row[key]
 		}
 
 		results = append(results, sql_entities.SQLRecord{Content: row})
@@ -248,15 +309,36 @@ func (reader *PSQLDatabaseReader) GetSchemaRecordChunk(schema entities.Schema, c
 	cursor.Offset += int(chunkSize)
 	cursor.LastPK = lastPKey
 	return &sql_entities.SQLRecordChunk{
-		Content: results,
+		PrimaryKey: table.PrimaryKeyColumns(),
+		Content:    results,
 	}, cursor, nil
 }
 
+func (reader *PSQLDatabaseReader) EncodeChunkCursor(chunkCursor interface{}) []byte {
+	cursor, ok := chunkCursor.(*sql_entities.SQLChunkCursor)
+	if !ok || cursor == nil {
+		// A nil cursor is read from the first record
+		return nil
+	}
+	return cursor.EncodeToBytes()
+}
+
+func (reader *PSQLDatabaseReader) DecodeChunkCursor(data []byte) (interface{}, error) {
+	if len(data) == 0 {
+		return nil, nil
+	}
+	var cursor sql_entities.SQLChunkCursor
+	if err := cursor.DecodeFromBytes(data); err != nil {
+		return nil, err
+	}
+	return &cursor, nil
+}
+
 func (reader *PSQLDatabaseReader) ListRoutines() ([]entities.Routine, error) {
 	routines := []entities.Routine{}
 
 	dependencies := make(map[string][]string)
-	dependRows, err := reader.db.Query(`
+	dependRows, err := reader.conn().Query(`
 		SELECT n1.nspname AS dependent_schema, p1.proname AS dependent_name, n2.nspname AS referenced_schema, p2.proname AS referenced_name
 		FROM pg_depend d
 			JOIN pg_proc p1 ON p1.oid = d.objid
@@ -285,7 +367,7 @@ func (reader *PSQLDatabaseReader) ListRoutines() ([]entities.Routine, error) {
 		}
 	}
 
-	routineRows, err := reader.db.Query(`
+	routineRows, err := reader.conn().Query(`
 		SELECT n.nspname AS schema, p.proname AS name, p.prokind AS type, l.lanname as language, CASE p.provolatile WHEN 'i' THEN 'IMMUTABLE' WHEN 's' THEN 'STABLE' ELSE NULL END AS volatility, NULLIF(pg_get_function_arguments(p.oid), '') AS parameters, CASE WHEN p.prokind = 'f' THEN pg_get_function_result(p.oid) ELSE NULL END AS return_type, REGEXP_REPLACE(pg_get_functiondef(p.oid), '^.*AS (\$[^$]*\$).*$', '\1', 's') AS tag, REGEXP_REPLACE(pg_get_functiondef(p.oid), '^.*AS (\$[^$]*\$)\s*(.*?)\1.*$', '\2', 'gs') AS definition
 		FROM pg_proc p
 			JOIN pg_namespace n ON n.oid = p.pronamespace
@@ -337,7 +419,7 @@ func (reader *PSQLDatabaseReader) ListRoutines() ([]entities.Routine, error) {
 		}
 	}
 
-	triggerRows, err := reader.db.Query(`
+	triggerRows, err := reader.conn().Query(`
 		SELECT t.tgname AS trigger_name, REGEXP_REPLACE(pg_get_triggerdef(t.oid, true), '^CREATE TRIGGER\s+' || t.tgname || '\s+', '', 'i') AS definition
 		FROM pg_trigger t
 			JOIN pg_class c ON c.oid = t.tgrelid
@@ -365,6 +447,43 @@ func (reader *PSQLDatabaseReader) ListRoutines() ([]entities.Routine, error) {
 	return routines, nil
 }
 
+// This function is a private PSQL function that runs f with the pgx connection of the transaction in progress,
+// or with a connection of the pool if there is none.
+func (dbReader *PSQLDatabaseReader) withPgxConn(f func(pgxConn *pgx.Conn) error) error {
+	if dbReader.dbConn != nil {
+		return withPgxConn(dbReader.dbConn, f)
+	}
+
+	dbConn, err := dbReader.db.Conn(context.Background())
+	if err != nil {
+		return err
+	}
+	defer dbConn.Close()
+
+	return withPgxConn(dbConn, f)
+}
+
+// This function is a private PSQL function that returns the types of the primary key columns of a table.
+func (dbReader *PSQLDatabaseReader) extractPKTypes(table *sql_entities.SQLTable, pKeys []string) []string {
+	pKeyTypes := make([]string, len(pKeys))
+	for i, key := range pKeys {
+		for _, column := range table.Columns {
+			if column.Name == key {
+				pKeyTypes[i] = column.Type
+			}
+		}
+	}
+	return pKeyTypes
+}
+
+// This function is a private PSQL function that returns the transaction in progress, or the DB if there is none.
+func (dbReader *PSQLDatabaseReader) conn() utils.SQLQuerier {
+	if dbReader.tx != nil {
+		return dbReader.tx
+	}
+	return dbReader.db
+}
+
 // As PSQL has schemes and our Schema names for this language is composed as <scheme-name>.<table-name>,
 // we need this function to obtain the name separately.
 func (dbReader *PSQLDatabaseReader) parseDBObjectName(objectName string) (string, string) {
@@ -376,9 +495,10 @@ func (dbReader *PSQLDatabaseReader) parseDBObjectName(objectName string) (string
 }
 
 // This function is a private PSQL function that extracts the column definitions from a table into the database.
+// Array columns are reported by their element type name, as information_schema only returns ARRAY for them. (_int4 -> integer[])
 func (dbReader *PSQLDatabaseReader) extractColumnsFromTable(tableSchema, tableName string) ([]sql_entities.SQLTableColumn, error) {
-	rows, err := dbReader.db.Query(`
-		SELECT column_name, data_type, CASE is_nullable WHEN 'YES' THEN true ELSE false END AS is_nullable, column_default, ordinal_position, character_maximum_length, numeric_precision, numeric_scale
+	rows, err := dbReader.conn().Query(`
+		SELECT column_name, CASE data_type WHEN 'ARRAY' THEN format('%I.%I', udt_schema, udt_name)::regtype::text ELSE data_type END AS data_type, CASE is_nullable WHEN 'YES' THEN true ELSE false END AS is_nullable, column_default, ordinal_position, character_maximum_length, numeric_precision, numeric_scale
 		FROM information_schema.columns
 		WHERE table_schema = $1 AND table_name = $2
 		ORDER BY ordinal_position
@@ -420,7 +540,7 @@ func (dbReader *PSQLDatabaseReader) extractColumnsFromTable(tableSchema, tableNa
 
 // This function is a private PSQL function that extracts the constraint definitions from a table into the database.
 func (dbReader *PSQLDatabaseReader) extractConstraintsFromTable(tableSchema, tableName string) ([]sql_entities.SQLTableConstraint, error) {
-	rows, err := dbReader.db.Query(`
+	rows, err := dbReader.conn().Query(`
 		SELECT tc.constraint_name, tc.constraint_type, kcu.column_name, CASE WHEN constraint_type = 'CHECK' THEN substring(pg_get_constraintdef(c.oid) FROM 'CHECK \((.*)\)') ELSE NULL END AS definition
 		FROM information_schema.table_constraints tc
 			LEFT JOIN information_schema.key_column_usage kcu ON tc.constraint_name = kcu.constraint_name
@@ -475,7 +595,7 @@ func (dbReader *PSQLDatabaseReader) extractConstraintsFromTable(tableSchema, tab
 
 // This function is a private PSQL function that extracts the foreign keys definitions from a table into the database.
 func (dbReader *PSQLDatabaseReader) extractForeignKeysFromTable(tableSchema, tableName string) ([]sql_entities.SQLTableForeignKey, error) {
-	rows, err := dbReader.db.Query(`
+	rows, err := dbReader.conn().Query(`
 		SELECT tc.constraint_name, kcu.column_name, ccu.table_schema AS referenced_schema, ccu.table_name AS referenced_table, ccu.column_name AS referenced_column, rc.update_rule, rc.delete_rule
 		FROM information_schema.table_constraints tc
 			LEFT JOIN information_schema.key_column_usage kcu ON tc.constraint_name = kcu.constraint_name
@@ -528,7 +648,7 @@ func (dbReader *PSQLDatabaseReader) extractForeignKeysFromTable(tableSchema, tab
 
 // This function is a private PSQL function that extracts the index definitions from a table into the database.
 func (dbReader *PSQLDatabaseReader) extractIndexesFromTable(tableSchema, tableName string) ([]sql_entities.SQLTableIndex, error) {
-	rows, err := dbReader.db.Query(`
+	rows, err := dbReader.conn().Query(`
 		SELECT pci.relname as index_name, am.amname as index_type, array_agg(a.attname ORDER BY x.ordinality) AS column_names, pi.indisunique as is_unique, pg_get_expr(pi.indpred, pi.indrelid) AS partial_condition
 		FROM pg_class pc
 			JOIN pg_namespace ns ON ns.oid = pc.relnamespace
@@ -559,7 +679,7 @@ func (dbReader *PSQLDatabaseReader) extractIndexesFromTable(tableSchema, tableNa
 
 		options := map[string]interface{}{"isUnique": isUnique}
 		if partialCondition != nil {
-			options["partialCondition"] = partialCondition
+			options["partialCondition"] = *partialCondition
 		}
 
 		indexes = append(indexes, sql_entities.SQLTableIndex{
@@ -1,22 +1,33 @@
 package psql
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
 	"historydb/src/internal/entities"
 	"historydb/src/internal/services"
+	database_services "historydb/src/internal/services/database"
 	"historydb/src/internal/services/entities/psql"
 	sql_entities "historydb/src/internal/services/entities/sql"
 	"math/big"
 	"strings"
-	"time"
 
+	"github.com/jackc/pgx/v5"
 	"github.com/lib/pq"
 )
 
+// PSQLDatabaseWriter inserts the backup data into a PostgreSQL database.
+//
+// Its transaction is bound to a single connection, so the records are copied with COPY inside the same transaction.
+// A restore can be committed in several transactions, so the writer keeps track of the objects it creates to drop them.
 type PSQLDatabaseWriter struct {
-	db *sql.DB
-	tx *sql.Tx
+	db     *sql.DB
+	dbConn *sql.Conn
+	tx     *sql.Tx
+
+	createdSchemas   []string
+	createdSequences []string
+	createdTables    []string
 }
 
 func NewPSQLDatabaseWriter(db *sql.DB) *PSQLDatabaseWriter {
@@ -27,9 +38,20 @@ func (writer *PSQLDatabaseWriter) BeginTransaction() error {
 	if writer.tx != nil {
 		return services.ErrDatabaseTransactionAlreadyStarted
 	}
-	var err error
-	writer.tx, err = writer.db.Begin()
-	return err
+
+	dbConn, err := writer.db.Conn(context.Background())
+	if err != nil {
+		return err
+	}
+	tx, err := dbConn.BeginTx(context.Background(), nil)
+	if err != nil {
+		dbConn.Close()
+		return err
+	}
+
+	writer.dbConn = dbConn
+	writer.tx = tx
+	return nil
 }
 
 func (writer *PSQLDatabaseWriter) CommitTransaction() error {
@@ -42,7 +64,7 @@ func (writer *PSQLDatabaseWriter) CommitTransaction() error {
 		return err
 	}
 
-	writer.tx = nil
+	writer.releaseConn()
 	return nil
 }
 
@@ -56,7 +78,7 @@ func (writer *PSQLDatabaseWriter) RollbackTransaction() error {
 		return err
 	}
 
-	writer.tx = nil
+	writer.releaseConn()
 	return nil
 }
 
@@ -68,7 +90,7 @@ func (writer *PSQLDatabaseWriter) SaveSchemaDependency(dependency entities.Schem
 	sequence := dependency.(*psql.PSQLSequence)
 	sequenceSchema, sequenceName := writer.parseDBObjectName(sequence.Name)
 
-	if _, err := writer.tx.Exec(fmt.Sprintf("CREATE SCHEMA IF NOT EXISTS %s", pq.QuoteIdentifier(sequenceSchema))); err != nil {
+	if err := writer.createSchema(sequenceSchema); err != nil {
 		return err
 	}
 
@@ -96,8 +118,12 @@ func (writer *PSQLDatabaseWriter) SaveSchemaDependency(dependency entities.Schem
 		updateQuery += fmt.Sprintf(" RESTART WITH %v", sequence.LastValue)
 	}
 
-	_, err := writer.tx.Exec(updateQuery)
-	return err
+	if _, err := writer.tx.Exec(updateQuery); err != nil {
+		return err
+	}
+
+	writer.createdSequences = append(writer.createdSequences, fmt.Sprintf("%s.%s", pq.QuoteIdentifier(sequenceSchema), pq.QuoteIdentifier(sequenceName)))
+	return nil
 }
 
 func (writer *PSQLDatabaseWriter) SaveSchema(schema entities.Schema) error {
@@ -108,7 +134,7 @@ func (writer *PSQLDatabaseWriter) SaveSchema(schema entities.Schema) error {
 	table := schema.(*sql_entities.SQLTable)
 	tableSchema, tableName := writer.parseDBObjectName(table.Name)
 
-	if _, err := writer.tx.Exec(fmt.Sprintf("CREATE SCHEMA IF NOT EXISTS %s", pq.QuoteIdentifier(tableSchema))); err != nil {
+	if err := writer.createSchema(tableSchema); err != nil {
 		return err
 	}
 
@@ -136,8 +162,12 @@ func (writer *PSQLDatabaseWriter) SaveSchema(schema entities.Schema) error {
 	}
 	query += ");"
 
-	_, err := writer.tx.Exec(query)
-	return err
+	if _, err := writer.tx.Exec(query); err != nil {
+		return err
+	}
+
+	writer.createdTables = append(writer.createdTables, fmt.Sprintf("%s.%s", pq.QuoteIdentifier(tableSchema), pq.QuoteIdentifier(tableName)))
+	return nil
 }
 
 func (writer *PSQLDatabaseWriter) SaveSchemaRules(schema entities.Schema) error {
@@ -148,9 +178,36 @@ func (writer *PSQLDatabaseWriter) SaveSchemaRules(schema entities.Schema) error
 	table := schema.(*sql_entities.SQLTable)
 	tableSchema, tableName := writer.parseDBObjectName(table.Name)
 
+	for _, idx := range table.Indexes {
+		query := fmt.Sprintf(
+			"CREATE INDEX %s ON %s.%s USING %s (%s);",
+			idx.Name,
+			pq.QuoteIdentifier(tableSchema),
+			pq.QuoteIdentifier(tableName),
+			idx.Type,
+			strings.Join(idx.Columns, ", "),
+		)
+		if _, err := writer.tx.Exec(query); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// SaveSchemaConstraints adds the foreign keys as NOT VALID, which only takes a brief lock of the tables
+// as the records are not checked until the foreign keys are validated.
+func (writer *PSQLDatabaseWriter) SaveSchemaConstraints(schema entities.Schema) error {
+	if writer.tx == nil {
+		return services.ErrDatabaseTransactionNotFound
+	}
+
+	table := schema.(*sql_entities.SQLTable)
+	tableSchema, tableName := writer.parseDBObjectName(table.Name)
+
 	for _, fk := range table.ForeignKeys {
 		query := fmt.Sprintf(
-			"ALTER TABLE %s.%s ADD CONSTRAINT %s FOREIGN KEY (%s) REFERENCES %s (%s) ON UPDATE %s ON DELETE %s;",
+			"ALTER TABLE %s.%s ADD CONSTRAINT %s FOREIGN KEY (%s) REFERENCES %s (%s) ON UPDATE %s ON DELETE %s NOT VALID;",
 			pq.QuoteIdentifier(tableSchema),
 			pq.QuoteIdentifier(tableName),
 			fk.Name,
@@ -165,15 +222,21 @@ func (writer *PSQLDatabaseWriter) SaveSchemaRules(schema entities.Schema) error
 		}
 	}
 
-	for _, idx := range table.Indexes {
-		query := fmt.Sprintf(
-			"CREATE INDEX %s ON %s.%s USING %s (%s);",
-			idx.Name,
-			pq.QuoteIdentifier(tableSchema),
-			pq.QuoteIdentifier(tableName),
-			idx.Type,
-			strings.Join(idx.Columns, ", "),
-		)
+	return nil
+}
+
+// ValidateSchemaConstraints validates the foreign keys of a table. It does not block the writes into the referenced tables,
+// so the foreign keys of several tables can be validated at once.
+func (writer *PSQLDatabaseWriter) ValidateSchemaConstraints(schema entities.Schema) error {
+	if writer.tx == nil {
+		return services.ErrDatabaseTransactionNotFound
+	}
+
+	table := schema.(*sql_entities.SQLTable)
+	tableSchema, tableName := writer.parseDBObjectName(table.Name)
+
+	for _, fk := range table.ForeignKeys {
+		query := fmt.Sprintf("ALTER TABLE %s.%s VALIDATE CONSTRAINT %s;", pq.QuoteIdentifier(tableSchema), pq.QuoteIdentifier(tableName), fk.Name)
 		if _, err := writer.tx.Exec(query); err != nil {
 			return err
 		}
@@ -191,45 +254,14 @@ func (writer *PSQLDatabaseWriter) SaveSchemaRecords(schema entities.Schema, chun
 	tableSchema, tableName := writer.parseDBObjectName(table.Name)
 
 	recordChunk := chunk.(*sql_entities.SQLRecordChunk)
-
-	query := fmt.Sprintf("INSERT INTO %s.%s (", pq.QuoteIdentifier(tableSchema), pq.QuoteIdentifier(tableName))
-	for i, col := range table.Columns {
-		query += col.Name
-		if i < len(table.Columns)-1 {
-			query += ", "
-		}
+	if len(recordChunk.Content) == 0 {
+		return nil
 	}
-	query += ") VALUES "
-
-	for i, record := range recordChunk.Content {
-		query += "("
-		for j, col := range table.Columns {
-			val := record.Content[col.Name]
-			switch v := val.(type) {
-			case nil:
-				query += "NULL"
-			case string:
-				query += fmt.Sprintf("'%s'", strings.ReplaceAll(v, "'", "''"))
-			case time.Time:
-				query += fmt.Sprintf("'%s'", v.Format(time.RFC3339))
-			default:
-				query += fmt.Sprintf("%v", v)
-			}
-
-			if j < len(table.Columns)-1 {
-				query += ", "
-			}
-		}
-		query += ")"
-
-		if i < len(recordChunk.Content)-1 {
-			query += ", "
-		}
-	}
-	query += ";"
 
-	_, err := writer.tx.Exec(query)
-	return err
+	columns, _ := buildCopyColumns(table.Columns)
+	return withPgxConn(writer.dbConn, func(pgxConn *pgx.Conn) error {
+		return copyRecordsFrom(pgxConn, tableSchema, tableName, columns, recordChunk.Content)
+	})
 }
 
 func (writer *PSQLDatabaseWriter) SaveRoutine(routine entities.Routine) error {
@@ -258,6 +290,121 @@ func (writer *PSQLDatabaseWriter) SaveRoutine(routine entities.Routine) error {
 	return err
 }
 
+// DropCreatedObjects drops the tables, sequences and schemas created by the writer. Tables are dropped with CASCADE, so their
+// indexes, foreign keys and triggers are dropped with them, while routines are only created in the last transaction of a restore.
+func (writer *PSQLDatabaseWriter) DropCreatedObjects() error {
+	if writer.tx != nil {
+		return services.ErrDatabaseTransactionAlreadyStarted
+	}
+
+	for i := len(writer.createdTables) - 1; i >= 0; i-- {
+		if _, err := writer.db.Exec(fmt.Sprintf("DROP TABLE IF EXISTS %s CASCADE", writer.createdTables[i])); err != nil {
+			return err
+		}
+	}
+	for i := len(writer.createdSequences) - 1; i >= 0; i-- {
+		if _, err := writer.db.Exec(fmt.Sprintf("DROP SEQUENCE IF EXISTS %s CASCADE", writer.createdSequences[i])); err != nil {
+			return err
+		}
+	}
+	for i := len(writer.createdSchemas) - 1; i >= 0; i-- {
+		if _, err := writer.db.Exec(fmt.Sprintf("DROP SCHEMA IF EXISTS %s CASCADE", writer.createdSchemas[i])); err != nil {
+			return err
+		}
+	}
+
+	writer.createdTables = nil
+	writer.createdSequences = nil
+	writer.createdSchemas = nil
+	return nil
+}
+
+func (writer *PSQLDatabaseWriter) KeepCreatedObjects() {
+	writer.createdTables = nil
+	writer.createdSequences = nil
+	writer.createdSchemas = nil
+}
+
+// CreateRestoreCheckpoint creates the checkpoint table in the public schema, inside the transaction.
+func (writer *PSQLDatabaseWriter) CreateRestoreCheckpoint() error {
+	if writer.tx == nil {
+		return services.ErrDatabaseTransactionNotFound
+	}
+
+	_, err := writer.tx.Exec(fmt.Sprintf("CREATE TABLE public.%s (name TEXT PRIMARY KEY, value TEXT NOT NULL)", pq.QuoteIdentifier(database_services.RestoreCheckpointTable)))
+	return err
+}
+
+func (writer *PSQLDatabaseWriter) SaveRestoreCheckpoint(name, value string) error {
+	if writer.tx == nil {
+		return services.ErrDatabaseTransactionNotFound
+	}
+
+	query := fmt.Sprintf("INSERT INTO public.%s (name, value) VALUES ($1, $2) ON CONFLICT (name) DO UPDATE SET value = EXCLUDED.value", pq.QuoteIdentifier(database_services.RestoreCheckpointTable))
+	_, err := writer.tx.Exec(query, name, value)
+	return err
+}
+
+func (writer *PSQLDatabaseWriter) GetRestoreCheckpoint() (map[string]string, error) {
+	var exists bool
+	if err := writer.db.QueryRow("SELECT to_regclass($1) IS NOT NULL", "public."+pq.QuoteIdentifier(database_services.RestoreCheckpointTable)).Scan(&exists); err != nil {
+		return nil, err
+	}
+	if !exists {
+		return nil, nil
+	}
+
+	rows, err := writer.db.Query(fmt.Sprintf("SELECT name, value FROM public.%s", pq.QuoteIdentifier(database_services.RestoreCheckpointTable)))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	entries := make(map[string]string)
+	for rows.Next() {
+		var name, value string
+		if err := rows.Scan(&name, &value); err != nil {
+			return nil, err
+		}
+		entries[name] = value
+	}
+	return entries, rows.Err()
+}
+
+func (writer *PSQLDatabaseWriter) DropRestoreCheckpoint() error {
+	if writer.tx == nil {
+		return services.ErrDatabaseTransactionNotFound
+	}
+
+	_, err := writer.tx.Exec(fmt.Sprintf("DROP TABLE public.%s", pq.QuoteIdentifier(database_services.RestoreCheckpointTable)))
+	return err
+}
+
+// This function is a private PSQL function that creates a DB schema if it does not exist, keeping track of it when created.
+func (writer *PSQLDatabaseWriter) createSchema(schemaName string) error {
+	var exists bool
+	if err := writer.tx.QueryRow("SELECT EXISTS (SELECT 1 FROM pg_namespace WHERE nspname = $1)", schemaName).Scan(&exists); err != nil {
+		return err
+	}
+	if exists {
+		return nil
+	}
+
+	if _, err := writer.tx.Exec(fmt.Sprintf("CREATE SCHEMA %s", pq.QuoteIdentifier(schemaName))); err != nil {
+		return err
+	}
+
+	writer.createdSchemas = append(writer.createdSchemas, pq.QuoteIdentifier(schemaName))
+	return nil
+}
+
+// This function is a private PSQL function that closes the connection of the finished transaction, returning it to the pool.
+func (writer *PSQLDatabaseWriter) releaseConn() {
+	writer.dbConn.Close()
+	writer.dbConn = nil
+	writer.tx = nil
+}
+
 func (writer *PSQLDatabaseWriter) parseDBObjectName(objectName string) (string, string) {
 	parts := strings.Split(objectName, ".")
 	if len(parts) == 2 {
@@ -0,0 +1,86 @@
+package sqlite
+
+import (
+	"database/sql"
+	"fmt"
+	"historydb/src/internal/services"
+	database_services "historydb/src/internal/services/database"
+	"net/url"
+	"strings"
+)
+
+type SQLiteDatabaseFactory struct {
+	db       *sql.DB
+	dbReader *SQLiteDatabaseReader
+	dbWriter *SQLiteDatabaseWriter
+}
+
+func NewSQLiteDatabaseFactory(db *sql.DB) *SQLiteDatabaseFactory {
+	return &SQLiteDatabaseFactory{db, nil, nil}
+}
+
+func (factory *SQLiteDatabaseFactory) CreateReader() database_services.DatabaseReader {
+	if factory.dbReader == nil {
+		factory.dbReader = NewSQLiteDatabaseReader(factory.db)
+	}
+	return factory.dbReader
+}
+
+// CreateParallelReader returns ErrParallelReadNotSupported, as SQLite transactions cannot share their snapshot with another connection.
+func (factory *SQLiteDatabaseFactory) CreateParallelReader() (database_services.DatabaseReader, error) {
+	return nil, services.ErrParallelReadNotSupported
+}
+
+func (factory *SQLiteDatabaseFactory) CreateWriter() database_services.DatabaseWriter {
+	if factory.dbWriter == nil {
+		factory.dbWriter = NewSQLiteDatabaseWriter(factory.db)
+	}
+	return factory.dbWriter
+}
+
+// CreateParallelWriter returns ErrParallelWriteNotSupported, as SQLite only allows a single writer at once.
+func (factory *SQLiteDatabaseFactory) CreateParallelWriter() (database_services.DatabaseWriter, error) {
+	return nil, services.ErrParallelWriteNotSupported
+}
+
+func (factory *SQLiteDatabaseFactory) GetDBEngine() string {
+	return "sqlite"
+}
+
+func (factory *SQLiteDatabaseFactory) CheckBackupDB(engine string) bool {
+	return engine == "sqlite"
+}
+
+// ParseConnString transforms a connection string in URL format (sqlite:///path/to/file.db?params)
+// into the DSN expected by the SQLite driver (/path/to/file.db?params).
+// A connection string with two slashes (sqlite://file.db) points to a path relative to the working directory.
+func ParseConnString(connString string) (string, error) {
+	parsedURL, err := url.Parse(connString)
+	if err != nil {
+		return "", err
+	}
+
+	path := parsedURL.Host + parsedURL.Path
+	if path == "" {
+		return "", fmt.Errorf("no database file provided in connection string")
+	}
+
+	if parsedURL.RawQuery != "" {
+		return fmt.Sprintf("%s?%s", path, parsedURL.RawQuery), nil
+	}
+	return path, nil
+}
+
+// quoteIdentifier quotes a SQLite identifier so it can be used safely inside a query.
+func quoteIdentifier(identifier string) string {
+	return `"` + strings.ReplaceAll(identifier, `"`, `""`) + `"`
+}
+
+// quoteIdentifiers quotes a list of SQLite identifiers.
+func quoteIdentifiers(identifiers []string) []string {
+	quoted := make([]string, len(identifiers))
+	for i, identifier := range identifiers {
+		quoted[i] = quoteIdentifier(identifier)
+	}
+	return quoted
+}
@@ -0,0 +1,618 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"historydb/src/internal/entities"
+	"historydb/src/internal/services"
+	sql_entities "historydb/src/internal/services/entities/sql"
+	"historydb/src/internal/services/entities/sqlite"
+	"historydb/src/internal/services/utils"
+	"historydb/src/internal/utils/types"
+	"math/big"
+	"sort"
+	"strings"
+)
+
+// SQLiteDatabaseReader queries a SQLite database.
+//
+// While its transaction is in progress, every query runs inside a single transaction,
+// so all of them read the same state of the database.
+type SQLiteDatabaseReader struct {
+	db *sql.DB
+	tx *sql.Tx
+}
+
+func NewSQLiteDatabaseReader(db *sql.DB) *SQLiteDatabaseReader {
+	return &SQLiteDatabaseReader{db: db}
+}
+
+func (reader *SQLiteDatabaseReader) BeginTransaction() error {
+	if reader.tx != nil {
+		return services.ErrDatabaseTransactionAlreadyStarted
+	}
+
+	tx, err := reader.db.BeginTx(context.Background(), nil)
+	if err != nil {
+		return err
+	}
+
+	reader.tx = tx
+	return nil
+}
+
+func (reader *SQLiteDatabaseReader) EndTransaction() error {
+	if reader.tx == nil {
+		return services.ErrDatabaseTransactionNotFound
+	}
+
+	// Nothing is written in a read-only transaction, so it is just discarded
+	err := reader.tx.Rollback()
+	reader.tx = nil
+	return err
+}
+
+func (reader *SQLiteDatabaseReader) CheckDBIsEmpty() (bool, error) {
+	var hasObjects bool
+	err := reader.conn().QueryRow(`
+		SELECT EXISTS (
+			SELECT 1
+			FROM sqlite_master
+			WHERE name NOT LIKE 'sqlite_%'
+		)
+	`).Scan(&hasObjects)
+	return !hasObjects, err
+}
+
+func (reader *SQLiteDatabaseReader) ListSchemaDependencies() ([]entities.SchemaDependency, error) {
+	sequences := []entities.SchemaDependency{}
+
+	// The sqlite_sequence table only exists once a table with AUTOINCREMENT has been created
+	var hasSequences bool
+	if err := reader.conn().QueryRow("SELECT EXISTS (SELECT 1 FROM sqlite_master WHERE type = 'table' AND name = 'sqlite_sequence')").Scan(&hasSequences); err != nil {
+		return nil, err
+	}
+	if !hasSequences {
+		return sequences, nil
+	}
+
+	rows, err := reader.conn().Query("SELECT name, seq FROM sqlite_sequence ORDER BY name")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var name string
+		var seq int64
+		if err := rows.Scan(&name, &seq); err != nil {
+			return nil, err
+		}
+
+		sequences = append(sequences, &sqlite.SQLiteSequence{
+			Name:  name,
+			Value: types.BigInt{Int: *big.NewInt(seq)},
+		})
+	}
+
+	return sequences, rows.Err()
+}
+
+func (reader *SQLiteDatabaseReader) ListSchemaNames() ([]string, error) {
+	rows, err := reader.conn().Query(`
+		SELECT name
+		FROM sqlite_master
+		WHERE type = 'table' AND name NOT LIKE 'sqlite_%'
+		ORDER BY name
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	schemaNames := []string{}
+	for rows.Next() {
+		var tableName string
+		if err := rows.Scan(&tableName); err != nil {
+			return nil, err
+		}
+
+		schemaNames = append(schemaNames, tableName)
+	}
+
+	return schemaNames, rows.Err()
+}
+
+func (reader *SQLiteDatabaseReader) GetSchemaDefinition(schemaName string) (entities.Schema, error) {
+	var createTable string
+	if err := reader.conn().QueryRow("SELECT sql FROM sqlite_master WHERE type = 'table' AND name = ?", schemaName).Scan(&createTable); err != nil {
+		return nil, err
+	}
+
+	columns, err := reader.extractColumnsFromTable(schemaName)
+	if err != nil {
+		return nil, err
+	}
+	constraints, err := reader.extractConstraintsFromTable(schemaName, createTable)
+	if err != nil {
+		return nil, err
+	}
+	foreignKeys, err := reader.extractForeignKeysFromTable(schemaName)
+	if err != nil {
+		return nil, err
+	}
+	indexes, err := reader.extractIndexesFromTable(schemaName)
+	if err != nil {
+		return nil, err
+	}
+
+	return &sql_entities.SQLTable{
+		Name:        schemaName,
+		Columns:     columns,
+		Constraints: constraints,
+		ForeignKeys: foreignKeys,
+		Indexes:     indexes,
+	}, nil
+}
+
+func (reader *SQLiteDatabaseReader) GetSchemaRecordMetadata(schemaName string) (entities.SchemaRecordMetadata, error) {
+	metadata := entities.SchemaRecordMetadata{}
+
+	countQuery := fmt.Sprintf("SELECT COUNT(*) FROM %s", quoteIdentifier(schemaName))
+	if err := reader.conn().QueryRow(countQuery).Scan(&metadata.Count); err != nil {
+		return metadata, err
+	}
+
+	columns, err := reader.extractColumnsFromTable(schemaName)
+	if err != nil {
+		return metadata, err
+	}
+	if len(columns) == 0 {
+		return metadata, nil
+	}
+
+	// SQLite has no function to get the size of a full row, so it is calculated adding up the size of every column
+	columnSizes := make([]string, len(columns))
+	for i, col := range columns {
+		columnSizes[i] = fmt.Sprintf("COALESCE(LENGTH(CAST(%s AS BLOB)), 0)", quoteIdentifier(col.Name))
+	}
+	sizeQuery := fmt.Sprintf("SELECT COALESCE(MAX(%s), 0) AS max_row_size FROM %s", strings.Join(columnSizes, " + "), quoteIdentifier(schemaName))
+	if err := reader.conn().QueryRow(sizeQuery).Scan(&metadata.MaxRecordSize); err != nil {
+		return metadata, err
+	}
+
+	return metadata, nil
+}
+
+func (reader *SQLiteDatabaseReader) GetSchemaRecordChunk(schema entities.Schema, chunkSize int64, chunkCursor interface{}) (entities.SchemaRecordChunk, interface{}, error) {
+	table := schema.(*sql_entities.SQLTable)
+
+	cursor, ok := chunkCursor.(*sql_entities.SQLChunkCursor)
+	if !ok || cursor == nil {
+		cursor = &sql_entities.SQLChunkCursor{}
+	}
+
+	// Columns are selected with the unary + operator, which keeps the value untouched but removes the declared type,
+	// so the driver returns the values as they are stored instead of converting the date columns.
+	selectColumns := make([]string, len(table.Columns))
+	for i, col := range table.Columns {
+		selectColumns[i] = fmt.Sprintf("+%s", quoteIdentifier(col.Name))
+	}
+
+	pKeys, ok := utils.ExtractSQLitePrimaryKey(*table)
+	if !ok {
+		// If table does not have primary keys, it iterates the table by its rowid
+		pKeys = []string{"rowid"}
+		selectColumns = append(selectColumns, "rowid")
+	}
+	selectClause := fmt.Sprintf("SELECT %s FROM %s", strings.Join(selectColumns, ", "), quoteIdentifier(table.Name))
+	orderClause := fmt.Sprintf("ORDER BY %s", strings.Join(quoteIdentifiers(pKeys), ", "))
+
+	var rows *sql.Rows
+	var err error
+	if cursor.LastPK == nil {
+		// No where clause since it is first chunk
+		query := fmt.Sprintf("%s %s LIMIT ?", selectClause, orderClause)
+		rows, err = reader.conn().Query(query, chunkSize)
+	} else {
+		query := fmt.Sprintf("%s WHERE %s %s LIMIT ?", selectClause, buildPKWhereClause(pKeys), orderClause)
+		args := append(types.ToInterfaceSlice(cursor.LastPK), chunkSize)
+		rows, err = reader.conn().Query(query, args...)
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	defer rows.Close()
+
+	numCols := len(selectColumns)
+	lastPKey := make([]interface{}, len(pKeys))
+
+	results := make([]sql_entities.SQLRecord, 0, chunkSize)
+	for rows.Next() {
+		values := make([]interface{}, numCols)
+		valuesPtrs := make([]interface{}, numCols)
+		for i := range values {
+			valuesPtrs[i] = &values[i]
+		}
+
+		if err := rows.Scan(valuesPtrs...); err != nil {
+			return nil, nil, err
+		}
+
+		// Save data in SQLRecord
+		row := make(map[string]interface{})
+		for i, col := range table.Columns {
+			if b, ok := values[i].([]byte); ok {
+				row[col.Name] = string(b)
+			} else {
+				row[col.Name] = values[i]
+			}
+		}
+
+		// Update lastPK with the raw values, so blobs are still compared as blobs in the next query
+		if len(values) > len(table.Columns) {
+			lastPKey[0] = values[len(table.Columns)]
+		} else {
+			for i, key := range pKeys {
+				for j, col := range table.Columns {
+					if col.Name == key {
+						lastPKey[i] = values[j]
+					}
+				}
+			}
+		}
+
+		results = append(results, sql_entities.SQLRecord{Content: row})
+	}
+	if err := rows.Err(); err != nil {
+		return nil, nil, err
+	}
+
+	if len(results) > 0 {
+		cursor.LastPK = lastPKey
+	}
+	cursor.Offset += int(chunkSize)
+	return &sql_entities.SQLRecordChunk{
+		PrimaryKey: table.PrimaryKeyColumns(),
+		Content:    results,
+	}, cursor, nil
+}
+
+func (reader *SQLiteDatabaseReader) EncodeChunkCursor(chunkCursor interface{}) []byte {
+	cursor, ok := chunkCursor.(*sql_entities.SQLChunkCursor)
+	if !ok || cursor == nil {
+		// A nil cursor is read from the first record
+		return nil
+	}
+	return cursor.EncodeToBytes()
+}
+
+func (reader *SQLiteDatabaseReader) DecodeChunkCursor(data []byte) (interface{}, error) {
+	if len(data) == 0 {
+		return nil, nil
+	}
+	var cursor sql_entities.SQLChunkCursor
+	if err := cursor.DecodeFromBytes(data); err != nil {
+		return nil, err
+	}
+	return &cursor, nil
+}
+
+func (reader *SQLiteDatabaseReader) ListRoutines() ([]entities.Routine, error) {
+	routines := []entities.Routine{}
+
+	viewRows, err := reader.conn().Query("SELECT name, sql FROM sqlite_master WHERE type = 'view' ORDER BY name")
+	if err != nil {
+		return nil, err
+	}
+	defer viewRows.Close()
+
+	views := []*sqlite.SQLiteView{}
+	viewNames := make(map[string]string)
+	for viewRows.Next() {
+		var view sqlite.SQLiteView
+		if err := viewRows.Scan(&view.Name, &view.Definition); err != nil {
+			return nil, err
+		}
+		views = append(views, &view)
+		viewNames[strings.ToLower(view.Name)] = view.Name
+	}
+	if err := viewRows.Err(); err != nil {
+		return nil, err
+	}
+
+	// A view depends on the other views it reads from, so they are searched by name in its definition
+	for _, view := range views {
+		dependencies := []string{}
+		for _, token := range tokenizeSQL(view.Definition) {
+			if token.kind != wordToken && token.kind != quotedIdentifierToken {
+				continue
+			}
+			if name, ok := viewNames[strings.ToLower(token.text)]; ok && name != view.Name && !types.SeachInSlice(dependencies, name) {
+				dependencies = append(dependencies, name)
+			}
+		}
+		sort.Strings(dependencies)
+		view.Dependencies = dependencies
+		routines = append(routines, view)
+	}
+
+	triggerRows, err := reader.conn().Query("SELECT name, tbl_name, sql FROM sqlite_master WHERE type = 'trigger' ORDER BY tbl_name, name")
+	if err != nil {
+		return nil, err
+	}
+	defer triggerRows.Close()
+
+	for triggerRows.Next() {
+		var triggerName, tableName, definition string
+		if err := triggerRows.Scan(&triggerName, &tableName, &definition); err != nil {
+			return nil, err
+		}
+
+		// INSTEAD OF triggers are attached to a view, which needs to exist before the trigger is created
+		dependencies := []string{}
+		if name, ok := viewNames[strings.ToLower(tableName)]; ok {
+			dependencies = append(dependencies, name)
+		}
+
+		routines = append(routines, &sqlite.SQLiteTrigger{
+			Name:         triggerName,
+			Dependencies: dependencies,
+			Definition:   definition,
+		})
+	}
+
+	return routines, triggerRows.Err()
+}
+
+// This function is a private SQLite function that extracts the column definitions from a table into the database.
+func (reader *SQLiteDatabaseReader) extractColumnsFromTable(tableName string) ([]sql_entities.SQLTableColumn, error) {
+	rows, err := reader.conn().Query(`
+		SELECT name, type, CASE "notnull" WHEN 0 THEN TRUE ELSE FALSE END AS is_nullable, dflt_value, cid + 1 AS position
+		FROM pragma_table_info(?)
+		ORDER BY cid
+	`, tableName)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	columns := []sql_entities.SQLTableColumn{}
+	for rows.Next() {
+		var columnName, dataType string
+		var columnDefault *string
+		var isNullable bool
+		var position int64
+		if err := rows.Scan(&columnName, &dataType, &isNullable, &columnDefault, &position); err != nil {
+			return nil, err
+		}
+
+		columns = append(columns, sql_entities.SQLTableColumn{
+			Name:         columnName,
+			Type:         dataType,
+			IsNullable:   isNullable,
+			DefaultValue: columnDefault,
+			Position:     position,
+		})
+	}
+
+	return columns, rows.Err()
+}
+
+// This function is a private SQLite function that extracts the constraint definitions from a table into the database.
+// SQLite does not name its primary key and unique constraints, so they are named after the table and their columns.
+// As check constraints are not exposed by any pragma, they are extracted from the CREATE TABLE statement.
+// A primary key declared with AUTOINCREMENT keeps it as its definition.
+func (reader *SQLiteDatabaseReader) extractConstraintsFromTable(tableName, createTable string) ([]sql_entities.SQLTableConstraint, error) {
+	constraints := []sql_entities.SQLTableConstraint{}
+
+	pkRows, err := reader.conn().Query("SELECT name FROM pragma_table_info(?) WHERE pk > 0 ORDER BY pk", tableName)
+	if err != nil {
+		return nil, err
+	}
+	defer pkRows.Close()
+
+	pKeys := []string{}
+	for pkRows.Next() {
+		var columnName string
+		if err := pkRows.Scan(&columnName); err != nil {
+			return nil, err
+		}
+		pKeys = append(pKeys, columnName)
+	}
+	if err := pkRows.Err(); err != nil {
+		return nil, err
+	}
+
+	tokens := tokenizeSQL(createTable)
+	if len(pKeys) > 0 {
+		pk := sql_entities.SQLTableConstraint{
+			Type:    sql_entities.PrimaryKey,
+			Name:    fmt.Sprintf("%s_pkey", tableName),
+			Columns: pKeys,
+		}
+		for _, token := range tokens {
+			if token.kind == wordToken && strings.EqualFold(token.text, "AUTOINCREMENT") {
+				pk.Definition = &token.text
+				break
+			}
+		}
+		constraints = append(constraints, pk)
+	}
+
+	uniqueIndexes, err := reader.listIndexes(tableName, "u")
+	if err != nil {
+		return nil, err
+	}
+	for _, index := range uniqueIndexes {
+		constraints = append(constraints, sql_entities.SQLTableConstraint{
+			Type:    sql_entities.Unique,
+			Name:    fmt.Sprintf("%s_%s_key", tableName, strings.Join(index.Columns, "_")),
+			Columns: index.Columns,
+		})
+	}
+
+	constraints = append(constraints, extractCheckConstraints(tableName, createTable, tokens)...)
+
+	sort.Slice(constraints, func(i, j int) bool { return constraints[i].Name < constraints[j].Name })
+	return constraints, nil
+}
+
+// This function is a private SQLite function that extracts the foreign keys definitions from a table into the database.
+// SQLite does not name its foreign keys, so they are named after the table and their columns.
+func (reader *SQLiteDatabaseReader) extractForeignKeysFromTable(tableName string) ([]sql_entities.SQLTableForeignKey, error) {
+	rows, err := reader.conn().Query(`
+		SELECT id, "from", "table", "to", on_update, on_delete
+		FROM pragma_foreign_key_list(?)
+		ORDER BY id, seq
+	`, tableName)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	foreignKeyMap := make(map[int64]*sql_entities.SQLTableForeignKey)
+	ids := []int64{}
+	for rows.Next() {
+		var id int64
+		var columnName, referencedTable, updateRule, deleteRule string
+		var referencedColumn *string
+		if err := rows.Scan(&id, &columnName, &referencedTable, &referencedColumn, &updateRule, &deleteRule); err != nil {
+			return nil, err
+		}
+
+		fk, exists := foreignKeyMap[id]
+		if !exists {
+			fk = &sql_entities.SQLTableForeignKey{
+				Columns:           []string{},
+				ReferencedTable:   referencedTable,
+				ReferencedColumns: []string{},
+				UpdateAction:      sql_entities.ActionType(updateRule),
+				DeleteAction:      sql_entities.ActionType(deleteRule),
+			}
+			foreignKeyMap[id] = fk
+			ids = append(ids, id)
+		}
+		fk.Columns = append(fk.Columns, columnName)
+		if referencedColumn != nil {
+			fk.ReferencedColumns = append(fk.ReferencedColumns, *referencedColumn)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	foreignKeys := make([]sql_entities.SQLTableForeignKey, 0, len(foreignKeyMap))
+	for _, id := range ids {
+		fk := foreignKeyMap[id]
+		fk.Name = fmt.Sprintf("%s_%s_fkey", tableName, strings.Join(fk.Columns, "_"))
+		foreignKeys = append(foreignKeys, *fk)
+	}
+	sort.Slice(foreignKeys, func(i, j int) bool { return foreignKeys[i].Name < foreignKeys[j].Name })
+
+	return foreignKeys, nil
+}
+
+// This function is a private SQLite function that extracts the index definitions from a table into the database.
+// The CREATE INDEX statement is kept in the options, as it is the only place holding expressions and partial conditions.
+func (reader *SQLiteDatabaseReader) extractIndexesFromTable(tableName string) ([]sql_entities.SQLTableIndex, error) {
+	return reader.listIndexes(tableName, "c")
+}
+
+// This function is a private SQLite function that lists the indexes of a table created by the given origin.
+// (c = CREATE INDEX, u = UNIQUE constraint, pk = PRIMARY KEY constraint)
+func (reader *SQLiteDatabaseReader) listIndexes(tableName, origin string) ([]sql_entities.SQLTableIndex, error) {
+	rows, err := reader.conn().Query(`
+		SELECT il.name, il."unique", m.sql
+		FROM pragma_index_list(?) il
+			LEFT JOIN sqlite_master m ON m.type = 'index' AND m.name = il.name
+		WHERE il.origin = ?
+		ORDER BY il.name
+	`, tableName, origin)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	indexes := []sql_entities.SQLTableIndex{}
+	for rows.Next() {
+		var indexName string
+		var isUnique bool
+		var definition *string
+		if err := rows.Scan(&indexName, &isUnique, &definition); err != nil {
+			return nil, err
+		}
+
+		options := map[string]interface{}{"isUnique": isUnique}
+		if definition != nil {
+			options["definition"] = *definition
+		}
+
+		indexes = append(indexes, sql_entities.SQLTableIndex{
+			Name:    indexName,
+			Type:    "btree",
+			Options: options,
+		})
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	for i := range indexes {
+		columns, err := reader.listIndexColumns(indexes[i].Name)
+		if err != nil {
+			return nil, err
+		}
+		indexes[i].Columns = columns
+	}
+
+	return indexes, nil
+}
+
+// This function is a private SQLite function that lists the columns of an index. Expressions are listed as <expression>.
+func (reader *SQLiteDatabaseReader) listIndexColumns(indexName string) ([]string, error) {
+	rows, err := reader.conn().Query("SELECT name FROM pragma_index_info(?) ORDER BY seqno", indexName)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	columns := []string{}
+	for rows.Next() {
+		var columnName *string
+		if err := rows.Scan(&columnName); err != nil {
+			return nil, err
+		}
+
+		if columnName != nil {
+			columns = append(columns, *columnName)
+		} else {
+			columns = append(columns, "<expression>")
+		}
+	}
+
+	return columns, rows.Err()
+}
+
+// buildPKWhereClause creates a where clause when batching data queries using the primary key of the table.
+func buildPKWhereClause(pKeys []string) string {
+	if len(pKeys) == 1 {
+		return fmt.Sprintf("%s > ?", quoteIdentifier(pKeys[0]))
+	}
+
+	placeholders := make([]string, len(pKeys))
+	for i := range pKeys {
+		placeholders[i] = "?"
+	}
+
+	return fmt.Sprintf("(%s) > (%s)", strings.Join(quoteIdentifiers(pKeys), ", "), strings.Join(placeholders, ", "))
+}
+
+// This function is a private SQLite function that returns the transaction in progress, or the DB if there is none.
+func (reader *SQLiteDatabaseReader) conn() utils.SQLQuerier {
+	if reader.tx != nil {
+		return reader.tx
+	}
+	return reader.db
+}
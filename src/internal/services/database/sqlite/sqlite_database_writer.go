@@ -0,0 +1,367 @@
+package sqlite
+
+import (
+	"database/sql"
+	"fmt"
+	"historydb/src/internal/entities"
+	"historydb/src/internal/services"
+	database_services "historydb/src/internal/services/database"
+	sql_entities "historydb/src/internal/services/entities/sql"
+	"historydb/src/internal/services/entities/sqlite"
+	"strings"
+	"time"
+)
+
+// SQLite limits the number of placeholders in a single statement.
+const maxStatementPlaceholders = 32766
+
+// SQLiteDatabaseWriter inserts the backup data into a SQLite database.
+//
+// SQLite only allows a single writer at once, so a restore is committed in a single transaction. Even so, the writer
+// keeps track of the tables and views it creates to drop them if a committed restore has to be discarded.
+type SQLiteDatabaseWriter struct {
+	db *sql.DB
+	tx *sql.Tx
+
+	sequences     map[string]*sqlite.SQLiteSequence
+	createdTables []string
+	createdViews  []string
+}
+
+func NewSQLiteDatabaseWriter(db *sql.DB) *SQLiteDatabaseWriter {
+	return &SQLiteDatabaseWriter{db: db, sequences: make(map[string]*sqlite.SQLiteSequence)}
+}
+
+func (writer *SQLiteDatabaseWriter) BeginTransaction() error {
+	if writer.tx != nil {
+		return services.ErrDatabaseTransactionAlreadyStarted
+	}
+	var err error
+	writer.tx, err = writer.db.Begin()
+	if err != nil {
+		return err
+	}
+
+	// Records are restored before every table exists, so foreign keys are checked when committing
+	if _, err := writer.tx.Exec("PRAGMA defer_foreign_keys = ON"); err != nil {
+		writer.tx.Rollback()
+		writer.tx = nil
+		return err
+	}
+
+	return nil
+}
+
+func (writer *SQLiteDatabaseWriter) CommitTransaction() error {
+	if writer.tx == nil {
+		return services.ErrDatabaseTransactionNotFound
+	}
+
+	err := writer.tx.Commit()
+	if err != nil {
+		return err
+	}
+
+	writer.tx = nil
+	return nil
+}
+
+func (writer *SQLiteDatabaseWriter) RollbackTransaction() error {
+	if writer.tx == nil {
+		return services.ErrDatabaseTransactionNotFound
+	}
+
+	err := writer.tx.Rollback()
+	if err != nil {
+		return err
+	}
+
+	writer.tx = nil
+	return nil
+}
+
+// SaveSchemaDependency saves the AUTOINCREMENT counter until its table is created,
+// as sqlite_sequence only holds counters of existing tables.
+func (writer *SQLiteDatabaseWriter) SaveSchemaDependency(dependency entities.SchemaDependency) error {
+	if writer.tx == nil {
+		return services.ErrDatabaseTransactionNotFound
+	}
+
+	sequence, ok := dependency.(*sqlite.SQLiteSequence)
+	if !ok {
+		return services.ErrDependencyNotSupported
+	}
+
+	writer.sequences[sequence.Name] = sequence
+	return nil
+}
+
+// SaveSchema creates the table with its constraints and foreign keys, as SQLite can not add them to an existing table.
+func (writer *SQLiteDatabaseWriter) SaveSchema(schema entities.Schema) error {
+	if writer.tx == nil {
+		return services.ErrDatabaseTransactionNotFound
+	}
+
+	table := schema.(*sql_entities.SQLTable)
+
+	// A primary key with AUTOINCREMENT can only be declared in its column
+	var autoIncrementColumn string
+	for _, c := range table.Constraints {
+		if c.Type == sql_entities.PrimaryKey && c.Definition != nil && strings.EqualFold(*c.Definition, "AUTOINCREMENT") && len(c.Columns) == 1 {
+			autoIncrementColumn = c.Columns[0]
+		}
+	}
+
+	definitions := []string{}
+	for _, col := range table.Columns {
+		definition := quoteIdentifier(col.Name)
+		if col.Type != "" {
+			definition += " " + col.Type
+		}
+		if col.Name == autoIncrementColumn {
+			definition += " PRIMARY KEY AUTOINCREMENT"
+		}
+		if !col.IsNullable {
+			definition += " NOT NULL"
+		}
+		if col.DefaultValue != nil {
+			definition += fmt.Sprintf(" DEFAULT %s", *col.DefaultValue)
+		}
+		definitions = append(definitions, definition)
+	}
+	for _, c := range table.Constraints {
+		switch c.Type {
+		case sql_entities.PrimaryKey:
+			if autoIncrementColumn == "" {
+				definitions = append(definitions, fmt.Sprintf("PRIMARY KEY (%s)", strings.Join(quoteIdentifiers(c.Columns), ", ")))
+			}
+		case sql_entities.Check:
+			definitions = append(definitions, fmt.Sprintf("CONSTRAINT %s CHECK (%s)", quoteIdentifier(c.Name), *c.Definition))
+		default:
+			definitions = append(definitions, fmt.Sprintf("%s (%s)", c.Type, strings.Join(quoteIdentifiers(c.Columns), ", ")))
+		}
+	}
+	for _, fk := range table.ForeignKeys {
+		definition := fmt.Sprintf("FOREIGN KEY (%s) REFERENCES %s", strings.Join(quoteIdentifiers(fk.Columns), ", "), quoteIdentifier(fk.ReferencedTable))
+		if len(fk.ReferencedColumns) > 0 {
+			definition += fmt.Sprintf(" (%s)", strings.Join(quoteIdentifiers(fk.ReferencedColumns), ", "))
+		}
+		definitions = append(definitions, fmt.Sprintf("%s ON UPDATE %s ON DELETE %s", definition, fk.UpdateAction, fk.DeleteAction))
+	}
+
+	query := fmt.Sprintf("CREATE TABLE %s (%s)", quoteIdentifier(table.Name), strings.Join(definitions, ", "))
+	if _, err := writer.tx.Exec(query); err != nil {
+		return err
+	}
+
+	writer.createdTables = append(writer.createdTables, table.Name)
+	return nil
+}
+
+func (writer *SQLiteDatabaseWriter) SaveSchemaRules(schema entities.Schema) error {
+	if writer.tx == nil {
+		return services.ErrDatabaseTransactionNotFound
+	}
+
+	table := schema.(*sql_entities.SQLTable)
+
+	for _, idx := range table.Indexes {
+		query, ok := idx.Options["definition"].(string)
+		if !ok {
+			kind := ""
+			if isUnique, ok := idx.Options["isUnique"].(bool); ok && isUnique {
+				kind = "UNIQUE "
+			}
+			query = fmt.Sprintf("CREATE %sINDEX %s ON %s (%s)", kind, quoteIdentifier(idx.Name), quoteIdentifier(table.Name), strings.Join(quoteIdentifiers(idx.Columns), ", "))
+		}
+
+		if _, err := writer.tx.Exec(query); err != nil {
+			return err
+		}
+	}
+
+	if sequence, ok := writer.sequences[table.Name]; ok {
+		if _, err := writer.tx.Exec("DELETE FROM sqlite_sequence WHERE name = ?", table.Name); err != nil {
+			return err
+		}
+		if _, err := writer.tx.Exec("INSERT INTO sqlite_sequence (name, seq) VALUES (?, ?)", table.Name, sequence.Value.Int64()); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// SaveSchemaConstraints does nothing, as SQLite foreign keys are declared when the table is created.
+func (writer *SQLiteDatabaseWriter) SaveSchemaConstraints(schema entities.Schema) error {
+	if writer.tx == nil {
+		return services.ErrDatabaseTransactionNotFound
+	}
+
+	return nil
+}
+
+// ValidateSchemaConstraints does nothing, as the foreign keys are checked when the transaction is committed.
+func (writer *SQLiteDatabaseWriter) ValidateSchemaConstraints(schema entities.Schema) error {
+	if writer.tx == nil {
+		return services.ErrDatabaseTransactionNotFound
+	}
+
+	return nil
+}
+
+func (writer *SQLiteDatabaseWriter) SaveSchemaRecords(schema entities.Schema, chunk entities.SchemaRecordChunk) error {
+	if writer.tx == nil {
+		return services.ErrDatabaseTransactionNotFound
+	}
+
+	table := schema.(*sql_entities.SQLTable)
+	recordChunk := chunk.(*sql_entities.SQLRecordChunk)
+	if len(recordChunk.Content) == 0 || len(table.Columns) == 0 {
+		return nil
+	}
+
+	columns := make([]string, len(table.Columns))
+	for i, col := range table.Columns {
+		columns[i] = col.Name
+	}
+
+	rowPlaceholders := fmt.Sprintf("(%s)", strings.TrimSuffix(strings.Repeat("?, ", len(columns)), ", "))
+	batchSize := maxStatementPlaceholders / len(columns)
+
+	for start := 0; start < len(recordChunk.Content); start += batchSize {
+		end := min(start+batchSize, len(recordChunk.Content))
+
+		placeholders := make([]string, 0, end-start)
+		args := make([]interface{}, 0, (end-start)*len(columns))
+		for _, record := range recordChunk.Content[start:end] {
+			placeholders = append(placeholders, rowPlaceholders)
+			for _, col := range table.Columns {
+				val := record.Content[col.Name]
+				switch v := val.(type) {
+				case string:
+					// Blobs are kept as strings in the backup, so they are converted back for the blob columns
+					if strings.Contains(strings.ToUpper(col.Type), "BLOB") {
+						val = []byte(v)
+					}
+				case time.Time:
+					val = v.Format(time.RFC3339)
+				}
+				args = append(args, val)
+			}
+		}
+
+		query := fmt.Sprintf("INSERT INTO %s (%s) VALUES %s", quoteIdentifier(table.Name), strings.Join(quoteIdentifiers(columns), ", "), strings.Join(placeholders, ", "))
+		if _, err := writer.tx.Exec(query, args...); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (writer *SQLiteDatabaseWriter) SaveRoutine(routine entities.Routine) error {
+	if writer.tx == nil {
+		return services.ErrDatabaseTransactionNotFound
+	}
+
+	var query string
+	switch r := routine.(type) {
+	case *sqlite.SQLiteView:
+		query = r.Definition
+	case *sqlite.SQLiteTrigger:
+		query = r.Definition
+	default:
+		return services.ErrBackupCorruptedFile
+	}
+
+	if _, err := writer.tx.Exec(query); err != nil {
+		return err
+	}
+
+	if routine.GetRoutineType() == entities.SQLiteView {
+		writer.createdViews = append(writer.createdViews, routine.GetName())
+	}
+	return nil
+}
+
+// DropCreatedObjects drops the views and tables created by the writer. Triggers are dropped together with their tables.
+func (writer *SQLiteDatabaseWriter) DropCreatedObjects() error {
+	if writer.tx != nil {
+		return services.ErrDatabaseTransactionAlreadyStarted
+	}
+
+	for i := len(writer.createdViews) - 1; i >= 0; i-- {
+		if _, err := writer.db.Exec(fmt.Sprintf("DROP VIEW IF EXISTS %s", quoteIdentifier(writer.createdViews[i]))); err != nil {
+			return err
+		}
+	}
+	for i := len(writer.createdTables) - 1; i >= 0; i-- {
+		if _, err := writer.db.Exec(fmt.Sprintf("DROP TABLE IF EXISTS %s", quoteIdentifier(writer.createdTables[i]))); err != nil {
+			return err
+		}
+	}
+
+	writer.createdViews = nil
+	writer.createdTables = nil
+	return nil
+}
+
+func (writer *SQLiteDatabaseWriter) KeepCreatedObjects() {
+	writer.createdTables = nil
+	writer.createdViews = nil
+}
+
+func (writer *SQLiteDatabaseWriter) CreateRestoreCheckpoint() error {
+	if writer.tx == nil {
+		return services.ErrDatabaseTransactionNotFound
+	}
+
+	_, err := writer.tx.Exec(fmt.Sprintf("CREATE TABLE %s (name TEXT PRIMARY KEY, value TEXT NOT NULL)", quoteIdentifier(database_services.RestoreCheckpointTable)))
+	return err
+}
+
+func (writer *SQLiteDatabaseWriter) SaveRestoreCheckpoint(name, value string) error {
+	if writer.tx == nil {
+		return services.ErrDatabaseTransactionNotFound
+	}
+
+	query := fmt.Sprintf("INSERT INTO %s (name, value) VALUES (?, ?) ON CONFLICT (name) DO UPDATE SET value = excluded.value", quoteIdentifier(database_services.RestoreCheckpointTable))
+	_, err := writer.tx.Exec(query, name, value)
+	return err
+}
+
+func (writer *SQLiteDatabaseWriter) GetRestoreCheckpoint() (map[string]string, error) {
+	var exists bool
+	if err := writer.db.QueryRow("SELECT COUNT(*) > 0 FROM sqlite_master WHERE type = 'table' AND name = ?", database_services.RestoreCheckpointTable).Scan(&exists); err != nil {
+		return nil, err
+	}
+	if !exists {
+		return nil, nil
+	}
+
+	rows, err := writer.db.Query(fmt.Sprintf("SELECT name, value FROM %s", quoteIdentifier(database_services.RestoreCheckpointTable)))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	entries := make(map[string]string)
+	for rows.Next() {
+		var name, value string
+		if err := rows.Scan(&name, &value); err != nil {
+			return nil, err
+		}
+		entries[name] = value
+	}
+	return entries, rows.Err()
+}
+
+func (writer *SQLiteDatabaseWriter) DropRestoreCheckpoint() error {
+	if writer.tx == nil {
+		return services.ErrDatabaseTransactionNotFound
+	}
+
+	_, err := writer.tx.Exec(fmt.Sprintf("DROP TABLE %s", quoteIdentifier(database_services.RestoreCheckpointTable)))
+	return err
+}
@@ -0,0 +1,162 @@
+package sqlite
+
+import (
+	"fmt"
+	sql_entities "historydb/src/internal/services/entities/sql"
+	"strings"
+)
+
+type sqlTokenKind int
+
+const (
+	wordToken sqlTokenKind = iota
+	quotedIdentifierToken
+	stringToken
+	symbolToken
+)
+
+// sqlToken is a token of a SQL statement. Its text is unquoted for quoted identifiers,
+// and start and end are its byte offsets in the statement.
+type sqlToken struct {
+	kind  sqlTokenKind
+	text  string
+	start int
+	end   int
+}
+
+// tokenizeSQL splits a SQL statement stored in sqlite_master into tokens, skipping whitespaces and comments.
+// It is not a full SQL parser, it only knows enough to find keywords and identifiers outside of strings.
+func tokenizeSQL(statement string) []sqlToken {
+	tokens := []sqlToken{}
+
+	for i := 0; i < len(statement); {
+		c := statement[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			i++
+		case c == '-' && i+1 < len(statement) && statement[i+1] == '-':
+			end := strings.IndexByte(statement[i:], '\n')
+			if end == -1 {
+				i = len(statement)
+			} else {
+				i += end + 1
+			}
+		case c == '/' && i+1 < len(statement) && statement[i+1] == '*':
+			end := strings.Index(statement[i+2:], "*/")
+			if end == -1 {
+				i = len(statement)
+			} else {
+				i += end + 4
+			}
+		case c == '\'' || c == '"' || c == '`' || c == '[':
+			closing := c
+			if c == '[' {
+				closing = ']'
+			}
+
+			j := i + 1
+			var text strings.Builder
+			for j < len(statement) {
+				if statement[j] == closing {
+					// Doubled quotes are escaped quotes
+					if closing != ']' && j+1 < len(statement) && statement[j+1] == closing {
+						text.WriteByte(closing)
+						j += 2
+						continue
+					}
+					break
+				}
+				text.WriteByte(statement[j])
+				j++
+			}
+
+			kind := quotedIdentifierToken
+			if c == '\'' {
+				kind = stringToken
+			}
+			end := min(j+1, len(statement))
+			tokens = append(tokens, sqlToken{kind: kind, text: text.String(), start: i, end: end})
+			i = end
+		case isWordByte(c):
+			j := i
+			for j < len(statement) && isWordByte(statement[j]) {
+				j++
+			}
+			tokens = append(tokens, sqlToken{kind: wordToken, text: statement[i:j], start: i, end: j})
+			i = j
+		default:
+			tokens = append(tokens, sqlToken{kind: symbolToken, text: string(c), start: i, end: i + 1})
+			i++
+		}
+	}
+
+	return tokens
+}
+
+func isWordByte(c byte) bool {
+	return c == '_' || c == '$' || c >= 0x80 || (c >= '0' && c <= '9') || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
+
+// extractCheckConstraints extracts the check constraints from a CREATE TABLE statement.
+// Column and table check constraints are returned as table constraints, and the unnamed ones are named
+// after the table and their position.
+func extractCheckConstraints(tableName, createTable string, tokens []sqlToken) []sql_entities.SQLTableConstraint {
+	constraints := []sql_entities.SQLTableConstraint{}
+
+	depth := 0
+	for i := 0; i < len(tokens); i++ {
+		token := tokens[i]
+		if token.kind == symbolToken && token.text == "(" {
+			depth++
+			continue
+		}
+		if token.kind == symbolToken && token.text == ")" {
+			depth--
+			continue
+		}
+
+		// Check constraints can only be defined inside the column list
+		if depth != 1 || token.kind != wordToken || !strings.EqualFold(token.text, "CHECK") {
+			continue
+		}
+		if i+1 >= len(tokens) || tokens[i+1].kind != symbolToken || tokens[i+1].text != "(" {
+			continue
+		}
+
+		// Looks for the parenthesis closing the check expression
+		closing := -1
+		checkDepth := 0
+		for j := i + 1; j < len(tokens); j++ {
+			if tokens[j].kind != symbolToken {
+				continue
+			}
+			if tokens[j].text == "(" {
+				checkDepth++
+			} else if tokens[j].text == ")" {
+				checkDepth--
+				if checkDepth == 0 {
+					closing = j
+					break
+				}
+			}
+		}
+		if closing == -1 {
+			break
+		}
+
+		name := fmt.Sprintf("%s_check%d", tableName, len(constraints)+1)
+		if i >= 2 && tokens[i-2].kind == wordToken && strings.EqualFold(tokens[i-2].text, "CONSTRAINT") {
+			name = tokens[i-1].text
+		}
+		definition := strings.TrimSpace(createTable[tokens[i+1].end:tokens[closing].start])
+
+		constraints = append(constraints, sql_entities.SQLTableConstraint{
+			Type:       sql_entities.Check,
+			Name:       name,
+			Definition: &definition,
+		})
+		i = closing
+	}
+
+	return constraints
+}
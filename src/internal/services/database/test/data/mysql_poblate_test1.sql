@@ -0,0 +1,43 @@
+CREATE TABLE authors (
+    id      INT NOT NULL AUTO_INCREMENT,
+    name    VARCHAR(50) NOT NULL,
+    country CHAR(2) DEFAULT 'ES',
+    PRIMARY KEY (id),
+    CONSTRAINT authors_name_uk UNIQUE (name)
+);
+
+CREATE TABLE books (
+    id        INT NOT NULL AUTO_INCREMENT,
+    author_id INT NOT NULL,
+    title     VARCHAR(100) NOT NULL,
+    price     DECIMAL(6,2),
+    published DATE,
+    PRIMARY KEY (id),
+    INDEX books_author_idx (author_id),
+    INDEX books_title_idx (title),
+    CONSTRAINT books_price_ck CHECK (price >= 0),
+    CONSTRAINT books_author_fk FOREIGN KEY (author_id) REFERENCES authors (id) ON UPDATE CASCADE ON DELETE CASCADE
+);
+
+INSERT INTO authors (id, name, country) VALUES
+    (1, 'Miguel de Cervantes', 'ES'),
+    (2, 'J. R. R. Tolkien', 'GB'),
+    (3, 'Jane Austen', NULL);
+
+INSERT INTO books (id, author_id, title, price, published) VALUES
+    (1, 1, 'Don Quijote', 12.50, '1605-01-16'),
+    (2, 2, 'The Hobbit', 9.99, '1937-09-21'),
+    (3, 2, 'The Lord of the Rings', 25.00, '1954-07-29'),
+    (4, 3, 'Pride and Prejudice', NULL, NULL);
+
+CREATE FUNCTION book_count(author INT) RETURNS INT READS SQL DATA
+    RETURN (SELECT COUNT(*) FROM books WHERE author_id = author);
+
+CREATE PROCEDURE rename_author(IN author INT, IN new_name VARCHAR(50))
+    UPDATE authors SET name = new_name WHERE id = author;
+
+CREATE TRIGGER books_title_trim BEFORE INSERT ON books FOR EACH ROW
+    SET NEW.title = TRIM(NEW.title);
+
+CREATE EVENT books_price_cleanup ON SCHEDULE EVERY 1 DAY STARTS '2099-01-01 00:00:00'
+    DO DELETE FROM books WHERE price IS NULL;
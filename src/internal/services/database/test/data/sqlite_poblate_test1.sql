@@ -0,0 +1,31 @@
+CREATE TABLE "users" (
+    "id"          INTEGER PRIMARY KEY AUTOINCREMENT,
+    "username"    VARCHAR(16) NOT NULL UNIQUE,
+    "email"       VARCHAR(50) NOT NULL,
+    "age"         INTEGER CHECK ("age" >= 0),
+    "active"      BOOLEAN NOT NULL DEFAULT 1
+);
+
+CREATE TABLE "posts" (
+    "user_id"     INTEGER NOT NULL,
+    "slug"        TEXT NOT NULL,
+    "content"     TEXT,
+    "cover"       BLOB,
+    CONSTRAINT "posts_pk" PRIMARY KEY ("user_id", "slug"),
+    CONSTRAINT "posts_user_fk" FOREIGN KEY ("user_id") REFERENCES "users" ("id") ON DELETE CASCADE
+);
+
+CREATE INDEX "posts_content_idx" ON "posts" ("content");
+
+CREATE TABLE "audit" (
+    "message"     TEXT NOT NULL
+);
+
+CREATE VIEW "active_users" AS SELECT "id", "username" FROM "users" WHERE "active" = 1;
+
+CREATE VIEW "active_usernames" AS SELECT "username" FROM "active_users";
+
+CREATE TRIGGER "users_audit" AFTER INSERT ON "users" BEGIN INSERT INTO "audit" VALUES ('new user ' || NEW."username"); END;
+
+INSERT INTO "users" ("username", "email", "age") VALUES ('alice', 'alice@test.com', 30), ('bob', 'bob@test.com', NULL), ('carol', 'carol@test.com', 25);
+INSERT INTO "posts" VALUES (1, 'hello', 'Hello world', X'CAFE'), (1, 'bye', NULL, NULL), (3, 'first', 'First post', NULL);
@@ -0,0 +1,256 @@
+package test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	services "historydb/src/internal/services/database"
+	"historydb/src/internal/services/database/mongo"
+	mongo_entities "historydb/src/internal/services/entities/mongo"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/testcontainers/testcontainers-go"
+	"github.com/testcontainers/testcontainers-go/wait"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	mongo_driver "go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+type MongoExpectedData struct {
+	IsEmpty           bool                              `json:"isEmpty"`
+	Collections       []mongo_entities.MongoCollection  `json:"collections"`
+	CollectionContent map[string]MongoCollectionContent `json:"collectionContent"`
+}
+
+type MongoCollectionContent struct {
+	Length          int                      `json:"length"`
+	MaxDocumentSize int                      `json:"maxDocumentSize"`
+	ChunkSize       int                      `json:"chunkSize"`
+	Documents       []map[string]interface{} `json:"documents"`
+}
+
+// MongoPoblateData holds the collections, written in Extended JSON, used to poblate a test database.
+type MongoPoblateData struct {
+	Collections []struct {
+		Name      string            `json:"name"`
+		Options   json.RawMessage   `json:"options"`
+		Indexes   []json.RawMessage `json:"indexes"`
+		Documents []json.RawMessage `json:"documents"`
+	} `json:"collections"`
+}
+
+func TestMongoReader(t *testing.T) {
+	testData, err := extractJSONTestData("data/mongo_test_data.json")
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+
+	for _, test := range testData {
+		db, cleanup, err := setupMongoTestContainer(t, test.Image, test.InitScript, test.DBName)
+		if err == nil {
+			var expectedData MongoExpectedData
+			expectedDataBytes, _ := json.Marshal(test.ExpectedData)
+			if err := json.Unmarshal(expectedDataBytes, &expectedData); err != nil {
+				t.Fatal("could not decode expected data", err)
+			}
+
+			dbReader := mongo.NewMongoDatabaseReader(db)
+			testCheckDBIsEmpty(t, test.Name, dbReader, expectedData.IsEmpty)
+			testListMongoSchemaDependencies(t, test.Name, dbReader)
+			testListMongoSchemaNames(t, test.Name, dbReader, expectedData.Collections)
+			testGetMongoSchemaDefinition(t, test.Name, dbReader, expectedData.Collections)
+			testGetMongoSchemaRecordMetadata(t, test.Name, dbReader, expectedData.CollectionContent)
+			testGetMongoSchemaRecordChunk(t, test.Name, dbReader, expectedData.CollectionContent)
+			testListRoutines(t, test.Name, dbReader, nil)
+
+			cleanup()
+		} else {
+			fmt.Println(err)
+		}
+	}
+}
+
+func setupMongoTestContainer(t *testing.T, image string, initScript *string, dbName string) (*mongo_driver.Database, func(), error) {
+	ctx := context.Background()
+
+	req := testcontainers.ContainerRequest{
+		Image:        image,
+		ExposedPorts: []string{"27017/tcp"},
+		WaitingFor:   wait.ForLog("Waiting for connections").WithStartupTimeout(60 * time.Second),
+	}
+
+	container, err := testcontainers.GenericContainer(ctx, testcontainers.GenericContainerRequest{
+		ContainerRequest: req,
+		Started:          true,
+	})
+	if err != nil {
+		t.Fatalf("could not generate testcontainer for: %s | %v", image, err)
+	}
+
+	host, err := container.Host(ctx)
+	if err != nil {
+		t.Fatalf("could not get testcontainer host: %v", err)
+	}
+
+	port, err := container.MappedPort(ctx, "27017/tcp")
+	if err != nil {
+		t.Fatalf("could not get testcontainer mapped port: %v", err)
+	}
+
+	client, err := mongo_driver.Connect(options.Client().ApplyURI(fmt.Sprintf("mongodb://%s:%s/%s", host, port.Port(), dbName)))
+	if err != nil {
+		t.Fatalf("failed to connect to mongo database: %v", err)
+	}
+	db := client.Database(dbName)
+
+	cleanup := func() {
+		client.Disconnect(ctx)
+		container.Terminate(ctx)
+	}
+
+	if initScript != nil {
+		if err := poblateMongoTestDatabase(db, *initScript); err != nil {
+			cleanup()
+			return nil, nil, err
+		}
+	}
+
+	return db, cleanup, nil
+}
+
+// poblateMongoTestDatabase creates the collections, indexes and documents of the given file in the database.
+func poblateMongoTestDatabase(db *mongo_driver.Database, initScript string) error {
+	ctx := context.Background()
+
+	content, err := os.ReadFile(initScript)
+	if err != nil {
+		return fmt.Errorf("init script file not found")
+	}
+
+	var poblateData MongoPoblateData
+	if err := json.Unmarshal(content, &poblateData); err != nil {
+		return err
+	}
+
+	for _, collection := range poblateData.Collections {
+		command := bson.D{{Key: "create", Value: collection.Name}}
+		if collection.Options != nil {
+			var collectionOptions bson.D
+			if err := bson.UnmarshalExtJSON(collection.Options, false, &collectionOptions); err != nil {
+				return err
+			}
+			command = append(command, collectionOptions...)
+		}
+		if err := db.RunCommand(ctx, command).Err(); err != nil {
+			return err
+		}
+
+		if len(collection.Indexes) > 0 {
+			indexes := bson.A{}
+			for _, index := range collection.Indexes {
+				var indexSpec bson.D
+				if err := bson.UnmarshalExtJSON(index, false, &indexSpec); err != nil {
+					return err
+				}
+				indexes = append(indexes, indexSpec)
+			}
+			if err := db.RunCommand(ctx, bson.D{{Key: "createIndexes", Value: collection.Name}, {Key: "indexes", Value: indexes}}).Err(); err != nil {
+				return err
+			}
+		}
+
+		if len(collection.Documents) > 0 {
+			documents := make([]interface{}, 0, len(collection.Documents))
+			for _, document := range collection.Documents {
+				var doc bson.D
+				if err := bson.UnmarshalExtJSON(document, false, &doc); err != nil {
+					return err
+				}
+				documents = append(documents, doc)
+			}
+			if _, err := db.Collection(collection.Name).InsertMany(ctx, documents); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+func testListMongoSchemaDependencies(t *testing.T, testName string, dbReader services.DatabaseReader) {
+	schemaDependencies, err := dbReader.ListSchemaDependencies()
+	assert.Nil(t, err, fmt.Sprintf("ListSchemaDependencies - Test: %s", testName))
+	assert.Empty(t, schemaDependencies, fmt.Sprintf("ListSchemaDependencies - Test: %s", testName))
+}
+
+func testListMongoSchemaNames(t *testing.T, testName string, dbReader services.DatabaseReader, expectedData []mongo_entities.MongoCollection) {
+	schemaNames, err := dbReader.ListSchemaNames()
+	assert.Nil(t, err, fmt.Sprintf("ListSchemaNames - Test: %s", testName))
+	if assert.Equal(t, len(expectedData), len(schemaNames), fmt.Sprintf("ListSchemaNames - Test: %s", testName)) {
+		for idx, collectionName := range schemaNames {
+			assert.Equal(t, expectedData[idx].Name, collectionName, fmt.Sprintf("ListSchemaNames - Test: %s", testName))
+		}
+	}
+}
+
+func testGetMongoSchemaDefinition(t *testing.T, testName string, dbReader services.DatabaseReader, expectedData []mongo_entities.MongoCollection) {
+	for _, expectedCollection := range expectedData {
+		schema, err := dbReader.GetSchemaDefinition(expectedCollection.Name)
+		assert.Nil(t, err, fmt.Sprintf("GetSchemaDefinition - Test: %s", testName))
+		assert.NotNil(t, schema, fmt.Sprintf("GetSchemaDefinition - Test: %s", testName))
+
+		if schema != nil {
+			assert.Equal(t, expectedCollection, *schema.(*mongo_entities.MongoCollection), fmt.Sprintf("GetSchemaDefinition - Test: %v", testName))
+		}
+	}
+}
+
+func testGetMongoSchemaRecordMetadata(t *testing.T, testName string, dbReader services.DatabaseReader, expectedData map[string]MongoCollectionContent) {
+	for collectionName, expectedCollectionData := range expectedData {
+		metadata, err := dbReader.GetSchemaRecordMetadata(collectionName)
+		assert.Nil(t, err, fmt.Sprintf("GetSchemaRecordMetadata - Test: %s", testName))
+		assert.Equal(t, expectedCollectionData.Length, metadata.Count, fmt.Sprintf("GetSchemaRecordMetadata - Test: %s", testName))
+		assert.Equal(t, expectedCollectionData.MaxDocumentSize, metadata.MaxRecordSize, fmt.Sprintf("GetSchemaRecordMetadata - Test: %s", testName))
+	}
+}
+
+func testGetMongoSchemaRecordChunk(t *testing.T, testName string, dbReader services.DatabaseReader, expectedData map[string]MongoCollectionContent) {
+	for collectionName, expectedCollectionData := range expectedData {
+		schema, err := dbReader.GetSchemaDefinition(collectionName)
+		assert.Nil(t, err, fmt.Sprintf("GetSchemaRecordChunk - GetSchemaDefinition - Test: %s", testName))
+		assert.NotNil(t, schema, fmt.Sprintf("GetSchemaRecordChunk - GetSchemaDefinition - Test: %s", testName))
+
+		var cursor interface{} = nil
+		expectedDocumentIndex := 0
+		for {
+			chunk, nextCursor, err := dbReader.GetSchemaRecordChunk(schema, int64(expectedCollectionData.ChunkSize), cursor)
+			assert.Nil(t, err, fmt.Sprintf("GetSchemaRecordChunk - Test: %s", testName))
+
+			documentChunk := chunk.(*mongo_entities.MongoDocumentChunk)
+			if len(documentChunk.Content) == 0 {
+				break
+			}
+			assert.LessOrEqual(t, len(documentChunk.Content), expectedCollectionData.ChunkSize, fmt.Sprintf("GetSchemaRecordChunk - Test: %s", testName))
+
+			for _, document := range documentChunk.Content {
+				if !assert.Less(t, expectedDocumentIndex, len(expectedCollectionData.Documents), fmt.Sprintf("GetSchemaRecordChunk - Test: %s", testName)) {
+					return
+				}
+
+				// Documents are compared as relaxed Extended JSON, so the BSON types without a JSON type are still checked
+				documentJSON, err := bson.MarshalExtJSON(bson.Raw(document.Content), false, false)
+				assert.Nil(t, err, fmt.Sprintf("GetSchemaRecordChunk - Test: %s", testName))
+				expectedJSON, _ := json.Marshal(expectedCollectionData.Documents[expectedDocumentIndex])
+				assert.JSONEq(t, string(expectedJSON), string(documentJSON), fmt.Sprintf("GetSchemaRecordChunk - Test: %v", testName))
+				expectedDocumentIndex++
+			}
+
+			cursor = nextCursor
+		}
+		assert.Equal(t, len(expectedCollectionData.Documents), expectedDocumentIndex, fmt.Sprintf("GetSchemaRecordChunk - Test: %s", testName))
+	}
+}
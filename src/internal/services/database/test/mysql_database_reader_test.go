@@ -0,0 +1,173 @@
+package test
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	services "historydb/src/internal/services/database"
+	"historydb/src/internal/services/database/mysql"
+	mysql_entities "historydb/src/internal/services/entities/mysql"
+	sql_entities "historydb/src/internal/services/entities/sql"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	_ "github.com/go-sql-driver/mysql"
+	"github.com/stretchr/testify/assert"
+	"github.com/testcontainers/testcontainers-go"
+	"github.com/testcontainers/testcontainers-go/wait"
+)
+
+type MySQLExpectedData struct {
+	IsEmpty        bool                                `json:"isEmpty"`
+	AutoIncrements []mysql_entities.MySQLAutoIncrement `json:"autoIncrements,omitempty"`
+	Tables         []sql_entities.SQLTable             `json:"tables"`
+	TableContent   map[string]PSQLTableContent         `json:"tableContent"`
+	Routines       []MySQLRoutineType                  `json:"routines"`
+}
+
+type MySQLRoutineType struct {
+	Type string `json:"type"`
+	Name string `json:"name"`
+}
+
+func TestMySQLReader(t *testing.T) {
+	testData, err := extractJSONTestData("data/mysql_test_data.json")
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+
+	for _, test := range testData {
+		db, cleanup, err := setupMySQLTestContainer(t, test.Image, test.InitScript, test.DBName)
+		if err == nil {
+			var expectedData MySQLExpectedData
+			expectedDataBytes, _ := json.Marshal(test.ExpectedData)
+			if err := json.Unmarshal(expectedDataBytes, &expectedData); err != nil {
+				t.Fatal("could not decode expected data", err)
+			}
+
+			dbReader := mysql.NewMySQLDatabaseReader(db)
+			testCheckDBIsEmpty(t, test.Name, dbReader, expectedData.IsEmpty)
+			testListMySQLSchemaDependencies(t, test.Name, dbReader, expectedData.AutoIncrements)
+			testListSchemaNames(t, test.Name, dbReader, expectedData.Tables)
+			testGetSchemaDefinition(t, test.Name, dbReader, expectedData.Tables)
+			testGetSchemaRecordMetadata(t, test.Name, dbReader, expectedData.TableContent)
+			testGetSchemaRecordChunk(t, test.Name, dbReader, expectedData.TableContent)
+			testListMySQLRoutines(t, test.Name, dbReader, expectedData.Routines)
+			testReaderTransaction(t, test.Name, dbReader)
+
+			cleanup()
+		} else {
+			fmt.Println(err)
+		}
+	}
+}
+
+func setupMySQLTestContainer(t *testing.T, image string, initScript *string, dbName string) (*sql.DB, func(), error) {
+	ctx := context.Background()
+
+	// The routines are read as root, as MySQL only shows their definition to their definer or privileged users
+	req := testcontainers.ContainerRequest{
+		Image:        image,
+		ExposedPorts: []string{"3306/tcp"},
+		Env:          map[string]string{"MYSQL_ROOT_PASSWORD": "test", "MYSQL_DATABASE": dbName},
+		WaitingFor:   wait.ForListeningPort("3306/tcp").WithStartupTimeout(120 * time.Second),
+	}
+	if initScript != nil {
+		if _, err := os.Stat(*initScript); err != nil {
+			return nil, nil, fmt.Errorf("init script file not found")
+		}
+
+		req.Files = []testcontainers.ContainerFile{{
+			HostFilePath:      *initScript,
+			ContainerFilePath: "/docker-entrypoint-initdb.d/init.sql",
+			FileMode:          0o644,
+		}}
+	}
+
+	container, err := testcontainers.GenericContainer(ctx, testcontainers.GenericContainerRequest{
+		ContainerRequest: req,
+		Started:          true,
+	})
+	if err != nil {
+		t.Fatalf("could not generate testcontainer for: %s | %v", image, err)
+	}
+
+	host, err := container.Host(ctx)
+	if err != nil {
+		t.Fatalf("could not get testcontainer host: %v", err)
+	}
+
+	port, err := container.MappedPort(ctx, "3306/tcp")
+	if err != nil {
+		t.Fatalf("could not get testcontainer mapped port: %v", err)
+	}
+
+	dsn, err := mysql.ParseConnString(fmt.Sprintf("mysql://root:test@%s:%s/%s", host, port.Port(), dbName))
+	if err != nil {
+		t.Fatalf("could not parse db url: %v", err)
+	}
+
+	db, err := sql.Open("mysql", dsn)
+	if err != nil {
+		t.Fatalf("failed to connect to mysql database: %v", err)
+	}
+
+	cleanup := func() {
+		db.Close()
+		container.Terminate(ctx)
+	}
+
+	return db, cleanup, nil
+}
+
+func testListMySQLSchemaDependencies(t *testing.T, testName string, dbReader services.DatabaseReader, expectedData []mysql_entities.MySQLAutoIncrement) {
+	schemaDependencies, err := dbReader.ListSchemaDependencies()
+	assert.Nil(t, err, fmt.Sprintf("ListSchemaDependencies - Test: %s", testName))
+	if assert.Equal(t, len(expectedData), len(schemaDependencies), fmt.Sprintf("ListSchemaDependencies - Test: %s", testName)) {
+		for idx, dependency := range schemaDependencies {
+			assert.Equal(t, expectedData[idx].Name, dependency.(*mysql_entities.MySQLAutoIncrement).Name, fmt.Sprintf("ListSchemaDependencies - Test: %s", testName))
+			assert.Equal(t, 0, expectedData[idx].Value.Cmp(&dependency.(*mysql_entities.MySQLAutoIncrement).Value.Int), fmt.Sprintf("ListSchemaDependencies - Test: %s", testName))
+		}
+	}
+}
+
+// testListMySQLRoutines checks the name and statement of every routine. Their definitions are formatted by the server, which
+// differs between versions, so only the CREATE statement they begin with and the missing DEFINER clause are checked.
+func testListMySQLRoutines(t *testing.T, testName string, dbReader services.DatabaseReader, expectedRoutines []MySQLRoutineType) {
+	routines, err := dbReader.ListRoutines()
+	assert.Nil(t, err, fmt.Sprintf("ListRoutines - Test: %s", testName))
+	if !assert.Equal(t, len(expectedRoutines), len(routines), fmt.Sprintf("ListRoutines - Test: %s", testName)) {
+		return
+	}
+
+	for i, routine := range routines {
+		var definition string
+		switch expectedRoutines[i].Type {
+		case "function":
+			function, ok := routine.(*mysql_entities.MySQLFunction)
+			assert.True(t, ok, fmt.Sprintf("ListRoutines - Test: %s", testName))
+			definition = function.Definition
+		case "procedure":
+			procedure, ok := routine.(*mysql_entities.MySQLProcedure)
+			assert.True(t, ok, fmt.Sprintf("ListRoutines - Test: %s", testName))
+			definition = procedure.Definition
+		case "trigger":
+			trigger, ok := routine.(*mysql_entities.MySQLTrigger)
+			assert.True(t, ok, fmt.Sprintf("ListRoutines - Test: %s", testName))
+			definition = trigger.Definition
+		case "event":
+			event, ok := routine.(*mysql_entities.MySQLEvent)
+			assert.True(t, ok, fmt.Sprintf("ListRoutines - Test: %s", testName))
+			definition = event.Definition
+		}
+
+		statement := fmt.Sprintf("CREATE %s", strings.ToUpper(expectedRoutines[i].Type))
+		assert.Equal(t, expectedRoutines[i].Name, routine.GetName(), fmt.Sprintf("ListRoutines - Test: %s", testName))
+		assert.True(t, strings.HasPrefix(definition, statement), fmt.Sprintf("ListRoutines - Test: %s | %s", testName, definition))
+		assert.NotContains(t, strings.ToUpper(definition), "DEFINER", fmt.Sprintf("ListRoutines - Test: %s", testName))
+	}
+}
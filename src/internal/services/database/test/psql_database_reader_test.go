@@ -6,6 +6,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"historydb/src/internal/entities"
+	service_errors "historydb/src/internal/services"
 	services "historydb/src/internal/services/database"
 	"historydb/src/internal/services/database/psql"
 	psql_entities "historydb/src/internal/services/entities/psql"
@@ -16,7 +17,7 @@ import (
 	"testing"
 	"time"
 
-	_ "github.com/lib/pq"
+	_ "github.com/jackc/pgx/v5/stdlib"
 	"github.com/stretchr/testify/assert"
 	"github.com/testcontainers/testcontainers-go"
 	"github.com/testcontainers/testcontainers-go/wait"
@@ -97,6 +98,8 @@ func TestPSQLReader(t *testing.T) {
 				}
 			}
 			testListRoutines(t, test.Name, dbReader, expectedRoutines)
+			testReaderTransaction(t, test.Name, dbReader)
+			testPSQLExportedSnapshot(t, test.Name, db, dbReader, expectedData.Sequences)
 
 			cleanup()
 		} else {
@@ -154,7 +157,7 @@ func setupPSQLTestContainer(t *testing.T, image string, initScript *string, dbNa
 		t.Fatalf("could not parse db url: %v", err)
 	}
 
-	db, err := sql.Open("postgres", dsn)
+	db, err := sql.Open("pgx", dsn)
 	if err != nil {
 		t.Fatalf("failed to connect to postgres database: %v", err)
 	}
@@ -172,6 +175,33 @@ func testCheckDBIsEmpty(t *testing.T, testName string, dbReader services.Databas
 	assert.Equal(t, expectedData, isEmpty, fmt.Sprintf("CheckDBIsEmpty - Test: %s", testName))
 }
 
+func testReaderTransaction(t *testing.T, testName string, dbReader services.DatabaseReader) {
+	assert.ErrorIs(t, dbReader.EndTransaction(), service_errors.ErrDatabaseTransactionNotFound, fmt.Sprintf("EndTransaction - Test: %s", testName))
+	assert.Nil(t, dbReader.BeginTransaction(), fmt.Sprintf("BeginTransaction - Test: %s", testName))
+	assert.ErrorIs(t, dbReader.BeginTransaction(), service_errors.ErrDatabaseTransactionAlreadyStarted, fmt.Sprintf("BeginTransaction - Test: %s", testName))
+	assert.Nil(t, dbReader.EndTransaction(), fmt.Sprintf("EndTransaction - Test: %s", testName))
+}
+
+func testPSQLExportedSnapshot(t *testing.T, testName string, db *sql.DB, dbReader *psql.PSQLDatabaseReader, expectedData []psql_entities.PSQLSequence) {
+	assert.Empty(t, dbReader.ExportedSnapshot(), fmt.Sprintf("ExportedSnapshot - Test: %s", testName))
+	if !assert.Nil(t, dbReader.BeginTransaction(), fmt.Sprintf("ExportedSnapshot - Test: %s", testName)) {
+		return
+	}
+	defer dbReader.EndTransaction()
+
+	snapshotId := dbReader.ExportedSnapshot()
+	assert.NotEmpty(t, snapshotId, fmt.Sprintf("ExportedSnapshot - Test: %s", testName))
+	testListSchemaDependencies(t, testName, dbReader, expectedData)
+
+	// A reader importing the snapshot reads through another connection of the pool
+	snapshotReader := psql.NewPSQLDatabaseReaderFromSnapshot(db, snapshotId)
+	if assert.Nil(t, snapshotReader.BeginTransaction(), fmt.Sprintf("ExportedSnapshot - Test: %s", testName)) {
+		assert.Equal(t, snapshotId, snapshotReader.ExportedSnapshot(), fmt.Sprintf("ExportedSnapshot - Test: %s", testName))
+		testListSchemaDependencies(t, testName, snapshotReader, expectedData)
+		assert.Nil(t, snapshotReader.EndTransaction(), fmt.Sprintf("ExportedSnapshot - Test: %s", testName))
+	}
+}
+
 func testListSchemaDependencies(t *testing.T, testName string, dbReader services.DatabaseReader, expectedData []psql_entities.PSQLSequence) {
 	schemaDependencies, err := dbReader.ListSchemaDependencies()
 	assert.Nil(t, err, fmt.Sprintf("ListSchemaDependencies - Test: %s", testName))
@@ -256,6 +286,22 @@ func testGetSchemaRecordChunk(t *testing.T, testName string, dbReader services.D
 							}
 						}
 						assert.Equal(t, v, int64(recordValue.(float64)), fmt.Sprintf("GetSchemaRecordChunk - Test: %v", testName))
+					case types.Date:
+						var recordValue interface{}
+						for key, value := range expectedTableData.Records[expectedRecordIndex] {
+							if key == k {
+								recordValue = value
+							}
+						}
+						assert.Equal(t, a.String(), recordValue, fmt.Sprintf("GetSchemaRecordChunk - Test: %v", testName))
+					case types.Decimal:
+						var recordValue interface{}
+						for key, value := range expectedTableData.Records[expectedRecordIndex] {
+							if key == k {
+								recordValue = value
+							}
+						}
+						assert.Equal(t, string(a), recordValue, fmt.Sprintf("GetSchemaRecordChunk - Test: %v", testName))
 					default:
 						var recordValue interface{}
 						for key, value := range expectedTableData.Records[expectedRecordIndex] {
@@ -0,0 +1,125 @@
+package test
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"historydb/src/internal/entities"
+	services "historydb/src/internal/services/database"
+	"historydb/src/internal/services/database/sqlite"
+	sql_entities "historydb/src/internal/services/entities/sql"
+	sqlite_entities "historydb/src/internal/services/entities/sqlite"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	_ "modernc.org/sqlite"
+)
+
+type SQLiteExpectedData struct {
+	IsEmpty      bool                             `json:"isEmpty"`
+	Sequences    []sqlite_entities.SQLiteSequence `json:"sequences,omitempty"`
+	Tables       []sql_entities.SQLTable          `json:"tables"`
+	TableContent map[string]PSQLTableContent      `json:"tableContent"`
+	Routines     []interface{}                    `json:"routines"`
+}
+
+type SQLiteRoutineType struct {
+	Type string                 `json:"type"`
+	Data map[string]interface{} `json:"data"`
+}
+
+func TestSQLiteReader(t *testing.T) {
+	testData, err := extractJSONTestData("data/sqlite_test_data.json")
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+
+	for _, test := range testData {
+		db, cleanup, err := setupSQLiteTestDatabase(t, test.InitScript, test.DBName)
+		if err == nil {
+			var expectedData SQLiteExpectedData
+			expectedDataBytes, _ := json.Marshal(test.ExpectedData)
+			if err := json.Unmarshal(expectedDataBytes, &expectedData); err != nil {
+				t.Fatal("could not decode expected data", err)
+			}
+
+			dbReader := sqlite.NewSQLiteDatabaseReader(db)
+			testCheckDBIsEmpty(t, test.Name, dbReader, expectedData.IsEmpty)
+			testListSQLiteSchemaDependencies(t, test.Name, dbReader, expectedData.Sequences)
+			testListSchemaNames(t, test.Name, dbReader, expectedData.Tables)
+			testGetSchemaDefinition(t, test.Name, dbReader, expectedData.Tables)
+			testGetSchemaRecordMetadata(t, test.Name, dbReader, expectedData.TableContent)
+			testGetSchemaRecordChunk(t, test.Name, dbReader, expectedData.TableContent)
+
+			expectedRoutines := make([]entities.Routine, 0, len(expectedData.Routines))
+			for _, routine := range expectedData.Routines {
+				var routineInfo SQLiteRoutineType
+				routineInfoBytes, _ := json.Marshal(routine)
+				if err := json.Unmarshal(routineInfoBytes, &routineInfo); err != nil {
+					t.Fatal("could not decode routine", err)
+				}
+
+				routineDataBytes, _ := json.Marshal(routineInfo.Data)
+				switch routineInfo.Type {
+				case "view":
+					var routineData sqlite_entities.SQLiteView
+					if err := json.Unmarshal(routineDataBytes, &routineData); err != nil {
+						t.Fatal("could not decode routine", err)
+					}
+					expectedRoutines = append(expectedRoutines, &routineData)
+				case "trigger":
+					var routineData sqlite_entities.SQLiteTrigger
+					if err := json.Unmarshal(routineDataBytes, &routineData); err != nil {
+						t.Fatal("could not decode routine", err)
+					}
+					expectedRoutines = append(expectedRoutines, &routineData)
+				}
+			}
+			testListRoutines(t, test.Name, dbReader, expectedRoutines)
+			testReaderTransaction(t, test.Name, dbReader)
+
+			cleanup()
+		} else {
+			fmt.Println(err)
+		}
+	}
+}
+
+func setupSQLiteTestDatabase(t *testing.T, initScript *string, dbName string) (*sql.DB, func(), error) {
+	db, err := sql.Open("sqlite", filepath.Join(t.TempDir(), dbName))
+	if err != nil {
+		t.Fatalf("failed to open sqlite database: %v", err)
+	}
+
+	if initScript != nil {
+		script, err := os.ReadFile(*initScript)
+		if err != nil {
+			db.Close()
+			return nil, nil, fmt.Errorf("init script file not found")
+		}
+
+		if _, err := db.Exec(string(script)); err != nil {
+			t.Fatalf("could not run init script: %v", err)
+		}
+	}
+
+	cleanup := func() {
+		db.Close()
+	}
+
+	return db, cleanup, nil
+}
+
+func testListSQLiteSchemaDependencies(t *testing.T, testName string, dbReader services.DatabaseReader, expectedData []sqlite_entities.SQLiteSequence) {
+	schemaDependencies, err := dbReader.ListSchemaDependencies()
+	assert.Nil(t, err, fmt.Sprintf("ListSchemaDependencies - Test: %s", testName))
+	if assert.Equal(t, len(expectedData), len(schemaDependencies), fmt.Sprintf("ListSchemaDependencies - Test: %s", testName)) {
+		for idx, dependency := range schemaDependencies {
+			assert.Equal(t, expectedData[idx].Name, dependency.(*sqlite_entities.SQLiteSequence).Name, fmt.Sprintf("ListSchemaDependencies - Test: %s", testName))
+			assert.Equal(t, 0, expectedData[idx].Value.Cmp(&dependency.(*sqlite_entities.SQLiteSequence).Value.Int), fmt.Sprintf("ListSchemaDependencies - Test: %s", testName))
+		}
+	}
+}
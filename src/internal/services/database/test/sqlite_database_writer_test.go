@@ -0,0 +1,61 @@
+package test
+
+import (
+	"historydb/src/internal/entities"
+	"historydb/src/internal/services/database/sqlite"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSQLiteWriterRestoreCheckpoint(t *testing.T) {
+	db, cleanup, err := setupSQLiteTestDatabase(t, nil, "checkpoint.db")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cleanup()
+
+	dbWriter := sqlite.NewSQLiteDatabaseWriter(db)
+
+	entries, err := dbWriter.GetRestoreCheckpoint()
+	assert.NoError(t, err)
+	assert.Nil(t, entries, "a database without a restore has no checkpoint")
+
+	// The entries of a committed step are kept, while the ones of a rolled back step are discarded
+	assert.NoError(t, dbWriter.BeginTransaction())
+	assert.NoError(t, dbWriter.CreateRestoreCheckpoint())
+	assert.NoError(t, dbWriter.SaveRestoreCheckpoint(entities.RestoreSnapshotEntry, "snapshot-id"))
+	assert.NoError(t, dbWriter.SaveRestoreCheckpoint(entities.RestoreCommitEntry, string(entities.RestoreCommitBatch)))
+	assert.NoError(t, dbWriter.SaveRestoreCheckpoint(entities.RestoreSchemasEntry, ""))
+	assert.NoError(t, dbWriter.SaveRestoreCheckpoint(entities.RestoreRecordsEntry("users"), "1"))
+	assert.NoError(t, dbWriter.CommitTransaction())
+
+	assert.NoError(t, dbWriter.BeginTransaction())
+	assert.NoError(t, dbWriter.SaveRestoreCheckpoint(entities.RestoreRecordsEntry("users"), "2"))
+	assert.NoError(t, dbWriter.RollbackTransaction())
+
+	assert.NoError(t, dbWriter.BeginTransaction())
+	assert.NoError(t, dbWriter.SaveRestoreCheckpoint(entities.RestoreRulesEntry("users"), ""))
+	assert.NoError(t, dbWriter.CommitTransaction())
+
+	entries, err = dbWriter.GetRestoreCheckpoint()
+	assert.NoError(t, err)
+
+	var checkpoint entities.RestoreCheckpoint
+	assert.NoError(t, checkpoint.DecodeFromEntries(entries))
+	assert.Equal(t, entities.RestoreCheckpoint{
+		SnapshotId:      "snapshot-id",
+		CommitMode:      entities.RestoreCommitBatch,
+		SchemasRestored: true,
+		RestoredBatches: map[string]int64{"users": 1},
+		RestoredRules:   map[string]bool{"users": true},
+	}, checkpoint)
+
+	assert.NoError(t, dbWriter.BeginTransaction())
+	assert.NoError(t, dbWriter.DropRestoreCheckpoint())
+	assert.NoError(t, dbWriter.CommitTransaction())
+
+	entries, err = dbWriter.GetRestoreCheckpoint()
+	assert.NoError(t, err)
+	assert.Nil(t, entries, "the checkpoint is dropped once the restore is completed")
+}
@@ -0,0 +1,355 @@
+package mongo
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"historydb/src/internal/entities"
+	"historydb/src/internal/utils/comparation"
+	"historydb/src/internal/utils/decode"
+	"historydb/src/internal/utils/encode"
+	"historydb/src/internal/utils/pointers"
+	"historydb/src/internal/utils/types"
+)
+
+var MONGOCOLLECTION_VERSION int64 = 1
+
+// MongoCollection represents a MongoDB collection with its capped options, its validator and its indexes.
+// The validator is kept as a canonical Extended JSON document, and it is empty when the collection has no validator.
+type MongoCollection struct {
+	Version          int64                  `json:"version"`
+	Name             string                 `json:"name"`
+	Capped           bool                   `json:"capped"`
+	Size             int64                  `json:"size"`
+	Max              int64                  `json:"max"`
+	Validator        string                 `json:"validator"`
+	ValidationLevel  string                 `json:"validationLevel"`
+	ValidationAction string                 `json:"validationAction"`
+	Indexes          []MongoCollectionIndex `json:"indexes"`
+}
+
+func (collection *MongoCollection) GetSchemaType() entities.SchemaType {
+	return entities.MongoCollection
+}
+
+func (collection *MongoCollection) GetName() string {
+	return collection.Name
+}
+
+func (collection *MongoCollection) Hash() string {
+	hash := sha256.Sum256(collection.encodeData())
+	return hex.EncodeToString(hash[:])
+}
+
+func (collection *MongoCollection) Diff(schema entities.Schema, isDiff bool) entities.SchemaDiff {
+	oldCollection := schema.(*MongoCollection)
+
+	var prevRef string
+	if isDiff {
+		prevRef = fmt.Sprintf("diffs/%s", schema.Hash())
+	} else {
+		prevRef = schema.Hash()
+	}
+
+	diff := MongoCollectionDiff{
+		hash:    collection.Hash(),
+		PrevRef: prevRef,
+	}
+	comparation.AssignIfChanged(&diff.Capped, &collection.Capped, &oldCollection.Capped)
+	comparation.AssignIfChanged(&diff.Size, &collection.Size, &oldCollection.Size)
+	comparation.AssignIfChanged(&diff.Max, &collection.Max, &oldCollection.Max)
+	comparation.AssignIfChanged(&diff.Validator, &collection.Validator, &oldCollection.Validator)
+	comparation.AssignIfChanged(&diff.ValidationLevel, &collection.ValidationLevel, &oldCollection.ValidationLevel)
+	comparation.AssignIfChanged(&diff.ValidationAction, &collection.ValidationAction, &oldCollection.ValidationAction)
+	diff.AddedIndexes, diff.RemovedIndexes = types.DiffSlices(collection.Indexes, oldCollection.Indexes)
+
+	return &diff
+}
+
+func (collection *MongoCollection) ApplyDiff(diff entities.SchemaDiff) entities.Schema {
+	updateCollection := *collection
+	collectionDiff := diff.(*MongoCollectionDiff)
+
+	comparation.AssignIfNotNil(&updateCollection.Capped, collectionDiff.Capped)
+	comparation.AssignIfNotNil(&updateCollection.Size, collectionDiff.Size)
+	comparation.AssignIfNotNil(&updateCollection.Max, collectionDiff.Max)
+	comparation.AssignIfNotNil(&updateCollection.Validator, collectionDiff.Validator)
+	comparation.AssignIfNotNil(&updateCollection.ValidationLevel, collectionDiff.ValidationLevel)
+	comparation.AssignIfNotNil(&updateCollection.ValidationAction, collectionDiff.ValidationAction)
+	updateCollection.Indexes = mergeIndexes(collection.Indexes, collectionDiff.AddedIndexes, collectionDiff.RemovedIndexes)
+
+	return &updateCollection
+}
+
+func (collection *MongoCollection) EncodeToBytes() []byte {
+	var buf bytes.Buffer
+
+	encodedData := collection.encodeData()
+	integrityHash := sha256.Sum256(encodedData)
+
+	buf.Write(integrityHash[:])
+	buf.Write(encodedData)
+
+	return buf.Bytes()
+}
+
+func (collection *MongoCollection) DecodeFromBytes(data []byte) error {
+	buf := bytes.NewBuffer(data)
+
+	if _, err := decode.DecodeString(buf); err != nil {
+		return err
+	}
+	version, err := decode.DecodeInt(buf)
+	if err != nil {
+		return err
+	}
+	flags, err := buf.ReadByte()
+	if err != nil {
+		return err
+	}
+	name, err := decode.DecodeString(buf)
+	if err != nil {
+		return err
+	}
+	capped, err := decode.DecodeBool(buf)
+	if err != nil {
+		return err
+	}
+	size, err := decode.DecodeInt(buf)
+	if err != nil {
+		return err
+	}
+	max, err := decode.DecodeInt(buf)
+	if err != nil {
+		return err
+	}
+	validator, err := decode.DecodeString(buf)
+	if err != nil {
+		return err
+	}
+	validationLevel, err := decode.DecodeString(buf)
+	if err != nil {
+		return err
+	}
+	validationAction, err := decode.DecodeString(buf)
+	if err != nil {
+		return err
+	}
+	var idxSlice []MongoCollectionIndex
+	if flags&(1<<0) != 0 {
+		idxs, err := decode.DecodeSlice[*MongoCollectionIndex](buf)
+		if err != nil {
+			return err
+		}
+
+		idxSlice = make([]MongoCollectionIndex, 0, len(idxs))
+		for _, v := range idxs {
+			idxSlice = append(idxSlice, *v)
+		}
+	}
+
+	collection.Version = *version
+	collection.Name = *name
+	collection.Capped = *capped
+	collection.Size = *size
+	collection.Max = *max
+	collection.Validator = *validator
+	collection.ValidationLevel = *validationLevel
+	collection.ValidationAction = *validationAction
+	collection.Indexes = idxSlice
+	return nil
+}
+
+func (collection *MongoCollection) encodeData() []byte {
+	var buf bytes.Buffer
+
+	encode.EncodeString(&buf, (*string)(pointers.Ptr(entities.MongoCollection)))
+	encode.EncodeInt(&buf, &MONGOCOLLECTION_VERSION)
+	buf.WriteByte(collection.getByteFlags())
+	encode.EncodeString(&buf, &collection.Name)
+	encode.EncodeBool(&buf, &collection.Capped)
+	encode.EncodeInt(&buf, &collection.Size)
+	encode.EncodeInt(&buf, &collection.Max)
+	encode.EncodeString(&buf, &collection.Validator)
+	encode.EncodeString(&buf, &collection.ValidationLevel)
+	encode.EncodeString(&buf, &collection.ValidationAction)
+	encode.EncodeSlice(&buf, collection.Indexes)
+
+	return buf.Bytes()
+}
+
+func (collection *MongoCollection) getByteFlags() byte {
+	var flags byte
+	if len(collection.Indexes) > 0 {
+		flags |= 1 << 0
+	}
+	return flags
+}
+
+type MongoCollectionDiff struct {
+	hash             string
+	PrevRef          string
+	Capped           *bool
+	Size             *int64
+	Max              *int64
+	Validator        *string
+	ValidationLevel  *string
+	ValidationAction *string
+	AddedIndexes     []MongoCollectionIndex
+	RemovedIndexes   []MongoCollectionIndex
+}
+
+func (diff *MongoCollectionDiff) Hash() string {
+	return diff.hash
+}
+
+func (diff *MongoCollectionDiff) GetPrevRef() string {
+	return diff.PrevRef
+}
+
+func (diff *MongoCollectionDiff) EncodeToBytes() []byte {
+	var buf bytes.Buffer
+
+	encodedData := diff.encodeData()
+	integrityHash := sha256.Sum256(encodedData)
+
+	buf.Write(integrityHash[:])
+	buf.Write(encodedData)
+
+	return buf.Bytes()
+}
+
+func (diff *MongoCollectionDiff) DecodeFromBytes(data []byte) error {
+	buf := bytes.NewBuffer(data)
+
+	prevRef, err := decode.DecodeString(buf)
+	if err != nil {
+		return err
+	}
+	flags, err := buf.ReadByte()
+	if err != nil {
+		return err
+	}
+
+	var capped *bool
+	var size, max *int64
+	var validator, validationLevel, validationAction *string
+	var addedIdxs, removedIdxs []MongoCollectionIndex
+
+	if flags&(1<<0) != 0 {
+		capped, err = decode.DecodeBool(buf)
+		if err != nil {
+			return err
+		}
+	}
+	if flags&(1<<1) != 0 {
+		size, err = decode.DecodeInt(buf)
+		if err != nil {
+			return err
+		}
+	}
+	if flags&(1<<2) != 0 {
+		max, err = decode.DecodeInt(buf)
+		if err != nil {
+			return err
+		}
+	}
+	if flags&(1<<3) != 0 {
+		validator, err = decode.DecodeString(buf)
+		if err != nil {
+			return err
+		}
+	}
+	if flags&(1<<4) != 0 {
+		validationLevel, err = decode.DecodeString(buf)
+		if err != nil {
+			return err
+		}
+	}
+	if flags&(1<<5) != 0 {
+		validationAction, err = decode.DecodeString(buf)
+		if err != nil {
+			return err
+		}
+	}
+	if flags&(1<<6) != 0 {
+		idxs, err := decode.DecodeSlice[*MongoCollectionIndex](buf)
+		if err != nil {
+			return err
+		}
+
+		addedIdxs = make([]MongoCollectionIndex, 0, len(idxs))
+		for _, v := range idxs {
+			addedIdxs = append(addedIdxs, *v)
+		}
+	}
+	if flags&(1<<7) != 0 {
+		idxs, err := decode.DecodeSlice[*MongoCollectionIndex](buf)
+		if err != nil {
+			return err
+		}
+
+		removedIdxs = make([]MongoCollectionIndex, 0, len(idxs))
+		for _, v := range idxs {
+			removedIdxs = append(removedIdxs, *v)
+		}
+	}
+
+	diff.PrevRef = *prevRef
+	diff.Capped = capped
+	diff.Size = size
+	diff.Max = max
+	diff.Validator = validator
+	diff.ValidationLevel = validationLevel
+	diff.ValidationAction = validationAction
+	diff.AddedIndexes = addedIdxs
+	diff.RemovedIndexes = removedIdxs
+	return nil
+}
+
+func (diff *MongoCollectionDiff) encodeData() []byte {
+	var buf bytes.Buffer
+
+	encode.EncodeString(&buf, &diff.PrevRef)
+	buf.WriteByte(diff.getByteFlags())
+	encode.EncodeBool(&buf, diff.Capped)
+	encode.EncodeInt(&buf, diff.Size)
+	encode.EncodeInt(&buf, diff.Max)
+	encode.EncodeString(&buf, diff.Validator)
+	encode.EncodeString(&buf, diff.ValidationLevel)
+	encode.EncodeString(&buf, diff.ValidationAction)
+	encode.EncodeSlice(&buf, diff.AddedIndexes)
+	encode.EncodeSlice(&buf, diff.RemovedIndexes)
+
+	return buf.Bytes()
+}
+
+func (diff *MongoCollectionDiff) getByteFlags() byte {
+	var flags byte
+	if diff.Capped != nil {
+		flags |= 1 << 0
+	}
+	if diff.Size != nil {
+		flags |= 1 << 1
+	}
+	if diff.Max != nil {
+		flags |= 1 << 2
+	}
+	if diff.Validator != nil {
+		flags |= 1 << 3
+	}
+	if diff.ValidationLevel != nil {
+		flags |= 1 << 4
+	}
+	if diff.ValidationAction != nil {
+		flags |= 1 << 5
+	}
+	if len(diff.AddedIndexes) > 0 {
+		flags |= 1 << 6
+	}
+	if len(diff.RemovedIndexes) > 0 {
+		flags |= 1 << 7
+	}
+	return flags
+}
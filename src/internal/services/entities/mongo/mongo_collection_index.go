@@ -0,0 +1,77 @@
+package mongo
+
+import (
+	"bytes"
+	"historydb/src/internal/utils/decode"
+	"historydb/src/internal/utils/encode"
+	"sort"
+)
+
+// MongoCollectionIndex represents an index of a collection. Its keys and options are kept as canonical Extended JSON
+// documents, so the key order and the BSON types of the values are not lost.
+type MongoCollectionIndex struct {
+	Name    string `json:"name"`
+	Keys    string `json:"keys"`
+	Options string `json:"options"`
+}
+
+func (index MongoCollectionIndex) EncodeToBytes() []byte {
+	var buf bytes.Buffer
+
+	encode.EncodeString(&buf, &index.Name)
+	encode.EncodeString(&buf, &index.Keys)
+	encode.EncodeString(&buf, &index.Options)
+
+	return buf.Bytes()
+}
+
+func (index *MongoCollectionIndex) DecodeFromBytes(data []byte) (*MongoCollectionIndex, error) {
+	buf := bytes.NewBuffer(data)
+
+	name, err := decode.DecodeString(buf)
+	if err != nil {
+		return nil, err
+	}
+	keys, err := decode.DecodeString(buf)
+	if err != nil {
+		return nil, err
+	}
+	options, err := decode.DecodeString(buf)
+	if err != nil {
+		return nil, err
+	}
+
+	if index == nil {
+		return &MongoCollectionIndex{
+			Name:    *name,
+			Keys:    *keys,
+			Options: *options,
+		}, nil
+	} else {
+		index.Name = *name
+		index.Keys = *keys
+		index.Options = *options
+		return nil, nil
+	}
+}
+
+func mergeIndexes(originalIndexes, addedIndexes, removedIndexes []MongoCollectionIndex) []MongoCollectionIndex {
+	newIndexes := make([]MongoCollectionIndex, 0, len(originalIndexes)+len(addedIndexes)-len(removedIndexes))
+
+outerLoop:
+	for _, idx := range originalIndexes {
+		for _, del := range removedIndexes {
+			if idx == del {
+				continue outerLoop
+			}
+		}
+		newIndexes = append(newIndexes, idx)
+	}
+
+	newIndexes = append(newIndexes, addedIndexes...)
+	sort.Slice(newIndexes, func(i, j int) bool {
+		return newIndexes[i].Name < newIndexes[j].Name
+	})
+
+	return newIndexes
+}
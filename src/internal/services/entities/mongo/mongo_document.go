@@ -0,0 +1,120 @@
+package mongo
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"historydb/src/internal/utils/decode"
+	"historydb/src/internal/utils/encode"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+// MongoDocument represents a document of a collection. It is kept as raw BSON, so no BSON type is lost between
+// the backup and the restore.
+type MongoDocument struct {
+	Content []byte
+}
+
+// ID returns the key of the document _id, which is its BSON type followed by its BSON value.
+func (document MongoDocument) ID() string {
+	return documentID(document.Content)
+}
+
+func (document MongoDocument) Hash() string {
+	hash := sha256.Sum256(document.Content)
+	return hex.EncodeToString(hash[:])
+}
+
+func (document MongoDocument) EncodeToBytes() []byte {
+	var buf bytes.Buffer
+	encode.EncodeBytes(&buf, document.Content)
+	return buf.Bytes()
+}
+
+func (document *MongoDocument) DecodeFromBytes(data []byte) (*MongoDocument, error) {
+	buf := bytes.NewBuffer(data)
+	content, err := decode.DecodeBytes(buf)
+	if err != nil {
+		return nil, err
+	}
+
+	return &MongoDocument{
+		Content: content,
+	}, nil
+}
+
+// MongoDocumentDiff represents a change of a document in a chunk, identified by its _id key. A diff without document
+// removes it, and a diff with position inserts it at that position of the new chunk.
+type MongoDocumentDiff struct {
+	ID       string
+	Position *int64
+	Document []byte
+}
+
+func (diff MongoDocumentDiff) EncodeToBytes() []byte {
+	var buf bytes.Buffer
+
+	buf.WriteByte(diff.getByteFlags())
+	encode.EncodeString(&buf, &diff.ID)
+	encode.EncodeInt(&buf, diff.Position)
+	encode.EncodeBytes(&buf, diff.Document)
+
+	return buf.Bytes()
+}
+
+func (diff *MongoDocumentDiff) DecodeFromBytes(data []byte) (*MongoDocumentDiff, error) {
+	buf := bytes.NewBuffer(data)
+
+	flags, err := buf.ReadByte()
+	if err != nil {
+		return nil, err
+	}
+	id, err := decode.DecodeString(buf)
+	if err != nil {
+		return nil, err
+	}
+	var position *int64
+	if flags&(1<<0) != 0 {
+		position, err = decode.DecodeInt(buf)
+		if err != nil {
+			return nil, err
+		}
+	}
+	var document []byte
+	if flags&(1<<1) != 0 {
+		document, err = decode.DecodeBytes(buf)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return &MongoDocumentDiff{
+		ID:       *id,
+		Position: position,
+		Document: document,
+	}, nil
+}
+
+func (diff MongoDocumentDiff) getByteFlags() byte {
+	var flags byte
+
+	if diff.Position != nil {
+		flags |= 1 << 0
+	}
+	if diff.Document != nil {
+		flags |= 1 << 1
+	}
+
+	return flags
+}
+
+// documentID returns the key of the _id of a BSON document, or an empty key if it has no _id.
+func documentID(content []byte) string {
+	value, err := bson.Raw(content).LookupErr("_id")
+	if err != nil {
+		return ""
+	}
+
+	return string(append([]byte{byte(value.Type)}, value.Value...))
+}
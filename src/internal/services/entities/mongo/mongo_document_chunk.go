@@ -0,0 +1,352 @@
+package mongo
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"historydb/src/internal/entities"
+	"historydb/src/internal/utils/decode"
+	"historydb/src/internal/utils/encode"
+	"historydb/src/internal/utils/pointers"
+	"sort"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+// MongoChunkCursor points to the last document read from a collection, so the next chunk starts after its _id.
+type MongoChunkCursor struct {
+	LastID bson.RawValue
+}
+
+// EncodeToBytes encodes the cursor, so the documents of a collection can be read from it again by another process.
+func (cursor *MongoChunkCursor) EncodeToBytes() []byte {
+	var buf bytes.Buffer
+	buf.WriteByte(byte(cursor.LastID.Type))
+	// The value is the rest of the cursor, as the value of some types, as null, is empty
+	buf.Write(cursor.LastID.Value)
+	return buf.Bytes()
+}
+
+func (cursor *MongoChunkCursor) DecodeFromBytes(data []byte) error {
+	buf := bytes.NewBuffer(data)
+
+	valueType, err := buf.ReadByte()
+	if err != nil {
+		return err
+	}
+
+	cursor.LastID = bson.RawValue{Type: bson.Type(valueType), Value: bytes.Clone(buf.Bytes())}
+	return nil
+}
+
+var MONGODOCUMENTCHUNK_VERSION int64 = 1
+
+// MongoDocumentChunk is a chunk of documents sorted by _id. Unlike SQL records, its diffs match the documents by
+// their _id instead of by their position in the chunk.
+type MongoDocumentChunk struct {
+	hash    string
+	Version int64
+	Content []MongoDocument
+}
+
+func (chunk *MongoDocumentChunk) Length() int {
+	return len(chunk.Content)
+}
+
+func (chunk *MongoDocumentChunk) Hash() string {
+	hash := sha256.Sum256(chunk.encodeData())
+	chunk.hash = hex.EncodeToString(hash[:])
+	return chunk.hash
+}
+
+func (chunk *MongoDocumentChunk) GetRecordType() entities.RecordType {
+	return entities.MongoDocument
+}
+
+func (chunk *MongoDocumentChunk) Diff(recordChunk entities.SchemaRecordChunk, isDiff bool) entities.SchemaRecordChunkDiff {
+	oldChunk := recordChunk.(*MongoDocumentChunk)
+
+	var prevRef string
+	if isDiff {
+		prevRef = fmt.Sprintf("diffs/%s", recordChunk.Hash())
+	} else {
+		prevRef = recordChunk.Hash()
+	}
+
+	diff := MongoDocumentChunkDiff{
+		hash:    pointers.Ptr(chunk.Hash()),
+		PrevRef: &prevRef,
+	}
+
+	oldDocuments := make(map[string]string, len(oldChunk.Content))
+	for _, document := range oldChunk.Content {
+		oldDocuments[document.ID()] = document.Hash()
+	}
+
+	documents := []MongoDocumentDiff{}
+	newDocuments := make(map[string]bool, len(chunk.Content))
+	for i, document := range chunk.Content {
+		id := document.ID()
+		newDocuments[id] = true
+
+		oldHash, ok := oldDocuments[id]
+		if !ok {
+			documents = append(documents, MongoDocumentDiff{ID: id, Position: pointers.Ptr(int64(i)), Document: document.Content})
+		} else if oldHash != document.Hash() {
+			documents = append(documents, MongoDocumentDiff{ID: id, Document: document.Content})
+		}
+	}
+	for _, document := range oldChunk.Content {
+		if id := document.ID(); !newDocuments[id] {
+			documents = append(documents, MongoDocumentDiff{ID: id})
+		}
+	}
+	diff.Content = documents
+
+	return &diff
+}
+
+func (chunk *MongoDocumentChunk) DiffFromEmpty() entities.SchemaRecordChunkDiff {
+	diff := MongoDocumentChunkDiff{
+		hash: pointers.Ptr(chunk.Hash()),
+	}
+	documents := []MongoDocumentDiff{}
+	for i, document := range chunk.Content {
+		documents = append(documents, MongoDocumentDiff{ID: document.ID(), Position: pointers.Ptr(int64(i)), Document: document.Content})
+	}
+	diff.Content = documents
+
+	return &diff
+}
+
+func (chunk *MongoDocumentChunk) DiffToEmpty(isDiff bool) entities.SchemaRecordChunkDiff {
+	var prevRef string
+	if isDiff {
+		prevRef = fmt.Sprintf("diffs/%s", chunk.Hash())
+	} else {
+		prevRef = chunk.Hash()
+	}
+
+	return &MongoDocumentChunkDiff{
+		PrevRef: &prevRef,
+	}
+}
+
+// ApplyDiff updates and removes the documents of the chunk, and then inserts the new documents in ascending
+// position, so the result keeps the same order as the chunk the diff was calculated from.
+func (chunk *MongoDocumentChunk) ApplyDiff(diff entities.SchemaRecordChunkDiff) entities.SchemaRecordChunk {
+	chunkDiff := diff.(*MongoDocumentChunkDiff)
+
+	positions := make(map[string]int, len(chunk.Content))
+	for i, document := range chunk.Content {
+		positions[document.ID()] = i
+	}
+
+	updated := make(map[string][]byte)
+	removed := make(map[string]bool)
+	inserted := []MongoDocumentDiff{}
+	for _, v := range chunkDiff.Content {
+		if v.Position != nil {
+			inserted = append(inserted, v)
+			continue
+		}
+
+		if _, ok := positions[v.ID]; !ok {
+			return nil
+		}
+		if v.Document != nil {
+			updated[v.ID] = v.Document
+		} else {
+			removed[v.ID] = true
+		}
+	}
+
+	content := make([]MongoDocument, 0, len(chunk.Content)+len(inserted))
+	for _, document := range chunk.Content {
+		id := document.ID()
+		if removed[id] {
+			continue
+		}
+		if newContent, ok := updated[id]; ok {
+			document = MongoDocument{Content: newContent}
+		}
+		content = append(content, document)
+	}
+
+	sort.Slice(inserted, func(i, j int) bool {
+		return *inserted[i].Position < *inserted[j].Position
+	})
+	for _, v := range inserted {
+		position := min(int(*v.Position), len(content))
+		content = append(content, MongoDocument{})
+		copy(content[position+1:], content[position:])
+		content[position] = MongoDocument{Content: v.Document}
+	}
+
+	return &MongoDocumentChunk{
+		Version: chunk.Version,
+		Content: content,
+	}
+}
+
+// EncodeToBytes encodes the chunk. The documents are kept as raw BSON, so their encoding never fails.
+func (chunk *MongoDocumentChunk) EncodeToBytes() ([]byte, error) {
+	var buf bytes.Buffer
+	var auxBuf bytes.Buffer
+	encodedData := chunk.encodeData()
+
+	encode.EncodeString(&auxBuf, &chunk.hash)
+	auxBuf.Write(encodedData)
+
+	encode.EncodeInt(&buf, pointers.Ptr(int64(len(auxBuf.Bytes())))) // Saves buffer size so then we can extract the full buffer
+	buf.Write(auxBuf.Bytes())
+
+	return buf.Bytes(), nil
+}
+
+func (chunk *MongoDocumentChunk) DecodeFromBytes(data []byte) error {
+	// Here it uses the buffer reader from the buffer size, so there is not that integer in the buffer
+	buf := bytes.NewBuffer(data)
+
+	hash, err := decode.DecodeString(buf)
+	if err != nil {
+		return err
+	}
+	version, err := decode.DecodeInt(buf)
+	if err != nil {
+		return err
+	}
+	var contentSlice []MongoDocument
+	content, err := decode.DecodeSlice[*MongoDocument](buf)
+	if err != nil {
+		return err
+	}
+
+	for _, v := range content {
+		contentSlice = append(contentSlice, *v)
+	}
+
+	chunk.hash = *hash
+	chunk.Version = *version
+	chunk.Content = contentSlice
+	return nil
+}
+
+func (chunk *MongoDocumentChunk) encodeData() []byte {
+	var buf bytes.Buffer
+	encode.EncodeInt(&buf, &MONGODOCUMENTCHUNK_VERSION)
+	encode.EncodeSlice(&buf, chunk.Content)
+
+	encodedData := buf.Bytes()
+
+	hash := sha256.Sum256(encodedData)
+	chunk.hash = hex.EncodeToString(hash[:])
+
+	return buf.Bytes()
+}
+
+type MongoDocumentChunkDiff struct {
+	hash    *string
+	PrevRef *string
+	Content []MongoDocumentDiff
+}
+
+func (diff *MongoDocumentChunkDiff) Length() int {
+	return len(diff.Content)
+}
+
+func (diff *MongoDocumentChunkDiff) Hash() *string {
+	return diff.hash
+}
+
+func (diff *MongoDocumentChunkDiff) GetPrevRef() *string {
+	return diff.PrevRef
+}
+
+func (diff *MongoDocumentChunkDiff) GetRecordType() entities.RecordType {
+	return entities.MongoDocument
+}
+
+func (diff *MongoDocumentChunkDiff) ApplyDiffFromEmpty() entities.SchemaRecordChunk {
+	var emptyChunk MongoDocumentChunk
+	chunk := emptyChunk.ApplyDiff(diff)
+	return chunk
+}
+
+func (diff *MongoDocumentChunkDiff) EncodeToBytes() ([]byte, error) {
+	var buf bytes.Buffer
+	encodedData := diff.encodeData()
+
+	encode.EncodeInt(&buf, pointers.Ptr(int64(len(encodedData)))) // Saves buffer size so then we can extract the full buffer
+	buf.Write(encodedData)
+
+	return buf.Bytes(), nil
+}
+
+func (diff *MongoDocumentChunkDiff) DecodeFromBytes(data []byte) error {
+	// Here it uses the buffer reader from the buffer size, so there is not that integer in the buffer
+	buf := bytes.NewBuffer(data)
+
+	flags, err := buf.ReadByte()
+	if err != nil {
+		return err
+	}
+	var hash *string
+	if flags&(1<<0) != 0 {
+		hash, err = decode.DecodeString(buf)
+		if err != nil {
+			return err
+		}
+	}
+	var prevRef *string
+	if flags&(1<<1) != 0 {
+		prevRef, err = decode.DecodeString(buf)
+		if err != nil {
+			return err
+		}
+	}
+	var contentSlice []MongoDocumentDiff
+	if flags&(1<<2) != 0 {
+		content, err := decode.DecodeSlice[*MongoDocumentDiff](buf)
+		if err != nil {
+			return err
+		}
+
+		for _, v := range content {
+			contentSlice = append(contentSlice, *v)
+		}
+	}
+
+	diff.hash = hash
+	diff.PrevRef = prevRef
+	diff.Content = contentSlice
+	return nil
+}
+
+func (diff *MongoDocumentChunkDiff) encodeData() []byte {
+	var buf bytes.Buffer
+
+	buf.WriteByte(diff.getByteFlags())
+	encode.EncodeString(&buf, diff.hash)
+	encode.EncodeString(&buf, diff.PrevRef)
+	encode.EncodeSlice(&buf, diff.Content)
+
+	return buf.Bytes()
+}
+
+func (diff *MongoDocumentChunkDiff) getByteFlags() byte {
+	var flags byte
+
+	if diff.hash != nil {
+		flags |= 1 << 0
+	}
+	if diff.PrevRef != nil {
+		flags |= 1 << 1
+	}
+	if len(diff.Content) != 0 {
+		flags |= 1 << 2
+	}
+
+	return flags
+}
@@ -0,0 +1,181 @@
+package mysql
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"historydb/src/internal/entities"
+	"historydb/src/internal/utils/comparation"
+	"historydb/src/internal/utils/decode"
+	"historydb/src/internal/utils/encode"
+	"historydb/src/internal/utils/pointers"
+	"historydb/src/internal/utils/types"
+)
+
+var MYSQLAUTOINCREMENT_VERSION int64 = 1
+
+// MySQLAutoIncrement represents the AUTO_INCREMENT counter of a MySQL table.
+// As in MySQL the counter lives inside the table definition, its name is the table it belongs to.
+type MySQLAutoIncrement struct {
+	Version int64        `json:"version"`
+	Name    string       `json:"name"`
+	Value   types.BigInt `json:"value"`
+}
+
+func (autoIncrement *MySQLAutoIncrement) GetDependencyType() entities.DependencyType {
+	return entities.MySQLAutoIncrement
+}
+
+func (autoIncrement *MySQLAutoIncrement) GetName() string {
+	return autoIncrement.Name
+}
+
+func (autoIncrement *MySQLAutoIncrement) Hash() string {
+	hash := sha256.Sum256(autoIncrement.encodeData())
+	return hex.EncodeToString(hash[:])
+}
+
+func (autoIncrement *MySQLAutoIncrement) Diff(dependency entities.SchemaDependency, isDiff bool) entities.SchemaDependencyDiff {
+	oldAutoIncrement := dependency.(*MySQLAutoIncrement)
+
+	var prevRef string
+	if isDiff {
+		prevRef = fmt.Sprintf("diffs/%s", dependency.Hash())
+	} else {
+		prevRef = dependency.Hash()
+	}
+
+	diff := MySQLAutoIncrementDiff{
+		hash:    autoIncrement.Hash(),
+		PrevRef: prevRef,
+	}
+	comparation.AssignIfChangedWithEquality(&diff.Value, &autoIncrement.Value, &oldAutoIncrement.Value, func(a, b *types.BigInt) bool { return a.Cmp(&b.Int) == 0 })
+
+	return &diff
+}
+
+func (autoIncrement *MySQLAutoIncrement) ApplyDiff(diff entities.SchemaDependencyDiff) entities.SchemaDependency {
+	updateAutoIncrement := *autoIncrement
+	autoIncrementDiff := diff.(*MySQLAutoIncrementDiff)
+
+	comparation.AssignIfNotNil(&updateAutoIncrement.Value, autoIncrementDiff.Value)
+
+	return &updateAutoIncrement
+}
+
+func (autoIncrement *MySQLAutoIncrement) EncodeToBytes() []byte {
+	var buf bytes.Buffer
+
+	encodedData := autoIncrement.encodeData()
+	integrityHash := sha256.Sum256(encodedData)
+
+	buf.Write(integrityHash[:])
+	buf.Write(encodedData)
+
+	return buf.Bytes()
+}
+
+func (autoIncrement *MySQLAutoIncrement) DecodeFromBytes(data []byte) error {
+	buf := bytes.NewBuffer(data)
+
+	if _, err := decode.DecodeString(buf); err != nil {
+		return err
+	}
+	version, err := decode.DecodeInt(buf)
+	if err != nil {
+		return err
+	}
+	name, err := decode.DecodeString(buf)
+	if err != nil {
+		return err
+	}
+	value, err := decode.DecodeBigInt(buf)
+	if err != nil {
+		return err
+	}
+
+	autoIncrement.Version = *version
+	autoIncrement.Name = *name
+	autoIncrement.Value = *value
+	return nil
+}
+
+func (autoIncrement *MySQLAutoIncrement) encodeData() []byte {
+	var buf bytes.Buffer
+
+	encode.EncodeString(&buf, (*string)(pointers.Ptr(entities.MySQLAutoIncrement)))
+	encode.EncodeInt(&buf, &MYSQLAUTOINCREMENT_VERSION)
+	encode.EncodeString(&buf, &autoIncrement.Name)
+	encode.EncodeBigInt(&buf, &autoIncrement.Value)
+
+	return buf.Bytes()
+}
+
+type MySQLAutoIncrementDiff struct {
+	hash    string
+	PrevRef string
+	Value   *types.BigInt
+}
+
+func (diff *MySQLAutoIncrementDiff) Hash() string {
+	return diff.hash
+}
+
+func (diff *MySQLAutoIncrementDiff) GetPrevRef() string {
+	return diff.PrevRef
+}
+
+func (diff *MySQLAutoIncrementDiff) EncodeToBytes() []byte {
+	var buf bytes.Buffer
+
+	encodedData := diff.encodeData()
+	integrityHash := sha256.Sum256(encodedData)
+
+	buf.Write(integrityHash[:])
+	buf.Write(encodedData)
+
+	return buf.Bytes()
+}
+
+func (diff *MySQLAutoIncrementDiff) DecodeFromBytes(data []byte) error {
+	buf := bytes.NewBuffer(data)
+
+	prevRef, err := decode.DecodeString(buf)
+	if err != nil {
+		return err
+	}
+	flags, err := buf.ReadByte()
+	if err != nil {
+		return err
+	}
+	var value *types.BigInt
+	if flags&(1<<0) != 0 {
+		value, err = decode.DecodeBigInt(buf)
+		if err != nil {
+			return err
+		}
+	}
+
+	diff.PrevRef = *prevRef
+	diff.Value = value
+	return nil
+}
+
+func (diff *MySQLAutoIncrementDiff) encodeData() []byte {
+	var buf bytes.Buffer
+
+	encode.EncodeString(&buf, &diff.PrevRef)
+	buf.WriteByte(diff.getByteFlags())
+	encode.EncodeBigInt(&buf, diff.Value)
+
+	return buf.Bytes()
+}
+
+func (diff *MySQLAutoIncrementDiff) getByteFlags() byte {
+	var flags byte
+	if diff.Value != nil {
+		flags |= 1 << 0
+	}
+	return flags
+}
@@ -0,0 +1,65 @@
+package mysql
+
+import (
+	"historydb/src/internal/entities"
+	"historydb/src/internal/utils/comparation"
+)
+
+var MYSQLEVENT_VERSION int64 = 1
+
+// MySQLEvent represents a MySQL event. Its definition is the full CREATE EVENT statement without the DEFINER clause.
+type MySQLEvent struct {
+	Version    int64
+	Name       string `json:"name"`
+	Definition string `json:"definition"`
+}
+
+func (event *MySQLEvent) GetName() string {
+	return event.Name
+}
+
+func (event *MySQLEvent) GetRoutineType() entities.RoutineType {
+	return entities.MySQLEvent
+}
+
+func (event *MySQLEvent) GetDependencies() []string {
+	return nil
+}
+
+func (event *MySQLEvent) Hash() string {
+	return hashRoutine(event.encodeData())
+}
+
+func (event *MySQLEvent) Diff(routine entities.Routine, isDiff bool) entities.RoutineDiff {
+	oldEvent := routine.(*MySQLEvent)
+	return diffRoutine(event.Hash(), routine, isDiff, &event.Definition, &oldEvent.Definition)
+}
+
+func (event *MySQLEvent) ApplyDiff(diff entities.RoutineDiff) entities.Routine {
+	updateEvent := *event
+	eventDiff := diff.(*MySQLRoutineDiff)
+
+	comparation.AssignIfNotNil(&updateEvent.Definition, eventDiff.Definition)
+
+	return &updateEvent
+}
+
+func (event *MySQLEvent) EncodeToBytes() []byte {
+	return encodeRoutine(event.encodeData())
+}
+
+func (event *MySQLEvent) DecodeFromBytes(data []byte) error {
+	version, name, definition, err := decodeRoutine(data)
+	if err != nil {
+		return err
+	}
+
+	event.Version = version
+	event.Name = name
+	event.Definition = definition
+	return nil
+}
+
+func (event *MySQLEvent) encodeData() []byte {
+	return encodeRoutineData(entities.MySQLEvent, MYSQLEVENT_VERSION, event.Name, event.Definition)
+}
@@ -0,0 +1,65 @@
+package mysql
+
+import (
+	"historydb/src/internal/entities"
+	"historydb/src/internal/utils/comparation"
+)
+
+var MYSQLFUNCTION_VERSION int64 = 1
+
+// MySQLFunction represents a MySQL function. Its definition is the full CREATE FUNCTION statement without the DEFINER clause.
+type MySQLFunction struct {
+	Version    int64
+	Name       string `json:"name"`
+	Definition string `json:"definition"`
+}
+
+func (function *MySQLFunction) GetName() string {
+	return function.Name
+}
+
+func (function *MySQLFunction) GetRoutineType() entities.RoutineType {
+	return entities.MySQLFunction
+}
+
+func (function *MySQLFunction) GetDependencies() []string {
+	return nil
+}
+
+func (function *MySQLFunction) Hash() string {
+	return hashRoutine(function.encodeData())
+}
+
+func (function *MySQLFunction) Diff(routine entities.Routine, isDiff bool) entities.RoutineDiff {
+	oldFunction := routine.(*MySQLFunction)
+	return diffRoutine(function.Hash(), routine, isDiff, &function.Definition, &oldFunction.Definition)
+}
+
+func (function *MySQLFunction) ApplyDiff(diff entities.RoutineDiff) entities.Routine {
+	updateFunction := *function
+	functionDiff := diff.(*MySQLRoutineDiff)
+
+	comparation.AssignIfNotNil(&updateFunction.Definition, functionDiff.Definition)
+
+	return &updateFunction
+}
+
+func (function *MySQLFunction) EncodeToBytes() []byte {
+	return encodeRoutine(function.encodeData())
+}
+
+func (function *MySQLFunction) DecodeFromBytes(data []byte) error {
+	version, name, definition, err := decodeRoutine(data)
+	if err != nil {
+		return err
+	}
+
+	function.Version = version
+	function.Name = name
+	function.Definition = definition
+	return nil
+}
+
+func (function *MySQLFunction) encodeData() []byte {
+	return encodeRoutineData(entities.MySQLFunction, MYSQLFUNCTION_VERSION, function.Name, function.Definition)
+}
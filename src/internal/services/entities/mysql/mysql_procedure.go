@@ -0,0 +1,65 @@
+package mysql
+
+import (
+	"historydb/src/internal/entities"
+	"historydb/src/internal/utils/comparation"
+)
+
+var MYSQLPROCEDURE_VERSION int64 = 1
+
+// MySQLProcedure represents a MySQL procedure. Its definition is the full CREATE PROCEDURE statement without the DEFINER clause.
+type MySQLProcedure struct {
+	Version    int64
+	Name       string `json:"name"`
+	Definition string `json:"definition"`
+}
+
+func (procedure *MySQLProcedure) GetName() string {
+	return procedure.Name
+}
+
+func (procedure *MySQLProcedure) GetRoutineType() entities.RoutineType {
+	return entities.MySQLProcedure
+}
+
+func (procedure *MySQLProcedure) GetDependencies() []string {
+	return nil
+}
+
+func (procedure *MySQLProcedure) Hash() string {
+	return hashRoutine(procedure.encodeData())
+}
+
+func (procedure *MySQLProcedure) Diff(routine entities.Routine, isDiff bool) entities.RoutineDiff {
+	oldProcedure := routine.(*MySQLProcedure)
+	return diffRoutine(procedure.Hash(), routine, isDiff, &procedure.Definition, &oldProcedure.Definition)
+}
+
+func (procedure *MySQLProcedure) ApplyDiff(diff entities.RoutineDiff) entities.Routine {
+	updateProcedure := *procedure
+	procedureDiff := diff.(*MySQLRoutineDiff)
+
+	comparation.AssignIfNotNil(&updateProcedure.Definition, procedureDiff.Definition)
+
+	return &updateProcedure
+}
+
+func (procedure *MySQLProcedure) EncodeToBytes() []byte {
+	return encodeRoutine(procedure.encodeData())
+}
+
+func (procedure *MySQLProcedure) DecodeFromBytes(data []byte) error {
+	version, name, definition, err := decodeRoutine(data)
+	if err != nil {
+		return err
+	}
+
+	procedure.Version = version
+	procedure.Name = name
+	procedure.Definition = definition
+	return nil
+}
+
+func (procedure *MySQLProcedure) encodeData() []byte {
+	return encodeRoutineData(entities.MySQLProcedure, MYSQLPROCEDURE_VERSION, procedure.Name, procedure.Definition)
+}
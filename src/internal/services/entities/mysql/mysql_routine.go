@@ -0,0 +1,131 @@
+package mysql
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"historydb/src/internal/entities"
+	"historydb/src/internal/utils/comparation"
+	"historydb/src/internal/utils/decode"
+	"historydb/src/internal/utils/encode"
+	"historydb/src/internal/utils/pointers"
+)
+
+// MySQL functions, procedures, triggers and events are all saved as their name and CREATE statement, so they share their
+// encoding and their diffs, which only differ by the routine type the data is tagged with.
+
+// hashRoutine returns the hash of the encoded data of a MySQL routine.
+func hashRoutine(data []byte) string {
+	hash := sha256.Sum256(data)
+	return hex.EncodeToString(hash[:])
+}
+
+// encodeRoutineData encodes a MySQL routine of the given type, which is the data its hash is taken from.
+func encodeRoutineData(routineType entities.RoutineType, version int64, name, definition string) []byte {
+	var buf bytes.Buffer
+
+	encode.EncodeString(&buf, (*string)(pointers.Ptr(routineType)))
+	encode.EncodeInt(&buf, &version)
+	encode.EncodeString(&buf, &name)
+	encode.EncodeString(&buf, &definition)
+
+	return buf.Bytes()
+}
+
+// encodeRoutine prefixes the encoded data of a MySQL routine or diff with its integrity hash.
+func encodeRoutine(encodedData []byte) []byte {
+	var buf bytes.Buffer
+
+	integrityHash := sha256.Sum256(encodedData)
+	buf.Write(integrityHash[:])
+	buf.Write(encodedData)
+
+	return buf.Bytes()
+}
+
+// decodeRoutine decodes the version, name and definition of a MySQL routine, skipping the routine type it is tagged with.
+func decodeRoutine(data []byte) (int64, string, string, error) {
+	buf := bytes.NewBuffer(data)
+
+	if _, err := decode.DecodeString(buf); err != nil {
+		return 0, "", "", err
+	}
+	version, err := decode.DecodeInt(buf)
+	if err != nil {
+		return 0, "", "", err
+	}
+	name, err := decode.DecodeString(buf)
+	if err != nil {
+		return 0, "", "", err
+	}
+	definition, err := decode.DecodeString(buf)
+	if err != nil {
+		return 0, "", "", err
+	}
+
+	return *version, *name, *definition, nil
+}
+
+// diffRoutine returns the diff between the old routine and a routine with the given hash and definition.
+func diffRoutine(hash string, oldRoutine entities.Routine, isDiff bool, definition, oldDefinition *string) *MySQLRoutineDiff {
+	var prevRef string
+	if isDiff {
+		prevRef = fmt.Sprintf("diffs/%s", oldRoutine.Hash())
+	} else {
+		prevRef = oldRoutine.Hash()
+	}
+
+	diff := MySQLRoutineDiff{
+		hash:    hash,
+		PrevRef: prevRef,
+	}
+	comparation.AssignIfChanged(&diff.Definition, definition, oldDefinition)
+
+	return &diff
+}
+
+// MySQLRoutineDiff is the diff of a MySQL function, procedure, trigger or event, which only holds its new definition.
+type MySQLRoutineDiff struct {
+	hash       string
+	PrevRef    string
+	Definition *string
+}
+
+func (diff *MySQLRoutineDiff) Hash() string {
+	return diff.hash
+}
+
+func (diff *MySQLRoutineDiff) GetPrevRef() string {
+	return diff.PrevRef
+}
+
+func (diff *MySQLRoutineDiff) EncodeToBytes() []byte {
+	return encodeRoutine(diff.encodeData())
+}
+
+func (diff *MySQLRoutineDiff) DecodeFromBytes(data []byte) error {
+	buf := bytes.NewBuffer(data)
+
+	prevRef, err := decode.DecodeString(buf)
+	if err != nil {
+		return err
+	}
+	definition, err := decode.DecodeString(buf)
+	if err != nil {
+		return err
+	}
+
+	diff.PrevRef = *prevRef
+	diff.Definition = definition
+	return nil
+}
+
+func (diff *MySQLRoutineDiff) encodeData() []byte {
+	var buf bytes.Buffer
+
+	encode.EncodeString(&buf, &diff.PrevRef)
+	encode.EncodeString(&buf, diff.Definition)
+
+	return buf.Bytes()
+}
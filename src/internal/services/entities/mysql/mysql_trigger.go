@@ -0,0 +1,65 @@
+package mysql
+
+import (
+	"historydb/src/internal/entities"
+	"historydb/src/internal/utils/comparation"
+)
+
+var MYSQLTRIGGER_VERSION int64 = 1
+
+// MySQLTrigger represents a MySQL trigger. Its definition is the full CREATE TRIGGER statement without the DEFINER clause.
+type MySQLTrigger struct {
+	Version    int64
+	Name       string `json:"name"`
+	Definition string `json:"definition"`
+}
+
+func (trigger *MySQLTrigger) GetName() string {
+	return trigger.Name
+}
+
+func (trigger *MySQLTrigger) GetRoutineType() entities.RoutineType {
+	return entities.MySQLTrigger
+}
+
+func (trigger *MySQLTrigger) GetDependencies() []string {
+	return nil
+}
+
+func (trigger *MySQLTrigger) Hash() string {
+	return hashRoutine(trigger.encodeData())
+}
+
+func (trigger *MySQLTrigger) Diff(routine entities.Routine, isDiff bool) entities.RoutineDiff {
+	oldTrigger := routine.(*MySQLTrigger)
+	return diffRoutine(trigger.Hash(), routine, isDiff, &trigger.Definition, &oldTrigger.Definition)
+}
+
+func (trigger *MySQLTrigger) ApplyDiff(diff entities.RoutineDiff) entities.Routine {
+	updateTrigger := *trigger
+	triggerDiff := diff.(*MySQLRoutineDiff)
+
+	comparation.AssignIfNotNil(&updateTrigger.Definition, triggerDiff.Definition)
+
+	return &updateTrigger
+}
+
+func (trigger *MySQLTrigger) EncodeToBytes() []byte {
+	return encodeRoutine(trigger.encodeData())
+}
+
+func (trigger *MySQLTrigger) DecodeFromBytes(data []byte) error {
+	version, name, definition, err := decodeRoutine(data)
+	if err != nil {
+		return err
+	}
+
+	trigger.Version = version
+	trigger.Name = name
+	trigger.Definition = definition
+	return nil
+}
+
+func (trigger *MySQLTrigger) encodeData() []byte {
+	return encodeRoutineData(entities.MySQLTrigger, MYSQLTRIGGER_VERSION, trigger.Name, trigger.Definition)
+}
@@ -0,0 +1,25 @@
+package mysql
+
+// ComparablePK is a map used to know which data types in a primary key can be used to retrieve batched data from querying a table.
+// The keys are the MySQL DATA_TYPE values, so column types need to be stripped of their length and modifiers before checking them.
+var ComparablePK = map[string]bool{
+	"tinyint":   true,
+	"smallint":  true,
+	"mediumint": true,
+	"int":       true,
+	"integer":   true,
+	"bigint":    true,
+	"decimal":   true,
+	"numeric":   true,
+	"float":     true,
+	"double":    true,
+	"char":      true,
+	"varchar":   true,
+	"binary":    true,
+	"varbinary": true,
+	"date":      true,
+	"datetime":  true,
+	"timestamp": true,
+	"time":      true,
+	"year":      true,
+}
@@ -6,11 +6,14 @@ import (
 	"encoding/hex"
 	"fmt"
 	"historydb/src/internal/entities"
+	"historydb/src/internal/utils/comparation"
 	"historydb/src/internal/utils/crypto"
 	"historydb/src/internal/utils/decode"
 	"historydb/src/internal/utils/encode"
 	"historydb/src/internal/utils/pointers"
 	"math"
+	"slices"
+	"strings"
 )
 
 type SQLChunkCursor struct {
@@ -18,21 +21,78 @@ type SQLChunkCursor struct {
 	LastPK interface{}
 }
 
+// EncodeToBytes encodes the cursor, so the records of a table can be read from it again by another process.
+func (cursor *SQLChunkCursor) EncodeToBytes() []byte {
+	var buf bytes.Buffer
+	encode.EncodeInt(&buf, pointers.Ptr(int64(cursor.Offset)))
+
+	lastPK, _ := cursor.LastPK.([]interface{})
+	encode.EncodeBool(&buf, pointers.Ptr(lastPK != nil))
+	if lastPK != nil {
+		encode.EncodeInt(&buf, pointers.Ptr(int64(len(lastPK))))
+		// The key values come from the records of the last chunk, which already failed to be saved if a type is not supported
+		for _, value := range lastPK {
+			encode.EncodeValue(&buf, value)
+		}
+	}
+	return buf.Bytes()
+}
+
+func (cursor *SQLChunkCursor) DecodeFromBytes(data []byte) error {
+	buf := bytes.NewBuffer(data)
+
+	offset, err := decode.DecodeInt(buf)
+	if err != nil {
+		return err
+	}
+	hasLastPK, err := decode.DecodeBool(buf)
+	if err != nil {
+		return err
+	}
+	var lastPK interface{}
+	if *hasLastPK {
+		count, err := decode.DecodeInt(buf)
+		if err != nil {
+			return err
+		}
+		values := make([]interface{}, 0, *count)
+		for range *count {
+			value, err := decode.DecodeValue(buf)
+			if err != nil {
+				return err
+			}
+			values = append(values, value)
+		}
+		lastPK = values
+	}
+
+	cursor.Offset = int(*offset)
+	cursor.LastPK = lastPK
+	return nil
+}
+
 var SQLRECORDCHUNK_VERSION int64 = 1
 
+// SQLRecordChunk is a chunk of table records.
+// When PrimaryKey is set, its diffs match the records by their primary key values instead of by their position in the chunk.
+// PrimaryKey is set by the database readers and it is not encoded, so it does not change the chunk hash.
 type SQLRecordChunk struct {
-	hash    string
-	Version int64
-	Content []SQLRecord
+	hash       string
+	Version    int64
+	PrimaryKey []string
+	Content    []SQLRecord
 }
 
 func (chunk *SQLRecordChunk) Length() int {
 	return len(chunk.Content)
 }
 
+// Hash returns the hash of the encoded chunk. A chunk with a value of an unsupported type has no hash, and it fails once
+// it is saved.
 func (chunk *SQLRecordChunk) Hash() string {
-	hash := sha256.Sum256(chunk.encodeData())
-	chunk.hash = hex.EncodeToString(hash[:])
+	if _, err := chunk.encodeData(); err != nil {
+		return ""
+	}
 	return chunk.hash
 }
 
@@ -54,12 +114,19 @@ func (chunk *SQLRecordChunk) Diff(recordChunk entities.SchemaRecordChunk, isDiff
 		hash:    pointers.Ptr(chunk.Hash()),
 		PrevRef: &prevRef,
 	}
+	if chunk.IsKeyed() {
+		diff.Content = chunk.keyedDiff(oldChunk)
+		return &diff
+	}
+
 	records := []SQLRecordDiff{}
 	for i := 0; i < int(math.Max(float64(len(oldChunk.Content)), float64(len(chunk.Content)))); i++ {
 		if len(chunk.Content) > i && len(oldChunk.Content) > i && !crypto.CompareHashes(chunk.Content[i].Hash(), oldChunk.Content[i].Hash()) {
 			records = append(records, SQLRecordDiff{PrevRef: pointers.Ptr(oldChunk.Content[i].Hash()), Record: chunk.Content[i].Content})
 		} else if len(oldChunk.Content) <= i && len(chunk.Content) > i {
 			records = append(records, SQLRecordDiff{PrevRef: nil, Record: chunk.Content[i].Content})
+		} else if len(chunk.Content) <= i && len(oldChunk.Content) > i {
+			records = append(records, SQLRecordDiff{PrevRef: pointers.Ptr(oldChunk.Content[i].Hash())})
 		}
 	}
 	diff.Content = records
@@ -67,6 +134,158 @@ func (chunk *SQLRecordChunk) Diff(recordChunk entities.SchemaRecordChunk, isDiff
 	return &diff
 }
 
+// keyedDiff returns the records updated, inserted and deleted from the old chunk, matching them by primary key.
+// Updated and deleted records point to the hash of the old record, and deleted records do not have content.
+func (chunk *SQLRecordChunk) keyedDiff(oldChunk *SQLRecordChunk) []SQLRecordDiff {
+	oldRecords := make(map[string]string, len(oldChunk.Content))
+	for _, record := range oldChunk.Content {
+		oldRecords[record.Key(chunk.PrimaryKey)] = record.Hash()
+	}
+
+	records := []SQLRecordDiff{}
+	newRecords := make(map[string]bool, len(chunk.Content))
+	for _, record := range chunk.Content {
+		key := record.Key(chunk.PrimaryKey)
+		newRecords[key] = true
+
+		oldHash, ok := oldRecords[key]
+		if !ok {
+			records = append(records, SQLRecordDiff{PrevRef: nil, Record: record.Content})
+		} else if !crypto.CompareHashes(oldHash, record.Hash()) {
+			records = append(records, SQLRecordDiff{PrevRef: pointers.Ptr(oldHash), Record: record.Content})
+		}
+	}
+	for _, record := range oldChunk.Content {
+		if !newRecords[record.Key(chunk.PrimaryKey)] {
+			records = append(records, SQLRecordDiff{PrevRef: pointers.Ptr(record.Hash())})
+		}
+	}
+
+	return records
+}
+
+func (chunk *SQLRecordChunk) IsKeyed() bool {
+	return len(chunk.PrimaryKey) != 0
+}
+
+func (chunk *SQLRecordChunk) RecordKeys() []entities.SchemaRecordKey {
+	keys := make([]entities.SchemaRecordKey, len(chunk.Content))
+	for i, record := range chunk.Content {
+		keys[i] = entities.SchemaRecordKey{Key: record.Key(chunk.PrimaryKey), Hash: record.Hash()}
+	}
+	return keys
+}
+
+// CompareKeys decodes the values of the primary key columns of both keys and compares them one after another. The DB may
+// order some values in another way, as text under a collation, which only makes the diffs of the snapshots bigger.
+func (chunk *SQLRecordChunk) CompareKeys(key, otherKey string) int {
+	buf := bytes.NewBufferString(key)
+	otherBuf := bytes.NewBufferString(otherKey)
+	for range chunk.PrimaryKey {
+		value, err := decode.DecodeValue(buf)
+		if err != nil {
+			return strings.Compare(key, otherKey)
+		}
+		otherValue, err := decode.DecodeValue(otherBuf)
+		if err != nil {
+			return strings.Compare(key, otherKey)
+		}
+
+		if order := comparation.CompareValues(value, otherValue); order != 0 {
+			return order
+		}
+	}
+	return 0
+}
+
+func (chunk *SQLRecordChunk) KeyRecords(recordChunk entities.SchemaRecordChunk) entities.KeyedSchemaRecordChunk {
+	keyedChunk := *recordChunk.(*SQLRecordChunk)
+	keyedChunk.PrimaryKey = chunk.PrimaryKey
+	return &keyedChunk
+}
+
+func (chunk *SQLRecordChunk) Select(positions []int) entities.KeyedSchemaRecordChunk {
+	content := make([]SQLRecord, len(positions))
+	for i, position := range positions {
+		content[i] = chunk.Content[position]
+	}
+
+	return &SQLRecordChunk{
+		Version:    chunk.Version,
+		PrimaryKey: chunk.PrimaryKey,
+		Content:    content,
+	}
+}
+
+// Patch keeps the order of the chunk records, so the result is the same chunk that is rebuilt applying its diff.
+func (chunk *SQLRecordChunk) Patch(changes []entities.KeyedSchemaRecordChunk, deletedKeys map[string]bool) entities.KeyedSchemaRecordChunk {
+	changedRecords := make(map[string]SQLRecord)
+	changedKeys := []string{}
+	for _, changeChunk := range changes {
+		for _, record := range changeChunk.(*SQLRecordChunk).Content {
+			key := record.Key(chunk.PrimaryKey)
+			if _, ok := changedRecords[key]; !ok {
+				changedKeys = append(changedKeys, key)
+			}
+			changedRecords[key] = record
+		}
+	}
+
+	content := make([]SQLRecord, 0, len(chunk.Content)+len(changedRecords))
+	for _, record := range chunk.Content {
+		key := record.Key(chunk.PrimaryKey)
+		if deletedKeys[key] {
+			continue
+		}
+		if changedRecord, ok := changedRecords[key]; ok {
+			record = changedRecord
+			delete(changedRecords, key)
+		}
+		content = append(content, record)
+	}
+	for _, key := range changedKeys {
+		if record, ok := changedRecords[key]; ok {
+			content = append(content, record)
+		}
+	}
+
+	return &SQLRecordChunk{
+		Version:    chunk.Version,
+		PrimaryKey: chunk.PrimaryKey,
+		Content:    content,
+	}
+}
+
+func (chunk *SQLRecordChunk) RecordHashes() []string {
+	hashes := make([]string, len(chunk.Content))
+	for i, record := range chunk.Content {
+		hashes[i] = record.Hash()
+	}
+	return hashes
+}
+
+func (chunk *SQLRecordChunk) Slice(start, end int) entities.SplittableSchemaRecordChunk {
+	return &SQLRecordChunk{
+		Version:    chunk.Version,
+		PrimaryKey: chunk.PrimaryKey,
+		Content:    slices.Clone(chunk.Content[start:end]),
+	}
+}
+
+func (chunk *SQLRecordChunk) Append(recordChunk entities.SchemaRecordChunk) entities.SplittableSchemaRecordChunk {
+	return &SQLRecordChunk{
+		Version:    chunk.Version,
+		PrimaryKey: chunk.PrimaryKey,
+		Content:    slices.Concat(chunk.Content, recordChunk.(*SQLRecordChunk).Content),
+	}
+}
+
+func (chunk *SQLRecordChunk) DiffFromEmptyBefore(nextRef string) entities.SchemaRecordChunkDiff {
+	diff := chunk.DiffFromEmpty().(*SQLRecordChunkDiff)
+	diff.NextRef = &nextRef
+	return diff
+}
+
 func (chunk *SQLRecordChunk) DiffFromEmpty() entities.SchemaRecordChunkDiff {
 	diff := SQLRecordChunkDiff{
 		hash: pointers.Ptr(chunk.Hash()),
@@ -123,10 +342,14 @@ func (chunk *SQLRecordChunk) ApplyDiff(diff entities.SchemaRecordChunkDiff) enti
 	return &updateChunk
 }
 
-func (chunk *SQLRecordChunk) EncodeToBytes() []byte {
+// EncodeToBytes encodes the chunk, failing with the column and type of the first value whose type is not supported.
+func (chunk *SQLRecordChunk) EncodeToBytes() ([]byte, error) {
 	var buf bytes.Buffer
 	var auxBuf bytes.Buffer
-	encodedData := chunk.encodeData()
+	encodedData, err := chunk.encodeData()
+	if err != nil {
+		return nil, err
+	}
 
 	encode.EncodeString(&auxBuf, &chunk.hash)
 	auxBuf.Write(encodedData)
@@ -134,7 +357,7 @@ func (chunk *SQLRecordChunk) EncodeToBytes() []byte {
 	encode.EncodeInt(&buf, pointers.Ptr(int64(len(auxBuf.Bytes())))) // Saves buffer size so then we can extract the full buffer
 	buf.Write(auxBuf.Bytes())
 
-	return buf.Bytes()
+	return buf.Bytes(), nil
 }
 
 func (chunk *SQLRecordChunk) DecodeFromBytes(data []byte) error {
@@ -165,22 +388,27 @@ func (chunk *SQLRecordChunk) DecodeFromBytes(data []byte) error {
 	return nil
 }
 
-func (chunk *SQLRecordChunk) encodeData() []byte {
+func (chunk *SQLRecordChunk) encodeData() ([]byte, error) {
 	var buf bytes.Buffer
 	encode.EncodeInt(&buf, &SQLRECORDCHUNK_VERSION)
-	encode.EncodeSlice(&buf, chunk.Content)
+	if err := encode.EncodeFallibleSlice(&buf, chunk.Content); err != nil {
+		return nil, err
+	}
 
 	encodedData := buf.Bytes()
 
 	hash := sha256.Sum256(encodedData)
 	chunk.hash = hex.EncodeToString(hash[:])
 
-	return buf.Bytes()
+	return buf.Bytes(), nil
 }
 
+// SQLRecordChunkDiff is the difference between two versions of a chunk.
+// A diff without PrevRef adds a new chunk to the batch, at the end or before the chunk referenced by NextRef.
 type SQLRecordChunkDiff struct {
 	hash    *string
 	PrevRef *string
+	NextRef *string
 	Content []SQLRecordDiff
 }
 
@@ -196,6 +424,10 @@ func (diff *SQLRecordChunkDiff) GetPrevRef() *string {
 	return diff.PrevRef
 }
 
+func (diff *SQLRecordChunkDiff) GetNextRef() *string {
+	return diff.NextRef
+}
+
 func (diff *SQLRecordChunkDiff) GetRecordType() entities.RecordType {
 	return entities.SQLRecord
 }
@@ -206,14 +438,18 @@ func (diff *SQLRecordChunkDiff) ApplyDiffFromEmpty() entities.SchemaRecordChunk
 	return chunk
 }
 
-func (diff *SQLRecordChunkDiff) EncodeToBytes() []byte {
+// EncodeToBytes encodes the diff, which fails like the chunk if a changed record has a value of an unsupported type.
+func (diff *SQLRecordChunkDiff) EncodeToBytes() ([]byte, error) {
 	var buf bytes.Buffer
-	encodedData := diff.encodeData()
+	encodedData, err := diff.encodeData()
+	if err != nil {
+		return nil, err
+	}
 
 	encode.EncodeInt(&buf, pointers.Ptr(int64(len(encodedData)))) // Saves buffer size so then we can extract the full buffer
 	buf.Write(encodedData)
 
-	return buf.Bytes()
+	return buf.Bytes(), nil
 }
 
 func (diff *SQLRecordChunkDiff) DecodeFromBytes(data []byte) error {
@@ -250,21 +486,33 @@ func (diff *SQLRecordChunkDiff) DecodeFromBytes(data []byte) error {
 		}
 	}
 
+	var nextRef *string
+	if flags&(1<<3) != 0 {
+		nextRef, err = decode.DecodeString(buf)
+		if err != nil {
+			return err
+		}
+	}
+
 	diff.hash = hash
 	diff.PrevRef = prevRef
+	diff.NextRef = nextRef
 	diff.Content = contentSlice
 	return nil
 }
 
-func (diff *SQLRecordChunkDiff) encodeData() []byte {
+func (diff *SQLRecordChunkDiff) encodeData() ([]byte, error) {
 	var buf bytes.Buffer
 
 	buf.WriteByte(diff.getByteFlags())
 	encode.EncodeString(&buf, diff.hash)
 	encode.EncodeString(&buf, diff.PrevRef)
-	encode.EncodeSlice(&buf, diff.Content)
+	if err := encode.EncodeFallibleSlice(&buf, diff.Content); err != nil {
+		return nil, err
+	}
+	encode.EncodeString(&buf, diff.NextRef)
 
-	return buf.Bytes()
+	return buf.Bytes(), nil
 }
 
 func (diff *SQLRecordChunkDiff) getByteFlags() byte {
@@ -279,6 +527,9 @@ func (diff *SQLRecordChunkDiff) getByteFlags() byte {
 	if len(diff.Content) != 0 {
 		flags |= 1 << 2
 	}
+	if diff.NextRef != nil {
+		flags |= 1 << 3
+	}
 
 	return flags
 }
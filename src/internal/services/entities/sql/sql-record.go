@@ -15,16 +15,34 @@ type SQLRecord struct {
 
 func (record SQLRecord) Hash() string {
 	if record.hash == "" {
-		hash := sha256.Sum256(record.EncodeToBytes())
+		// A record with a value of an unsupported type has no hash, so it matches no other record until its chunk fails to be saved
+		content, err := record.EncodeToBytes()
+		if err != nil {
+			return ""
+		}
+		hash := sha256.Sum256(content)
 		record.hash = hex.EncodeToString(hash[:])
 	}
 	return record.hash
 }
 
-func (record SQLRecord) EncodeToBytes() []byte {
+// Key returns the values of the primary key columns encoded with their types, so records with the same values
+// in those columns have the same key.
+func (record SQLRecord) Key(primaryKey []string) string {
+	var buf bytes.Buffer
+	for _, column := range primaryKey {
+		// The key values are also saved with the record, whose encoding reports the unsupported ones
+		encode.EncodeValue(&buf, record.Content[column])
+	}
+	return buf.String()
+}
+
+func (record SQLRecord) EncodeToBytes() ([]byte, error) {
 	var buf bytes.Buffer
-	encode.EncodeMap(&buf, record.Content)
-	return buf.Bytes()
+	if err := encode.EncodeMap(&buf, record.Content); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
 }
 
 func (record *SQLRecord) DecodeFromBytes(data []byte) (*SQLRecord, error) {
@@ -44,14 +62,16 @@ type SQLRecordDiff struct {
 	Record  map[string]interface{}
 }
 
-func (diff SQLRecordDiff) EncodeToBytes() []byte {
+func (diff SQLRecordDiff) EncodeToBytes() ([]byte, error) {
 	var buf bytes.Buffer
 
 	buf.WriteByte(diff.getByteFlags())
 	encode.EncodeString(&buf, diff.PrevRef)
-	encode.EncodeMap(&buf, diff.Record)
+	if err := encode.EncodeMap(&buf, diff.Record); err != nil {
+		return nil, err
+	}
 
-	return buf.Bytes()
+	return buf.Bytes(), nil
 }
 
 func (diff *SQLRecordDiff) DecodeFromBytes(data []byte) (*SQLRecordDiff, error) {
@@ -31,6 +31,16 @@ func (table *SQLTable) GetName() string {
 	return table.Name
 }
 
+// PrimaryKeyColumns returns the columns of the table primary key, or nil if the table does not have one.
+func (table *SQLTable) PrimaryKeyColumns() []string {
+	for _, constraint := range table.Constraints {
+		if constraint.Type == PrimaryKey {
+			return constraint.Columns
+		}
+	}
+	return nil
+}
+
 func (table *SQLTable) Hash() string {
 	hash := sha256.Sum256(table.encodeData())
 	return hex.EncodeToString(hash[:])
@@ -137,7 +147,8 @@ func (table *SQLTable) DecodeFromBytes(data []byte) error {
 		}
 	}
 	var idxSlice []SQLTableIndex
-	if flags&(1<<3) != 0 {
+	// Older backups flagged the indexes in the fifth bit
+	if flags&(1<<3|1<<4) != 0 {
 		idxs, err := decode.DecodeSlice[*SQLTableIndex](buf)
 		if err != nil {
 			return err
@@ -185,7 +196,7 @@ func (table *SQLTable) getByteFlags() byte {
 		flags |= 1 << 2
 	}
 	if len(table.Indexes) > 0 {
-		flags |= 2 << 3
+		flags |= 1 << 3
 	}
 	return flags
 }
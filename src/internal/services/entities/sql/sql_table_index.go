@@ -22,6 +22,7 @@ func (index SQLTableIndex) EncodeToBytes() []byte {
 	encode.EncodeString(&buf, &index.Name)
 	encode.EncodeString(&buf, &index.Type)
 	encode.EncodePrimitiveSlice(&buf, index.Columns)
+	// The options are only strings and booleans read from the catalog of the DB, whose types are always supported
 	encode.EncodeMap(&buf, index.Options)
 
 	return buf.Bytes()
@@ -0,0 +1,176 @@
+package test
+
+import (
+	"fmt"
+	"historydb/src/internal/entities"
+	"historydb/src/internal/services/entities/sql"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSQLRecordChunkRecordKeys(t *testing.T) {
+	chunk := testUsersChunk(1, 2, 3)
+	updatedChunk := testUsersChunk(1, 2, 3)
+	updatedChunk.Content[1].Content["name"] = "updated"
+
+	keys := chunk.RecordKeys()
+	updatedKeys := updatedChunk.RecordKeys()
+	if !assert.Len(t, keys, 3, "RecordKeys") {
+		return
+	}
+	for i := range keys {
+		assert.Equal(t, chunk.Content[i].Hash(), keys[i].Hash, "RecordKeys - Hash")
+		assert.Equal(t, keys[i].Key, updatedKeys[i].Key, "RecordKeys - Key of updated record")
+	}
+	assert.NotEqual(t, keys[0].Key, keys[1].Key, "RecordKeys - Key of another record")
+	assert.NotEqual(t, keys[1].Hash, updatedKeys[1].Hash, "RecordKeys - Hash of updated record")
+
+	// Keys only depend on the primary key columns
+	compositeChunk := &sql.SQLRecordChunk{PrimaryKey: []string{"tenant", "id"}, Content: []sql.SQLRecord{
+		{Content: map[string]interface{}{"tenant": int64(1), "id": int64(1), "name": "a"}},
+		{Content: map[string]interface{}{"tenant": int64(1), "id": int64(1), "name": "b"}},
+		{Content: map[string]interface{}{"tenant": int64(2), "id": int64(1), "name": "a"}},
+	}}
+	compositeKeys := compositeChunk.RecordKeys()
+	assert.Equal(t, compositeKeys[0].Key, compositeKeys[1].Key, "RecordKeys - Composite key")
+	assert.NotEqual(t, compositeKeys[0].Key, compositeKeys[2].Key, "RecordKeys - Composite key")
+	assert.True(t, compositeChunk.IsKeyed(), "IsKeyed")
+	assert.False(t, (&sql.SQLRecordChunk{}).IsKeyed(), "IsKeyed without primary key")
+}
+
+func TestSQLRecordChunkCompareKeys(t *testing.T) {
+	chunk := testUsersChunk(2, 10, -5, 10)
+	keys := chunk.RecordKeys()
+
+	// Numbers are compared by value instead of by their encoding
+	assert.Negative(t, chunk.CompareKeys(keys[0].Key, keys[1].Key), "CompareKeys - 2 < 10")
+	assert.Positive(t, chunk.CompareKeys(keys[0].Key, keys[2].Key), "CompareKeys - 2 > -5")
+	assert.Zero(t, chunk.CompareKeys(keys[1].Key, keys[3].Key), "CompareKeys - 10 = 10")
+
+	compositeChunk := &sql.SQLRecordChunk{PrimaryKey: []string{"tenant", "name"}, Content: []sql.SQLRecord{
+		{Content: map[string]interface{}{"tenant": int64(1), "name": "b"}},
+		{Content: map[string]interface{}{"tenant": int64(1), "name": "ab"}},
+		{Content: map[string]interface{}{"tenant": int64(2), "name": "a"}},
+	}}
+	compositeKeys := compositeChunk.RecordKeys()
+	assert.Positive(t, compositeChunk.CompareKeys(compositeKeys[0].Key, compositeKeys[1].Key), "CompareKeys - Second column")
+	assert.Negative(t, compositeChunk.CompareKeys(compositeKeys[0].Key, compositeKeys[2].Key), "CompareKeys - First column")
+}
+
+func TestSQLRecordChunkSelect(t *testing.T) {
+	chunk := testUsersChunk(1, 2, 3, 4)
+
+	selected := chunk.Select([]int{3, 1}).(*sql.SQLRecordChunk)
+	assert.Equal(t, testUsersChunk(4, 2).Content, selected.Content, "Select")
+	assert.Equal(t, chunk.PrimaryKey, selected.PrimaryKey, "Select - Primary key")
+	assert.Equal(t, 0, chunk.Select(nil).Length(), "Select - No positions")
+}
+
+func TestSQLRecordChunkPatch(t *testing.T) {
+	chunk := testUsersChunk(1, 2, 3, 4)
+	changes := testUsersChunk(3, 7, 5)
+	changes.Content[0].Content["name"] = "updated"
+
+	// Updated records keep their position, deleted ones are removed and inserted ones are appended in order
+	patched := chunk.Patch([]entities.KeyedSchemaRecordChunk{changes}, testDeletedKeys(chunk, 1))
+	expected := testUsersChunk(1, 3, 4, 7, 5)
+	expected.Content[1].Content["name"] = "updated"
+	assert.Equal(t, expected.Content, patched.(*sql.SQLRecordChunk).Content, "Patch")
+
+	// The last change of a key is kept
+	lastChanges := testUsersChunk(2)
+	lastChanges.Content[0].Content["name"] = "last"
+	patched = chunk.Patch([]entities.KeyedSchemaRecordChunk{testUsersChunk(2), lastChanges}, nil)
+	assert.Equal(t, "last", patched.(*sql.SQLRecordChunk).Content[1].Content["name"], "Patch - Repeated key")
+	assert.Equal(t, 4, patched.Length(), "Patch - Repeated key")
+}
+
+func TestSQLRecordChunkApplyDiff(t *testing.T) {
+	// Records are updated, deleted and moved across two chunks, and the diffs rebuild the patched chunks
+	firstChunk := testUsersChunk(1, 2, 3, 4)
+	secondChunk := testUsersChunk(5, 6, 7, 8)
+
+	firstChanges := testUsersChunk(2, 9)
+	firstChanges.Content[0].Content["name"] = "updated"
+	secondChanges := testUsersChunk(4, 8)
+	secondChanges.Content[1].Content["name"] = "updated"
+
+	tests := []struct {
+		name    string
+		chunk   *sql.SQLRecordChunk
+		patched entities.KeyedSchemaRecordChunk
+	}{
+		{"First chunk", firstChunk, firstChunk.Patch([]entities.KeyedSchemaRecordChunk{firstChanges}, testDeletedKeys(firstChunk, 0, 3))},
+		{"Second chunk", secondChunk, secondChunk.Patch([]entities.KeyedSchemaRecordChunk{secondChanges}, testDeletedKeys(secondChunk, 1))},
+	}
+	for _, test := range tests {
+		diff := test.patched.Diff(test.chunk, false).(*sql.SQLRecordChunkDiff)
+
+		// Updated and deleted records point to the hash of the old record
+		for _, recordDiff := range diff.Content {
+			if recordDiff.PrevRef != nil {
+				assert.Contains(t, test.chunk.RecordHashes(), *recordDiff.PrevRef, fmt.Sprintf("Diff - Test: %s", test.name))
+			}
+		}
+
+		rebuilt := testCloneChunk(test.chunk).ApplyDiff(diff)
+		if assert.NotNil(t, rebuilt, fmt.Sprintf("ApplyDiff - Test: %s", test.name)) {
+			assert.Equal(t, test.patched.Hash(), rebuilt.Hash(), fmt.Sprintf("ApplyDiff - Test: %s", test.name))
+			assert.Equal(t, *diff.Hash(), rebuilt.Hash(), fmt.Sprintf("ApplyDiff - Test: %s", test.name))
+		}
+	}
+
+	// Records are matched by hash, so the diff can not be applied to a chunk without the old record
+	diff := tests[0].patched.Diff(firstChunk, false)
+	assert.Nil(t, testCloneChunk(secondChunk).ApplyDiff(diff), "ApplyDiff - Another chunk")
+}
+
+func TestSQLRecordChunkUnsupportedValue(t *testing.T) {
+	// A value of a type the codec does not know fails the encoding with its column and type, instead of a panic
+	chunk := testUsersChunk(1, 2)
+	chunk.Content[1].Content["age"] = int32(30)
+
+	_, err := chunk.EncodeToBytes()
+	assert.ErrorContains(t, err, "age: unsupported value type: int32", "EncodeToBytes")
+	assert.Empty(t, chunk.Hash(), "Hash")
+	assert.Empty(t, chunk.Content[1].Hash(), "Hash - Record")
+
+	diff := chunk.DiffFromEmpty()
+	_, err = diff.EncodeToBytes()
+	assert.ErrorContains(t, err, "age: unsupported value type: int32", "EncodeToBytes - Diff")
+
+	_, err = testUsersChunk(1, 2).EncodeToBytes()
+	assert.NoError(t, err, "EncodeToBytes - Supported values")
+}
+
+// testUsersChunk returns a chunk of users keyed by id, with a record for every id.
+func testUsersChunk(ids ...int64) *sql.SQLRecordChunk {
+	content := make([]sql.SQLRecord, len(ids))
+	for i, id := range ids {
+		content[i] = sql.SQLRecord{Content: map[string]interface{}{"id": id, "name": fmt.Sprintf("user-%d", id)}}
+	}
+	return &sql.SQLRecordChunk{PrimaryKey: []string{"id"}, Content: content}
+}
+
+func testDeletedKeys(chunk *sql.SQLRecordChunk, positions ...int) map[string]bool {
+	keys := chunk.RecordKeys()
+	deletedKeys := make(map[string]bool)
+	for _, position := range positions {
+		deletedKeys[keys[position].Key] = true
+	}
+	return deletedKeys
+}
+
+// testCloneChunk copies the records of the chunk, as applying a diff updates the records in place.
+func testCloneChunk(chunk *sql.SQLRecordChunk) *sql.SQLRecordChunk {
+	content := make([]sql.SQLRecord, len(chunk.Content))
+	for i, record := range chunk.Content {
+		recordContent := make(map[string]interface{}, len(record.Content))
+		for column, value := range record.Content {
+			recordContent[column] = value
+		}
+		content[i] = sql.SQLRecord{Content: recordContent}
+	}
+	return &sql.SQLRecordChunk{PrimaryKey: chunk.PrimaryKey, Content: content}
+}
@@ -0,0 +1,181 @@
+package sqlite
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"historydb/src/internal/entities"
+	"historydb/src/internal/utils/comparation"
+	"historydb/src/internal/utils/decode"
+	"historydb/src/internal/utils/encode"
+	"historydb/src/internal/utils/pointers"
+	"historydb/src/internal/utils/types"
+)
+
+var SQLITESEQUENCE_VERSION int64 = 1
+
+// SQLiteSequence represents the AUTOINCREMENT counter of a SQLite table stored in sqlite_sequence.
+// Its name is the table it belongs to, as the counter can not exist without the table.
+type SQLiteSequence struct {
+	Version int64        `json:"version"`
+	Name    string       `json:"name"`
+	Value   types.BigInt `json:"value"`
+}
+
+func (sequence *SQLiteSequence) GetDependencyType() entities.DependencyType {
+	return entities.SQLiteSequence
+}
+
+func (sequence *SQLiteSequence) GetName() string {
+	return sequence.Name
+}
+
+func (sequence *SQLiteSequence) Hash() string {
+	hash := sha256.Sum256(sequence.encodeData())
+	return hex.EncodeToString(hash[:])
+}
+
+func (sequence *SQLiteSequence) Diff(dependency entities.SchemaDependency, isDiff bool) entities.SchemaDependencyDiff {
+	oldSequence := dependency.(*SQLiteSequence)
+
+	var prevRef string
+	if isDiff {
+		prevRef = fmt.Sprintf("diffs/%s", dependency.Hash())
+	} else {
+		prevRef = dependency.Hash()
+	}
+
+	diff := SQLiteSequenceDiff{
+		hash:    sequence.Hash(),
+		PrevRef: prevRef,
+	}
+	comparation.AssignIfChangedWithEquality(&diff.Value, &sequence.Value, &oldSequence.Value, func(a, b *types.BigInt) bool { return a.Cmp(&b.Int) == 0 })
+
+	return &diff
+}
+
+func (sequence *SQLiteSequence) ApplyDiff(diff entities.SchemaDependencyDiff) entities.SchemaDependency {
+	updateSequence := *sequence
+	sequenceDiff := diff.(*SQLiteSequenceDiff)
+
+	comparation.AssignIfNotNil(&updateSequence.Value, sequenceDiff.Value)
+
+	return &updateSequence
+}
+
+func (sequence *SQLiteSequence) EncodeToBytes() []byte {
+	var buf bytes.Buffer
+
+	encodedData := sequence.encodeData()
+	integrityHash := sha256.Sum256(encodedData)
+
+	buf.Write(integrityHash[:])
+	buf.Write(encodedData)
+
+	return buf.Bytes()
+}
+
+func (sequence *SQLiteSequence) DecodeFromBytes(data []byte) error {
+	buf := bytes.NewBuffer(data)
+
+	if _, err := decode.DecodeString(buf); err != nil {
+		return err
+	}
+	version, err := decode.DecodeInt(buf)
+	if err != nil {
+		return err
+	}
+	name, err := decode.DecodeString(buf)
+	if err != nil {
+		return err
+	}
+	value, err := decode.DecodeBigInt(buf)
+	if err != nil {
+		return err
+	}
+
+	sequence.Version = *version
+	sequence.Name = *name
+	sequence.Value = *value
+	return nil
+}
+
+func (sequence *SQLiteSequence) encodeData() []byte {
+	var buf bytes.Buffer
+
+	encode.EncodeString(&buf, (*string)(pointers.Ptr(entities.SQLiteSequence)))
+	encode.EncodeInt(&buf, &SQLITESEQUENCE_VERSION)
+	encode.EncodeString(&buf, &sequence.Name)
+	encode.EncodeBigInt(&buf, &sequence.Value)
+
+	return buf.Bytes()
+}
+
+type SQLiteSequenceDiff struct {
+	hash    string
+	PrevRef string
+	Value   *types.BigInt
+}
+
+func (diff *SQLiteSequenceDiff) Hash() string {
+	return diff.hash
+}
+
+func (diff *SQLiteSequenceDiff) GetPrevRef() string {
+	return diff.PrevRef
+}
+
+func (diff *SQLiteSequenceDiff) EncodeToBytes() []byte {
+	var buf bytes.Buffer
+
+	encodedData := diff.encodeData()
+	integrityHash := sha256.Sum256(encodedData)
+
+	buf.Write(integrityHash[:])
+	buf.Write(encodedData)
+
+	return buf.Bytes()
+}
+
+func (diff *SQLiteSequenceDiff) DecodeFromBytes(data []byte) error {
+	buf := bytes.NewBuffer(data)
+
+	prevRef, err := decode.DecodeString(buf)
+	if err != nil {
+		return err
+	}
+	flags, err := buf.ReadByte()
+	if err != nil {
+		return err
+	}
+	var value *types.BigInt
+	if flags&(1<<0) != 0 {
+		value, err = decode.DecodeBigInt(buf)
+		if err != nil {
+			return err
+		}
+	}
+
+	diff.PrevRef = *prevRef
+	diff.Value = value
+	return nil
+}
+
+func (diff *SQLiteSequenceDiff) encodeData() []byte {
+	var buf bytes.Buffer
+
+	encode.EncodeString(&buf, &diff.PrevRef)
+	buf.WriteByte(diff.getByteFlags())
+	encode.EncodeBigInt(&buf, diff.Value)
+
+	return buf.Bytes()
+}
+
+func (diff *SQLiteSequenceDiff) getByteFlags() byte {
+	var flags byte
+	if diff.Value != nil {
+		flags |= 1 << 0
+	}
+	return flags
+}
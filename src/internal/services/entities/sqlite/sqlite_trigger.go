@@ -0,0 +1,235 @@
+package sqlite
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"historydb/src/internal/entities"
+	"historydb/src/internal/utils/comparation"
+	"historydb/src/internal/utils/decode"
+	"historydb/src/internal/utils/encode"
+	"historydb/src/internal/utils/pointers"
+	"slices"
+)
+
+var SQLITETRIGGER_VERSION int64 = 1
+
+// SQLiteTrigger represents a SQLite trigger. Its definition is the CREATE TRIGGER statement stored in sqlite_master,
+// and it is attached to a table or, for INSTEAD OF triggers, to a view. The view is its only dependency, as tables are
+// restored before every routine.
+type SQLiteTrigger struct {
+	Version      int64
+	Name         string   `json:"name"`
+	Dependencies []string `json:"dependencies"`
+	Definition   string   `json:"definition"`
+}
+
+func (trigger *SQLiteTrigger) GetName() string {
+	return trigger.Name
+}
+
+func (trigger *SQLiteTrigger) GetRoutineType() entities.RoutineType {
+	return entities.SQLiteTrigger
+}
+
+func (trigger *SQLiteTrigger) GetDependencies() []string {
+	return trigger.Dependencies
+}
+
+func (trigger *SQLiteTrigger) Hash() string {
+	hash := sha256.Sum256(trigger.encodeData())
+	return hex.EncodeToString(hash[:])
+}
+
+func (trigger *SQLiteTrigger) Diff(routine entities.Routine, isDiff bool) entities.RoutineDiff {
+	oldTrigger := routine.(*SQLiteTrigger)
+
+	var prevRef string
+	if isDiff {
+		prevRef = fmt.Sprintf("diffs/%s", routine.Hash())
+	} else {
+		prevRef = routine.Hash()
+	}
+
+	diff := SQLiteTriggerDiff{
+		hash:    trigger.Hash(),
+		PrevRef: prevRef,
+	}
+	comparation.AssignIfChanged(&diff.Definition, &trigger.Definition, &oldTrigger.Definition)
+
+	if !slices.Equal(trigger.Dependencies, oldTrigger.Dependencies) {
+		diff.Dependencies = make([]string, len(trigger.Dependencies))
+		copy(diff.Dependencies, trigger.Dependencies)
+	}
+
+	return &diff
+}
+
+func (trigger *SQLiteTrigger) ApplyDiff(diff entities.RoutineDiff) entities.Routine {
+	updateTrigger := *trigger
+	triggerDiff := diff.(*SQLiteTriggerDiff)
+
+	comparation.AssignIfNotNil(&updateTrigger.Definition, triggerDiff.Definition)
+
+	if len(triggerDiff.Dependencies) > 0 {
+		updateTrigger.Dependencies = make([]string, len(triggerDiff.Dependencies))
+		copy(updateTrigger.Dependencies, triggerDiff.Dependencies)
+	}
+
+	return &updateTrigger
+}
+
+func (trigger *SQLiteTrigger) EncodeToBytes() []byte {
+	var buf bytes.Buffer
+
+	encodedData := trigger.encodeData()
+	integrityHash := sha256.Sum256(encodedData)
+
+	buf.Write(integrityHash[:])
+	buf.Write(encodedData)
+
+	return buf.Bytes()
+}
+
+func (trigger *SQLiteTrigger) DecodeFromBytes(data []byte) error {
+	buf := bytes.NewBuffer(data)
+
+	dependencies := []string{}
+
+	if _, err := decode.DecodeString(buf); err != nil {
+		return err
+	}
+	version, err := decode.DecodeInt(buf)
+	if err != nil {
+		return err
+	}
+	flags, err := buf.ReadByte()
+	if err != nil {
+		return err
+	}
+	name, err := decode.DecodeString(buf)
+	if err != nil {
+		return err
+	}
+	if flags&(1<<0) != 0 {
+		dependencies, err = decode.DecodePrimitiveSlice[string](buf)
+		if err != nil {
+			return err
+		}
+	}
+	definition, err := decode.DecodeString(buf)
+	if err != nil {
+		return err
+	}
+
+	trigger.Version = *version
+	trigger.Name = *name
+	trigger.Dependencies = dependencies
+	trigger.Definition = *definition
+	return nil
+}
+
+func (trigger *SQLiteTrigger) encodeData() []byte {
+	var buf bytes.Buffer
+
+	encode.EncodeString(&buf, (*string)(pointers.Ptr(entities.SQLiteTrigger)))
+	encode.EncodeInt(&buf, &SQLITETRIGGER_VERSION)
+	buf.WriteByte(trigger.getByteFlags())
+	encode.EncodeString(&buf, &trigger.Name)
+	encode.EncodePrimitiveSlice(&buf, trigger.Dependencies)
+	encode.EncodeString(&buf, &trigger.Definition)
+
+	return buf.Bytes()
+}
+
+func (trigger *SQLiteTrigger) getByteFlags() byte {
+	var flags byte
+	if len(trigger.Dependencies) > 0 {
+		flags |= 1 << 0
+	}
+	return flags
+}
+
+type SQLiteTriggerDiff struct {
+	hash         string
+	PrevRef      string
+	Dependencies []string
+	Definition   *string
+}
+
+func (diff *SQLiteTriggerDiff) Hash() string {
+	return diff.hash
+}
+
+func (diff *SQLiteTriggerDiff) GetPrevRef() string {
+	return diff.PrevRef
+}
+
+func (diff *SQLiteTriggerDiff) EncodeToBytes() []byte {
+	var buf bytes.Buffer
+
+	encodedData := diff.encodeData()
+	integrityHash := sha256.Sum256(encodedData)
+
+	buf.Write(integrityHash[:])
+	buf.Write(encodedData)
+
+	return buf.Bytes()
+}
+
+func (diff *SQLiteTriggerDiff) DecodeFromBytes(data []byte) error {
+	buf := bytes.NewBuffer(data)
+
+	prevRef, err := decode.DecodeString(buf)
+	if err != nil {
+		return err
+	}
+	flags, err := buf.ReadByte()
+	if err != nil {
+		return err
+	}
+
+	var definition *string
+	var dependencies []string
+
+	if flags&(1<<0) != 0 {
+		dependencies, err = decode.DecodePrimitiveSlice[string](buf)
+		if err != nil {
+			return err
+		}
+	}
+	if flags&(1<<1) != 0 {
+		definition, err = decode.DecodeString(buf)
+		if err != nil {
+			return err
+		}
+	}
+
+	diff.PrevRef = *prevRef
+	diff.Dependencies = dependencies
+	diff.Definition = definition
+	return nil
+}
+
+func (diff *SQLiteTriggerDiff) encodeData() []byte {
+	var buf bytes.Buffer
+
+	encode.EncodeString(&buf, &diff.PrevRef)
+	buf.WriteByte(diff.getByteFlags())
+	encode.EncodePrimitiveSlice(&buf, diff.Dependencies)
+	encode.EncodeString(&buf, diff.Definition)
+
+	return buf.Bytes()
+}
+
+func (diff *SQLiteTriggerDiff) getByteFlags() byte {
+	var flags byte
+	if len(diff.Dependencies) > 0 {
+		flags |= 1 << 0
+	}
+	if diff.Definition != nil {
+		flags |= 1 << 1
+	}
+	return flags
+}
@@ -0,0 +1,234 @@
+package sqlite
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"historydb/src/internal/entities"
+	"historydb/src/internal/utils/comparation"
+	"historydb/src/internal/utils/decode"
+	"historydb/src/internal/utils/encode"
+	"historydb/src/internal/utils/pointers"
+	"slices"
+)
+
+var SQLITEVIEW_VERSION int64 = 1
+
+// SQLiteView represents a SQLite view. Its definition is the CREATE VIEW statement stored in sqlite_master,
+// and its dependencies are the views it reads from, so they are restored before it.
+type SQLiteView struct {
+	Version      int64
+	Name         string   `json:"name"`
+	Dependencies []string `json:"dependencies"`
+	Definition   string   `json:"definition"`
+}
+
+func (view *SQLiteView) GetName() string {
+	return view.Name
+}
+
+func (view *SQLiteView) GetRoutineType() entities.RoutineType {
+	return entities.SQLiteView
+}
+
+func (view *SQLiteView) GetDependencies() []string {
+	return view.Dependencies
+}
+
+func (view *SQLiteView) Hash() string {
+	hash := sha256.Sum256(view.encodeData())
+	return hex.EncodeToString(hash[:])
+}
+
+func (view *SQLiteView) Diff(routine entities.Routine, isDiff bool) entities.RoutineDiff {
+	oldView := routine.(*SQLiteView)
+
+	var prevRef string
+	if isDiff {
+		prevRef = fmt.Sprintf("diffs/%s", routine.Hash())
+	} else {
+		prevRef = routine.Hash()
+	}
+
+	diff := SQLiteViewDiff{
+		hash:    view.Hash(),
+		PrevRef: prevRef,
+	}
+	comparation.AssignIfChanged(&diff.Definition, &view.Definition, &oldView.Definition)
+
+	if !slices.Equal(view.Dependencies, oldView.Dependencies) {
+		diff.Dependencies = make([]string, len(view.Dependencies))
+		copy(diff.Dependencies, view.Dependencies)
+	}
+
+	return &diff
+}
+
+func (view *SQLiteView) ApplyDiff(diff entities.RoutineDiff) entities.Routine {
+	updateView := *view
+	viewDiff := diff.(*SQLiteViewDiff)
+
+	comparation.AssignIfNotNil(&updateView.Definition, viewDiff.Definition)
+
+	if len(viewDiff.Dependencies) > 0 {
+		updateView.Dependencies = make([]string, len(viewDiff.Dependencies))
+		copy(updateView.Dependencies, viewDiff.Dependencies)
+	}
+
+	return &updateView
+}
+
+func (view *SQLiteView) EncodeToBytes() []byte {
+	var buf bytes.Buffer
+
+	encodedData := view.encodeData()
+	integrityHash := sha256.Sum256(encodedData)
+
+	buf.Write(integrityHash[:])
+	buf.Write(encodedData)
+
+	return buf.Bytes()
+}
+
+func (view *SQLiteView) DecodeFromBytes(data []byte) error {
+	buf := bytes.NewBuffer(data)
+
+	dependencies := []string{}
+
+	if _, err := decode.DecodeString(buf); err != nil {
+		return err
+	}
+	version, err := decode.DecodeInt(buf)
+	if err != nil {
+		return err
+	}
+	flags, err := buf.ReadByte()
+	if err != nil {
+		return err
+	}
+	name, err := decode.DecodeString(buf)
+	if err != nil {
+		return err
+	}
+	if flags&(1<<0) != 0 {
+		dependencies, err = decode.DecodePrimitiveSlice[string](buf)
+		if err != nil {
+			return err
+		}
+	}
+	definition, err := decode.DecodeString(buf)
+	if err != nil {
+		return err
+	}
+
+	view.Version = *version
+	view.Name = *name
+	view.Dependencies = dependencies
+	view.Definition = *definition
+	return nil
+}
+
+func (view *SQLiteView) encodeData() []byte {
+	var buf bytes.Buffer
+
+	encode.EncodeString(&buf, (*string)(pointers.Ptr(entities.SQLiteView)))
+	encode.EncodeInt(&buf, &SQLITEVIEW_VERSION)
+	buf.WriteByte(view.getByteFlags())
+	encode.EncodeString(&buf, &view.Name)
+	encode.EncodePrimitiveSlice(&buf, view.Dependencies)
+	encode.EncodeString(&buf, &view.Definition)
+
+	return buf.Bytes()
+}
+
+func (view *SQLiteView) getByteFlags() byte {
+	var flags byte
+	if len(view.Dependencies) > 0 {
+		flags |= 1 << 0
+	}
+	return flags
+}
+
+type SQLiteViewDiff struct {
+	hash         string
+	PrevRef      string
+	Dependencies []string
+	Definition   *string
+}
+
+func (diff *SQLiteViewDiff) Hash() string {
+	return diff.hash
+}
+
+func (diff *SQLiteViewDiff) GetPrevRef() string {
+	return diff.PrevRef
+}
+
+func (diff *SQLiteViewDiff) EncodeToBytes() []byte {
+	var buf bytes.Buffer
+
+	encodedData := diff.encodeData()
+	integrityHash := sha256.Sum256(encodedData)
+
+	buf.Write(integrityHash[:])
+	buf.Write(encodedData)
+
+	return buf.Bytes()
+}
+
+func (diff *SQLiteViewDiff) DecodeFromBytes(data []byte) error {
+	buf := bytes.NewBuffer(data)
+
+	prevRef, err := decode.DecodeString(buf)
+	if err != nil {
+		return err
+	}
+	flags, err := buf.ReadByte()
+	if err != nil {
+		return err
+	}
+
+	var definition *string
+	var dependencies []string
+
+	if flags&(1<<0) != 0 {
+		dependencies, err = decode.DecodePrimitiveSlice[string](buf)
+		if err != nil {
+			return err
+		}
+	}
+	if flags&(1<<1) != 0 {
+		definition, err = decode.DecodeString(buf)
+		if err != nil {
+			return err
+		}
+	}
+
+	diff.PrevRef = *prevRef
+	diff.Dependencies = dependencies
+	diff.Definition = definition
+	return nil
+}
+
+func (diff *SQLiteViewDiff) encodeData() []byte {
+	var buf bytes.Buffer
+
+	encode.EncodeString(&buf, &diff.PrevRef)
+	buf.WriteByte(diff.getByteFlags())
+	encode.EncodePrimitiveSlice(&buf, diff.Dependencies)
+	encode.EncodeString(&buf, diff.Definition)
+
+	return buf.Bytes()
+}
+
+func (diff *SQLiteViewDiff) getByteFlags() byte {
+	var flags byte
+	if len(diff.Dependencies) > 0 {
+		flags |= 1 << 0
+	}
+	if diff.Definition != nil {
+		flags |= 1 << 1
+	}
+	return flags
+}
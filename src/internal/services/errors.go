@@ -3,15 +3,32 @@ package services
 import "errors"
 
 var (
+	ErrBackupCheckpointOutdated          = errors.New("interrupted snapshot was begun from another state of the backup")
 	ErrBackupChunkNotFound               = errors.New("backup record chunk not found")
 	ErrBackupCorruptedFile               = errors.New("backup file is corrupted")
 	ErrBackupDirNotExists                = errors.New("backup directory not exists")
+	ErrBackupKeyInvalid                  = errors.New("backup passphrase is not valid")
+	ErrBackupKeyRequired                 = errors.New("backup is encrypted and no passphrase was provided")
+	ErrBackupLocked                      = errors.New("backup is locked by another process")
+	ErrBackupLockHeld                    = errors.New("backup lock is already held")
+	ErrBackupLockNotFound                = errors.New("no backup lock held")
+	ErrBackupLockNotExclusive            = errors.New("backup is not locked exclusively")
+	ErrBackupNotEncrypted                = errors.New("backup is not encrypted")
+	ErrBackupSnapshotNotFound            = errors.New("backup snapshot not found")
 	ErrBackupTransactionInProgress       = errors.New("backup transaction is already in progress")
 	ErrBackupTransactionNotFound         = errors.New("no backup transaction in progress")
+	ErrBackupVersionNotSupported         = errors.New("backup was created by a newer version")
+	ErrChainDepthNotSupported            = errors.New("backup without a chunk store cannot bound the diff chains of its batches")
+	ErrCompressionNotSupported           = errors.New("unsupported backup compression codec")
+	ErrDatabaseDriverNotSupported        = errors.New("unsupported database driver")
 	ErrDatabaseTransactionAlreadyStarted = errors.New("database transaction already started")
 	ErrDatabaseTransactionNotFound       = errors.New("no db transaction in progress")
 	ErrDependencyNotSupported            = errors.New("unsupported schema dependency type")
+	ErrEncryptionNotSupported            = errors.New("unsupported backup key derivation function")
+	ErrParallelReadNotSupported          = errors.New("database engine cannot share its transaction state between connections")
+	ErrParallelWriteNotSupported         = errors.New("database engine cannot write from several connections at once")
 	ErrRecordNotSupported                = errors.New("unsupported schema record type")
+	ErrRestoreCheckpointNotSupported     = errors.New("database engine cannot commit a restore in several transactions")
 	ErrRoutineNotSupported               = errors.New("unsupported routine type")
 	ErrSchemaNotSupported                = errors.New("unsupported schema type")
 )
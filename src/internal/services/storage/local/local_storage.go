@@ -0,0 +1,138 @@
+package local
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// LocalStorage keeps the backup files in a directory of the local disk. Snapshots are staged inside the same directory,
+// so committing a file only renames it.
+type LocalStorage struct {
+	rootPath string
+}
+
+func NewLocalStorage(rootPath string) *LocalStorage {
+	return &LocalStorage{rootPath}
+}
+
+func (storage *LocalStorage) Get(name string) ([]byte, error) {
+	return os.ReadFile(storage.path(name))
+}
+
+func (storage *LocalStorage) GetRange(name string, offset, length int64) ([]byte, error) {
+	f, err := os.Open(storage.path(name))
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	content := make([]byte, length)
+	if _, err := f.ReadAt(content, offset); err != nil {
+		return nil, err
+	}
+	return content, nil
+}
+
+func (storage *LocalStorage) Put(name string, content []byte) error {
+	pathToFile := storage.path(name)
+	if err := os.MkdirAll(filepath.Dir(pathToFile), 0755); err != nil {
+		return err
+	}
+
+	// The file is synced and replaced at once, so it is never read half written, even after a crash
+	f, err := os.Create(pathToFile + ".tmp")
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(content); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+
+	if err := os.Rename(pathToFile+".tmp", pathToFile); err != nil {
+		return err
+	}
+	return syncDir(filepath.Dir(pathToFile))
+}
+
+func (storage *LocalStorage) Commit(name, stagedPath string) error {
+	pathToFile := storage.path(name)
+	if err := os.MkdirAll(filepath.Dir(pathToFile), 0755); err != nil {
+		return err
+	}
+	if err := os.Rename(stagedPath, pathToFile); err != nil {
+		return err
+	}
+	return syncDir(filepath.Dir(pathToFile))
+}
+
+func (storage *LocalStorage) List(prefix string) ([]string, error) {
+	names := []string{}
+
+	// Only the directory of the prefix is walked, as the root also holds the staged snapshots
+	walkPath := storage.path(prefix[:strings.LastIndex(prefix, "/")+1])
+	err := filepath.WalkDir(walkPath, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			return nil
+		}
+
+		rel, err := filepath.Rel(storage.rootPath, path)
+		if err != nil {
+			return err
+		}
+		if name := filepath.ToSlash(rel); strings.HasPrefix(name, prefix) {
+			names = append(names, name)
+		}
+		return nil
+	})
+	if errors.Is(err, fs.ErrNotExist) {
+		return names, nil
+	}
+	return names, err
+}
+
+func (storage *LocalStorage) Size(name string) (int64, error) {
+	info, err := os.Stat(storage.path(name))
+	if err != nil {
+		return 0, err
+	}
+	return info.Size(), nil
+}
+
+func (storage *LocalStorage) Delete(name string) error {
+	if err := os.Remove(storage.path(name)); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	return nil
+}
+
+func (storage *LocalStorage) StagingPath() string {
+	return storage.rootPath
+}
+
+// syncDir flushes the entries of a directory to disk, so the files renamed into it are kept after a crash.
+func syncDir(dir string) error {
+	f, err := os.Open(dir)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	return f.Sync()
+}
+
+func (storage *LocalStorage) path(name string) string {
+	return filepath.Join(storage.rootPath, filepath.FromSlash(name))
+}
@@ -0,0 +1,157 @@
+package s3
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	aws_s3 "github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+)
+
+// S3Storage keeps the backup files as objects of an S3-compatible bucket, under a prefix. As objects can not be appended
+// to nor renamed, snapshots are staged in a local directory and every file is uploaded once it is committed.
+type S3Storage struct {
+	client      *aws_s3.Client
+	bucket      string
+	prefix      string
+	stagingPath string
+}
+
+// NewS3Storage creates the storage of the backup kept in bucket under prefix. Snapshots are staged in a directory of the
+// temporary dir of the system which is only used by this bucket and prefix.
+func NewS3Storage(client *aws_s3.Client, bucket, prefix string) *S3Storage {
+	prefix = strings.Trim(prefix, "/")
+	stagingPath := filepath.Join(os.TempDir(), "historydb", bucket, filepath.FromSlash(prefix))
+	return &S3Storage{client, bucket, prefix, stagingPath}
+}
+
+func (storage *S3Storage) Get(name string) ([]byte, error) {
+	output, err := storage.client.GetObject(context.Background(), &aws_s3.GetObjectInput{
+		Bucket: aws.String(storage.bucket),
+		Key:    aws.String(storage.key(name)),
+	})
+	if err != nil {
+		var noSuchKey *types.NoSuchKey
+		if errors.As(err, &noSuchKey) {
+			return nil, fmt.Errorf("%w: %s", fs.ErrNotExist, storage.key(name))
+		}
+		return nil, err
+	}
+	defer output.Body.Close()
+
+	return io.ReadAll(output.Body)
+}
+
+func (storage *S3Storage) GetRange(name string, offset, length int64) ([]byte, error) {
+	output, err := storage.client.GetObject(context.Background(), &aws_s3.GetObjectInput{
+		Bucket: aws.String(storage.bucket),
+		Key:    aws.String(storage.key(name)),
+		Range:  aws.String(fmt.Sprintf("bytes=%d-%d", offset, offset+length-1)),
+	})
+	if err != nil {
+		var noSuchKey *types.NoSuchKey
+		if errors.As(err, &noSuchKey) {
+			return nil, fmt.Errorf("%w: %s", fs.ErrNotExist, storage.key(name))
+		}
+		return nil, err
+	}
+	defer output.Body.Close()
+
+	content := make([]byte, length)
+	if _, err := io.ReadFull(output.Body, content); err != nil {
+		return nil, err
+	}
+	return content, nil
+}
+
+func (storage *S3Storage) Put(name string, content []byte) error {
+	_, err := storage.client.PutObject(context.Background(), &aws_s3.PutObjectInput{
+		Bucket: aws.String(storage.bucket),
+		Key:    aws.String(storage.key(name)),
+		Body:   bytes.NewReader(content),
+	})
+	return err
+}
+
+func (storage *S3Storage) Commit(name, stagedPath string) error {
+	f, err := os.Open(stagedPath)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	if _, err := storage.client.PutObject(context.Background(), &aws_s3.PutObjectInput{
+		Bucket: aws.String(storage.bucket),
+		Key:    aws.String(storage.key(name)),
+		Body:   f,
+	}); err != nil {
+		return err
+	}
+	return os.Remove(stagedPath)
+}
+
+func (storage *S3Storage) List(prefix string) ([]string, error) {
+	names := []string{}
+
+	paginator := aws_s3.NewListObjectsV2Paginator(storage.client, &aws_s3.ListObjectsV2Input{
+		Bucket: aws.String(storage.bucket),
+		Prefix: aws.String(storage.key(prefix)),
+	})
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(context.Background())
+		if err != nil {
+			return nil, err
+		}
+
+		for _, object := range page.Contents {
+			names = append(names, strings.TrimPrefix(*object.Key, storage.key("")))
+		}
+	}
+
+	return names, nil
+}
+
+func (storage *S3Storage) Size(name string) (int64, error) {
+	output, err := storage.client.HeadObject(context.Background(), &aws_s3.HeadObjectInput{
+		Bucket: aws.String(storage.bucket),
+		Key:    aws.String(storage.key(name)),
+	})
+	if err != nil {
+		// HEAD responses have no body, so a missing object is reported as NotFound instead of NoSuchKey
+		var notFound *types.NotFound
+		if errors.As(err, &notFound) {
+			return 0, fmt.Errorf("%w: %s", fs.ErrNotExist, storage.key(name))
+		}
+		return 0, err
+	}
+	return aws.ToInt64(output.ContentLength), nil
+}
+
+func (storage *S3Storage) Delete(name string) error {
+	// S3 does not fail when the object does not exist
+	_, err := storage.client.DeleteObject(context.Background(), &aws_s3.DeleteObjectInput{
+		Bucket: aws.String(storage.bucket),
+		Key:    aws.String(storage.key(name)),
+	})
+	return err
+}
+
+func (storage *S3Storage) StagingPath() string {
+	return storage.stagingPath
+}
+
+// key returns the key of the object of a file, which is its name under the prefix of the backup.
+func (storage *S3Storage) key(name string) string {
+	if storage.prefix == "" {
+		return name
+	}
+	return storage.prefix + "/" + name
+}
@@ -0,0 +1,120 @@
+package sftp
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"os"
+
+	"github.com/pkg/sftp"
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/agent"
+	"golang.org/x/crypto/ssh/knownhosts"
+)
+
+// SSHOptions defines how the connection to the SFTP host is authenticated
+//
+// AgentSocket -> The socket of the SSH agent whose keys are tried first, empty if no agent is used
+// KeyFiles -> The private key files tried after the keys of the agent. Keys protected by a passphrase are skipped
+// KnownHostsFile -> The known hosts file the key of the host is checked against
+type SSHOptions struct {
+	AgentSocket    string
+	KeyFiles       []string
+	KnownHostsFile string
+}
+
+// Dial connects as user to the SSH server at address, which must be listed in the known hosts file, and opens an SFTP
+// session over the connection. The returned function closes both.
+func Dial(user, address string, options SSHOptions) (*sftp.Client, func(), error) {
+	hostKeyCallback, err := knownhosts.New(options.KnownHostsFile)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	signers, closeAgent, err := loadSigners(options)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer closeAgent()
+	if len(signers) == 0 {
+		return nil, nil, fmt.Errorf("no ssh key available to authenticate %s", user)
+	}
+
+	config := &ssh.ClientConfig{
+		User:            user,
+		Auth:            []ssh.AuthMethod{ssh.PublicKeys(signers...)},
+		HostKeyCallback: hostKeyCallback,
+	}
+	conn, err := ssh.Dial("tcp", address, config)
+
+	// The host may offer a key of another type than the one known, so the known types are asked for
+	var keyErr *knownhosts.KeyError
+	if errors.As(err, &keyErr) && len(keyErr.Want) > 0 {
+		config.HostKeyAlgorithms = hostKeyAlgorithms(keyErr.Want)
+		conn, err = ssh.Dial("tcp", address, config)
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+
+	client, err := sftp.NewClient(conn)
+	if err != nil {
+		conn.Close()
+		return nil, nil, err
+	}
+	return client, func() { client.Close(); conn.Close() }, nil
+}
+
+// loadSigners returns the keys of the agent followed by the ones of the key files. The returned function closes the
+// connection to the agent, which is only needed until the user is authenticated.
+func loadSigners(options SSHOptions) ([]ssh.Signer, func(), error) {
+	signers := []ssh.Signer{}
+	closeAgent := func() {}
+
+	if options.AgentSocket != "" {
+		conn, err := net.Dial("unix", options.AgentSocket)
+		if err != nil {
+			return nil, nil, fmt.Errorf("could not connect to the ssh agent: %w", err)
+		}
+		closeAgent = func() { conn.Close() }
+
+		agentSigners, err := agent.NewClient(conn).Signers()
+		if err != nil {
+			conn.Close()
+			return nil, nil, fmt.Errorf("could not list the keys of the ssh agent: %w", err)
+		}
+		signers = append(signers, agentSigners...)
+	}
+
+	for _, keyFile := range options.KeyFiles {
+		key, err := os.ReadFile(keyFile)
+		if err != nil {
+			closeAgent()
+			return nil, nil, err
+		}
+
+		signer, err := ssh.ParsePrivateKey(key)
+		var passphraseErr *ssh.PassphraseMissingError
+		if errors.As(err, &passphraseErr) {
+			continue
+		} else if err != nil {
+			closeAgent()
+			return nil, nil, fmt.Errorf("could not parse the ssh key %s: %w", keyFile, err)
+		}
+		signers = append(signers, signer)
+	}
+
+	return signers, closeAgent, nil
+}
+
+// hostKeyAlgorithms returns the algorithms of the known keys of a host, including the SHA-2 signatures of RSA keys.
+func hostKeyAlgorithms(knownKeys []knownhosts.KnownKey) []string {
+	algorithms := []string{}
+	for _, knownKey := range knownKeys {
+		if knownKey.Key.Type() == ssh.KeyAlgoRSA {
+			algorithms = append(algorithms, ssh.KeyAlgoRSASHA512, ssh.KeyAlgoRSASHA256)
+		}
+		algorithms = append(algorithms, knownKey.Key.Type())
+	}
+	return algorithms
+}
@@ -0,0 +1,153 @@
+package sftp
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/pkg/sftp"
+)
+
+// SFTPStorage keeps the backup files in a directory of a remote host reached over SFTP. Snapshots are staged in a local
+// directory, and every file is uploaded with a temporary name and renamed, so it is never read half written.
+type SFTPStorage struct {
+	client      *sftp.Client
+	rootPath    string
+	stagingPath string
+}
+
+// NewSFTPStorage creates the storage of the backup kept in rootPath of host. Snapshots are staged in a directory of the
+// temporary dir of the system which is only used by this host and path.
+func NewSFTPStorage(client *sftp.Client, host, rootPath string) *SFTPStorage {
+	rootPath = path.Clean(rootPath)
+	stagingPath := filepath.Join(os.TempDir(), "historydb", "sftp", host, filepath.FromSlash(strings.Trim(rootPath, "/")))
+	return &SFTPStorage{client, rootPath, stagingPath}
+}
+
+func (storage *SFTPStorage) Get(name string) ([]byte, error) {
+	f, err := storage.client.Open(storage.path(name))
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	return io.ReadAll(f)
+}
+
+func (storage *SFTPStorage) GetRange(name string, offset, length int64) ([]byte, error) {
+	f, err := storage.client.Open(storage.path(name))
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	content := make([]byte, length)
+	if _, err := f.ReadAt(content, offset); err != nil {
+		return nil, err
+	}
+	return content, nil
+}
+
+func (storage *SFTPStorage) Put(name string, content []byte) error {
+	return storage.writeFile(name, bytes.NewReader(content))
+}
+
+func (storage *SFTPStorage) Commit(name, stagedPath string) error {
+	f, err := os.Open(stagedPath)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	if err := storage.writeFile(name, f); err != nil {
+		return err
+	}
+	return os.Remove(stagedPath)
+}
+
+func (storage *SFTPStorage) List(prefix string) ([]string, error) {
+	names := []string{}
+
+	// Only the directory of the prefix is walked, as the rest of the backup may hold many files
+	walker := storage.client.Walk(storage.path(prefix[:strings.LastIndex(prefix, "/")+1]))
+	for walker.Step() {
+		if err := walker.Err(); errors.Is(err, fs.ErrNotExist) {
+			continue
+		} else if err != nil {
+			return nil, err
+		}
+		if walker.Stat().IsDir() {
+			continue
+		}
+
+		name := strings.TrimPrefix(walker.Path(), storage.rootPath+"/")
+		if strings.HasPrefix(name, prefix) {
+			names = append(names, name)
+		}
+	}
+
+	return names, nil
+}
+
+func (storage *SFTPStorage) Size(name string) (int64, error) {
+	info, err := storage.client.Stat(storage.path(name))
+	if err != nil {
+		return 0, err
+	}
+	return info.Size(), nil
+}
+
+func (storage *SFTPStorage) Delete(name string) error {
+	if err := storage.client.Remove(storage.path(name)); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	return nil
+}
+
+func (storage *SFTPStorage) StagingPath() string {
+	return storage.stagingPath
+}
+
+// writeFile uploads the content of a file with a temporary name and renames it once it is complete.
+func (storage *SFTPStorage) writeFile(name string, content io.Reader) error {
+	pathToFile := storage.path(name)
+	if err := storage.client.MkdirAll(path.Dir(pathToFile)); err != nil {
+		return err
+	}
+
+	f, err := storage.client.Create(pathToFile + ".tmp")
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(f, content); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+
+	return storage.rename(pathToFile+".tmp", pathToFile)
+}
+
+// rename replaces newPath with oldPath. SFTP renames fail if newPath already exists, so the posix-rename extension of
+// OpenSSH is used when the server supports it, and newPath is removed first otherwise.
+func (storage *SFTPStorage) rename(oldPath, newPath string) error {
+	if _, ok := storage.client.HasExtension("posix-rename@openssh.com"); ok {
+		return storage.client.PosixRename(oldPath, newPath)
+	}
+
+	if err := storage.client.Remove(newPath); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	return storage.client.Rename(oldPath, newPath)
+}
+
+func (storage *SFTPStorage) path(name string) string {
+	return path.Join(storage.rootPath, name)
+}
@@ -0,0 +1,23 @@
+package storage_services
+
+// Storage is the interface that defines where the files of a backup are kept. Every file is named by its path inside
+// the backup, using slashes as separator.
+//
+// Get() -> Reads the whole content of a file. The error wraps fs.ErrNotExist if the file does not exist.
+// GetRange() -> Reads length bytes of a file from offset on, so a part of a large file is read without the rest.
+// Put() -> Writes the whole content of a file at once, replacing the file if it already exists.
+// Commit() -> Moves a file staged in the local disk into the storage, so the staged file no longer exists.
+// List() -> Lists the names of all the files whose name starts with the prefix.
+// Size() -> Returns the size of a file in bytes. The error wraps fs.ErrNotExist if the file does not exist.
+// Delete() -> Deletes a file. Deleting a file which does not exist is not an error.
+// StagingPath() -> Returns the local directory where the files of a snapshot are staged until they are committed.
+type Storage interface {
+	Get(name string) ([]byte, error)
+	GetRange(name string, offset, length int64) ([]byte, error)
+	Put(name string, content []byte) error
+	Commit(name, stagedPath string) error
+	List(prefix string) ([]string, error)
+	Size(name string) (int64, error)
+	Delete(name string) error
+	StagingPath() string
+}
@@ -0,0 +1,241 @@
+package test
+
+import (
+	"historydb/src/internal/entities"
+	"historydb/src/internal/services/backup/binary"
+	"historydb/src/internal/services/entities/sql"
+	storage_services "historydb/src/internal/services/storage"
+	"historydb/src/internal/services/storage/local"
+	"historydb/src/internal/services/storage/s3"
+	"historydb/src/internal/services/storage/sftp"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/crypto/ssh/knownhosts"
+)
+
+func TestLocalStorage(t *testing.T) {
+	testStorage(t, local.NewLocalStorage(t.TempDir()))
+}
+
+func TestS3Storage(t *testing.T) {
+	client, fake := setupFakeS3(t)
+	storage := s3.NewS3Storage(client, "backups", "/team/db/")
+	testStorage(t, storage)
+
+	// Every file is kept under the prefix of the backup
+	for _, key := range fake.keys() {
+		assert.Regexp(t, "^backups/team/db/", key)
+	}
+}
+
+func TestSFTPStorage(t *testing.T) {
+	key := generateKey(t)
+	address, hostKey := setupSFTPServer(t, publicKey(t, key))
+	client, closeClient, err := sftp.Dial("backup", address, sftp.SSHOptions{
+		KeyFiles:       []string{writeKeyFile(t, key, "")},
+		KnownHostsFile: writeKnownHostsFile(t, address, hostKey),
+	})
+	if err != nil {
+		t.Fatal("could not connect to sftp server", err)
+	}
+	defer closeClient()
+
+	storage := sftp.NewSFTPStorage(client, "localhost", t.TempDir())
+	testStorage(t, storage)
+
+	// Files are uploaded with a temporary name which is renamed once they are complete
+	names, err := storage.List("")
+	assert.NoError(t, err)
+	assert.NotEmpty(t, names)
+	for _, name := range names {
+		assert.NotRegexp(t, `\.tmp$`, name)
+	}
+}
+
+func TestSFTPDialWithAgent(t *testing.T) {
+	key := generateKey(t)
+	address, hostKey := setupSFTPServer(t, publicKey(t, key))
+	knownHostsFile := writeKnownHostsFile(t, address, hostKey)
+
+	// Keys protected by a passphrase are skipped, so the agent is the only way to authenticate
+	options := sftp.SSHOptions{KeyFiles: []string{writeKeyFile(t, key, "secret")}, KnownHostsFile: knownHostsFile}
+	_, _, err := sftp.Dial("backup", address, options)
+	assert.Error(t, err, "protected keys are not used")
+
+	options.AgentSocket = setupSSHAgent(t, key)
+	_, closeClient, err := sftp.Dial("backup", address, options)
+	assert.NoError(t, err, "keys of the agent are used")
+	if err == nil {
+		closeClient()
+	}
+}
+
+func TestSFTPDialRejectsUnknownHost(t *testing.T) {
+	key := generateKey(t)
+	address, _ := setupSFTPServer(t, publicKey(t, key))
+	otherKey := generateSigner(t)
+
+	_, _, err := sftp.Dial("backup", address, sftp.SSHOptions{
+		KeyFiles:       []string{writeKeyFile(t, key, "")},
+		KnownHostsFile: writeKnownHostsFile(t, address, otherKey.PublicKey()),
+	})
+	var keyErr *knownhosts.KeyError
+	assert.ErrorAs(t, err, &keyErr, "host with another key is rejected")
+
+	_, _, err = sftp.Dial("backup", address, sftp.SSHOptions{
+		KeyFiles:       []string{writeKeyFile(t, key, "")},
+		KnownHostsFile: writeKnownHostsFile(t, "127.0.0.1:1", otherKey.PublicKey()),
+	})
+	assert.ErrorAs(t, err, &keyErr, "unknown host is rejected")
+}
+
+func TestBinaryBackupInLocalStorage(t *testing.T) {
+	testBinaryBackup(t, local.NewLocalStorage(filepath.Join(t.TempDir(), "backup")), binary.BinaryBackupOptions{Compression: entities.NoCompression})
+}
+
+func TestBinaryBackupInS3Storage(t *testing.T) {
+	client, _ := setupFakeS3(t)
+	testBinaryBackup(t, s3.NewS3Storage(client, "backups", uuid.NewString()), binary.BinaryBackupOptions{Compression: entities.ZstdCompression, CompressionLevel: binary.DefaultCompressionLevel})
+}
+
+func TestEncryptedBinaryBackupInS3Storage(t *testing.T) {
+	client, _ := setupFakeS3(t)
+	options := binary.BinaryBackupOptions{Compression: entities.ZstdCompression, CompressionLevel: binary.DefaultCompressionLevel, Encrypt: true, Passphrase: []byte("secret")}
+	testBinaryBackup(t, s3.NewS3Storage(client, "backups", uuid.NewString()), options)
+}
+
+func TestEncryptedBinaryBackupInSFTPStorage(t *testing.T) {
+	key := generateKey(t)
+	address, hostKey := setupSFTPServer(t, publicKey(t, key))
+	client, closeClient, err := sftp.Dial("backup", address, sftp.SSHOptions{
+		AgentSocket:    setupSSHAgent(t, key),
+		KnownHostsFile: writeKnownHostsFile(t, address, hostKey),
+	})
+	if err != nil {
+		t.Fatal("could not connect to sftp server", err)
+	}
+	defer closeClient()
+
+	options := binary.BinaryBackupOptions{Compression: entities.ZstdCompression, CompressionLevel: binary.DefaultCompressionLevel, Encrypt: true, Passphrase: []byte("secret")}
+	testBinaryBackup(t, sftp.NewSFTPStorage(client, "localhost", filepath.Join(t.TempDir(), "backup")), options)
+}
+
+func testStorage(t *testing.T, storage storage_services.Storage) {
+	_, err := storage.Get("metadata.hdb")
+	assert.ErrorIs(t, err, fs.ErrNotExist, "missing file is reported as not existing")
+
+	assert.NoError(t, storage.Put("metadata.hdb", []byte("first")))
+	assert.NoError(t, storage.Put("metadata.hdb", []byte("second")))
+	content, err := storage.Get("metadata.hdb")
+	assert.NoError(t, err)
+	assert.Equal(t, []byte("second"), content, "put replaces the file")
+	content, err = storage.GetRange("metadata.hdb", 1, 3)
+	assert.NoError(t, err)
+	assert.Equal(t, []byte("eco"), content, "range is read from its offset")
+	_, err = storage.GetRange("missing.hdb", 0, 1)
+	assert.ErrorIs(t, err, fs.ErrNotExist)
+
+	stagedPath := filepath.Join(storage.StagingPath(), "tx", "batch.hdb")
+	assert.NoError(t, os.MkdirAll(filepath.Dir(stagedPath), 0755))
+	assert.NoError(t, os.WriteFile(stagedPath, []byte("batch"), 0644))
+	assert.NoError(t, storage.Commit("data/batch.hdb", stagedPath))
+	_, err = os.Stat(stagedPath)
+	assert.ErrorIs(t, err, fs.ErrNotExist, "staged file is removed once committed")
+	content, err = storage.Get("data/batch.hdb")
+	assert.NoError(t, err)
+	assert.Equal(t, []byte("batch"), content)
+	size, err := storage.Size("data/batch.hdb")
+	assert.NoError(t, err)
+	assert.Equal(t, int64(len("batch")), size)
+	_, err = storage.Size("missing.hdb")
+	assert.ErrorIs(t, err, fs.ErrNotExist)
+
+	assert.NoError(t, storage.Put("data/diffs/diff.hdb", []byte("diff")))
+	assert.NoError(t, storage.Put("schemas/schema.hdb", []byte("schema")))
+	names, err := storage.List("data/")
+	assert.NoError(t, err)
+	slices.Sort(names)
+	assert.Equal(t, []string{"data/batch.hdb", "data/diffs/diff.hdb"}, names)
+	names, err = storage.List("routines/")
+	assert.NoError(t, err)
+	assert.Empty(t, names, "listing a missing prefix is not an error")
+
+	assert.NoError(t, storage.Delete("data/batch.hdb"))
+	assert.NoError(t, storage.Delete("data/batch.hdb"), "deleting a missing file is not an error")
+	_, err = storage.Get("data/batch.hdb")
+	assert.ErrorIs(t, err, fs.ErrNotExist)
+}
+
+// testBinaryBackup writes a backup with a table and one batch into the storage, and checks it is read back
+func testBinaryBackup(t *testing.T, storage storage_services.Storage, options binary.BinaryBackupOptions) {
+	table := &sql.SQLTable{Name: "users", Columns: []sql.SQLTableColumn{{Name: "id", Type: "integer", Position: 1}}}
+	chunk := &sql.SQLRecordChunk{PrimaryKey: []string{"id"}, Content: []sql.SQLRecord{{Content: map[string]interface{}{"id": "1"}}}}
+	chunkHash := chunk.Hash()
+
+	snapshot := entities.BackupSnapshot{
+		SnapshotId:         uuid.NewString(),
+		Timestamp:          time.Now().UTC().Truncate(time.Second),
+		SchemaDependencies: make(map[string]string),
+		Schemas:            map[string]string{table.Name: table.Hash()},
+		Data:               make(map[string]entities.BackupSnapshotSchemaData),
+		Routines:           make(map[string]string),
+	}
+	metadata := entities.BackupMetadata{DatabaseEngine: "sqlite", Snapshots: []entities.BackupMetadataSnapshot{{Timestamp: snapshot.Timestamp, SnapshotId: snapshot.SnapshotId}}}
+
+	writer := binary.NewBinaryBackupWriter(storage, options)
+	assert.NoError(t, writer.CreateBackupStructure())
+	assert.NoError(t, writer.BeginSnapshot(&snapshot))
+	assert.NoError(t, writer.SaveSchema(table))
+	assert.NoError(t, writer.SaveSchemaRecordChunk("temp", chunk))
+	assert.NoError(t, writer.SaveSchemaRecordBatch("temp", "batch"))
+	assert.NoError(t, writer.CommitSnapshot(&metadata))
+
+	_, err := os.Stat(filepath.Join(storage.StagingPath(), snapshot.SnapshotId))
+	assert.ErrorIs(t, err, fs.ErrNotExist, "transaction is not staged after the commit")
+	for _, prefix := range []string{"schemas/", "data/", "chunks/"} {
+		names, err := storage.List(prefix)
+		assert.NoError(t, err)
+		assert.Empty(t, names, "%s files are saved in pack files", prefix)
+	}
+	packs, err := storage.List("packs/")
+	assert.NoError(t, err)
+	assert.Len(t, packs, 2, "snapshot objects are saved in one pack and its index")
+
+	reader := binary.NewBinaryBackupReader(storage, options.Passphrase)
+	assert.True(t, reader.CheckBackupExists())
+
+	readMetadata, err := reader.GetBackupMetadata()
+	assert.NoError(t, err)
+	assert.Equal(t, metadata.Snapshots, readMetadata.Snapshots)
+
+	readSnapshot, err := reader.GetBackupSnapshot(snapshot.SnapshotId)
+	assert.NoError(t, err)
+	assert.Equal(t, snapshot.Schemas, readSnapshot.Schemas)
+
+	readTable, _, err := reader.GetSchema(table.Hash())
+	assert.NoError(t, err)
+	assert.Equal(t, table.Hash(), readTable.Hash())
+
+	chunkRefs, err := reader.GetSchemaRecordChunkRefsInBatch("batch")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{chunkHash}, chunkRefs)
+
+	readChunk, _, err := reader.GetSchemaRecordChunk("batch", chunkHash)
+	assert.NoError(t, err)
+	assert.Equal(t, chunkHash, readChunk.Hash())
+
+	assert.NoError(t, writer.DeleteBackupStructure())
+	assert.False(t, reader.CheckBackupExists(), "backup is deleted")
+	for _, prefix := range []string{"snapshots/", "schemas/", "data/", "chunks/", "packs/"} {
+		names, err := storage.List(prefix)
+		assert.NoError(t, err)
+		assert.Empty(t, names, "%s files are deleted", prefix)
+	}
+}
@@ -0,0 +1,291 @@
+package test
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/pem"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	aws_s3 "github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/pkg/sftp"
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/agent"
+	"golang.org/x/crypto/ssh/knownhosts"
+)
+
+// fakeS3 is an in-memory S3 server which only implements the object calls used by the S3 storage
+type fakeS3 struct {
+	mu      sync.Mutex
+	objects map[string][]byte
+}
+
+type listBucketResult struct {
+	XMLName     xml.Name        `xml:"ListBucketResult"`
+	Name        string          `xml:"Name"`
+	Prefix      string          `xml:"Prefix"`
+	KeyCount    int             `xml:"KeyCount"`
+	IsTruncated bool            `xml:"IsTruncated"`
+	Contents    []listBucketKey `xml:"Contents"`
+}
+
+type listBucketKey struct {
+	Key  string `xml:"Key"`
+	Size int    `xml:"Size"`
+}
+
+// setupFakeS3 starts a fake S3 server and returns a client which reaches it with path style requests
+func setupFakeS3(t *testing.T) (*aws_s3.Client, *fakeS3) {
+	fake := &fakeS3{objects: make(map[string][]byte)}
+	server := httptest.NewServer(fake)
+	t.Cleanup(server.Close)
+
+	client := aws_s3.New(aws_s3.Options{
+		Region:       "us-east-1",
+		BaseEndpoint: aws.String(server.URL),
+		UsePathStyle: true,
+		Credentials:  aws.AnonymousCredentials{},
+	})
+	return client, fake
+}
+
+func (fake *fakeS3) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	fake.mu.Lock()
+	defer fake.mu.Unlock()
+
+	bucket, key, _ := strings.Cut(strings.TrimPrefix(r.URL.Path, "/"), "/")
+
+	switch {
+	case r.Method == http.MethodGet && key == "" && r.URL.Query().Get("list-type") == "2":
+		prefix := r.URL.Query().Get("prefix")
+		result := listBucketResult{Name: bucket, Prefix: prefix}
+		for objectKey, content := range fake.objects {
+			if name, ok := strings.CutPrefix(objectKey, bucket+"/"); ok && strings.HasPrefix(name, prefix) {
+				result.Contents = append(result.Contents, listBucketKey{Key: name, Size: len(content)})
+			}
+		}
+		slices.SortFunc(result.Contents, func(a, b listBucketKey) int { return strings.Compare(a.Key, b.Key) })
+		result.KeyCount = len(result.Contents)
+
+		w.Header().Set("Content-Type", "application/xml")
+		xml.NewEncoder(w).Encode(result)
+	case r.Method == http.MethodPut:
+		content, err := io.ReadAll(r.Body)
+		if err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		fake.objects[bucket+"/"+key] = content
+		w.WriteHeader(http.StatusOK)
+	case r.Method == http.MethodGet:
+		content, ok := fake.objects[bucket+"/"+key]
+		if !ok {
+			w.Header().Set("Content-Type", "application/xml")
+			w.WriteHeader(http.StatusNotFound)
+			io.WriteString(w, "<Error><Code>NoSuchKey</Code><Message>The specified key does not exist.</Message></Error>")
+			return
+		}
+		var start, end int
+		if _, err := fmt.Sscanf(r.Header.Get("Range"), "bytes=%d-%d", &start, &end); err == nil {
+			w.WriteHeader(http.StatusPartialContent)
+			w.Write(content[start : end+1])
+			return
+		}
+		w.Write(content)
+	case r.Method == http.MethodHead:
+		content, ok := fake.objects[bucket+"/"+key]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Header().Set("Content-Length", fmt.Sprint(len(content)))
+		w.WriteHeader(http.StatusOK)
+	case r.Method == http.MethodDelete:
+		delete(fake.objects, bucket+"/"+key)
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		w.WriteHeader(http.StatusNotImplemented)
+	}
+}
+
+// keys returns the keys of all the objects saved in the fake server, including their bucket
+func (fake *fakeS3) keys() []string {
+	fake.mu.Lock()
+	defer fake.mu.Unlock()
+
+	keys := make([]string, 0, len(fake.objects))
+	for key := range fake.objects {
+		keys = append(keys, key)
+	}
+	slices.Sort(keys)
+	return keys
+}
+
+// setupSFTPServer starts an SSH server which only accepts the key of the client and serves SFTP over the local disk.
+// It returns the address and the host key of the server.
+func setupSFTPServer(t *testing.T, clientKey ssh.PublicKey) (string, ssh.PublicKey) {
+	hostSigner := generateSigner(t)
+	config := &ssh.ServerConfig{
+		PublicKeyCallback: func(conn ssh.ConnMetadata, key ssh.PublicKey) (*ssh.Permissions, error) {
+			if !bytes.Equal(key.Marshal(), clientKey.Marshal()) {
+				return nil, ssh.ErrNoAuth
+			}
+			return nil, nil
+		},
+	}
+	config.AddHostKey(hostSigner)
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal("could not start ssh server", err)
+	}
+	t.Cleanup(func() { listener.Close() })
+
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go serveSFTP(conn, config)
+		}
+	}()
+
+	return listener.Addr().String(), hostSigner.PublicKey()
+}
+
+// serveSFTP serves the sftp subsystem in every session opened through an SSH connection
+func serveSFTP(conn net.Conn, config *ssh.ServerConfig) {
+	_, channels, requests, err := ssh.NewServerConn(conn, config)
+	if err != nil {
+		conn.Close()
+		return
+	}
+	go ssh.DiscardRequests(requests)
+
+	for newChannel := range channels {
+		if newChannel.ChannelType() != "session" {
+			newChannel.Reject(ssh.UnknownChannelType, "only sessions are supported")
+			continue
+		}
+		channel, channelRequests, err := newChannel.Accept()
+		if err != nil {
+			continue
+		}
+
+		go func() {
+			defer channel.Close()
+			for req := range channelRequests {
+				// The payload of a subsystem request is the length of its name followed by the name
+				if req.Type != "subsystem" || len(req.Payload) < 4 || string(req.Payload[4:]) != "sftp" {
+					req.Reply(false, nil)
+					continue
+				}
+				req.Reply(true, nil)
+
+				server, err := sftp.NewServer(channel)
+				if err != nil {
+					return
+				}
+				server.Serve()
+				return
+			}
+		}()
+	}
+}
+
+// setupSSHAgent serves an agent holding the key in a unix socket, and returns the path of the socket
+func setupSSHAgent(t *testing.T, key ed25519.PrivateKey) string {
+	keyring := agent.NewKeyring()
+	if err := keyring.Add(agent.AddedKey{PrivateKey: key}); err != nil {
+		t.Fatal("could not add key to the ssh agent", err)
+	}
+
+	socket := filepath.Join(t.TempDir(), "agent.sock")
+	listener, err := net.Listen("unix", socket)
+	if err != nil {
+		t.Fatal("could not start ssh agent", err)
+	}
+	t.Cleanup(func() { listener.Close() })
+
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				agent.ServeAgent(keyring, conn)
+				conn.Close()
+			}()
+		}
+	}()
+
+	return socket
+}
+
+// writeKeyFile writes the private key in the OpenSSH format, protected by the passphrase if it is not empty
+func writeKeyFile(t *testing.T, key ed25519.PrivateKey, passphrase string) string {
+	var block *pem.Block
+	var err error
+	if passphrase == "" {
+		block, err = ssh.MarshalPrivateKey(key, "")
+	} else {
+		block, err = ssh.MarshalPrivateKeyWithPassphrase(key, "", []byte(passphrase))
+	}
+	if err != nil {
+		t.Fatal("could not encode ssh key", err)
+	}
+
+	keyFile := filepath.Join(t.TempDir(), "id_ed25519")
+	if err := os.WriteFile(keyFile, pem.EncodeToMemory(block), 0600); err != nil {
+		t.Fatal("could not write ssh key", err)
+	}
+	return keyFile
+}
+
+// writeKnownHostsFile writes a known hosts file with the host key of the server at address
+func writeKnownHostsFile(t *testing.T, address string, hostKey ssh.PublicKey) string {
+	knownHostsFile := filepath.Join(t.TempDir(), "known_hosts")
+	line := knownhosts.Line([]string{knownhosts.Normalize(address)}, hostKey)
+	if err := os.WriteFile(knownHostsFile, []byte(line+"\n"), 0600); err != nil {
+		t.Fatal("could not write known hosts", err)
+	}
+	return knownHostsFile
+}
+
+func generateKey(t *testing.T) ed25519.PrivateKey {
+	_, key, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal("could not generate ssh key", err)
+	}
+	return key
+}
+
+func generateSigner(t *testing.T) ssh.Signer {
+	signer, err := ssh.NewSignerFromKey(generateKey(t))
+	if err != nil {
+		t.Fatal("could not create ssh signer", err)
+	}
+	return signer
+}
+
+func publicKey(t *testing.T, key ed25519.PrivateKey) ssh.PublicKey {
+	publicKey, err := ssh.NewPublicKey(key.Public())
+	if err != nil {
+		t.Fatal("could not encode ssh public key", err)
+	}
+	return publicKey
+}
@@ -0,0 +1,10 @@
+package utils
+
+import "database/sql"
+
+// SQLQuerier is implemented by both sql.DB and sql.Tx, so a database reader can run the same queries
+// inside or outside a transaction.
+type SQLQuerier interface {
+	Query(query string, args ...any) (*sql.Rows, error)
+	QueryRow(query string, args ...any) *sql.Row
+}
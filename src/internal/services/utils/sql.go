@@ -2,9 +2,12 @@ package utils
 
 import (
 	"fmt"
+	"historydb/src/internal/services/entities/mysql"
 	"historydb/src/internal/services/entities/psql"
 	"historydb/src/internal/services/entities/sql"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/lib/pq"
 )
@@ -22,19 +25,31 @@ func QuoteIdentifiers(identifiers []string) []string {
 }
 
 // BuildPKWhereClause is a function that creates a where clause when batching data queries using the primary key of the table.
+// COPY queries do not accept parameters, so the last primary key values are written as literals cast to the column types.
 //
 // Using PostgreSQL specific library. Should be moved to a specific PostgreSQL file?????
-func BuildPKWhereClause(pKeys []string, lastPK interface{}) string {
-	if len(pKeys) == 1 {
-		return fmt.Sprintf("%s > $1", pq.QuoteIdentifier(pKeys[0]))
-	}
+func BuildPKWhereClause(pKeys []string, pKeyTypes []string, lastPK interface{}) string {
+	lastValues := lastPK.([]interface{})
 
-	placeholders := make([]string, len(pKeys))
+	literals := make([]string, len(pKeys))
 	for i := range pKeys {
-		placeholders[i] = fmt.Sprintf("$%d", i+1)
+		var text string
+		switch value := lastValues[i].(type) {
+		case float64:
+			text = strconv.FormatFloat(value, 'g', -1, 64)
+		case time.Time:
+			text = value.Format(time.RFC3339Nano)
+		default:
+			text = fmt.Sprintf("%v", value)
+		}
+		literals[i] = fmt.Sprintf("%s::%s", pq.QuoteLiteral(text), pKeyTypes[i])
+	}
+
+	if len(pKeys) == 1 {
+		return fmt.Sprintf("%s > %s", pq.QuoteIdentifier(pKeys[0]), literals[0])
 	}
 
-	return fmt.Sprintf("(%s) > (%s)", strings.Join(QuoteIdentifiers(pKeys), ", "), strings.Join(placeholders, ", "))
+	return fmt.Sprintf("(%s) > (%s)", strings.Join(QuoteIdentifiers(pKeys), ", "), strings.Join(literals, ", "))
 }
 
 // ExtractPrimaryKey obtains the primary key column names from the primary key of the table.
@@ -43,13 +58,34 @@ func BuildPKWhereClause(pKeys []string, lastPK interface{}) string {
 // It returns a slice of column names used in the primary key, and a bool indicating if the columns retrieved
 // or not are compatible to use in a batch query by primary key.
 func ExtractPrimaryKey(table sql.SQLTable) ([]string, bool) {
-	var pKeys []string
-	for _, constraint := range table.Constraints {
-		if constraint.Type == sql.PrimaryKey {
-			pKeys = constraint.Columns
+	return extractPrimaryKey(table, func(dataType string) bool {
+		return psql.ComparablePK[dataType]
+	})
+}
+
+// ExtractMySQLPrimaryKey does the same as ExtractPrimaryKey but for MySQL tables.
+// MySQL column types contain their length and modifiers (int unsigned AUTO_INCREMENT, varchar(255)...), so only
+// the base type is checked.
+func ExtractMySQLPrimaryKey(table sql.SQLTable) ([]string, bool) {
+	return extractPrimaryKey(table, func(dataType string) bool {
+		baseType := strings.ToLower(strings.Fields(dataType)[0])
+		if i := strings.Index(baseType, "("); i != -1 {
+			baseType = baseType[:i]
 		}
-	}
+		return mysql.ComparablePK[baseType]
+	})
+}
+
+// ExtractSQLitePrimaryKey does the same as ExtractPrimaryKey but for SQLite tables.
+// SQLite can compare values of any type, so every primary key can be used in a batch query.
+func ExtractSQLitePrimaryKey(table sql.SQLTable) ([]string, bool) {
+	return extractPrimaryKey(table, func(dataType string) bool {
+		return true
+	})
+}
 
+func extractPrimaryKey(table sql.SQLTable, isComparable func(dataType string) bool) ([]string, bool) {
+	pKeys := table.PrimaryKeyColumns()
 	if len(pKeys) == 0 {
 		return nil, false
 	}
@@ -61,7 +97,7 @@ func ExtractPrimaryKey(table sql.SQLTable) ([]string, bool) {
 
 	for _, column := range pKeys {
 		if dataType, exists := columnsMap[column]; exists {
-			if _, exists := psql.ComparablePK[dataType]; !exists {
+			if !isComparable(dataType) {
 				return nil, false
 			}
 		}
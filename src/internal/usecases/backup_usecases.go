@@ -7,31 +7,42 @@ import "historydb/src/internal/entities"
 // GetBackupMetadata() -> Retrieves the backup metadata if it exists.
 // GetSnapshot() -> Retrieves all the snapshot info from a snapshotId.
 // CreateSnapshot() -> Creates a new or the first snapshot into the backup.
+// ResumeSnapshot() -> Resumes the last snapshot interrupted in the backup, returning it with its checkpoint. Returns nil if there is no snapshot to resume.
 // CommitSnapshot() -> Commits a snapshot into the backup making it a new stable version.
-// RollbackSnapshot() -> Rollbacks the current working snapshot to preserve the last stable version of the backup.
+// RollbackSnapshot() -> Rollbacks the current working snapshot to preserve the last stable version of the backup. The records saved are kept if the snapshot can be resumed.
+// BeginDatabaseTransaction() -> Begins a read-only transaction in the DB, so the whole snapshot is taken from the same state of the DB.
+// EndDatabaseTransaction() -> Ends the read-only transaction in the DB.
+// CreateWorker() -> Creates usecases with their own DB connection to save schema records next to other workers. Once its transaction begins, it reads the same state of the DB as the transaction in progress. Returns nil if the DB engine cannot share that state.
 // BackupSchemaDependencies() -> Saves into the backup all the dependencies contained in the DB.
 // SnapshotSchemaDependencies() -> Makes a new version of the dependencies contained in the DB by their differences.
 // BackupSchemas() -> Saves into the backup all the schemas contained in the DB.
 // SnapshotSchemas() -> Makes a new version of the schemas contained in the DB by their defferences.
-// BackupSchemaRecords() -> Saves into the backup all the schema data records contained in the DB.
-// SnapshotSchemaRecords() -> Makes a new version of the schema data records contained in the DB by their differences.
+// BackupSchemaRecords() -> Saves into the backup all the schema data records contained in the DB, going on from the progress of an interrupted snapshot if it is given.
+// SnapshotSchemaRecords() -> Makes a new version of the schema data records contained in the DB by their differences, going on from the progress of an interrupted snapshot if it is given.
+// CheckpointSchemaRecords() -> Saves into the checkpoint of the snapshot that the records of a schema are saved, so they are not read again if the snapshot is resumed.
 // BackupRoutines() -> Saves into the backup all the routines contained in the DB.
 // SnapshotRoutines() -> Makes a new version of the routines contained in the DB by their differences.
 type BackupUsecases interface {
 	GetBackupMetadata() *entities.BackupMetadata
 	GetSnapshot(snapshotId string) *entities.BackupSnapshot
 	CreateSnapshot(first bool, message string) *entities.BackupSnapshot
+	ResumeSnapshot(first bool) (*entities.BackupSnapshot, *entities.BackupCheckpoint)
 	CommitSnapshot(metadata *entities.BackupMetadata, snapshot *entities.BackupSnapshot) bool
 	RollbackSnapshot(first bool)
 
+	BeginDatabaseTransaction() bool
+	EndDatabaseTransaction()
+	CreateWorker() BackupUsecases
+
 	BackupSchemaDependencies(snapshot *entities.BackupSnapshot) bool
 	SnapshotSchemaDependencies(lastSnapshot, snapshot *entities.BackupSnapshot) bool
 
 	BackupSchemas(snapshot *entities.BackupSnapshot) []entities.Schema
 	SnapshotSchemas(lastSnapshot, snapshot *entities.BackupSnapshot) []entities.Schema
 
-	BackupSchemaRecords(snapshot *entities.BackupSnapshot, schema entities.Schema) bool
-	SnapshotSchemaRecords(lastSnapshot, snapshot *entities.BackupSnapshot, schema entities.Schema) bool
+	BackupSchemaRecords(snapshot *entities.BackupSnapshot, schema entities.Schema, progress *entities.BackupSchemaProgress) bool
+	SnapshotSchemaRecords(lastSnapshot, snapshot *entities.BackupSnapshot, schema entities.Schema, progress *entities.BackupSchemaProgress) bool
+	CheckpointSchemaRecords(snapshot *entities.BackupSnapshot, schema entities.Schema) bool
 
 	BackupRoutines(snapshot *entities.BackupSnapshot) bool
 	SnapshotRoutines(lastSnapshot, snapshot *entities.BackupSnapshot) bool
@@ -10,9 +10,12 @@ import (
 	backup_services "historydb/src/internal/services/backup"
 	database_services "historydb/src/internal/services/database"
 	"historydb/src/internal/usecases/dtos"
+	"historydb/src/internal/utils/chunking"
 	"historydb/src/internal/utils/crypto"
+	"io"
 	"math"
 	"os"
+	"slices"
 	"strings"
 	"time"
 
@@ -25,10 +28,31 @@ type BackupUsecasesImpl struct {
 	dbFactory     database_services.DatabaseFactory
 	backupFactory backup_services.BackupFactory
 	logger        *logrus.Logger
+	isWorker      bool
 }
 
 func NewBackupUsecasesImpl(dbFactory database_services.DatabaseFactory, backupFactory backup_services.BackupFactory, logger *logrus.Logger) *BackupUsecasesImpl {
-	return &BackupUsecasesImpl{dbFactory, backupFactory, logger}
+	return &BackupUsecasesImpl{dbFactory, backupFactory, logger, false}
+}
+
+// workerDatabaseFactory is the DatabaseFactory of a worker, which creates the parallel reader of the worker instead of the shared one.
+type workerDatabaseFactory struct {
+	database_services.DatabaseFactory
+	dbReader database_services.DatabaseReader
+}
+
+func (factory *workerDatabaseFactory) CreateReader() database_services.DatabaseReader {
+	return factory.dbReader
+}
+
+func (uc *BackupUsecasesImpl) CreateWorker() BackupUsecases {
+	dbReader, err := uc.dbFactory.CreateParallelReader()
+	if err != nil {
+		uc.logger.Warnf("could not create a parallel reader of the DB: %v", err)
+		return nil
+	}
+
+	return &BackupUsecasesImpl{&workerDatabaseFactory{uc.dbFactory, dbReader}, uc.backupFactory, uc.logger, true}
 }
 
 func (uc *BackupUsecasesImpl) GetBackupMetadata() *entities.BackupMetadata {
@@ -40,6 +64,10 @@ func (uc *BackupUsecasesImpl) GetBackupMetadata() *entities.BackupMetadata {
 			fmt.Println("The specified backup path does not contain any backup.")
 		} else if errors.Is(err, services.ErrBackupCorruptedFile) {
 			fmt.Println("The specified backup is corrupted.")
+		} else if errors.Is(err, services.ErrBackupKeyRequired) {
+			fmt.Println("The specified backup is encrypted. Its passphrase must be provided with --keyFile or HISTORYDB_PASSPHRASE.")
+		} else if errors.Is(err, services.ErrBackupKeyInvalid) {
+			fmt.Println("The provided passphrase does not open the specified backup.")
 		}
 
 		uc.logger.Errorf("could not retrieve backup metadata: %v", err)
@@ -96,6 +124,10 @@ func (uc *BackupUsecasesImpl) CreateSnapshot(first bool, message string) *entiti
 	}
 
 	if err := backupWriter.BeginSnapshot(&snapshot); err != nil {
+		if errors.Is(err, services.ErrChainDepthNotSupported) {
+			fmt.Println("The specified backup keeps its record chunks in its batch files, whose diff chains can not be bounded. Take the snapshot without --max-chain-depth.")
+		}
+
 		uc.logger.Errorf("could not begin snapshot: %v", err)
 		return nil
 	}
@@ -104,6 +136,48 @@ func (uc *BackupUsecasesImpl) CreateSnapshot(first bool, message string) *entiti
 	return &snapshot
 }
 
+func (uc *BackupUsecasesImpl) ResumeSnapshot(first bool) (*entities.BackupSnapshot, *entities.BackupCheckpoint) {
+	backupReader := uc.backupFactory.CreateReader()
+	backupWriter := uc.backupFactory.CreateWriter()
+
+	// A new backup is only created into a non-existing path, which CreateSnapshot reports
+	if first && backupReader.CheckBackupExists() {
+		return nil, nil
+	}
+
+	snapshot := entities.BackupSnapshot{
+		SchemaDependencies: make(map[string]string),
+		Schemas:            make(map[string]string),
+		Data:               make(map[string]entities.BackupSnapshotSchemaData),
+		Routines:           make(map[string]string),
+	}
+	checkpoint, err := backupWriter.ResumeSnapshot(&snapshot)
+	if err != nil {
+		if errors.Is(err, services.ErrBackupCheckpointOutdated) {
+			fmt.Println("The interrupted snapshot was begun from another state of the backup, so a new snapshot is taken instead.")
+		} else {
+			fmt.Println("The interrupted snapshot could not be resumed, so a new snapshot is taken instead.")
+		}
+
+		uc.logger.Warnf("could not resume snapshot: %v", err)
+		return nil, nil
+	} else if checkpoint == nil {
+		fmt.Println("There is no interrupted snapshot to resume, so a new snapshot is taken.")
+		return nil, nil
+	}
+
+	savedSchemas := 0
+	for _, progress := range checkpoint.Schemas {
+		if progress.Complete {
+			savedSchemas++
+		}
+	}
+	fmt.Printf("Resuming snapshot %s, whose records of %d schemas were already saved.\n", snapshot.SnapshotId, savedSchemas)
+	fmt.Println("  ! The rest of records are read in a new transaction of the DB, so they may not be consistent with the records already saved.")
+	uc.logger.Warnf("resuming snapshot %s, its records are not read in a single transaction of the DB", snapshot.SnapshotId)
+	return &snapshot, checkpoint
+}
+
 func (uc *BackupUsecasesImpl) CommitSnapshot(metadata *entities.BackupMetadata, snapshot *entities.BackupSnapshot) bool {
 	backupWriter := uc.backupFactory.CreateWriter()
 
@@ -128,7 +202,14 @@ func (uc *BackupUsecasesImpl) RollbackSnapshot(first bool) {
 	fmt.Println("Process failed. Aborting operation...")
 	uc.logger.Errorf("Rollback of snapshot in backup. Restoring previous state...")
 
-	if first {
+	if backupWriter.CanResumeSnapshot() {
+		fmt.Println("  - Keeping the records saved so far...")
+		if err := backupWriter.RollbackSnapshot(); err != nil {
+			uc.logger.Errorf("could not rollback to previous state: %v", err)
+			return
+		}
+		fmt.Println("  + The snapshot can be resumed with --resume")
+	} else if first {
 		fmt.Println("  - Cleaning backup directory...")
 		if err := backupWriter.DeleteBackupStructure(); err != nil {
 			uc.logger.Errorf("could not delete backup directory: %v", err)
@@ -146,6 +227,25 @@ func (uc *BackupUsecasesImpl) RollbackSnapshot(first bool) {
 	fmt.Println("Closing app...")
 }
 
+func (uc *BackupUsecasesImpl) BeginDatabaseTransaction() bool {
+	dbReader := uc.dbFactory.CreateReader()
+
+	if err := dbReader.BeginTransaction(); err != nil {
+		uc.logger.Errorf("could not begin transaction in DB: %v", err)
+		return false
+	}
+
+	return true
+}
+
+func (uc *BackupUsecasesImpl) EndDatabaseTransaction() {
+	dbReader := uc.dbFactory.CreateReader()
+
+	if err := dbReader.EndTransaction(); err != nil {
+		uc.logger.Errorf("could not end transaction in DB: %v", err)
+	}
+}
+
 func (uc *BackupUsecasesImpl) BackupSchemaDependencies(snapshot *entities.BackupSnapshot) bool {
 	dbReader := uc.dbFactory.CreateReader()
 	backupWriter := uc.backupFactory.CreateWriter()
@@ -192,7 +292,7 @@ func (uc *BackupUsecasesImpl) SnapshotSchemaDependencies(lastSnapshot, snapshot
 	for _, dependency := range schemaDependencies {
 		// If no condition it stays unmodified
 		hash := dependency.Hash()
-		prevHash, ok := lastSnapshot.SchemaDependencies[dependency.GetName()]
+		prevRef, ok := lastSnapshot.SchemaDependencies[dependency.GetName()]
 		if !ok {
 			// New dependency to write into backup
 			if err := backupWriter.SaveSchemaDependency(dependency); err != nil {
@@ -201,9 +301,9 @@ func (uc *BackupUsecasesImpl) SnapshotSchemaDependencies(lastSnapshot, snapshot
 			}
 
 			snapshot.SchemaDependencies[dependency.GetName()] = hash
-		} else if !crypto.CompareHashes(prevHash, hash) {
+		} else if prevHash, _ := strings.CutPrefix(prevRef, "diffs/"); !crypto.CompareHashes(prevHash, hash) {
 			// Updates dependency into backup
-			prevDependency, isDiff, err := backupReader.GetSchemaDependency(prevHash)
+			prevDependency, isDiff, err := backupReader.GetSchemaDependency(prevRef)
 			if err != nil {
 				if errors.Is(err, services.ErrBackupCorruptedFile) {
 					fmt.Printf("The %s schema dependency in backup is corrupted\n", dependency.GetName())
@@ -220,7 +320,7 @@ func (uc *BackupUsecasesImpl) SnapshotSchemaDependencies(lastSnapshot, snapshot
 
 			snapshot.SchemaDependencies[dependency.GetName()] = fmt.Sprintf("diffs/%s", hash)
 		} else {
-			snapshot.SchemaDependencies[dependency.GetName()] = prevHash
+			snapshot.SchemaDependencies[dependency.GetName()] = prevRef
 		}
 
 		schemaDependenciesProgress.Add(1)
@@ -295,7 +395,7 @@ func (uc *BackupUsecasesImpl) SnapshotSchemas(lastSnapshot, snapshot *entities.B
 		hash := schema.Hash()
 
 		// If no condition it stays unmodified
-		prevHash, ok := lastSnapshot.Schemas[schemaName]
+		prevRef, ok := lastSnapshot.Schemas[schemaName]
 		if !ok {
 			// New schema to write into backup
 			if err := backupWriter.SaveSchema(schema); err != nil {
@@ -304,9 +404,9 @@ func (uc *BackupUsecasesImpl) SnapshotSchemas(lastSnapshot, snapshot *entities.B
 			}
 
 			snapshot.Schemas[schemaName] = hash
-		} else if !crypto.CompareHashes(prevHash, hash) {
+		} else if prevHash, _ := strings.CutPrefix(prevRef, "diffs/"); !crypto.CompareHashes(prevHash, hash) {
 			// Updates schema into backup
-			prevSchema, isDiff, err := backupReader.GetSchema(prevHash)
+			prevSchema, isDiff, err := backupReader.GetSchema(prevRef)
 			if err != nil {
 				if errors.Is(err, services.ErrBackupCorruptedFile) {
 					fmt.Printf("The %s schema in backup is corrupted\n", schema.GetName())
@@ -324,7 +424,7 @@ func (uc *BackupUsecasesImpl) SnapshotSchemas(lastSnapshot, snapshot *entities.B
 
 			snapshot.Schemas[schemaName] = fmt.Sprintf("diffs/%s", hash)
 		} else {
-			snapshot.Schemas[schemaName] = prevHash
+			snapshot.Schemas[schemaName] = prevRef
 		}
 
 		schemas = append(schemas, schema)
@@ -335,7 +435,7 @@ func (uc *BackupUsecasesImpl) SnapshotSchemas(lastSnapshot, snapshot *entities.B
 	return schemas
 }
 
-func (uc *BackupUsecasesImpl) BackupSchemaRecords(snapshot *entities.BackupSnapshot, schema entities.Schema) bool {
+func (uc *BackupUsecasesImpl) BackupSchemaRecords(snapshot *entities.BackupSnapshot, schema entities.Schema, progress *entities.BackupSchemaProgress) bool {
 	dbReader := uc.dbFactory.CreateReader()
 	backupWriter := uc.backupFactory.CreateWriter()
 
@@ -360,17 +460,39 @@ func (uc *BackupUsecasesImpl) BackupSchemaRecords(snapshot *entities.BackupSnaps
 		chunkSize = batchSize / 10
 	}
 
-	// Loops until savedRecords == Database total records
+	// Schemas whose records do not have a key (tables without primary key) are split with content-defined chunks
+	firstChunk, _, err := dbReader.GetSchemaRecordChunk(schema, chunkSize, nil)
+	if err != nil {
+		uc.logger.Errorf("could not retrieve record chunk from %s schema: %v", schema.GetName(), err)
+		return false
+	}
+	if isContentDefinedChunk(firstChunk) {
+		return uc.backupContentDefinedSchemaRecords(snapshot, schema, recordMetadata, batchSize, chunkSize, progress)
+	}
+
+	// An interrupted snapshot goes on from the last batch saved, reading the rest of records with the same sizes
 	savedRecords := 0
 	batchHashes := []string{}
-	dataProgress := progressbar.NewOptions(int(math.Ceil(float64(recordMetadata.Count)/float64(chunkSize))), progressbar.OptionSetDescription(fmt.Sprintf("  + Saving %s schema records...", schema.GetName())), progressbar.OptionSetWidth(30), progressbar.OptionSetWriter(os.Stdout), progressbar.OptionSetRenderBlankState(true))
+	var cursor interface{}
+	if progress != nil && progress.Data != nil && progress.Data.Chunking == entities.FixedSizeChunking {
+		if cursor, err = dbReader.DecodeChunkCursor(progress.Cursor); err != nil {
+			uc.logger.Errorf("could not decode the record cursor of %s schema: %v", schema.GetName(), err)
+			return false
+		}
+		batchSize, chunkSize = progress.Data.BatchSize, progress.Data.ChunkSize
+		batchHashes = append(batchHashes, progress.Data.Data...)
+		savedRecords = int(progress.SavedRecords)
+	}
+
+	// Loops until savedRecords == Database total records
+	dataProgress := uc.newRecordsProgressBar(int(math.Ceil(float64(recordMetadata.Count)/float64(chunkSize))), fmt.Sprintf("  + Saving %s schema records...", schema.GetName()))
+	dataProgress.Add(savedRecords / int(chunkSize))
 	for savedRecords < recordMetadata.Count {
 		currentBatchSize := 0
 		tempBatchName := uuid.NewString()
 		batchHashBytes := sha256.New()
 
 		// Loops until batch is full or there is no more content
-		var cursor interface{}
 		for currentBatchSize < int(batchSize) {
 			// Reads record chunk from DB
 			chunk, nextCursor, err := dbReader.GetSchemaRecordChunk(schema, chunkSize, cursor)
@@ -398,6 +520,11 @@ func (uc *BackupUsecasesImpl) BackupSchemaRecords(snapshot *entities.BackupSnaps
 			dataProgress.Add(1)
 		}
 
+		// The records of a resumed snapshot are counted in another transaction, so they may run out before the count
+		if currentBatchSize == 0 {
+			break
+		}
+
 		// After full batch is completed, renames the temp batch file to the final one
 		batchHash := hex.EncodeToString(batchHashBytes.Sum(nil))
 		if err := backupWriter.SaveSchemaRecordBatch(tempBatchName, batchHash); err != nil {
@@ -407,18 +534,30 @@ func (uc *BackupUsecasesImpl) BackupSchemaRecords(snapshot *entities.BackupSnaps
 
 		batchHashes = append(batchHashes, batchHash)
 		savedRecords += currentBatchSize
+
+		// Saves the checkpoint of the batch, so the snapshot can be resumed from it if it is interrupted
+		if err := backupWriter.SaveSnapshotProgress(schema.GetName(), &entities.BackupSchemaProgress{
+			SchemaHash:   schema.Hash(),
+			Data:         &entities.BackupSnapshotSchemaData{BatchSize: batchSize, ChunkSize: chunkSize, Data: slices.Clone(batchHashes), Chunking: entities.FixedSizeChunking},
+			SavedRecords: int64(savedRecords),
+			Cursor:       dbReader.EncodeChunkCursor(cursor),
+		}); err != nil {
+			uc.logger.Errorf("could not save the checkpoint of %s schema records: %v", schema.GetName(), err)
+			return false
+		}
 	}
-	fmt.Println("  - All schema records saved successfully")
+	uc.printRecordsDone(schema, "saved")
 
 	snapshot.Data[schema.GetName()] = entities.BackupSnapshotSchemaData{
 		BatchSize: batchSize,
 		ChunkSize: chunkSize,
 		Data:      batchHashes,
+		Chunking:  entities.FixedSizeChunking,
 	}
 	return true
 }
 
-func (uc *BackupUsecasesImpl) SnapshotSchemaRecords(lastSnapshot, snapshot *entities.BackupSnapshot, schema entities.Schema) bool {
+func (uc *BackupUsecasesImpl) SnapshotSchemaRecords(lastSnapshot, snapshot *entities.BackupSnapshot, schema entities.Schema, progress *entities.BackupSchemaProgress) bool {
 	dbReader := uc.dbFactory.CreateReader()
 	backupReader := uc.backupFactory.CreateReader()
 	backupWriter := uc.backupFactory.CreateWriter()
@@ -435,22 +574,48 @@ func (uc *BackupUsecasesImpl) SnapshotSchemaRecords(lastSnapshot, snapshot *enti
 		return false
 	}
 
+	// Schemas whose records have a key (tables with primary key) are compared record by record instead of by chunk position
+	firstChunk, _, err := dbReader.GetSchemaRecordChunk(schema, backupMetadata.ChunkSize, nil)
+	if err != nil {
+		uc.logger.Errorf("could not retrieve record chunk from %s schema: %v", schema.GetName(), err)
+		return false
+	}
+	if keyChunk, ok := firstChunk.(entities.KeyedSchemaRecordChunk); ok && keyChunk.IsKeyed() {
+		return uc.snapshotKeyedSchemaRecords(snapshot, schema, recordMetadata, backupMetadata, keyChunk, progress)
+	}
+	if backupMetadata.Chunking == entities.ContentDefinedChunking {
+		return uc.snapshotContentDefinedSchemaRecords(snapshot, schema, recordMetadata, backupMetadata, progress)
+	}
+
 	savedRecords := 0
-	batchIndex := 0
 	snapshotData := entities.BackupSnapshotSchemaData{
 		BatchSize: backupMetadata.BatchSize,
 		ChunkSize: backupMetadata.ChunkSize,
 		Data:      []string{},
+		Chunking:  entities.FixedSizeChunking,
+	}
+
+	// An interrupted snapshot goes on from the last batch saved, which is compared with the batch in the same position
+	var cursor interface{}
+	if progress != nil && progress.Data != nil {
+		if cursor, err = dbReader.DecodeChunkCursor(progress.Cursor); err != nil {
+			uc.logger.Errorf("could not decode the record cursor of %s schema: %v", schema.GetName(), err)
+			return false
+		}
+		snapshotData.Data = append(snapshotData.Data, progress.Data.Data...)
+		savedRecords = int(progress.SavedRecords)
 	}
+	batchIndex := len(snapshotData.Data)
+
 	// Loops until savedRecords == Database total records
-	dataProgress := progressbar.NewOptions(int(math.Ceil(float64(recordMetadata.Count)/float64(backupMetadata.ChunkSize))), progressbar.OptionSetDescription(fmt.Sprintf("  + Updating %s schema records...", schema.GetName())), progressbar.OptionSetWidth(30), progressbar.OptionSetWriter(os.Stdout), progressbar.OptionSetRenderBlankState(true))
+	dataProgress := uc.newRecordsProgressBar(int(math.Ceil(float64(recordMetadata.Count)/float64(backupMetadata.ChunkSize))), fmt.Sprintf("  + Updating %s schema records...", schema.GetName()))
+	dataProgress.Add(savedRecords / int(backupMetadata.ChunkSize))
 	for savedRecords < recordMetadata.Count {
 		currentBatchSize := 0
 		batchHashBytes := sha256.New()
 		batchChunks := []dtos.BatchChunkInfo{}
 
 		// Calculates all new chunk hashes in batch
-		var cursor interface{}
 		for currentBatchSize < int(backupMetadata.BatchSize) {
 			// Reads record chunk from DB
 			chunk, nextCursor, err := dbReader.GetSchemaRecordChunk(schema, backupMetadata.ChunkSize, cursor)
@@ -474,6 +639,11 @@ func (uc *BackupUsecasesImpl) SnapshotSchemaRecords(lastSnapshot, snapshot *enti
 			dataProgress.Add(1)
 		}
 
+		// The records of a resumed snapshot are counted in another transaction, so they may run out before the count
+		if currentBatchSize == 0 {
+			break
+		}
+
 		// Calculates new batch hash
 		batchHash := hex.EncodeToString(batchHashBytes.Sum(nil))
 
@@ -565,13 +735,767 @@ func (uc *BackupUsecasesImpl) SnapshotSchemaRecords(lastSnapshot, snapshot *enti
 
 		savedRecords += currentBatchSize
 		batchIndex++
+
+		// Saves the checkpoint of the batch, so the snapshot can be resumed from it if it is interrupted
+		if err := backupWriter.SaveSnapshotProgress(schema.GetName(), &entities.BackupSchemaProgress{
+			SchemaHash:   schema.Hash(),
+			Data:         &entities.BackupSnapshotSchemaData{BatchSize: snapshotData.BatchSize, ChunkSize: snapshotData.ChunkSize, Data: slices.Clone(snapshotData.Data), Chunking: snapshotData.Chunking},
+			SavedRecords: int64(savedRecords),
+			Cursor:       dbReader.EncodeChunkCursor(cursor),
+		}); err != nil {
+			uc.logger.Errorf("could not save the checkpoint of %s schema records: %v", schema.GetName(), err)
+			return false
+		}
+	}
+	uc.printRecordsDone(schema, "updated")
+
+	snapshot.Data[schema.GetName()] = snapshotData
+	return true
+}
+
+// snapshotKeyedSchemaRecords makes a new version of the schema records matching them by key with the records of the last snapshot.
+//
+// The records are read from the DB in key order and merged with the chunks of the last snapshot one chunk at a time: every
+// chunk takes the DB records up to its greatest key, so the records with a different hash update the chunk, the records that
+// are not in it are inserted into it and the records of the chunk that are not read are deleted from it. The records after
+// the last chunk fill it and then new chunks and batches. Only the changed chunks are saved as diffs, batch by batch, so an
+// insert or a delete does not shift the following chunks, and only a chunk and a batch of diffs are held in memory at once.
+func (uc *BackupUsecasesImpl) snapshotKeyedSchemaRecords(snapshot *entities.BackupSnapshot, schema entities.Schema, recordMetadata entities.SchemaRecordMetadata, backupMetadata entities.BackupSnapshotSchemaData, keyChunk entities.KeyedSchemaRecordChunk, progress *entities.BackupSchemaProgress) bool {
+	backupWriter := uc.backupFactory.CreateWriter()
+
+	chunkSize := int(backupMetadata.ChunkSize)
+	batchSize := int(backupMetadata.BatchSize)
+	dataProgress := uc.newRecordsProgressBar(int(math.Ceil(float64(recordMetadata.Count)/float64(backupMetadata.ChunkSize))), fmt.Sprintf("  + Updating %s schema records...", schema.GetName()))
+	records := &keyedRecordStream{dbReader: uc.dbFactory.CreateReader(), schema: schema, chunkSize: backupMetadata.ChunkSize, progress: dataProgress}
+
+	snapshotData := entities.BackupSnapshotSchemaData{
+		BatchSize: backupMetadata.BatchSize,
+		ChunkSize: backupMetadata.ChunkSize,
+		Data:      []string{},
+		Chunking:  backupMetadata.Chunking,
+	}
+
+	// An interrupted snapshot goes on from the last batch saved, with the records after the last one it holds
+	comparedBatches := 0
+	if progress != nil && progress.Data != nil {
+		if err := records.Resume(progress.Cursor, progress.CursorOffset); err != nil {
+			uc.logger.Errorf("could not decode the record cursor of %s schema: %v", schema.GetName(), err)
+			return false
+		}
+		snapshotData.Data = append(snapshotData.Data, progress.Data.Data...)
+		comparedBatches = int(progress.ComparedBatches)
+	}
+
+	// Saves the checkpoint of every batch, so the snapshot can be resumed from it if it is interrupted
+	saveProgress := func() bool {
+		cursor, cursorOffset := records.Checkpoint()
+		if err := backupWriter.SaveSnapshotProgress(schema.GetName(), &entities.BackupSchemaProgress{
+			SchemaHash:      schema.Hash(),
+			Data:            &entities.BackupSnapshotSchemaData{BatchSize: snapshotData.BatchSize, ChunkSize: snapshotData.ChunkSize, Data: slices.Clone(snapshotData.Data), Chunking: snapshotData.Chunking},
+			Cursor:          cursor,
+			CursorOffset:    cursorOffset,
+			ComparedBatches: int64(comparedBatches),
+		}); err != nil {
+			uc.logger.Errorf("could not save the checkpoint of %s schema records: %v", schema.GetName(), err)
+			return false
+		}
+		return true
+	}
+
+	for comparedBatches < len(backupMetadata.Data) {
+		isLastBatch := comparedBatches == len(backupMetadata.Data)-1
+		batchRef, ok := uc.mergeKeyedBatch(schema, records, keyChunk, backupMetadata.Data[comparedBatches], chunkSize, batchSize, isLastBatch)
+		if !ok {
+			return false
+		}
+		if batchRef != "" {
+			snapshotData.Data = append(snapshotData.Data, batchRef)
+		}
+
+		comparedBatches++
+		if ok := saveProgress(); !ok {
+			return false
+		}
+	}
+
+	// Saves the rest of inserted records in new batches
+	for {
+		batchLength := 0
+		tempBatchName := uuid.NewString()
+		batchHashBytes := sha256.New()
+
+		for batchLength < batchSize {
+			newChunk, err := records.Take(keyChunk, min(chunkSize, batchSize-batchLength))
+			if err != nil {
+				uc.logger.Errorf("could not retrieve record chunk from %s schema: %v", schema.GetName(), err)
+				return false
+			}
+			if newChunk.Length() == 0 {
+				break
+			}
+			batchLength += newChunk.Length()
+
+			chunkHash := newChunk.Hash()
+			if err := backupWriter.SaveSchemaRecordChunk(tempBatchName, newChunk); err != nil {
+				uc.logger.Errorf("could not save record chunk in backup: %v", err)
+				return false
+			}
+			batchHashBytes.Write([]byte(chunkHash))
+			batchHashBytes.Write([]byte("|"))
+		}
+		if batchLength == 0 {
+			break
+		}
+
+		batchHash := hex.EncodeToString(batchHashBytes.Sum(nil))
+		if err := backupWriter.SaveSchemaRecordBatch(tempBatchName, batchHash); err != nil {
+			uc.logger.Errorf("could not save record batch in backup: %v", err)
+			return false
+		}
+		snapshotData.Data = append(snapshotData.Data, batchHash)
+		if ok := saveProgress(); !ok {
+			return false
+		}
+	}
+	uc.printRecordsDone(schema, "updated")
+
+	snapshot.Data[schema.GetName()] = snapshotData
+	return true
+}
+
+// mergeKeyedBatch merges the DB records into the chunks of a batch of the last snapshot, saving the changed chunks as diffs.
+// It returns the reference of the batch in the new snapshot, which is empty if every record of the batch was deleted.
+func (uc *BackupUsecasesImpl) mergeKeyedBatch(schema entities.Schema, records *keyedRecordStream, keyChunk entities.KeyedSchemaRecordChunk, batchRef string, chunkSize, batchSize int, isLastBatch bool) (string, bool) {
+	backupReader := uc.backupFactory.CreateReader()
+	backupWriter := uc.backupFactory.CreateWriter()
+
+	chunkRefs, err := backupReader.GetSchemaRecordChunkRefsInBatch(batchRef)
+	if err != nil {
+		uc.logger.Errorf("could not retrieve chunks from backup batch in %s schema: %v", schema.GetName(), err)
+		return "", false
+	}
+
+	batchLength := 0
+	newChunkRefs := []string{}
+	appendedChunkRefs := []string{}
+	recordDiffs := []entities.SchemaRecordChunkDiff{}
+	addChunk := func(chunk entities.KeyedSchemaRecordChunk, nextRef string) {
+		// New chunks are placed before the next chunk when the batch can keep the order of its chunks
+		batchLength += chunk.Length()
+		if splittableChunk, ok := chunk.(entities.SplittableSchemaRecordChunk); ok && nextRef != "" {
+			newChunkRefs = append(newChunkRefs, chunk.Hash())
+			recordDiffs = append(recordDiffs, splittableChunk.DiffFromEmptyBefore(nextRef))
+		} else {
+			appendedChunkRefs = append(appendedChunkRefs, chunk.Hash())
+			recordDiffs = append(recordDiffs, chunk.DiffFromEmpty())
+		}
+	}
+
+	for chunkIndex, chunkRef := range chunkRefs {
+		isLastChunk := isLastBatch && chunkIndex == len(chunkRefs)-1
+		backupChunk, isDiff, err := backupReader.GetSchemaRecordChunk(batchRef, chunkRef)
+		if err != nil {
+			uc.logger.Errorf("could not retrieve record chunk from backup: %v", err)
+			return "", false
+		}
+		oldChunk := keyChunk.KeyRecords(backupChunk)
+
+		oldKeys := oldChunk.RecordKeys()
+		oldPositions := make(map[string]int, len(oldKeys))
+		var maxKey string
+		for position, recordKey := range oldKeys {
+			oldPositions[recordKey.Key] = position
+			if position == 0 || oldChunk.CompareKeys(recordKey.Key, maxKey) > 0 {
+				maxKey = recordKey.Key
+			}
+		}
+
+		// Merges the DB records up to the greatest key of the chunk, or until the last chunk is full
+		matchedKeys := make(map[string]bool)
+		deletedKeys := make(map[string]bool)
+		keptPositions := []int{}
+		assignedKeys := []string{}
+		insertedCount := 0
+		for {
+			recordKey, err := records.Peek()
+			if err != nil {
+				uc.logger.Errorf("could not retrieve record chunk from %s schema: %v", schema.GetName(), err)
+				return "", false
+			}
+			if recordKey == nil || (oldChunk.CompareKeys(recordKey.Key, maxKey) > 0 && (!isLastChunk || len(assignedKeys)+insertedCount >= chunkSize)) {
+				break
+			}
+
+			position, ok := oldPositions[recordKey.Key]
+			if !ok {
+				records.Next(true)
+				insertedCount++
+			} else {
+				matchedKeys[recordKey.Key] = true
+				assignedKeys = append(assignedKeys, recordKey.Key)
+				if crypto.CompareHashes(oldKeys[position].Hash, recordKey.Hash) {
+					keptPositions = append(keptPositions, position)
+					records.Next(false)
+				} else {
+					records.Next(true)
+				}
+			}
+
+			// A chunk which grows too much with inserted records is split, moving the records read so far into a new chunk
+			if insertedCount >= chunkSize {
+				addChunk(oldChunk.Select(keptPositions).Patch(records.TakeSelected(), nil), chunkRef)
+				for _, key := range assignedKeys {
+					deletedKeys[key] = true
+				}
+				keptPositions = []int{}
+				assignedKeys = []string{}
+				insertedCount = 0
+			}
+		}
+		for key := range oldPositions {
+			if !matchedKeys[key] {
+				deletedKeys[key] = true
+			}
+		}
+
+		changes := records.TakeSelected()
+		if len(changes) == 0 && len(deletedKeys) == 0 {
+			// Chunk has not changed -> Keep its reference
+			batchLength += oldChunk.Length()
+			newChunkRefs = append(newChunkRefs, chunkRef)
+			continue
+		}
+
+		updatedChunk := oldChunk.Patch(changes, deletedKeys)
+		if updatedChunk.Length() == 0 {
+			// Every record of the chunk was deleted -> Delete chunk from backup batch
+			recordDiffs = append(recordDiffs, backupChunk.DiffToEmpty(isDiff))
+		} else {
+			batchLength += updatedChunk.Length()
+			newChunkRefs = append(newChunkRefs, updatedChunk.Hash())
+			recordDiffs = append(recordDiffs, updatedChunk.Diff(backupChunk, isDiff))
+		}
+	}
+
+	// Fills the last batch with new chunks of inserted records
+	for isLastBatch && batchLength < batchSize {
+		newChunk, err := records.Take(keyChunk, min(chunkSize, batchSize-batchLength))
+		if err != nil {
+			uc.logger.Errorf("could not retrieve record chunk from %s schema: %v", schema.GetName(), err)
+			return "", false
+		}
+		if newChunk.Length() == 0 {
+			break
+		}
+		addChunk(newChunk, "")
+	}
+
+	if len(recordDiffs) == 0 {
+		// Batch has not changed -> Keep its reference
+		return batchRef, true
+	}
+	newChunkRefs = append(newChunkRefs, appendedChunkRefs...)
+	if len(newChunkRefs) == 0 {
+		// Every record of the batch was deleted -> Batch is no longer part of the snapshot
+		return "", true
+	}
+
+	batchHashBytes := sha256.New()
+	for _, chunkRef := range newChunkRefs {
+		batchHashBytes.Write([]byte(chunkRef))
+		batchHashBytes.Write([]byte("|"))
 	}
-	fmt.Println("  - All schema records updated successfully")
+	batchHash := hex.EncodeToString(batchHashBytes.Sum(nil))
+	if oldBatchHash, _ := strings.CutPrefix(batchRef, "diffs/"); crypto.CompareHashes(oldBatchHash, batchHash) {
+		// Chunks were rewritten with the same content -> Keep the batch reference
+		return batchRef, true
+	}
+
+	for _, recordDiff := range recordDiffs {
+		if err := backupWriter.SaveSchemaRecordChunkDiff(batchRef, fmt.Sprintf("diffs/%s", batchHash), recordDiff); err != nil {
+			uc.logger.Errorf("could not update %s schema record chunk into backup: %v", schema.GetName(), err)
+			return "", false
+		}
+	}
+	return fmt.Sprintf("diffs/%s", batchHash), true
+}
+
+// keyedRecordStream reads the records of a keyed schema from the DB one chunk at a time. The records are read one after
+// another, and the selected ones are taken from their DB chunks, so only a DB chunk is held in memory besides them.
+type keyedRecordStream struct {
+	dbReader    database_services.DatabaseReader
+	schema      entities.Schema
+	chunkSize   int64
+	progress    *progressbar.ProgressBar
+	cursor      interface{}
+	chunkCursor []byte
+	skip        int
+	chunk       entities.KeyedSchemaRecordChunk
+	keys        []entities.SchemaRecordKey
+	position    int
+	positions   []int
+	selected    []entities.KeyedSchemaRecordChunk
+	done        bool
+}
+
+// Resume moves the stream to the record at offset in the DB chunk read from the encoded cursor.
+func (stream *keyedRecordStream) Resume(cursor []byte, offset int64) error {
+	decodedCursor, err := stream.dbReader.DecodeChunkCursor(cursor)
+	if err != nil {
+		return err
+	}
+	stream.cursor = decodedCursor
+	stream.chunkCursor = cursor
+	stream.skip = int(offset)
+	return nil
+}
+
+// Checkpoint returns the encoded cursor of the DB chunk of the next record and the offset of the record in it, which
+// Resume goes on from.
+func (stream *keyedRecordStream) Checkpoint() ([]byte, int64) {
+	if stream.chunk == nil {
+		return stream.chunkCursor, int64(stream.skip)
+	}
+	return stream.chunkCursor, int64(stream.position)
+}
+
+// Peek returns the key of the next record, reading the next DB chunk if needed, or nil once every record was read.
+func (stream *keyedRecordStream) Peek() (*entities.SchemaRecordKey, error) {
+	for stream.chunk == nil || stream.position >= len(stream.keys) {
+		if stream.done {
+			return nil, nil
+		}
+
+		// The cursor is encoded before reading, as some readers move the same cursor to the next chunk
+		chunkCursor := stream.dbReader.EncodeChunkCursor(stream.cursor)
+		chunk, nextCursor, err := stream.dbReader.GetSchemaRecordChunk(stream.schema, stream.chunkSize, stream.cursor)
+		if err != nil {
+			return nil, err
+		}
+		stream.takePositions()
+		stream.chunkCursor = chunkCursor
+		if chunk.Length() == 0 {
+			stream.chunk = nil
+			stream.skip = 0
+			stream.done = true
+			return nil, nil
+		}
+
+		// The records of a resumed stream that were saved before it was interrupted are skipped
+		stream.chunk = chunk.(entities.KeyedSchemaRecordChunk)
+		stream.keys = stream.chunk.RecordKeys()
+		stream.position = stream.skip
+		stream.skip = 0
+		stream.cursor = nextCursor
+		stream.progress.Add(1)
+	}
+	return &stream.keys[stream.position], nil
+}
+
+// Next moves to the record after the peeked one, selecting the peeked one if selected is true.
+func (stream *keyedRecordStream) Next(selected bool) {
+	if selected {
+		stream.positions = append(stream.positions, stream.position)
+	}
+	stream.position++
+}
+
+// TakeSelected returns the records selected since the last call, in the chunks of the DB they were read from.
+func (stream *keyedRecordStream) TakeSelected() []entities.KeyedSchemaRecordChunk {
+	stream.takePositions()
+	selected := stream.selected
+	stream.selected = nil
+	return selected
+}
+
+// Take reads up to count records and returns them in a single chunk, which is empty once every record was read.
+func (stream *keyedRecordStream) Take(keyChunk entities.KeyedSchemaRecordChunk, count int) (entities.KeyedSchemaRecordChunk, error) {
+	for taken := 0; taken < count; taken++ {
+		recordKey, err := stream.Peek()
+		if err != nil {
+			return nil, err
+		}
+		if recordKey == nil {
+			break
+		}
+		stream.Next(true)
+	}
+	return keyChunk.Select(nil).Patch(stream.TakeSelected(), nil), nil
+}
+
+func (stream *keyedRecordStream) takePositions() {
+	if len(stream.positions) > 0 {
+		stream.selected = append(stream.selected, stream.chunk.Select(stream.positions))
+		stream.positions = nil
+	}
+}
+
+// backupContentDefinedSchemaRecords saves the schema records in content-defined chunks and batches.
+func (uc *BackupUsecasesImpl) backupContentDefinedSchemaRecords(snapshot *entities.BackupSnapshot, schema entities.Schema, recordMetadata entities.SchemaRecordMetadata, batchSize, chunkSize int64, progress *entities.BackupSchemaProgress) bool {
+	schemaData := entities.BackupSnapshotSchemaData{
+		BatchSize: batchSize,
+		ChunkSize: chunkSize,
+		Data:      []string{},
+		Chunking:  entities.ContentDefinedChunking,
+	}
+
+	// An interrupted snapshot goes on from the last batch saved, reading the rest of records with the same sizes
+	var cursor []byte
+	var cursorOffset int64
+	if progress != nil && progress.Data != nil && progress.Data.Chunking == entities.ContentDefinedChunking {
+		schemaData.BatchSize, schemaData.ChunkSize = progress.Data.BatchSize, progress.Data.ChunkSize
+		schemaData.Data = append(schemaData.Data, progress.Data.Data...)
+		cursor, cursorOffset = progress.Cursor, progress.CursorOffset
+	}
+
+	dataProgress := uc.newRecordsProgressBar(int(math.Ceil(float64(recordMetadata.Count)/float64(schemaData.ChunkSize))), fmt.Sprintf("  + Saving %s schema records...", schema.GetName()))
+	ok := uc.readContentDefinedBatches(schema, schemaData.BatchSize, schemaData.ChunkSize, cursor, cursorOffset, dataProgress, func(chunks []entities.SplittableSchemaRecordChunk, cursor []byte, cursorOffset int64) bool {
+		batchHash, ok := uc.saveContentDefinedBatch(chunks)
+		if !ok {
+			return false
+		}
+		schemaData.Data = append(schemaData.Data, batchHash)
+		return uc.saveContentDefinedProgress(schema, schemaData, cursor, cursorOffset)
+	})
+	if !ok {
+		return false
+	}
+	uc.printRecordsDone(schema, "saved")
+
+	snapshot.Data[schema.GetName()] = schemaData
+	return true
+}
+
+// snapshotContentDefinedSchemaRecords makes a new version of the schema records splitting them into content-defined chunks.
+//
+// As the chunk and batch boundaries only move around the changed records, most of the new chunks are already in a batch of
+// the last snapshot. Every new batch is saved as a diff of the unused batch that shares more chunks with it: the chunks that
+// are not in the new batch are deleted and the new chunks are placed before the next kept chunk, so the batch keeps the
+// record order. Batches without any chunk in the last snapshot are saved as new batches.
+func (uc *BackupUsecasesImpl) snapshotContentDefinedSchemaRecords(snapshot *entities.BackupSnapshot, schema entities.Schema, recordMetadata entities.SchemaRecordMetadata, backupMetadata entities.BackupSnapshotSchemaData, progress *entities.BackupSchemaProgress) bool {
+	backupReader := uc.backupFactory.CreateReader()
+	backupWriter := uc.backupFactory.CreateWriter()
+
+	// Indexes the chunks of the last snapshot by the batches that contain them
+	batchChunkRefs := make([][]string, len(backupMetadata.Data))
+	chunkBatches := make(map[string][]int)
+	for batchIndex, batchRef := range backupMetadata.Data {
+		chunkRefs, err := backupReader.GetSchemaRecordChunkRefsInBatch(batchRef)
+		if err != nil {
+			uc.logger.Errorf("could not retrieve chunks from backup batch in %s schema: %v", schema.GetName(), err)
+			return false
+		}
+		batchChunkRefs[batchIndex] = chunkRefs
+
+		for _, chunkRef := range chunkRefs {
+			chunkBatches[chunkRef] = append(chunkBatches[chunkRef], batchIndex)
+		}
+	}
+
+	usedBatches := make(map[int]bool)
+	snapshotData := entities.BackupSnapshotSchemaData{
+		BatchSize: backupMetadata.BatchSize,
+		ChunkSize: backupMetadata.ChunkSize,
+		Data:      []string{},
+		Chunking:  entities.ContentDefinedChunking,
+	}
+
+	// An interrupted snapshot goes on from the last batch saved. The batches of the last snapshot it kept are not used again,
+	// while the ones its diffs are based on may be, which only makes the diffs of the next batches bigger
+	var cursor []byte
+	var cursorOffset int64
+	if progress != nil && progress.Data != nil {
+		snapshotData.Data = append(snapshotData.Data, progress.Data.Data...)
+		cursor, cursorOffset = progress.Cursor, progress.CursorOffset
+
+		for batchIndex, batchRef := range backupMetadata.Data {
+			if slices.Contains(snapshotData.Data, batchRef) {
+				usedBatches[batchIndex] = true
+			}
+		}
+	}
+
+	saveBatch := func(chunks []entities.SplittableSchemaRecordChunk) bool {
+		chunkRefs := make([]string, len(chunks))
+		batchHashBytes := sha256.New()
+		for i, chunk := range chunks {
+			chunkRefs[i] = chunk.Hash()
+			batchHashBytes.Write([]byte(chunkRefs[i]))
+			batchHashBytes.Write([]byte("|"))
+		}
+		batchHash := hex.EncodeToString(batchHashBytes.Sum(nil))
+
+		// Picks the unused batch of the last snapshot that shares more chunks with the new batch
+		prevBatch := -1
+		sharedChunks := make(map[int]int)
+		for _, chunkRef := range chunkRefs {
+			for _, batchIndex := range chunkBatches[chunkRef] {
+				if usedBatches[batchIndex] {
+					continue
+				}
+				sharedChunks[batchIndex]++
+				if prevBatch == -1 || sharedChunks[batchIndex] > sharedChunks[prevBatch] {
+					prevBatch = batchIndex
+				}
+			}
+		}
+
+		// Batches with repeated chunks are saved as new batches, as their chunks cannot be told apart in a diff
+		if prevBatch == -1 || hasRepeatedChunks(chunkRefs) || hasRepeatedChunks(batchChunkRefs[prevBatch]) {
+			batchHash, ok := uc.saveContentDefinedBatch(chunks)
+			snapshotData.Data = append(snapshotData.Data, batchHash)
+			return ok
+		}
+		usedBatches[prevBatch] = true
+
+		prevBatchRef := backupMetadata.Data[prevBatch]
+		if oldBatchHash, _ := strings.CutPrefix(prevBatchRef, "diffs/"); crypto.CompareHashes(oldBatchHash, batchHash) {
+			// Batch has not changed -> Keep its reference
+			snapshotData.Data = append(snapshotData.Data, prevBatchRef)
+			return true
+		}
+
+		// Keeps the chunks of the last batch that are in the new batch in the same order
+		prevPositions := make(map[string]int)
+		for position, chunkRef := range batchChunkRefs[prevBatch] {
+			prevPositions[chunkRef] = position
+		}
+		keptChunks := make(map[string]bool)
+		lastPosition := -1
+		for _, chunkRef := range chunkRefs {
+			if position, ok := prevPositions[chunkRef]; ok && position > lastPosition {
+				keptChunks[chunkRef] = true
+				lastPosition = position
+			}
+		}
+
+		recordDiffs := []entities.SchemaRecordChunkDiff{}
+		for _, chunkRef := range batchChunkRefs[prevBatch] {
+			if keptChunks[chunkRef] {
+				continue
+			}
+
+			// Chunk does not exist in new batch -> Delete chunk from backup batch
+			backupChunk, isDiff, err := backupReader.GetSchemaRecordChunk(prevBatchRef, chunkRef)
+			if err != nil {
+				uc.logger.Errorf("could not retrieve record chunk from backup: %v", err)
+				return false
+			}
+			recordDiffs = append(recordDiffs, backupChunk.DiffToEmpty(isDiff))
+		}
+		nextRef := ""
+		newChunkDiffs := make([]entities.SchemaRecordChunkDiff, len(chunks))
+		for i := len(chunks) - 1; i >= 0; i-- {
+			if keptChunks[chunkRefs[i]] {
+				nextRef = chunkRefs[i]
+				continue
+			}
+
+			// Chunk does not exist in backup batch -> Create new chunk before the next kept chunk
+			if nextRef == "" {
+				newChunkDiffs[i] = chunks[i].DiffFromEmpty()
+			} else {
+				newChunkDiffs[i] = chunks[i].DiffFromEmptyBefore(nextRef)
+			}
+		}
+		for _, recordDiff := range newChunkDiffs {
+			if recordDiff != nil {
+				recordDiffs = append(recordDiffs, recordDiff)
+			}
+		}
+
+		for _, recordDiff := range recordDiffs {
+			if err := backupWriter.SaveSchemaRecordChunkDiff(prevBatchRef, fmt.Sprintf("diffs/%s", batchHash), recordDiff); err != nil {
+				uc.logger.Errorf("could not update %s schema record chunk into backup: %v", schema.GetName(), err)
+				return false
+			}
+		}
+		snapshotData.Data = append(snapshotData.Data, fmt.Sprintf("diffs/%s", batchHash))
+		return true
+	}
+
+	dataProgress := uc.newRecordsProgressBar(int(math.Ceil(float64(recordMetadata.Count)/float64(backupMetadata.ChunkSize))), fmt.Sprintf("  + Updating %s schema records...", schema.GetName()))
+	ok := uc.readContentDefinedBatches(schema, backupMetadata.BatchSize, backupMetadata.ChunkSize, cursor, cursorOffset, dataProgress, func(chunks []entities.SplittableSchemaRecordChunk, cursor []byte, cursorOffset int64) bool {
+		return saveBatch(chunks) && uc.saveContentDefinedProgress(schema, snapshotData, cursor, cursorOffset)
+	})
+	if !ok {
+		return false
+	}
+	uc.printRecordsDone(schema, "updated")
 
 	snapshot.Data[schema.GetName()] = snapshotData
 	return true
 }
 
+// readContentDefinedBatches reads the schema records from the DB in order and splits them into content-defined chunks,
+// which are grouped into content-defined batches of at most batchSize records. saveBatch is called with the chunks of
+// every batch, and the reading stops if it returns false.
+//
+// Batches always end at a chunk boundary, so the reading can go on after any batch without the chunks before it: it starts
+// from the record at offset in the DB chunk read from the encoded cursor, and saveBatch is also given the cursor and offset
+// of the record after the batch.
+func (uc *BackupUsecasesImpl) readContentDefinedBatches(schema entities.Schema, batchSize, chunkSize int64, encodedCursor []byte, offset int64, progress *progressbar.ProgressBar, saveBatch func(chunks []entities.SplittableSchemaRecordChunk, cursor []byte, offset int64) bool) bool {
+	dbReader := uc.dbFactory.CreateReader()
+
+	recordChunker := chunking.NewChunker(int(chunkSize))
+	batchChunker := chunking.NewChunker(int(batchSize/max(chunkSize, 1)) / 4)
+
+	batchChunks := []entities.SplittableSchemaRecordChunk{}
+	batchLength := 0
+	addChunk := func(chunk entities.SplittableSchemaRecordChunk, cursor []byte, offset int64) bool {
+		batchChunks = append(batchChunks, chunk)
+		batchLength += chunk.Length()
+
+		// The batch also ends when the next chunk could exceed its max-size
+		if batchChunker.Next(chunk.Hash()) || batchLength+recordChunker.MaxSize() > int(batchSize) {
+			batchChunker.Reset()
+			ok := saveBatch(batchChunks, cursor, offset)
+			batchChunks = []entities.SplittableSchemaRecordChunk{}
+			batchLength = 0
+			return ok
+		}
+		return true
+	}
+
+	cursor, err := dbReader.DecodeChunkCursor(encodedCursor)
+	if err != nil {
+		uc.logger.Errorf("could not decode the record cursor of %s schema: %v", schema.GetName(), err)
+		return false
+	}
+
+	var pendingChunk entities.SplittableSchemaRecordChunk
+	for {
+		// Reads record chunk from DB, encoding its cursor before, as some readers move the same cursor to the next chunk
+		encodedCursor = dbReader.EncodeChunkCursor(cursor)
+		chunk, nextCursor, err := dbReader.GetSchemaRecordChunk(schema, chunkSize, cursor)
+		if err != nil {
+			uc.logger.Errorf("could not retrieve record chunk from %s schema: %v", schema.GetName(), err)
+			return false
+		}
+		if chunk.Length() == 0 {
+			break
+		}
+
+		splittableChunk, ok := chunk.(entities.SplittableSchemaRecordChunk)
+		if !ok {
+			uc.logger.Errorf("could not split record chunk from %s schema: %v", schema.GetName(), services.ErrRecordNotSupported)
+			return false
+		}
+		if pendingChunk == nil {
+			pendingChunk = splittableChunk.Slice(0, 0)
+		}
+
+		// Ends a chunk after every record where the rolling hash finds a boundary, skipping the records already saved
+		start := min(int(offset), splittableChunk.Length())
+		offset = 0
+		recordHashes := splittableChunk.RecordHashes()
+		for i := start; i < len(recordHashes); i++ {
+			if recordChunker.Next(recordHashes[i]) {
+				if ok := addChunk(pendingChunk.Append(splittableChunk.Slice(start, i+1)), encodedCursor, int64(i+1)); !ok {
+					return false
+				}
+				pendingChunk = splittableChunk.Slice(0, 0)
+				start = i + 1
+			}
+		}
+		pendingChunk = pendingChunk.Append(splittableChunk.Slice(start, splittableChunk.Length()))
+
+		cursor = nextCursor
+		progress.Add(1)
+	}
+
+	if pendingChunk != nil && pendingChunk.Length() > 0 {
+		if ok := addChunk(pendingChunk, encodedCursor, 0); !ok {
+			return false
+		}
+	}
+	if len(batchChunks) > 0 {
+		return saveBatch(batchChunks, encodedCursor, 0)
+	}
+	return true
+}
+
+// saveContentDefinedProgress saves the checkpoint of the content-defined batches saved so far, so the snapshot can be
+// resumed from the record at offset in the DB chunk read from the cursor if it is interrupted.
+func (uc *BackupUsecasesImpl) saveContentDefinedProgress(schema entities.Schema, schemaData entities.BackupSnapshotSchemaData, cursor []byte, offset int64) bool {
+	backupWriter := uc.backupFactory.CreateWriter()
+
+	schemaData.Data = slices.Clone(schemaData.Data)
+	if err := backupWriter.SaveSnapshotProgress(schema.GetName(), &entities.BackupSchemaProgress{
+		SchemaHash:   schema.Hash(),
+		Data:         &schemaData,
+		Cursor:       cursor,
+		CursorOffset: offset,
+	}); err != nil {
+		uc.logger.Errorf("could not save the checkpoint of %s schema records: %v", schema.GetName(), err)
+		return false
+	}
+	return true
+}
+
+// saveContentDefinedBatch saves the chunks into a new batch, returning its hash.
+func (uc *BackupUsecasesImpl) saveContentDefinedBatch(chunks []entities.SplittableSchemaRecordChunk) (string, bool) {
+	backupWriter := uc.backupFactory.CreateWriter()
+
+	tempBatchName := uuid.NewString()
+	batchHashBytes := sha256.New()
+	for _, chunk := range chunks {
+		// Saves chunks in backup temporal batch file
+		chunkHash := chunk.Hash()
+		if err := backupWriter.SaveSchemaRecordChunk(tempBatchName, chunk); err != nil {
+			uc.logger.Errorf("could not save record chunk in backup: %v", err)
+			return "", false
+		}
+
+		batchHashBytes.Write([]byte(chunkHash))
+		batchHashBytes.Write([]byte("|"))
+	}
+
+	// After full batch is completed, renames the temp batch file to the final one
+	batchHash := hex.EncodeToString(batchHashBytes.Sum(nil))
+	if err := backupWriter.SaveSchemaRecordBatch(tempBatchName, batchHash); err != nil {
+		uc.logger.Errorf("could not save record batch in backup: %v", err)
+		return "", false
+	}
+	return batchHash, true
+}
+
+// isContentDefinedChunk returns whether the records of the chunk schema are split with content-defined chunks,
+// which are used for the schemas whose records cannot be matched by key.
+func isContentDefinedChunk(chunk entities.SchemaRecordChunk) bool {
+	if keyChunk, ok := chunk.(entities.KeyedSchemaRecordChunk); ok && keyChunk.IsKeyed() {
+		return false
+	}
+	_, ok := chunk.(entities.SplittableSchemaRecordChunk)
+	return ok
+}
+
+func hasRepeatedChunks(chunkRefs []string) bool {
+	seen := make(map[string]bool, len(chunkRefs))
+	for _, chunkRef := range chunkRefs {
+		if seen[chunkRef] {
+			return true
+		}
+		seen[chunkRef] = true
+	}
+	return false
+}
+
+func (uc *BackupUsecasesImpl) CheckpointSchemaRecords(snapshot *entities.BackupSnapshot, schema entities.Schema) bool {
+	backupWriter := uc.backupFactory.CreateWriter()
+
+	progress := entities.BackupSchemaProgress{SchemaHash: schema.Hash(), Complete: true}
+	if schemaData, ok := snapshot.Data[schema.GetName()]; ok {
+		progress.Data = &schemaData
+	}
+	if err := backupWriter.SaveSnapshotProgress(schema.GetName(), &progress); err != nil {
+		uc.logger.Errorf("could not save the checkpoint of %s schema records: %v", schema.GetName(), err)
+		return false
+	}
+
+	return true
+}
+
 func (uc *BackupUsecasesImpl) BackupRoutines(snapshot *entities.BackupSnapshot) bool {
 	dbReader := uc.dbFactory.CreateReader()
 	backupWriter := uc.backupFactory.CreateWriter()
@@ -619,16 +1543,18 @@ func (uc *BackupUsecasesImpl) SnapshotRoutines(lastSnapshot, snapshot *entities.
 		hash := routine.Hash()
 
 		// If no condition it stays unmodified
-		prevHash, ok := lastSnapshot.Routines[routine.GetName()]
+		prevRef, ok := lastSnapshot.Routines[routine.GetName()]
 		if !ok {
 			// New routine to write into backup
 			if err := backupWriter.SaveRoutine(routine); err != nil {
 				uc.logger.Errorf("could not save %s routine into backup: %v", routine.GetName(), err)
 				return false
 			}
-		} else if !crypto.CompareHashes(prevHash, hash) {
+
+			snapshot.Routines[routine.GetName()] = hash
+		} else if prevHash, _ := strings.CutPrefix(prevRef, "diffs/"); !crypto.CompareHashes(prevHash, hash) {
 			// Updates routine into backup
-			prevRoutine, isDiff, err := backupReader.GetRoutine(prevHash)
+			prevRoutine, isDiff, err := backupReader.GetRoutine(prevRef)
 			if err != nil {
 				if errors.Is(err, services.ErrBackupCorruptedFile) {
 					fmt.Printf("The %s routine in backup is corrupted\n", routine.GetName())
@@ -646,7 +1572,7 @@ func (uc *BackupUsecasesImpl) SnapshotRoutines(lastSnapshot, snapshot *entities.
 
 			snapshot.Routines[routine.GetName()] = fmt.Sprintf("diffs/%s", hash)
 		} else {
-			snapshot.Schemas[routine.GetName()] = prevHash
+			snapshot.Routines[routine.GetName()] = prevRef
 		}
 
 		routineProgress.Add(1)
@@ -655,3 +1581,20 @@ func (uc *BackupUsecasesImpl) SnapshotRoutines(lastSnapshot, snapshot *entities.
 	fmt.Println("  - All routines updated successfully")
 	return true
 }
+
+// newRecordsProgressBar creates the progress bar of the schema records. Workers run next to other workers,
+// so their progress bars are not rendered and only the schemas they finish are printed.
+func (uc *BackupUsecasesImpl) newRecordsProgressBar(max int, description string) *progressbar.ProgressBar {
+	if uc.isWorker {
+		return progressbar.NewOptions(max, progressbar.OptionSetWriter(io.Discard))
+	}
+	return progressbar.NewOptions(max, progressbar.OptionSetDescription(description), progressbar.OptionSetWidth(30), progressbar.OptionSetWriter(os.Stdout), progressbar.OptionSetRenderBlankState(true))
+}
+
+func (uc *BackupUsecasesImpl) printRecordsDone(schema entities.Schema, action string) {
+	if uc.isWorker {
+		fmt.Printf("  - %s schema records %s successfully\n", schema.GetName(), action)
+	} else {
+		fmt.Printf("  - All schema records %s successfully\n", action)
+	}
+}
@@ -4,3 +4,15 @@ type BatchChunkInfo struct {
 	Hash   string
 	Cursor interface{}
 }
+
+// ChunkLocation is the position of a chunk in the batches of a schema snapshot.
+type ChunkLocation struct {
+	Batch int
+	Chunk int
+}
+
+// RecordLocation is the chunk that contains a record in a schema snapshot, and the hash of the record content.
+type RecordLocation struct {
+	ChunkLocation
+	Hash string
+}
@@ -0,0 +1,5 @@
+package usecases
+
+type GcUsecases interface {
+	CollectGarbage(dryRun bool) bool
+}
@@ -0,0 +1,80 @@
+package usecases
+
+import (
+	"errors"
+	"fmt"
+	"historydb/src/internal/services"
+	backup_services "historydb/src/internal/services/backup"
+	"io/fs"
+
+	"github.com/sirupsen/logrus"
+)
+
+type GcUsecasesImpl struct {
+	backupFactory backup_services.BackupFactory
+	logger        *logrus.Logger
+}
+
+func NewGcUsecasesImpl(backupFactory backup_services.BackupFactory, logger *logrus.Logger) *GcUsecasesImpl {
+	return &GcUsecasesImpl{backupFactory, logger}
+}
+
+func (uc *GcUsecasesImpl) CollectGarbage(dryRun bool) bool {
+	backupReader := uc.backupFactory.CreateReader()
+	backupWriter := uc.backupFactory.CreateWriter()
+
+	if ok := backupReader.CheckBackupExists(); !ok {
+		fmt.Println("The specified backup path does not exist.")
+		return false
+	}
+
+	sweep, err := backupWriter.CollectGarbage(dryRun)
+	if err != nil {
+		if errors.Is(err, services.ErrBackupKeyRequired) {
+			fmt.Println("The specified backup is encrypted. Its passphrase must be provided with --keyFile or HISTORYDB_PASSPHRASE.")
+		} else if errors.Is(err, services.ErrBackupKeyInvalid) {
+			fmt.Println("The provided passphrase does not open the specified backup.")
+		} else if errors.Is(err, services.ErrBackupCorruptedFile) || errors.Is(err, services.ErrBackupChunkNotFound) || errors.Is(err, fs.ErrNotExist) {
+			fmt.Println("The unreferenced files could not be collected, as the backup is damaged. It can be checked with historydb verify.")
+		} else {
+			fmt.Println("The unreferenced files could not be collected")
+		}
+
+		uc.logger.Errorf("could not collect the unreferenced files of the backup: %v", err)
+		return false
+	}
+
+	if len(sweep.DeletedFiles) == 0 {
+		fmt.Println("Every file of the backup is referenced by its snapshots.")
+		return true
+	}
+	if dryRun {
+		fmt.Printf("%d files are not referenced by any snapshot:\n", len(sweep.DeletedFiles))
+		for _, name := range sweep.DeletedFiles {
+			fmt.Printf("  - %s\n", name)
+		}
+		fmt.Printf("Collecting them would reclaim %s.\n", formatBytes(sweep.ReclaimedBytes))
+		return true
+	}
+
+	for _, name := range sweep.DeletedFiles {
+		uc.logger.Infof("deleted unreferenced backup file %s", name)
+	}
+	fmt.Printf("Unreferenced files collected successfully! %d files were deleted, reclaiming %s.\n", len(sweep.DeletedFiles), formatBytes(sweep.ReclaimedBytes))
+	return true
+}
+
+// formatBytes formats a number of bytes with the largest binary unit it reaches.
+func formatBytes(bytes int64) string {
+	const unit = 1024
+	if bytes < unit && bytes > -unit {
+		return fmt.Sprintf("%d B", bytes)
+	}
+
+	value := float64(bytes)
+	prefix := -1
+	for ; (value >= unit || value <= -unit) && prefix < 4; prefix++ {
+		value /= unit
+	}
+	return fmt.Sprintf("%.1f %ciB", value, "KMGTP"[prefix])
+}
@@ -0,0 +1,5 @@
+package usecases
+
+type KeyUsecases interface {
+	RotateKey(newPassphrase []byte) bool
+}
@@ -0,0 +1,48 @@
+package usecases
+
+import (
+	"errors"
+	"fmt"
+	"historydb/src/internal/services"
+	backup_services "historydb/src/internal/services/backup"
+
+	"github.com/sirupsen/logrus"
+)
+
+type KeyUsecasesImpl struct {
+	backupFactory backup_services.BackupFactory
+	logger        *logrus.Logger
+}
+
+func NewKeyUsecasesImpl(backupFactory backup_services.BackupFactory, logger *logrus.Logger) *KeyUsecasesImpl {
+	return &KeyUsecasesImpl{backupFactory, logger}
+}
+
+func (uc *KeyUsecasesImpl) RotateKey(newPassphrase []byte) bool {
+	backupReader := uc.backupFactory.CreateReader()
+	backupWriter := uc.backupFactory.CreateWriter()
+
+	if ok := backupReader.CheckBackupExists(); !ok {
+		fmt.Println("The specified backup path does not exist.")
+		return false
+	}
+
+	if err := backupWriter.RotateBackupKey(newPassphrase); err != nil {
+		if errors.Is(err, services.ErrBackupNotEncrypted) {
+			fmt.Println("The specified backup is not encrypted.")
+		} else if errors.Is(err, services.ErrBackupKeyRequired) {
+			fmt.Println("The current passphrase of the backup must be provided with --keyFile or HISTORYDB_PASSPHRASE.")
+		} else if errors.Is(err, services.ErrBackupKeyInvalid) {
+			fmt.Println("The provided passphrase does not open the specified backup.")
+		} else if errors.Is(err, services.ErrBackupCorruptedFile) {
+			fmt.Println("The specified backup is corrupted.")
+		}
+
+		uc.logger.Errorf("could not rotate backup key: %v", err)
+		return false
+	}
+
+	fmt.Println("Backup key rotated successfully!")
+	uc.logger.Infof("Rotated the passphrase of the backup key")
+	return true
+}
@@ -33,6 +33,10 @@ func (uc *LogUsecasesImpl) ListSnapshots() {
 	if err != nil {
 		if errors.Is(err, services.ErrBackupCorruptedFile) {
 			fmt.Println("The specified backup is corrupted.")
+		} else if errors.Is(err, services.ErrBackupKeyRequired) {
+			fmt.Println("The specified backup is encrypted. Its passphrase must be provided with --keyFile or HISTORYDB_PASSPHRASE.")
+		} else if errors.Is(err, services.ErrBackupKeyInvalid) {
+			fmt.Println("The provided passphrase does not open the specified backup.")
 		}
 
 		uc.logger.Errorf("could not retrieve backup metadata: %v", err)
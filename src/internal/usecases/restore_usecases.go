@@ -4,24 +4,44 @@ import "historydb/src/internal/entities"
 
 // RestoreUsecases is the interface that defines all the functionality to restore a database from a backup.
 //
-// GetBackupSnapshot() -> Retrieves the backup metadata if it exists.
+// GetRestoreCheckpoint() -> Retrieves the progress of the interrupted restore of the DB, so it can be resumed.
+// GetBackupSnapshot() -> Retrieves the backup metadata if it exists. When a restore is resumed, it retrieves the snapshot of its checkpoint.
 // BeginDatabaseRestore() -> Begins a database transaction to fully restores the DB.
 // CommitDatabaseRestore() -> Commits the database transaction ending successfully the restore process.
-// RollbackDatabaseRestore() -> Rollbacks the database transaction ending abruptly the restore process.
+// RollbackDatabaseRestore() -> Rollbacks the database transaction ending abruptly the restore process. Once checkpointed, it also drops the restored objects.
+// CheckpointDatabaseRestore() -> Commits the objects restored so far, so other DB connections can use them, and begins a new database transaction.
+// CommitRestoreStep() -> Commits a step of a restore committed in several transactions, keeping the restored objects so the restore can be resumed if a later step fails.
+// CreateRestoreCheckpoint() -> Creates the checkpoint of a restore committed in several transactions into the DB.
+// DropRestoreCheckpoint() -> Drops the checkpoint of the restore from the DB, once the restore is completed.
+// CreateWorker() -> Creates usecases with their own DB connection to restore schemas next to other workers. Returns nil if the DB engine cannot write from several connections.
 // RestoreSchemaDependencies() -> Restore the schema dependencies into the DB from the backup.
+// GetSnapshotSchemas() -> Retrieves the schemas of the snapshot from the backup, without restoring them.
 // RestoreSchemas() -> Restore the schemas into the DB from the backup.
-// RestoreSchemaRules() -> Restore the schema rules and constraints into the DB from the backup.
+// RestoreSchemaRules() -> Restore the schema indexes and rules into the DB from the backup.
+// RestoreSchemaConstraints() -> Restore the schema foreign keys into the DB from the backup, without checking the records when the DB allows it.
+// ValidateSchemaConstraints() -> Checks the restored records against the schema foreign keys.
 // RestoreSchemaRecords() -> Restore the schema data records into the DB from the backup.
 // RestoreRoutines() -> Restore the routines into the DB from the backup.
+//
+// The restore steps given a checkpoint skip what it lists as restored, and save their progress into it.
 type RestoreUsecases interface {
-	GetBackupSnapshot(snapshotId *string) *entities.BackupSnapshot
+	GetRestoreCheckpoint() *entities.RestoreCheckpoint
+	GetBackupSnapshot(snapshotId *string, checkpoint *entities.RestoreCheckpoint) *entities.BackupSnapshot
 	BeginDatabaseRestore() bool
 	CommitDatabaseRestore() bool
 	RollbackDatabaseRestore()
+	CheckpointDatabaseRestore() bool
+	CommitRestoreStep() bool
+	CreateRestoreCheckpoint(checkpoint *entities.RestoreCheckpoint) bool
+	DropRestoreCheckpoint() bool
+	CreateWorker() RestoreUsecases
 
 	RestoreSchemaDependencies(snapshot *entities.BackupSnapshot) bool
-	RestoreSchemas(snapshot *entities.BackupSnapshot) []entities.Schema
-	RestoreSchemaRules(snapshot *entities.BackupSnapshot, schemas []entities.Schema) bool
-	RestoreSchemaRecords(snapshot *entities.BackupSnapshot, schema entities.Schema) bool
+	GetSnapshotSchemas(snapshot *entities.BackupSnapshot) []entities.Schema
+	RestoreSchemas(snapshot *entities.BackupSnapshot, checkpoint *entities.RestoreCheckpoint) []entities.Schema
+	RestoreSchemaRules(snapshot *entities.BackupSnapshot, schemas []entities.Schema, checkpoint *entities.RestoreCheckpoint) bool
+	RestoreSchemaConstraints(snapshot *entities.BackupSnapshot, schemas []entities.Schema, checkpoint *entities.RestoreCheckpoint) bool
+	ValidateSchemaConstraints(snapshot *entities.BackupSnapshot, schemas []entities.Schema) bool
+	RestoreSchemaRecords(snapshot *entities.BackupSnapshot, schema entities.Schema, checkpoint *entities.RestoreCheckpoint) bool
 	RestoreRoutines(snapshot *entities.BackupSnapshot) bool
 }
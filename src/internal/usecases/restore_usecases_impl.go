@@ -8,7 +8,9 @@ import (
 	backup_services "historydb/src/internal/services/backup"
 	database_services "historydb/src/internal/services/database"
 	"historydb/src/internal/utils/types"
+	"io"
 	"os"
+	"strconv"
 	"time"
 
 	"github.com/schollz/progressbar/v3"
@@ -19,22 +21,81 @@ type RestoreUsecasesImpl struct {
 	dbFactory     database_services.DatabaseFactory
 	backupFactory backup_services.BackupFactory
 	logger        *logrus.Logger
+	isWorker      bool
+	checkpointed  bool
+	resumable     bool
 }
 
 func NewRestoreUsecasesImpl(dbFactory database_services.DatabaseFactory, backupFactory backup_services.BackupFactory, logger *logrus.Logger) *RestoreUsecasesImpl {
-	return &RestoreUsecasesImpl{dbFactory, backupFactory, logger}
+	return &RestoreUsecasesImpl{dbFactory, backupFactory, logger, false, false, false}
 }
 
-func (uc *RestoreUsecasesImpl) GetBackupSnapshot(snapshotId *string) *entities.BackupSnapshot {
+// restoreWorkerDatabaseFactory is the DatabaseFactory of a restore worker, which creates the parallel writer of the worker instead of the shared one.
+type restoreWorkerDatabaseFactory struct {
+	database_services.DatabaseFactory
+	dbWriter database_services.DatabaseWriter
+}
+
+func (factory *restoreWorkerDatabaseFactory) CreateWriter() database_services.DatabaseWriter {
+	return factory.dbWriter
+}
+
+func (uc *RestoreUsecasesImpl) CreateWorker() RestoreUsecases {
+	dbWriter, err := uc.dbFactory.CreateParallelWriter()
+	if err != nil {
+		uc.logger.Warnf("could not create a parallel writer of the DB: %v", err)
+		return nil
+	}
+
+	return &RestoreUsecasesImpl{&restoreWorkerDatabaseFactory{uc.dbFactory, dbWriter}, uc.backupFactory, uc.logger, true, false, false}
+}
+
+func (uc *RestoreUsecasesImpl) GetRestoreCheckpoint() *entities.RestoreCheckpoint {
+	dbWriter := uc.dbFactory.CreateWriter()
+
+	entries, err := dbWriter.GetRestoreCheckpoint()
+	if err != nil {
+		if errors.Is(err, services.ErrRestoreCheckpointNotSupported) {
+			fmt.Println("The restores of this database engine are committed at once, so they cannot be resumed.")
+		}
+
+		uc.logger.Errorf("could not retrieve restore checkpoint from DB: %v", err)
+		return nil
+	} else if entries == nil {
+		fmt.Println("The database does not contain an interrupted restore to resume.")
+		return nil
+	}
+
+	var checkpoint entities.RestoreCheckpoint
+	if err := checkpoint.DecodeFromEntries(entries); err != nil {
+		fmt.Println("The progress of the interrupted restore is corrupted, so it cannot be resumed.")
+		uc.logger.Errorf("could not decode restore checkpoint: %v", err)
+		return nil
+	}
+
+	fmt.Printf("Resuming the restore of snapshot %s, committed by %s.\n", checkpoint.SnapshotId, checkpoint.CommitMode)
+	uc.logger.Infof("resuming the restore of snapshot %s", checkpoint.SnapshotId)
+	// The steps committed before the restore was interrupted are kept if it fails again
+	uc.resumable = true
+	return &checkpoint
+}
+
+func (uc *RestoreUsecasesImpl) GetBackupSnapshot(snapshotId *string, checkpoint *entities.RestoreCheckpoint) *entities.BackupSnapshot {
 	backupReader := uc.backupFactory.CreateReader()
 	dbReader := uc.dbFactory.CreateReader()
+	dbWriter := uc.dbFactory.CreateWriter()
 
-	// Checking if DB is empty to dump all the backup content
-	if isEmpty, err := dbReader.CheckDBIsEmpty(); err != nil {
+	// Checking if DB is empty to dump all the backup content, unless an interrupted restore is resumed into it
+	if checkpoint != nil {
+		snapshotId = &checkpoint.SnapshotId
+	} else if isEmpty, err := dbReader.CheckDBIsEmpty(); err != nil {
 		uc.logger.Errorf("could not check if database is empty: %v", err)
 		return nil
 	} else if !isEmpty {
 		fmt.Println("To restore the database it is required an empty database")
+		if entries, err := dbWriter.GetRestoreCheckpoint(); err == nil && entries != nil {
+			fmt.Println("The database contains an interrupted restore, which can be resumed with --resume")
+		}
 		return nil
 	}
 
@@ -46,7 +107,13 @@ func (uc *RestoreUsecasesImpl) GetBackupSnapshot(snapshotId *string) *entities.B
 
 	backupMetadata, err := backupReader.GetBackupMetadata()
 	if err != nil {
-		fmt.Println("The specified path does not seem to contain a backup")
+		if errors.Is(err, services.ErrBackupKeyRequired) {
+			fmt.Println("The specified backup is encrypted. Its passphrase must be provided with --keyFile or HISTORYDB_PASSPHRASE.")
+		} else if errors.Is(err, services.ErrBackupKeyInvalid) {
+			fmt.Println("The provided passphrase does not open the specified backup.")
+		} else {
+			fmt.Println("The specified path does not seem to contain a backup")
+		}
 		uc.logger.Errorf("could not retrieve backup: %v", err)
 		return nil
 	}
@@ -101,7 +168,9 @@ func (uc *RestoreUsecasesImpl) BeginDatabaseRestore() bool {
 		uc.logger.Errorf("could not begin DB transaction: %v", err)
 		return false
 	}
-	uc.logger.Info("started database restoring")
+	if !uc.isWorker && !uc.resumable {
+		uc.logger.Info("started database restoring")
+	}
 	return true
 }
 
@@ -112,20 +181,107 @@ func (uc *RestoreUsecasesImpl) CommitDatabaseRestore() bool {
 		uc.logger.Errorf("could not commit restored DB: %v", err)
 		return false
 	}
-	uc.logger.Info("finished database restoring successfully")
+	if !uc.isWorker {
+		uc.logger.Info("finished database restoring successfully")
+	}
+	return true
+}
+
+func (uc *RestoreUsecasesImpl) CheckpointDatabaseRestore() bool {
+	dbWriter := uc.dbFactory.CreateWriter()
+
+	if err := dbWriter.CommitTransaction(); err != nil {
+		uc.logger.Errorf("could not commit restored DB objects: %v", err)
+		return false
+	}
+	// Once committed, the rollback has to drop the restored objects
+	uc.checkpointed = true
+
+	if err := dbWriter.BeginTransaction(); err != nil {
+		uc.logger.Errorf("could not begin DB transaction: %v", err)
+		return false
+	}
+	return true
+}
+
+func (uc *RestoreUsecasesImpl) CommitRestoreStep() bool {
+	dbWriter := uc.dbFactory.CreateWriter()
+
+	if err := dbWriter.CommitTransaction(); err != nil {
+		uc.logger.Errorf("could not commit restore step: %v", err)
+		return false
+	}
+	// Once a step is committed the restore can be resumed, so the restored objects are kept if a later step fails
+	dbWriter.KeepCreatedObjects()
+	uc.resumable = true
+	return true
+}
+
+func (uc *RestoreUsecasesImpl) CreateRestoreCheckpoint(checkpoint *entities.RestoreCheckpoint) bool {
+	dbWriter := uc.dbFactory.CreateWriter()
+
+	if err := dbWriter.CreateRestoreCheckpoint(); err != nil {
+		if errors.Is(err, services.ErrRestoreCheckpointNotSupported) {
+			fmt.Println("The restores of this database engine can only be committed at once, with --commit all.")
+		}
+
+		uc.logger.Errorf("could not create restore checkpoint in DB: %v", err)
+		return false
+	}
+
+	return uc.saveRestoreCheckpoint(entities.RestoreSnapshotEntry, checkpoint.SnapshotId) &&
+		uc.saveRestoreCheckpoint(entities.RestoreCommitEntry, string(checkpoint.CommitMode))
+}
+
+func (uc *RestoreUsecasesImpl) DropRestoreCheckpoint() bool {
+	dbWriter := uc.dbFactory.CreateWriter()
+
+	if err := dbWriter.DropRestoreCheckpoint(); err != nil {
+		uc.logger.Errorf("could not drop restore checkpoint from DB: %v", err)
+		return false
+	}
 	return true
 }
 
 func (uc *RestoreUsecasesImpl) RollbackDatabaseRestore() {
 	dbWriter := uc.dbFactory.CreateWriter()
+	if uc.isWorker {
+		if err := dbWriter.RollbackTransaction(); err != nil {
+			uc.logger.Errorf("could not rollback worker DB transaction: %v", err)
+		}
+		return
+	}
+
 	fmt.Println("Process failed. Aborting operation...")
+	if uc.resumable {
+		fmt.Println("  - Rollback DB to the last step restored...")
+		uc.logger.Error("failed database restoring, keeping the steps restored to resume it")
+
+		// The failure may happen between steps, when no transaction is in progress
+		if err := dbWriter.RollbackTransaction(); err != nil && !errors.Is(err, services.ErrDatabaseTransactionNotFound) {
+			uc.logger.Errorf("could not rollback DB to the last step restored: %v", err)
+			return
+		}
+
+		fmt.Println("  + The restore can be resumed with --resume")
+		fmt.Println("Closing app...")
+		return
+	}
+
 	fmt.Println("  - Rollback DB to previous state...")
 	uc.logger.Error("failed database restoring")
 
-	if err := dbWriter.RollbackTransaction(); err != nil {
+	// The transaction is not found if the restore failed while being checkpointed, so the committed objects are dropped anyway
+	if err := dbWriter.RollbackTransaction(); err != nil && !(uc.checkpointed && errors.Is(err, services.ErrDatabaseTransactionNotFound)) {
 		uc.logger.Errorf("could not rollback DB to previous state: %v", err)
 		return
 	}
+	if uc.checkpointed {
+		if err := dbWriter.DropCreatedObjects(); err != nil {
+			uc.logger.Errorf("could not drop restored DB objects: %v", err)
+			return
+		}
+	}
 
 	fmt.Println("  + Rollback completed!")
 	fmt.Println("Closing app...")
@@ -159,7 +315,28 @@ func (uc *RestoreUsecasesImpl) RestoreSchemaDependencies(snapshot *entities.Back
 	return true
 }
 
-func (uc *RestoreUsecasesImpl) RestoreSchemas(snapshot *entities.BackupSnapshot) []entities.Schema {
+func (uc *RestoreUsecasesImpl) GetSnapshotSchemas(snapshot *entities.BackupSnapshot) []entities.Schema {
+	backupReader := uc.backupFactory.CreateReader()
+
+	schemas := make([]entities.Schema, 0, len(snapshot.Schemas))
+	for schemaName, snapshotSchema := range snapshot.Schemas {
+		schema, _, err := backupReader.GetSchema(snapshotSchema)
+		if err != nil {
+			if errors.Is(err, services.ErrBackupCorruptedFile) {
+				fmt.Printf("The %s schema in backup is corrupted\n", schemaName)
+			}
+
+			uc.logger.Errorf("could not read %s schema from backup: %v", schemaName, err)
+			return nil
+		}
+
+		schemas = append(schemas, schema)
+	}
+
+	return schemas
+}
+
+func (uc *RestoreUsecasesImpl) RestoreSchemas(snapshot *entities.BackupSnapshot, checkpoint *entities.RestoreCheckpoint) []entities.Schema {
 	backupReader := uc.backupFactory.CreateReader()
 	dbWriter := uc.dbFactory.CreateWriter()
 
@@ -184,27 +361,74 @@ func (uc *RestoreUsecasesImpl) RestoreSchemas(snapshot *entities.BackupSnapshot)
 		schemas = append(schemas, schema)
 		schemaProgress.Add(1)
 	}
+	if checkpoint != nil && !uc.saveRestoreCheckpoint(entities.RestoreSchemasEntry, "") {
+		return nil
+	}
 	fmt.Println("  - All schemas restored successfully")
 
 	return schemas
 }
 
-func (uc *RestoreUsecasesImpl) RestoreSchemaRules(snapshot *entities.BackupSnapshot, schemas []entities.Schema) bool {
+func (uc *RestoreUsecasesImpl) RestoreSchemaRules(snapshot *entities.BackupSnapshot, schemas []entities.Schema, checkpoint *entities.RestoreCheckpoint) bool {
 	dbWriter := uc.dbFactory.CreateWriter()
 
-	fmt.Println("  + Restoring schema rules...")
+	uc.printStepStarted("  + Restoring schema rules...")
 	for _, schema := range schemas {
+		if checkpoint != nil && checkpoint.RestoredRules[schema.GetName()] {
+			continue
+		}
+
 		if err := dbWriter.SaveSchemaRules(schema); err != nil {
 			uc.logger.Errorf("could not restore %s schema rules: %v", schema.GetName(), err)
 			return false
 		}
+		if checkpoint != nil && !uc.saveRestoreCheckpoint(entities.RestoreRulesEntry(schema.GetName()), "") {
+			return false
+		}
+	}
+
+	uc.printStepDone(schemas, "rules restored")
+	return true
+}
+
+func (uc *RestoreUsecasesImpl) RestoreSchemaConstraints(snapshot *entities.BackupSnapshot, schemas []entities.Schema, checkpoint *entities.RestoreCheckpoint) bool {
+	dbWriter := uc.dbFactory.CreateWriter()
+
+	if checkpoint != nil && checkpoint.ConstraintsRestored {
+		return true
+	}
+
+	uc.printStepStarted("  + Restoring schema constraints...")
+	for _, schema := range schemas {
+		if err := dbWriter.SaveSchemaConstraints(schema); err != nil {
+			uc.logger.Errorf("could not restore %s schema constraints: %v", schema.GetName(), err)
+			return false
+		}
+	}
+	if checkpoint != nil && !uc.saveRestoreCheckpoint(entities.RestoreConstraintsEntry, "") {
+		return false
 	}
 
-	fmt.Println("  - All schema rules restored successfully")
+	uc.printStepDone(schemas, "constraints restored")
 	return true
 }
 
-func (uc *RestoreUsecasesImpl) RestoreSchemaRecords(snapshot *entities.BackupSnapshot, schema entities.Schema) bool {
+func (uc *RestoreUsecasesImpl) ValidateSchemaConstraints(snapshot *entities.BackupSnapshot, schemas []entities.Schema) bool {
+	dbWriter := uc.dbFactory.CreateWriter()
+
+	uc.printStepStarted("  + Validating schema constraints...")
+	for _, schema := range schemas {
+		if err := dbWriter.ValidateSchemaConstraints(schema); err != nil {
+			uc.logger.Errorf("could not validate %s schema constraints: %v", schema.GetName(), err)
+			return false
+		}
+	}
+
+	uc.printStepDone(schemas, "constraints validated")
+	return true
+}
+
+func (uc *RestoreUsecasesImpl) RestoreSchemaRecords(snapshot *entities.BackupSnapshot, schema entities.Schema, checkpoint *entities.RestoreCheckpoint) bool {
 	backupReader := uc.backupFactory.CreateReader()
 	dbWriter := uc.dbFactory.CreateWriter()
 
@@ -214,14 +438,34 @@ func (uc *RestoreUsecasesImpl) RestoreSchemaRecords(snapshot *entities.BackupSna
 		return false
 	}
 
+	// The batches restored by an interrupted restore are skipped
+	var restoredBatches int64
+	if checkpoint != nil {
+		batches, ok := checkpoint.RestoredBatches[schema.GetName()]
+		if ok && batches >= int64(len(backupMetadata.Data)) {
+			fmt.Printf("  + The records of %s were already restored\n", schema.GetName())
+			return true
+		}
+		restoredBatches = batches
+	}
+
 	if len(backupMetadata.Data) == 0 {
 		fmt.Printf("  + There are no records to restore for %s\n", schema.GetName())
-		return true
+		return checkpoint == nil || uc.saveRestoreCheckpoint(entities.RestoreRecordsEntry(schema.GetName()), "0")
 	}
 
-	batchProgress := progressbar.NewOptions(len(backupMetadata.Data), progressbar.OptionSetDescription(fmt.Sprintf("  + Restoring all %d batches for %s schema...", len(backupMetadata.Data), schema.GetName())), progressbar.OptionSetWidth(30), progressbar.OptionSetWriter(os.Stdout), progressbar.OptionSetRenderBlankState(true))
+	switch backupMetadata.Chunking {
+	case entities.FixedSizeChunking, entities.ContentDefinedChunking:
+		// Both modes save the chunks of every batch in the records order, so the chunks are restored one after another
+	default:
+		uc.logger.Errorf("%s schema records were saved with the unsupported %s chunking mode", schema.GetName(), backupMetadata.Chunking)
+		return false
+	}
+
+	batchProgress := uc.newBatchProgressBar(len(backupMetadata.Data), fmt.Sprintf("  + Restoring all %d batches for %s schema...", len(backupMetadata.Data), schema.GetName()))
+	batchProgress.Add(int(restoredBatches))
 	// Loops over every schema batch
-	for _, batch := range backupMetadata.Data {
+	for i, batch := range backupMetadata.Data[restoredBatches:] {
 		// Retrieves chunk references in the batch
 		chunkRefs, err := backupReader.GetSchemaRecordChunkRefsInBatch(batch)
 		if err != nil {
@@ -244,10 +488,29 @@ func (uc *RestoreUsecasesImpl) RestoreSchemaRecords(snapshot *entities.BackupSna
 				return false
 			}
 		}
+
+		// Commits the batch along with its checkpoint, so it is not restored again if the restore is resumed
+		if checkpoint != nil && checkpoint.CommitMode == entities.RestoreCommitBatch {
+			if ok := uc.saveRestoreCheckpoint(entities.RestoreRecordsEntry(schema.GetName()), strconv.FormatInt(restoredBatches+int64(i)+1, 10)); !ok {
+				return false
+			}
+			if ok := uc.commitRestoredBatch(); !ok {
+				return false
+			}
+		}
 		batchProgress.Add(1)
 	}
+	if checkpoint != nil && checkpoint.CommitMode == entities.RestoreCommitTable {
+		if ok := uc.saveRestoreCheckpoint(entities.RestoreRecordsEntry(schema.GetName()), strconv.Itoa(len(backupMetadata.Data))); !ok {
+			return false
+		}
+	}
 
-	fmt.Println("  - All batches restored successfully")
+	if uc.isWorker {
+		fmt.Printf("  - %s schema records restored successfully\n", schema.GetName())
+	} else {
+		fmt.Println("  - All batches restored successfully")
+	}
 	return true
 }
 
@@ -256,19 +519,16 @@ func (uc *RestoreUsecasesImpl) RestoreRoutines(snapshot *entities.BackupSnapshot
 	backupReader := uc.backupFactory.CreateReader()
 	dbWriter := uc.dbFactory.CreateWriter()
 
-	restoredDependencies := []string{}
+	restoredRoutines := []string{}
 	routineProgress := progressbar.NewOptions(len(snapshot.Routines), progressbar.OptionSetDescription(fmt.Sprintf("  + Restoring all %d routines...", len(snapshot.Routines))), progressbar.OptionSetWidth(30), progressbar.OptionSetWriter(os.Stdout), progressbar.OptionSetRenderBlankState(true))
 	for routineName, snapshotRoutine := range snapshot.Routines {
-		if ok := types.SeachInSlice(restoredDependencies, snapshotRoutine); !ok {
-			newRestoredDependencies, ok := uc.restoreSingleRoutine(snapshot, backupReader, dbWriter, routineName, snapshotRoutine)
-			if !ok {
+		if ok := types.SeachInSlice(restoredRoutines, snapshotRoutine); !ok {
+			prevRestored := len(restoredRoutines)
+			if ok := uc.restoreSingleRoutine(snapshot, backupReader, dbWriter, routineName, snapshotRoutine, &restoredRoutines); !ok {
 				return false
 			}
 
-			restoredDependencies = append(restoredDependencies, newRestoredDependencies...)
-			restoredDependencies = append(restoredDependencies, snapshotRoutine)
-
-			routineProgress.Add(len(newRestoredDependencies) + 1)
+			routineProgress.Add(len(restoredRoutines) - prevRestored)
 		}
 	}
 
@@ -276,9 +536,9 @@ func (uc *RestoreUsecasesImpl) RestoreRoutines(snapshot *entities.BackupSnapshot
 	return true
 }
 
-func (uc *RestoreUsecasesImpl) restoreSingleRoutine(snapshot *entities.BackupSnapshot, backupReader backup_services.BackupReader, dbWriter database_services.DatabaseWriter, routineName, routineRef string) ([]string, bool) {
-	restoredDependencies := []string{}
-
+// restoreSingleRoutine restores a routine after its dependencies, adding every restored routine into restoredRoutines
+// so routines shared as dependencies are only restored once.
+func (uc *RestoreUsecasesImpl) restoreSingleRoutine(snapshot *entities.BackupSnapshot, backupReader backup_services.BackupReader, dbWriter database_services.DatabaseWriter, routineName, routineRef string, restoredRoutines *[]string) bool {
 	routine, _, err := backupReader.GetRoutine(routineRef)
 	if err != nil {
 		if errors.Is(err, services.ErrBackupCorruptedFile) {
@@ -286,30 +546,79 @@ func (uc *RestoreUsecasesImpl) restoreSingleRoutine(snapshot *entities.BackupSna
 		}
 
 		uc.logger.Errorf("could not read %s routine from backup: %v", routineName, err)
-		return nil, false
+		return false
 	}
 
 	for _, dependency := range routine.GetDependencies() {
 		snapshotDependency, ok := snapshot.Routines[dependency]
 		if !ok {
 			uc.logger.Errorf("could not find %s routine in backup", dependency)
-			return nil, false
+			return false
 		}
 
-		if ok := types.SeachInSlice(restoredDependencies, snapshotDependency); !ok {
-			newRestoredDependencies, ok := uc.restoreSingleRoutine(snapshot, backupReader, dbWriter, dependency, snapshotDependency)
-			if !ok {
-				return nil, false
+		if ok := types.SeachInSlice(*restoredRoutines, snapshotDependency); !ok {
+			if ok := uc.restoreSingleRoutine(snapshot, backupReader, dbWriter, dependency, snapshotDependency, restoredRoutines); !ok {
+				return false
 			}
-			restoredDependencies = append(restoredDependencies, newRestoredDependencies...)
-			restoredDependencies = append(restoredDependencies, snapshotDependency)
 		}
 	}
 
 	if err := dbWriter.SaveRoutine(routine); err != nil {
 		uc.logger.Errorf("could not restore %s routine: %v", routineName, err)
-		return nil, false
+		return false
+	}
+
+	*restoredRoutines = append(*restoredRoutines, routineRef)
+	return true
+}
+
+// saveRestoreCheckpoint saves an entry of the restore checkpoint into the DB transaction in progress.
+func (uc *RestoreUsecasesImpl) saveRestoreCheckpoint(name, value string) bool {
+	dbWriter := uc.dbFactory.CreateWriter()
+
+	if err := dbWriter.SaveRestoreCheckpoint(name, value); err != nil {
+		uc.logger.Errorf("could not save %s restore checkpoint in DB: %v", name, err)
+		return false
 	}
+	return true
+}
+
+// commitRestoredBatch commits the records restored so far and begins a new transaction for the next batch.
+func (uc *RestoreUsecasesImpl) commitRestoredBatch() bool {
+	dbWriter := uc.dbFactory.CreateWriter()
 
-	return restoredDependencies, true
+	if err := dbWriter.CommitTransaction(); err != nil {
+		uc.logger.Errorf("could not commit restored batch: %v", err)
+		return false
+	}
+	if err := dbWriter.BeginTransaction(); err != nil {
+		uc.logger.Errorf("could not begin DB transaction: %v", err)
+		return false
+	}
+	return true
+}
+
+// Workers restore the schemas next to each other, so instead of sharing the output with progress bars,
+// they only print every schema when it is done.
+func (uc *RestoreUsecasesImpl) newBatchProgressBar(max int, description string) *progressbar.ProgressBar {
+	if uc.isWorker {
+		return progressbar.NewOptions(max, progressbar.OptionSetWriter(io.Discard))
+	}
+	return progressbar.NewOptions(max, progressbar.OptionSetDescription(description), progressbar.OptionSetWidth(30), progressbar.OptionSetWriter(os.Stdout), progressbar.OptionSetRenderBlankState(true))
+}
+
+func (uc *RestoreUsecasesImpl) printStepStarted(message string) {
+	if !uc.isWorker {
+		fmt.Println(message)
+	}
+}
+
+func (uc *RestoreUsecasesImpl) printStepDone(schemas []entities.Schema, action string) {
+	if uc.isWorker {
+		for _, schema := range schemas {
+			fmt.Printf("  - %s schema %s successfully\n", schema.GetName(), action)
+		}
+	} else {
+		fmt.Printf("  - All schema %s successfully\n", action)
+	}
 }
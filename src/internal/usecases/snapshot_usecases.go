@@ -0,0 +1,16 @@
+package usecases
+
+import "historydb/src/internal/entities"
+
+// SnapshotUsecases is the interface that defines the removal of snapshots from a backup
+//
+// GetBackupMetadata() -> Retrieves the metadata of the backup whose snapshots are removed.
+// GetDeletedSnapshot() -> Retrieves the snapshot of the backup with the given identifier, which can be deleted.
+// GetPrunedSnapshots() -> Retrieves the snapshots of the backup which the retention policy does not keep.
+// DeleteSnapshots() -> Deletes the snapshots from the backup, along with every file the rest of snapshots do not need.
+type SnapshotUsecases interface {
+	GetBackupMetadata() *entities.BackupMetadata
+	GetDeletedSnapshot(metadata *entities.BackupMetadata, snapshotId string) *entities.BackupMetadataSnapshot
+	GetPrunedSnapshots(metadata *entities.BackupMetadata, policy entities.RetentionPolicy) []entities.BackupMetadataSnapshot
+	DeleteSnapshots(snapshots []entities.BackupMetadataSnapshot) bool
+}
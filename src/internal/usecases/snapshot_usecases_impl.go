@@ -0,0 +1,121 @@
+package usecases
+
+import (
+	"errors"
+	"fmt"
+	"historydb/src/internal/entities"
+	"historydb/src/internal/services"
+	backup_services "historydb/src/internal/services/backup"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+type SnapshotUsecasesImpl struct {
+	backupFactory backup_services.BackupFactory
+	logger        *logrus.Logger
+}
+
+func NewSnapshotUsecasesImpl(backupFactory backup_services.BackupFactory, logger *logrus.Logger) *SnapshotUsecasesImpl {
+	return &SnapshotUsecasesImpl{backupFactory, logger}
+}
+
+func (uc *SnapshotUsecasesImpl) GetBackupMetadata() *entities.BackupMetadata {
+	backupReader := uc.backupFactory.CreateReader()
+
+	if ok := backupReader.CheckBackupExists(); !ok {
+		fmt.Println("The specified backup path does not exist.")
+		return nil
+	}
+
+	backupMetadata, err := backupReader.GetBackupMetadata()
+	if err != nil {
+		if errors.Is(err, services.ErrBackupCorruptedFile) {
+			fmt.Println("The specified backup is corrupted.")
+		} else if errors.Is(err, services.ErrBackupKeyRequired) {
+			fmt.Println("The specified backup is encrypted. Its passphrase must be provided with --keyFile or HISTORYDB_PASSPHRASE.")
+		} else if errors.Is(err, services.ErrBackupKeyInvalid) {
+			fmt.Println("The provided passphrase does not open the specified backup.")
+		}
+
+		uc.logger.Errorf("could not retrieve backup metadata: %v", err)
+		return nil
+	}
+
+	return &backupMetadata
+}
+
+func (uc *SnapshotUsecasesImpl) GetDeletedSnapshot(metadata *entities.BackupMetadata, snapshotId string) *entities.BackupMetadataSnapshot {
+	for _, snapshot := range metadata.Snapshots {
+		if snapshot.SnapshotId != snapshotId {
+			continue
+		}
+
+		// A backup without snapshots has nothing to take the next diff snapshot from
+		if len(metadata.Snapshots) == 1 {
+			fmt.Println("The only snapshot of the backup can not be deleted.")
+			return nil
+		}
+		return &snapshot
+	}
+
+	fmt.Printf("The snapshot %s does not exist in the backup.\n", snapshotId)
+	return nil
+}
+
+func (uc *SnapshotUsecasesImpl) GetPrunedSnapshots(metadata *entities.BackupMetadata, policy entities.RetentionPolicy) []entities.BackupMetadataSnapshot {
+	kept := policy.KeptSnapshots(metadata.Snapshots)
+
+	pruned := []entities.BackupMetadataSnapshot{}
+	for _, snapshot := range metadata.Snapshots {
+		if !kept[snapshot.SnapshotId] {
+			pruned = append(pruned, snapshot)
+		}
+	}
+
+	if len(pruned) == 0 {
+		fmt.Printf("All %d snapshots of the backup are kept by the retention policy.\n", len(metadata.Snapshots))
+		return pruned
+	}
+	fmt.Printf("Keeping %d of the %d snapshots of the backup\n", len(kept), len(metadata.Snapshots))
+	for _, snapshot := range pruned {
+		fmt.Printf("  - Removing snapshot %s taken at %s\n", snapshot.SnapshotId, snapshot.Timestamp.Format(time.RFC3339))
+	}
+	return pruned
+}
+
+func (uc *SnapshotUsecasesImpl) DeleteSnapshots(snapshots []entities.BackupMetadataSnapshot) bool {
+	backupWriter := uc.backupFactory.CreateWriter()
+
+	snapshotIds := make([]string, 0, len(snapshots))
+	for _, snapshot := range snapshots {
+		snapshotIds = append(snapshotIds, snapshot.SnapshotId)
+	}
+
+	sweep, err := backupWriter.DeleteSnapshots(snapshotIds)
+	if err != nil {
+		if errors.Is(err, services.ErrBackupSnapshotNotFound) {
+			fmt.Println("The snapshots to delete no longer exist in the backup.")
+		} else if errors.Is(err, services.ErrBackupCorruptedFile) || errors.Is(err, services.ErrBackupChunkNotFound) {
+			fmt.Println("The snapshots could not be deleted, as the backup is damaged. It can be checked with historydb verify.")
+		} else {
+			fmt.Println("The snapshots could not be deleted")
+		}
+
+		uc.logger.Errorf("could not delete snapshots %v: %v", snapshotIds, err)
+		return false
+	}
+
+	for _, snapshotId := range snapshotIds {
+		uc.logger.Infof("deleted snapshot %s", snapshotId)
+	}
+	for _, name := range sweep.RebasedObjects {
+		uc.logger.Infof("re-based %s onto a full object", name)
+	}
+	for _, name := range sweep.DeletedFiles {
+		uc.logger.Infof("deleted backup file %s", name)
+	}
+
+	fmt.Printf("Snapshots deleted successfully! %d objects were re-based and %d files were deleted.\n", len(sweep.RebasedObjects), len(sweep.DeletedFiles))
+	return true
+}
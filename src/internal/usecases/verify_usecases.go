@@ -0,0 +1,16 @@
+package usecases
+
+import "historydb/src/internal/entities"
+
+// VerifyUsecases is the interface that defines the checks of the integrity of a backup
+//
+// GetBackupMetadata() -> Retrieves the metadata of the backup to verify.
+// VerifyBackupFiles() -> Checks the integrity hash of every file of the backup.
+// VerifySnapshot() -> Rebuilds every object of a snapshot through its diff chain, checking that its hash matches its reference.
+// VerifyBackupObjects() -> Checks that every object saved in the backup is referenced by some snapshot.
+type VerifyUsecases interface {
+	GetBackupMetadata() *entities.BackupMetadata
+	VerifyBackupFiles() bool
+	VerifySnapshot(snapshotMetadata entities.BackupMetadataSnapshot) (*entities.BackupSnapshot, bool)
+	VerifyBackupObjects(snapshots []entities.BackupSnapshot, complete bool) bool
+}
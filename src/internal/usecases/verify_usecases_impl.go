@@ -0,0 +1,276 @@
+package usecases
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"historydb/src/internal/entities"
+	"historydb/src/internal/services"
+	backup_services "historydb/src/internal/services/backup"
+	"historydb/src/internal/utils/crypto"
+	"io/fs"
+	"maps"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/schollz/progressbar/v3"
+	"github.com/sirupsen/logrus"
+)
+
+type VerifyUsecasesImpl struct {
+	backupFactory backup_services.BackupFactory
+	logger        *logrus.Logger
+
+	// verified keeps the problem found in every object already verified, empty if it is intact, as most objects are
+	// shared by many snapshots
+	verified map[string]string
+}
+
+func NewVerifyUsecasesImpl(backupFactory backup_services.BackupFactory, logger *logrus.Logger) *VerifyUsecasesImpl {
+	return &VerifyUsecasesImpl{backupFactory, logger, make(map[string]string)}
+}
+
+func (uc *VerifyUsecasesImpl) GetBackupMetadata() *entities.BackupMetadata {
+	backupReader := uc.backupFactory.CreateReader()
+
+	if ok := backupReader.CheckBackupExists(); !ok {
+		fmt.Println("The specified backup path does not exist.")
+		return nil
+	}
+
+	backupMetadata, err := backupReader.GetBackupMetadata()
+	if err != nil {
+		if errors.Is(err, services.ErrBackupCorruptedFile) {
+			fmt.Println("The metadata of the specified backup is corrupted.")
+		} else if errors.Is(err, services.ErrBackupKeyRequired) {
+			fmt.Println("The specified backup is encrypted. Its passphrase must be provided with --keyFile or HISTORYDB_PASSPHRASE.")
+		} else if errors.Is(err, services.ErrBackupKeyInvalid) {
+			fmt.Println("The provided passphrase does not open the specified backup.")
+		}
+
+		uc.logger.Errorf("could not retrieve backup metadata: %v", err)
+		return nil
+	}
+
+	uc.logger.Info("started backup verification")
+	return &backupMetadata
+}
+
+func (uc *VerifyUsecasesImpl) VerifyBackupFiles() bool {
+	backupReader := uc.backupFactory.CreateReader()
+
+	files, err := backupReader.ListBackupFiles()
+	if err != nil {
+		fmt.Println("The files of the backup could not be listed")
+		uc.logger.Errorf("could not list backup files: %v", err)
+		return false
+	}
+
+	problems := []string{}
+	fileProgress := progressbar.NewOptions(len(files), progressbar.OptionSetDescription(fmt.Sprintf("  + Checking all %d backup files...", len(files))), progressbar.OptionSetWidth(30), progressbar.OptionSetWriter(os.Stdout), progressbar.OptionSetRenderBlankState(true))
+	for _, name := range files {
+		if err := backupReader.CheckBackupFile(name); err != nil {
+			problems = append(problems, fmt.Sprintf("%s is %s", name, describeVerifyError(err)))
+			uc.logger.Errorf("backup file %s failed its integrity check: %v", name, err)
+		}
+		fileProgress.Add(1)
+	}
+	fmt.Println()
+
+	return uc.printVerifyResult(problems, fmt.Sprintf("All %d backup files are intact", len(files)))
+}
+
+func (uc *VerifyUsecasesImpl) VerifySnapshot(snapshotMetadata entities.BackupMetadataSnapshot) (*entities.BackupSnapshot, bool) {
+	backupReader := uc.backupFactory.CreateReader()
+
+	fmt.Printf("Snapshot %s taken at %s\n", snapshotMetadata.SnapshotId, snapshotMetadata.Timestamp.Format(time.RFC3339))
+	snapshot, err := backupReader.GetBackupSnapshot(snapshotMetadata.SnapshotId)
+	if err != nil {
+		uc.logger.Errorf("could not read snapshot %s: %v", snapshotMetadata.SnapshotId, err)
+		return nil, uc.printVerifyResult([]string{fmt.Sprintf("The snapshot file is %s", describeVerifyError(err))}, "")
+	}
+
+	problems := []string{}
+	for _, name := range slices.Sorted(maps.Keys(snapshot.SchemaDependencies)) {
+		ref := snapshot.SchemaDependencies[name]
+		if problem := uc.verifyObject("dependency", ref, func() (string, error) {
+			dependency, _, err := backupReader.GetSchemaDependency(ref)
+			if err != nil {
+				return "", err
+			}
+			return dependency.Hash(), nil
+		}); problem != "" {
+			problems = append(problems, fmt.Sprintf("Schema dependency %s is %s", name, problem))
+		}
+	}
+
+	for _, name := range slices.Sorted(maps.Keys(snapshot.Schemas)) {
+		ref := snapshot.Schemas[name]
+		if problem := uc.verifyObject("schema", ref, func() (string, error) {
+			schema, _, err := backupReader.GetSchema(ref)
+			if err != nil {
+				return "", err
+			}
+			return schema.Hash(), nil
+		}); problem != "" {
+			problems = append(problems, fmt.Sprintf("Schema %s is %s", name, problem))
+		}
+	}
+
+	batches := 0
+	for _, schemaData := range snapshot.Data {
+		batches += len(schemaData.Data)
+	}
+	batchProgress := progressbar.NewOptions(batches, progressbar.OptionSetDescription(fmt.Sprintf("  + Verifying all %d batches...", batches)), progressbar.OptionSetWidth(30), progressbar.OptionSetWriter(os.Stdout), progressbar.OptionSetRenderBlankState(true))
+	for _, name := range slices.Sorted(maps.Keys(snapshot.Data)) {
+		for i, batchRef := range snapshot.Data[name].Data {
+			if problem := uc.verifyObject("batch", batchRef, func() (string, error) {
+				return uc.verifyBatch(backupReader, batchRef)
+			}); problem != "" {
+				problems = append(problems, fmt.Sprintf("Batch %d of %s records is %s", i+1, name, problem))
+			}
+			batchProgress.Add(1)
+		}
+	}
+	fmt.Println()
+
+	for _, name := range slices.Sorted(maps.Keys(snapshot.Routines)) {
+		ref := snapshot.Routines[name]
+		if problem := uc.verifyObject("routine", ref, func() (string, error) {
+			routine, _, err := backupReader.GetRoutine(ref)
+			if err != nil {
+				return "", err
+			}
+			return routine.Hash(), nil
+		}); problem != "" {
+			problems = append(problems, fmt.Sprintf("Routine %s is %s", name, problem))
+		}
+	}
+
+	return &snapshot, uc.printVerifyResult(problems, fmt.Sprintf("All %d schema dependencies, %d schemas, %d batches and %d routines are intact",
+		len(snapshot.SchemaDependencies), len(snapshot.Schemas), batches, len(snapshot.Routines)))
+}
+
+func (uc *VerifyUsecasesImpl) VerifyBackupObjects(snapshots []entities.BackupSnapshot, complete bool) bool {
+	backupReader := uc.backupFactory.CreateReader()
+
+	fmt.Println("Backup objects")
+	// The objects of the snapshots which could not be read would be taken as orphaned
+	if !complete {
+		return uc.printVerifyResult([]string{"The orphaned objects could not be checked, as some snapshots could not be read"}, "")
+	}
+
+	stored, err := backupReader.ListBackupObjects()
+	if err != nil {
+		uc.logger.Errorf("could not list backup objects: %v", err)
+		return uc.printVerifyResult([]string{fmt.Sprintf("The objects of the backup could not be listed, as %s", describeVerifyError(err))}, "")
+	}
+	referenced, err := backupReader.ListReferencedObjects(snapshots)
+	if err != nil {
+		uc.logger.Errorf("could not list the objects referenced by the snapshots: %v", err)
+		return uc.printVerifyResult([]string{fmt.Sprintf("The orphaned objects could not be checked, as an object referenced by the snapshots is %s", describeVerifyError(err))}, "")
+	}
+
+	problems := []string{}
+	for _, name := range stored {
+		if _, ok := slices.BinarySearch(referenced, name); !ok {
+			problems = append(problems, fmt.Sprintf("%s is orphaned, as no snapshot references it", name))
+			uc.logger.Warnf("backup object %s is not referenced by any snapshot", name)
+		}
+	}
+
+	return uc.printVerifyResult(problems, fmt.Sprintf("All %d objects are referenced by the snapshots", len(stored)))
+}
+
+// verifyObject verifies the object ref of the given kind with verify, which rebuilds the object and returns its hash, and
+// returns the problem found in it, or an empty string if it is intact.
+func (uc *VerifyUsecasesImpl) verifyObject(kind, ref string, verify func() (string, error)) string {
+	key := fmt.Sprintf("%s:%s", kind, ref)
+	if problem, ok := uc.verified[key]; ok {
+		return problem
+	}
+
+	var problem string
+	var chunkErr *verifyChunkError
+	hash, err := verify()
+	if errors.As(err, &chunkErr) {
+		problem = fmt.Sprintf("damaged, as its chunk %s is %s", chunkErr.chunkRef, describeVerifyError(chunkErr.err))
+		uc.logger.Errorf("could not verify %s %s: %v", kind, ref, err)
+	} else if err != nil {
+		problem = describeVerifyError(err)
+		uc.logger.Errorf("could not verify %s %s: %v", kind, ref, err)
+	} else if refHash, _ := strings.CutPrefix(ref, "diffs/"); !crypto.CompareHashes(refHash, hash) {
+		problem = "altered, as its content does not match its hash"
+		uc.logger.Errorf("the content of %s %s has the hash %s", kind, ref, hash)
+	}
+
+	uc.verified[key] = problem
+	return problem
+}
+
+// verifyBatch rebuilds every chunk of a batch, checking that its hash matches its reference, and returns the hash of the
+// batch computed from the references of its chunks.
+func (uc *VerifyUsecasesImpl) verifyBatch(backupReader backup_services.BackupReader, batchRef string) (string, error) {
+	chunkRefs, err := backupReader.GetSchemaRecordChunkRefsInBatch(batchRef)
+	if err != nil {
+		return "", err
+	}
+
+	batchHashBytes := sha256.New()
+	for _, chunkRef := range chunkRefs {
+		chunk, _, err := backupReader.GetSchemaRecordChunk(batchRef, chunkRef)
+		if err != nil {
+			return "", &verifyChunkError{chunkRef, err}
+		}
+		if !crypto.CompareHashes(chunkRef, chunk.Hash()) {
+			return "", &verifyChunkError{chunkRef, fmt.Errorf("%w: its content has the hash %s", services.ErrBackupCorruptedFile, chunk.Hash())}
+		}
+
+		batchHashBytes.Write([]byte(chunkRef))
+		batchHashBytes.Write([]byte("|"))
+	}
+	return hex.EncodeToString(batchHashBytes.Sum(nil)), nil
+}
+
+// verifyChunkError is the error found in a chunk of a batch being verified, so the problem of the batch names the chunk.
+type verifyChunkError struct {
+	chunkRef string
+	err      error
+}
+
+func (err *verifyChunkError) Error() string {
+	return fmt.Sprintf("chunk %s: %v", err.chunkRef, err.err)
+}
+
+func (err *verifyChunkError) Unwrap() error {
+	return err.err
+}
+
+// printVerifyResult prints the problems found by a check, or the message of an intact backup if there are none, and returns
+// whether there are none.
+func (uc *VerifyUsecasesImpl) printVerifyResult(problems []string, intactMessage string) bool {
+	if len(problems) == 0 {
+		fmt.Printf("  - %s\n", intactMessage)
+		return true
+	}
+
+	for _, problem := range problems {
+		fmt.Printf("  x %s\n", problem)
+	}
+	return false
+}
+
+// describeVerifyError describes the problem of a backup file which could not be read.
+func describeVerifyError(err error) string {
+	switch {
+	case errors.Is(err, fs.ErrNotExist), errors.Is(err, services.ErrBackupChunkNotFound):
+		return "missing"
+	case errors.Is(err, services.ErrBackupCorruptedFile), errors.Is(err, services.ErrBackupKeyInvalid):
+		return "corrupted"
+	default:
+		return fmt.Sprintf("unreadable (%v)", err)
+	}
+}
@@ -0,0 +1,96 @@
+package chunking
+
+import "math/bits"
+
+// gearTable holds a pseudo-random value for every byte, used by the gear rolling hash. It is generated from a fixed
+// seed, so the same content gets the same boundaries in every run.
+var gearTable = func() [256]uint64 {
+	var table [256]uint64
+	seed := uint64(0x9e3779b97f4a7c15)
+	for i := range table {
+		// splitmix64
+		seed += 0x9e3779b97f4a7c15
+		z := seed
+		z = (z ^ (z >> 30)) * 0xbf58476d1ce4e5b9
+		z = (z ^ (z >> 27)) * 0x94d049bb133111eb
+		table[i] = z ^ (z >> 31)
+	}
+	return table
+}()
+
+// Chunker finds content-defined boundaries in a stream of items, as the records of a table or the chunks of a batch.
+//
+// It follows FastCDC: every item hash is rolled into a gear hash, and a chunk ends after an item when the top bits of the
+// hash are zero. Chunks never end before the minimum size, a stricter mask is used until the average size and a looser one
+// after it, so chunk sizes stay close to the average, and chunks always end at the maximum size.
+//
+// Item hashes are 64 hex characters and each of them shifts the fingerprint by one bit, so the fingerprint after an item
+// only depends on the hash of that item. Whether a chunk ends after an item then only depends on the item and on the number
+// of items since the last boundary, so after a local change in the stream, once a boundary falls again on an unchanged item,
+// every later boundary is found in the same place.
+type Chunker struct {
+	minSize     int
+	averageSize int
+	maxSize     int
+	strictMask  uint64
+	looseMask   uint64
+	fingerprint uint64
+	size        int
+}
+
+// NewChunker returns a chunker whose chunks have averageSize items on average, between a quarter and four times that size.
+func NewChunker(averageSize int) *Chunker {
+	averageSize = max(averageSize, 1)
+	maskBits := bits.Len(uint(averageSize)) - 1
+
+	return &Chunker{
+		minSize:     max(averageSize/4, 1),
+		averageSize: averageSize,
+		maxSize:     averageSize * 4,
+		strictMask:  topBitsMask(maskBits + 1),
+		looseMask:   topBitsMask(maskBits - 1),
+	}
+}
+
+// Next adds an item to the current chunk and returns whether the chunk ends after it.
+func (chunker *Chunker) Next(hash string) bool {
+	for i := 0; i < len(hash); i++ {
+		chunker.fingerprint = (chunker.fingerprint << 1) + gearTable[hash[i]]
+	}
+	chunker.size++
+
+	var boundary bool
+	switch {
+	case chunker.size < chunker.minSize:
+		boundary = false
+	case chunker.size < chunker.averageSize:
+		boundary = chunker.fingerprint&chunker.strictMask == 0
+	case chunker.size < chunker.maxSize:
+		boundary = chunker.fingerprint&chunker.looseMask == 0
+	default:
+		boundary = true
+	}
+
+	if boundary {
+		chunker.Reset()
+	}
+	return boundary
+}
+
+// Reset starts a new chunk, as when the chunk has to end before a boundary is found.
+func (chunker *Chunker) Reset() {
+	chunker.fingerprint = 0
+	chunker.size = 0
+}
+
+// MaxSize returns the number of items after which a chunk always ends.
+func (chunker *Chunker) MaxSize() int {
+	return chunker.maxSize
+}
+
+func topBitsMask(count int) uint64 {
+	if count <= 0 {
+		return 0
+	}
+	return ^uint64(0) << (64 - count)
+}
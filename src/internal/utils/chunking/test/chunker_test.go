@@ -0,0 +1,133 @@
+package test
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"historydb/src/internal/utils/chunking"
+	"slices"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestChunkerSizes(t *testing.T) {
+	for _, averageSize := range []int{4, 16, 64, 100} {
+		items := testItemHashes("item", 200*averageSize)
+		sizes := testChunkSizes(chunking.NewChunker(averageSize), items)
+
+		total := 0
+		for i, size := range sizes {
+			total += size
+			if i == len(sizes)-1 {
+				// The last chunk ends with the stream, so it can be smaller than the minimum size
+				assert.LessOrEqual(t, size, averageSize*4, fmt.Sprintf("Chunk size - Average: %d", averageSize))
+				continue
+			}
+			assert.GreaterOrEqual(t, size, max(averageSize/4, 1), fmt.Sprintf("Chunk size - Average: %d", averageSize))
+			assert.LessOrEqual(t, size, averageSize*4, fmt.Sprintf("Chunk size - Average: %d", averageSize))
+		}
+		assert.Equal(t, len(items), total, fmt.Sprintf("Chunk size - Average: %d", averageSize))
+
+		mean := float64(total) / float64(len(sizes))
+		assert.Greater(t, mean, float64(averageSize)/2, fmt.Sprintf("Chunk mean size - Average: %d", averageSize))
+		assert.Less(t, mean, float64(averageSize)*2, fmt.Sprintf("Chunk mean size - Average: %d", averageSize))
+	}
+}
+
+func TestChunkerResynchronises(t *testing.T) {
+	averageSize := 16
+	items := testItemHashes("item", 100*averageSize)
+	boundaries := testChunkBoundaries(chunking.NewChunker(averageSize), items)
+
+	middle := len(items) / 2
+	tests := []struct {
+		name  string
+		items []string
+	}{
+		{"Insert", slices.Concat(items[:middle], testItemHashes("inserted", 3), items[middle:])},
+		{"Delete", slices.Concat(items[:middle], items[middle+3:])},
+		{"Update", slices.Concat(items[:middle], testItemHashes("updated", 1), items[middle+1:])},
+	}
+
+	for _, test := range tests {
+		changedBoundaries := testChunkBoundaries(chunking.NewChunker(averageSize), test.items)
+
+		// Boundaries before the change are the same, and a few chunks after it they fall again on the same items
+		// until the end of the stream
+		resyncItem := items[middle+averageSize*4*4]
+		assert.Equal(t, testBoundariesBetween(boundaries, "", items[middle-1]), testBoundariesBetween(changedBoundaries, "", items[middle-1]), fmt.Sprintf("Boundaries before change - Test: %s", test.name))
+		assert.Equal(t, testBoundariesBetween(boundaries, resyncItem, ""), testBoundariesBetween(changedBoundaries, resyncItem, ""), fmt.Sprintf("Boundaries after change - Test: %s", test.name))
+		assert.NotEmpty(t, testBoundariesBetween(boundaries, resyncItem, ""), fmt.Sprintf("Boundaries after change - Test: %s", test.name))
+	}
+}
+
+func TestChunkerReset(t *testing.T) {
+	items := testItemHashes("item", 1000)
+	chunker := chunking.NewChunker(16)
+	for _, item := range items[:10] {
+		chunker.Next(item)
+	}
+	chunker.Reset()
+
+	assert.Equal(t, testChunkSizes(chunking.NewChunker(16), items[10:]), testChunkSizes(chunker, items[10:]), "Chunker reset")
+	assert.Equal(t, 64, chunker.MaxSize(), "Chunker max size")
+}
+
+func testItemHashes(prefix string, count int) []string {
+	hashes := make([]string, count)
+	for i := range hashes {
+		hash := sha256.Sum256([]byte(fmt.Sprintf("%s-%d", prefix, i)))
+		hashes[i] = hex.EncodeToString(hash[:])
+	}
+	return hashes
+}
+
+func testChunkSizes(chunker *chunking.Chunker, items []string) []int {
+	sizes := []int{}
+	size := 0
+	for _, item := range items {
+		size++
+		if chunker.Next(item) {
+			sizes = append(sizes, size)
+			size = 0
+		}
+	}
+	if size > 0 {
+		sizes = append(sizes, size)
+	}
+	return sizes
+}
+
+// testChunkBoundaries returns the items of the stream, marking the ones every chunk ends with.
+func testChunkBoundaries(chunker *chunking.Chunker, items []string) []testItem {
+	boundaries := make([]testItem, len(items))
+	for i, item := range items {
+		boundaries[i] = testItem{hash: item, boundary: chunker.Next(item)}
+	}
+	return boundaries
+}
+
+// testBoundariesBetween returns the items chunks end with from the from item, or the start of the stream, until the
+// to item, or the end of the stream.
+func testBoundariesBetween(items []testItem, from, to string) []string {
+	boundaries := []string{}
+	started := from == ""
+	for _, item := range items {
+		if item.hash == to {
+			break
+		}
+		if item.hash == from {
+			started = true
+		}
+		if started && item.boundary {
+			boundaries = append(boundaries, item.hash)
+		}
+	}
+	return boundaries
+}
+
+type testItem struct {
+	hash     string
+	boundary bool
+}
@@ -0,0 +1,101 @@
+package comparation
+
+import (
+	"bytes"
+	"cmp"
+	"fmt"
+	"historydb/src/internal/utils/types"
+	"math/big"
+	"strings"
+	"time"
+)
+
+// CompareValues orders two record values as databases order their keys: numbers by their value, strings and bytes byte
+// by byte, and times and dates chronologically. Values of different kinds are ordered by kind, and the rest of values
+// by their text, so every pair of values has an order even if the database would order them in another way.
+func CompareValues(a, b interface{}) int {
+	if kindA, kindB := valueKind(a), valueKind(b); kindA != kindB {
+		return cmp.Compare(kindA, kindB)
+	}
+
+	switch a := a.(type) {
+	case nil:
+		return 0
+	case int64:
+		if b, ok := b.(int64); ok {
+			return cmp.Compare(a, b)
+		}
+	case float64:
+		if b, ok := b.(float64); ok {
+			return cmp.Compare(a, b)
+		}
+	case string:
+		return strings.Compare(a, b.(string))
+	case []byte:
+		return bytes.Compare(a, b.([]byte))
+	case bool:
+		return cmp.Compare(boolRank(a), boolRank(b.(bool)))
+	case time.Time:
+		return a.Compare(b.(time.Time))
+	case types.Date:
+		return a.Time().Compare(b.(types.Date).Time())
+	case types.UUID:
+		b := b.(types.UUID)
+		return bytes.Compare(a[:], b[:])
+	}
+
+	// Numbers of different types are compared by their exact value
+	if ratA, ok := toRat(a); ok {
+		if ratB, ok := toRat(b); ok {
+			return ratA.Cmp(ratB)
+		}
+	}
+	return strings.Compare(fmt.Sprint(a), fmt.Sprint(b))
+}
+
+// valueKind returns the kind of the value, which orders the values of different kinds.
+func valueKind(value interface{}) int {
+	switch value.(type) {
+	case nil:
+		return 0
+	case int64, float64, types.Decimal:
+		return 1
+	case string:
+		return 2
+	case []byte:
+		return 3
+	case bool:
+		return 4
+	case time.Time:
+		return 5
+	case types.Date:
+		return 6
+	case types.UUID:
+		return 7
+	default:
+		return 8
+	}
+}
+
+func boolRank(value bool) int {
+	if value {
+		return 1
+	}
+	return 0
+}
+
+func toRat(value interface{}) (*big.Rat, bool) {
+	switch value := value.(type) {
+	case int64:
+		return new(big.Rat).SetInt64(value), true
+	case float64:
+		rat := new(big.Rat)
+		if rat.SetFloat64(value) == nil {
+			return nil, false
+		}
+		return rat, true
+	case types.Decimal:
+		return new(big.Rat).SetString(string(value))
+	}
+	return nil, false
+}
@@ -30,47 +30,11 @@ func DecodeMap(buf *bytes.Buffer) (map[string]interface{}, error) {
 			return nil, err
 		}
 
-		typ, err := mapBuf.ReadByte()
+		value, err := DecodeValue(mapBuf)
 		if err != nil {
 			return nil, err
 		}
-
-		switch typ {
-		case 0x00:
-			m[*k] = nil
-		case 0x01:
-			str, err := DecodeString(mapBuf)
-			if err != nil {
-				return nil, err
-			}
-			m[*k] = *str
-		case 0x02:
-			i, err := DecodeInt(mapBuf)
-			if err != nil {
-				return nil, err
-			}
-			m[*k] = *i
-		case 0x03:
-			b, err := DecodeBool(mapBuf)
-			if err != nil {
-				return nil, err
-			}
-			m[*k] = *b
-		case 0x04:
-			f, err := DecodeFloat(mapBuf)
-			if err != nil {
-				return nil, err
-			}
-			m[*k] = *f
-		case 0x05:
-			t, err := DecodeTime(mapBuf)
-			if err != nil {
-				return nil, err
-			}
-			m[*k] = *t
-		default:
-			panic("unsupported type")
-		}
+		m[*k] = value
 	}
 
 	return m, nil
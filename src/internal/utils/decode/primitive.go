@@ -77,10 +77,24 @@ func DecodeTime(buf *bytes.Buffer) (*time.Time, error) {
 		return nil, err
 	}
 
-	time, err := time.Parse(time.RFC3339, *timeString)
+	time, err := time.Parse(time.RFC3339Nano, *timeString)
 	if err != nil {
 		return nil, err
 	}
 
 	return &time, nil
 }
+
+func DecodeBytes(buf *bytes.Buffer) ([]byte, error) {
+	var length uint64
+	if err := binary.Read(buf, binary.LittleEndian, &length); err != nil {
+		return nil, err
+	}
+
+	b := make([]byte, length)
+	if _, err := io.ReadFull(buf, b); err != nil {
+		return nil, err
+	}
+
+	return b, nil
+}
@@ -0,0 +1,199 @@
+package decode
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"historydb/src/internal/utils/types"
+	"io"
+	"time"
+)
+
+// DecodeValue reads a value written by encode.EncodeValue into the type of its tag.
+func DecodeValue(buf *bytes.Buffer) (interface{}, error) {
+	typ, err := buf.ReadByte()
+	if err != nil {
+		return nil, err
+	}
+
+	switch typ {
+	case 0x00:
+		return nil, nil
+	case 0x01:
+		str, err := DecodeString(buf)
+		if err != nil {
+			return nil, err
+		}
+		return *str, nil
+	case 0x02:
+		i, err := DecodeInt(buf)
+		if err != nil {
+			return nil, err
+		}
+		return *i, nil
+	case 0x03:
+		b, err := DecodeBool(buf)
+		if err != nil {
+			return nil, err
+		}
+		return *b, nil
+	case 0x04:
+		f, err := DecodeFloat(buf)
+		if err != nil {
+			return nil, err
+		}
+		return *f, nil
+	case 0x05:
+		t, err := DecodeTime(buf)
+		if err != nil {
+			return nil, err
+		}
+		return *t, nil
+	case 0x06:
+		return DecodeBytes(buf)
+	case 0x07:
+		str, err := DecodeString(buf)
+		if err != nil {
+			return nil, err
+		}
+		return types.Decimal(*str), nil
+	case 0x08:
+		return decodeZonedTime(buf)
+	case 0x09:
+		var year int64
+		if err := binary.Read(buf, binary.LittleEndian, &year); err != nil {
+			return nil, err
+		}
+		monthDay := buf.Next(2)
+		if len(monthDay) != 2 {
+			return nil, io.ErrUnexpectedEOF
+		}
+		return types.Date{Year: int(year), Month: time.Month(monthDay[0]), Day: int(monthDay[1])}, nil
+	case 0x0A:
+		var interval types.Interval
+		if err := binary.Read(buf, binary.LittleEndian, &interval.Months); err != nil {
+			return nil, err
+		}
+		if err := binary.Read(buf, binary.LittleEndian, &interval.Days); err != nil {
+			return nil, err
+		}
+		if err := binary.Read(buf, binary.LittleEndian, &interval.Microseconds); err != nil {
+			return nil, err
+		}
+		return interval, nil
+	case 0x0B:
+		var uuid types.UUID
+		if _, err := io.ReadFull(buf, uuid[:]); err != nil {
+			return nil, err
+		}
+		return uuid, nil
+	case 0x0C:
+		str, err := DecodeString(buf)
+		if err != nil {
+			return nil, err
+		}
+		return types.JSON(*str), nil
+	case 0x0D:
+		return decodeArray(buf)
+	case 0x0E:
+		return decodeRange(buf)
+	case 0x0F:
+		infinity, err := buf.ReadByte()
+		if err != nil {
+			return nil, err
+		}
+		return types.Infinity(int8(infinity)), nil
+	default:
+		return nil, fmt.Errorf("unsupported value type: 0x%02x", typ)
+	}
+}
+
+// decodeZonedTime reads a time written with its Unix seconds, nanoseconds and time zone.
+func decodeZonedTime(buf *bytes.Buffer) (time.Time, error) {
+	var seconds int64
+	var nanoseconds, zoneOffset int32
+	if err := binary.Read(buf, binary.LittleEndian, &seconds); err != nil {
+		return time.Time{}, err
+	}
+	if err := binary.Read(buf, binary.LittleEndian, &nanoseconds); err != nil {
+		return time.Time{}, err
+	}
+	zoneName, err := DecodeString(buf)
+	if err != nil {
+		return time.Time{}, err
+	}
+	if err := binary.Read(buf, binary.LittleEndian, &zoneOffset); err != nil {
+		return time.Time{}, err
+	}
+
+	location := time.UTC
+	if *zoneName != "UTC" || zoneOffset != 0 {
+		location = time.FixedZone(*zoneName, int(zoneOffset))
+	}
+	return time.Unix(seconds, int64(nanoseconds)).In(location), nil
+}
+
+func decodeArray(buf *bytes.Buffer) (types.Array, error) {
+	var dimensionCount uint64
+	if err := binary.Read(buf, binary.LittleEndian, &dimensionCount); err != nil {
+		return types.Array{}, err
+	}
+	// Every dimension takes 8 bytes, so a corrupted count can not allocate more than the buffer size
+	if dimensionCount > uint64(buf.Len()) {
+		return types.Array{}, io.ErrUnexpectedEOF
+	}
+
+	array := types.Array{Dimensions: make([]types.ArrayDimension, dimensionCount)}
+	for i := range array.Dimensions {
+		if err := binary.Read(buf, binary.LittleEndian, &array.Dimensions[i].Length); err != nil {
+			return types.Array{}, err
+		}
+		if err := binary.Read(buf, binary.LittleEndian, &array.Dimensions[i].LowerBound); err != nil {
+			return types.Array{}, err
+		}
+	}
+
+	var elementCount uint64
+	if err := binary.Read(buf, binary.LittleEndian, &elementCount); err != nil {
+		return types.Array{}, err
+	}
+	if elementCount > uint64(buf.Len()) {
+		return types.Array{}, io.ErrUnexpectedEOF
+	}
+
+	array.Elements = make([]interface{}, elementCount)
+	for i := range array.Elements {
+		element, err := DecodeValue(buf)
+		if err != nil {
+			return types.Array{}, err
+		}
+		array.Elements[i] = element
+	}
+
+	return array, nil
+}
+
+func decodeRange(buf *bytes.Buffer) (types.Range, error) {
+	flags, err := buf.ReadByte()
+	if err != nil {
+		return types.Range{}, err
+	}
+
+	r := types.Range{
+		Empty:          flags&(1<<0) != 0,
+		LowerInclusive: flags&(1<<1) != 0,
+		UpperInclusive: flags&(1<<2) != 0,
+	}
+	if flags&(1<<3) != 0 {
+		if r.Lower, err = DecodeValue(buf); err != nil {
+			return types.Range{}, err
+		}
+	}
+	if flags&(1<<4) != 0 {
+		if r.Upper, err = DecodeValue(buf); err != nil {
+			return types.Range{}, err
+		}
+	}
+
+	return r, nil
+}
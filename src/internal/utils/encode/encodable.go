@@ -3,3 +3,8 @@ package encode
 type Encodable interface {
 	EncodeToBytes() []byte
 }
+
+// FallibleEncodable is an entity whose encoding fails if it holds a value of an unsupported type.
+type FallibleEncodable interface {
+	EncodeToBytes() ([]byte, error)
+}
@@ -4,12 +4,11 @@ import (
 	"bytes"
 	"encoding/binary"
 	"fmt"
-	"historydb/src/internal/utils/pointers"
 	"sort"
-	"time"
 )
 
-func EncodeMap(buf *bytes.Buffer, m map[string]interface{}) {
+// EncodeMap writes the values of the map sorted by key. It fails if a value has a type EncodeValue does not support.
+func EncodeMap(buf *bytes.Buffer, m map[string]interface{}) error {
 	if len(m) > 0 {
 		var mapBuf bytes.Buffer
 
@@ -22,38 +21,16 @@ func EncodeMap(buf *bytes.Buffer, m map[string]interface{}) {
 
 		// Encode each key-value pair
 		for _, k := range keys {
-			v := m[k]
 			EncodeString(&mapBuf, &k)
-
-			switch val := v.(type) {
-			case nil:
-				mapBuf.WriteByte(0x00)
-			case string:
-				mapBuf.WriteByte(0x01)
-				EncodeString(&mapBuf, &val)
-			case int64:
-				mapBuf.WriteByte(0x02)
-				EncodeInt(&mapBuf, &val)
-			case int:
-				mapBuf.WriteByte(0x02)
-				EncodeInt(&mapBuf, pointers.Ptr(int64(val)))
-			case bool:
-				mapBuf.WriteByte(0x03)
-				EncodeBool(&mapBuf, &val)
-			case float64:
-				mapBuf.WriteByte(0x04)
-				EncodeFloat(&mapBuf, &val)
-			case time.Time:
-				mapBuf.WriteByte(0x05)
-				EncodeTime(&mapBuf, &val)
-			default:
-				panic(fmt.Sprintf("unsupported type: %T", val))
+			if err := EncodeValue(&mapBuf, m[k]); err != nil {
+				return fmt.Errorf("%s: %w", k, err)
 			}
 		}
 
 		binary.Write(buf, binary.LittleEndian, uint64(len(mapBuf.Bytes())))
 		buf.Write(mapBuf.Bytes())
 	}
+	return nil
 }
 
 func EncodeStructMap[T Encodable](buf *bytes.Buffer, m map[string]T) {
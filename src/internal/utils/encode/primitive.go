@@ -47,7 +47,14 @@ func EncodeFloat(buf *bytes.Buffer, f *float64) {
 
 func EncodeTime(buf *bytes.Buffer, t *time.Time) {
 	if t != nil {
-		timeString := t.Format(time.RFC3339)
+		timeString := t.Format(time.RFC3339Nano)
 		EncodeString(buf, &timeString)
 	}
 }
+
+func EncodeBytes(buf *bytes.Buffer, b []byte) {
+	if b != nil {
+		binary.Write(buf, binary.LittleEndian, uint64(len(b)))
+		buf.Write(b)
+	}
+}
@@ -46,3 +46,27 @@ func EncodeSlice[T Encodable](buf *bytes.Buffer, s []T) {
 		buf.Write(sliceBuf.Bytes())
 	}
 }
+
+// EncodeFallibleSlice writes the slice like EncodeSlice, failing with the error of the first item which cannot be encoded.
+func EncodeFallibleSlice[T FallibleEncodable](buf *bytes.Buffer, s []T) error {
+	if len(s) > 0 {
+		var sliceBuf bytes.Buffer
+		var totalSize uint64
+
+		for _, v := range s {
+			data, err := v.EncodeToBytes()
+			if err != nil {
+				return err
+			}
+			size := uint64(len(data))
+
+			binary.Write(&sliceBuf, binary.LittleEndian, size)
+			sliceBuf.Write(data)
+			totalSize += size + 8 // Size of size littleEndian
+		}
+
+		binary.Write(buf, binary.LittleEndian, totalSize)
+		buf.Write(sliceBuf.Bytes())
+	}
+	return nil
+}
@@ -0,0 +1,137 @@
+package test
+
+import (
+	"bytes"
+	"fmt"
+	"historydb/src/internal/utils/decode"
+	"historydb/src/internal/utils/encode"
+	"historydb/src/internal/utils/pointers"
+	"historydb/src/internal/utils/types"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValueRoundTrip(t *testing.T) {
+	cest := time.FixedZone("CEST", 2*60*60)
+	newYork := time.FixedZone("EST", -5*60*60)
+	uuid := types.UUID{0x12, 0x3e, 0x45, 0x67, 0xe8, 0x9b, 0x12, 0xd3, 0xa4, 0x56, 0x42, 0x66, 0x14, 0x17, 0x40, 0x00}
+
+	tests := []struct {
+		name  string
+		value interface{}
+	}{
+		{"Nil", nil},
+		{"String", "naïve café"},
+		{"Empty string", ""},
+		{"Int64", int64(math.MinInt64)},
+		{"Bool", true},
+		{"Float64", 3.141592653589793},
+		{"Float64 infinity", math.Inf(-1)},
+		{"Bytes", []byte{0x00, 0xff, 0x10, 0x00}},
+		{"Decimal", types.Decimal("12345678901234567890.123456789000")},
+		{"Decimal small", types.Decimal("-0.000001")},
+		{"Decimal NaN", types.Decimal("NaN")},
+		{"Zoned time", time.Date(2024, time.March, 31, 2, 30, 15, 123456789, cest)},
+		{"Zoned time before epoch", time.Date(1969, time.December, 31, 23, 59, 59, 999999999, newYork)},
+		{"UTC time", time.Date(2024, time.January, 1, 0, 0, 0, 1, time.UTC)},
+		{"Date", types.Date{Year: 2024, Month: time.February, Day: 29}},
+		{"Date before Christ", types.Date{Year: -43, Month: time.March, Day: 15}},
+		{"Interval", types.Interval{Months: -14, Days: 3, Microseconds: 86399999999}},
+		{"UUID", uuid},
+		{"JSON", types.JSON(`{"tags": ["a", "b"], "nested": {"value": null}}`)},
+		{"Infinity", types.Infinity(1)},
+		{"Negative infinity", types.Infinity(-1)},
+		{"Array", types.Array{
+			Dimensions: []types.ArrayDimension{{Length: 3, LowerBound: 1}},
+			Elements:   []interface{}{int64(1), nil, int64(3)},
+		}},
+		{"Multidimensional array", types.Array{
+			Dimensions: []types.ArrayDimension{{Length: 2, LowerBound: 0}, {Length: 3, LowerBound: -1}},
+			Elements:   []interface{}{"a", nil, "c", nil, "e", nil},
+		}},
+		{"Array of typed values", types.Array{
+			Dimensions: []types.ArrayDimension{{Length: 3, LowerBound: 1}},
+			Elements:   []interface{}{types.Decimal("1.50"), types.Date{Year: 2000, Month: time.January, Day: 1}, uuid},
+		}},
+		{"Empty array", types.Array{Dimensions: []types.ArrayDimension{}, Elements: []interface{}{}}},
+		{"Range", types.Range{Lower: int64(1), Upper: int64(10), LowerInclusive: true}},
+		{"Inclusive range", types.Range{Lower: types.Decimal("0.5"), Upper: types.Decimal("1.5"), LowerInclusive: true, UpperInclusive: true}},
+		{"Range with infinite lower bound", types.Range{Upper: time.Date(2024, time.June, 1, 12, 0, 0, 500, cest)}},
+		{"Range with infinite upper bound", types.Range{Lower: types.Date{Year: 2024, Month: time.June, Day: 1}, LowerInclusive: true}},
+		{"Range with infinity bound", types.Range{Lower: types.Infinity(-1), Upper: types.Date{Year: 2024, Month: time.June, Day: 1}}},
+		{"Unbounded range", types.Range{}},
+		{"Empty range", types.Range{Empty: true}},
+	}
+	for _, test := range tests {
+		var buf bytes.Buffer
+		if !assert.NoError(t, encode.EncodeValue(&buf, test.value), fmt.Sprintf("EncodeValue - Test: %s", test.name)) {
+			continue
+		}
+		encoded := bytes.Clone(buf.Bytes())
+
+		decoded, err := decode.DecodeValue(&buf)
+		if !assert.NoError(t, err, fmt.Sprintf("DecodeValue - Test: %s", test.name)) {
+			continue
+		}
+		assert.Equal(t, test.value, decoded, fmt.Sprintf("DecodeValue - Test: %s", test.name))
+		assert.Zero(t, buf.Len(), fmt.Sprintf("DecodeValue - Test: %s - Whole value read", test.name))
+
+		// The decoded value is encoded again into the same bytes, so it is saved bit-for-bit on the next snapshot
+		var reencoded bytes.Buffer
+		assert.NoError(t, encode.EncodeValue(&reencoded, decoded), fmt.Sprintf("EncodeValue - Test: %s - Decoded", test.name))
+		assert.Equal(t, encoded, reencoded.Bytes(), fmt.Sprintf("EncodeValue - Test: %s - Decoded", test.name))
+	}
+}
+
+func TestValueInt(t *testing.T) {
+	// Ints are saved as int64, as the drivers read integers
+	var buf bytes.Buffer
+	assert.NoError(t, encode.EncodeValue(&buf, 42))
+	decoded, err := decode.DecodeValue(&buf)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(42), decoded)
+}
+
+func TestValueLegacyTime(t *testing.T) {
+	// Backups of version 1 saved times as RFC3339 text, without their sub-second precision
+	var buf bytes.Buffer
+	buf.WriteByte(0x05)
+	encode.EncodeString(&buf, pointers.Ptr("2023-05-01T12:30:45+02:00"))
+
+	decoded, err := decode.DecodeValue(&buf)
+	if !assert.NoError(t, err) {
+		return
+	}
+	decodedTime, ok := decoded.(time.Time)
+	if !assert.True(t, ok, "DecodeValue - Type") {
+		return
+	}
+	assert.True(t, time.Date(2023, time.May, 1, 10, 30, 45, 0, time.UTC).Equal(decodedTime), "DecodeValue - Instant")
+	_, offset := decodedTime.Zone()
+	assert.Equal(t, 2*60*60, offset, "DecodeValue - Offset")
+}
+
+func TestValueUnsupported(t *testing.T) {
+	tests := []struct {
+		name  string
+		value interface{}
+		err   string
+	}{
+		{"Int32", int32(1), "unsupported value type: int32"},
+		{"Pointer", pointers.Ptr("value"), "unsupported value type: *string"},
+		{"Array element", types.Array{Dimensions: []types.ArrayDimension{{Length: 1, LowerBound: 1}}, Elements: []interface{}{uint32(1)}}, "unsupported value type: uint32"},
+		{"Range bound", types.Range{Lower: float32(1)}, "unsupported value type: float32"},
+	}
+	for _, test := range tests {
+		var buf bytes.Buffer
+		assert.EqualError(t, encode.EncodeValue(&buf, test.value), test.err, fmt.Sprintf("EncodeValue - Test: %s", test.name))
+	}
+
+	var buf bytes.Buffer
+	err := encode.EncodeMap(&buf, map[string]interface{}{"id": int64(1), "total": int32(1)})
+	assert.EqualError(t, err, "total: unsupported value type: int32", "EncodeMap")
+	assert.Zero(t, buf.Len(), "EncodeMap - Nothing written")
+}
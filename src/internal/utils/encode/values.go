@@ -0,0 +1,142 @@
+package encode
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"historydb/src/internal/utils/pointers"
+	"historydb/src/internal/utils/types"
+	"time"
+)
+
+// EncodeValue writes a value preceded by the tag of its type, so it can be decoded back into the same type.
+//
+// 0x00 -> nil
+// 0x01 -> string
+// 0x02 -> int64
+// 0x03 -> bool
+// 0x04 -> float64
+// 0x05 -> time.Time as RFC3339 text. Only written by backups of version 1, which lost the sub-second precision
+// 0x06 -> []byte
+// 0x07 -> types.Decimal
+// 0x08 -> time.Time with nanoseconds and time zone
+// 0x09 -> types.Date
+// 0x0A -> types.Interval
+// 0x0B -> types.UUID
+// 0x0C -> types.JSON
+// 0x0D -> types.Array
+// 0x0E -> types.Range
+// 0x0F -> types.Infinity
+//
+// Tags are never reused, so the values of older backups are always decoded into their original type. Values of any other
+// type are not written and return an error which names their type.
+func EncodeValue(buf *bytes.Buffer, v interface{}) error {
+	switch val := v.(type) {
+	case nil:
+		buf.WriteByte(0x00)
+	case string:
+		buf.WriteByte(0x01)
+		EncodeString(buf, &val)
+	case int64:
+		buf.WriteByte(0x02)
+		EncodeInt(buf, &val)
+	case int:
+		buf.WriteByte(0x02)
+		EncodeInt(buf, pointers.Ptr(int64(val)))
+	case bool:
+		buf.WriteByte(0x03)
+		EncodeBool(buf, &val)
+	case float64:
+		buf.WriteByte(0x04)
+		EncodeFloat(buf, &val)
+	case []byte:
+		buf.WriteByte(0x06)
+		EncodeBytes(buf, val)
+	case types.Decimal:
+		buf.WriteByte(0x07)
+		EncodeString(buf, pointers.Ptr(string(val)))
+	case time.Time:
+		buf.WriteByte(0x08)
+		encodeZonedTime(buf, val)
+	case types.Date:
+		buf.WriteByte(0x09)
+		binary.Write(buf, binary.LittleEndian, int64(val.Year))
+		buf.WriteByte(byte(val.Month))
+		buf.WriteByte(byte(val.Day))
+	case types.Interval:
+		buf.WriteByte(0x0A)
+		binary.Write(buf, binary.LittleEndian, val.Months)
+		binary.Write(buf, binary.LittleEndian, val.Days)
+		binary.Write(buf, binary.LittleEndian, val.Microseconds)
+	case types.UUID:
+		buf.WriteByte(0x0B)
+		buf.Write(val[:])
+	case types.JSON:
+		buf.WriteByte(0x0C)
+		EncodeString(buf, pointers.Ptr(string(val)))
+	case types.Array:
+		buf.WriteByte(0x0D)
+		binary.Write(buf, binary.LittleEndian, uint64(len(val.Dimensions)))
+		for _, dimension := range val.Dimensions {
+			binary.Write(buf, binary.LittleEndian, dimension.Length)
+			binary.Write(buf, binary.LittleEndian, dimension.LowerBound)
+		}
+		binary.Write(buf, binary.LittleEndian, uint64(len(val.Elements)))
+		for _, element := range val.Elements {
+			if err := EncodeValue(buf, element); err != nil {
+				return err
+			}
+		}
+	case types.Range:
+		buf.WriteByte(0x0E)
+		buf.WriteByte(rangeFlags(val))
+		if val.Lower != nil {
+			if err := EncodeValue(buf, val.Lower); err != nil {
+				return err
+			}
+		}
+		if val.Upper != nil {
+			if err := EncodeValue(buf, val.Upper); err != nil {
+				return err
+			}
+		}
+	case types.Infinity:
+		buf.WriteByte(0x0F)
+		buf.WriteByte(byte(val))
+	default:
+		return fmt.Errorf("unsupported value type: %T", val)
+	}
+	return nil
+}
+
+// encodeZonedTime writes a time as its Unix seconds and nanoseconds, followed by the name and offset of its time zone.
+func encodeZonedTime(buf *bytes.Buffer, t time.Time) {
+	zoneName, zoneOffset := t.Zone()
+
+	binary.Write(buf, binary.LittleEndian, t.Unix())
+	binary.Write(buf, binary.LittleEndian, int32(t.Nanosecond()))
+	EncodeString(buf, &zoneName)
+	binary.Write(buf, binary.LittleEndian, int32(zoneOffset))
+}
+
+func rangeFlags(r types.Range) byte {
+	var flags byte
+
+	if r.Empty {
+		flags |= 1 << 0
+	}
+	if r.LowerInclusive {
+		flags |= 1 << 1
+	}
+	if r.UpperInclusive {
+		flags |= 1 << 2
+	}
+	if r.Lower != nil {
+		flags |= 1 << 3
+	}
+	if r.Upper != nil {
+		flags |= 1 << 4
+	}
+
+	return flags
+}
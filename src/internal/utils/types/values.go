@@ -0,0 +1,87 @@
+package types
+
+import (
+	"encoding/hex"
+	"fmt"
+	"time"
+)
+
+// Decimal is an arbitrary-precision number, kept as its exact text representation so no digit or trailing zero is lost.
+// (12.30, -0.000001, NaN, Infinity)
+type Decimal string
+
+// Date is a calendar date without time of day or time zone.
+type Date struct {
+	Year  int
+	Month time.Month
+	Day   int
+}
+
+func DateOf(t time.Time) Date {
+	return Date{Year: t.Year(), Month: t.Month(), Day: t.Day()}
+}
+
+// Time returns the date at midnight UTC.
+func (date Date) Time() time.Time {
+	return time.Date(date.Year, date.Month, date.Day, 0, 0, 0, 0, time.UTC)
+}
+
+func (date Date) String() string {
+	return date.Time().Format(time.DateOnly)
+}
+
+// Infinity is an infinite date or timestamp. Positive values are after every other value, negative values before.
+type Infinity int8
+
+func (infinity Infinity) String() string {
+	if infinity < 0 {
+		return "-infinity"
+	}
+	return "infinity"
+}
+
+// Interval is a time span kept in the same units PostgreSQL uses, as months and days do not have a fixed length.
+type Interval struct {
+	Months       int32
+	Days         int32
+	Microseconds int64
+}
+
+// UUID is a universally unique identifier in its binary form.
+type UUID [16]byte
+
+func (uuid UUID) String() string {
+	return fmt.Sprintf("%s-%s-%s-%s-%s", hex.EncodeToString(uuid[0:4]), hex.EncodeToString(uuid[4:6]),
+		hex.EncodeToString(uuid[6:8]), hex.EncodeToString(uuid[8:10]), hex.EncodeToString(uuid[10:16]))
+}
+
+// JSON is a JSON document, kept as the text returned by the database.
+type JSON string
+
+// ArrayDimension is the length and the index of the first element of an array dimension.
+type ArrayDimension struct {
+	Length     int32
+	LowerBound int32
+}
+
+// Array is a multidimensional array.
+//
+// Dimensions -> The dimensions of the array, from the outermost to the innermost. Empty arrays have no dimensions
+// Elements -> The elements of the array in row-major order
+type Array struct {
+	Dimensions []ArrayDimension
+	Elements   []interface{}
+}
+
+// Range is a range of values.
+//
+// Lower, Upper -> The bounds of the range. They are nil when the bound is infinite
+// LowerInclusive, UpperInclusive -> Whether the bounds are part of the range
+// Empty -> Whether the range has no values. Empty ranges have no bounds
+type Range struct {
+	Lower          interface{}
+	Upper          interface{}
+	LowerInclusive bool
+	UpperInclusive bool
+	Empty          bool
+}